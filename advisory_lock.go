@@ -0,0 +1,193 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// advisoryUnlockTimeout 释放会话级咨询锁的超时
+const advisoryUnlockTimeout = 5 * time.Second
+
+var (
+	// ErrAdvisoryLockUnsupported 当前数据库不支持 PostgreSQL 咨询锁
+	ErrAdvisoryLockUnsupported = errors.New("advisory locks require postgresql")
+	// ErrAdvisoryLockNotHeld 咨询锁已释放
+	ErrAdvisoryLockNotHeld = errors.New("advisory lock not held")
+)
+
+// AdvisoryKey 将字符串锁名哈希为咨询锁的 64 位键（FNV-1a），不同服务使用相同名称时得到相同的键
+func AdvisoryKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLockHandle 已获取的会话级咨询锁，持有期间独占一个连接，需调用 Unlock 释放
+type AdvisoryLockHandle struct {
+	key  int64
+	conn *sql.Conn
+
+	once sync.Once
+	err  error
+}
+
+// AdvisoryLock 获取会话级咨询锁（pg_advisory_lock），其他会话持有时等待，直到获取成功或 ctx 取消。
+// 锁绑定在从连接池取出的专用连接上，与调用方后续的查询使用的连接无关；连接断开时锁自动释放
+func AdvisoryLock(ctx context.Context, gdb *gorm.DB, key int64) (*AdvisoryLockHandle, error) {
+	conn, err := advisoryConn(ctx, gdb)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		discardConn(conn)
+		return nil, fmt.Errorf("failed to acquire advisory lock %d: %w", key, err)
+	}
+	return &AdvisoryLockHandle{key: key, conn: conn}, nil
+}
+
+// TryAdvisoryLock 尝试获取会话级咨询锁（pg_try_advisory_lock），不等待；其他会话持有时返回 false
+func TryAdvisoryLock(ctx context.Context, gdb *gorm.DB, key int64) (*AdvisoryLockHandle, bool, error) {
+	conn, err := advisoryConn(ctx, gdb)
+	if err != nil {
+		return nil, false, err
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil {
+		discardConn(conn)
+		return nil, false, fmt.Errorf("failed to acquire advisory lock %d: %w", key, err)
+	}
+	if !ok {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+	return &AdvisoryLockHandle{key: key, conn: conn}, true, nil
+}
+
+// WithAdvisoryLock 持有会话级咨询锁执行 fn，fn 返回后释放锁
+func WithAdvisoryLock(ctx context.Context, gdb *gorm.DB, key int64, fn func(ctx context.Context) error) error {
+	lock, err := AdvisoryLock(ctx, gdb, key)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Unlock(ctx) }()
+	return fn(ctx)
+}
+
+// Key 返回锁的键
+func (l *AdvisoryLockHandle) Key() int64 {
+	return l.key
+}
+
+// Unlock 释放咨询锁并归还连接，可重复调用。释放失败时关闭连接，由数据库在会话结束时释放锁
+func (l *AdvisoryLockHandle) Unlock(ctx context.Context) error {
+	l.once.Do(func() {
+		// 使用独立的 context 释放锁，避免调用方 context 取消后锁残留在连接上
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), advisoryUnlockTimeout)
+		defer cancel()
+
+		var released bool
+		if err := l.conn.QueryRowContext(unlockCtx, "SELECT pg_advisory_unlock($1)", l.key).Scan(&released); err != nil {
+			log.FromContext(ctx).Warn("Failed to release advisory lock, closing connection",
+				zap.Int64("key", l.key), zap.Error(err))
+			discardConn(l.conn)
+			l.err = fmt.Errorf("failed to release advisory lock %d: %w", l.key, err)
+			return
+		}
+		_ = l.conn.Close()
+		if !released {
+			l.err = fmt.Errorf("%w: %d", ErrAdvisoryLockNotHeld, l.key)
+		}
+	})
+	return l.err
+}
+
+// AdvisoryXactLock 获取事务级咨询锁（pg_advisory_xact_lock），其他事务持有时等待，事务提交或回滚时自动释放。
+// tx 必须是事务中的连接：
+//
+//	gdb.Transaction(func(tx *gorm.DB) error {
+//		if err := db.AdvisoryXactLock(ctx, tx, db.AdvisoryKey("billing:close-day")); err != nil {
+//			return err
+//		}
+//		...
+//	})
+func AdvisoryXactLock(ctx context.Context, tx *gorm.DB, key int64) error {
+	if err := checkAdvisoryTx(tx); err != nil {
+		return err
+	}
+	if err := tx.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(?)", key).Error; err != nil {
+		return fmt.Errorf("failed to acquire advisory lock %d: %w", key, err)
+	}
+	return nil
+}
+
+// TryAdvisoryXactLock 尝试获取事务级咨询锁（pg_try_advisory_xact_lock），不等待；其他事务持有时返回 false
+func TryAdvisoryXactLock(ctx context.Context, tx *gorm.DB, key int64) (bool, error) {
+	if err := checkAdvisoryTx(tx); err != nil {
+		return false, err
+	}
+	var ok bool
+	if err := tx.WithContext(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", key).Scan(&ok).Error; err != nil {
+		return false, fmt.Errorf("failed to acquire advisory lock %d: %w", key, err)
+	}
+	return ok, nil
+}
+
+// advisoryConn 检查方言并从连接池取出专用连接
+func advisoryConn(ctx context.Context, gdb *gorm.DB) (*sql.Conn, error) {
+	if dialectOf(gdb) != "postgres" {
+		return nil, ErrAdvisoryLockUnsupported
+	}
+	sqlDB, err := SQLDB(gdb)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+	return conn, nil
+}
+
+// checkAdvisoryTx 检查方言和事务
+func checkAdvisoryTx(tx *gorm.DB) error {
+	if dialectOf(tx) != "postgres" {
+		return ErrAdvisoryLockUnsupported
+	}
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		return fmt.Errorf("transaction-scoped advisory lock must be acquired within a transaction")
+	}
+	return nil
+}
+
+// discardConn 关闭连接而不归还连接池，使其持有的会话级锁随会话结束释放
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
@@ -0,0 +1,416 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	auditSnapshotKey = "_audit_snapshot"
+
+	defaultAuditLogTable = "audit_logs"
+	defaultAuditMaxRows  = 1000
+)
+
+// 审计日志的操作类型
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditModel 需要记录变更日志的模型
+type AuditModel struct {
+	Model  any      // 模型实例，如 &User{}
+	Ignore []string // 不记录变更的列，如 updated_at、密码等敏感列
+}
+
+// AuditOptions 审计插件配置选项
+type AuditOptions struct {
+	CreatedByColumn string       // 创建者列名，默认 created_by，模型不包含该列时忽略
+	UpdatedByColumn string       // 更新者列名，默认 updated_by，模型不包含该列时忽略
+	LogTable        string       // 变更日志表名，默认 audit_logs
+	Models          []AuditModel // 需要记录变更日志的模型，为空时只填充操作者列
+	MaxRows         int          // 单条语句最多记录的行数，超过时不记录该语句的变更，默认 1000
+	// RecordRequestMeta 是否在变更日志中记录 context 中的租户和请求 ID（tenant、request_id 列），默认不记录。
+	// 开启后自动迁移会为已有的变更日志表添加这两列，大表应先在维护窗口手动执行：
+	//   ALTER TABLE audit_logs ADD COLUMN tenant VARCHAR(191), ADD COLUMN request_id VARCHAR(64);
+	//   CREATE INDEX idx_audit_logs_request_id ON audit_logs (request_id);
+	RecordRequestMeta bool
+}
+
+// AuditLog 变更日志记录，Changes 为 JSON：{"列名": {"old": 旧值, "new": 新值}}
+// Tenant 和 RequestID 仅在开启 AuditOptions.RecordRequestMeta 时写入，未开启时变更日志表不包含这两列
+type AuditLog struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement"`
+	Table      string    `gorm:"column:table_name;size:128;not null;index:idx_audit_logs_record"`
+	PrimaryKey string    `gorm:"size:191;not null;index:idx_audit_logs_record"`
+	Action     string    `gorm:"size:16;not null"`
+	Actor      string    `gorm:"size:191"`
+	Tenant     string    `gorm:"size:191"`
+	RequestID  string    `gorm:"size:64;index"`
+	Changes    string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+}
+
+// auditLogWithoutMeta 未开启 RecordRequestMeta 时用于自动迁移的变更日志表结构，不包含租户和请求 ID 列
+type auditLogWithoutMeta struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement"`
+	Table      string    `gorm:"column:table_name;size:128;not null;index:idx_audit_logs_record"`
+	PrimaryKey string    `gorm:"size:191;not null;index:idx_audit_logs_record"`
+	Action     string    `gorm:"size:16;not null"`
+	Actor      string    `gorm:"size:191"`
+	Changes    string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+}
+
+// auditChange 单列的变更
+type auditChange struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// auditSnapshot 更新或删除前的行数据，键为主键
+type auditSnapshot struct {
+	keys []any
+	rows map[string]map[string]any
+}
+
+// AuditPlugin 审计插件：从 context 中的操作者（WithActor）填充创建者和更新者列，
+// 并将已登记模型的新增、更新、删除以逐列前后值的形式写入变更日志表，开启 RecordRequestMeta 时同时记录 context 中的租户和请求 ID（见 WithRequestMeta）。
+// 变更日志与业务写操作使用同一连接，在事务中时随事务一起提交或回滚；
+// 更新和删除会额外查询变更前后的数据，只应为需要审计的模型开启变更日志
+type AuditPlugin struct {
+	opts   AuditOptions
+	models map[reflect.Type]map[string]bool // 模型类型 → 忽略的列
+}
+
+// NewAuditPlugin 创建审计插件，通过 db.Use 注册
+func NewAuditPlugin(opts *AuditOptions) (*AuditPlugin, error) {
+	p := &AuditPlugin{models: make(map[reflect.Type]map[string]bool)}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.CreatedByColumn == "" {
+		p.opts.CreatedByColumn = "created_by"
+	}
+	if p.opts.UpdatedByColumn == "" {
+		p.opts.UpdatedByColumn = "updated_by"
+	}
+	if p.opts.LogTable == "" {
+		p.opts.LogTable = defaultAuditLogTable
+	}
+	if p.opts.MaxRows <= 0 {
+		p.opts.MaxRows = defaultAuditMaxRows
+	}
+	for _, m := range p.opts.Models {
+		if m.Model == nil {
+			return nil, fmt.Errorf("audit model cannot be nil")
+		}
+		t := reflect.TypeOf(m.Model)
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		ignore := make(map[string]bool, len(m.Ignore))
+		for _, c := range m.Ignore {
+			ignore[c] = true
+		}
+		p.models[t] = ignore
+	}
+	return p, nil
+}
+
+// Name 返回审计插件的名称
+func (p *AuditPlugin) Name() string {
+	return "AuditPlugin"
+}
+
+// Initialize 注册回调，登记了模型时自动创建变更日志表
+func (p *AuditPlugin) Initialize(db *gorm.DB) error {
+	if len(p.models) > 0 {
+		var model any = &auditLogWithoutMeta{}
+		if p.opts.RecordRequestMeta {
+			model = &AuditLog{}
+		}
+		if err := db.Table(p.opts.LogTable).AutoMigrate(model); err != nil {
+			return fmt.Errorf("failed to create audit log table: %w", err)
+		}
+	}
+
+	_ = db.Callback().Create().Before("gorm:create").Register("audit:fill_actor", p.fillCreate)
+	_ = db.Callback().Update().Before("gorm:update").Register("audit:fill_actor", p.fillUpdate)
+	if len(p.models) == 0 {
+		return nil
+	}
+	_ = db.Callback().Create().After("gorm:create").Register("audit:log", p.afterCreate)
+	_ = db.Callback().Update().Before("gorm:update").Register("audit:snapshot", p.snapshot)
+	_ = db.Callback().Update().After("gorm:update").Register("audit:log", p.afterUpdate)
+	_ = db.Callback().Delete().Before("gorm:delete").Register("audit:snapshot", p.snapshot)
+	_ = db.Callback().Delete().After("gorm:delete").Register("audit:log", p.afterDelete)
+	return nil
+}
+
+// 确保 AuditPlugin 实现了 gorm.Plugin 接口
+var _ gorm.Plugin = &AuditPlugin{}
+
+// fillCreate 新增时填充创建者和更新者列
+func (p *AuditPlugin) fillCreate(db *gorm.DB) {
+	actor, ok := ActorFromDB(db)
+	if !ok || db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	for _, column := range []string{p.opts.CreatedByColumn, p.opts.UpdatedByColumn} {
+		field := db.Statement.Schema.LookUpField(column)
+		if field == nil {
+			continue
+		}
+		eachAuditRow(db, func(rv reflect.Value) {
+			if _, zero := field.ValueOf(db.Statement.Context, rv); zero {
+				_ = field.Set(db.Statement.Context, rv, actor)
+			}
+		})
+	}
+}
+
+// fillUpdate 更新时填充更新者列
+func (p *AuditPlugin) fillUpdate(db *gorm.DB) {
+	actor, ok := ActorFromDB(db)
+	if !ok || db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	if field := db.Statement.Schema.LookUpField(p.opts.UpdatedByColumn); field != nil {
+		db.Statement.SetColumn(field.DBName, actor, true)
+	}
+}
+
+// ignored 返回模型忽略的列，模型未登记时返回 false
+func (p *AuditPlugin) ignored(db *gorm.DB) (map[string]bool, bool) {
+	if db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil || db.Statement.Table == p.opts.LogTable {
+		return nil, false
+	}
+	ignore, ok := p.models[db.Statement.Schema.ModelType]
+	return ignore, ok
+}
+
+// afterCreate 记录新增的行
+func (p *AuditPlugin) afterCreate(db *gorm.DB) {
+	ignore, ok := p.ignored(db)
+	if !ok || db.Error != nil {
+		return
+	}
+	var logs []AuditLog
+	eachAuditRow(db, func(rv reflect.Value) {
+		changes := make(map[string]auditChange)
+		for column, v := range auditRow(db, rv, ignore) {
+			changes[column] = auditChange{New: v}
+		}
+		logs = append(logs, p.newLog(db, AuditActionCreate, auditPrimaryKey(db, rv), changes))
+	})
+	p.write(db, logs)
+}
+
+// snapshot 更新或删除前读取将被修改的行
+func (p *AuditPlugin) snapshot(db *gorm.DB) {
+	ignore, ok := p.ignored(db)
+	if !ok || db.Error != nil {
+		return
+	}
+
+	// 与 GORM 一致，模型带有主键值时按主键过滤
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	var keys []any
+	eachAuditRow(db, func(rv reflect.Value) {
+		if v, zero := pk.ValueOf(db.Statement.Context, rv); !zero {
+			keys = append(keys, v)
+		}
+	})
+	where, hasWhere := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	if len(keys) == 0 && (!hasWhere || len(where.Exprs) == 0) {
+		return
+	}
+
+	q := p.query(db)
+	if db.Statement.Unscoped {
+		q = q.Unscoped()
+	}
+	if hasWhere && len(where.Exprs) > 0 {
+		q = q.Where(clause.And(where.Exprs...))
+	}
+	if len(keys) > 0 {
+		q = q.Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: keys})
+	}
+	if snap, ok := p.load(db, q, ignore); ok {
+		db.InstanceSet(auditSnapshotKey, snap)
+	}
+}
+
+// afterUpdate 比较更新前后的数据，记录发生变化的列
+func (p *AuditPlugin) afterUpdate(db *gorm.DB) {
+	ignore, ok := p.ignored(db)
+	if !ok || db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+	v, ok := db.InstanceGet(auditSnapshotKey)
+	if !ok {
+		return
+	}
+	snap := v.(*auditSnapshot)
+	if len(snap.keys) == 0 {
+		return
+	}
+
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	q := p.query(db).Unscoped().Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: snap.keys})
+	after, ok := p.load(db, q, ignore)
+	if !ok {
+		return
+	}
+
+	var logs []AuditLog
+	for key, newRow := range after.rows {
+		oldRow := snap.rows[key]
+		changes := make(map[string]auditChange)
+		for column, nv := range newRow {
+			if ov := oldRow[column]; !reflect.DeepEqual(ov, nv) {
+				changes[column] = auditChange{Old: ov, New: nv}
+			}
+		}
+		if len(changes) > 0 {
+			logs = append(logs, p.newLog(db, AuditActionUpdate, key, changes))
+		}
+	}
+	p.write(db, logs)
+}
+
+// afterDelete 记录被删除的行
+func (p *AuditPlugin) afterDelete(db *gorm.DB) {
+	if _, ok := p.ignored(db); !ok || db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+	v, ok := db.InstanceGet(auditSnapshotKey)
+	if !ok {
+		return
+	}
+	var logs []AuditLog
+	for key, row := range v.(*auditSnapshot).rows {
+		changes := make(map[string]auditChange, len(row))
+		for column, v := range row {
+			changes[column] = auditChange{Old: v}
+		}
+		logs = append(logs, p.newLog(db, AuditActionDelete, key, changes))
+	}
+	p.write(db, logs)
+}
+
+// query 返回与当前语句使用同一连接（事务）的新查询
+func (p *AuditPlugin) query(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Table(db.Statement.Table)
+}
+
+// load 查询行数据；行数超过 MaxRows 或查询失败时返回 false
+func (p *AuditPlugin) load(db *gorm.DB, q *gorm.DB, ignore map[string]bool) (*auditSnapshot, bool) {
+	dest := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+	if err := q.Limit(p.opts.MaxRows + 1).Find(dest.Interface()).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("audit: load rows: %w", err))
+		return nil, false
+	}
+	items := dest.Elem()
+	if items.Len() > p.opts.MaxRows {
+		return nil, false
+	}
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	snap := &auditSnapshot{rows: make(map[string]map[string]any, items.Len())}
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		key, _ := pk.ValueOf(db.Statement.Context, item)
+		snap.keys = append(snap.keys, key)
+		snap.rows[fmt.Sprint(key)] = auditRow(db, item, ignore)
+	}
+	return snap, true
+}
+
+// newLog 创建变更日志记录
+func (p *AuditPlugin) newLog(db *gorm.DB, action, key string, changes map[string]auditChange) AuditLog {
+	meta := RequestMetaFromDB(db)
+	data, _ := json.Marshal(changes)
+	return AuditLog{
+		Table:      db.Statement.Table,
+		PrimaryKey: key,
+		Action:     action,
+		Actor:      meta.Actor,
+		Tenant:     meta.Tenant,
+		RequestID:  meta.RequestID,
+		Changes:    string(data),
+		CreatedAt:  time.Now(),
+	}
+}
+
+// write 写入变更日志，失败时使当前语句返回错误，事务中会导致整个事务回滚
+func (p *AuditPlugin) write(db *gorm.DB, logs []AuditLog) {
+	if len(logs) == 0 {
+		return
+	}
+	q := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Table(p.opts.LogTable)
+	if !p.opts.RecordRequestMeta {
+		q = q.Omit("Tenant", "RequestID")
+	}
+	if err := q.Create(&logs).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("audit: write log: %w", err))
+	}
+}
+
+// eachAuditRow 遍历语句操作的实体（单个结构体或切片）
+func eachAuditRow(db *gorm.DB, fn func(rv reflect.Value)) {
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				fn(elem)
+			}
+		}
+	case reflect.Struct:
+		fn(rv)
+	}
+}
+
+// auditRow 返回实体的列值，跳过忽略的列
+func auditRow(db *gorm.DB, rv reflect.Value, ignore map[string]bool) map[string]any {
+	row := make(map[string]any, len(db.Statement.Schema.DBNames))
+	for _, name := range db.Statement.Schema.DBNames {
+		if ignore[name] {
+			continue
+		}
+		if field := db.Statement.Schema.FieldsByDBName[name]; field != nil {
+			row[name], _ = field.ValueOf(db.Statement.Context, rv)
+		}
+	}
+	return row
+}
+
+// auditPrimaryKey 返回实体主键的字符串形式
+func auditPrimaryKey(db *gorm.DB, rv reflect.Value) string {
+	v, _ := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, rv)
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// authRefreshCooldown 两次凭据刷新的最小间隔，避免大量请求同时失败时反复刷新
+const authRefreshCooldown = 10 * time.Second
+
+// authRefreshTimeout 刷新凭据的超时
+const authRefreshTimeout = 10 * time.Second
+
+// IsAuthError 判断错误是否为服务端拒绝凭据：MySQL 1045（Access denied）、1862（密码已过期），
+// PostgreSQL 28P01（invalid_password）、28000（invalid_authorization_specification），Redis WRONGPASS、NOAUTH
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1045 || myErr.Number == 1862
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "28P01" || pgErr.Code == "28000"
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		msg := redisErr.Error()
+		return strings.HasPrefix(msg, "WRONGPASS") || strings.HasPrefix(msg, "NOAUTH")
+	}
+	return false
+}
+
+// authRotation 凭据被拒绝时刷新凭据提供者并重建连接池，使密码轮换后无需重启即可恢复
+type authRotation struct {
+	datasource string
+	provider   CredentialProvider
+	rebuild    func() // 重建连接池，nil 时只刷新凭据
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// handle 在 err 为认证错误时触发刷新，冷却期内的重复错误被忽略
+func (a *authRotation) handle(ctx context.Context, err error) {
+	if !IsAuthError(err) {
+		return
+	}
+	a.mu.Lock()
+	if time.Since(a.last) < authRefreshCooldown {
+		a.mu.Unlock()
+		return
+	}
+	a.last = time.Now()
+	a.mu.Unlock()
+
+	logger := log.FromContext(ctx)
+	logger.Warn("Credentials rejected by server, refreshing credential provider",
+		zap.String("datasource", a.datasource), zap.Error(err))
+
+	refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), authRefreshTimeout)
+	defer cancel()
+	if rerr := RefreshCredentials(refreshCtx, a.provider); rerr != nil {
+		logger.Warn("Failed to refresh credentials", zap.String("datasource", a.datasource), zap.Error(rerr))
+		recordAuthRefresh(a.datasource, "failed")
+		return
+	}
+	if a.rebuild != nil {
+		a.rebuild()
+	}
+	recordAuthRefresh(a.datasource, "succeeded")
+}
+
+// enableSQLAuthRotation 在连接上注册拦截器，语句因凭据被拒绝而失败时刷新凭据，
+// 并关闭连接池中的空闲连接，后续请求使用新凭据建立连接
+func enableSQLAuthRotation(db *gorm.DB, datasource string, provider CredentialProvider, sqlDB *sql.DB, maxIdle int) error {
+	if maxIdle <= 0 {
+		maxIdle = 2 // database/sql 未设置时的默认值
+	}
+	rotation := &authRotation{
+		datasource: datasource,
+		provider:   provider,
+		rebuild: func() {
+			sqlDB.SetMaxIdleConns(0)
+			sqlDB.SetMaxIdleConns(maxIdle)
+		},
+	}
+	return Intercept(db, func(next Handler) Handler {
+		return func(db *gorm.DB) {
+			next(db)
+			if db.Error != nil {
+				rotation.handle(db.Statement.Context, db.Error)
+			}
+		}
+	})
+}
+
+// authRotationHook 命令或建立连接因凭据被拒绝而失败时刷新凭据，新建立的连接使用新凭据
+type authRotationHook struct {
+	rotation *authRotation
+}
+
+// DialHook 实现 redis.Hook 接口
+func (h authRotationHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook 实现 redis.Hook 接口，建立连接时的 AUTH 失败也通过命令返回
+func (h authRotationHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if err != nil {
+			h.rotation.handle(ctx, err)
+		}
+		return err
+	}
+}
+
+// ProcessPipelineHook 实现 redis.Hook 接口
+func (h authRotationHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		if err != nil {
+			h.rotation.handle(ctx, err)
+		}
+		return err
+	}
+}
+
+// recordAuthRefresh 记录凭据刷新指标
+func recordAuthRefresh(datasource, result string) {
+	if metrics.IsEnabled() {
+		CredentialRefreshTotal.WithLabelValues(datasource, result).Inc()
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAutoMigrateDestructive AutoMigrate 生成了删除表、列、索引或约束的 DDL，已拒绝执行
+	ErrAutoMigrateDestructive = errors.New("automigrate refused destructive ddl")
+	// ErrModelNotAllowed 模型不在 AutoMigrator 的允许列表中
+	ErrModelNotAllowed = errors.New("model not in automigrate allowlist")
+)
+
+// destructiveDDLPattern 会删除数据或结构的 DDL；ALTER COLUMN ... DROP NOT NULL/DEFAULT 不在此列
+var destructiveDDLPattern = regexp.MustCompile(`(?i)\bDROP\s+(TABLE|VIEW|COLUMN|INDEX|CONSTRAINT|FOREIGN\s+KEY|PRIMARY\s+KEY|CHECK)\b`)
+
+// AutoMigrateOptions 托管 AutoMigrate 配置选项
+type AutoMigrateOptions struct {
+	LockName    string        // 咨询锁名称，防止多个实例同时迁移，默认 automigrate
+	LockTimeout time.Duration // 等待咨询锁的超时，默认 10m
+	DryRun      bool          // 只输出将要执行的 DDL，不实际执行
+	Output      io.Writer     // DryRun 时输出 DDL 脚本的位置，默认 os.Stdout
+}
+
+// AutoMigrateResult 单个表的迁移结果
+type AutoMigrateResult struct {
+	Table      string        // 表名
+	Statements []string      // 执行（DryRun 时为计划执行）的 DDL，表结构已是最新时为空
+	Duration   time.Duration // 迁移耗时
+	Err        error         // 迁移失败的原因
+}
+
+// autoMigrateModel 允许列表中的模型
+type autoMigrateModel struct {
+	table string
+	model any
+}
+
+// AutoMigrator 托管的 GORM AutoMigrate：只迁移显式注册的模型，持有咨询锁（MySQL GET_LOCK，
+// PostgreSQL pg_advisory_lock）逐表执行，拒绝执行删除列、索引、约束等破坏性 DDL，并记录每个表的迁移耗时。
+// 破坏性变更应通过 MigrationRunner 的版本化迁移完成
+//
+//	m, err := db.NewAutoMigrator(gdb, &db.AutoMigrateOptions{DryRun: true}, &User{}, &Order{})
+//	results, err := m.Migrate(ctx)
+type AutoMigrator struct {
+	db     *gorm.DB
+	opts   AutoMigrateOptions
+	models []autoMigrateModel
+}
+
+// NewAutoMigrator 创建托管 AutoMigrate，models 为允许迁移的模型，按注册顺序迁移
+func NewAutoMigrator(db *gorm.DB, opts *AutoMigrateOptions, models ...any) (*AutoMigrator, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("automigrate requires at least one model")
+	}
+	m := &AutoMigrator{db: db}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.LockName == "" {
+		m.opts.LockName = "automigrate"
+	}
+	if m.opts.LockTimeout <= 0 {
+		m.opts.LockTimeout = 10 * time.Minute
+	}
+	if m.opts.Output == nil {
+		m.opts.Output = os.Stdout
+	}
+
+	seen := make(map[string]struct{}, len(models))
+	for _, model := range models {
+		table, err := autoMigrateTable(db, model)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[table]; ok {
+			return nil, fmt.Errorf("duplicate automigrate model for table %s", table)
+		}
+		seen[table] = struct{}{}
+		m.models = append(m.models, autoMigrateModel{table: table, model: model})
+	}
+	return m, nil
+}
+
+// Tables 返回允许迁移的表名
+func (m *AutoMigrator) Tables() []string {
+	tables := make([]string, len(m.models))
+	for i, model := range m.models {
+		tables[i] = model.table
+	}
+	return tables
+}
+
+// Migrate 迁移指定的模型，未指定时迁移全部已注册的模型；指定了未注册的模型时返回 ErrModelNotAllowed 且不执行任何迁移。
+// 某个表迁移失败时停止，返回已处理表的结果；该表在失败前已执行的 DDL 不会回滚
+func (m *AutoMigrator) Migrate(ctx context.Context, models ...any) ([]AutoMigrateResult, error) {
+	selected, err := m.selectModels(models)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := pkgtrace.StartSpan(ctx, "automigrate.run",
+		trace.WithAttributes(
+			attribute.String("db.system", dialectOf(m.db)),
+			attribute.Int("automigrate.tables", len(selected)),
+			attribute.Bool("automigrate.dry_run", m.opts.DryRun),
+		),
+	)
+	defer span.End()
+
+	results := make([]AutoMigrateResult, 0, len(selected))
+	err = m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		release, err := acquireMigrationLock(ctx, conn, m.opts.LockName, m.opts.LockTimeout)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		for _, model := range selected {
+			res := m.migrate(ctx, conn, model)
+			results = append(results, res)
+			if res.Err != nil {
+				return fmt.Errorf("automigrate %s failed: %w", res.Table, res.Err)
+			}
+		}
+		return nil
+	})
+
+	if m.opts.DryRun {
+		if werr := writeAutoMigrateScript(m.opts.Output, results); werr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to write automigrate script: %w", werr))
+		}
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		return results, err
+	}
+	span.SetStatus(codes.Ok, "")
+	return results, nil
+}
+
+// selectModels 返回要迁移的模型，未指定时返回全部
+func (m *AutoMigrator) selectModels(models []any) ([]autoMigrateModel, error) {
+	if len(models) == 0 {
+		return m.models, nil
+	}
+	selected := make([]autoMigrateModel, 0, len(models))
+	for _, model := range models {
+		table, err := autoMigrateTable(m.db, model)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, allowed := range m.models {
+			if allowed.table == table {
+				selected = append(selected, allowed)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrModelNotAllowed, table)
+		}
+	}
+	return selected, nil
+}
+
+// migrate 在持有锁的连接上迁移单个模型，DDL 经 ddlGuardPool 检查和记录
+func (m *AutoMigrator) migrate(ctx context.Context, conn *gorm.DB, model autoMigrateModel) AutoMigrateResult {
+	pool := &ddlGuardPool{ConnPool: conn.Statement.ConnPool, dialector: conn.Dialector, dryRun: m.opts.DryRun}
+	tx := conn.WithContext(ctx)
+	tx.Statement.ConnPool = pool
+
+	start := time.Now()
+	err := tx.AutoMigrate(model.model)
+	res := AutoMigrateResult{Table: model.table, Statements: pool.statements, Duration: time.Since(start), Err: err}
+
+	status := "success"
+	switch {
+	case err != nil:
+		status = "failed"
+		log.FromContext(ctx).Error("AutoMigrate failed",
+			zap.String("table", res.Table),
+			zap.Strings("statements", res.Statements),
+			zap.Duration("duration", res.Duration),
+			zap.Error(err),
+		)
+	case m.opts.DryRun:
+		status = "dry_run"
+	case len(res.Statements) > 0:
+		log.FromContext(ctx).Info("AutoMigrate applied",
+			zap.String("table", res.Table),
+			zap.Strings("statements", res.Statements),
+			zap.Duration("duration", res.Duration),
+		)
+	}
+	if metrics.IsEnabled() {
+		AutoMigrateDuration.WithLabelValues(res.Table, status).Observe(res.Duration.Seconds())
+	}
+	return res
+}
+
+// autoMigrateTable 解析模型的表名
+func autoMigrateTable(db *gorm.DB, model any) (string, error) {
+	if model == nil {
+		return "", fmt.Errorf("automigrate model cannot be nil")
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", fmt.Errorf("failed to parse automigrate model %T: %w", model, err)
+	}
+	return stmt.Table, nil
+}
+
+// writeAutoMigrateScript 将 DryRun 的 DDL 按表写成 SQL 脚本
+func writeAutoMigrateScript(w io.Writer, results []AutoMigrateResult) error {
+	for _, res := range results {
+		if len(res.Statements) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "-- %s\n", res.Table); err != nil {
+			return err
+		}
+		for _, stmt := range res.Statements {
+			if _, err := fmt.Fprintf(w, "%s;\n", strings.TrimSuffix(strings.TrimSpace(stmt), ";")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ddlGuardPool 包装迁移连接：记录 AutoMigrate 执行的 DDL，拒绝破坏性 DDL，DryRun 时不执行 DDL。
+// 查询表结构的语句照常执行，使生成的 DDL 与数据库当前的结构一致
+type ddlGuardPool struct {
+	gorm.ConnPool
+	dialector  gorm.Dialector
+	dryRun     bool
+	statements []string
+}
+
+// ExecContext 检查并记录 DDL
+func (p *ddlGuardPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	stmt := p.dialector.Explain(query, args...)
+	if destructiveDDLPattern.MatchString(query) {
+		return nil, fmt.Errorf("%w: %s", ErrAutoMigrateDestructive, stmt)
+	}
+	p.statements = append(p.statements, stmt)
+	if p.dryRun {
+		return driver.RowsAffected(0), nil
+	}
+	return p.ConnPool.ExecContext(ctx, query, args...)
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// azureDatabaseResource Azure Database for MySQL/PostgreSQL 的令牌资源
+	azureDatabaseResource = "https://ossrdbms-aad.database.windows.net"
+	// azureIMDSEndpoint 虚拟机和 AKS 节点上的实例元数据服务令牌端点
+	azureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+// AzureADConfig 使用 Azure AD（Entra ID）托管标识认证的配置
+type AzureADConfig struct {
+	Enabled  bool   `yaml:"enabled"`   // 启用后以托管标识获取的访问令牌作为密码，忽略 password 和 password_file
+	ClientID string `yaml:"client_id"` // 用户分配托管标识的客户端 ID，为空时使用系统分配的托管标识
+	Resource string `yaml:"resource"`  // 令牌的资源，默认 https://ossrdbms-aad.database.windows.net
+}
+
+// provider 根据配置创建凭据提供者，未启用时返回 nil
+func (c *AzureADConfig) provider() CredentialProvider {
+	if !c.Enabled {
+		return nil
+	}
+	return NewAzureADCredentialProvider(&AzureADOptions{
+		ClientID: c.ClientID,
+		Resource: c.Resource,
+	})
+}
+
+// AzureADOptions Azure AD 凭据提供者配置
+type AzureADOptions struct {
+	ClientID      string        // 用户分配托管标识的客户端 ID，为空时使用系统分配的托管标识
+	Resource      string        // 令牌的资源，默认 https://ossrdbms-aad.database.windows.net
+	RefreshBefore time.Duration // 令牌过期前多久刷新，默认 5m
+	Timeout       time.Duration // 获取令牌的超时，默认 10s
+}
+
+// AzureADCredentialProvider 通过 Azure 托管标识获取访问令牌作为数据库密码
+// 在 App Service、Functions、Container Apps 中使用 IDENTITY_ENDPOINT，否则使用实例元数据服务（IMDS）。
+// 令牌缓存到过期前 RefreshBefore 再刷新；刷新失败时，在旧令牌过期前继续使用旧令牌
+type AzureADCredentialProvider struct {
+	opts   AzureADOptions
+	client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewAzureADCredentialProvider 创建 Azure AD 凭据提供者
+func NewAzureADCredentialProvider(opts *AzureADOptions) *AzureADCredentialProvider {
+	p := &AzureADCredentialProvider{}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.Resource == "" {
+		p.opts.Resource = azureDatabaseResource
+	}
+	if p.opts.RefreshBefore <= 0 {
+		p.opts.RefreshBefore = 5 * time.Minute
+	}
+	if p.opts.Timeout <= 0 {
+		p.opts.Timeout = 10 * time.Second
+	}
+	// 令牌端点是本机或平台内部地址，不经过代理
+	p.client = &http.Client{Timeout: p.opts.Timeout, Transport: &http.Transport{Proxy: nil}}
+	return p
+}
+
+// Password 返回当前访问令牌，即将过期时重新获取
+func (p *AzureADCredentialProvider) Password(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Until(p.expiresAt) > p.opts.RefreshBefore {
+		return p.token, nil
+	}
+	token, expiresAt, err := p.fetch(ctx)
+	if err != nil {
+		if p.token != "" && time.Now().Before(p.expiresAt) {
+			log.Warn("Failed to refresh Azure AD token, using cached token",
+				zap.Time("expires_at", p.expiresAt), zap.Error(err))
+			return p.token, nil
+		}
+		return "", fmt.Errorf("failed to get azure ad token: %w", err)
+	}
+	p.token, p.expiresAt = token, expiresAt
+	return token, nil
+}
+
+// Refresh 实现 CredentialRefresher 接口，丢弃缓存的令牌并重新获取
+func (p *AzureADCredentialProvider) Refresh(ctx context.Context) error {
+	token, expiresAt, err := p.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to refresh azure ad token: %w", err)
+	}
+	p.mu.Lock()
+	p.token, p.expiresAt = token, expiresAt
+	p.mu.Unlock()
+	return nil
+}
+
+// azureTokenResponse 托管标识令牌端点的响应，数值字段为字符串
+type azureTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresOn   string `json:"expires_on"`
+	ExpiresIn   string `json:"expires_in"`
+}
+
+// fetch 从托管标识令牌端点获取访问令牌
+func (p *AzureADCredentialProvider) fetch(ctx context.Context) (string, time.Time, error) {
+	query := url.Values{"resource": {p.opts.Resource}}
+	if p.opts.ClientID != "" {
+		query.Set("client_id", p.opts.ClientID)
+	}
+
+	endpoint, header, value := azureIMDSEndpoint, "Metadata", "true"
+	query.Set("api-version", "2018-02-01")
+	if e, h := os.Getenv("IDENTITY_ENDPOINT"), os.Getenv("IDENTITY_HEADER"); e != "" && h != "" {
+		endpoint, header, value = e, "X-IDENTITY-HEADER", h
+		query.Set("api-version", "2019-08-01")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set(header, value)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("token endpoint returned %s: %s", resp.Status, body)
+	}
+
+	var tr azureTokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("token response has no access_token")
+	}
+	if sec, err := strconv.ParseInt(tr.ExpiresOn, 10, 64); err == nil {
+		return tr.AccessToken, time.Unix(sec, 0), nil
+	}
+	if sec, err := strconv.ParseInt(tr.ExpiresIn, 10, 64); err == nil {
+		return tr.AccessToken, time.Now().Add(time.Duration(sec) * time.Second), nil
+	}
+	return "", time.Time{}, fmt.Errorf("token response has invalid expires_on %q", tr.ExpiresOn)
+}
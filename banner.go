@@ -0,0 +1,133 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// bannerTimeout 获取启动信息（版本、DNS 解析）的超时时间
+const bannerTimeout = 2 * time.Second
+
+// datasourceBanner 数据源启动信息，不包含任何凭据
+type datasourceBanner struct {
+	driver   string
+	instance string
+	addr     string
+	database string
+	username string
+	version  string
+	tls      bool
+	maxOpen  int
+	maxIdle  int
+	trace    bool
+}
+
+// log 输出一条结构化的数据源启动日志
+func (b datasourceBanner) log(ctx context.Context) {
+	host, port, err := net.SplitHostPort(b.addr)
+	if err != nil {
+		host = b.addr
+	}
+
+	resolved := host
+	if net.ParseIP(host) == nil {
+		if addrs, err := net.DefaultResolver.LookupHost(ctx, host); err == nil {
+			resolved = strings.Join(addrs, ",")
+		}
+	}
+
+	version := b.version
+	if version == "" {
+		version = "unknown"
+	}
+
+	log.Info("Datasource initialized",
+		zap.String("driver", b.driver),
+		zap.String("instance", b.instance),
+		zap.String("host", host),
+		zap.String("port", port),
+		zap.String("resolved_host", resolved),
+		zap.String("database", b.database),
+		zap.String("username", b.username),
+		zap.String("server_version", version),
+		zap.Int("max_open_connections", b.maxOpen),
+		zap.Int("max_idle_connections", b.maxIdle),
+		zap.Bool("tls", b.tls),
+		zap.Bool("trace", b.trace),
+		zap.Bool("metrics", metrics.IsEnabled()),
+	)
+}
+
+// logSQLBanner 查询数据库版本并输出启动日志
+func logSQLBanner(db *gorm.DB, b datasourceBanner, versionSQL string) {
+	ctx, cancel := context.WithTimeout(context.Background(), bannerTimeout)
+	defer cancel()
+
+	// 直接使用底层 sql.DB 查询，不经过追踪插件，避免启动查询计入指标
+	var version string
+	if sqlDB, err := db.DB(); err == nil {
+		_ = sqlDB.QueryRowContext(ctx, versionSQL).Scan(&version)
+	}
+	b.version = version
+	b.log(ctx)
+}
+
+// logRedisBanner 查询 Redis 版本并输出启动日志
+func logRedisBanner(client *redis.Client, opts *RedisOptions, tls bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), bannerTimeout)
+	defer cancel()
+
+	b := datasourceBanner{
+		driver:   "redis",
+		instance: opts.InstanceName,
+		addr:     opts.Addr,
+		username: opts.Username,
+		tls:      tls,
+		maxOpen:  opts.PoolSize,
+		maxIdle:  opts.MinIdleConns,
+		trace:    opts.EnableTrace,
+	}
+	if info, err := client.Info(ctx, "server").Result(); err == nil {
+		b.version = parseRedisInfoField(info, "redis_version")
+	}
+	b.log(ctx)
+}
+
+// parseRedisInfoField 从 INFO 命令输出中提取字段值
+func parseRedisInfoField(info, field string) string {
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	prefix := field + ":"
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,518 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+// BinlogOptions MySQL binlog 消费配置选项
+type BinlogOptions struct {
+	Name                string          // 消费者名称，用于日志、指标和检查点，默认 binlog
+	ServerID            uint32          // 伪装为从库时使用的 server_id，需在复制拓扑中唯一，默认随机生成
+	Flavor              string          // mysql 或 mariadb，默认 mysql
+	GTID                bool            // 使用 GTID 定位和保存检查点，需开启 gtid_mode；否则使用 binlog 文件位置
+	Tables              []string        // 只消费指定的表，格式为 schema.table，为空时消费所有表
+	Checkpoints         CheckpointStore // 保存已处理的位置，为空时每次启动从当前位置开始
+	CheckpointKey       string          // 检查点的键，默认 binlog:<name>
+	HeartbeatPeriod     time.Duration   // 主库心跳间隔，用于及时发现断开的连接，默认 30s
+	MinReconnectBackoff time.Duration   // 连接断开后首次重连的等待时间，默认 1s
+	MaxReconnectBackoff time.Duration   // 重连的最大等待时间，默认 1m
+}
+
+// BinlogListener 以从库身份消费 MySQL 行格式 binlog（binlog_format=ROW），
+// 将新增、更新、删除解码为 ChangeEvent 按提交顺序交给处理函数。
+// 列名优先使用 binlog 中的元数据（binlog_row_metadata=FULL），否则查询 information_schema 并在 DDL 后刷新。
+// 事务提交且其中所有事件处理成功后保存检查点；处理失败或连接断开时重连并从上次的检查点重新投递，处理函数需要幂等
+type BinlogListener struct {
+	mysql   *Options
+	opts    BinlogOptions
+	handler ChangeHandler
+	tables  map[string]bool
+
+	db      *sql.DB // 查询列名和当前位置
+	columns map[string][]string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// NewBinlogListener 创建 binlog 消费者，需调用 Start 开始消费
+func NewBinlogListener(opts *Options, bopts *BinlogOptions, handler ChangeHandler) (*BinlogListener, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("mysql options cannot be nil")
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("handler cannot be nil")
+	}
+
+	l := &BinlogListener{mysql: opts, handler: handler, columns: make(map[string][]string)}
+	if bopts != nil {
+		l.opts = *bopts
+	}
+	if l.opts.Name == "" {
+		l.opts.Name = "binlog"
+	}
+	if l.opts.ServerID == 0 {
+		l.opts.ServerID = 1<<20 + rand.Uint32N(1<<30)
+	}
+	switch l.opts.Flavor {
+	case "":
+		l.opts.Flavor = gomysql.MySQLFlavor
+	case gomysql.MySQLFlavor, gomysql.MariaDBFlavor:
+	default:
+		return nil, fmt.Errorf("unsupported binlog flavor %q", l.opts.Flavor)
+	}
+	if l.opts.CheckpointKey == "" {
+		l.opts.CheckpointKey = "binlog:" + l.opts.Name
+	}
+	if l.opts.HeartbeatPeriod <= 0 {
+		l.opts.HeartbeatPeriod = 30 * time.Second
+	}
+	if l.opts.MinReconnectBackoff <= 0 {
+		l.opts.MinReconnectBackoff = time.Second
+	}
+	if l.opts.MaxReconnectBackoff <= 0 {
+		l.opts.MaxReconnectBackoff = time.Minute
+	}
+	if len(l.opts.Tables) > 0 {
+		l.tables = make(map[string]bool, len(l.opts.Tables))
+		for _, t := range l.opts.Tables {
+			l.tables[t] = true
+		}
+	}
+
+	o := *opts
+	o.SessionVars = nil
+	o.IsolationReadEngines = nil
+	cfg, err := mysqldriver.ParseDSN(mySQLDSN(&o))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mysql dsn: %w", err)
+	}
+	if opts.PasswordProvider != nil {
+		err = cfg.Apply(mysqldriver.BeforeConnect(func(ctx context.Context, c *mysqldriver.Config) error {
+			password, err := opts.PasswordProvider.Password(ctx)
+			if err != nil {
+				return err
+			}
+			c.Passwd = password
+			return nil
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply mysql credential provider: %w", err)
+		}
+	}
+	connector, err := mysqldriver.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mysql connector: %w", err)
+	}
+	l.db = sql.OpenDB(connector)
+	l.db.SetMaxOpenConns(1)
+	return l, nil
+}
+
+// Start 在后台开始消费
+func (l *BinlogListener) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cancel != nil {
+		return fmt.Errorf("binlog listener already started")
+	}
+	ctx, l.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		backoff := l.opts.MinReconnectBackoff
+		for ctx.Err() == nil {
+			err := l.consume(ctx, func() { backoff = l.opts.MinReconnectBackoff })
+			if ctx.Err() != nil {
+				return
+			}
+			log.FromContext(ctx).Warn("Binlog replication interrupted, reconnecting",
+				zap.String("name", l.opts.Name), zap.Error(err), zap.Duration("backoff", backoff))
+			if metrics.IsEnabled() {
+				DatabaseCDCReconnectTotal.WithLabelValues(l.opts.Name).Inc()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, l.opts.MaxReconnectBackoff)
+		}
+	}()
+	return nil
+}
+
+// Stop 停止消费，等待处理中的事件完成并关闭连接
+func (l *BinlogListener) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	cancel := l.cancel
+	l.cancel = nil
+	l.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		done := make(chan struct{})
+		go func() {
+			l.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return l.db.Close()
+}
+
+// consume 建立复制连接并持续消费，直到出错或 ctx 取消；收到数据时调用 healthy 重置退避
+func (l *BinlogListener) consume(ctx context.Context, healthy func()) error {
+	cfg, err := l.syncerConfig(ctx)
+	if err != nil {
+		return err
+	}
+	syncer := replication.NewBinlogSyncer(cfg)
+	defer syncer.Close()
+
+	pos, gset, err := l.startPosition(ctx)
+	if err != nil {
+		return err
+	}
+	var streamer *replication.BinlogStreamer
+	if gset != nil {
+		streamer, err = syncer.StartSyncGTID(gset)
+	} else {
+		streamer, err = syncer.StartSync(pos)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start binlog sync: %w", err)
+	}
+	log.FromContext(ctx).Info("Binlog replication started",
+		zap.String("name", l.opts.Name), zap.String("position", l.position(pos, gset)))
+
+	var pending []*ChangeEvent
+	for {
+		ev, err := streamer.GetEvent(ctx)
+		if err != nil {
+			return err
+		}
+		healthy()
+
+		switch e := ev.Event.(type) {
+		case *replication.RotateEvent:
+			pos = gomysql.Position{Name: string(e.NextLogName), Pos: uint32(e.Position)}
+		case *replication.QueryEvent:
+			query := strings.ToUpper(strings.TrimSpace(string(e.Query)))
+			if query == "BEGIN" {
+				pending = pending[:0]
+			} else if isDDL(query) {
+				// 表结构变更后重新查询列名
+				l.columns = make(map[string][]string)
+			}
+		case *replication.RowsEvent:
+			events, err := l.rowsEvents(ctx, ev.Header, e, l.position(gomysql.Position{Name: pos.Name, Pos: ev.Header.LogPos}, gset))
+			if err != nil {
+				return err
+			}
+			pending = append(pending, events...)
+		case *replication.XIDEvent:
+			commitTime := time.Unix(int64(ev.Header.Timestamp), 0)
+			for _, c := range pending {
+				c.CommitTime = commitTime
+				if err := l.handle(ctx, c); err != nil {
+					recordChangeEvent(c, "failed")
+					return fmt.Errorf("handle %s on %s.%s at %s: %w", c.Action, c.Schema, c.Table, c.Position, err)
+				}
+				recordChangeEvent(c, "handled")
+			}
+			pending = pending[:0]
+
+			pos.Pos = ev.Header.LogPos
+			if gset != nil && e.GSet != nil {
+				gset = e.GSet.Clone()
+			}
+			if l.opts.Checkpoints != nil {
+				if err := l.opts.Checkpoints.Save(ctx, l.opts.CheckpointKey, l.position(pos, gset)); err != nil {
+					return fmt.Errorf("failed to save checkpoint: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// syncerConfig 返回复制连接配置
+func (l *BinlogListener) syncerConfig(ctx context.Context) (replication.BinlogSyncerConfig, error) {
+	host, portStr, err := net.SplitHostPort(l.mysql.Host)
+	if err != nil {
+		host, portStr = l.mysql.Host, "3306"
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, fmt.Errorf("invalid mysql port %q: %w", portStr, err)
+	}
+	password, err := resolveMySQLPassword(ctx, l.mysql)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, err
+	}
+	return replication.BinlogSyncerConfig{
+		ServerID:         l.opts.ServerID,
+		Flavor:           l.opts.Flavor,
+		Host:             host,
+		Port:             uint16(port),
+		User:             l.mysql.Username,
+		Password:         password,
+		Charset:          "utf8mb4",
+		ParseTime:        true,
+		HeartbeatPeriod:  l.opts.HeartbeatPeriod,
+		ReadTimeout:      2 * l.opts.HeartbeatPeriod,
+		DisableRetrySync: true, // 由 BinlogListener 负责从检查点重连
+	}, nil
+}
+
+// resolveMySQLPassword 返回连接密码，设置了凭据提供者时优先使用
+func resolveMySQLPassword(ctx context.Context, opts *Options) (string, error) {
+	if opts.PasswordProvider == nil {
+		return opts.Password, nil
+	}
+	password, err := opts.PasswordProvider.Password(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get password: %w", err)
+	}
+	return password, nil
+}
+
+// startPosition 返回开始消费的位置：优先使用检查点，否则使用主库当前位置
+func (l *BinlogListener) startPosition(ctx context.Context) (gomysql.Position, gomysql.GTIDSet, error) {
+	if l.opts.Checkpoints != nil {
+		saved, ok, err := l.opts.Checkpoints.Load(ctx, l.opts.CheckpointKey)
+		if err != nil {
+			return gomysql.Position{}, nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		if ok {
+			return l.parsePosition(saved)
+		}
+	}
+
+	if l.opts.GTID {
+		variable := "@@GLOBAL.gtid_executed"
+		if l.opts.Flavor == gomysql.MariaDBFlavor {
+			variable = "@@GLOBAL.gtid_current_pos"
+		}
+		var executed string
+		if err := l.db.QueryRowContext(ctx, "SELECT "+variable).Scan(&executed); err != nil {
+			return gomysql.Position{}, nil, fmt.Errorf("failed to query gtid position: %w", err)
+		}
+		return l.parsePosition(executed)
+	}
+
+	// MySQL 8.4 起 SHOW MASTER STATUS 更名为 SHOW BINARY LOG STATUS
+	var pos gomysql.Position
+	var err error
+	for _, query := range []string{"SHOW BINARY LOG STATUS", "SHOW MASTER STATUS"} {
+		if pos, err = l.queryPosition(ctx, query); err == nil {
+			return pos, nil, nil
+		}
+	}
+	return pos, nil, fmt.Errorf("failed to query binlog position: %w", err)
+}
+
+// queryPosition 执行 SHOW MASTER STATUS 类语句，返回当前 binlog 文件和位置
+func (l *BinlogListener) queryPosition(ctx context.Context, query string) (gomysql.Position, error) {
+	rows, err := l.db.QueryContext(ctx, query)
+	if err != nil {
+		return gomysql.Position{}, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return gomysql.Position{}, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return gomysql.Position{}, err
+		}
+		return gomysql.Position{}, fmt.Errorf("binary logging is not enabled")
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return gomysql.Position{}, err
+	}
+	if len(values) < 2 {
+		return gomysql.Position{}, fmt.Errorf("unexpected result of %s", query)
+	}
+	p, err := strconv.ParseUint(string(values[1]), 10, 32)
+	if err != nil {
+		return gomysql.Position{}, err
+	}
+	return gomysql.Position{Name: string(values[0]), Pos: uint32(p)}, nil
+}
+
+// parsePosition 解析检查点：GTID 模式为 GTID 集合，否则为 文件名:位置
+func (l *BinlogListener) parsePosition(s string) (gomysql.Position, gomysql.GTIDSet, error) {
+	if l.opts.GTID {
+		gset, err := gomysql.ParseGTIDSet(l.opts.Flavor, s)
+		if err != nil {
+			return gomysql.Position{}, nil, fmt.Errorf("invalid gtid set %q: %w", s, err)
+		}
+		return gomysql.Position{}, gset, nil
+	}
+	name, offset, ok := strings.Cut(s, ":")
+	p, err := strconv.ParseUint(offset, 10, 32)
+	if !ok || err != nil {
+		return gomysql.Position{}, nil, fmt.Errorf("invalid binlog position %q", s)
+	}
+	return gomysql.Position{Name: name, Pos: uint32(p)}, nil, nil
+}
+
+// position 格式化位置，用于检查点和事件
+func (l *BinlogListener) position(pos gomysql.Position, gset gomysql.GTIDSet) string {
+	if gset != nil {
+		return gset.String()
+	}
+	return pos.Name + ":" + strconv.FormatUint(uint64(pos.Pos), 10)
+}
+
+// rowsEvents 将行事件转换为变更事件
+func (l *BinlogListener) rowsEvents(ctx context.Context, header *replication.EventHeader, e *replication.RowsEvent, position string) ([]*ChangeEvent, error) {
+	schemaName, table := string(e.Table.Schema), string(e.Table.Table)
+	if l.tables != nil && !l.tables[schemaName+"."+table] {
+		return nil, nil
+	}
+	columns, err := l.columnNames(ctx, e.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	row := func(values []any) map[string]any {
+		m := make(map[string]any, len(values))
+		for i, v := range values {
+			if i < len(columns) {
+				m[columns[i]] = v
+			}
+		}
+		return m
+	}
+	newEvent := func(action string) *ChangeEvent {
+		return &ChangeEvent{
+			Source:     l.opts.Name,
+			Position:   position,
+			Action:     action,
+			Schema:     schemaName,
+			Table:      table,
+			CommitTime: time.Unix(int64(header.Timestamp), 0),
+		}
+	}
+
+	var events []*ChangeEvent
+	switch e.Type() {
+	case replication.EnumRowsEventTypeInsert:
+		for _, r := range e.Rows {
+			c := newEvent(ChangeInsert)
+			c.After = row(r)
+			events = append(events, c)
+		}
+	case replication.EnumRowsEventTypeUpdate:
+		// 更新事件的行按 变更前、变更后 成对出现
+		for i := 0; i+1 < len(e.Rows); i += 2 {
+			c := newEvent(ChangeUpdate)
+			c.Before, c.After = row(e.Rows[i]), row(e.Rows[i+1])
+			events = append(events, c)
+		}
+	case replication.EnumRowsEventTypeDelete:
+		for _, r := range e.Rows {
+			c := newEvent(ChangeDelete)
+			c.Before = row(r)
+			events = append(events, c)
+		}
+	}
+	return events, nil
+}
+
+// columnNames 返回表的列名，binlog 不包含列名元数据时查询 information_schema
+func (l *BinlogListener) columnNames(ctx context.Context, t *replication.TableMapEvent) ([]string, error) {
+	if names := t.ColumnNameString(); len(names) == int(t.ColumnCount) {
+		return names, nil
+	}
+	key := string(t.Schema) + "." + string(t.Table)
+	if names, ok := l.columns[key]; ok {
+		return names, nil
+	}
+
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION",
+		string(t.Schema), string(t.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns of %s: %w", key, err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	l.columns[key] = names
+	return names, nil
+}
+
+// handle 调用处理函数，捕获 panic 并转换为错误
+func (l *BinlogListener) handle(ctx context.Context, e *ChangeEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return l.handler(ctx, e)
+}
+
+// isDDL 判断语句是否可能修改表结构
+func isDDL(query string) bool {
+	for _, prefix := range []string{"ALTER ", "CREATE ", "DROP ", "RENAME ", "TRUNCATE "} {
+		if strings.HasPrefix(query, prefix) {
+			return true
+		}
+	}
+	return false
+}
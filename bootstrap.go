@@ -0,0 +1,192 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// bootstrapTimeout 创建数据库的超时时间
+const bootstrapTimeout = 30 * time.Second
+
+// postgresMaintenanceDatabase 创建数据库时连接的维护库
+const postgresMaintenanceDatabase = "postgres"
+
+// sqlOptionPattern 字符集、排序规则等 SQL 选项名称的合法格式
+var sqlOptionPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ensureMySQLDatabase 连接到服务器（不选择数据库），数据库不存在时按配置的字符集和排序规则创建
+func ensureMySQLDatabase(opts *Options) error {
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+
+	password, err := bootstrapPassword(ctx, opts.Password, opts.PasswordProvider)
+	if err != nil {
+		return err
+	}
+
+	cfg := mysqldriver.NewConfig()
+	cfg.User = opts.Username
+	cfg.Passwd = password
+	cfg.Net = mySQLNetwork(opts.Dialer)
+	cfg.Addr = opts.Host
+	if opts.CleartextPassword {
+		cfg.TLSConfig = "true"
+		cfg.AllowCleartextPasswords = true
+	}
+
+	sqlDB, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return fmt.Errorf("failed to open mysql server connection: %w", err)
+	}
+	defer sqlDB.Close()
+
+	stmt := "CREATE DATABASE IF NOT EXISTS " + quoteMySQLIdent(opts.Database)
+	if opts.Charset != "" {
+		stmt += " CHARACTER SET " + opts.Charset
+	}
+	if opts.Collation != "" {
+		stmt += " COLLATE " + opts.Collation
+	}
+	res, err := sqlDB.ExecContext(ctx, stmt)
+	if err != nil {
+		return fmt.Errorf("failed to create mysql database %s: %w", opts.Database, err)
+	}
+	// 数据库已存在时 IF NOT EXISTS 只产生警告，影响行数为 0
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		log.Info("Database created",
+			zap.String("driver", driverMySQL),
+			zap.String("database", opts.Database),
+			zap.String("charset", opts.Charset),
+			zap.String("collation", opts.Collation),
+		)
+	}
+	return nil
+}
+
+// ensurePostgreSQLDatabase 连接到维护库，数据库不存在时按配置的模板创建
+func ensurePostgreSQLDatabase(opts *PostgreSQLOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+
+	password, err := bootstrapPassword(ctx, opts.Password, opts.PasswordProvider)
+	if err != nil {
+		return err
+	}
+
+	dsn := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
+		url.QueryEscape(opts.Username),
+		url.QueryEscape(password),
+		postgreSQLHosts(opts),
+		postgresMaintenanceDatabase,
+		url.QueryEscape(opts.SSLMode),
+	)
+	if opts.DefaultQueryExecMode != "" {
+		dsn += "&default_query_exec_mode=" + url.QueryEscape(opts.DefaultQueryExecMode)
+	}
+	if opts.ConnectTimeout > 0 {
+		dsn += "&connect_timeout=" + postgreSQLConnectTimeout(opts.ConnectTimeout)
+	}
+	var sqlDB *sql.DB
+	if opts.Dialer != nil {
+		cfg, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return fmt.Errorf("failed to parse postgresql dsn: %w", err)
+		}
+		cfg.DialFunc = opts.Dialer.DialContext
+		sqlDB = stdlib.OpenDB(*cfg)
+	} else if sqlDB, err = sql.Open("pgx", dsn); err != nil {
+		return fmt.Errorf("failed to open postgresql server connection: %w", err)
+	}
+	defer sqlDB.Close()
+
+	var exists bool
+	err = sqlDB.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", opts.Database).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check postgresql database %s: %w", opts.Database, err)
+	}
+	if exists {
+		return nil
+	}
+
+	// CREATE DATABASE 不支持 IF NOT EXISTS 且不能在事务中执行
+	stmt := "CREATE DATABASE " + quotePostgreSQLIdent(opts.Database)
+	if opts.Template != "" {
+		stmt += " TEMPLATE " + quotePostgreSQLIdent(opts.Template)
+	}
+	if _, err := sqlDB.ExecContext(ctx, stmt); err != nil {
+		// 并发启动时其他实例可能已创建（42P04 duplicate_database）
+		if strings.Contains(err.Error(), "42P04") {
+			return nil
+		}
+		return fmt.Errorf("failed to create postgresql database %s: %w", opts.Database, err)
+	}
+	log.Info("Database created",
+		zap.String("driver", driverPostgreSQL),
+		zap.String("database", opts.Database),
+		zap.String("template", opts.Template),
+	)
+	return nil
+}
+
+// ensurePostgreSQLSchema 在目标数据库中创建 schema（已存在时忽略）
+func ensurePostgreSQLSchema(db *gorm.DB, schema string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Exec("CREATE SCHEMA IF NOT EXISTS " + quotePostgreSQLIdent(schema)).Error; err != nil {
+		return fmt.Errorf("failed to create postgresql schema %s: %w", schema, err)
+	}
+	return nil
+}
+
+// bootstrapPassword 返回创建数据库时使用的密码，设置了凭据提供者时优先使用
+func bootstrapPassword(ctx context.Context, password string, provider CredentialProvider) (string, error) {
+	if provider == nil {
+		return password, nil
+	}
+	p, err := provider.Password(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get password: %w", err)
+	}
+	return p, nil
+}
+
+// quoteMySQLIdent 使用反引号引用 MySQL 标识符
+func quoteMySQLIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quotePostgreSQLIdent 使用双引号引用 PostgreSQL 标识符
+func quotePostgreSQLIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,236 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// defaultBulkBatchSize 每条 INSERT 或 LOAD DATA 语句默认写入的行数
+const defaultBulkBatchSize = 1000
+
+// bulkReaderSeq 生成 LOAD DATA 读取器的唯一名称
+var bulkReaderSeq atomic.Uint64
+
+// BulkUpsertOptions 批量写入选项
+type BulkUpsertOptions struct {
+	BatchSize       int      // 每条语句写入的行数，默认 1000
+	ConflictColumns []string // PostgreSQL ON CONFLICT 的冲突列，默认为主键；MySQL 按表上的主键和唯一索引判断冲突，忽略该项
+	UpdateColumns   []string // 冲突时更新的列，默认更新除主键外的所有列
+	DoNothing       bool     // 冲突时保留已有的行
+	LoadData        bool     // MySQL 使用 LOAD DATA LOCAL INFILE 导入，冲突时整行替换（DoNothing 时忽略新行），不支持 UpdateColumns，需要服务端开启 local_infile
+}
+
+// BulkUpsert 将 rows（模型切片或切片指针）分批写入，冲突时按选项更新或忽略，返回影响的行数
+// MySQL 生成 INSERT ... ON DUPLICATE KEY UPDATE，PostgreSQL 生成 INSERT ... ON CONFLICT；
+// MySQL 中被更新的行计为 2 行影响。每批独立执行，需要原子性时在事务中调用
+func BulkUpsert(ctx context.Context, db *gorm.DB, rows any, opts *BulkUpsertOptions) (int64, error) {
+	var o BulkUpsertOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultBulkBatchSize
+	}
+	if o.DoNothing && len(o.UpdateColumns) > 0 {
+		return 0, fmt.Errorf("bulk upsert: do_nothing and update_columns are mutually exclusive")
+	}
+
+	if o.LoadData {
+		if dialectOf(db) != "mysql" {
+			return 0, fmt.Errorf("bulk upsert: load data is only supported on mysql, got %s", dialectOf(db))
+		}
+		if len(o.UpdateColumns) > 0 {
+			return 0, fmt.Errorf("bulk upsert: load data does not support update_columns")
+		}
+		return loadData(ctx, db, rows, &o)
+	}
+
+	onConflict := clause.OnConflict{DoNothing: o.DoNothing}
+	for _, c := range o.ConflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: c})
+	}
+	if len(o.UpdateColumns) > 0 {
+		onConflict.DoUpdates = clause.AssignmentColumns(o.UpdateColumns)
+	} else if !o.DoNothing {
+		onConflict.UpdateAll = true
+	}
+
+	res := db.WithContext(ctx).Clauses(onConflict).CreateInBatches(rows, o.BatchSize)
+	if res.Error != nil {
+		return res.RowsAffected, fmt.Errorf("bulk upsert: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// loadData 使用 LOAD DATA LOCAL INFILE 分批导入，数据通过驱动的读取器传输，不落地临时文件
+func loadData(ctx context.Context, db *gorm.DB, rows any, o *BulkUpsertOptions) (int64, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(rows); err != nil {
+		return 0, fmt.Errorf("bulk upsert: %w", err)
+	}
+
+	var fields []*schema.Field
+	for _, f := range stmt.Schema.Fields {
+		if f.DBName != "" && f.Creatable {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("bulk upsert: %s has no columns to load", stmt.Schema.Table)
+	}
+
+	// 语句中读取器名称之后的部分
+	var sql strings.Builder
+	if o.DoNothing {
+		sql.WriteString("' IGNORE")
+	} else {
+		sql.WriteString("' REPLACE")
+	}
+	sql.WriteString(" INTO TABLE ")
+	stmt.QuoteTo(&sql, stmt.Schema.Table)
+	sql.WriteString(` FIELDS TERMINATED BY '\t' ESCAPED BY '\\' LINES TERMINATED BY '\n' (`)
+	for i, f := range fields {
+		if i > 0 {
+			sql.WriteByte(',')
+		}
+		stmt.QuoteTo(&sql, f.DBName)
+	}
+	sql.WriteByte(')')
+
+	value := reflect.Indirect(reflect.ValueOf(rows))
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return 0, fmt.Errorf("bulk upsert: rows must be a slice, got %T", rows)
+	}
+
+	var affected int64
+	var buf bytes.Buffer
+	for start := 0; start < value.Len(); start += o.BatchSize {
+		end := min(start+o.BatchSize, value.Len())
+		buf.Reset()
+		for i := start; i < end; i++ {
+			elem := reflect.Indirect(value.Index(i))
+			for j, f := range fields {
+				if j > 0 {
+					buf.WriteByte('\t')
+				}
+				v, _ := f.ValueOf(ctx, elem)
+				if err := writeLoadDataValue(&buf, v); err != nil {
+					return affected, fmt.Errorf("bulk upsert: %s: %w", f.DBName, err)
+				}
+			}
+			buf.WriteByte('\n')
+		}
+
+		name := "bulk_" + strconv.FormatUint(bulkReaderSeq.Add(1), 10)
+		data := buf.Bytes()
+		mysqldriver.RegisterReaderHandler(name, func() io.Reader { return bytes.NewReader(data) })
+		res := db.WithContext(ctx).Exec("LOAD DATA LOCAL INFILE 'Reader::" + name + sql.String())
+		mysqldriver.DeregisterReaderHandler(name)
+
+		affected += res.RowsAffected
+		if res.Error != nil {
+			return affected, fmt.Errorf("bulk upsert: %w", res.Error)
+		}
+	}
+	return affected, nil
+}
+
+// writeLoadDataValue 按 LOAD DATA 的默认转义规则写入字段值，NULL 写为 \N
+func writeLoadDataValue(buf *bytes.Buffer, v any) error {
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return err
+		}
+	}
+
+	var s string
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString(`\N`)
+		return nil
+	case string:
+		s = val
+	case []byte:
+		if val == nil {
+			buf.WriteString(`\N`)
+			return nil
+		}
+		s = string(val)
+	case time.Time:
+		// 与 DSN 的 loc=Local 一致，按本地时区写入
+		s = val.In(time.Local).Format("2006-01-02 15:04:05.999999")
+	case *time.Time:
+		if val == nil {
+			buf.WriteString(`\N`)
+			return nil
+		}
+		s = val.In(time.Local).Format("2006-01-02 15:04:05.999999")
+	case bool:
+		s = "0"
+		if val {
+			s = "1"
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				buf.WriteString(`\N`)
+				return nil
+			}
+			return writeLoadDataValue(buf, rv.Elem().Interface())
+		}
+		if rv.Kind() == reflect.Struct || rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice {
+			return errors.New("unsupported value type " + rv.Type().String())
+		}
+		s = fmt.Sprint(v)
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case 0:
+			buf.WriteString(`\0`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return nil
+}
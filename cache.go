@@ -0,0 +1,459 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrCacheMiss 缓存中不存在指定的键
+var ErrCacheMiss = errors.New("cache: key not found")
+
+// CacheOptions 缓存配置选项
+type CacheOptions struct {
+	Fallback        bool          // 是否启用降级模式：Redis 不可用时使用本地 LRU 缓存
+	FallbackSize    int           // 本地缓存最大条目数，默认 10000
+	ReplayQueueSize int           // 降级期间最多排队等待回放的写操作数，默认 1000，超出时最早的写操作改为恢复后删除该键
+	ProbeInterval   time.Duration // 降级期间探测 Redis 是否恢复的间隔，默认 1s
+}
+
+// cacheWrite 降级期间排队的写操作
+type cacheWrite struct {
+	key      string
+	value    []byte
+	ttl      time.Duration // <= 0（不过期或 redis.KeepTTL）时原样回放
+	expireAt time.Time     // ttl > 0 时的绝对过期时间，回放时只设置剩余的有效期
+	delete   bool
+}
+
+// newCacheSet 创建排队的写入操作，按入队时间计算绝对过期时间
+func newCacheSet(key string, value []byte, ttl time.Duration) cacheWrite {
+	w := cacheWrite{key: key, value: value, ttl: ttl}
+	if ttl > 0 {
+		w.expireAt = time.Now().Add(ttl)
+	}
+	return w
+}
+
+// Cache 基于 Redis 的缓存，支持 Redis 不可用时降级到本地缓存
+type Cache struct {
+	client redis.UniversalClient
+	opts   CacheOptions
+	local  *lruCache
+
+	mu         sync.Mutex
+	degraded   bool
+	queue      []cacheWrite
+	invalidate map[string]struct{} // 队列满时丢弃的写操作涉及的键，Redis 恢复后先删除，避免保留降级前的旧值
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewCache 创建新的缓存实例
+func NewCache(client redis.UniversalClient, opts *CacheOptions) (*Cache, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+
+	c := &Cache{
+		client:  client,
+		closeCh: make(chan struct{}),
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.FallbackSize <= 0 {
+		c.opts.FallbackSize = 10000
+	}
+	if c.opts.ReplayQueueSize <= 0 {
+		c.opts.ReplayQueueSize = 1000
+	}
+	if c.opts.ProbeInterval <= 0 {
+		c.opts.ProbeInterval = time.Second
+	}
+	if c.opts.Fallback {
+		c.local = newLRUCache(c.opts.FallbackSize)
+	}
+	return c, nil
+}
+
+// Client 返回底层 Redis 客户端
+func (c *Cache) Client() redis.UniversalClient {
+	return c.client
+}
+
+// Degraded 返回缓存当前是否处于降级模式
+func (c *Cache) Degraded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.degraded
+}
+
+// Get 获取缓存值，键不存在时返回 ErrCacheMiss
+// 降级模式下从本地缓存读取（可能是过期数据）
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	if c.Degraded() {
+		return c.localGet(key)
+	}
+
+	val, err := c.client.Get(ctx, key).Bytes()
+	switch {
+	case err == nil:
+		if c.local != nil {
+			c.local.set(key, val)
+		}
+		return val, nil
+	case errors.Is(err, redis.Nil):
+		if c.local != nil {
+			c.local.delete(key)
+		}
+		return nil, ErrCacheMiss
+	case c.local != nil && isRedisUnavailable(ctx, err):
+		c.enterDegraded(err)
+		return c.localGet(key)
+	default:
+		return nil, err
+	}
+}
+
+// Set 设置缓存值，降级模式下写入本地缓存并排队等待 Redis 恢复后回放
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.localWrite(newCacheSet(key, value, ttl)) {
+		return nil
+	}
+
+	err := c.client.Set(ctx, key, value, ttl).Err()
+	switch {
+	case err == nil:
+		if c.local != nil {
+			c.local.set(key, value)
+		}
+		return nil
+	case c.local != nil && isRedisUnavailable(ctx, err):
+		c.enterDegraded(err)
+		if c.localWrite(newCacheSet(key, value, ttl)) {
+			return nil
+		}
+		return err
+	default:
+		return err
+	}
+}
+
+// Delete 删除缓存键，降级模式下删除本地缓存并排队等待 Redis 恢复后回放
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	deletes := make([]cacheWrite, len(keys))
+	for i, key := range keys {
+		deletes[i] = cacheWrite{key: key, delete: true}
+	}
+	if c.localWrite(deletes...) {
+		return nil
+	}
+
+	err := c.client.Del(ctx, keys...).Err()
+	switch {
+	case err == nil:
+		if c.local != nil {
+			for _, key := range keys {
+				c.local.delete(key)
+			}
+		}
+		return nil
+	case c.local != nil && isRedisUnavailable(ctx, err):
+		c.enterDegraded(err)
+		if c.localWrite(deletes...) {
+			return nil
+		}
+		return err
+	default:
+		return err
+	}
+}
+
+// Close 停止后台探测，不会关闭底层 Redis 客户端
+func (c *Cache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
+	return nil
+}
+
+// localGet 从本地缓存读取
+func (c *Cache) localGet(key string) ([]byte, error) {
+	if val, ok := c.local.get(key); ok {
+		recordDegradedOperation("get", "hit")
+		return val, nil
+	}
+	recordDegradedOperation("get", "miss")
+	return nil, ErrCacheMiss
+}
+
+// localWrite 降级期间写入本地缓存并加入回放队列，队列满时丢弃最早的写操作并记录其键，
+// Redis 恢复后先删除这些键，被丢弃的写入或删除不会让 Redis 保留降级前的旧值
+// 未处于降级模式时返回 false，调用方应直接写入 Redis。判断和入队持有同一把锁，
+// probe 清空队列和退出降级模式也在该锁内完成，写操作不会在回放结束后进入队列而丢失
+func (c *Cache) localWrite(writes ...cacheWrite) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.degraded {
+		return false
+	}
+
+	for _, w := range writes {
+		operation := "set"
+		if w.delete {
+			operation = "delete"
+			c.local.delete(w.key)
+		} else {
+			c.local.set(w.key, w.value)
+		}
+		recordDegradedOperation(operation, "queued")
+
+		if len(c.queue) >= c.opts.ReplayQueueSize {
+			if c.invalidate == nil {
+				c.invalidate = make(map[string]struct{})
+			}
+			c.invalidate[c.queue[0].key] = struct{}{}
+			c.queue = c.queue[1:]
+			recordReplay("dropped")
+		}
+		c.queue = append(c.queue, w)
+	}
+	return true
+}
+
+// enterDegraded 进入降级模式并启动后台探测
+func (c *Cache) enterDegraded(cause error) {
+	c.mu.Lock()
+	if c.degraded {
+		c.mu.Unlock()
+		return
+	}
+	c.degraded = true
+	c.mu.Unlock()
+
+	log.Warn("Redis unavailable, cache switched to local fallback", zap.Error(cause))
+	if metrics.IsEnabled() {
+		CacheDegraded.Set(1)
+	}
+	go c.probe()
+}
+
+// probe 周期性探测 Redis，恢复后回放排队的写操作并退出降级模式
+func (c *Cache) probe() {
+	ticker := time.NewTicker(c.opts.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.ProbeInterval)
+		err := c.client.Ping(ctx).Err()
+		if err == nil {
+			err = c.replay(ctx)
+		}
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		// 队列为空时在同一把锁内退出降级模式，之后的写操作直接写入 Redis
+		c.mu.Lock()
+		if len(c.queue) > 0 || len(c.invalidate) > 0 {
+			// 回放期间又有新的写操作进入队列，下一轮继续回放
+			c.mu.Unlock()
+			continue
+		}
+		c.degraded = false
+		c.mu.Unlock()
+
+		log.Info("Redis recovered, cache switched back from local fallback")
+		if metrics.IsEnabled() {
+			CacheDegraded.Set(0)
+		}
+		return
+	}
+}
+
+// replay 先删除需要失效的键，再按顺序回放排队的写操作，失败时将剩余操作放回队列头部
+func (c *Cache) replay(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.queue
+	invalidate := c.invalidate
+	c.queue = nil
+	c.invalidate = nil
+	c.mu.Unlock()
+
+	// 被丢弃的写操作早于队列中的所有操作，先删除不会覆盖之后的写入
+	for key := range invalidate {
+		if err := c.client.Del(ctx, key).Err(); err != nil {
+			c.mu.Lock()
+			if c.invalidate == nil {
+				c.invalidate = make(map[string]struct{}, len(invalidate))
+			}
+			for k := range invalidate {
+				c.invalidate[k] = struct{}{}
+			}
+			c.queue = append(pending, c.queue...)
+			c.mu.Unlock()
+			return err
+		}
+		delete(invalidate, key)
+		recordReplay("invalidated")
+	}
+
+	for i, w := range pending {
+		status := "replayed"
+		var err error
+		switch {
+		case w.delete:
+			err = c.client.Del(ctx, w.key).Err()
+		case w.ttl <= 0:
+			err = c.client.Set(ctx, w.key, w.value, w.ttl).Err()
+		default:
+			// 只设置剩余的有效期，降级期间已过期的写入改为删除 Redis 中更早的值
+			if remaining := time.Until(w.expireAt); remaining > 0 {
+				err = c.client.Set(ctx, w.key, w.value, remaining).Err()
+			} else {
+				err = c.client.Del(ctx, w.key).Err()
+				status = "expired"
+			}
+		}
+		if err != nil {
+			c.mu.Lock()
+			c.queue = append(pending[i:], c.queue...)
+			c.mu.Unlock()
+			return err
+		}
+		recordReplay(status)
+	}
+	return nil
+}
+
+// isRedisUnavailable 判断错误是否表示 Redis 不可达（而非业务错误），只有网络错误和连接池错误视为不可达
+// 调用方的 ctx 超时或取消不代表 Redis 故障，不触发降级：ctx 的截止时间也会设置到连接上，此时的网络超时同样排除
+func isRedisUnavailable(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || ctx.Err() != nil {
+		return false
+	}
+	// context 的超时错误实现了 net.Error，需在判断网络错误之前排除
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, redis.ErrPoolTimeout) ||
+		errors.Is(err, redis.ErrClosed)
+}
+
+// recordDegradedOperation 记录降级模式下的缓存操作指标
+func recordDegradedOperation(operation, result string) {
+	if metrics.IsEnabled() {
+		CacheDegradedOperationTotal.WithLabelValues(operation, result).Inc()
+	}
+}
+
+// recordReplay 记录写操作回放指标
+func recordReplay(status string) {
+	if metrics.IsEnabled() {
+		CacheReplayTotal.WithLabelValues(status).Inc()
+	}
+}
+
+// lruCache 并发安全的定长 LRU 缓存，用于降级模式下的本地缓存
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+// lruEntry LRU 缓存条目
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// newLRUCache 创建指定容量的 LRU 缓存
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get 读取条目并将其移动到队首
+func (l *lruCache) get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.ll.MoveToFront(el)
+		return el.Value.(*lruEntry).value, true
+	}
+	return nil, false
+}
+
+// set 写入条目，超出容量时淘汰最久未使用的条目
+func (l *lruCache) set(key string, value []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		el.Value.(*lruEntry).value = value
+		l.ll.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, value: value})
+	if l.ll.Len() > l.capacity {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// delete 删除条目
+func (l *lruCache) delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.ll.Remove(el)
+		delete(l.items, key)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// 编码后数据的首字节，标识是否压缩
+const (
+	encodingPlain byte = 0
+	encodingGzip  byte = 1
+)
+
+// Codec 缓存值的序列化方式
+type Codec interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// 内置编解码器
+var (
+	JSONCodec     Codec = jsonCodec{}
+	GobCodec      Codec = gobCodec{}
+	MsgpackCodec  Codec = msgpackCodec{}
+	ProtobufCodec Codec = protobufCodec{} // 值类型必须实现 proto.Message
+)
+
+// jsonCodec JSON 编解码
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return "json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// gobCodec gob 编解码
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// msgpackCodec msgpack 编解码
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string                       { return "msgpack" }
+func (msgpackCodec) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+// protobufCodec protobuf 编解码
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string { return "protobuf" }
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	// TypedCache[*pb.User] 解码时传入 **pb.User，需要先分配消息
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Ptr {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+		if m, ok := rv.Elem().Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, m)
+		}
+	}
+	return fmt.Errorf("protobuf codec: %T does not implement proto.Message", v)
+}
+
+// TypedCacheOptions 类型化缓存配置选项
+type TypedCacheOptions struct {
+	Codec             Codec // 序列化方式，默认 JSON
+	CompressThreshold int   // 编码后超过该字节数时使用 gzip 压缩，0 表示不压缩
+}
+
+// TypedCache 在 Cache 之上提供类型化的读写，统一序列化和压缩
+// 写入的数据带有 1 字节的编码头，不能与直接使用 Cache 读写的键混用
+type TypedCache[T any] struct {
+	cache *Cache
+	opts  TypedCacheOptions
+}
+
+// NewTypedCache 创建类型化缓存
+func NewTypedCache[T any](cache *Cache, opts *TypedCacheOptions) (*TypedCache[T], error) {
+	if cache == nil {
+		return nil, fmt.Errorf("cache cannot be nil")
+	}
+	c := &TypedCache[T]{cache: cache}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Codec == nil {
+		c.opts.Codec = JSONCodec
+	}
+	if c.opts.CompressThreshold < 0 {
+		return nil, fmt.Errorf("compress threshold must be non-negative, got %d", c.opts.CompressThreshold)
+	}
+	return c, nil
+}
+
+// Get 读取并解码缓存值，键不存在时返回 ErrCacheMiss
+func (c *TypedCache[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	data, err := c.cache.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	if err := decodeCacheValue(c.opts.Codec, data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode cache value %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// Set 编码并写入缓存值
+func (c *TypedCache[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	data, err := encodeCacheValue(c.opts.Codec, c.opts.CompressThreshold, value)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache value %s: %w", key, err)
+	}
+	return c.cache.Set(ctx, key, data, ttl)
+}
+
+// Delete 删除缓存键
+func (c *TypedCache[T]) Delete(ctx context.Context, keys ...string) error {
+	return c.cache.Delete(ctx, keys...)
+}
+
+// CacheGet 使用 JSON 编码读取类型化的缓存值
+func CacheGet[T any](ctx context.Context, cache *Cache, key string) (T, error) {
+	var value T
+	data, err := cache.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	if err := decodeCacheValue(JSONCodec, data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode cache value %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// CacheSet 使用 JSON 编码写入类型化的缓存值
+func CacheSet[T any](ctx context.Context, cache *Cache, key string, value T, ttl time.Duration) error {
+	data, err := encodeCacheValue(JSONCodec, 0, value)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache value %s: %w", key, err)
+	}
+	return cache.Set(ctx, key, data, ttl)
+}
+
+// encodeCacheValue 编码缓存值，超过阈值时压缩，首字节为编码头
+func encodeCacheValue(codec Codec, threshold int, value any) ([]byte, error) {
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if threshold <= 0 || len(data) <= threshold {
+		return append([]byte{encodingPlain}, data...), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(encodingGzip)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeCacheValue 根据编码头解压并解码缓存值
+func decodeCacheValue(codec Codec, data []byte, value any) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty cache value")
+	}
+
+	switch data[0] {
+	case encodingPlain:
+		return codec.Unmarshal(data[1:], value)
+	case encodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		raw, err := io.ReadAll(zr)
+		if err != nil {
+			return err
+		}
+		return codec.Unmarshal(raw, value)
+	default:
+		return fmt.Errorf("unknown cache value encoding %d", data[0])
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis 在 ProcessHook 中处理 GET、SET、DEL、PING 的内存 Redis，down 时返回网络错误
+type fakeRedis struct {
+	mu    sync.Mutex
+	down  bool
+	store map[string]string
+}
+
+func (f *fakeRedis) setDown(down bool) {
+	f.mu.Lock()
+	f.down = down
+	f.mu.Unlock()
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.store[key]
+	return v, ok
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.down {
+			err := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+			cmd.SetErr(err)
+			return err
+		}
+		args := cmd.Args()
+		switch c := cmd.(type) {
+		case *redis.StringCmd:
+			v, ok := f.store[redisArg(args[1])]
+			if !ok {
+				c.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			c.SetVal(v)
+		case *redis.StatusCmd:
+			if cmd.Name() == "set" {
+				f.store[redisArg(args[1])] = redisArg(args[2])
+			}
+			c.SetVal("OK")
+		case *redis.IntCmd:
+			var n int64
+			for _, k := range args[1:] {
+				if _, ok := f.store[redisArg(k)]; ok {
+					delete(f.store, redisArg(k))
+					n++
+				}
+			}
+			c.SetVal(n)
+		}
+		return nil
+	}
+}
+
+// redisArg 将命令参数转为字符串，值参数为 []byte
+func redisArg(arg any) string {
+	if b, ok := arg.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(arg)
+}
+
+func newFakeRedisCache(t *testing.T, store map[string]string, opts *CacheOptions) (*Cache, *fakeRedis) {
+	t.Helper()
+	fake := &fakeRedis{store: store}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	client.AddHook(fake)
+	t.Cleanup(func() { _ = client.Close() })
+	c, err := NewCache(client, opts)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, fake
+}
+
+// waitRecovered 等待缓存退出降级模式
+func waitRecovered(t *testing.T, c *Cache) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Degraded() {
+		if time.Now().After(deadline) {
+			t.Fatal("cache did not recover from degraded mode")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestIsRedisUnavailable(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	netErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "nil", ctx: context.Background(), err: nil, want: false},
+		{name: "redis nil", ctx: context.Background(), err: redis.Nil, want: false},
+		{name: "server error", ctx: context.Background(), err: errors.New("WRONGTYPE Operation against a key"), want: false},
+		{name: "network error", ctx: context.Background(), err: netErr, want: true},
+		{name: "eof", ctx: context.Background(), err: io.EOF, want: true},
+		{name: "pool timeout", ctx: context.Background(), err: redis.ErrPoolTimeout, want: true},
+		{name: "client closed", ctx: context.Background(), err: redis.ErrClosed, want: true},
+		{name: "deadline exceeded", ctx: context.Background(), err: context.DeadlineExceeded, want: false},
+		{name: "canceled", ctx: context.Background(), err: fmt.Errorf("get: %w", context.Canceled), want: false},
+		{name: "network error after caller canceled", ctx: canceled, err: netErr, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRedisUnavailable(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("isRedisUnavailable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheFallbackReplay(t *testing.T) {
+	ctx := context.Background()
+	c, fake := newFakeRedisCache(t, map[string]string{"stale": "old"}, &CacheOptions{Fallback: true, ProbeInterval: 10 * time.Millisecond})
+
+	if err := c.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	fake.setDown(true)
+
+	if err := c.Set(ctx, "b", []byte("2"), 0); err != nil {
+		t.Fatalf("Set while down: %v", err)
+	}
+	if !c.Degraded() {
+		t.Fatal("cache not degraded after network error")
+	}
+	if err := c.Delete(ctx, "stale"); err != nil {
+		t.Fatalf("Delete while down: %v", err)
+	}
+	if v, err := c.Get(ctx, "a"); err != nil || string(v) != "1" {
+		t.Errorf("local Get a = %q, %v, want 1", v, err)
+	}
+	if _, err := c.Get(ctx, "stale"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("local Get stale error = %v, want ErrCacheMiss", err)
+	}
+
+	fake.setDown(false)
+	waitRecovered(t, c)
+	if v, _ := fake.get("b"); v != "2" {
+		t.Errorf("replayed b = %q, want 2", v)
+	}
+	if _, ok := fake.get("stale"); ok {
+		t.Error("queued delete of stale was not replayed")
+	}
+}
+
+func TestCacheReplayQueueOverflowInvalidates(t *testing.T) {
+	ctx := context.Background()
+	store := map[string]string{"deleted": "old", "overwritten": "old"}
+	c, fake := newFakeRedisCache(t, store, &CacheOptions{Fallback: true, ReplayQueueSize: 1, ProbeInterval: 10 * time.Millisecond})
+
+	fake.setDown(true)
+	if err := c.Delete(ctx, "deleted"); err != nil {
+		t.Fatalf("Delete while down: %v", err)
+	}
+	if err := c.Set(ctx, "overwritten", []byte("new"), 0); err != nil {
+		t.Fatalf("Set while down: %v", err)
+	}
+	if err := c.Set(ctx, "kept", []byte("v"), 0); err != nil {
+		t.Fatalf("Set while down: %v", err)
+	}
+
+	fake.setDown(false)
+	waitRecovered(t, c)
+	tests := []struct {
+		key    string
+		want   string
+		exists bool
+	}{
+		{key: "deleted"},
+		{key: "overwritten"},
+		{key: "kept", want: "v", exists: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			v, ok := fake.get(tt.key)
+			if ok != tt.exists || v != tt.want {
+				t.Errorf("redis %s = %q (exists %v), want %q (exists %v)", tt.key, v, ok, tt.want, tt.exists)
+			}
+		})
+	}
+}
+
+func TestCacheCallerTimeoutDoesNotDegrade(t *testing.T) {
+	c, fake := newFakeRedisCache(t, map[string]string{}, &CacheOptions{Fallback: true})
+	fake.setDown(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// 调用方 ctx 已取消时的网络错误不代表 Redis 故障
+	if _, err := c.Get(ctx, "a"); err == nil {
+		t.Fatal("Get succeeded, want error")
+	}
+	if c.Degraded() {
+		t.Error("cache degraded after caller context was canceled")
+	}
+}
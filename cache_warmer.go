@@ -0,0 +1,324 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// CacheWarmPut 写入一个预热条目，value 为已编码的缓存值。写入按批次提交，返回错误时应停止加载
+type CacheWarmPut func(ctx context.Context, key string, value []byte) error
+
+// CacheLoader 加载器，从数据源读取匹配其键模式的数据，通过 put 逐条写入缓存
+type CacheLoader func(ctx context.Context, put CacheWarmPut) error
+
+// CacheLoaderOptions 加载器配置选项
+type CacheLoaderOptions struct {
+	TTL      time.Duration // 写入条目的过期时间，0 表示不过期
+	Timeout  time.Duration // 单次加载的超时，默认不限制
+	Schedule string        // cron 表达式，设置后通过 RegisterSchedules 定时刷新，见 ParseCron
+}
+
+// CacheWarmerOptions 缓存预热配置选项
+type CacheWarmerOptions struct {
+	Concurrency int                     // 同时执行的加载器数，默认 4
+	BatchSize   int                     // 每次管道写入的条目数，默认 100
+	OnProgress  func(CacheWarmProgress) // 每批写入后和加载结束时调用，用于上报进度，需并发安全
+}
+
+// CacheWarmProgress 加载器的进度
+type CacheWarmProgress struct {
+	Pattern  string        // 加载器注册的键模式
+	Written  int64         // 已写入的条目数
+	Failed   int64         // 写入失败的条目数
+	Duration time.Duration // 已耗时
+	Done     bool          // 加载是否已结束
+	Err      error         // 加载器返回的错误，仅 Done 时有效
+}
+
+// CacheWarmReport 一次预热的结果，每个加载器一项
+type CacheWarmReport struct {
+	Results []CacheWarmProgress
+}
+
+// Err 合并所有失败加载器的错误，全部成功时返回 nil
+func (r *CacheWarmReport) Err() error {
+	var errs []error
+	for _, res := range r.Results {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("cache warm %s: %w", res.Pattern, res.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cacheLoaderEntry 已注册的加载器
+type cacheLoaderEntry struct {
+	pattern string
+	loader  CacheLoader
+	opts    CacheLoaderOptions
+}
+
+// CacheWarmer 缓存预热：服务注册键模式和对应的加载器，在启动时或按计划执行，预先填充 Redis，
+// 避免冷启动后大量请求穿透到数据库。加载器之间并发执行且相互独立，单个加载器失败或写入失败
+// 不影响其他加载器，已写入的条目保留（部分预热）
+//
+//	warmer.Register("product:*", func(ctx context.Context, put db.CacheWarmPut) error {
+//		for p, err := range db.Iterate[Product](ctx, query) {
+//			if err != nil {
+//				return err
+//			}
+//			data, _ := json.Marshal(p)
+//			if err := put(ctx, "product:"+p.ID, data); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	}, &db.CacheLoaderOptions{TTL: time.Hour, Schedule: "*/30 * * * *"})
+type CacheWarmer struct {
+	client redis.UniversalClient
+	opts   CacheWarmerOptions
+
+	mu      sync.Mutex
+	loaders []*cacheLoaderEntry
+}
+
+// NewCacheWarmer 创建缓存预热器
+func NewCacheWarmer(client redis.UniversalClient, opts *CacheWarmerOptions) (*CacheWarmer, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	w := &CacheWarmer{client: client}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.Concurrency <= 0 {
+		w.opts.Concurrency = 4
+	}
+	if w.opts.BatchSize <= 0 {
+		w.opts.BatchSize = 100
+	}
+	return w, nil
+}
+
+// Register 注册键模式的加载器，模式仅用于标识、日志和指标，不限制加载器写入的键
+func (w *CacheWarmer) Register(pattern string, loader CacheLoader, opts *CacheLoaderOptions) error {
+	if pattern == "" {
+		return fmt.Errorf("cache warm pattern is required")
+	}
+	if loader == nil {
+		return fmt.Errorf("cache loader cannot be nil")
+	}
+	entry := &cacheLoaderEntry{pattern: pattern, loader: loader}
+	if opts != nil {
+		entry.opts = *opts
+	}
+	if entry.opts.Schedule != "" {
+		if _, err := ParseCron(entry.opts.Schedule); err != nil {
+			return fmt.Errorf("cache warm %s: %w", pattern, err)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, l := range w.loaders {
+		if l.pattern == pattern {
+			return fmt.Errorf("cache loader for %s already registered", pattern)
+		}
+	}
+	w.loaders = append(w.loaders, entry)
+	return nil
+}
+
+// Warm 执行指定模式的加载器，未指定时执行全部，等待全部结束后返回结果。
+// 返回的错误为所有失败加载器的错误，此时 report 仍包含成功加载器的结果
+func (w *CacheWarmer) Warm(ctx context.Context, patterns ...string) (*CacheWarmReport, error) {
+	loaders, err := w.lookup(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CacheWarmReport{Results: make([]CacheWarmProgress, len(loaders))}
+	sem := make(chan struct{}, w.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, l := range loaders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				report.Results[i] = w.run(ctx, l)
+			case <-ctx.Done():
+				report.Results[i] = CacheWarmProgress{Pattern: l.pattern, Done: true, Err: ctx.Err()}
+			}
+		}()
+	}
+	wg.Wait()
+	return report, report.Err()
+}
+
+// RegisterSchedules 将设置了 Schedule 的加载器注册为定时作业，作业名为 cache-warm:<pattern>，
+// 多实例部署时由 Scheduler 的 Store 保证每次计划只有一个实例执行
+func (w *CacheWarmer) RegisterSchedules(s *Scheduler) error {
+	if s == nil {
+		return fmt.Errorf("scheduler cannot be nil")
+	}
+	w.mu.Lock()
+	loaders := append([]*cacheLoaderEntry(nil), w.loaders...)
+	w.mu.Unlock()
+
+	for _, l := range loaders {
+		if l.opts.Schedule == "" {
+			continue
+		}
+		err := s.Register("cache-warm:"+l.pattern, l.opts.Schedule, func(ctx context.Context, _ time.Time) error {
+			return w.run(ctx, l).Err
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup 返回指定模式的加载器，未指定时返回全部
+func (w *CacheWarmer) lookup(patterns []string) ([]*cacheLoaderEntry, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(patterns) == 0 {
+		return append([]*cacheLoaderEntry(nil), w.loaders...), nil
+	}
+	loaders := make([]*cacheLoaderEntry, 0, len(patterns))
+	for _, pattern := range patterns {
+		var found *cacheLoaderEntry
+		for _, l := range w.loaders {
+			if l.pattern == pattern {
+				found = l
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("cache loader for %s not registered", pattern)
+		}
+		loaders = append(loaders, found)
+	}
+	return loaders, nil
+}
+
+// run 执行一个加载器，按批次写入 Redis 并上报进度
+func (w *CacheWarmer) run(ctx context.Context, l *cacheLoaderEntry) CacheWarmProgress {
+	if l.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.opts.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	progress := CacheWarmProgress{Pattern: l.pattern}
+	batch := make(map[string][]byte, w.opts.BatchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		_, err := w.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for key, value := range batch {
+				pipe.Set(ctx, key, value, l.opts.TTL)
+			}
+			return nil
+		})
+		n := int64(len(batch))
+		clear(batch)
+		if err != nil {
+			// 写入失败的批次计入 Failed，继续加载后续条目
+			progress.Failed += n
+			recordCacheWarmKeys(l.pattern, "failed", n)
+			log.FromContext(ctx).Warn("Failed to write cache warm batch",
+				zap.String("pattern", l.pattern), zap.Int64("entries", n), zap.Error(err))
+		} else {
+			progress.Written += n
+			recordCacheWarmKeys(l.pattern, "written", n)
+		}
+		progress.Duration = time.Since(start)
+		w.report(progress)
+	}
+
+	err := runCacheLoader(ctx, l.loader, func(ctx context.Context, key string, value []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch[key] = value
+		if len(batch) >= w.opts.BatchSize {
+			flush(ctx)
+		}
+		return nil
+	})
+	// 加载器失败时仍提交已读取的条目
+	flush(context.WithoutCancel(ctx))
+
+	progress.Done, progress.Err, progress.Duration = true, err, time.Since(start)
+	w.report(progress)
+
+	status := "succeeded"
+	if err != nil {
+		status = "failed"
+		log.FromContext(ctx).Error("Cache warm loader failed", zap.String("pattern", l.pattern),
+			zap.Int64("written", progress.Written), zap.Int64("failed", progress.Failed), zap.Error(err))
+	} else {
+		log.FromContext(ctx).Info("Cache warm loader finished", zap.String("pattern", l.pattern),
+			zap.Int64("written", progress.Written), zap.Int64("failed", progress.Failed), zap.Duration("duration", progress.Duration))
+	}
+	if metrics.IsEnabled() {
+		CacheWarmRunsTotal.WithLabelValues(l.pattern, status).Inc()
+		CacheWarmDuration.WithLabelValues(l.pattern).Observe(progress.Duration.Seconds())
+	}
+	return progress
+}
+
+// report 调用进度回调
+func (w *CacheWarmer) report(progress CacheWarmProgress) {
+	if w.opts.OnProgress != nil {
+		w.opts.OnProgress(progress)
+	}
+}
+
+// runCacheLoader 调用加载器，捕获 panic 并转换为错误
+func runCacheLoader(ctx context.Context, loader CacheLoader, put CacheWarmPut) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cache loader panic: %v", r)
+		}
+	}()
+	return loader(ctx, put)
+}
+
+// recordCacheWarmKeys 记录预热写入的条目数
+func recordCacheWarmKeys(pattern, result string, n int64) {
+	if metrics.IsEnabled() {
+		CacheWarmKeysTotal.WithLabelValues(pattern, result).Add(float64(n))
+	}
+}
@@ -0,0 +1,500 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"go.uber.org/zap"
+	"gorm.io/gorm/schema"
+)
+
+// 变更事件的操作类型
+const (
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// ChangeEvent 行变更事件
+type ChangeEvent struct {
+	Source     string         // 事件来源：复制槽或 binlog 消费者名称
+	Position   string         // 事件位置：PostgreSQL 为 LSN，MySQL 为 GTID 集合或 binlog 文件位置
+	Action     string         // 操作类型：insert、update、delete
+	Schema     string         // PostgreSQL schema 或 MySQL 数据库
+	Table      string         // 表名
+	Before     map[string]any // 变更前的列值（更新和删除），PostgreSQL 仅包含 REPLICA IDENTITY 列
+	After      map[string]any // 变更后的列值（新增和更新）
+	CommitTime time.Time      // 事务提交时间
+}
+
+// ChangeHandler 处理变更事件，返回错误时不推进检查点，重连后从上次的检查点重新投递
+type ChangeHandler func(ctx context.Context, e *ChangeEvent) error
+
+// changeSchemas Scan 解析的模型缓存
+var changeSchemas sync.Map
+
+// Scan 按 GORM 列名将变更后的列值（删除事件为变更前的列值）写入 dest，dest 需为模型指针
+func (e *ChangeEvent) Scan(dest any) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("scan destination must be a non-nil pointer, got %T", dest)
+	}
+	s, err := schema.Parse(dest, &changeSchemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+
+	values := e.After
+	if e.Action == ChangeDelete {
+		values = e.Before
+	}
+	ctx := context.Background()
+	for column, v := range values {
+		field := s.LookUpField(column)
+		if field == nil || v == nil {
+			continue
+		}
+		if n, ok := v.(json.Number); ok {
+			v = jsonNumberValue(n)
+		}
+		if err := field.Set(ctx, rv.Elem(), v); err != nil {
+			return fmt.Errorf("scan column %s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// jsonNumberValue 将 JSON 数字转换为 int64 或 float64，超出范围时保留字符串
+func jsonNumberValue(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
+
+// CDCOptions PostgreSQL 逻辑复制消费配置选项
+type CDCOptions struct {
+	Slot                string          // 复制槽名称，必填
+	CreateSlot          bool            // 复制槽不存在时使用 wal2json 插件创建
+	Tables              []string        // 只消费指定的表，格式为 schema.table，为空时消费所有表
+	StatusInterval      time.Duration   // 向服务端确认进度的间隔，默认 10s
+	Checkpoints         CheckpointStore // 保存已处理的 LSN，为空时只依赖复制槽的 confirmed_flush_lsn
+	CheckpointKey       string          // 检查点的键，默认 cdc:<slot>
+	MinReconnectBackoff time.Duration   // 连接断开后首次重连的等待时间，默认 1s
+	MaxReconnectBackoff time.Duration   // 重连的最大等待时间，默认 1m
+}
+
+// CDCConsumer 消费 PostgreSQL 逻辑复制槽（wal2json 插件，format-version 2），
+// 将新增、更新、删除解码为 ChangeEvent 按提交顺序交给处理函数。
+// 事务提交且其中所有事件处理成功后推进检查点并向服务端确认，之前的 WAL 才可被回收；
+// 处理失败或连接断开时重连并从上次确认的位置重新投递，处理函数需要幂等
+type CDCConsumer struct {
+	pg      *PostgreSQLOptions
+	opts    CDCOptions
+	handler ChangeHandler
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// NewCDCConsumer 创建逻辑复制消费者，需调用 Start 开始消费
+func NewCDCConsumer(pg *PostgreSQLOptions, opts *CDCOptions, handler ChangeHandler) (*CDCConsumer, error) {
+	if pg == nil {
+		return nil, fmt.Errorf("postgresql options cannot be nil")
+	}
+	if opts == nil || opts.Slot == "" {
+		return nil, fmt.Errorf("replication slot is required")
+	}
+	if !sqlOptionPattern.MatchString(opts.Slot) {
+		return nil, fmt.Errorf("invalid replication slot name %q", opts.Slot)
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("handler cannot be nil")
+	}
+
+	c := &CDCConsumer{pg: pg, opts: *opts, handler: handler}
+	if c.opts.StatusInterval <= 0 {
+		c.opts.StatusInterval = 10 * time.Second
+	}
+	if c.opts.CheckpointKey == "" {
+		c.opts.CheckpointKey = "cdc:" + c.opts.Slot
+	}
+	if c.opts.MinReconnectBackoff <= 0 {
+		c.opts.MinReconnectBackoff = time.Second
+	}
+	if c.opts.MaxReconnectBackoff <= 0 {
+		c.opts.MaxReconnectBackoff = time.Minute
+	}
+	return c, nil
+}
+
+// Start 在后台开始消费
+func (c *CDCConsumer) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		return fmt.Errorf("cdc consumer already started")
+	}
+	ctx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		backoff := c.opts.MinReconnectBackoff
+		for ctx.Err() == nil {
+			err := c.consume(ctx, func() { backoff = c.opts.MinReconnectBackoff })
+			if ctx.Err() != nil {
+				return
+			}
+			log.FromContext(ctx).Warn("CDC replication interrupted, reconnecting",
+				zap.String("slot", c.opts.Slot), zap.Error(err), zap.Duration("backoff", backoff))
+			if metrics.IsEnabled() {
+				DatabaseCDCReconnectTotal.WithLabelValues(c.opts.Slot).Inc()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, c.opts.MaxReconnectBackoff)
+		}
+	}()
+	return nil
+}
+
+// Stop 停止消费，等待处理中的事件完成
+func (c *CDCConsumer) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// consume 建立复制连接并持续消费，直到出错或 ctx 取消；收到数据时调用 healthy 重置退避
+func (c *CDCConsumer) consume(ctx context.Context, healthy func()) error {
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	if c.opts.CreateSlot {
+		err := conn.Exec(ctx, fmt.Sprintf("CREATE_REPLICATION_SLOT %s LOGICAL wal2json", c.opts.Slot)).Close()
+		var pgErr *pgconn.PgError
+		if err != nil && !(errors.As(err, &pgErr) && pgErr.Code == "42710") {
+			return fmt.Errorf("failed to create replication slot %s: %w", c.opts.Slot, err)
+		}
+	}
+
+	var start uint64
+	if c.opts.Checkpoints != nil {
+		saved, ok, err := c.opts.Checkpoints.Load(ctx, c.opts.CheckpointKey)
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		if ok {
+			if start, err = parseLSN(saved); err != nil {
+				return fmt.Errorf("invalid checkpoint %q: %w", saved, err)
+			}
+		}
+	}
+	if err := c.startReplication(ctx, conn, start); err != nil {
+		return err
+	}
+
+	confirmed := start
+	nextStatus := time.Now().Add(c.opts.StatusInterval)
+	var pending []*ChangeEvent
+	var commitTime time.Time
+	for {
+		if time.Now().After(nextStatus) {
+			if err := sendStandbyStatus(conn, confirmed); err != nil {
+				return err
+			}
+			nextStatus = time.Now().Add(c.opts.StatusInterval)
+		}
+
+		recvCtx, cancel := context.WithDeadline(ctx, nextStatus)
+		msg, err := conn.ReceiveMessage(recvCtx)
+		cancel()
+		if err != nil {
+			if pgconn.Timeout(err) && ctx.Err() == nil {
+				continue
+			}
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(m)
+		case *pgproto3.CopyData:
+			if len(m.Data) == 0 {
+				continue
+			}
+			switch m.Data[0] {
+			case 'k': // 主库心跳：WAL 结束位置(8) + 时间(8) + 是否需要回复(1)
+				if len(m.Data) >= 18 && m.Data[17] == 1 {
+					if err := sendStandbyStatus(conn, confirmed); err != nil {
+						return err
+					}
+					nextStatus = time.Now().Add(c.opts.StatusInterval)
+				}
+			case 'w': // WAL 数据：起始位置(8) + WAL 结束位置(8) + 时间(8) + 数据
+				if len(m.Data) < 25 {
+					return fmt.Errorf("malformed XLogData message")
+				}
+				healthy()
+				walStart := binary.BigEndian.Uint64(m.Data[1:9])
+				lsn := walStart + uint64(len(m.Data)-25)
+				action, event, ts, err := c.decode(m.Data[25:], walStart)
+				if err != nil {
+					return err
+				}
+				switch action {
+				case "B":
+					pending, commitTime = pending[:0], ts
+				case "C":
+					if err := c.dispatch(ctx, pending, commitTime); err != nil {
+						return err
+					}
+					pending = pending[:0]
+					confirmed = max(confirmed, lsn)
+					if c.opts.Checkpoints != nil {
+						if err := c.opts.Checkpoints.Save(ctx, c.opts.CheckpointKey, formatLSN(confirmed)); err != nil {
+							return fmt.Errorf("failed to save checkpoint: %w", err)
+						}
+					}
+				default:
+					if event != nil {
+						pending = append(pending, event)
+					}
+				}
+			}
+		}
+	}
+}
+
+// connect 建立复制连接
+func (c *CDCConsumer) connect(ctx context.Context) (*pgconn.PgConn, error) {
+	cfg, err := pgconn.ParseConfig(postgreSQLDSN(c.pg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgresql dsn: %w", err)
+	}
+	cfg.RuntimeParams["replication"] = "database"
+	if c.pg.PasswordProvider != nil {
+		password, err := c.pg.PasswordProvider.Password(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Password = password
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	return pgconn.ConnectConfig(connectCtx, cfg)
+}
+
+// startReplication 发送 START_REPLICATION 并等待服务端进入 CopyBoth 模式
+func (c *CDCConsumer) startReplication(ctx context.Context, conn *pgconn.PgConn, start uint64) error {
+	options := []string{`"format-version" '2'`, `"include-timestamp" 'true'`}
+	if len(c.opts.Tables) > 0 {
+		tables := strings.ReplaceAll(strings.Join(c.opts.Tables, ","), "'", "''")
+		options = append(options, fmt.Sprintf(`"add-tables" '%s'`, tables))
+	}
+	sql := fmt.Sprintf("START_REPLICATION SLOT %s LOGICAL %s (%s)", c.opts.Slot, formatLSN(start), strings.Join(options, ", "))
+
+	conn.Frontend().Send(&pgproto3.Query{String: sql})
+	if err := conn.Frontend().Flush(); err != nil {
+		return fmt.Errorf("failed to start replication: %w", err)
+	}
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start replication: %w", err)
+		}
+		switch m := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			log.FromContext(ctx).Info("CDC replication started",
+				zap.String("slot", c.opts.Slot), zap.String("lsn", formatLSN(start)))
+			return nil
+		case *pgproto3.ErrorResponse:
+			return fmt.Errorf("failed to start replication: %w", pgconn.ErrorResponseToPgError(m))
+		}
+	}
+}
+
+// wal2jsonColumn wal2json 输出的列
+type wal2jsonColumn struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// wal2jsonMessage wal2json format-version 2 输出的单条消息
+type wal2jsonMessage struct {
+	Action    string           `json:"action"`
+	Timestamp string           `json:"timestamp"`
+	Schema    string           `json:"schema"`
+	Table     string           `json:"table"`
+	Columns   []wal2jsonColumn `json:"columns"`
+	Identity  []wal2jsonColumn `json:"identity"`
+}
+
+// decode 解码 wal2json 消息，返回消息类型；新增、更新、删除时返回事件，事务开始时返回提交时间
+func (c *CDCConsumer) decode(data []byte, walStart uint64) (string, *ChangeEvent, time.Time, error) {
+	var msg wal2jsonMessage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&msg); err != nil {
+		return "", nil, time.Time{}, fmt.Errorf("failed to decode wal2json message: %w", err)
+	}
+
+	var ts time.Time
+	if msg.Timestamp != "" {
+		ts, _ = time.Parse("2006-01-02 15:04:05.999999-07", msg.Timestamp)
+	}
+	e := &ChangeEvent{Source: c.opts.Slot, Position: formatLSN(walStart), Schema: msg.Schema, Table: msg.Table}
+	switch msg.Action {
+	case "I":
+		e.Action, e.After = ChangeInsert, wal2jsonValues(msg.Columns)
+	case "U":
+		e.Action, e.Before, e.After = ChangeUpdate, wal2jsonValues(msg.Identity), wal2jsonValues(msg.Columns)
+	case "D":
+		e.Action, e.Before = ChangeDelete, wal2jsonValues(msg.Identity)
+	default:
+		// 事务边界、TRUNCATE 和逻辑消息不产生行事件
+		return msg.Action, nil, ts, nil
+	}
+	return msg.Action, e, ts, nil
+}
+
+// wal2jsonValues 将列数组转换为列名到值的映射
+func wal2jsonValues(columns []wal2jsonColumn) map[string]any {
+	if columns == nil {
+		return nil
+	}
+	values := make(map[string]any, len(columns))
+	for _, col := range columns {
+		values[col.Name] = col.Value
+	}
+	return values
+}
+
+// dispatch 按顺序处理事务中的事件
+func (c *CDCConsumer) dispatch(ctx context.Context, events []*ChangeEvent, commitTime time.Time) error {
+	for _, e := range events {
+		e.CommitTime = commitTime
+		if err := c.handle(ctx, e); err != nil {
+			recordChangeEvent(e, "failed")
+			return fmt.Errorf("handle %s on %s.%s at %s: %w", e.Action, e.Schema, e.Table, e.Position, err)
+		}
+		recordChangeEvent(e, "handled")
+	}
+	return nil
+}
+
+// handle 调用处理函数，捕获 panic 并转换为错误
+func (c *CDCConsumer) handle(ctx context.Context, e *ChangeEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return c.handler(ctx, e)
+}
+
+// recordChangeEvent 记录变更事件处理指标
+func recordChangeEvent(e *ChangeEvent, status string) {
+	if !metrics.IsEnabled() {
+		return
+	}
+	DatabaseCDCEventsTotal.WithLabelValues(e.Source, e.Schema+"."+e.Table, e.Action, status).Inc()
+	if status == "handled" && !e.CommitTime.IsZero() {
+		DatabaseCDCLag.WithLabelValues(e.Source).Set(time.Since(e.CommitTime).Seconds())
+	}
+}
+
+// postgresEpoch 复制协议中时间戳的起点
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sendStandbyStatus 向服务端确认已处理到 lsn，服务端据此推进复制槽并回收 WAL
+func sendStandbyStatus(conn *pgconn.PgConn, lsn uint64) error {
+	data := make([]byte, 0, 34)
+	data = append(data, 'r')
+	data = binary.BigEndian.AppendUint64(data, lsn) // 已写入
+	data = binary.BigEndian.AppendUint64(data, lsn) // 已刷盘
+	data = binary.BigEndian.AppendUint64(data, lsn) // 已应用
+	data = binary.BigEndian.AppendUint64(data, uint64(time.Since(postgresEpoch).Microseconds()))
+	data = append(data, 0)
+
+	conn.Frontend().Send(&pgproto3.CopyData{Data: data})
+	if err := conn.Frontend().Flush(); err != nil {
+		return fmt.Errorf("failed to send standby status: %w", err)
+	}
+	return nil
+}
+
+// parseLSN 解析 X/X 格式的 LSN
+func parseLSN(s string) (uint64, error) {
+	var hi, lo uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &hi, &lo); err != nil {
+		return 0, err
+	}
+	return uint64(hi)<<32 | uint64(lo), nil
+}
+
+// formatLSN 将 LSN 格式化为 X/X
+func formatLSN(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", uint32(lsn>>32), uint32(lsn))
+}
@@ -0,0 +1,399 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrCircuitOpen 熔断器处于打开状态，请求未发送到数据库
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+const (
+	circuitBreakerCallName = "core:circuit_breaker"
+	circuitBreakerBuckets  = 10
+)
+
+// CircuitState 熔断器状态
+type CircuitState int
+
+// 熔断器状态，数值用于 db_circuit_breaker_state 指标
+const (
+	CircuitClosed   CircuitState = iota // 正常放行
+	CircuitHalfOpen                     // 放行少量探测请求，全部成功后关闭
+	CircuitOpen                         // 拒绝所有请求
+)
+
+// String 返回状态名称
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half_open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerOptions 熔断器配置选项
+type CircuitBreakerOptions struct {
+	Window         time.Duration // 统计失败率的滚动窗口，默认 10s
+	MinRequests    int           // 窗口内请求数达到该值后才判断失败率，默认 20
+	FailureRatio   float64       // 窗口内失败率达到该值时打开，默认 0.5
+	SlowThreshold  time.Duration // 耗时超过该值的请求计为失败，0 表示不按耗时判断
+	OpenTimeout    time.Duration // 打开后经过该时间进入半开状态，默认 30s
+	HalfOpenProbes int           // 半开状态放行的探测请求数，全部成功后关闭，任一失败重新打开，默认 3
+}
+
+// circuitBucket 滚动窗口中单个桶的统计
+type circuitBucket struct {
+	start    int64 // 桶起始时间（UnixNano，按桶宽度对齐）
+	total    int
+	failures int
+}
+
+// CircuitBreaker 按滚动窗口失败率熔断的熔断器，同一连接的所有操作共享。
+// 连接错误、超时、数据库资源不足等计为失败；记录不存在、约束冲突、调用方取消等业务错误不计入
+type CircuitBreaker struct {
+	name string
+	opts CircuitBreakerOptions
+
+	mu         sync.Mutex
+	state      CircuitState
+	generation uint64 // 每次状态变化递增，忽略旧状态下放行的请求结果
+	openedAt   time.Time
+	probes     int // 半开状态已放行的探测请求数
+	succeeded  int // 半开状态成功的探测请求数
+	buckets    [circuitBreakerBuckets]circuitBucket
+}
+
+// NewCircuitBreaker 创建熔断器，name 用于日志和指标
+func NewCircuitBreaker(name string, opts *CircuitBreakerOptions) *CircuitBreaker {
+	b := &CircuitBreaker{name: name}
+	if opts != nil {
+		b.opts = *opts
+	}
+	if b.opts.Window <= 0 {
+		b.opts.Window = 10 * time.Second
+	}
+	if b.opts.MinRequests <= 0 {
+		b.opts.MinRequests = 20
+	}
+	if b.opts.FailureRatio <= 0 || b.opts.FailureRatio > 1 {
+		b.opts.FailureRatio = 0.5
+	}
+	if b.opts.OpenTimeout <= 0 {
+		b.opts.OpenTimeout = 30 * time.Second
+	}
+	if b.opts.HalfOpenProbes <= 0 {
+		b.opts.HalfOpenProbes = 3
+	}
+	if metrics.IsEnabled() {
+		DatabaseCircuitBreakerState.WithLabelValues(name).Set(float64(CircuitClosed))
+	}
+	return b
+}
+
+// State 返回当前状态
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maybeHalfOpen(time.Now())
+	return b.state
+}
+
+// Allow 判断是否放行请求，放行时返回的 done 必须在请求完成后调用；拒绝时返回 ErrCircuitOpen
+func (b *CircuitBreaker) Allow() (done func(err error, duration time.Duration), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.maybeHalfOpen(time.Now())
+	switch b.state {
+	case CircuitOpen:
+		b.reject()
+		return nil, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probes >= b.opts.HalfOpenProbes {
+			b.reject()
+			return nil, ErrCircuitOpen
+		}
+		b.probes++
+	}
+	generation := b.generation
+	return func(err error, duration time.Duration) {
+		b.record(generation, circuitFailure(err) || (b.opts.SlowThreshold > 0 && duration > b.opts.SlowThreshold))
+	}, nil
+}
+
+// reject 记录被拒绝的请求（调用方需持有锁）
+func (b *CircuitBreaker) reject() {
+	if metrics.IsEnabled() {
+		DatabaseCircuitBreakerRejectedTotal.WithLabelValues(b.name).Inc()
+	}
+}
+
+// record 记录请求结果并更新状态
+func (b *CircuitBreaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+
+	now := time.Now()
+	switch b.state {
+	case CircuitHalfOpen:
+		if failed {
+			b.transition(CircuitOpen, now)
+			return
+		}
+		b.succeeded++
+		if b.succeeded >= b.opts.HalfOpenProbes {
+			b.transition(CircuitClosed, now)
+		}
+	case CircuitClosed:
+		width := int64(b.opts.Window / circuitBreakerBuckets)
+		start := now.UnixNano() - now.UnixNano()%width
+		bucket := &b.buckets[int(start/width)%circuitBreakerBuckets]
+		if bucket.start != start {
+			*bucket = circuitBucket{start: start}
+		}
+		bucket.total++
+		if failed {
+			bucket.failures++
+		}
+
+		var total, failures int
+		oldest := now.UnixNano() - int64(b.opts.Window)
+		for _, bk := range b.buckets {
+			if bk.start > oldest {
+				total += bk.total
+				failures += bk.failures
+			}
+		}
+		if total >= b.opts.MinRequests && float64(failures)/float64(total) >= b.opts.FailureRatio {
+			b.transition(CircuitOpen, now)
+		}
+	}
+}
+
+// maybeHalfOpen 打开超过 OpenTimeout 后进入半开状态（调用方需持有锁）
+func (b *CircuitBreaker) maybeHalfOpen(now time.Time) {
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.opts.OpenTimeout {
+		b.transition(CircuitHalfOpen, now)
+	}
+}
+
+// transition 切换状态并重置统计（调用方需持有锁）
+func (b *CircuitBreaker) transition(to CircuitState, now time.Time) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.probes, b.succeeded = 0, 0
+	b.buckets = [circuitBreakerBuckets]circuitBucket{}
+	if to == CircuitOpen {
+		b.openedAt = now
+	}
+
+	if to == CircuitOpen {
+		log.Warn("Circuit breaker opened", zap.String("name", b.name), zap.Duration("open_timeout", b.opts.OpenTimeout))
+	} else {
+		log.Info("Circuit breaker state changed", zap.String("name", b.name),
+			zap.String("from", from.String()), zap.String("to", to.String()))
+	}
+	if metrics.IsEnabled() {
+		DatabaseCircuitBreakerState.WithLabelValues(b.name).Set(float64(to))
+		DatabaseCircuitBreakerTransitionsTotal.WithLabelValues(b.name, from.String(), to.String()).Inc()
+	}
+}
+
+// circuitIgnoredErrors 由调用方或数据引起、不计入失败的错误
+var circuitIgnoredErrors = []error{
+	ErrCircuitOpen,
+	ErrResultTooLarge,
+	gorm.ErrRecordNotFound,
+	gorm.ErrMissingWhereClause,
+	gorm.ErrPrimaryKeyRequired,
+	gorm.ErrModelValueRequired,
+	gorm.ErrInvalidData,
+	gorm.ErrInvalidField,
+	gorm.ErrInvalidValue,
+	gorm.ErrInvalidValueOfLength,
+	gorm.ErrUnsupportedRelation,
+	gorm.ErrDuplicatedKey,
+	gorm.ErrForeignKeyViolated,
+}
+
+// circuitFailure 判断错误是否说明数据库不可用或过载
+func circuitFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, redis.Nil) {
+		return false
+	}
+	for _, target := range circuitIgnoredErrors {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1040, 1053, 1205, 3024: // 连接数过多、服务关闭、锁等待超时、执行超时
+			return true
+		}
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08 连接异常、53 资源不足、57 管理员干预或语句超时、58 系统错误
+		if len(pgErr.Code) < 2 {
+			return false
+		}
+		switch pgErr.Code[:2] {
+		case "08", "53", "57", "58":
+			return true
+		}
+		return false
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		for _, prefix := range []string{"LOADING", "BUSY", "CLUSTERDOWN", "MASTERDOWN", "TRYAGAIN"} {
+			if strings.HasPrefix(redisErr.Error(), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	// 其余为网络错误、连接失效、超时等
+	return true
+}
+
+// circuitBreakerPlugin 在 GORM 语句执行前检查熔断器，执行后记录结果
+type circuitBreakerPlugin struct {
+	breaker *CircuitBreaker
+}
+
+// circuitCall 单条语句的熔断器调用
+type circuitCall struct {
+	done  func(err error, duration time.Duration)
+	start time.Time
+}
+
+// Name 返回插件名称
+func (p *circuitBreakerPlugin) Name() string {
+	return "CircuitBreakerPlugin"
+}
+
+// Initialize 注册回调
+func (p *circuitBreakerPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	_ = cb.Create().Before("gorm:create").Register(circuitBreakerCallName+":before", p.before)
+	_ = cb.Query().Before("gorm:query").Register(circuitBreakerCallName+":before", p.before)
+	_ = cb.Update().Before("gorm:update").Register(circuitBreakerCallName+":before", p.before)
+	_ = cb.Delete().Before("gorm:delete").Register(circuitBreakerCallName+":before", p.before)
+	_ = cb.Row().Before("gorm:row").Register(circuitBreakerCallName+":before", p.before)
+	_ = cb.Raw().Before("gorm:raw").Register(circuitBreakerCallName+":before", p.before)
+
+	_ = cb.Create().After("gorm:create").Register(circuitBreakerCallName+":after", p.after)
+	_ = cb.Query().After("gorm:query").Register(circuitBreakerCallName+":after", p.after)
+	_ = cb.Update().After("gorm:update").Register(circuitBreakerCallName+":after", p.after)
+	_ = cb.Delete().After("gorm:delete").Register(circuitBreakerCallName+":after", p.after)
+	_ = cb.Row().After("gorm:row").Register(circuitBreakerCallName+":after", p.after)
+	_ = cb.Raw().After("gorm:raw").Register(circuitBreakerCallName+":after", p.after)
+	return nil
+}
+
+// before 熔断器打开时直接返回 ErrCircuitOpen
+func (p *circuitBreakerPlugin) before(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	done, err := p.breaker.Allow()
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	db.Statement.Settings.Store(circuitBreakerCallName, &circuitCall{done: done, start: time.Now()})
+}
+
+// after 记录语句结果
+func (p *circuitBreakerPlugin) after(db *gorm.DB) {
+	v, ok := db.Statement.Settings.LoadAndDelete(circuitBreakerCallName)
+	if !ok {
+		return
+	}
+	call := v.(*circuitCall)
+	call.done(db.Error, time.Since(call.start))
+}
+
+// circuitBreakerHook 在 Redis 命令执行前检查熔断器，执行后记录结果
+type circuitBreakerHook struct {
+	breaker *CircuitBreaker
+}
+
+// DialHook 在建立连接时调用
+func (h circuitBreakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook 在处理命令时调用
+func (h circuitBreakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		done, err := h.breaker.Allow()
+		if err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		start := time.Now()
+		err = next(ctx, cmd)
+		done(err, time.Since(start))
+		return err
+	}
+}
+
+// ProcessPipelineHook 在处理管道命令时调用，整个管道计为一次请求
+func (h circuitBreakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		done, err := h.breaker.Allow()
+		if err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		start := time.Now()
+		err = next(ctx, cmds)
+		done(err, time.Since(start))
+		return err
+	}
+}
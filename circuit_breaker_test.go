@@ -0,0 +1,147 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+func TestCircuitFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "record not found", err: gorm.ErrRecordNotFound, want: false},
+		{name: "duplicated key", err: fmt.Errorf("create: %w", gorm.ErrDuplicatedKey), want: false},
+		{name: "circuit open", err: ErrCircuitOpen, want: false},
+		{name: "mysql too many connections", err: &mysqldriver.MySQLError{Number: 1040}, want: true},
+		{name: "mysql duplicate entry", err: &mysqldriver.MySQLError{Number: 1062}, want: false},
+		{name: "postgresql connection failure", err: &pgconn.PgError{Code: "08006"}, want: true},
+		{name: "postgresql unique violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "network error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := circuitFailure(tt.err); got != tt.want {
+				t.Errorf("circuitFailure = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// expireOpen 让打开状态的熔断器立即到达 OpenTimeout
+func expireOpen(b *CircuitBreaker) {
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-b.opts.OpenTimeout)
+	b.mu.Unlock()
+}
+
+// callBreaker 通过熔断器执行一次结果为 err 的请求，返回 Allow 的错误
+func callBreaker(b *CircuitBreaker, err error) error {
+	done, allowErr := b.Allow()
+	if allowErr != nil {
+		return allowErr
+	}
+	done(err, time.Millisecond)
+	return nil
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	errDown := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	tests := []struct {
+		name       string
+		probeErr   error
+		wantState  CircuitState
+		wantReject bool
+	}{
+		{name: "probes succeed", probeErr: nil, wantState: CircuitClosed},
+		{name: "probe fails", probeErr: errDown, wantState: CircuitOpen, wantReject: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker("test", &CircuitBreakerOptions{MinRequests: 4, FailureRatio: 0.5, HalfOpenProbes: 2})
+
+			for _, err := range []error{nil, gorm.ErrRecordNotFound, errDown} {
+				if allowErr := callBreaker(b, err); allowErr != nil {
+					t.Fatalf("Allow before threshold: %v", allowErr)
+				}
+			}
+			if got := b.State(); got != CircuitClosed {
+				t.Fatalf("state below MinRequests = %v, want closed", got)
+			}
+			if err := callBreaker(b, errDown); err != nil {
+				t.Fatalf("Allow: %v", err)
+			}
+			if got := b.State(); got != CircuitOpen {
+				t.Fatalf("state at failure ratio = %v, want open", got)
+			}
+			if err := callBreaker(b, nil); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("Allow while open = %v, want ErrCircuitOpen", err)
+			}
+
+			expireOpen(b)
+			if got := b.State(); got != CircuitHalfOpen {
+				t.Fatalf("state after open timeout = %v, want half_open", got)
+			}
+			first, err := b.Allow()
+			if err != nil {
+				t.Fatalf("first probe: %v", err)
+			}
+			second, err := b.Allow()
+			if err != nil {
+				t.Fatalf("second probe: %v", err)
+			}
+			if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("probe beyond HalfOpenProbes = %v, want ErrCircuitOpen", err)
+			}
+			first(nil, time.Millisecond)
+			second(tt.probeErr, time.Millisecond)
+
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("state after probes = %v, want %v", got, tt.wantState)
+			}
+			if err := callBreaker(b, nil); errors.Is(err, ErrCircuitOpen) != tt.wantReject {
+				t.Errorf("Allow after probes = %v, wantReject %v", err, tt.wantReject)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerSlowThreshold(t *testing.T) {
+	b := NewCircuitBreaker("test", &CircuitBreakerOptions{MinRequests: 1, SlowThreshold: 100 * time.Millisecond})
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	done(nil, time.Second)
+	if got := b.State(); got != CircuitOpen {
+		t.Errorf("state after slow request = %v, want open", got)
+	}
+}
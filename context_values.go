@@ -0,0 +1,197 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// RequestIDHeader RequestMetaMiddleware 默认读取的请求 ID 请求头
+const RequestIDHeader = "X-Request-ID"
+
+// ContextKey 类型化的 context 键，用于在请求链路和 GORM 模型钩子之间传递值
+// 不同 ContextKey 实例互不冲突，即使名称相同
+type ContextKey[T any] struct {
+	name *string
+}
+
+// NewContextKey 创建类型化的 context 键，name 仅用于调试输出
+func NewContextKey[T any](name string) ContextKey[T] {
+	return ContextKey[T]{name: &name}
+}
+
+// String 返回键名称
+func (k ContextKey[T]) String() string {
+	if k.name == nil {
+		return ""
+	}
+	return *k.name
+}
+
+// WithValue 返回携带该键值的新 context
+func (k ContextKey[T]) WithValue(ctx context.Context, value T) context.Context {
+	return context.WithValue(ctx, k, value)
+}
+
+// From 从 context 中读取值
+func (k ContextKey[T]) From(ctx context.Context) (T, bool) {
+	var zero T
+	if ctx == nil {
+		return zero, false
+	}
+	v, ok := ctx.Value(k).(T)
+	if !ok {
+		return zero, false
+	}
+	return v, true
+}
+
+// FromDB 从 GORM 的 Statement.Context 中读取值，可在 BeforeSave、AfterFind 等模型钩子中使用
+func (k ContextKey[T]) FromDB(db *gorm.DB) (T, bool) {
+	var zero T
+	if db == nil || db.Statement == nil {
+		return zero, false
+	}
+	return k.From(db.Statement.Context)
+}
+
+// MustFromDB 从 GORM 的 Statement.Context 中读取值，不存在时返回类型零值
+func (k ContextKey[T]) MustFromDB(db *gorm.DB) T {
+	v, _ := k.FromDB(db)
+	return v
+}
+
+// 预定义的请求级上下文键
+var (
+	// ActorKey 当前操作者（用户 ID 或服务名）
+	ActorKey = NewContextKey[string]("actor")
+	// TenantKey 当前租户
+	TenantKey = NewContextKey[string]("tenant")
+	// LocaleKey 当前语言区域
+	LocaleKey = NewContextKey[string]("locale")
+	// RequestIDKey 当前请求 ID
+	RequestIDKey = NewContextKey[string]("request_id")
+)
+
+// WithActor 返回携带操作者的 context
+func WithActor(ctx context.Context, actor string) context.Context {
+	return ActorKey.WithValue(ctx, actor)
+}
+
+// ActorFromDB 在模型钩子中获取操作者
+func ActorFromDB(db *gorm.DB) (string, bool) {
+	return ActorKey.FromDB(db)
+}
+
+// WithTenant 返回携带租户的 context
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return TenantKey.WithValue(ctx, tenant)
+}
+
+// TenantFromDB 在模型钩子中获取租户
+func TenantFromDB(db *gorm.DB) (string, bool) {
+	return TenantKey.FromDB(db)
+}
+
+// WithLocale 返回携带语言区域的 context
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return LocaleKey.WithValue(ctx, locale)
+}
+
+// LocaleFromDB 在模型钩子中获取语言区域
+func LocaleFromDB(db *gorm.DB) (string, bool) {
+	return LocaleKey.FromDB(db)
+}
+
+// WithRequestID 返回携带请求 ID 的 context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return RequestIDKey.WithValue(ctx, requestID)
+}
+
+// RequestIDFromDB 在模型钩子中获取请求 ID
+func RequestIDFromDB(db *gorm.DB) (string, bool) {
+	return RequestIDKey.FromDB(db)
+}
+
+// RequestMeta 请求级元数据，由 HTTP 中间件写入 context，经 db.WithContext 传递到模型钩子和审计插件
+type RequestMeta struct {
+	Actor     string // 操作者（用户 ID 或服务名），对应 ActorKey
+	Tenant    string // 租户，对应 TenantKey
+	RequestID string // 请求 ID，对应 RequestIDKey
+	Locale    string // 语言区域，对应 LocaleKey
+}
+
+// WithRequestMeta 将元数据中非空的字段写入对应的键，之后仍可通过 ActorFromDB、TenantFromDB 等单独读取
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	if meta.Actor != "" {
+		ctx = ActorKey.WithValue(ctx, meta.Actor)
+	}
+	if meta.Tenant != "" {
+		ctx = TenantKey.WithValue(ctx, meta.Tenant)
+	}
+	if meta.RequestID != "" {
+		ctx = RequestIDKey.WithValue(ctx, meta.RequestID)
+	}
+	if meta.Locale != "" {
+		ctx = LocaleKey.WithValue(ctx, meta.Locale)
+	}
+	return ctx
+}
+
+// RequestMetaFromContext 读取 context 中的元数据，未设置的字段为空
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	var meta RequestMeta
+	meta.Actor, _ = ActorKey.From(ctx)
+	meta.Tenant, _ = TenantKey.From(ctx)
+	meta.RequestID, _ = RequestIDKey.From(ctx)
+	meta.Locale, _ = LocaleKey.From(ctx)
+	return meta
+}
+
+// RequestMetaFromDB 在模型钩子和回调中读取 Statement.Context 中的元数据
+func RequestMetaFromDB(db *gorm.DB) RequestMeta {
+	if db == nil || db.Statement == nil {
+		return RequestMeta{}
+	}
+	return RequestMetaFromContext(db.Statement.Context)
+}
+
+// RequestMetaMiddleware 返回将请求元数据写入请求 context 的 HTTP 中间件，extract 通常从认证结果中
+// 读取操作者和租户，为 nil 时只读取请求 ID。extract 未返回请求 ID 时使用 X-Request-ID 请求头：
+//
+//	mux := db.RequestMetaMiddleware(func(r *http.Request) db.RequestMeta {
+//		return db.RequestMeta{Actor: auth.UserID(r), Tenant: r.Header.Get("X-Tenant")}
+//	})(handler)
+//
+// 处理函数中以 db.WithContext(r.Context()) 执行的语句即可在钩子中通过 RequestMetaFromDB 读取
+func RequestMetaMiddleware(extract func(r *http.Request) RequestMeta) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var meta RequestMeta
+			if extract != nil {
+				meta = extract(r)
+			}
+			if meta.RequestID == "" {
+				meta.RequestID = r.Header.Get(RequestIDHeader)
+			}
+			next.ServeHTTP(w, r.WithContext(WithRequestMeta(r.Context(), meta)))
+		})
+	}
+}
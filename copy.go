@@ -0,0 +1,152 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultCopyChunkSize 每条 COPY 语句默认导入的行数
+const defaultCopyChunkSize = 10000
+
+// CopyFromOptions COPY 批量导入选项
+type CopyFromOptions struct {
+	ChunkSize int                // 每条 COPY 语句导入的行数，默认 10000
+	Progress  func(copied int64) // 每个分块完成后调用，参数为累计导入的行数
+}
+
+// CopyFrom 使用 PostgreSQL COPY 协议将 rows 批量导入 table，返回导入的行数
+// rows 可以使用 pgx.CopyFromRows、pgx.CopyFromSlice 或自定义的流式数据源构造；table 支持 schema.table 格式。
+// 数据按 ChunkSize 分块，每块一条 COPY 语句并独立提交：失败时已完成的分块不会回滚，返回值为已导入的行数。
+// 仅支持基于 pgx 驱动的 PostgreSQL 连接，不能在事务中使用
+func CopyFrom(ctx context.Context, db *gorm.DB, table string, columns []string, rows pgx.CopyFromSource, opts *CopyFromOptions) (int64, error) {
+	chunkSize := defaultCopyChunkSize
+	var progress func(int64)
+	if opts != nil {
+		if opts.ChunkSize > 0 {
+			chunkSize = opts.ChunkSize
+		}
+		progress = opts.Progress
+	}
+
+	sqlDB, err := SQLDB(db)
+	if err != nil {
+		return 0, fmt.Errorf("copy from: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("copy from: %w", err)
+	}
+	defer conn.Close()
+
+	var copied int64
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("copy from requires the pgx driver, got %T", driverConn)
+		}
+
+		identifier := pgx.Identifier(strings.Split(table, "."))
+		src := &copyChunkSource{src: rows, size: chunkSize}
+		for !src.done {
+			src.count = 0
+			start := time.Now()
+			n, err := c.Conn().CopyFrom(ctx, identifier, columns, src)
+			copied += n
+
+			status := "success"
+			if err == nil {
+				err = src.advance()
+			}
+			if err != nil {
+				status = "error"
+			}
+			if metrics.IsEnabled() {
+				DatabaseCopyRowsTotal.WithLabelValues(table).Add(float64(n))
+				DatabaseCopyDuration.WithLabelValues(table, status).Observe(time.Since(start).Seconds())
+			}
+			if err != nil {
+				return err
+			}
+			if progress != nil {
+				progress(copied)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.FromContext(ctx).Error("COPY bulk load failed",
+			zap.String("table", table),
+			zap.Int64("copied", copied),
+			zap.Error(err),
+		)
+		return copied, fmt.Errorf("copy from %s: %w", table, err)
+	}
+	return copied, nil
+}
+
+// copyChunkSource 将数据源按行数切分为多个分块，每个分块作为一次 COPY 的数据源
+type copyChunkSource struct {
+	src     pgx.CopyFromSource
+	size    int  // 每个分块的行数
+	count   int  // 当前分块已读取的行数
+	pending bool // 数据源已前进到下一行但该行尚未被读取
+	done    bool // 数据源已读完
+}
+
+// Next 读取当前分块的下一行，分块已满或数据源读完时返回 false
+func (s *copyChunkSource) Next() bool {
+	if s.count >= s.size {
+		return false
+	}
+	if s.pending {
+		s.pending = false
+	} else if !s.src.Next() {
+		s.done = true
+		return false
+	}
+	s.count++
+	return true
+}
+
+func (s *copyChunkSource) Values() ([]any, error) { return s.src.Values() }
+
+func (s *copyChunkSource) Err() error { return s.src.Err() }
+
+// advance 分块结束后检查数据源是否还有数据，避免数据恰好按分块大小结束时多执行一次空的 COPY
+func (s *copyChunkSource) advance() error {
+	if s.done {
+		return nil
+	}
+	if s.src.Next() {
+		s.pending = true
+		return nil
+	}
+	s.done = true
+	return s.src.Err()
+}
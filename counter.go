@@ -0,0 +1,298 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// counterFlushBatch 每次从待持久化集合中取出的计数器数
+const counterFlushBatch = 100
+
+// counterIncrScript 计数器存在时增加并返回新值，不存在时返回 nil，由调用方从数据库加载初始值
+// KEYS[1] 计数器键；ARGV[1] 增量
+var counterIncrScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+return redis.call('INCRBY', KEYS[1], ARGV[1])
+`)
+
+// CounterRecord 计数器持久化的值
+type CounterRecord struct {
+	Name      string    `gorm:"primaryKey;size:191"`
+	Value     int64     `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// CounterOptions 计数器配置选项
+type CounterOptions struct {
+	Prefix        string        // 计数器键前缀，默认 counter:
+	DB            *gorm.DB      // 持久化的数据库，nil 表示只保存在 Redis
+	Table         string        // 持久化的表名，表不存在时自动创建，默认 counters
+	FlushInterval time.Duration // 定时持久化的间隔，默认 1m
+}
+
+// Counters 基于 Redis INCRBY 的原子计数器（浏览量、点赞数、配额用量等），设置 DB 时定期将变化的计数器
+// 写入数据库。Redis 中的值为准，持久化写入的是快照，重复写入是幂等的；Redis 中的键丢失（如淘汰、重启）时
+// 从数据库恢复，上次持久化之后的增量会丢失
+type Counters struct {
+	client redis.UniversalClient
+	opts   CounterOptions
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCounters 创建计数器
+func NewCounters(client redis.UniversalClient, opts *CounterOptions) (*Counters, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	c := &Counters{client: client}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Prefix == "" {
+		c.opts.Prefix = "counter:"
+	}
+	if c.opts.Table == "" {
+		c.opts.Table = "counters"
+	}
+	if c.opts.FlushInterval <= 0 {
+		c.opts.FlushInterval = time.Minute
+	}
+	if c.opts.DB != nil {
+		if err := c.opts.DB.Table(c.opts.Table).AutoMigrate(&CounterRecord{}); err != nil {
+			return nil, fmt.Errorf("failed to create counter table: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Incr 原子地增加计数器并返回新值，delta 可为负数
+func (c *Counters) Incr(ctx context.Context, name string, delta int64) (int64, error) {
+	if c.opts.DB == nil {
+		return c.client.IncrBy(ctx, c.key(name), delta).Result()
+	}
+	for range 2 {
+		value, err := counterIncrScript.Run(ctx, c.client, []string{c.key(name)}, delta).Int64()
+		if err == nil {
+			if err := c.client.SAdd(ctx, c.dirtyKey(), name).Err(); err != nil {
+				return 0, fmt.Errorf("counter: mark %s: %w", name, err)
+			}
+			return value, nil
+		}
+		if !errors.Is(err, redis.Nil) {
+			return 0, fmt.Errorf("counter: incr %s: %w", name, err)
+		}
+		// 键不存在，从数据库恢复后重试
+		if err := c.restore(ctx, name); err != nil {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("counter: incr %s: key evicted while restoring", name)
+}
+
+// Get 返回计数器的值，不存在时返回 0
+func (c *Counters) Get(ctx context.Context, name string) (int64, error) {
+	value, err := c.client.Get(ctx, c.key(name)).Int64()
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("counter: get %s: %w", name, err)
+	}
+	if c.opts.DB == nil {
+		return 0, nil
+	}
+	if err := c.restore(ctx, name); err != nil {
+		return 0, err
+	}
+	value, err = c.client.Get(ctx, c.key(name)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return value, err
+}
+
+// Reset 将计数器置为 0
+func (c *Counters) Reset(ctx context.Context, name string) error {
+	if err := c.client.Set(ctx, c.key(name), 0, 0).Err(); err != nil {
+		return fmt.Errorf("counter: reset %s: %w", name, err)
+	}
+	if c.opts.DB != nil {
+		return c.client.SAdd(ctx, c.dirtyKey(), name).Err()
+	}
+	return nil
+}
+
+// Flush 将上次持久化之后变化的计数器写入数据库，未设置 DB 时不做任何事。
+// 写入失败的计数器重新标记为待持久化，下次 Flush 时重试
+func (c *Counters) Flush(ctx context.Context) error {
+	if c.opts.DB == nil {
+		return nil
+	}
+	for {
+		names, err := c.client.SPopN(ctx, c.dirtyKey(), counterFlushBatch).Result()
+		if err != nil {
+			return fmt.Errorf("counter: flush: %w", err)
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		if err := c.flushBatch(ctx, names); err != nil {
+			if rerr := c.client.SAdd(context.WithoutCancel(ctx), c.dirtyKey(), stringArgs(names)...).Err(); rerr != nil {
+				log.FromContext(ctx).Warn("Failed to requeue counters after flush error",
+					zap.Strings("counters", names), zap.Error(rerr))
+			}
+			recordCounterFlush("failed", len(names))
+			return fmt.Errorf("counter: flush: %w", err)
+		}
+		recordCounterFlush("succeeded", len(names))
+		if len(names) < counterFlushBatch {
+			return nil
+		}
+	}
+}
+
+// Start 在后台定期持久化计数器，未设置 DB 时不做任何事
+func (c *Counters) Start(ctx context.Context) error {
+	if c.opts.DB == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		return fmt.Errorf("counter flusher already started")
+	}
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.opts.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := c.Flush(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("Failed to flush counters", zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止定期持久化，并最后持久化一次
+func (c *Counters) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return c.Flush(ctx)
+}
+
+// flushBatch 读取一批计数器的当前值并写入数据库
+func (c *Counters) flushBatch(ctx context.Context, names []string) error {
+	cmds := make([]*redis.StringCmd, len(names))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, name := range names {
+			cmds[i] = pipe.Get(ctx, c.key(name))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	now := time.Now()
+	records := make([]CounterRecord, 0, len(names))
+	for i, cmd := range cmds {
+		value, err := cmd.Int64()
+		if errors.Is(err, redis.Nil) {
+			continue // 已被删除或淘汰，保留数据库中的值
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", names[i], err)
+		}
+		records = append(records, CounterRecord{Name: names[i], Value: value, UpdatedAt: now})
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return c.opts.DB.WithContext(ctx).Table(c.opts.Table).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&records).Error
+}
+
+// restore 从数据库加载计数器的值写入 Redis，键已存在时不覆盖
+func (c *Counters) restore(ctx context.Context, name string) error {
+	var rec CounterRecord
+	err := c.opts.DB.WithContext(ctx).Table(c.opts.Table).
+		Where(clause.Eq{Column: clause.Column{Name: "name"}, Value: name}).Take(&rec).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("counter: restore %s: %w", name, err)
+	}
+	if err := c.client.SetNX(ctx, c.key(name), strconv.FormatInt(rec.Value, 10), 0).Err(); err != nil {
+		return fmt.Errorf("counter: restore %s: %w", name, err)
+	}
+	return nil
+}
+
+// key 返回计数器的键
+func (c *Counters) key(name string) string {
+	return c.opts.Prefix + name
+}
+
+// dirtyKey 返回待持久化计数器集合的键
+func (c *Counters) dirtyKey() string {
+	return c.opts.Prefix + "{dirty}"
+}
+
+// recordCounterFlush 记录计数器持久化指标
+func recordCounterFlush(result string, n int) {
+	if metrics.IsEnabled() {
+		CounterFlushTotal.WithLabelValues(result).Add(float64(n))
+	}
+}
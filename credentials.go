@@ -0,0 +1,236 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.uber.org/zap"
+)
+
+// CredentialProvider 凭据提供者，在建立新连接时动态获取密码
+type CredentialProvider interface {
+	Password(ctx context.Context) (string, error)
+}
+
+// CredentialRefresher 可强制刷新的凭据提供者，服务端拒绝当前凭据（如密码已轮换）时调用
+type CredentialRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// RefreshCredentials 强制刷新凭据提供者缓存的凭据，提供者未实现 CredentialRefresher 时不做任何操作
+func RefreshCredentials(ctx context.Context, provider CredentialProvider) error {
+	if r, ok := provider.(CredentialRefresher); ok {
+		return r.Refresh(ctx)
+	}
+	return nil
+}
+
+// FileCredentialProvider 从文件读取密码（适用于 Kubernetes/Docker 挂载的 Secret）
+// 每次获取密码时会检查文件的修改时间，文件变化后自动重新读取。
+// 文件暂时无法读取时继续返回上一次读取的密码，输出告警并通过 LastError 暴露错误
+type FileCredentialProvider struct {
+	path    string
+	mu      sync.RWMutex
+	value   string
+	modTime time.Time
+	size    int64
+	lastErr error // 最近一次检查或读取文件的错误，读取成功后清空
+}
+
+var (
+	fileProvidersMu sync.Mutex
+	fileProviders   = make(map[string]*FileCredentialProvider)
+)
+
+// NewFileCredentialProvider 创建文件凭据提供者，同一路径复用同一实例
+func NewFileCredentialProvider(path string) (*FileCredentialProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("credential file path cannot be empty")
+	}
+
+	fileProvidersMu.Lock()
+	defer fileProvidersMu.Unlock()
+
+	if p, ok := fileProviders[path]; ok {
+		return p, nil
+	}
+
+	p := &FileCredentialProvider{path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	fileProviders[path] = p
+	return p, nil
+}
+
+// Path 返回密码文件路径
+func (p *FileCredentialProvider) Path() string {
+	return p.path
+}
+
+// LastError 返回最近一次检查或读取密码文件的错误，文件正常时返回 nil
+// 不为 nil 时 Password 返回的是上一次成功读取的密码，可能已经过期
+func (p *FileCredentialProvider) LastError() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastErr
+}
+
+// Password 返回当前密码，如果文件已变化则重新读取
+func (p *FileCredentialProvider) Password(_ context.Context) (string, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		err = fmt.Errorf("failed to stat credential file %s: %w", p.path, err)
+		p.setLastError(err)
+		// 文件暂时不可用（如 Secret 正在轮换），返回上一次读取的值
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		if p.value != "" {
+			return p.value, nil
+		}
+		return "", err
+	}
+
+	p.mu.RLock()
+	changed := !info.ModTime().Equal(p.modTime) || info.Size() != p.size
+	value := p.value
+	p.mu.RUnlock()
+
+	if !changed {
+		p.setLastError(nil)
+		return value, nil
+	}
+	if err := p.Reload(); err != nil {
+		return value, nil
+	}
+	p.mu.RLock()
+	value = p.value
+	p.mu.RUnlock()
+	return value, nil
+}
+
+// setLastError 记录检查或读取文件的结果，文件从可用变为不可用时输出告警
+func (p *FileCredentialProvider) setLastError(err error) {
+	p.mu.Lock()
+	prev := p.lastErr
+	p.lastErr = err
+	p.mu.Unlock()
+
+	switch {
+	case err != nil && prev == nil:
+		log.Warn("Credential file unavailable, keeping the last read password",
+			zap.String("path", p.path), zap.Error(err))
+	case err == nil && prev != nil:
+		log.Info("Credential file available again", zap.String("path", p.path))
+	}
+}
+
+// Reload 重新读取密码文件
+func (p *FileCredentialProvider) Reload() error {
+	err := p.reload()
+	p.setLastError(err)
+	return err
+}
+
+// reload 读取密码文件并更新缓存
+func (p *FileCredentialProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat credential file %s: %w", p.path, err)
+	}
+
+	// #nosec G304 -- 文件路径来自配置
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read credential file %s: %w", p.path, err)
+	}
+
+	// 去掉末尾换行，Secret 文件通常以换行结尾
+	value := strings.TrimRight(string(data), "\r\n")
+
+	p.mu.Lock()
+	changed := p.value != "" && p.value != value
+	p.value = value
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.mu.Unlock()
+
+	if changed {
+		log.Info("Credential file reloaded", zap.String("path", p.path))
+	}
+	return nil
+}
+
+// Refresh 实现 CredentialRefresher 接口，无论文件的修改时间是否变化都重新读取
+func (p *FileCredentialProvider) Refresh(_ context.Context) error {
+	return p.Reload()
+}
+
+// ReloadCredentialsOnSIGHUP 监听 SIGHUP 信号，收到后重新读取所有密码文件
+// 阻塞直到 ctx 结束，通常在独立的 goroutine 中调用
+func ReloadCredentialsOnSIGHUP(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			fileProvidersMu.Lock()
+			providers := make([]*FileCredentialProvider, 0, len(fileProviders))
+			for _, p := range fileProviders {
+				providers = append(providers, p)
+			}
+			fileProvidersMu.Unlock()
+
+			for _, p := range providers {
+				if err := p.Reload(); err != nil {
+					log.Warn("Failed to reload credential file on SIGHUP",
+						zap.String("path", p.path), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// resolvePassword 根据密码与密码文件配置解析初始密码和凭据提供者
+func resolvePassword(password, passwordFile string) (string, CredentialProvider, error) {
+	if passwordFile == "" {
+		return password, nil, nil
+	}
+	provider, err := NewFileCredentialProvider(passwordFile)
+	if err != nil {
+		return "", nil, err
+	}
+	value, err := provider.Password(context.Background())
+	if err != nil {
+		return "", nil, err
+	}
+	return value, provider, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileCredentialProviderLastError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	p, err := NewFileCredentialProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileCredentialProvider: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		prepare func() error
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "initial read",
+			prepare: func() error { return nil },
+			want:    "first",
+		},
+		{
+			name:    "file removed keeps cached password",
+			prepare: func() error { return os.Remove(path) },
+			want:    "first",
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "file restored",
+			prepare: func() error { return os.WriteFile(path, []byte("second\n"), 0o600) },
+			want:    "second",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.prepare(); err != nil {
+				t.Fatalf("prepare: %v", err)
+			}
+			got, err := p.Password(ctx)
+			if err != nil || got != tt.want {
+				t.Errorf("Password = %q, %v, want %q", got, err, tt.want)
+			}
+			if lastErr := p.LastError(); !errors.Is(lastErr, tt.wantErr) {
+				t.Errorf("LastError = %v, want %v", lastErr, tt.wantErr)
+			}
+		})
+	}
+}
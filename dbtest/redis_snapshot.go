@@ -0,0 +1,154 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisSnapshotBatch 每次 SCAN 和管道 DUMP 的键数
+const redisSnapshotBatch = 500
+
+// RedisSnapshot Redis 的快照，保存在内存中：每个键的 DUMP 序列化值和剩余过期时间
+type RedisSnapshot struct {
+	entries []redisEntry
+}
+
+// redisEntry 快照中的键
+type redisEntry struct {
+	key   string
+	value string
+	ttl   time.Duration // 0 表示不过期
+}
+
+// Len 返回快照中的键数
+func (s *RedisSnapshot) Len() int {
+	return len(s.entries)
+}
+
+// SnapshotRedis 创建 Redis 当前库（集群时为所有主节点）的快照，通过 SCAN + DUMP 读取所有键，适用于测试规模的数据
+func SnapshotRedis(ctx context.Context, client redis.UniversalClient) (*RedisSnapshot, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	snap := &RedisSnapshot{}
+	var mu sync.Mutex
+	err := forEachRedisNode(ctx, client, func(ctx context.Context, node redis.Cmdable) error {
+		entries, err := dumpRedisNode(ctx, node)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		snap.entries = append(snap.entries, entries...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dbtest: redis snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// RestoreRedis 清空 Redis 当前库（FLUSHDB，集群时清空所有主节点）后以 RESTORE 写回快照中的键，
+// 快照时设置了过期时间的键恢复为快照时的剩余时间
+func RestoreRedis(ctx context.Context, client redis.UniversalClient, snap *RedisSnapshot) error {
+	if client == nil {
+		return fmt.Errorf("redis client cannot be nil")
+	}
+	if snap == nil {
+		return fmt.Errorf("snapshot cannot be nil")
+	}
+	err := forEachRedisNode(ctx, client, func(ctx context.Context, node redis.Cmdable) error {
+		return node.FlushDB(ctx).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("dbtest: redis restore: %w", err)
+	}
+	for start := 0; start < len(snap.entries); start += redisSnapshotBatch {
+		batch := snap.entries[start:min(start+redisSnapshotBatch, len(snap.entries))]
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, e := range batch {
+				pipe.RestoreReplace(ctx, e.key, e.ttl, e.value)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("dbtest: redis restore: %w", err)
+		}
+	}
+	return nil
+}
+
+// forEachRedisNode 集群客户端在每个主节点上调用 fn，其他客户端直接调用
+func forEachRedisNode(ctx context.Context, client redis.UniversalClient, fn func(ctx context.Context, node redis.Cmdable) error) error {
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return fn(ctx, node)
+		})
+	}
+	return fn(ctx, client)
+}
+
+// dumpRedisNode 读取节点上所有键的序列化值和剩余过期时间
+func dumpRedisNode(ctx context.Context, node redis.Cmdable) ([]redisEntry, error) {
+	var entries []redisEntry
+	var cursor uint64
+	for {
+		keys, next, err := node.Scan(ctx, cursor, "*", redisSnapshotBatch).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			dumps := make([]*redis.StringCmd, len(keys))
+			ttls := make([]*redis.DurationCmd, len(keys))
+			_, err := node.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for i, key := range keys {
+					dumps[i] = pipe.Dump(ctx, key)
+					ttls[i] = pipe.PTTL(ctx, key)
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return nil, err
+			}
+			for i, key := range keys {
+				value, err := dumps[i].Result()
+				if errors.Is(err, redis.Nil) {
+					continue // SCAN 之后已删除或过期
+				}
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", key, err)
+				}
+				// PTTL 返回 -1 表示不过期，-2 表示键已不存在
+				ttl := ttls[i].Val()
+				if ttl == -2 {
+					continue
+				}
+				entries = append(entries, redisEntry{key: key, value: value, ttl: max(ttl, 0)})
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return entries, nil
+		}
+	}
+}
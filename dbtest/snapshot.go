@@ -0,0 +1,341 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+// Package dbtest 集成测试辅助工具：数据库和 Redis 的快照与恢复，使每个测试从已知状态开始
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	db "github.com/go-anyway/framework-db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// ErrUnsupportedDialect 快照不支持的数据库类型
+var ErrUnsupportedDialect = errors.New("dbtest: snapshot supports mysql and postgres only")
+
+// maxSnapshotBaseLen 快照库名中源库名部分的最大长度，PostgreSQL 标识符最长 63 字节
+const maxSnapshotBaseLen = 40
+
+// SQLSnapshot 数据库的快照（表结构和数据），保存在同一服务器上的独立数据库中，可多次恢复，不再使用时调用 Drop 删除
+type SQLSnapshot struct {
+	Database string // 快照的源数据库
+	Name     string // 保存快照的数据库
+
+	dialect string
+	tables  []mysqlTable // MySQL 快照的表，按表名排序
+}
+
+// mysqlTable MySQL 快照中的表
+type mysqlTable struct {
+	name    string
+	create  string   // SHOW CREATE TABLE 的结果，恢复时用于重建表（包含外键）
+	columns []string // 非生成列，用于复制数据
+}
+
+// Snapshot 创建 gdb 所连接数据库的快照：PostgreSQL 以源库为模板创建快照库（CREATE DATABASE ... TEMPLATE），
+// MySQL 将每个表的结构和数据复制到快照库（CREATE TABLE ... LIKE + INSERT ... SELECT）。
+// PostgreSQL 创建快照时源库不能有其他连接：连接池的空闲连接会被关闭，其他会话的连接会被终止。
+// MySQL 快照只包含基本表，不包含视图、存储过程和触发器
+func Snapshot(ctx context.Context, gdb *gorm.DB) (*SQLSnapshot, error) {
+	switch gdb.Dialector.Name() {
+	case "postgres":
+		return snapshotPostgres(ctx, gdb)
+	case "mysql":
+		return snapshotMySQL(ctx, gdb)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDialect, gdb.Dialector.Name())
+	}
+}
+
+// Restore 将 gdb 所连接的数据库恢复到快照时的状态：PostgreSQL 删除源库后以快照库为模板重建，
+// MySQL 删除当前所有基本表后按快照重建并复制数据。恢复期间不应有其他会话使用该数据库
+func Restore(ctx context.Context, gdb *gorm.DB, snap *SQLSnapshot) error {
+	if snap == nil {
+		return fmt.Errorf("snapshot cannot be nil")
+	}
+	if dialect := gdb.Dialector.Name(); dialect != snap.dialect {
+		return fmt.Errorf("dbtest: snapshot of %s cannot be restored to %s", snap.dialect, dialect)
+	}
+	if snap.dialect == "postgres" {
+		return restorePostgres(ctx, gdb, snap)
+	}
+	return restoreMySQL(ctx, gdb, snap)
+}
+
+// Drop 删除保存快照的数据库
+func (s *SQLSnapshot) Drop(ctx context.Context, gdb *gorm.DB) error {
+	if s.dialect == "postgres" {
+		conn, _, err := postgresMaintenanceConn(ctx, gdb)
+		if err != nil {
+			return err
+		}
+		defer conn.Close(context.WithoutCancel(ctx))
+		if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+quotePostgres(s.Name)); err != nil {
+			return fmt.Errorf("dbtest: drop snapshot %s: %w", s.Name, err)
+		}
+		return nil
+	}
+	if err := gdb.WithContext(ctx).Exec("DROP DATABASE IF EXISTS " + quoteMySQL(s.Name)).Error; err != nil {
+		return fmt.Errorf("dbtest: drop snapshot %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// snapshotPostgres 以源库为模板创建快照库
+func snapshotPostgres(ctx context.Context, gdb *gorm.DB) (*SQLSnapshot, error) {
+	conn, database, err := postgresMaintenanceConn(ctx, gdb)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	snap := &SQLSnapshot{Database: database, Name: snapshotName(database), dialect: "postgres"}
+	if err := disconnectPostgres(ctx, gdb, conn, database); err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "CREATE DATABASE "+quotePostgres(snap.Name)+" TEMPLATE "+quotePostgres(database))
+	if err != nil {
+		return nil, fmt.Errorf("dbtest: snapshot %s: %w", database, err)
+	}
+	return snap, nil
+}
+
+// restorePostgres 删除源库后以快照库为模板重建
+func restorePostgres(ctx context.Context, gdb *gorm.DB, snap *SQLSnapshot) error {
+	conn, _, err := postgresMaintenanceConn(ctx, gdb)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if err := disconnectPostgres(ctx, gdb, conn, snap.Database); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+quotePostgres(snap.Database)); err != nil {
+		return fmt.Errorf("dbtest: restore %s: %w", snap.Database, err)
+	}
+	_, err = conn.Exec(ctx, "CREATE DATABASE "+quotePostgres(snap.Database)+" TEMPLATE "+quotePostgres(snap.Name))
+	if err != nil {
+		return fmt.Errorf("dbtest: restore %s: %w", snap.Database, err)
+	}
+	return nil
+}
+
+// postgresMaintenanceConn 使用 gdb 的连接配置建立到 postgres 维护库的独立连接，返回连接和 gdb 所连接的库名。
+// 创建和删除数据库不能在连接到源库的会话中执行
+func postgresMaintenanceConn(ctx context.Context, gdb *gorm.DB) (*pgx.Conn, string, error) {
+	sqlDB, err := db.SQLDB(gdb)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer c.Close()
+
+	var cfg *pgx.ConnConfig
+	err = c.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("dbtest: snapshot requires the pgx driver, got %T", driverConn)
+		}
+		cfg = pc.Conn().Config()
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	database := cfg.Database
+	cfg.Database = "postgres"
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, "", fmt.Errorf("dbtest: connect to maintenance database: %w", err)
+	}
+	return conn, database, nil
+}
+
+// disconnectPostgres 关闭连接池的空闲连接并终止其他会话到 database 的连接，
+// CREATE DATABASE ... TEMPLATE 和 DROP DATABASE 要求数据库没有其他连接
+func disconnectPostgres(ctx context.Context, gdb *gorm.DB, conn *pgx.Conn, database string) error {
+	sqlDB, err := db.SQLDB(gdb)
+	if err != nil {
+		return err
+	}
+	// 先关闭空闲连接，避免被服务端终止的连接留在池中；之后恢复空闲连接数上限（无法读取原值，按最大连接数恢复）
+	idle := 2
+	if n := sqlDB.Stats().MaxOpenConnections; n > 0 {
+		idle = n
+	}
+	sqlDB.SetMaxIdleConns(0)
+	sqlDB.SetMaxIdleConns(idle)
+
+	_, err = conn.Exec(ctx,
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()", database)
+	if err != nil {
+		return fmt.Errorf("dbtest: disconnect %s: %w", database, err)
+	}
+	return nil
+}
+
+// snapshotMySQL 将每个基本表的结构和数据复制到快照库
+func snapshotMySQL(ctx context.Context, gdb *gorm.DB) (*SQLSnapshot, error) {
+	snap := &SQLSnapshot{dialect: "mysql"}
+	err := gdb.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Raw("SELECT DATABASE()").Scan(&snap.Database).Error; err != nil {
+			return err
+		}
+		snap.Name = snapshotName(snap.Database)
+
+		var err error
+		if snap.tables, err = mysqlTables(conn, snap.Database); err != nil {
+			return err
+		}
+		if err := conn.Exec("CREATE DATABASE " + quoteMySQL(snap.Name)).Error; err != nil {
+			return err
+		}
+		for _, t := range snap.tables {
+			dst, src := quoteMySQL(snap.Name)+"."+quoteMySQL(t.name), quoteMySQL(snap.Database)+"."+quoteMySQL(t.name)
+			if err := conn.Exec("CREATE TABLE " + dst + " LIKE " + src).Error; err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+			if err := conn.Exec(mysqlCopyStatement(dst, src, t.columns)).Error; err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if snap.Name != "" {
+			_ = gdb.WithContext(context.WithoutCancel(ctx)).Exec("DROP DATABASE IF EXISTS " + quoteMySQL(snap.Name)).Error
+		}
+		return nil, fmt.Errorf("dbtest: snapshot %s: %w", snap.Database, err)
+	}
+	return snap, nil
+}
+
+// restoreMySQL 删除当前所有基本表，按快照的建表语句重建并从快照库复制数据
+func restoreMySQL(ctx context.Context, gdb *gorm.DB, snap *SQLSnapshot) error {
+	err := gdb.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var current string
+		if err := conn.Raw("SELECT DATABASE()").Scan(&current).Error; err != nil {
+			return err
+		}
+		if current != snap.Database {
+			return fmt.Errorf("connected to %s, snapshot is of %s", current, snap.Database)
+		}
+
+		if err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+
+		names, err := mysqlTableNames(conn, snap.Database)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := conn.Exec("DROP TABLE IF EXISTS " + quoteMySQL(name)).Error; err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		for _, t := range snap.tables {
+			if err := conn.Exec(t.create).Error; err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+			src := quoteMySQL(snap.Name) + "." + quoteMySQL(t.name)
+			if err := conn.Exec(mysqlCopyStatement(quoteMySQL(t.name), src, t.columns)).Error; err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("dbtest: restore %s: %w", snap.Database, err)
+	}
+	return nil
+}
+
+// mysqlTableNames 返回库中的基本表名
+func mysqlTableNames(conn *gorm.DB, database string) ([]string, error) {
+	var names []string
+	err := conn.Raw("SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME",
+		database).Scan(&names).Error
+	return names, err
+}
+
+// mysqlTables 返回库中的基本表及其建表语句和非生成列
+func mysqlTables(conn *gorm.DB, database string) ([]mysqlTable, error) {
+	names, err := mysqlTableNames(conn, database)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]mysqlTable, len(names))
+	for i, name := range names {
+		t := mysqlTable{name: name}
+		var table string
+		if err := conn.Raw("SHOW CREATE TABLE "+quoteMySQL(name)).Row().Scan(&table, &t.create); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		err := conn.Raw("SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND EXTRA NOT LIKE '%GENERATED%' ORDER BY ORDINAL_POSITION",
+			database, name).Scan(&t.columns).Error
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		tables[i] = t
+	}
+	return tables, nil
+}
+
+// mysqlCopyStatement 返回复制非生成列数据的 INSERT ... SELECT 语句
+func mysqlCopyStatement(dst, src string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteMySQL(c)
+	}
+	list := strings.Join(quoted, ", ")
+	return "INSERT INTO " + dst + " (" + list + ") SELECT " + list + " FROM " + src
+}
+
+// snapshotName 生成快照库名：<源库名>_snap_<随机后缀>
+func snapshotName(database string) string {
+	if len(database) > maxSnapshotBaseLen {
+		database = database[:maxSnapshotBaseLen]
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return database + "_snap_" + hex.EncodeToString(b)
+}
+
+// quoteMySQL 使用反引号引用 MySQL 标识符
+func quoteMySQL(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quotePostgres 使用双引号引用 PostgreSQL 标识符
+func quotePostgres(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// TxDB 在共享的 gdb 上开启事务，返回绑定该事务的会话，测试结束时回滚，测试之间无需重新准备数据：
+//
+//	func TestCreateOrder(t *testing.T) {
+//		tx := dbtest.TxDB(t, sharedDB)
+//		svc := NewOrderService(tx)
+//		...
+//	}
+//
+// 被测代码在返回的会话上开启和提交的事务（Begin/Commit、Transaction）以保存点实现，提交只释放保存点，
+// 数据仍在测试事务中，对其他测试不可见。返回的会话绑定同一个连接，不能在多个 goroutine 中并发使用
+func TxDB(t testing.TB, gdb *gorm.DB) *gorm.DB {
+	t.Helper()
+	// 不使用 t.Context()：其在 Cleanup 之前取消，事务会被 database/sql 提前回滚
+	outer := gdb.Session(&gorm.Session{NewDB: true, Context: context.Background()}).Begin()
+	if outer.Error != nil {
+		t.Fatalf("dbtest: begin test transaction: %v", outer.Error)
+	}
+	t.Cleanup(func() {
+		if err := outer.Rollback().Error; err != nil && !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("dbtest: rollback test transaction: %v", err)
+		}
+	})
+
+	tx := outer.Session(&gorm.Session{NewDB: true, Context: context.Background()})
+	tx.Statement.ConnPool = &rollbackPool{ConnPool: outer.Statement.ConnPool}
+	return tx
+}
+
+// rollbackPool 测试事务的连接：语句在测试事务中执行，开启的事务以保存点实现。
+// 不实现 gorm.TxCommitter，在返回的会话上直接调用 Commit 会得到 gorm.ErrInvalidTransaction 而不是提交测试事务
+type rollbackPool struct {
+	gorm.ConnPool
+	seq atomic.Int64
+}
+
+// BeginTx 创建保存点，返回以释放/回滚到保存点实现提交/回滚的事务
+func (p *rollbackPool) BeginTx(ctx context.Context, _ *sql.TxOptions) (gorm.ConnPool, error) {
+	name := fmt.Sprintf("dbtest_sp_%d", p.seq.Add(1))
+	if _, err := p.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, fmt.Errorf("dbtest: savepoint %s: %w", name, err)
+	}
+	return &savepointTx{rollbackPool: p, name: name}, nil
+}
+
+// savepointTx 被测代码开启的事务
+type savepointTx struct {
+	*rollbackPool
+	name string
+}
+
+// Commit 释放保存点，修改保留在测试事务中
+func (tx *savepointTx) Commit() error {
+	_, err := tx.ExecContext(context.Background(), "RELEASE SAVEPOINT "+tx.name)
+	return err
+}
+
+// Rollback 回滚到保存点
+func (tx *savepointTx) Rollback() error {
+	_, err := tx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+tx.name)
+	return err
+}
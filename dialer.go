@@ -0,0 +1,372 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/net/proxy"
+)
+
+// TunnelConfig 通过代理或 SSH 隧道连接数据库的配置，用于从外部网络访问受限 VPC 中的数据库
+// Proxy 和 SSHHost 只能设置一个，均为空时直接连接
+type TunnelConfig struct {
+	Proxy                    string `yaml:"proxy"`                        // 代理地址：socks5://[user:pass@]host:port 或 http://[user:pass@]host:port
+	SSHHost                  string `yaml:"ssh_host"`                     // SSH 跳板机 host:port，未指定端口时使用 22
+	SSHUser                  string `yaml:"ssh_user"`                     // SSH 用户名
+	SSHKeyFile               string `yaml:"ssh_key_file"`                 // SSH 私钥文件路径
+	SSHKeyPassphrase         string `yaml:"ssh_key_passphrase"`           // SSH 私钥的密码，私钥未加密时为空
+	SSHKnownHostsFile        string `yaml:"ssh_known_hosts_file"`         // known_hosts 文件路径，用于校验跳板机的主机密钥
+	SSHInsecureIgnoreHostKey bool   `yaml:"ssh_insecure_ignore_host_key"` // 不校验跳板机的主机密钥，仅用于测试环境，未设置 ssh_known_hosts_file 时必须显式开启
+}
+
+// Validate 验证隧道配置
+func (c *TunnelConfig) Validate() error {
+	if c.Proxy != "" && c.SSHHost != "" {
+		return fmt.Errorf("tunnel proxy and ssh_host cannot both be set")
+	}
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid tunnel proxy: %w", err)
+		}
+		switch u.Scheme {
+		case "socks5", "socks5h", "http":
+		default:
+			return fmt.Errorf("tunnel proxy scheme must be socks5 or http, got %q", u.Scheme)
+		}
+	}
+	if c.SSHHost != "" {
+		if c.SSHUser == "" {
+			return fmt.Errorf("tunnel ssh_user is required when ssh_host is set")
+		}
+		if c.SSHKeyFile == "" {
+			return fmt.Errorf("tunnel ssh_key_file is required when ssh_host is set")
+		}
+		if c.SSHKnownHostsFile == "" && !c.SSHInsecureIgnoreHostKey {
+			return fmt.Errorf("tunnel ssh_known_hosts_file is required when ssh_host is set, or set ssh_insecure_ignore_host_key to skip host key verification")
+		}
+	}
+	return nil
+}
+
+// dialer 根据配置创建拨号器，未设置代理和 SSH 隧道时返回 nil
+func (c *TunnelConfig) dialer() (*Dialer, error) {
+	switch {
+	case c.Proxy != "":
+		return NewProxyDialer(c.Proxy)
+	case c.SSHHost != "":
+		key, err := os.ReadFile(c.SSHKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssh key file: %w", err)
+		}
+		return NewSSHTunnelDialer(&SSHTunnelOptions{
+			Host:                  c.SSHHost,
+			User:                  c.SSHUser,
+			PrivateKey:            key,
+			Passphrase:            c.SSHKeyPassphrase,
+			KnownHostsFile:        c.SSHKnownHostsFile,
+			InsecureIgnoreHostKey: c.SSHInsecureIgnoreHostKey,
+		})
+	default:
+		return nil, nil
+	}
+}
+
+// Dialer 建立数据库网络连接的拨号器，通过 Options、PostgreSQLOptions、RedisOptions 的 Dialer 字段使用
+type Dialer struct {
+	dial  func(ctx context.Context, network, addr string) (net.Conn, error)
+	close func() error
+
+	mysqlOnce sync.Once
+	mysqlNet  string
+}
+
+// mySQLDialers 已注册到 MySQL 驱动的拨号器数量，用于生成网络名称
+var mySQLDialers atomic.Int64
+
+// NewDialer 使用自定义拨号函数创建拨号器
+func NewDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *Dialer {
+	return &Dialer{dial: dial}
+}
+
+// DialContext 建立到 addr 的连接
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.dial(ctx, network, addr)
+}
+
+// Close 释放拨号器持有的资源（如 SSH 连接），已建立的数据库连接随之断开
+func (d *Dialer) Close() error {
+	if d.close == nil {
+		return nil
+	}
+	return d.close()
+}
+
+// mySQLNetwork 返回 DSN 中使用的网络名称，首次调用时将拨号器注册到 MySQL 驱动
+func (d *Dialer) mySQLNetwork() string {
+	d.mysqlOnce.Do(func() {
+		d.mysqlNet = fmt.Sprintf("framework-db-dialer-%d", mySQLDialers.Add(1))
+		mysqldriver.RegisterDialContext(d.mysqlNet, func(ctx context.Context, addr string) (net.Conn, error) {
+			return d.dial(ctx, "tcp", addr)
+		})
+	})
+	return d.mysqlNet
+}
+
+// mySQLNetwork 返回 MySQL 连接使用的网络名称，未设置拨号器时为 tcp
+func mySQLNetwork(d *Dialer) string {
+	if d == nil {
+		return "tcp"
+	}
+	return d.mySQLNetwork()
+}
+
+// NewProxyDialer 创建通过代理建立连接的拨号器，支持 socks5://（socks5h:// 由代理解析域名）和 http://（CONNECT 隧道）
+func NewProxyDialer(proxyURL string) (*Dialer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	direct := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		d, err := proxy.FromURL(u, direct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create socks5 dialer: %w", err)
+		}
+		cd, ok := d.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("socks5 dialer does not support context")
+		}
+		return NewDialer(cd.DialContext), nil
+	case "http":
+		return NewDialer(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialHTTPConnect(ctx, direct, u, addr)
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+}
+
+// dialHTTPConnect 通过 HTTP 代理的 CONNECT 方法建立隧道
+func dialHTTPConnect(ctx context.Context, direct *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := direct.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to http proxy: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to send http proxy connect: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to read http proxy response: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("http proxy connect to %s failed: %s", addr, resp.Status)
+	}
+	// MySQL 等协议由服务端先发送数据，可能已被读入缓冲区
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn 先读取缓冲区中剩余数据的连接
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read 从缓冲区读取
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// SSHTunnelOptions SSH 隧道配置选项
+type SSHTunnelOptions struct {
+	Host                  string        // 跳板机 host:port，未指定端口时使用 22
+	User                  string        // 用户名
+	PrivateKey            []byte        // PEM 格式的私钥
+	Passphrase            string        // 私钥的密码，私钥未加密时为空
+	KnownHostsFile        string        // known_hosts 文件路径，用于校验跳板机的主机密钥
+	InsecureIgnoreHostKey bool          // 不校验主机密钥，仅用于测试环境，KnownHostsFile 为空时必须显式开启
+	Timeout               time.Duration // 连接跳板机的超时，默认 10s
+}
+
+// sshTunnel 共享一条 SSH 连接的隧道，连接断开后在下次拨号时重建
+type sshTunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// NewSSHTunnelDialer 创建通过 SSH 跳板机转发连接的拨号器，首次拨号时建立 SSH 连接，所有数据库连接共享该 SSH 连接
+func NewSSHTunnelDialer(opts *SSHTunnelOptions) (*Dialer, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("ssh tunnel options cannot be nil")
+	}
+	var (
+		signer ssh.Signer
+		err    error
+	)
+	if opts.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(opts.PrivateKey, []byte(opts.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(opts.PrivateKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh private key: %w", err)
+	}
+
+	var hostKey ssh.HostKeyCallback
+	switch {
+	case opts.KnownHostsFile != "":
+		if hostKey, err = knownhosts.New(opts.KnownHostsFile); err != nil {
+			return nil, fmt.Errorf("failed to load ssh known hosts: %w", err)
+		}
+	case opts.InsecureIgnoreHostKey:
+		hostKey = ssh.InsecureIgnoreHostKey()
+		log.Warn("SSH tunnel host key verification disabled, set known hosts file in production",
+			zap.String("host", opts.Host))
+	default:
+		return nil, fmt.Errorf("ssh known hosts file is required, or set InsecureIgnoreHostKey to skip host key verification")
+	}
+
+	addr := opts.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	t := &sshTunnel{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            opts.User,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKey,
+			Timeout:         timeout,
+		},
+	}
+	return &Dialer{dial: t.dial, close: t.close}, nil
+}
+
+// dial 通过 SSH 连接转发到 addr，转发失败时重建 SSH 连接后重试一次
+func (t *sshTunnel) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+
+	t.reset(client)
+	if client, err = t.connect(ctx); err != nil {
+		return nil, err
+	}
+	return client.DialContext(ctx, network, addr)
+}
+
+// connect 返回当前的 SSH 连接，不存在时建立
+func (t *sshTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	conn, err := (&net.Dialer{Timeout: t.config.Timeout, KeepAlive: 30 * time.Second}).DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh host %s: %w", t.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to establish ssh connection to %s: %w", t.addr, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	t.client = ssh.NewClient(c, chans, reqs)
+	return t.client, nil
+}
+
+// reset 关闭已失效的 SSH 连接，client 已被其他调用重建时不处理
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == client {
+		_ = client.Close()
+		t.client = nil
+	}
+}
+
+// close 关闭 SSH 连接
+func (t *sshTunnel) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == nil {
+		return nil
+	}
+	err := t.client.Close()
+	t.client = nil
+	return err
+}
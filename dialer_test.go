@@ -0,0 +1,90 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewSSHTunnelDialerHostKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	key := pem.EncodeToMemory(block)
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(knownHosts, nil, 0o600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    SSHTunnelOptions
+		wantErr bool
+	}{
+		{name: "no known hosts", opts: SSHTunnelOptions{}, wantErr: true},
+		{name: "missing known hosts file", opts: SSHTunnelOptions{KnownHostsFile: filepath.Join(t.TempDir(), "missing")}, wantErr: true},
+		{name: "known hosts file", opts: SSHTunnelOptions{KnownHostsFile: knownHosts}},
+		{name: "insecure opt-in", opts: SSHTunnelOptions{InsecureIgnoreHostKey: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Host, opts.User, opts.PrivateKey = "bastion.example.com", "deploy", key
+			d, err := NewSSHTunnelDialer(&opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSSHTunnelDialer error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if d != nil {
+				_ = d.Close()
+			}
+		})
+	}
+}
+
+func TestTunnelConfigValidateHostKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TunnelConfig
+		wantErr bool
+	}{
+		{name: "no host key setting", config: TunnelConfig{}, wantErr: true},
+		{name: "known hosts file", config: TunnelConfig{SSHKnownHostsFile: "/etc/ssh/ssh_known_hosts"}},
+		{name: "insecure opt-in", config: TunnelConfig{SSHInsecureIgnoreHostKey: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			c.SSHHost, c.SSHUser, c.SSHKeyFile = "bastion.example.com", "deploy", "/etc/ssh/id_ed25519"
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
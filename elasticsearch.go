@@ -0,0 +1,340 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const driverElasticsearch = "elasticsearch"
+
+// ElasticsearchConfig Elasticsearch 配置结构体（用于从配置文件创建）
+type ElasticsearchConfig struct {
+	Enabled               bool               `yaml:"enabled" env:"ES_ENABLED" default:"true"`
+	Addresses             []string           `yaml:"addresses" env:"ES_ADDRESSES" default:"http://localhost:9200"` // 逗号分隔的节点地址
+	Username              string             `yaml:"username" env:"ES_USERNAME"`
+	Password              string             `yaml:"password" env:"ES_PASSWORD"`
+	PasswordFile          string             `yaml:"password_file" env:"ES_PASSWORD_FILE"`         // 密码文件路径，优先于 Password，仅在创建客户端时读取
+	APIKey                string             `yaml:"api_key" env:"ES_API_KEY"`                     // Base64 编码的 API Key，设置后优先于用户名密码
+	CloudID               string             `yaml:"cloud_id" env:"ES_CLOUD_ID"`                   // Elastic Cloud 部署 ID，设置后忽略 addresses
+	TLSCAFile             string             `yaml:"tls_ca_file" env:"ES_TLS_CA_FILE"`             // 服务端 CA 证书
+	TLSInsecure           bool               `yaml:"tls_insecure" env:"ES_TLS_INSECURE"`           // 跳过服务端证书校验，仅用于测试环境
+	MaxRetries            int                `yaml:"max_retries" env:"ES_MAX_RETRIES" default:"3"` // 最大重试次数，-1 表示不重试
+	MinRetryBackoff       pkgConfig.Duration `yaml:"min_retry_backoff" env:"ES_MIN_RETRY_BACKOFF" default:"100ms"`
+	MaxRetryBackoff       pkgConfig.Duration `yaml:"max_retry_backoff" env:"ES_MAX_RETRY_BACKOFF" default:"5s"`
+	RetryOnStatus         []int              `yaml:"retry_on_status" env:"ES_RETRY_ON_STATUS" default:"502,503,504"` // 需要重试的 HTTP 状态码
+	DiscoverNodesOnStart  bool               `yaml:"discover_nodes_on_start" env:"ES_DISCOVER_NODES_ON_START"`       // 启动时嗅探集群节点
+	DiscoverNodesInterval pkgConfig.Duration `yaml:"discover_nodes_interval" env:"ES_DISCOVER_NODES_INTERVAL"`       // 定期嗅探集群节点的间隔，0 表示不嗅探
+	CompressRequestBody   bool               `yaml:"compress_request_body" env:"ES_COMPRESS_REQUEST_BODY"`
+	RequestTimeout        pkgConfig.Duration `yaml:"request_timeout" env:"ES_REQUEST_TIMEOUT" default:"5s"` // 连接检查的超时
+	EnableTrace           bool               `yaml:"enable_trace" env:"ES_ENABLE_TRACE" default:"true"`
+}
+
+// Validate 验证 Elasticsearch 配置
+func (c *ElasticsearchConfig) Validate() error {
+	if c == nil {
+		return fmt.Errorf("elasticsearch config cannot be nil")
+	}
+	if !c.Enabled {
+		return nil // 如果未启用，不需要验证
+	}
+	if c.CloudID == "" && len(c.Addresses) == 0 {
+		return fmt.Errorf("elasticsearch addresses or cloud_id is required")
+	}
+	for _, addr := range c.Addresses {
+		if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+			return fmt.Errorf("elasticsearch address must start with http:// or https://, got %s", addr)
+		}
+	}
+	if c.MaxRetries < -1 {
+		return fmt.Errorf("elasticsearch max_retries must be -1 or non-negative, got %d", c.MaxRetries)
+	}
+	if minBackoff, maxBackoff := c.MinRetryBackoff.Duration(), c.MaxRetryBackoff.Duration(); minBackoff > 0 && maxBackoff > 0 && minBackoff > maxBackoff {
+		return fmt.Errorf("elasticsearch min_retry_backoff (%s) must not exceed max_retry_backoff (%s)", minBackoff, maxBackoff)
+	}
+	return nil
+}
+
+// ToOptions 转换为 ElasticsearchOptions
+func (c *ElasticsearchConfig) ToOptions() (*ElasticsearchOptions, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if !c.Enabled {
+		return nil, fmt.Errorf("elasticsearch is not enabled")
+	}
+
+	password, _, err := resolvePassword(c.Password, c.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("elasticsearch: %w", err)
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLSCAFile != "" || c.TLSInsecure {
+		tlsConfig = &tls.Config{InsecureSkipVerify: c.TLSInsecure}
+		if c.TLSCAFile != "" {
+			pem, err := os.ReadFile(c.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("elasticsearch: failed to read tls_ca_file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("elasticsearch: no certificates found in %s", c.TLSCAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+
+	return &ElasticsearchOptions{
+		Addresses:             append([]string(nil), c.Addresses...),
+		Username:              c.Username,
+		Password:              password,
+		APIKey:                c.APIKey,
+		CloudID:               c.CloudID,
+		TLS:                   tlsConfig,
+		MaxRetries:            c.MaxRetries,
+		MinRetryBackoff:       durationOr(c.MinRetryBackoff.Duration(), 100*time.Millisecond),
+		MaxRetryBackoff:       durationOr(c.MaxRetryBackoff.Duration(), 5*time.Second),
+		RetryOnStatus:         append([]int(nil), c.RetryOnStatus...),
+		DiscoverNodesOnStart:  c.DiscoverNodesOnStart,
+		DiscoverNodesInterval: c.DiscoverNodesInterval.Duration(),
+		CompressRequestBody:   c.CompressRequestBody,
+		RequestTimeout:        durationOr(c.RequestTimeout.Duration(), 5*time.Second),
+		EnableTrace:           c.EnableTrace,
+	}, nil
+}
+
+// ElasticsearchOptions 结构体定义了 Elasticsearch 客户端的配置选项（内部使用）
+type ElasticsearchOptions struct {
+	Addresses             []string
+	Username              string
+	Password              string
+	APIKey                string
+	CloudID               string
+	TLS                   *tls.Config // nil 使用系统默认配置
+	MaxRetries            int         // 最大重试次数，0 使用默认值 3，-1 表示不重试
+	MinRetryBackoff       time.Duration
+	MaxRetryBackoff       time.Duration
+	RetryOnStatus         []int
+	DiscoverNodesOnStart  bool
+	DiscoverNodesInterval time.Duration
+	CompressRequestBody   bool
+	RequestTimeout        time.Duration // 连接检查的超时
+	EnableTrace           bool          // 是否启用请求追踪，用于记录请求执行时间
+	ErrorLogInterval      time.Duration // 相同错误日志的去重窗口，0 使用默认值 10s，负数表示不去重
+	SLO                   *SLOOptions   // SLI 指标配置，nil 表示不统计
+}
+
+// NewElasticsearch 根据给定的选项创建一个新的 Elasticsearch 客户端实例
+// 官方客户端会校验服务端的产品标识，OpenSearch 集群请使用 opensearch-go 并通过 NewElasticsearchTransport 接入追踪
+func NewElasticsearch(opts *ElasticsearchOptions) (*elasticsearch.Client, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("elasticsearch options cannot be nil")
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.TLS != nil {
+		base.TLSClientConfig = opts.TLS
+	}
+	var transport http.RoundTripper = base
+	if opts.EnableTrace {
+		transport = NewElasticsearchTransport(base,
+			withDatasource(driverElasticsearch),
+			WithErrorLogInterval(opts.ErrorLogInterval),
+			WithSLO(opts.SLO),
+		)
+	}
+
+	minBackoff := durationOr(opts.MinRetryBackoff, 100*time.Millisecond)
+	maxBackoff := durationOr(opts.MaxRetryBackoff, 5*time.Second)
+	cfg := elasticsearch.Config{
+		Addresses:             opts.Addresses,
+		Username:              opts.Username,
+		Password:              opts.Password,
+		APIKey:                opts.APIKey,
+		CloudID:               opts.CloudID,
+		RetryOnStatus:         opts.RetryOnStatus,
+		DisableRetry:          opts.MaxRetries < 0,
+		MaxRetries:            opts.MaxRetries,
+		DiscoverNodesOnStart:  opts.DiscoverNodesOnStart,
+		DiscoverNodesInterval: opts.DiscoverNodesInterval,
+		CompressRequestBody:   opts.CompressRequestBody,
+		Transport:             transport,
+		RetryBackoff: func(attempt int) time.Duration {
+			d := minBackoff << (attempt - 1)
+			if d <= 0 || d > maxBackoff {
+				return maxBackoff
+			}
+			return d
+		},
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+
+	client, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create elasticsearch client: %w", err)
+	}
+
+	// 测试连接
+	ctx, cancel := context.WithTimeout(context.Background(), durationOr(opts.RequestTimeout, 5*time.Second))
+	defer cancel()
+	res, err := client.Info(client.Info.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to elasticsearch: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to connect to elasticsearch: %s", res.Status())
+	}
+
+	log.Info("Datasource initialized",
+		zap.String("driver", driverElasticsearch),
+		zap.Strings("addresses", opts.Addresses),
+		zap.Bool("cloud", opts.CloudID != ""),
+		zap.String("username", opts.Username),
+		zap.Bool("tls", opts.TLS != nil || opts.CloudID != "" || hasHTTPSAddress(opts.Addresses)),
+		zap.Bool("trace", opts.EnableTrace),
+		zap.Bool("metrics", metrics.IsEnabled()),
+	)
+	return client, nil
+}
+
+// hasHTTPSAddress 是否存在 https 地址
+func hasHTTPSAddress(addresses []string) bool {
+	for _, addr := range addresses {
+		if strings.HasPrefix(addr, "https://") {
+			return true
+		}
+	}
+	return false
+}
+
+// esTransport 包装 HTTP Transport，为每个请求创建 span、记录日志和指标
+type esTransport struct {
+	next         http.RoundTripper
+	errorSampler *errorLogSampler
+	slo          *sloTracker
+}
+
+// NewElasticsearchTransport 返回带追踪、日志和指标的 HTTP Transport
+// 可用于官方客户端之外的兼容客户端（如 opensearch-go），next 为 nil 时使用 http.DefaultTransport
+func NewElasticsearchTransport(next http.RoundTripper, opts ...TraceOption) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	o := newTraceOptions(append([]TraceOption{withDatasource(driverElasticsearch)}, opts...)...)
+	return &esTransport{
+		next:         next,
+		errorSampler: newErrorLogSampler(o.errorLogInterval),
+		slo:          sloTrackerFor(o.datasource, o.slo),
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *esTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation := esOperation(req)
+	ctx, span := pkgtrace.StartSpan(req.Context(), "elasticsearch."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", driverElasticsearch),
+			attribute.String("db.operation", operation),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.URL.Path),
+			attribute.String("server.address", req.URL.Host),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	duration := time.Since(start)
+
+	// 404 表示文档或索引不存在，不视为故障
+	status := "success"
+	if err == nil && res.StatusCode >= 400 && res.StatusCode != http.StatusNotFound {
+		err = fmt.Errorf("elasticsearch: %s", res.Status)
+		status = "error"
+	} else if err != nil {
+		status = "error"
+	}
+
+	span.SetAttributes(attribute.Float64("db.duration_ms", float64(duration.Milliseconds())))
+	if res != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		if t.errorSampler.allow(operation+"|"+err.Error(), zap.String("operation", operation), zap.String("error", err.Error())) {
+			log.FromContext(ctx).Error(
+				"Elasticsearch request failed",
+				zap.String("operation", operation),
+				zap.String("method", req.Method),
+				zap.String("path", req.URL.Path),
+				zap.Duration("duration", duration),
+				zap.Error(err),
+			)
+		}
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+
+	if metrics.IsEnabled() {
+		ElasticsearchRequestTotal.WithLabelValues(operation, status).Inc()
+		ElasticsearchRequestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	}
+	t.slo.record(operation, status == "success", duration)
+
+	// 状态码错误只用于记录，仍然返回原始响应，由客户端处理
+	if res != nil {
+		return res, nil
+	}
+	return nil, err
+}
+
+// esOperation 根据请求路径推断操作名：使用第一个以下划线开头的路径段（如 _search、_bulk），否则使用请求方法
+func esOperation(req *http.Request) string {
+	for _, seg := range strings.Split(strings.Trim(req.URL.Path, "/"), "/") {
+		if strings.HasPrefix(seg, "_") && len(seg) > 1 {
+			return strings.TrimPrefix(seg, "_")
+		}
+	}
+	if req.URL.Path == "" || req.URL.Path == "/" {
+		return "info"
+	}
+	return strings.ToLower(req.Method)
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// ConsistencyNone 普通的 cache-aside：写后删除缓存
+	ConsistencyNone = ""
+	// ConsistencyDoubleDelete 延迟双删：写后立即删除，延迟一段时间后再删除一次
+	ConsistencyDoubleDelete = "double_delete"
+	// ConsistencyVersioned 版本号：写后递增版本，读取方仅在版本未变化时回填缓存
+	ConsistencyVersioned = "versioned"
+)
+
+// versionedSetScript 版本未变化时才写入缓存
+// KEYS[1] 版本键，KEYS[2] 数据键；ARGV[1] 读取前的版本，ARGV[2] 值，ARGV[3] 过期时间（毫秒）
+var versionedSetScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1]) or '0'
+if v ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// EntityCacheOptions 实体缓存配置选项
+type EntityCacheOptions struct {
+	TTL               time.Duration // 缓存过期时间，0 表示不过期
+	Strategy          string        // 读写一致性策略：""、double_delete 或 versioned
+	DoubleDeleteDelay time.Duration // 延迟双删的第二次删除延迟，默认 500ms，应大于一次读库并回填的耗时
+	VersionTTL        time.Duration // 版本键的过期时间，默认 24h，应大于 TTL
+}
+
+// EntityCache 基于 Cache 的 cache-aside 实体缓存
+// 解决写库后并发读取把旧值重新写回缓存的问题
+// Redis Cluster 下使用 versioned 策略时，键中应包含 hash tag，保证版本键与数据键位于同一槽位
+type EntityCache struct {
+	cache *Cache
+	opts  EntityCacheOptions
+}
+
+// NewEntityCache 创建实体缓存
+func NewEntityCache(cache *Cache, opts *EntityCacheOptions) (*EntityCache, error) {
+	if cache == nil {
+		return nil, fmt.Errorf("cache cannot be nil")
+	}
+
+	e := &EntityCache{cache: cache}
+	if opts != nil {
+		e.opts = *opts
+	}
+	switch e.opts.Strategy {
+	case ConsistencyNone, ConsistencyDoubleDelete, ConsistencyVersioned:
+	default:
+		return nil, fmt.Errorf("entity cache strategy must be one of: double_delete, versioned, got %s", e.opts.Strategy)
+	}
+	if e.opts.DoubleDeleteDelay <= 0 {
+		e.opts.DoubleDeleteDelay = 500 * time.Millisecond
+	}
+	if e.opts.VersionTTL <= 0 {
+		e.opts.VersionTTL = 24 * time.Hour
+	}
+	return e, nil
+}
+
+// GetOrLoad 读取缓存，未命中时调用 load 从数据库加载并回填缓存
+func (e *EntityCache) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	val, err := e.cache.Get(ctx, key)
+	if err == nil {
+		return val, nil
+	}
+	if !errors.Is(err, ErrCacheMiss) {
+		return nil, err
+	}
+
+	// 版本号需在读库之前获取，读库期间发生的写入会使回填失效
+	var version string
+	versioned := e.opts.Strategy == ConsistencyVersioned && !e.cache.Degraded()
+	if versioned {
+		version, err = e.version(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	val, err = load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if versioned {
+		err = versionedSetScript.Run(ctx, e.cache.Client(),
+			[]string{versionKey(key), key}, version, val, e.opts.TTL.Milliseconds()).Err()
+	} else {
+		err = e.cache.Set(ctx, key, val, e.opts.TTL)
+	}
+	if err != nil {
+		// 回填失败不影响本次读取
+		log.FromContext(ctx).Warn("Failed to populate entity cache", zap.String("key", key), zap.Error(err))
+	}
+	return val, nil
+}
+
+// Invalidate 在写库成功后调用，按配置的策略使缓存失效
+func (e *EntityCache) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if e.opts.Strategy == ConsistencyVersioned && !e.cache.Degraded() {
+		pipe := e.cache.Client().TxPipeline()
+		for _, key := range keys {
+			vk := versionKey(key)
+			pipe.Incr(ctx, vk)
+			pipe.Expire(ctx, vk, e.opts.VersionTTL)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to bump entity cache version: %w", err)
+		}
+	}
+
+	if err := e.cache.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	if e.opts.Strategy == ConsistencyDoubleDelete {
+		time.AfterFunc(e.opts.DoubleDeleteDelay, func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+			defer cancel()
+			if err := e.cache.Delete(ctx, keys...); err != nil {
+				log.FromContext(ctx).Warn("Entity cache delayed delete failed",
+					zap.Strings("keys", keys), zap.Error(err))
+			}
+		})
+	}
+	return nil
+}
+
+// version 返回键当前的版本号，不存在时为 "0"
+func (e *EntityCache) version(ctx context.Context, key string) (string, error) {
+	v, err := e.cache.Client().Get(ctx, versionKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "0", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get entity cache version: %w", err)
+	}
+	return v, nil
+}
+
+// versionKey 返回数据键对应的版本键
+func versionKey(key string) string {
+	return key + ":version"
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 错误分类，用于告警路由和聚合
+const (
+	ErrorClassTimeout    = "timeout"    // 超过截止时间
+	ErrorClassCanceled   = "canceled"   // context 被取消，通常由调用方中断
+	ErrorClassConnection = "connection" // 连接失败、断开或连接池耗尽
+	ErrorClassConflict   = "conflict"   // 死锁、序列化失败等可通过重试事务解决的冲突
+	ErrorClassConstraint = "constraint" // 违反唯一约束或外键约束
+	ErrorClassOther      = "other"      // 其他错误，如语法错误、权限不足
+)
+
+// ErrorEvent 一次失败的 SQL 语句或 Redis 命令
+type ErrorEvent struct {
+	System      string        // db.system：mysql、postgresql、redis 等
+	Datasource  string        // 数据源标识，设置实例名时为实例名
+	Operation   string        // 操作类型，如 select、insert、get、pipeline
+	Statement   string        // 参数化 SQL 或脱敏后的 Redis 命令，不含参数值
+	Fingerprint string        // Statement 归一化后的哈希，相同结构的语句相同，用于聚合告警
+	Duration    time.Duration // 执行耗时
+	Class       string        // 错误分类，见 ErrorClassTimeout 等
+	Err         error         // 原始错误
+}
+
+// ErrorReporter 错误上报接口，追踪插件和 Redis Hook 在语句或命令失败时调用，用于接入 Sentry、Bugsnag
+// 或内部告警。记录不存在（gorm.ErrRecordNotFound、sql.ErrNoRows、redis.Nil）不视为失败，不会上报。
+// 在语句执行的 goroutine 中同步调用，实现应避免阻塞，需并发安全
+type ErrorReporter interface {
+	ReportError(ctx context.Context, e ErrorEvent)
+}
+
+// ErrorReporterFunc 函数形式的 ErrorReporter
+type ErrorReporterFunc func(ctx context.Context, e ErrorEvent)
+
+// ReportError 调用 f
+func (f ErrorReporterFunc) ReportError(ctx context.Context, e ErrorEvent) {
+	f(ctx, e)
+}
+
+// ClassifyError 返回错误的分类，err 为 nil 时返回空字符串
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	var netErr net.Error
+	var myErr *mysqldriver.MySQLError
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassConnection
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysqldriver.ErrInvalidConn),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, redis.ErrPoolTimeout), errors.Is(err, redis.ErrClosed), errors.Is(err, sql.ErrConnDone):
+		return ErrorClassConnection
+	case errors.Is(err, gorm.ErrDuplicatedKey), errors.Is(err, gorm.ErrForeignKeyViolated),
+		errors.Is(err, ErrDuplicateKey), errors.Is(err, ErrForeignKey):
+		return ErrorClassConstraint
+	case isTxRetryable(err):
+		return ErrorClassConflict
+	case errors.As(err, &myErr):
+		switch myErr.Number {
+		case 1205: // 锁等待超时
+			return ErrorClassTimeout
+		case 1062, 1451, 1452:
+			return ErrorClassConstraint
+		}
+	case errors.As(err, &pgErr):
+		switch {
+		case pgErr.Code == "40P01":
+			return ErrorClassConflict
+		case pgErr.Code == "57014":
+			return ErrorClassTimeout
+		case strings.HasPrefix(pgErr.Code, "23"):
+			return ErrorClassConstraint
+		case strings.HasPrefix(pgErr.Code, "08"):
+			return ErrorClassConnection
+		}
+	}
+	return ErrorClassOther
+}
+
+var (
+	fingerprintString  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	fingerprintNumber  = regexp.MustCompile(`\b-?\d+(?:\.\d+)?\b|\$\d+`)
+	fingerprintList    = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	fingerprintRows    = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+	fingerprintSpace   = regexp.MustCompile(`\s+`)
+	fingerprintReplace = strings.NewReplacer("( ", "(", " )", ")")
+)
+
+// QueryFingerprint 返回语句的指纹：字面量、占位符和 IN 列表归一化后的 FNV-64 哈希（16 位十六进制），
+// 参数不同、IN 列表长度不同但结构相同的语句指纹相同
+func QueryFingerprint(statement string) string {
+	s := fingerprintString.ReplaceAllString(statement, "?")
+	s = fingerprintNumber.ReplaceAllString(s, "?")
+	s = fingerprintSpace.ReplaceAllString(strings.ToLower(strings.TrimSpace(s)), " ")
+	s = fingerprintReplace.Replace(s)
+	s = fingerprintList.ReplaceAllString(s, "(?)")
+	s = fingerprintRows.ReplaceAllString(s, "(?)")
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// redisFingerprintStatement 返回用于计算 Redis 命令指纹的语句：命令名和第一个键，键中的数字由 QueryFingerprint 归一化
+func redisFingerprintStatement(cmd redis.Cmder) string {
+	key, ok := redisCmdKey(cmd)
+	if !ok {
+		return cmd.Name()
+	}
+	return cmd.Name() + " " + key
+}
+
+// isNotFoundError 判断错误是否表示记录或键不存在
+func isNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) || errors.Is(err, redis.Nil)
+}
+
+// reportError 调用错误上报，未设置上报或错误为记录不存在时不调用，上报的 panic 被捕获并记录日志
+func (o *traceOptions) reportError(ctx context.Context, e ErrorEvent) {
+	if o.errorReporter == nil || e.Err == nil || isNotFoundError(e.Err) {
+		return
+	}
+	e.Datasource = o.datasource
+	e.Class = ClassifyError(e.Err)
+	if e.Fingerprint == "" {
+		e.Fingerprint = QueryFingerprint(e.Statement)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			o.logger(ctx).Error("Error reporter panic", zap.Any("panic", r), zap.String("operation", e.Operation))
+		}
+	}()
+	o.errorReporter.ReportError(ctx, e)
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultErrorLogInterval 相同错误日志的默认采样窗口
+	defaultErrorLogInterval = 10 * time.Second
+	// maxSampledErrors 同时跟踪的不同错误数上限，超出后不再去重
+	maxSampledErrors = 1024
+)
+
+// errorLogSampler 对重复的相同错误日志去重：窗口内只记录首次出现，窗口结束时输出被抑制的次数
+type errorLogSampler struct {
+	interval time.Duration
+	mu       sync.Mutex
+	entries  map[string]*sampledError
+}
+
+// sampledError 采样窗口内的错误统计
+type sampledError struct {
+	suppressed int
+	fields     []zap.Field
+}
+
+// newErrorLogSampler 创建错误日志采样器，interval <= 0 时不去重
+func newErrorLogSampler(interval time.Duration) *errorLogSampler {
+	return &errorLogSampler{
+		interval: interval,
+		entries:  make(map[string]*sampledError),
+	}
+}
+
+// allow 判断本次错误是否应记录日志
+// key 标识"相同错误"，fields 用于窗口结束时输出汇总日志
+func (s *errorLogSampler) allow(key string, fields ...zap.Field) bool {
+	if s == nil || s.interval <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.suppressed++
+		return false
+	}
+	if len(s.entries) >= maxSampledErrors {
+		return true
+	}
+
+	s.entries[key] = &sampledError{fields: fields}
+	time.AfterFunc(s.interval, func() { s.flush(key) })
+	return true
+}
+
+// flush 结束采样窗口，如有被抑制的日志则输出汇总
+func (s *errorLogSampler) flush(key string) {
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	delete(s.entries, key)
+	s.mu.Unlock()
+
+	if !ok || e.suppressed == 0 {
+		return
+	}
+	fields := append(e.fields,
+		zap.Int("suppressed", e.suppressed),
+		zap.Duration("interval", s.interval),
+	)
+	log.Error("Repeated error logs suppressed", fields...)
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound 记录不存在
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicateKey 违反主键或唯一约束
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrForeignKey 违反外键约束
+	ErrForeignKey = errors.New("foreign key violation")
+)
+
+// NormalizeError 将 GORM 和驱动的错误转换为与数据库无关的错误，原始错误仍可通过 errors.As 获取
+// 无法识别的错误原样返回
+func NormalizeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var target error
+	var myErr *mysqldriver.MySQLError
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrDuplicateKey), errors.Is(err, ErrForeignKey):
+		return err
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		target = ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		target = ErrDuplicateKey
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		target = ErrForeignKey
+	case errors.As(err, &myErr):
+		switch myErr.Number {
+		case 1062:
+			target = ErrDuplicateKey
+		case 1451, 1452:
+			target = ErrForeignKey
+		}
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case "23505":
+			target = ErrDuplicateKey
+		case "23503":
+			target = ErrForeignKey
+		}
+	}
+	if target == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", target, err)
+}
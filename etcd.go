@@ -0,0 +1,206 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// EtcdConfig etcd 配置结构体（用于从配置文件创建）
+type EtcdConfig struct {
+	Enabled              bool               `yaml:"enabled" env:"ETCD_ENABLED" default:"true"`
+	Endpoints            []string           `yaml:"endpoints" env:"ETCD_ENDPOINTS" default:"localhost:2379"` // 逗号分隔的地址列表
+	Username             string             `yaml:"username" env:"ETCD_USERNAME"`
+	Password             string             `yaml:"password" env:"ETCD_PASSWORD"`
+	PasswordFile         string             `yaml:"password_file" env:"ETCD_PASSWORD_FILE"` // 密码文件路径，优先于 Password，仅在创建客户端时读取
+	DialTimeout          pkgConfig.Duration `yaml:"dial_timeout" env:"ETCD_DIAL_TIMEOUT" default:"5s"`
+	DialKeepAliveTime    pkgConfig.Duration `yaml:"dial_keepalive_time" env:"ETCD_DIAL_KEEPALIVE_TIME" default:"30s"`       // 客户端 keepalive 探测间隔
+	DialKeepAliveTimeout pkgConfig.Duration `yaml:"dial_keepalive_timeout" env:"ETCD_DIAL_KEEPALIVE_TIMEOUT" default:"10s"` // keepalive 探测超时
+	AutoSyncInterval     pkgConfig.Duration `yaml:"auto_sync_interval" env:"ETCD_AUTO_SYNC_INTERVAL"`                       // 自动同步集群成员的间隔，0 表示不同步
+	RequestTimeout       pkgConfig.Duration `yaml:"request_timeout" env:"ETCD_REQUEST_TIMEOUT" default:"5s"`                // 连接检查的超时
+	TLSCertFile          string             `yaml:"tls_cert_file" env:"ETCD_TLS_CERT_FILE"`                                 // 客户端证书
+	TLSKeyFile           string             `yaml:"tls_key_file" env:"ETCD_TLS_KEY_FILE"`                                   // 客户端私钥
+	TLSCAFile            string             `yaml:"tls_ca_file" env:"ETCD_TLS_CA_FILE"`                                     // 服务端 CA 证书
+	TLSInsecure          bool               `yaml:"tls_insecure" env:"ETCD_TLS_INSECURE"`                                   // 跳过服务端证书校验，仅用于测试环境
+	EnableTrace          bool               `yaml:"enable_trace" env:"ETCD_ENABLE_TRACE" default:"true"`
+}
+
+// Validate 验证 etcd 配置
+func (c *EtcdConfig) Validate() error {
+	if c == nil {
+		return fmt.Errorf("etcd config cannot be nil")
+	}
+	if !c.Enabled {
+		return nil // 如果未启用，不需要验证
+	}
+	if len(c.Endpoints) == 0 {
+		return fmt.Errorf("etcd endpoints are required")
+	}
+	for _, ep := range c.Endpoints {
+		if strings.TrimSpace(ep) == "" {
+			return fmt.Errorf("etcd endpoints cannot contain empty values")
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("etcd tls_cert_file and tls_key_file must be set together")
+	}
+	if c.Password != "" && c.Username == "" {
+		return fmt.Errorf("etcd username is required when password is set")
+	}
+	if c.DialTimeout.Duration() < 0 || c.DialKeepAliveTime.Duration() < 0 || c.DialKeepAliveTimeout.Duration() < 0 {
+		return fmt.Errorf("etcd dial timeouts must be non-negative")
+	}
+	return nil
+}
+
+// ToOptions 转换为 EtcdOptions
+func (c *EtcdConfig) ToOptions() (*EtcdOptions, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if !c.Enabled {
+		return nil, fmt.Errorf("etcd is not enabled")
+	}
+
+	password, _, err := resolvePassword(c.Password, c.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %w", err)
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLSCertFile != "" || c.TLSCAFile != "" || c.TLSInsecure {
+		info := transport.TLSInfo{
+			CertFile:           c.TLSCertFile,
+			KeyFile:            c.TLSKeyFile,
+			TrustedCAFile:      c.TLSCAFile,
+			InsecureSkipVerify: c.TLSInsecure,
+		}
+		if tlsConfig, err = info.ClientConfig(); err != nil {
+			return nil, fmt.Errorf("etcd: failed to load tls config: %w", err)
+		}
+	}
+
+	endpoints := make([]string, 0, len(c.Endpoints))
+	for _, ep := range c.Endpoints {
+		endpoints = append(endpoints, strings.TrimSpace(ep))
+	}
+
+	return &EtcdOptions{
+		Endpoints:            endpoints,
+		Username:             c.Username,
+		Password:             password,
+		DialTimeout:          durationOr(c.DialTimeout.Duration(), 5*time.Second),
+		DialKeepAliveTime:    c.DialKeepAliveTime.Duration(),
+		DialKeepAliveTimeout: c.DialKeepAliveTimeout.Duration(),
+		AutoSyncInterval:     c.AutoSyncInterval.Duration(),
+		RequestTimeout:       durationOr(c.RequestTimeout.Duration(), 5*time.Second),
+		TLS:                  tlsConfig,
+		EnableTrace:          c.EnableTrace,
+	}, nil
+}
+
+// EtcdOptions 结构体定义了 etcd 客户端的配置选项（内部使用）
+type EtcdOptions struct {
+	Endpoints            []string
+	Username             string
+	Password             string
+	DialTimeout          time.Duration
+	DialKeepAliveTime    time.Duration
+	DialKeepAliveTimeout time.Duration
+	AutoSyncInterval     time.Duration
+	RequestTimeout       time.Duration // 连接检查的超时
+	TLS                  *tls.Config   // nil 表示不使用 TLS
+	EnableTrace          bool          // 是否通过 gRPC OTel 拦截器追踪请求
+}
+
+// NewEtcd 根据给定的选项创建一个新的 etcd 客户端实例
+func NewEtcd(opts *EtcdOptions) (*clientv3.Client, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("etcd options cannot be nil")
+	}
+	if len(opts.Endpoints) == 0 {
+		return nil, fmt.Errorf("etcd endpoints cannot be empty")
+	}
+
+	var dialOpts []grpc.DialOption
+	if opts.EnableTrace {
+		dialOpts = append(dialOpts, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:            opts.Endpoints,
+		Username:             opts.Username,
+		Password:             opts.Password,
+		DialTimeout:          opts.DialTimeout,
+		DialKeepAliveTime:    opts.DialKeepAliveTime,
+		DialKeepAliveTimeout: opts.DialKeepAliveTimeout,
+		AutoSyncInterval:     opts.AutoSyncInterval,
+		TLS:                  opts.TLS,
+		DialOptions:          dialOpts,
+		Logger:               log.GetLogger().Named("etcd"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create etcd client: %w", err)
+	}
+
+	// 测试连接：clientv3.New 不会等待连接建立，通过查询第一个节点的状态确认集群可用
+	ctx, cancel := context.WithTimeout(context.Background(), durationOr(opts.RequestTimeout, 5*time.Second))
+	defer cancel()
+	status, err := client.Status(ctx, opts.Endpoints[0])
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to etcd: %w", err)
+	}
+
+	log.Info("Datasource initialized",
+		zap.String("driver", "etcd"),
+		zap.Strings("endpoints", etcdHosts(opts.Endpoints)),
+		zap.String("username", opts.Username),
+		zap.String("server_version", status.Version),
+		zap.Bool("tls", opts.TLS != nil),
+		zap.Bool("trace", opts.EnableTrace),
+		zap.Bool("metrics", metrics.IsEnabled()),
+	)
+	return client, nil
+}
+
+// etcdHosts 返回去掉协议前缀的地址列表，用于日志
+func etcdHosts(endpoints []string) []string {
+	hosts := make([]string, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if u, err := url.Parse(ep); err == nil && u.Host != "" {
+			ep = u.Host
+		}
+		hosts = append(hosts, ep)
+	}
+	return hosts
+}
@@ -0,0 +1,290 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// FailoverEvent 多节点连接切换节点的事件
+type FailoverEvent struct {
+	Datasource string    // 数据源标识，如 mysql、postgresql
+	From       string    // 切换前的节点，首次连接时为空
+	To         string    // 切换后的节点
+	Time       time.Time // 切换时间
+}
+
+// failoverSelector 记录多节点连接的当前节点：新连接优先使用当前节点，失败时按顺序尝试其余节点，
+// 成功连接到其他节点时视为故障转移，清空连接池中仍指向旧节点的空闲连接
+type failoverSelector struct {
+	datasource string
+	hosts      []string
+	onFailover func(FailoverEvent)
+
+	mu      sync.Mutex
+	current int
+	known   bool
+	flush   func() // 清空连接池中的空闲连接，连接池创建后设置
+}
+
+// newFailoverSelector 创建节点选择器
+func newFailoverSelector(datasource string, hosts []string, onFailover func(FailoverEvent)) *failoverSelector {
+	return &failoverSelector{datasource: datasource, hosts: hosts, onFailover: onFailover}
+}
+
+// order 返回新连接尝试节点的顺序，从当前节点开始
+func (s *failoverSelector) order() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hosts := make([]string, 0, len(s.hosts))
+	for i := range s.hosts {
+		hosts = append(hosts, s.hosts[(s.current+i)%len(s.hosts)])
+	}
+	return hosts
+}
+
+// connected 记录成功连接的节点，节点变化时触发故障转移
+func (s *failoverSelector) connected(host string) {
+	s.mu.Lock()
+	from := ""
+	if s.known {
+		from = s.hosts[s.current]
+	}
+	for i, h := range s.hosts {
+		if h == host {
+			s.current = i
+		}
+	}
+	changed := s.known && from != host
+	s.known = true
+	flush := s.flush
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	log.Warn("Database failover, switched to another host",
+		zap.String("datasource", s.datasource), zap.String("from", from), zap.String("to", host))
+	if metrics.IsEnabled() {
+		DatabaseFailoverTotal.WithLabelValues(s.datasource, host).Inc()
+	}
+	if flush != nil {
+		flush()
+	}
+	if s.onFailover != nil {
+		s.onFailover(FailoverEvent{Datasource: s.datasource, From: from, To: host, Time: time.Now()})
+	}
+}
+
+// invalidate 当前节点不再可写（如主库被降级为只读）时清空空闲连接，使新连接重新选择节点
+func (s *failoverSelector) invalidate() {
+	s.mu.Lock()
+	flush := s.flush
+	s.mu.Unlock()
+	if flush != nil {
+		flush()
+	}
+}
+
+// attach 关联连接池，用于故障转移后清空空闲连接
+func (s *failoverSelector) attach(sqlDB *sql.DB, maxIdle int) {
+	if maxIdle <= 0 {
+		maxIdle = 2 // database/sql 的默认值
+	}
+	s.mu.Lock()
+	s.flush = func() {
+		sqlDB.SetMaxIdleConns(0)
+		sqlDB.SetMaxIdleConns(maxIdle)
+	}
+	s.mu.Unlock()
+}
+
+// failoverConnector 按节点选择器的顺序建立连接的 driver.Connector
+type failoverConnector struct {
+	selector *failoverSelector
+	driver   driver.Driver
+	connect  func(ctx context.Context, host string) (driver.Conn, error)
+}
+
+// Connect 依次尝试节点，返回第一个可用节点的连接
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var errs []error
+	for _, host := range c.selector.order() {
+		conn, err := c.connect(ctx, host)
+		if err == nil {
+			c.selector.connected(host)
+			return conn, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", host, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("failed to connect to any host: %w", errors.Join(errs...))
+}
+
+// Driver 返回底层驱动
+func (c *failoverConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// newMySQLFailoverConnector 创建 MySQL 多节点连接器，read-write 时只接受 read_only=OFF 的节点
+func newMySQLFailoverConnector(cfg *mysqldriver.Config, opts *Options) (*failoverConnector, error) {
+	connectors := make(map[string]driver.Connector, len(opts.Hosts))
+	for _, host := range opts.Hosts {
+		c := cfg.Clone()
+		c.Addr = host
+		connector, err := mysqldriver.NewConnector(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mysql connector for %s: %w", host, err)
+		}
+		connectors[host] = connector
+	}
+
+	checkWritable := opts.TargetSessionAttrs == "read-write"
+	return &failoverConnector{
+		selector: newFailoverSelector(mySQLDriver(opts), opts.Hosts, opts.OnFailover),
+		driver:   mysqldriver.MySQLDriver{},
+		connect: func(ctx context.Context, host string) (driver.Conn, error) {
+			conn, err := connectors[host].Connect(ctx)
+			if err != nil || !checkWritable {
+				return conn, err
+			}
+			readOnly, err := mySQLReadOnly(ctx, conn)
+			if err == nil && readOnly {
+				err = fmt.Errorf("host is read-only")
+			}
+			if err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		},
+	}, nil
+}
+
+// mySQLReadOnly 查询节点是否只读
+func mySQLReadOnly(ctx context.Context, conn driver.Conn) (bool, error) {
+	queryer, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return false, fmt.Errorf("mysql connection does not support queries")
+	}
+	rows, err := queryer.QueryContext(ctx, "SELECT @@global.read_only", nil)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		if err == io.EOF {
+			return false, fmt.Errorf("empty result of read_only")
+		}
+		return false, err
+	}
+	switch v := dest[0].(type) {
+	case int64:
+		return v != 0, nil
+	case []byte:
+		return string(v) != "0", nil
+	default:
+		return false, fmt.Errorf("unexpected read_only value %v", v)
+	}
+}
+
+// newPostgreSQLFailoverConnector 创建 PostgreSQL 多节点连接器，节点类型由 DSN 中的 target_session_attrs 校验
+func newPostgreSQLFailoverConnector(opts *PostgreSQLOptions) (*failoverConnector, error) {
+	connectors := make(map[string]driver.Connector, len(opts.Hosts))
+	for _, host := range opts.Hosts {
+		o := *opts
+		o.Hosts = []string{host}
+		cfg, err := pgx.ParseConfig(postgreSQLDSN(&o))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse postgresql dsn: %w", err)
+		}
+		connectors[host] = stdlib.GetConnector(*cfg, stdlib.OptionBeforeConnect(postgreSQLBeforeConnect(opts.PasswordProvider, opts.Dialer)))
+	}
+
+	return &failoverConnector{
+		selector: newFailoverSelector(postgreSQLDriver(opts), opts.Hosts, opts.OnFailover),
+		driver:   stdlib.GetDefaultDriver(),
+		connect: func(ctx context.Context, host string) (driver.Conn, error) {
+			return connectors[host].Connect(ctx)
+		},
+	}, nil
+}
+
+// postgreSQLFailover 判断是否按主库故障转移的方式选择节点，否则沿用随机选择节点的负载分摊方式
+func postgreSQLFailover(opts *PostgreSQLOptions) bool {
+	return len(opts.Hosts) > 1 && (opts.TargetSessionAttrs == "read-write" || opts.TargetSessionAttrs == "primary")
+}
+
+// isReadOnlyError 判断错误是否由节点只读引起（连接的主库已被降级）
+func isReadOnlyError(err error) bool {
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		// 1290: --read-only 阻止执行，1836: 只读模式
+		return myErr.Number == 1290 || myErr.Number == 1836
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "25006" // read_only_sql_transaction
+	}
+	return false
+}
+
+// failoverPlugin 写入遇到只读错误时清空空闲连接，使后续连接重新选择主库
+type failoverPlugin struct {
+	selector *failoverSelector
+}
+
+// Name 返回插件名称
+func (p *failoverPlugin) Name() string {
+	return "FailoverPlugin"
+}
+
+// Initialize 注册回调
+func (p *failoverPlugin) Initialize(db *gorm.DB) error {
+	_ = db.Callback().Create().After("gorm:create").Register("failover:detect", p.detect)
+	_ = db.Callback().Update().After("gorm:update").Register("failover:detect", p.detect)
+	_ = db.Callback().Delete().After("gorm:delete").Register("failover:detect", p.detect)
+	_ = db.Callback().Raw().After("gorm:raw").Register("failover:detect", p.detect)
+	return nil
+}
+
+// detect 检查语句错误
+func (p *failoverPlugin) detect(db *gorm.DB) {
+	if db.Error != nil && isReadOnlyError(db.Error) {
+		p.selector.invalidate()
+	}
+}
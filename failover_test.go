@@ -0,0 +1,124 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDriverConn 只用于标识连接到的节点
+type fakeDriverConn struct {
+	host string
+}
+
+func (c *fakeDriverConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeDriverConn) Close() error {
+	return nil
+}
+
+func (c *fakeDriverConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestFailoverConnector(t *testing.T) {
+	down := map[string]bool{}
+	var events []FailoverEvent
+	flushes := 0
+	selector := newFailoverSelector("mysql", []string{"db-1", "db-2", "db-3"}, func(e FailoverEvent) { events = append(events, e) })
+	selector.flush = func() { flushes++ }
+	c := &failoverConnector{
+		selector: selector,
+		connect: func(_ context.Context, host string) (driver.Conn, error) {
+			if down[host] {
+				return nil, errors.New("connection refused")
+			}
+			return &fakeDriverConn{host: host}, nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		down       []string
+		wantHost   string
+		wantErr    bool
+		wantOrder  []string
+		wantEvents int
+	}{
+		{name: "first connection", wantHost: "db-1", wantOrder: []string{"db-1", "db-2", "db-3"}},
+		{name: "current host down", down: []string{"db-1"}, wantHost: "db-2", wantOrder: []string{"db-2", "db-3", "db-1"}, wantEvents: 1},
+		{name: "stays on current host", wantHost: "db-2", wantOrder: []string{"db-2", "db-3", "db-1"}, wantEvents: 1},
+		{name: "wraps around", down: []string{"db-2", "db-3"}, wantHost: "db-1", wantOrder: []string{"db-1", "db-2", "db-3"}, wantEvents: 2},
+		{name: "all hosts down", down: []string{"db-1", "db-2", "db-3"}, wantErr: true, wantOrder: []string{"db-1", "db-2", "db-3"}, wantEvents: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(down)
+			for _, h := range tt.down {
+				down[h] = true
+			}
+			conn, err := c.Connect(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Connect error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && conn.(*fakeDriverConn).host != tt.wantHost {
+				t.Errorf("connected to %s, want %s", conn.(*fakeDriverConn).host, tt.wantHost)
+			}
+			if got := selector.order(); !slices.Equal(got, tt.wantOrder) {
+				t.Errorf("order = %v, want %v", got, tt.wantOrder)
+			}
+			if len(events) != tt.wantEvents || flushes != tt.wantEvents {
+				t.Errorf("failover events = %d, flushes = %d, want %d", len(events), flushes, tt.wantEvents)
+			}
+		})
+	}
+	if len(events) == 2 && (events[1].From != "db-2" || events[1].To != "db-1") {
+		t.Errorf("last event = %s -> %s, want db-2 -> db-1", events[1].From, events[1].To)
+	}
+}
+
+func TestIsReadOnlyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "mysql read-only option", err: &mysqldriver.MySQLError{Number: 1290}, want: true},
+		{name: "mysql read-only mode", err: &mysqldriver.MySQLError{Number: 1836}, want: true},
+		{name: "mysql duplicate entry", err: &mysqldriver.MySQLError{Number: 1062}, want: false},
+		{name: "postgresql read-only transaction", err: &pgconn.PgError{Code: "25006"}, want: true},
+		{name: "postgresql unique violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "other error", err: errors.New("connection refused"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReadOnlyError(tt.err); got != tt.want {
+				t.Errorf("isReadOnlyError = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultGenOutPath 生成的查询代码的默认输出目录，对应 gen.Config 的 OutPath
+	DefaultGenOutPath = "./internal/dal/query"
+	// DefaultGenModelPkgPath 生成的模型的默认包名，对应 gen.Config 的 ModelPkgPath
+	DefaultGenModelPkgPath = "model"
+
+	// genImportPath 本包的导入路径，映射到本包类型时需要导入
+	genImportPath = "github.com/go-anyway/framework-db"
+)
+
+// genInternalTables 本包自动创建的内部表，不生成模型
+var genInternalTables = []string{defaultAuditLogTable, "schema_migrations", "idempotency_keys", "backfill_checkpoints", "scheduled_jobs", "counters", "cache_invalidations"}
+
+// Generator gorm.io/gen 的 *gen.Generator 实现的配置方法。本包不依赖 gorm.io/gen，
+// 由使用代码生成的项目创建生成器后交给 ConfigureGenerator 配置：
+//
+//	g := gen.NewGenerator(gen.Config{OutPath: db.DefaultGenOutPath, ModelPkgPath: db.DefaultGenModelPkgPath})
+//	if err := db.ConfigureGenerator(g, &db.GeneratorOptions{DB: gormDB}); err != nil {
+//		return err
+//	}
+//	g.ApplyBasic(g.GenerateAllTable()...)
+//	g.Execute()
+type Generator interface {
+	UseDB(db *gorm.DB)
+	WithTableNameStrategy(ns func(tableName string) string)
+	WithModelNameStrategy(ns func(tableName string) string)
+	WithFileNameStrategy(ns func(tableName string) string)
+	WithJSONTagNameStrategy(ns func(columnName string) string)
+	WithDataTypeMap(newMap map[string]func(columnType gorm.ColumnType) string)
+	WithImportPkgPath(paths ...string)
+}
+
+// GeneratorOptions 代码生成的配置选项
+type GeneratorOptions struct {
+	DB            *gorm.DB // 读取表结构的连接，应由 New、NewPostgreSQL 或 Manager 创建，与业务代码使用相同的插件
+	TablePrefix   string   // 表名前缀，与连接的 NamingStrategy 一致，生成模型名和文件名时去掉
+	SingularTable bool     // 表名为单数形式，与连接的 NamingStrategy 一致
+	SkipTables    []string // 不生成模型的表，本包的内部表（audit_logs、schema_migrations 等）总是跳过
+	CamelJSONTag  bool     // JSON 标签使用小驼峰（userName），默认与列名相同
+	DisableTypes  bool     // 不将 PostgreSQL 数组、hstore、tstzrange 列映射为本包的 StringArray 等类型
+}
+
+// ConfigureGenerator 使用本包的连接和命名约定配置 gorm.io/gen 生成器：
+// 模型名、文件名与 GORM 默认命名策略互逆，生成的模型通过同一连接访问时表名不变；
+// 生成的查询代码使用 opts.DB 执行，因此保留连接上的追踪、超时、熔断等插件
+func ConfigureGenerator(g Generator, opts *GeneratorOptions) error {
+	if g == nil {
+		return fmt.Errorf("generator cannot be nil")
+	}
+	if opts == nil || opts.DB == nil {
+		return fmt.Errorf("generator db cannot be nil")
+	}
+
+	skip := make(map[string]bool, len(genInternalTables)+len(opts.SkipTables))
+	for _, t := range append(append([]string(nil), genInternalTables...), opts.SkipTables...) {
+		skip[t] = true
+	}
+	naming := schema.NamingStrategy{TablePrefix: opts.TablePrefix, SingularTable: opts.SingularTable}
+
+	g.UseDB(opts.DB)
+	g.WithTableNameStrategy(func(table string) string {
+		if skip[table] {
+			return "" // 返回空字符串时 gen 跳过该表
+		}
+		return table
+	})
+	g.WithModelNameStrategy(naming.SchemaName)
+	g.WithFileNameStrategy(func(table string) string {
+		return naming.ColumnName("", naming.SchemaName(table))
+	})
+	if opts.CamelJSONTag {
+		g.WithJSONTagNameStrategy(lowerCamelCase)
+	}
+	if !opts.DisableTypes && dialectOf(opts.DB) == "postgres" {
+		typeOf := func(t string) func(gorm.ColumnType) string {
+			return func(gorm.ColumnType) string { return t }
+		}
+		g.WithDataTypeMap(map[string]func(gorm.ColumnType) string{
+			"_text":     typeOf("db.StringArray"),
+			"_varchar":  typeOf("db.StringArray"),
+			"_int8":     typeOf("db.Int64Array"),
+			"hstore":    typeOf("db.HStore"),
+			"tstzrange": typeOf("db.TimeRange"),
+		})
+		g.WithImportPkgPath(genImportPath)
+	}
+	return nil
+}
+
+// lowerCamelCase 将 snake_case 列名转换为小驼峰，如 user_name 转换为 userName
+func lowerCamelCase(column string) string {
+	parts := strings.Split(column, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
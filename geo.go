@@ -0,0 +1,223 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// GeoUnitMeters 米
+	GeoUnitMeters = "m"
+	// GeoUnitKilometers 千米
+	GeoUnitKilometers = "km"
+	// GeoUnitMiles 英里
+	GeoUnitMiles = "mi"
+	// GeoUnitFeet 英尺
+	GeoUnitFeet = "ft"
+)
+
+// GeoOptions 地理位置集合配置选项
+type GeoOptions struct {
+	Unit string // 距离单位：m、km、mi、ft，默认 km
+}
+
+// GeoLocation 成员及其坐标
+type GeoLocation struct {
+	Member    string  `json:"member"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// GeoResult 查询结果，Distance 为到查询中心的距离
+type GeoResult struct {
+	Member    string  `json:"member"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+	Distance  float64 `json:"distance"` // 单位与查询的单位一致
+}
+
+// GeoQuery 范围查询条件，中心为 Member 或经纬度，范围为半径或矩形
+type GeoQuery struct {
+	Member    string  // 以已有成员为中心，设置时忽略 Longitude 和 Latitude
+	Longitude float64 // 中心经度
+	Latitude  float64 // 中心纬度
+	Radius    float64 // 半径，与 Width/Height 二选一
+	Width     float64 // 矩形宽度
+	Height    float64 // 矩形高度
+	Unit      string  // 半径和距离的单位，默认使用 GeoOptions.Unit
+	Desc      bool    // 按距离从远到近排序，默认从近到远
+}
+
+// Geo 基于 Redis GEO 命令的地理位置集合，用于“附近的门店”等按距离查询的场景。
+// 命令通过传入的客户端执行，与其他命令一样经过追踪、熔断和指标 Hook
+type Geo struct {
+	client redis.UniversalClient
+	key    string
+	unit   string
+}
+
+// NewGeo 创建地理位置集合，key 为 Redis 中保存位置的有序集合键
+func NewGeo(client redis.UniversalClient, key string, opts *GeoOptions) (*Geo, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("geo key is required")
+	}
+	g := &Geo{client: client, key: key, unit: GeoUnitKilometers}
+	if opts != nil && opts.Unit != "" {
+		if err := validateGeoUnit(opts.Unit); err != nil {
+			return nil, err
+		}
+		g.unit = opts.Unit
+	}
+	return g, nil
+}
+
+// Key 返回集合的键
+func (g *Geo) Key() string {
+	return g.key
+}
+
+// Add 添加或更新成员的位置，返回新增的成员数
+func (g *Geo) Add(ctx context.Context, locations ...GeoLocation) (int64, error) {
+	if len(locations) == 0 {
+		return 0, nil
+	}
+	locs := make([]*redis.GeoLocation, len(locations))
+	for i, l := range locations {
+		locs[i] = &redis.GeoLocation{Name: l.Member, Longitude: l.Longitude, Latitude: l.Latitude}
+	}
+	return g.client.GeoAdd(ctx, g.key, locs...).Result()
+}
+
+// Remove 删除成员，返回删除的成员数
+func (g *Geo) Remove(ctx context.Context, members ...string) (int64, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
+	return g.client.ZRem(ctx, g.key, stringArgs(members)...).Result()
+}
+
+// Position 返回成员的坐标，结果与 members 一一对应，不存在的成员为 nil
+func (g *Geo) Position(ctx context.Context, members ...string) ([]*GeoLocation, error) {
+	if len(members) == 0 {
+		return nil, nil
+	}
+	positions, err := g.client.GeoPos(ctx, g.key, members...).Result()
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]*GeoLocation, len(members))
+	for i, pos := range positions {
+		if pos != nil && i < len(members) {
+			locations[i] = &GeoLocation{Member: members[i], Longitude: pos.Longitude, Latitude: pos.Latitude}
+		}
+	}
+	return locations, nil
+}
+
+// Distance 返回两个成员之间的距离，unit 为空时使用默认单位，任一成员不存在时返回 ErrNotFound
+func (g *Geo) Distance(ctx context.Context, from, to, unit string) (float64, error) {
+	if unit == "" {
+		unit = g.unit
+	}
+	if err := validateGeoUnit(unit); err != nil {
+		return 0, err
+	}
+	dist, err := g.client.GeoDist(ctx, g.key, from, to, unit).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrNotFound
+	}
+	return dist, err
+}
+
+// Search 按距离排序查询范围内的成员并偏移分页，page 从 1 开始，每页条数按 Paginator 规范化。
+// GEOSEARCH 不支持偏移，第 page 页需要读取前 page*size 条结果，不返回总条数，深分页应缩小查询范围
+func (g *Geo) Search(ctx context.Context, q *GeoQuery, p *Paginator, page, size int) (*Page[GeoResult], error) {
+	if q == nil {
+		return nil, fmt.Errorf("geo query cannot be nil")
+	}
+	unit := q.Unit
+	if unit == "" {
+		unit = g.unit
+	}
+	if err := validateGeoUnit(unit); err != nil {
+		return nil, err
+	}
+	if q.Radius <= 0 && (q.Width <= 0 || q.Height <= 0) {
+		return nil, fmt.Errorf("geo query requires a positive radius or width and height")
+	}
+	size = p.size(size)
+	page = max(page, 1)
+	offset := (page - 1) * size
+
+	query := redis.GeoSearchQuery{
+		Member:    q.Member,
+		Longitude: q.Longitude,
+		Latitude:  q.Latitude,
+		Sort:      "ASC",
+		Count:     offset + size + 1, // 多取一条判断是否还有下一页
+	}
+	if q.Desc {
+		query.Sort = "DESC"
+	}
+	if q.Radius > 0 {
+		query.Radius, query.RadiusUnit = q.Radius, unit
+	} else {
+		query.BoxWidth, query.BoxHeight, query.BoxUnit = q.Width, q.Height, unit
+	}
+
+	locations, err := g.client.GeoSearchLocation(ctx, g.key, &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: query,
+		WithCoord:      true,
+		WithDist:       true,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("geo search %s: %w", g.key, err)
+	}
+
+	result := &Page[GeoResult]{Items: []GeoResult{}, Page: page, Size: size}
+	if len(locations) > offset+size {
+		locations = locations[:offset+size]
+		result.HasMore = true
+	}
+	for i := offset; i < len(locations); i++ {
+		l := locations[i]
+		result.Items = append(result.Items, GeoResult{Member: l.Name, Longitude: l.Longitude, Latitude: l.Latitude, Distance: l.Dist})
+	}
+	return result, nil
+}
+
+// Delete 删除整个集合
+func (g *Geo) Delete(ctx context.Context) error {
+	return g.client.Del(ctx, g.key).Err()
+}
+
+// validateGeoUnit 检查距离单位
+func validateGeoUnit(unit string) error {
+	switch unit {
+	case GeoUnitMeters, GeoUnitKilometers, GeoUnitMiles, GeoUnitFeet:
+		return nil
+	}
+	return fmt.Errorf("geo unit must be m, km, mi or ft, got %q", unit)
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// levelLogger 可在运行中调整级别的 GORM 日志器。gorm.Config.Logger 在每次执行语句时被并发读取，
+// 不能直接替换，热更新通过 setLevel 原子地切换内部的日志器
+type levelLogger struct {
+	base    logger.Interface                 // 原始日志器，按级别派生新日志器
+	current atomic.Pointer[logger.Interface] // 当前级别的日志器
+}
+
+// newLevelLogger 创建可调整级别的日志器，调整级别前直接使用 base
+func newLevelLogger(base logger.Interface) *levelLogger {
+	l := &levelLogger{base: base}
+	l.current.Store(&base)
+	return l
+}
+
+// setLevel 切换日志级别，对之后执行的语句生效
+func (l *levelLogger) setLevel(level logger.LogLevel) {
+	current := l.base.LogMode(level)
+	l.current.Store(&current)
+}
+
+func (l *levelLogger) load() logger.Interface {
+	return *l.current.Load()
+}
+
+// LogMode 返回指定级别的独立日志器（如 db.Debug()），不影响连接的日志级别
+func (l *levelLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return l.base.LogMode(level)
+}
+
+// Info 记录信息日志
+func (l *levelLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.load().Info(ctx, msg, args...)
+}
+
+// Warn 记录告警日志
+func (l *levelLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.load().Warn(ctx, msg, args...)
+}
+
+// Error 记录错误日志
+func (l *levelLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.load().Error(ctx, msg, args...)
+}
+
+// Trace 记录语句执行日志
+func (l *levelLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	l.load().Trace(ctx, begin, fc, err)
+}
+
+// ParamsFilter 转发给当前日志器，保持 ParameterizedQueries 等参数过滤行为
+func (l *levelLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if f, ok := l.load().(gorm.ParamsFilter); ok {
+		return f.ParamsFilter(ctx, sql, params...)
+	}
+	return sql, params
+}
+
+// 确保 levelLogger 实现了 GORM 的日志接口
+var (
+	_ logger.Interface  = &levelLogger{}
+	_ gorm.ParamsFilter = &levelLogger{}
+)
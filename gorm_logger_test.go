@@ -0,0 +1,96 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// countingLogger 按级别统计 Trace 调用次数的日志器
+type countingLogger struct {
+	level  logger.LogLevel
+	traces *[logger.Info + 1]atomic.Int64
+}
+
+func (l countingLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return countingLogger{level: level, traces: l.traces}
+}
+func (l countingLogger) Info(context.Context, string, ...interface{})  {}
+func (l countingLogger) Warn(context.Context, string, ...interface{})  {}
+func (l countingLogger) Error(context.Context, string, ...interface{}) {}
+func (l countingLogger) Trace(context.Context, time.Time, func() (string, int64), error) {
+	l.traces[l.level].Add(1)
+}
+
+func TestApplyLogLevel(t *testing.T) {
+	base := countingLogger{level: logger.Warn, traces: new([logger.Info + 1]atomic.Int64)}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: newLevelLogger(base)})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	tests := []struct {
+		level string // 热更新设置的级别，为空表示保持初始级别
+		want  logger.LogLevel
+	}{
+		{"", logger.Warn},
+		{"info", logger.Info},
+		{"error", logger.Error},
+		{"silent", logger.Silent},
+	}
+	for _, tt := range tests {
+		if tt.level != "" {
+			applyLogLevel(db, tt.level)
+		}
+		before := base.traces[tt.want].Load()
+		if err := db.Exec("SELECT 1").Error; err != nil {
+			t.Fatalf("exec: %v", err)
+		}
+		if got := base.traces[tt.want].Load() - before; got != 1 {
+			t.Errorf("level %q: %d traces at level %d, want 1", tt.level, got, tt.want)
+		}
+	}
+
+	// 与执行中的语句并发调整级别，配合 -race 检查数据竞争
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = db.Exec("SELECT 1").Error
+			}
+		}()
+	}
+	for _, level := range []string{"info", "warn", "error", "silent"} {
+		applyLogLevel(db, level)
+	}
+	wg.Wait()
+}
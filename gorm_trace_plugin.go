@@ -18,14 +18,14 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime/pprof"
 	"strings"
 	"time"
 
 	"gorm.io/gorm"
 
-	"github.com/go-anyway/framework-log"
-	"github.com/go-anyway/framework-metrics"
 	pkgtrace "github.com/go-anyway/framework-trace"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -39,34 +39,50 @@ const (
 	callBackAfterName  = "core:after"
 	startTime          = "_start_time"
 	spanKey            = "_span"
+	inFlightKey        = "_in_flight"
+	pprofCtxKey        = "_pprof_ctx"
 )
 
 // GormTracePlugin 定义了一个 GORM 插件，用于追踪 SQL 查询的执行时间（支持 OpenTelemetry）
 type GormTracePlugin struct {
-	enableTrace bool // 是否启用 OpenTelemetry 追踪
+	enableTrace  bool // 是否启用 OpenTelemetry 追踪
+	opts         traceOptions
+	errorSampler *errorLogSampler // 相同错误日志去重
+	slo          *sloTracker      // SLI 统计
 }
 
 // NewGormTracePlugin 创建新的 GORM 追踪插件
-func NewGormTracePlugin(enableTrace bool) *GormTracePlugin {
+func NewGormTracePlugin(enableTrace bool, opts ...TraceOption) *GormTracePlugin {
+	o := newTraceOptions(opts...)
 	return &GormTracePlugin{
-		enableTrace: enableTrace,
+		enableTrace:  enableTrace,
+		opts:         o,
+		errorSampler: newErrorLogSampler(o.errorLogInterval),
+		slo:          sloTrackerFor(o.datasource, o.slo),
 	}
 }
 
+// release 释放 SLI 统计器，连接关闭时调用
+func (op *GormTracePlugin) release() {
+	op.slo.release()
+}
+
 // Name 返回追踪插件的名称
 func (op *GormTracePlugin) Name() string {
 	return "GormTracePlugin"
 }
 
 // Initialize 初始化追踪插件，注册 GORM 回调
+// 追踪覆盖完整的回调链，模型钩子（BeforeCreate、AfterFind 等）和拦截器都在 span 内执行并计入耗时。
+// 追踪不是 Interceptor：拦截器只包装核心回调，模型钩子会落在拦截器之外，因此追踪以回调的形式注册在整个回调链的首尾
 func (op *GormTracePlugin) Initialize(db *gorm.DB) (err error) {
-	// 在操作开始前注册回调
-	_ = db.Callback().Create().Before("gorm:before_create").Register(callBackBeforeName, op.before)
-	_ = db.Callback().Query().Before("gorm:query").Register(callBackBeforeName, op.before)
-	_ = db.Callback().Delete().Before("gorm:before_delete").Register(callBackBeforeName, op.before)
-	_ = db.Callback().Update().Before("gorm:setup_reflect_value").Register(callBackBeforeName, op.before)
-	_ = db.Callback().Row().Before("gorm:row").Register(callBackBeforeName, op.before)
-	_ = db.Callback().Raw().Before("gorm:raw").Register(callBackBeforeName, op.before)
+	// 在操作开始前注册回调（按回调类型区分，用于统计并发中的查询数）
+	_ = db.Callback().Create().Before("gorm:before_create").Register(callBackBeforeName, op.beforeFor("create"))
+	_ = db.Callback().Query().Before("gorm:query").Register(callBackBeforeName, op.beforeFor("query"))
+	_ = db.Callback().Delete().Before("gorm:before_delete").Register(callBackBeforeName, op.beforeFor("delete"))
+	_ = db.Callback().Update().Before("gorm:setup_reflect_value").Register(callBackBeforeName, op.beforeFor("update"))
+	_ = db.Callback().Row().Before("gorm:row").Register(callBackBeforeName, op.beforeFor("row"))
+	_ = db.Callback().Raw().Before("gorm:raw").Register(callBackBeforeName, op.beforeFor("raw"))
 
 	// 在操作结束后注册回调
 	_ = db.Callback().Create().After("gorm:after_create").Register(callBackAfterName, op.after)
@@ -76,36 +92,70 @@ func (op *GormTracePlugin) Initialize(db *gorm.DB) (err error) {
 	_ = db.Callback().Row().After("gorm:row").Register(callBackAfterName, op.after)
 	_ = db.Callback().Raw().After("gorm:raw").Register(callBackAfterName, op.after)
 
-	return
+	// 查询结果行数检查作为拦截器包装核心回调，位于追踪的内层，使错误体现在追踪和日志中
+	return resultGuard{maxRows: op.opts.maxResultRows, abort: op.opts.abortOnLargeResult}.register(db)
 }
 
 // 确保 GormTracePlugin 实现了 gorm.Plugin 接口
 var _ gorm.Plugin = &GormTracePlugin{}
 
+// beforeFor 返回指定回调类型的前置回调，在 before 的基础上增加查询预算检查、pprof 标签和并发查询计数
+func (op *GormTracePlugin) beforeFor(kind string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		// 请求的查询预算已用完且设置了 Enforce 时拒绝执行，后续回调因 db.Error 跳过
+		if err := checkQueryBudget(db); err != nil {
+			_ = db.AddError(err)
+			return
+		}
+		if op.opts.pprofLabels && db.Statement != nil {
+			ctx := db.Statement.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			// 与 pprof.Do 相同，after 中恢复为原 context 的标签
+			db.InstanceSet(pprofCtxKey, ctx)
+			ctx = pprof.WithLabels(ctx, pprof.Labels("db_datasource", op.opts.datasource, "db_operation", getOperationType(db), "db_table", db.Statement.Table))
+			pprof.SetGoroutineLabels(ctx)
+			db.Statement.Context = ctx
+		}
+		op.before(db)
+		if op.opts.metricsEnabled() {
+			op.opts.metrics.addInFlight(db.Statement.Context, op.opts.datasource, kind, 1)
+			db.InstanceSet(inFlightKey, kind)
+		}
+	}
+}
+
 // before 是 GORM 操作开始前的回调函数，记录当前时间并创建追踪 span
 func (op *GormTracePlugin) before(db *gorm.DB) {
 	// 记录开始时间
 	db.InstanceSet(startTime, time.Now())
 
-	// 如果启用了追踪，创建 OpenTelemetry span
-	if op.enableTrace {
-		var ctx context.Context
-		if db.Statement != nil && db.Statement.Context != nil {
-			ctx = db.Statement.Context
-		} else {
-			ctx = context.Background()
-		}
+	var ctx context.Context
+	if db.Statement != nil && db.Statement.Context != nil {
+		ctx = db.Statement.Context
+	} else {
+		ctx = context.Background()
+	}
+
+	// 如果启用了追踪且命中采样，创建 OpenTelemetry span
+	if op.enableTrace && op.opts.traceSampled(ctx) {
 
 		// 确定操作类型
 		operation := getOperationType(db)
 		spanName := "gorm." + operation
 
+		dbSystem := op.opts.dbSystem
+		if dbSystem == "" {
+			dbSystem = "sql" // 通用 SQL 数据库
+		}
+
 		// 创建 span
 		ctx, span := pkgtrace.StartSpan(ctx, spanName,
-			trace.WithAttributes(
-				attribute.String("db.system", "sql"), // 通用 SQL 数据库
+			trace.WithAttributes(op.opts.spanAttributes(
+				attribute.String("db.system", dbSystem),
 				attribute.String("db.operation", operation),
-			),
+			)...),
 		)
 
 		// 保存 span 到实例中
@@ -119,6 +169,17 @@ func (op *GormTracePlugin) before(db *gorm.DB) {
 
 // after 是 GORM 操作结束后的回调函数，计算并记录 SQL 执行时间
 func (op *GormTracePlugin) after(db *gorm.DB) {
+	// 减少并发查询计数（使用前置回调记录的类型，保证增减标签一致）
+	if kind, ok := db.InstanceGet(inFlightKey); ok && kind.(string) != "" {
+		op.opts.metrics.addInFlight(db.Statement.Context, op.opts.datasource, kind.(string), -1)
+		db.InstanceSet(inFlightKey, "")
+	}
+	// 恢复执行前的 pprof 标签
+	if v, ok := db.InstanceGet(pprofCtxKey); ok && v != nil {
+		pprof.SetGoroutineLabels(v.(context.Context))
+		db.InstanceSet(pprofCtxKey, nil)
+	}
+
 	_ts, isExist := db.InstanceGet(startTime)
 	if !isExist {
 		return
@@ -130,14 +191,14 @@ func (op *GormTracePlugin) after(db *gorm.DB) {
 	}
 
 	duration := time.Since(ts)
-	durationSeconds := duration.Seconds()
 
 	// 确定操作类型
 	operation := getOperationType(db)
 
-	// 确定状态（成功或失败）
+	// 确定状态（成功或失败），记录不存在属于正常业务结果，不视为失败
+	failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
 	status := "success"
-	if db.Error != nil {
+	if failed {
 		status = "error"
 	}
 
@@ -156,7 +217,7 @@ func (op *GormTracePlugin) after(db *gorm.DB) {
 				)
 
 				// 设置状态
-				if db.Error != nil {
+				if failed {
 					span.SetStatus(codes.Error, db.Error.Error())
 					span.RecordError(db.Error)
 				} else {
@@ -169,20 +230,60 @@ func (op *GormTracePlugin) after(db *gorm.DB) {
 		}
 	}
 
-	// 记录日志
-	log.FromContext(db.Statement.Context).Info(
-		"SQL cost time",
-		zap.Float64("cost_ms", float64(duration.Microseconds())/1000.0),
-		zap.String("sql", sql),
-		zap.String("operation", operation),
-		zap.String("status", status),
-	)
-
-	// 记录 Prometheus 指标（仅在启用时）
-	if metrics.IsEnabled() {
-		metrics.DatabaseQueryTotal.WithLabelValues(operation, status).Inc()
-		metrics.DatabaseQueryDuration.WithLabelValues(operation).Observe(durationSeconds)
+	// 记录日志，相同错误在采样窗口内只记录一次，避免数据库故障时日志风暴
+	if failed {
+		errMsg := db.Error.Error()
+		if op.errorSampler.allow(operation+"|"+errMsg, zap.String("operation", operation), zap.String("error", errMsg)) {
+			op.opts.logger(db.Statement.Context).Error(
+				"SQL execution failed",
+				zap.Float64("cost_ms", float64(duration.Microseconds())/1000.0),
+				zap.String("sql", sql),
+				zap.String("operation", operation),
+				zap.String("status", status),
+				zap.Error(db.Error),
+			)
+		}
+	} else {
+		op.opts.logger(db.Statement.Context).Info(
+			"SQL cost time",
+			zap.Float64("cost_ms", float64(duration.Microseconds())/1000.0),
+			zap.String("sql", sql),
+			zap.String("operation", operation),
+			zap.String("status", status),
+		)
+	}
+
+	// 记录指标（仅在启用时）
+	dbSystem := op.opts.dbSystem
+	if dbSystem == "" {
+		dbSystem = "sql"
 	}
+	if op.opts.metricsEnabled() {
+		op.opts.metrics.recordQuery(db.Statement.Context, dbSystem, operation, status, duration)
+	}
+	op.opts.recordInstance(db.Statement.Context, dbSystem, operation, status, duration)
+
+	if op.opts.slowQuery > 0 && duration >= op.opts.slowQuery {
+		q := SlowQuery{Datasource: op.opts.datasource, SQL: sql, Operation: operation, Duration: duration, Time: ts}
+		if db.Error != nil {
+			q.Error = db.Error.Error()
+		}
+		recordSlowQuery(op.opts.datasource, q)
+		op.opts.writeSlowQuery(db.Statement.Context, q)
+	}
+
+	recordQueryBudget(db, duration)
+
+	op.opts.reportError(db.Statement.Context, ErrorEvent{
+		System:    dbSystem,
+		Operation: operation,
+		Statement: db.Statement.SQL.String(),
+		Duration:  duration,
+		Err:       db.Error,
+	})
+
+	// 记录 SLI（记录不存在属于正常业务结果，不计为失败）
+	op.slo.record(operation, !failed, duration)
 }
 
 // getOperationType 根据 GORM 的 Statement 确定操作类型
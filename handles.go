@@ -0,0 +1,283 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-anyway/framework-log"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const callBackHandleGuardName = "core:handle_guard"
+
+var (
+	// ErrInvalidHandle 传入的连接为空或不是由连接池创建的
+	ErrInvalidHandle = errors.New("invalid database handle")
+	// ErrHandleClosed 底层连接已关闭
+	ErrHandleClosed = errors.New("database handle is closed")
+)
+
+var (
+	// handleTracking 是否跟踪底层连接的关闭位置
+	handleTracking atomic.Bool
+	// handleStates 被跟踪的底层连接，键为 *sql.DB 或 redis.UniversalClient
+	handleStates sync.Map
+
+	closeHooksMu sync.Mutex
+	// closeHooks 通过 CloseSQLDB、CloseRedis 关闭连接时执行的清理，如释放 SLI 统计器
+	closeHooks = make(map[any][]func())
+)
+
+// SetHandleTracking 设置是否跟踪底层连接的生命周期，建议仅在开发环境开启
+// 开启后 Manager 创建的连接会记录关闭位置：重复关闭返回 ErrHandleClosed，
+// 关闭后继续执行的查询和命令返回带关闭位置的 ErrHandleClosed，便于定位误关闭的代码
+// 只影响开启之后创建的连接
+func SetHandleTracking(enabled bool) {
+	handleTracking.Store(enabled)
+}
+
+// handleState 被跟踪连接的状态
+type handleState struct {
+	owner string // 连接标识，如 mysql:main
+
+	mu       sync.Mutex
+	closed   bool
+	closedAt string // 关闭时的调用栈
+}
+
+// err 返回带关闭位置的 ErrHandleClosed，未关闭时返回 nil
+func (s *handleState) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		return nil
+	}
+	return fmt.Errorf("%w: %s closed at %s", ErrHandleClosed, s.owner, s.closedAt)
+}
+
+// SQLDB 返回 GORM 连接底层的 *sql.DB，不会 panic
+// 返回的 *sql.DB 与 GORM 连接共享连接池：关闭它会使 GORM 连接不可用，
+// 由 Manager 管理的连接不应由调用方关闭
+func SQLDB(db *gorm.DB) (*sql.DB, error) {
+	if db == nil || db.Config == nil || db.Statement == nil || db.Statement.ConnPool == nil {
+		return nil, ErrInvalidHandle
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHandle, err)
+	}
+	if s := stateOf(sqlDB); s != nil {
+		if err := s.err(); err != nil {
+			return nil, err
+		}
+	}
+	return sqlDB, nil
+}
+
+// CloseSQLDB 关闭 GORM 连接底层的连接池，被跟踪的连接重复关闭时返回 ErrHandleClosed
+func CloseSQLDB(db *gorm.DB) error {
+	sqlDB, err := SQLDB(db)
+	if err != nil {
+		return err
+	}
+	return closeHandle(sqlDB, sqlDB.Close)
+}
+
+// CloseRedis 关闭 Redis 客户端，重复关闭时返回 ErrHandleClosed
+func CloseRedis(client redis.UniversalClient) error {
+	if client == nil {
+		return ErrInvalidHandle
+	}
+	err := closeHandle(client, client.Close)
+	if errors.Is(err, redis.ErrClosed) {
+		return fmt.Errorf("%w: %v", ErrHandleClosed, err)
+	}
+	return err
+}
+
+// onHandleClose 注册连接通过 CloseSQLDB 或 CloseRedis 关闭时执行的清理，key 为 *sql.DB 或 Redis 客户端
+func onHandleClose(key any, fn func()) {
+	closeHooksMu.Lock()
+	defer closeHooksMu.Unlock()
+	closeHooks[key] = append(closeHooks[key], fn)
+}
+
+// runCloseHooks 执行并注销连接的清理
+func runCloseHooks(key any) {
+	closeHooksMu.Lock()
+	hooks := closeHooks[key]
+	delete(closeHooks, key)
+	closeHooksMu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// closeHandle 关闭底层连接并记录关闭位置
+func closeHandle(key any, closeFn func() error) error {
+	defer runCloseHooks(key)
+	s := stateOf(key)
+	if s == nil {
+		return closeFn()
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		err := s.err()
+		log.Warn("Database handle closed twice",
+			zap.String("handle", s.owner),
+			zap.String("caller", callerStack(3)),
+			zap.Error(err),
+		)
+		return err
+	}
+	s.closed = true
+	s.closedAt = callerStack(3)
+	s.mu.Unlock()
+	return closeFn()
+}
+
+// stateOf 返回被跟踪连接的状态，未跟踪时返回 nil
+func stateOf(key any) *handleState {
+	if v, ok := handleStates.Load(key); ok {
+		return v.(*handleState)
+	}
+	return nil
+}
+
+// trackGormDB 开启跟踪时记录连接，并注册回调拦截关闭后的查询
+func trackGormDB(db *gorm.DB, owner string) {
+	if !handleTracking.Load() {
+		return
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	s := &handleState{owner: owner}
+	if _, loaded := handleStates.LoadOrStore(sqlDB, s); loaded {
+		return
+	}
+
+	guard := func(db *gorm.DB) {
+		if err := s.err(); err != nil {
+			_ = db.AddError(err)
+		}
+	}
+	cb := db.Callback()
+	_ = cb.Create().Before("gorm:create").Register(callBackHandleGuardName, guard)
+	_ = cb.Query().Before("gorm:query").Register(callBackHandleGuardName, guard)
+	_ = cb.Update().Before("gorm:update").Register(callBackHandleGuardName, guard)
+	_ = cb.Delete().Before("gorm:delete").Register(callBackHandleGuardName, guard)
+	_ = cb.Row().Before("gorm:row").Register(callBackHandleGuardName, guard)
+	_ = cb.Raw().Before("gorm:raw").Register(callBackHandleGuardName, guard)
+}
+
+// trackRedis 开启跟踪时记录客户端，并添加 Hook 拦截关闭后的命令
+func trackRedis(client redis.UniversalClient, owner string) {
+	if !handleTracking.Load() {
+		return
+	}
+	s := &handleState{owner: owner}
+	if _, loaded := handleStates.LoadOrStore(client, s); loaded {
+		return
+	}
+	client.AddHook(handleGuardHook{state: s})
+}
+
+// handleGuardHook 拦截已关闭客户端上的命令，返回带关闭位置的错误
+type handleGuardHook struct {
+	state *handleState
+}
+
+// DialHook 在建立连接时调用
+func (h handleGuardHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook 在处理命令时调用
+func (h handleGuardHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.state.err(); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+// ProcessPipelineHook 在处理管道命令时调用
+func (h handleGuardHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := h.state.err(); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// callerStack 返回调用栈的前几帧，跳过 skip 层
+func callerStack(skip int) string {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var parts []string
+	for {
+		frame, more := frames.Next()
+		parts = append(parts, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+	return strings.Join(parts, " <- ")
+}
+
+// SQLDB 返回指定名称连接底层的 *sql.DB
+// 句柄归管理器所有，调用方不应关闭；热更新重建连接后旧句柄会延迟关闭，长期持有时应每次通过该方法获取
+func (m *Manager) SQLDB(name string) (*sql.DB, error) {
+	db, err := m.DB(name)
+	if err != nil {
+		return nil, err
+	}
+	return SQLDB(db)
+}
+
+// UniversalClient 以 redis.UniversalClient 接口返回指定名称的 Redis 客户端
+// 句柄归管理器所有，调用方不应关闭；热更新重建客户端后旧客户端会延迟关闭，长期持有时应每次通过该方法获取
+func (m *Manager) UniversalClient(name string) (redis.UniversalClient, error) {
+	client, err := m.Redis(name)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,250 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 查询提示 scope，按数据库方言注入优化器提示：
+//
+//	db.Scopes(db.UseIndex("idx_user_email"), db.MaxExecutionTime(500*time.Millisecond)).Find(&users)
+//
+// MySQL 使用 /*+ ... */ 优化器提示和 USE/FORCE/IGNORE INDEX 索引提示；
+// PostgreSQL 生成 pg_hint_plan 格式的注释（需要安装 pg_hint_plan 扩展），不支持的提示会被忽略。
+// MySQL 索引提示写在 FROM 子句的表名之后，不能与 Joins 同时使用。
+
+// UseIndex 建议使用指定索引（MySQL USE INDEX，PostgreSQL IndexScan）
+func UseIndex(indexes ...string) func(*gorm.DB) *gorm.DB {
+	return indexHintScope("USE", indexes)
+}
+
+// ForceIndex 强制使用指定索引（MySQL FORCE INDEX，PostgreSQL IndexScan）
+func ForceIndex(indexes ...string) func(*gorm.DB) *gorm.DB {
+	return indexHintScope("FORCE", indexes)
+}
+
+// IgnoreIndex 忽略指定索引（仅 MySQL IGNORE INDEX）
+func IgnoreIndex(indexes ...string) func(*gorm.DB) *gorm.DB {
+	return indexHintScope("IGNORE", indexes)
+}
+
+// MaxExecutionTime 限制查询最长执行时间（仅 MySQL 5.7.8+ 的 SELECT 语句）
+func MaxExecutionTime(d time.Duration) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if dialectOf(db) != "mysql" {
+			return db
+		}
+		return db.Clauses(optimizerHints{hints: []string{fmt.Sprintf("MAX_EXECUTION_TIME(%d)", d.Milliseconds())}})
+	}
+}
+
+// OptimizerHint 添加 MySQL 优化器提示，如 "BKA(t1)"、"SET_VAR(sort_buffer_size = 16M)"
+func OptimizerHint(hint string) func(*gorm.DB) *gorm.DB {
+	return rawHintScope("mysql", hint)
+}
+
+// PGHint 添加 pg_hint_plan 提示，如 "SeqScan(users)"、"Leading(a b)"
+func PGHint(hint string) func(*gorm.DB) *gorm.DB {
+	return rawHintScope("postgres", hint)
+}
+
+// rawHintScope 返回仅对指定方言生效的原始提示 scope
+func rawHintScope(dialect, hint string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if strings.Contains(hint, "*/") {
+			_ = db.AddError(fmt.Errorf("invalid query hint %q: must not contain */", hint))
+			return db
+		}
+		if dialectOf(db) != dialect {
+			return db
+		}
+		return db.Clauses(optimizerHints{hints: []string{hint}})
+	}
+}
+
+// indexHintScope 返回索引提示 scope
+func indexHintScope(kind string, indexes []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, idx := range indexes {
+			if !sqlOptionPattern.MatchString(idx) {
+				_ = db.AddError(fmt.Errorf("invalid index name %q", idx))
+				return db
+			}
+		}
+		if len(indexes) == 0 {
+			return db
+		}
+
+		switch dialectOf(db) {
+		case "mysql":
+			return db.Clauses(indexHints{{kind: kind, indexes: indexes}})
+		case "postgres":
+			if kind == "IGNORE" {
+				return db
+			}
+			return db.Clauses(optimizerHints{indexes: indexes})
+		default:
+			return db
+		}
+	}
+}
+
+// HintScope 查询提示 scope，如 UseIndex、MaxExecutionTime 的返回值
+type HintScope = func(*gorm.DB) *gorm.DB
+
+// queryHintsKey 请求级查询提示的 context 键
+var queryHintsKey = NewContextKey[[]HintScope]("query_hints")
+
+// WithQueryHints 返回携带查询提示的 context，由 HintInterceptor 应用到该 context 下的查询，
+// 适合在调用方为已有的仓储方法追加提示而不修改其中的 GORM 查询链
+func WithQueryHints(ctx context.Context, hints ...HintScope) context.Context {
+	if old, ok := queryHintsKey.From(ctx); ok {
+		hints = append(append([]HintScope(nil), old...), hints...)
+	}
+	return queryHintsKey.WithValue(ctx, hints)
+}
+
+// TableHints 按表名配置的查询提示，键为表名
+type TableHints map[string][]HintScope
+
+// HintInterceptor 返回注入查询提示的拦截器，用于在不改写查询的情况下为热点表统一加提示：
+//
+//	db.Intercept(gormDB, db.HintInterceptor(db.TableHints{
+//		"orders": {db.ForceIndex("idx_orders_created_at"), db.MaxExecutionTime(time.Second)},
+//	}))
+//
+// 对访问 tables 中表的 SELECT、UPDATE、DELETE 语句应用对应提示，并应用 WithQueryHints 携带的提示；
+// 提示按方言生效，与当前方言不符的提示被忽略。Raw、Exec 和已构建 SQL 的语句不受影响
+func HintInterceptor(tables TableHints) Interceptor {
+	return func(next Handler) Handler {
+		return func(db *gorm.DB) {
+			if db.Error == nil && db.Statement.SQL.Len() == 0 {
+				switch OperationKind(db) {
+				case "query", "row", "update", "delete":
+					for _, hint := range tables[db.Statement.Table] {
+						hint(db)
+					}
+					if hints, ok := queryHintsKey.FromDB(db); ok {
+						for _, hint := range hints {
+							hint(db)
+						}
+					}
+				}
+				if db.Error != nil {
+					return
+				}
+			}
+			next(db)
+		}
+	}
+}
+
+// dialectOf 返回当前连接的方言名称
+func dialectOf(db *gorm.DB) string {
+	if db == nil || db.Dialector == nil {
+		return ""
+	}
+	return db.Dialector.Name()
+}
+
+// optimizerHints 优化器提示，MySQL 写在 SELECT/UPDATE 关键字之后，PostgreSQL 写在语句开头
+type optimizerHints struct {
+	hints   []string
+	indexes []string // PostgreSQL 下转换为 IndexScan(table idx...)，表名在构建 SQL 时确定
+}
+
+// ModifyStatement 合并已有提示并设置到对应子句（实现 gorm.StatementModifier 接口）
+func (h optimizerHints) ModifyStatement(stmt *gorm.Statement) {
+	postgres := stmt.DB != nil && dialectOf(stmt.DB) == "postgres"
+	names := []string{"SELECT", "UPDATE"}
+	if postgres {
+		names = append(names, "DELETE")
+	}
+
+	for _, name := range names {
+		c := stmt.Clauses[name]
+		current := &c.AfterNameExpression
+		if postgres {
+			current = &c.BeforeExpression
+		}
+		merged := h
+		if old, ok := (*current).(optimizerHints); ok {
+			merged = optimizerHints{
+				hints:   append(append([]string(nil), old.hints...), h.hints...),
+				indexes: append(append([]string(nil), old.indexes...), h.indexes...),
+			}
+		}
+		*current = merged
+		stmt.Clauses[name] = c
+	}
+}
+
+// Build 构建 /*+ ... */ 提示注释
+func (h optimizerHints) Build(builder clause.Builder) {
+	parts := append([]string(nil), h.hints...)
+	if len(h.indexes) > 0 {
+		table := ""
+		if stmt, ok := builder.(*gorm.Statement); ok {
+			table = stmt.Table
+		}
+		parts = append(parts, fmt.Sprintf("IndexScan(%s %s)", table, strings.Join(h.indexes, " ")))
+	}
+	builder.WriteString("/*+ " + strings.Join(parts, " ") + " */")
+}
+
+// indexHint MySQL 索引提示
+type indexHint struct {
+	kind    string // USE、FORCE、IGNORE
+	indexes []string
+}
+
+// indexHints 写在 FROM 子句之后的 MySQL 索引提示
+type indexHints []indexHint
+
+// ModifyStatement 合并已有索引提示并设置到 FROM 子句（实现 gorm.StatementModifier 接口）
+func (h indexHints) ModifyStatement(stmt *gorm.Statement) {
+	c := stmt.Clauses["FROM"]
+	if old, ok := c.AfterExpression.(indexHints); ok {
+		h = append(append(indexHints(nil), old...), h...)
+	}
+	c.AfterExpression = h
+	stmt.Clauses["FROM"] = c
+}
+
+// Build 构建 USE INDEX (...) 等索引提示
+func (h indexHints) Build(builder clause.Builder) {
+	for i, hint := range h {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(hint.kind + " INDEX (")
+		for j, idx := range hint.indexes {
+			if j > 0 {
+				builder.WriteString(", ")
+			}
+			builder.WriteQuoted(idx)
+		}
+		builder.WriteByte(')')
+	}
+}
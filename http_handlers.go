@@ -0,0 +1,245 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// healthCheckTimeout 健康检查中单个连接 Ping 的超时
+const healthCheckTimeout = 2 * time.Second
+
+// ConnectionHealth 单个连接的健康状态
+type ConnectionHealth struct {
+	Name      string `json:"name"`            // 注册名称
+	Type      string `json:"type"`            // mysql、postgresql、redis、mongo
+	Status    string `json:"status"`          // up 或 down
+	LatencyMs int64  `json:"latency_ms"`      // Ping 耗时
+	Error     string `json:"error,omitempty"` // Ping 失败的原因
+}
+
+// HealthReport 健康检查结果，任一连接不可用时 Status 为 down
+type HealthReport struct {
+	Status      string             `json:"status"`
+	Connections []ConnectionHealth `json:"connections"`
+}
+
+// Health 并发 Ping 所有已注册的连接，返回各连接的状态
+func (m *Manager) Health(ctx context.Context) HealthReport {
+	type check struct {
+		name, kind string
+		ping       func(ctx context.Context) error
+	}
+
+	m.mu.RLock()
+	checks := make([]check, 0, len(m.sql)+len(m.redis)+len(m.mongo))
+	for name, conn := range m.sql {
+		db := conn.db
+		checks = append(checks, check{name: name, kind: conn.driver, ping: func(ctx context.Context) error {
+			sqlDB, err := SQLDB(db)
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		}})
+	}
+	for name, conn := range m.redis {
+		client := conn.client
+		checks = append(checks, check{name: name, kind: "redis", ping: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		}})
+	}
+	for name, client := range m.mongo {
+		checks = append(checks, check{name: name, kind: "mongo", ping: func(ctx context.Context) error {
+			return client.Ping(ctx, readpref.Primary())
+		}})
+	}
+	m.mu.RUnlock()
+
+	report := HealthReport{Status: "up", Connections: make([]ConnectionHealth, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := c.ping(pingCtx)
+			h := ConnectionHealth{Name: c.name, Type: c.kind, Status: "up", LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				h.Status, h.Error = "down", err.Error()
+			}
+			report.Connections[i] = h
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(report.Connections, func(i, j int) bool {
+		a, b := report.Connections[i], report.Connections[j]
+		return a.Type < b.Type || (a.Type == b.Type && a.Name < b.Name)
+	})
+	for _, h := range report.Connections {
+		if h.Status != "up" {
+			report.Status = "down"
+		}
+	}
+	return report
+}
+
+// HealthHandler 返回健康检查的 http.Handler，可挂载到 /healthz：
+// 所有连接可用时返回 200，否则返回 503，响应体为 HealthReport 的 JSON
+func (m *Manager) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := m.Health(r.Context())
+		status := http.StatusOK
+		if report.Status != "up" {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+// connDebugInfo 连接的调试信息
+type connDebugInfo struct {
+	Name   string         `json:"name"`
+	Type   string         `json:"type"`
+	Config map[string]any `json:"config,omitempty"`
+}
+
+// debugReport DebugHandler 的响应
+type debugReport struct {
+	Connections []connDebugInfo        `json:"connections"`
+	Pools       Stats                  `json:"pools"`
+	SlowQueries map[string][]SlowQuery `json:"slow_queries"`
+}
+
+// DebugHandler 返回调试信息的 http.Handler，可挂载到 /debug/db：输出各连接的连接池统计（同 Stats）、
+// 脱敏后的配置（密码、密钥类字段替换为 <redacted>）和按数据源的最近慢查询。
+// 输出包含 SQL 语句和内部配置，应只在内部端口或经过鉴权的路由上暴露
+func (m *Manager) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.debugReport())
+	})
+}
+
+// debugReport 收集调试信息
+func (m *Manager) debugReport() debugReport {
+	report := debugReport{
+		Connections: []connDebugInfo{},
+		Pools:       m.Stats(),
+		SlowQueries: make(map[string][]SlowQuery),
+	}
+
+	m.mu.RLock()
+	for name, conn := range m.sql {
+		info := connDebugInfo{Name: name, Type: conn.driver}
+		if conn.mysql != nil {
+			info.Config = redactConfig(conn.mysql)
+		} else if conn.pg != nil {
+			info.Config = redactConfig(conn.pg)
+		}
+		report.Connections = append(report.Connections, info)
+	}
+	for name, conn := range m.redis {
+		report.Connections = append(report.Connections, connDebugInfo{Name: name, Type: "redis", Config: redactConfig(conn.cfg)})
+	}
+	for name := range m.mongo {
+		report.Connections = append(report.Connections, connDebugInfo{Name: name, Type: "mongo"})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(report.Connections, func(i, j int) bool {
+		a, b := report.Connections[i], report.Connections[j]
+		return a.Type < b.Type || (a.Type == b.Type && a.Name < b.Name)
+	})
+	for _, datasource := range slowQueryDatasources() {
+		report.SlowQueries[datasource] = RecentSlowQueries(datasource)
+	}
+	return report
+}
+
+// redactConfig 将配置结构体转换为以 YAML 键为键的 map，密码、密钥类字段替换为 <redacted>
+func redactConfig(cfg any) map[string]any {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	out := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		key := yamlKey(t.Field(i))
+		field := v.Field(i)
+		switch {
+		case sensitiveConfigKey(key):
+			if !field.IsZero() {
+				out[key] = "<redacted>"
+			}
+		case field.Kind() == reflect.Func || field.Kind() == reflect.Chan || field.Kind() == reflect.Interface:
+			continue
+		case field.Type() == reflect.TypeOf(pkgConfig.Duration(0)):
+			out[key] = formatConfigValue(field.Interface()).(time.Duration).String()
+		case field.Kind() == reflect.Struct || (field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct):
+			if nested := redactConfig(field.Interface()); nested != nil {
+				out[key] = nested
+			}
+		default:
+			out[key] = field.Interface()
+		}
+	}
+	return out
+}
+
+// sensitiveConfigKey 判断配置键是否包含敏感信息
+func sensitiveConfigKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"password", "secret", "token", "credential", "dsn"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
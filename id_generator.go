@@ -0,0 +1,249 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const callBackIDGeneratorName = "core:id_generator"
+
+// 字符串主键的生成算法
+const (
+	IDFormatUUIDv7    = "uuidv7"
+	IDFormatULID      = "ulid"
+	IDFormatSnowflake = "snowflake"
+)
+
+const (
+	// snowflakeEpoch 雪花 ID 的起始时间（2024-01-01 UTC），41 位毫秒时间戳可使用约 69 年
+	snowflakeEpoch        = int64(1704067200000)
+	snowflakeMachineBits  = 10
+	snowflakeSequenceBits = 12
+	// MaxSnowflakeMachineID 雪花 ID 的最大机器 ID
+	MaxSnowflakeMachineID = 1<<snowflakeMachineBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// IDGeneratorConfig 主键自动填充的配置
+type IDGeneratorConfig struct {
+	Enabled   bool   `yaml:"enabled"`    // 新增时为零值的字符串主键（及开启 integer 时的整数主键）生成 ID
+	Format    string `yaml:"format"`     // 字符串主键的生成算法：uuidv7、ulid、snowflake，默认 uuidv7
+	Integer   bool   `yaml:"integer"`    // 为零值的整数主键生成雪花 ID，不开启时整数主键保持数据库自增
+	MachineID int64  `yaml:"machine_id"` // 雪花 ID 的机器 ID，0-1023，同一数据库的不同实例必须不同
+}
+
+// Validate 验证主键自动填充配置
+func (c *IDGeneratorConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Format {
+	case "", IDFormatUUIDv7, IDFormatULID, IDFormatSnowflake:
+	default:
+		return fmt.Errorf("id_generator format must be uuidv7, ulid or snowflake, got %q", c.Format)
+	}
+	if c.MachineID < 0 || c.MachineID > MaxSnowflakeMachineID {
+		return fmt.Errorf("id_generator machine_id must be between 0 and %d, got %d", MaxSnowflakeMachineID, c.MachineID)
+	}
+	return nil
+}
+
+// options 根据配置创建主键自动填充选项，未启用时返回 nil
+func (c *IDGeneratorConfig) options() (*IDGeneratorOptions, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	opts := &IDGeneratorOptions{}
+	var snowflake *Snowflake
+	if c.Format == IDFormatSnowflake || c.Integer {
+		var err error
+		if snowflake, err = NewSnowflake(c.MachineID); err != nil {
+			return nil, err
+		}
+	}
+	switch c.Format {
+	case IDFormatULID:
+		opts.String = NewULID
+	case IDFormatSnowflake:
+		opts.String = func() string { return strconv.FormatUint(snowflake.Next(), 10) }
+	default:
+		opts.String = NewUUIDv7
+	}
+	if c.Integer {
+		opts.Integer = snowflake.Next
+	}
+	return opts, nil
+}
+
+// IDGeneratorOptions 主键自动填充插件的配置选项
+type IDGeneratorOptions struct {
+	String  func() string // 字符串主键的生成函数，默认 NewUUIDv7
+	Integer func() uint64 // 整数主键的生成函数，如 Snowflake.Next，nil 时不填充整数主键
+}
+
+// idGeneratorPlugin 新增前为零值主键生成 ID
+type idGeneratorPlugin struct {
+	opts IDGeneratorOptions
+}
+
+// NewIDGeneratorPlugin 创建主键自动填充插件，通过 db.Use 注册：
+// 新增时为零值的字符串主键和（设置了 Integer 时）整数主键生成 ID，已有值的主键保持不变，
+// 用于代替各模型中重复的 BeforeCreate 钩子。复合主键只填充优先主键
+func NewIDGeneratorPlugin(opts *IDGeneratorOptions) gorm.Plugin {
+	p := &idGeneratorPlugin{}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.String == nil {
+		p.opts.String = NewUUIDv7
+	}
+	return p
+}
+
+// Name 返回插件名称
+func (p *idGeneratorPlugin) Name() string {
+	return "IDGeneratorPlugin"
+}
+
+// Initialize 注册回调，在模型的 BeforeCreate 钩子之后、写入之前填充主键
+func (p *idGeneratorPlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Create().Before("gorm:create").Register(callBackIDGeneratorName, p.fill)
+}
+
+// fill 为语句中每个实体的零值主键生成 ID
+func (p *idGeneratorPlugin) fill(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	var next func() any
+	switch field.FieldType.Kind() {
+	case reflect.String:
+		next = func() any { return p.opts.String() }
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		if p.opts.Integer == nil {
+			return
+		}
+		next = func() any { return p.opts.Integer() }
+	default:
+		return
+	}
+
+	ctx := db.Statement.Context
+	eachAuditRow(db, func(rv reflect.Value) {
+		if _, zero := field.ValueOf(ctx, rv); !zero {
+			return
+		}
+		if err := field.Set(ctx, rv, next()); err != nil {
+			_ = db.AddError(fmt.Errorf("failed to set primary key %s: %w", field.Name, err))
+		}
+	})
+}
+
+// NewUUIDv7 生成 RFC 9562 UUIDv7：48 位毫秒时间戳加随机数，按时间有序，适合作为 B+ 树索引的主键
+func NewUUIDv7() string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	b[6] = 0x70 | b[6]&0x0f // 版本 7
+	b[8] = 0x80 | b[8]&0x3f // RFC 9562 变体
+
+	var out [36]byte
+	hex.Encode(out[0:8], b[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], b[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], b[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], b[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], b[10:])
+	return string(out[:])
+}
+
+// crockfordBase32 ULID 使用的 Crockford Base32 字母表
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID 生成 ULID：48 位毫秒时间戳加 80 位随机数，编码为 26 位 Crockford Base32，按时间有序
+func NewULID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+
+	// 128 位按 5 位一组编码，首字符只使用高 3 位
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// Snowflake 雪花 ID 生成器：41 位毫秒时间戳、10 位机器 ID、12 位序列号，同一机器 ID 内单调递增。
+// 时钟回拨时沿用上次的时间戳继续分配序列号，序列号用尽后等待下一毫秒
+type Snowflake struct {
+	machineID int64
+
+	mu       sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// NewSnowflake 创建雪花 ID 生成器，machineID 取值 0-1023，同时写入同一张表的实例必须使用不同的机器 ID
+func NewSnowflake(machineID int64) (*Snowflake, error) {
+	if machineID < 0 || machineID > MaxSnowflakeMachineID {
+		return nil, fmt.Errorf("snowflake machine id must be between 0 and %d, got %d", MaxSnowflakeMachineID, machineID)
+	}
+	return &Snowflake{machineID: machineID}, nil
+}
+
+// Next 生成下一个 ID
+func (s *Snowflake) Next() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := max(time.Now().UnixMilli()-snowflakeEpoch, s.last)
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & snowflakeMaxSequence
+		if s.sequence == 0 {
+			for now <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Now().UnixMilli() - snowflakeEpoch
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = now
+	return uint64(now<<(snowflakeMachineBits+snowflakeSequenceBits) | s.machineID<<snowflakeSequenceBits | s.sequence)
+}
@@ -0,0 +1,255 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrIdempotencyInProgress 相同 key 的请求正在处理中
+var ErrIdempotencyInProgress = errors.New("idempotent request in progress")
+
+const (
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultIdempotencyLockTTL = time.Minute
+
+	idempotencyPending = "pending"
+	idempotencyDone    = "done"
+)
+
+// IdempotencyStore 记录已处理的请求 key 及其结果
+type IdempotencyStore interface {
+	// Acquire 占用 key：占用成功时返回 done=false；key 已处理完成时返回 done=true 和保存的结果；
+	// 其他请求正在处理时返回 ErrIdempotencyInProgress
+	Acquire(ctx context.Context, key string) (result []byte, done bool, err error)
+	// Complete 保存处理结果，之后相同 key 的请求直接返回该结果
+	Complete(ctx context.Context, key string, result []byte) error
+	// Release 释放处理失败的 key，允许重试
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotent 以 key 去重执行 fn：首次请求执行 fn 并保存结果，重复请求直接返回首次的结果
+// fn 返回错误时不保存结果并释放 key，之后的请求会重新执行；结果以 JSON 保存
+func Idempotent[T any](ctx context.Context, store IdempotencyStore, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	saved, done, err := store.Acquire(ctx, key)
+	if err != nil {
+		return zero, err
+	}
+	if done {
+		var result T
+		if err := json.Unmarshal(saved, &result); err != nil {
+			return zero, fmt.Errorf("idempotency: decode result of %s: %w", key, err)
+		}
+		return result, nil
+	}
+
+	result, err := fn(ctx)
+	if err != nil {
+		// 使用独立的 context 释放，避免调用方 ctx 已取消时 key 一直处于处理中
+		_ = store.Release(context.WithoutCancel(ctx), key)
+		return zero, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		_ = store.Release(context.WithoutCancel(ctx), key)
+		return zero, fmt.Errorf("idempotency: encode result of %s: %w", key, err)
+	}
+	if err := store.Complete(context.WithoutCancel(ctx), key, data); err != nil {
+		return result, fmt.Errorf("idempotency: save result of %s: %w", key, err)
+	}
+	return result, nil
+}
+
+// IdempotencyOptions 幂等存储配置选项
+type IdempotencyOptions struct {
+	Prefix  string        // Redis 键前缀，默认 "idempotency:"；数据库存储时为表名，默认 idempotency_keys
+	TTL     time.Duration // 处理结果的保留时间，默认 24h
+	LockTTL time.Duration // 处理中状态的最长保留时间，超时后视为处理者已崩溃，允许其他请求接管，默认 1m
+}
+
+// withDefaults 返回填充默认值后的配置
+func (o *IdempotencyOptions) withDefaults(prefix string) IdempotencyOptions {
+	var opts IdempotencyOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = prefix
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultIdempotencyTTL
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = defaultIdempotencyLockTTL
+	}
+	return opts
+}
+
+// RedisIdempotencyStore 基于 Redis SET NX 的幂等存储
+type RedisIdempotencyStore struct {
+	client redis.UniversalClient
+	opts   IdempotencyOptions
+}
+
+// releaseScript 仅删除处理中的 key，避免删除已完成的结果
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// NewRedisIdempotencyStore 创建基于 Redis 的幂等存储
+func NewRedisIdempotencyStore(client redis.UniversalClient, opts *IdempotencyOptions) (*RedisIdempotencyStore, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	return &RedisIdempotencyStore{client: client, opts: opts.withDefaults("idempotency:")}, nil
+}
+
+// Acquire 占用 key
+func (s *RedisIdempotencyStore) Acquire(ctx context.Context, key string) ([]byte, bool, error) {
+	k := s.opts.Prefix + key
+	ok, err := s.client.SetNX(ctx, k, idempotencyPending, s.opts.LockTTL).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("idempotency: %w", err)
+	}
+	if ok {
+		return nil, false, nil
+	}
+
+	val, err := s.client.Get(ctx, k).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// 处理中的 key 恰好过期或被释放，重新占用
+		return s.Acquire(ctx, key)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("idempotency: %w", err)
+	}
+	if string(val) == idempotencyPending {
+		return nil, false, ErrIdempotencyInProgress
+	}
+	return val, true, nil
+}
+
+// Complete 保存处理结果
+func (s *RedisIdempotencyStore) Complete(ctx context.Context, key string, result []byte) error {
+	return s.client.Set(ctx, s.opts.Prefix+key, result, s.opts.TTL).Err()
+}
+
+// Release 释放处理中的 key
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return releaseScript.Run(ctx, s.client, []string{s.opts.Prefix + key}, idempotencyPending).Err()
+}
+
+// IdempotencyRecord 幂等 key 的处理记录
+type IdempotencyRecord struct {
+	Key       string    `gorm:"primaryKey;size:191"`
+	Status    string    `gorm:"size:16;not null"`
+	Result    []byte    `gorm:""`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// TableIdempotencyStore 基于去重表的幂等存储，可与业务写操作放在同一数据库中
+type TableIdempotencyStore struct {
+	db   *gorm.DB
+	opts IdempotencyOptions
+}
+
+// NewTableIdempotencyStore 创建基于数据库表的幂等存储，表不存在时自动创建
+// 过期的记录不会自动删除，可定期调用 Purge 清理
+func NewTableIdempotencyStore(db *gorm.DB, opts *IdempotencyOptions) (*TableIdempotencyStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	s := &TableIdempotencyStore{db: db, opts: opts.withDefaults("idempotency_keys")}
+	if err := db.Table(s.opts.Prefix).AutoMigrate(&IdempotencyRecord{}); err != nil {
+		return nil, fmt.Errorf("failed to create idempotency table: %w", err)
+	}
+	return s, nil
+}
+
+// Acquire 占用 key
+func (s *TableIdempotencyStore) Acquire(ctx context.Context, key string) ([]byte, bool, error) {
+	now := time.Now()
+	rec := IdempotencyRecord{Key: key, Status: idempotencyPending, ExpiresAt: now.Add(s.opts.LockTTL)}
+	res := s.db.WithContext(ctx).Table(s.opts.Prefix).Clauses(clause.OnConflict{DoNothing: true}).Create(&rec)
+	if res.Error != nil {
+		return nil, false, fmt.Errorf("idempotency: %w", res.Error)
+	}
+	if res.RowsAffected == 1 {
+		return nil, false, nil
+	}
+
+	// 已有记录过期（处理结果超过保留时间，或处理者崩溃未释放）时接管
+	res = s.db.WithContext(ctx).Table(s.opts.Prefix).
+		Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).
+		Where(clause.Lt{Column: clause.Column{Name: "expires_at"}, Value: now}).
+		Updates(map[string]any{"status": idempotencyPending, "result": nil, "expires_at": now.Add(s.opts.LockTTL)})
+	if res.Error != nil {
+		return nil, false, fmt.Errorf("idempotency: %w", res.Error)
+	}
+	if res.RowsAffected == 1 {
+		return nil, false, nil
+	}
+
+	var existing IdempotencyRecord
+	err := s.db.WithContext(ctx).Table(s.opts.Prefix).Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).Take(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 记录在两次查询之间被释放，重新占用
+		return s.Acquire(ctx, key)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("idempotency: %w", err)
+	}
+	if existing.Status != idempotencyDone {
+		return nil, false, ErrIdempotencyInProgress
+	}
+	return existing.Result, true, nil
+}
+
+// Complete 保存处理结果
+func (s *TableIdempotencyStore) Complete(ctx context.Context, key string, result []byte) error {
+	return s.db.WithContext(ctx).Table(s.opts.Prefix).
+		Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).
+		Updates(map[string]any{"status": idempotencyDone, "result": result, "expires_at": time.Now().Add(s.opts.TTL)}).Error
+}
+
+// Release 释放处理中的 key
+func (s *TableIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Table(s.opts.Prefix).
+		Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).
+		Where(clause.Eq{Column: clause.Column{Name: "status"}, Value: idempotencyPending}).
+		Delete(&IdempotencyRecord{}).Error
+}
+
+// Purge 删除已过期的记录，返回删除的行数
+func (s *TableIdempotencyStore) Purge(ctx context.Context) (int64, error) {
+	res := s.db.WithContext(ctx).Table(s.opts.Prefix).
+		Where(clause.Lt{Column: clause.Column{Name: "expires_at"}, Value: time.Now()}).
+		Delete(&IdempotencyRecord{})
+	return res.RowsAffected, res.Error
+}
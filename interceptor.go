@@ -0,0 +1,148 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const (
+	interceptorPluginName = "core:interceptors"
+	operationKindKey      = "_operation_kind"
+)
+
+// interceptorKinds 可拦截的操作类型，对应 GORM 的核心回调 gorm:<kind>
+var interceptorKinds = []string{"create", "query", "update", "delete", "row", "raw"}
+
+// Handler 执行一次 GORM 操作的语句，结果和错误记录在 db.Statement 和 db.Error 中
+type Handler func(db *gorm.DB)
+
+// Interceptor 包装 next 以在语句执行前后加入横切逻辑，如权限检查、改写语句、打标签，
+// 不调用 next 即可中止执行（应通过 db.AddError 说明原因）
+type Interceptor func(next Handler) Handler
+
+// Intercept 为连接注册拦截器，先注册的位于外层。拦截器包装 GORM 的核心回调（gorm:create、gorm:query 等），
+// 在模型钩子（BeforeCreate 等）之后、语句执行前后运行。追踪不经过拦截器链，而是以回调的形式包围整个回调链，
+// 启用追踪时拦截器和模型钩子都位于追踪 span 内
+func Intercept(db *gorm.DB, interceptors ...Interceptor) error {
+	if db == nil {
+		return fmt.Errorf("gorm db cannot be nil")
+	}
+	p, ok := db.Config.Plugins[interceptorPluginName].(*interceptorPlugin)
+	if !ok {
+		p = &interceptorPlugin{}
+		if err := db.Use(p); err != nil {
+			return fmt.Errorf("failed to register interceptor plugin: %w", err)
+		}
+	}
+	p.add(interceptors...)
+	return nil
+}
+
+// OperationKind 返回拦截器中当前操作的类型：create、query、update、delete、row、raw
+func OperationKind(db *gorm.DB) string {
+	if v, ok := db.InstanceGet(operationKindKey); ok {
+		return v.(string)
+	}
+	return ""
+}
+
+// interceptorPlugin 保存连接上注册的拦截器，替换核心回调为经过拦截器链的版本
+type interceptorPlugin struct {
+	mu           sync.RWMutex
+	core         map[string]Handler // 操作类型 -> 原始核心回调
+	interceptors []Interceptor
+	handlers     map[string]Handler // 操作类型 -> 组合后的处理函数
+}
+
+// Name 返回插件名称
+func (p *interceptorPlugin) Name() string {
+	return interceptorPluginName
+}
+
+// Initialize 替换各操作的核心回调
+func (p *interceptorPlugin) Initialize(db *gorm.DB) error {
+	p.core = make(map[string]Handler, len(interceptorKinds))
+	for _, kind := range interceptorKinds {
+		processor := interceptorProcessor(db, kind)
+		name := "gorm:" + kind
+		core := processor.Get(name)
+		if core == nil {
+			continue
+		}
+		p.core[kind] = core
+		if err := processor.Replace(name, p.wrap(kind, core)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add 追加拦截器并重新组合各操作的处理函数
+func (p *interceptorPlugin) add(interceptors ...Interceptor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interceptors = append(p.interceptors, interceptors...)
+	handlers := make(map[string]Handler, len(p.core))
+	for kind, core := range p.core {
+		h := core
+		for i := len(p.interceptors) - 1; i >= 0; i-- {
+			h = p.interceptors[i](h)
+		}
+		handlers[kind] = h
+	}
+	p.handlers = handlers
+}
+
+// wrap 返回替换核心回调的函数，经过拦截器链执行
+func (p *interceptorPlugin) wrap(kind string, core Handler) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		p.mu.RLock()
+		h := p.handlers[kind]
+		p.mu.RUnlock()
+		if h == nil {
+			core(db)
+			return
+		}
+		db.InstanceSet(operationKindKey, kind)
+		h(db)
+	}
+}
+
+// interceptorProcessor 返回操作类型对应的回调处理器
+func interceptorProcessor(db *gorm.DB, kind string) interface {
+	Get(name string) func(*gorm.DB)
+	Replace(name string, fn func(*gorm.DB)) error
+} {
+	switch kind {
+	case "create":
+		return db.Callback().Create()
+	case "query":
+		return db.Callback().Query()
+	case "update":
+		return db.Callback().Update()
+	case "delete":
+		return db.Callback().Delete()
+	case "row":
+		return db.Callback().Row()
+	default:
+		return db.Callback().Raw()
+	}
+}
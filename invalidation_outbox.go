@@ -0,0 +1,259 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CacheInvalidation 待执行的缓存失效记录，与业务写操作在同一事务中写入
+type CacheInvalidation struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	CacheKey  string    `gorm:"size:512;not null"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+// InvalidationOutboxOptions 缓存失效发件箱配置选项
+type InvalidationOutboxOptions struct {
+	Table      string                                          // 发件箱表名，表不存在时自动创建，默认 cache_invalidations
+	Invalidate func(ctx context.Context, keys ...string) error // 执行失效，默认逐个 DEL，可传入 EntityCache.Invalidate 或 Cache.Delete
+	Interval   time.Duration                                   // 后台补偿的扫描间隔，默认 5s
+	Grace      time.Duration                                   // 记录写入多久后由后台补偿处理，避免与提交后的即时失效重复，默认 10s
+	BatchSize  int                                             // 后台补偿每次处理的记录数，默认 100
+}
+
+// InvalidationOutbox 保证“先写数据库、再删缓存”中的缓存失效最终一定执行：需要失效的键与业务数据在同一事务中
+// 写入发件箱表，提交后立即删除缓存并清理记录；进程在两次写入之间退出或 Redis 暂时不可用时，
+// 由后台补偿（Start）重新执行失效。失效是幂等的，重复执行无副作用
+//
+//	err := outbox.Execute(ctx, func(tx *gorm.DB, invalidate func(keys ...string)) error {
+//		if err := tx.Save(&user).Error; err != nil {
+//			return err
+//		}
+//		invalidate("user:" + user.ID)
+//		return nil
+//	})
+type InvalidationOutbox struct {
+	db     *gorm.DB
+	client redis.UniversalClient
+	opts   InvalidationOutboxOptions
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewInvalidationOutbox 创建缓存失效发件箱，client 仅在未设置 Invalidate 时使用
+func NewInvalidationOutbox(db *gorm.DB, client redis.UniversalClient, opts *InvalidationOutboxOptions) (*InvalidationOutbox, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	o := &InvalidationOutbox{db: db, client: client}
+	if opts != nil {
+		o.opts = *opts
+	}
+	if o.opts.Invalidate == nil {
+		if client == nil {
+			return nil, fmt.Errorf("redis client cannot be nil when Invalidate is not set")
+		}
+		o.opts.Invalidate = o.deleteKeys
+	}
+	if o.opts.Table == "" {
+		o.opts.Table = "cache_invalidations"
+	}
+	if o.opts.Interval <= 0 {
+		o.opts.Interval = 5 * time.Second
+	}
+	if o.opts.Grace <= 0 {
+		o.opts.Grace = 10 * time.Second
+	}
+	if o.opts.BatchSize <= 0 {
+		o.opts.BatchSize = 100
+	}
+	if err := db.Table(o.opts.Table).AutoMigrate(&CacheInvalidation{}); err != nil {
+		return nil, fmt.Errorf("failed to create cache invalidation table: %w", err)
+	}
+	return o, nil
+}
+
+// Execute 在事务中执行 fn，fn 通过 invalidate 登记需要失效的键，键随事务写入发件箱；
+// 提交后立即执行失效，失败时只记录日志，由后台补偿重试。事务按连接配置重试，fn 可能被执行多次
+func (o *InvalidationOutbox) Execute(ctx context.Context, fn func(tx *gorm.DB, invalidate func(keys ...string)) error) error {
+	var records []CacheInvalidation
+	err := ExecuteTx(ctx, o.db, func(tx *gorm.DB) error {
+		records = records[:0]
+		if err := fn(tx, func(keys ...string) {
+			for _, key := range keys {
+				records = append(records, CacheInvalidation{CacheKey: key})
+			}
+		}); err != nil {
+			return err
+		}
+		return o.insert(tx, records)
+	})
+	if err != nil || len(records) == 0 {
+		return err
+	}
+
+	if err := o.apply(context.WithoutCancel(ctx), records); err != nil {
+		recordCacheInvalidation("deferred", len(records))
+		log.FromContext(ctx).Warn("Cache invalidation deferred to outbox relay",
+			zap.Int("keys", len(records)), zap.Error(err))
+		return nil
+	}
+	recordCacheInvalidation("immediate", len(records))
+	return nil
+}
+
+// Enqueue 在调用方的事务 tx 中登记需要失效的键，不立即执行，由后台补偿在 Grace 之后处理。
+// 用于已通过 TxManager 或 ExecuteTx 开启事务、无法使用 Execute 的场景
+func (o *InvalidationOutbox) Enqueue(tx *gorm.DB, keys ...string) error {
+	records := make([]CacheInvalidation, len(keys))
+	for i, key := range keys {
+		records[i] = CacheInvalidation{CacheKey: key}
+	}
+	return o.insert(tx, records)
+}
+
+// Relay 处理写入超过 Grace 的记录，返回处理的记录数，Start 会定期调用
+func (o *InvalidationOutbox) Relay(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		var records []CacheInvalidation
+		err := o.db.WithContext(ctx).Table(o.opts.Table).
+			Where(clause.Lt{Column: clause.Column{Name: "created_at"}, Value: time.Now().Add(-o.opts.Grace)}).
+			Order("id").Limit(o.opts.BatchSize).Find(&records).Error
+		if err != nil {
+			return total, fmt.Errorf("invalidation outbox: load: %w", err)
+		}
+		if len(records) == 0 {
+			return total, nil
+		}
+		if err := o.apply(ctx, records); err != nil {
+			recordCacheInvalidation("failed", len(records))
+			return total, fmt.Errorf("invalidation outbox: relay: %w", err)
+		}
+		recordCacheInvalidation("relayed", len(records))
+		total += len(records)
+		if len(records) < o.opts.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+// Start 在后台定期执行补偿
+func (o *InvalidationOutbox) Start(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cancel != nil {
+		return fmt.Errorf("invalidation outbox relay already started")
+	}
+	ctx, o.cancel = context.WithCancel(ctx)
+	o.done = make(chan struct{})
+	go func() {
+		defer close(o.done)
+		ticker := time.NewTicker(o.opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := o.Relay(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("Failed to relay cache invalidations", zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止后台补偿
+func (o *InvalidationOutbox) Stop(ctx context.Context) error {
+	o.mu.Lock()
+	cancel, done := o.cancel, o.done
+	o.cancel = nil
+	o.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// insert 在事务中写入发件箱记录
+func (o *InvalidationOutbox) insert(tx *gorm.DB, records []CacheInvalidation) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := tx.Table(o.opts.Table).Create(&records).Error; err != nil {
+		return fmt.Errorf("invalidation outbox: enqueue: %w", err)
+	}
+	return nil
+}
+
+// apply 执行失效并删除对应的记录，删除失败时记录保留，之后重复失效无副作用
+func (o *InvalidationOutbox) apply(ctx context.Context, records []CacheInvalidation) error {
+	keys := make([]string, 0, len(records))
+	ids := make([]uint64, len(records))
+	seen := make(map[string]bool, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+		if !seen[r.CacheKey] {
+			seen[r.CacheKey] = true
+			keys = append(keys, r.CacheKey)
+		}
+	}
+	if err := o.opts.Invalidate(ctx, keys...); err != nil {
+		return err
+	}
+	return o.db.WithContext(ctx).Table(o.opts.Table).Where("id IN ?", ids).Delete(&CacheInvalidation{}).Error
+}
+
+// deleteKeys 默认的失效方式：通过管道逐个删除，兼容键分布在不同槽位的 Redis Cluster
+func (o *InvalidationOutbox) deleteKeys(ctx context.Context, keys ...string) error {
+	_, err := o.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+// recordCacheInvalidation 记录缓存失效指标
+func recordCacheInvalidation(result string, n int) {
+	if metrics.IsEnabled() {
+		CacheInvalidationTotal.WithLabelValues(result).Add(float64(n))
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Iterate 逐行流式读取 query 的结果，不会将全部结果加载到内存：
+//
+//	for user, err := range db.Iterate[User](ctx, gdb.Where("status = ?", 1)) {
+//		if err != nil { ... }
+//	}
+//
+// query 未指定 Model 或 Table 时使用 T 作为模型。提前退出循环或 ctx 取消时会关闭底层的 rows；
+// 迭代期间独占一个连接，循环体中不应在同一连接池上执行大量耗时操作
+func Iterate[T any](ctx context.Context, query *gorm.DB) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		q := query.WithContext(ctx)
+		if q.Statement.Model == nil && q.Statement.Table == "" {
+			q = q.Model(new(T))
+		}
+
+		rows, err := q.Rows()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			var v T
+			if err := q.ScanRows(rows, &v); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// CheckpointStore 保存回填任务的进度，用于中断后从上次的位置继续
+type CheckpointStore interface {
+	Load(ctx context.Context, key string) (string, bool, error) // 读取进度，不存在时返回 false
+	Save(ctx context.Context, key, value string) error
+}
+
+// BackfillCheckpoint 回填任务的进度记录
+type BackfillCheckpoint struct {
+	Key       string    `gorm:"primaryKey;size:191"`
+	Value     string    `gorm:"type:text"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// TableCheckpointStore 将回填进度保存在数据库表中
+type TableCheckpointStore struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewTableCheckpointStore 创建基于数据库表的进度存储，表不存在时自动创建，table 为空时使用 backfill_checkpoints
+func NewTableCheckpointStore(db *gorm.DB, table string) (*TableCheckpointStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	if table == "" {
+		table = "backfill_checkpoints"
+	}
+	if err := db.Table(table).AutoMigrate(&BackfillCheckpoint{}); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint table: %w", err)
+	}
+	return &TableCheckpointStore{db: db, table: table}, nil
+}
+
+// Load 读取进度
+func (s *TableCheckpointStore) Load(ctx context.Context, key string) (string, bool, error) {
+	var rec BackfillCheckpoint
+	err := s.db.WithContext(ctx).Table(s.table).Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return rec.Value, true, nil
+}
+
+// Save 保存进度
+func (s *TableCheckpointStore) Save(ctx context.Context, key, value string) error {
+	rec := BackfillCheckpoint{Key: key, Value: value, UpdatedAt: time.Now()}
+	return s.db.WithContext(ctx).Table(s.table).Clauses(clause.OnConflict{UpdateAll: true}).Create(&rec).Error
+}
+
+// FindInBatchesWithCheckpoint 按主键顺序分批处理 query 的结果，每批处理成功后将最后一行的主键保存到 store
+// 再次以相同的 key 调用时从上次保存的主键之后继续，适用于可中断、可恢复的数据回填。
+// 使用主键游标分页而不是 OFFSET，要求 T 只有一个主键且 query 不包含自定义排序
+func FindInBatchesWithCheckpoint[T any](ctx context.Context, query *gorm.DB, store CheckpointStore, key string, batchSize int, fn func(tx *gorm.DB, batch []T) error) error {
+	if store == nil {
+		return fmt.Errorf("checkpoint store cannot be nil")
+	}
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be greater than 0, got %d", batchSize)
+	}
+
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(new(T)); err != nil {
+		return fmt.Errorf("find in batches: %w", err)
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return fmt.Errorf("find in batches: %s must have exactly one primary key", stmt.Schema.Name)
+	}
+
+	// 进度以 JSON 保存，恢复时按主键的类型解码，避免字符串与数字比较
+	var cursor any
+	saved, ok, err := store.Load(ctx, key)
+	if err != nil {
+		return fmt.Errorf("find in batches: load checkpoint: %w", err)
+	}
+	if ok {
+		v := reflect.New(pk.FieldType)
+		if err := json.Unmarshal([]byte(saved), v.Interface()); err != nil {
+			return fmt.Errorf("find in batches: invalid checkpoint %q: %w", saved, err)
+		}
+		cursor = v.Elem().Interface()
+	}
+
+	column := clause.Column{Table: clause.CurrentTable, Name: pk.DBName}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		q := query.Session(&gorm.Session{Context: ctx})
+		if cursor != nil {
+			q = q.Where(clause.Gt{Column: column, Value: cursor})
+		}
+		var batch []T
+		if err := q.Order(clause.OrderByColumn{Column: column}).Limit(batchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("find in batches: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := fn(query.Session(&gorm.Session{NewDB: true, Context: ctx}), batch); err != nil {
+			return err
+		}
+
+		last, _ := pk.ValueOf(ctx, reflect.ValueOf(&batch[len(batch)-1]).Elem())
+		value, err := json.Marshal(last)
+		if err != nil {
+			return fmt.Errorf("find in batches: encode checkpoint: %w", err)
+		}
+		if err := store.Save(ctx, key, string(value)); err != nil {
+			return fmt.Errorf("find in batches: save checkpoint: %w", err)
+		}
+		cursor = last
+
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// jobField Stream 消息中保存任务内容的字段名
+const jobField = "job"
+
+// promoteScript 将到期的延迟任务从有序集合移动到就绪 Stream
+// KEYS[1] 延迟任务有序集合，KEYS[2] 就绪 Stream；ARGV[1] 当前时间（毫秒），ARGV[2] 单次最多移动的任务数
+var promoteScript = redis.NewScript(`
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, job in ipairs(jobs) do
+	redis.call('XADD', KEYS[2], '*', 'job', job)
+	redis.call('ZREM', KEYS[1], job)
+end
+return #jobs
+`)
+
+// Job 队列中的任务
+type Job struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Payload    []byte            `json:"payload,omitempty"`
+	Attempt    int               `json:"attempt"`              // 已执行次数
+	EnqueuedAt time.Time         `json:"enqueued_at"`          // 首次入队时间
+	ReadyAt    time.Time         `json:"ready_at"`             // 计划执行时间
+	LastError  string            `json:"last_error,omitempty"` // 上次执行失败的错误
+	Headers    map[string]string `json:"headers,omitempty"`    // 链路追踪上下文
+}
+
+// JobHandler 处理任务，返回错误时按退避策略重试
+type JobHandler func(ctx context.Context, job *Job) error
+
+// JobQueueOptions 任务队列配置选项
+type JobQueueOptions struct {
+	Name              string        // 队列名称，用作 Redis 键前缀（使用 hash tag 保证 Cluster 下位于同一槽位）
+	Group             string        // 消费者组名称，默认 "workers"
+	Consumer          string        // 消费者名称，默认 hostname-pid
+	Concurrency       int           // 并发处理的 worker 数，默认 1
+	VisibilityTimeout time.Duration // 任务被取走后未确认超过该时间将被其他 worker 重新执行，默认 30s
+	MaxRetries        int           // 最大重试次数，超过后转入死信队列，默认 5
+	MinRetryBackoff   time.Duration // 首次重试间隔，之后按指数增长，默认 1s
+	MaxRetryBackoff   time.Duration // 最大重试间隔，默认 10m
+	PollInterval      time.Duration // 检查到期延迟任务的间隔，默认 1s
+	BatchSize         int64         // 单次移动/读取的最大任务数，默认 100
+}
+
+// JobQueue 基于 Redis 有序集合和 Streams 的延迟任务队列
+// 延迟任务保存在有序集合中，到期后移动到就绪 Stream，由消费者组处理
+type JobQueue struct {
+	client redis.UniversalClient
+	opts   JobQueueOptions
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+
+	consumer *StreamConsumer
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewJobQueue 创建任务队列
+func NewJobQueue(client redis.UniversalClient, opts *JobQueueOptions) (*JobQueue, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if opts == nil || opts.Name == "" {
+		return nil, fmt.Errorf("job queue name is required")
+	}
+
+	q := &JobQueue{
+		client:   client,
+		opts:     *opts,
+		handlers: make(map[string]JobHandler),
+	}
+	if q.opts.Group == "" {
+		q.opts.Group = "workers"
+	}
+	if q.opts.Concurrency <= 0 {
+		q.opts.Concurrency = 1
+	}
+	if q.opts.VisibilityTimeout <= 0 {
+		q.opts.VisibilityTimeout = 30 * time.Second
+	}
+	if q.opts.MaxRetries <= 0 {
+		q.opts.MaxRetries = 5
+	}
+	if q.opts.MinRetryBackoff <= 0 {
+		q.opts.MinRetryBackoff = time.Second
+	}
+	if q.opts.MaxRetryBackoff <= 0 {
+		q.opts.MaxRetryBackoff = 10 * time.Minute
+	}
+	if q.opts.PollInterval <= 0 {
+		q.opts.PollInterval = time.Second
+	}
+	if q.opts.BatchSize <= 0 {
+		q.opts.BatchSize = 100
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	return q, nil
+}
+
+// Handle 注册指定类型任务的处理函数，需在 Start 之前调用
+func (q *JobQueue) Handle(jobType string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Enqueue 将任务加入队列，delay 大于 0 时延迟执行，返回任务 ID
+func (q *JobQueue) Enqueue(ctx context.Context, job *Job, delay time.Duration) (string, error) {
+	if job == nil || job.Type == "" {
+		return "", fmt.Errorf("job type is required")
+	}
+
+	j := *job
+	if j.ID == "" {
+		j.ID = newJobID()
+	}
+	now := time.Now()
+	if j.EnqueuedAt.IsZero() {
+		j.EnqueuedAt = now
+	}
+	j.ReadyAt = now.Add(max(delay, 0))
+
+	// 注入链路追踪上下文，使执行任务的 span 与入队方关联
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		j.Headers = carrier
+	}
+
+	if err := q.schedule(ctx, &j); err != nil {
+		return "", err
+	}
+	recordJob(q.opts.Name, j.Type, "enqueued")
+	return j.ID, nil
+}
+
+// Start 启动延迟任务调度和 worker
+func (q *JobQueue) Start(ctx context.Context) error {
+	consumer, err := NewStreamConsumer(q.client, &StreamConsumerOptions{
+		Streams:          []string{q.readyKey()},
+		Group:            q.opts.Group,
+		Consumer:         q.opts.Consumer,
+		StartID:          "0",
+		Concurrency:      q.opts.Concurrency,
+		BatchSize:        q.opts.BatchSize,
+		MaxRetries:       int64(q.opts.MaxRetries),
+		DeadLetterSuffix: ":dead",
+		ClaimMinIdle:     q.opts.VisibilityTimeout,
+		ClaimInterval:    max(q.opts.VisibilityTimeout/2, time.Second),
+	}, q.process)
+	if err != nil {
+		return err
+	}
+	if err := consumer.Start(ctx); err != nil {
+		return err
+	}
+	q.consumer = consumer
+
+	q.wg.Add(1)
+	go q.promoteLoop()
+	return nil
+}
+
+// Stop 停止调度和 worker，等待处理中的任务完成
+func (q *JobQueue) Stop(ctx context.Context) error {
+	q.cancel()
+	q.wg.Wait()
+	if q.consumer == nil {
+		return nil
+	}
+	return q.consumer.Stop(ctx)
+}
+
+// promoteLoop 周期性将到期的延迟任务移动到就绪 Stream
+func (q *JobQueue) promoteLoop() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for q.ctx.Err() == nil {
+			n, err := promoteScript.Run(q.ctx, q.client,
+				[]string{q.delayedKey(), q.readyKey()},
+				time.Now().UnixMilli(), q.opts.BatchSize).Int64()
+			if err != nil {
+				if q.ctx.Err() == nil {
+					log.Warn("Failed to promote delayed jobs", zap.String("queue", q.opts.Name), zap.Error(err))
+				}
+				break
+			}
+			// 本批已满说明可能还有到期任务，继续移动
+			if n < q.opts.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// process 执行就绪 Stream 中的任务，失败时重新调度或转入死信队列
+func (q *JobQueue) process(ctx context.Context, _ string, msg redis.XMessage) error {
+	raw, _ := msg.Values[jobField].(string)
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		log.Error("Invalid job payload, moving to dead letter queue",
+			zap.String("queue", q.opts.Name), zap.String("id", msg.ID), zap.Error(err))
+		return q.deadLetter(ctx, raw, fmt.Sprintf("invalid job payload: %v", err))
+	}
+
+	job.Attempt++
+	q.mu.RLock()
+	handler := q.handlers[job.Type]
+	q.mu.RUnlock()
+	if handler == nil {
+		return q.fail(ctx, &job, fmt.Errorf("no handler registered for job type %s", job.Type))
+	}
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(job.Headers))
+	latency := time.Since(job.ReadyAt)
+	ctx, span := pkgtrace.StartSpan(ctx, "job."+job.Type,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job.queue", q.opts.Name),
+			attribute.String("job.id", job.ID),
+			attribute.String("job.type", job.Type),
+			attribute.Int("job.attempt", job.Attempt),
+			attribute.Float64("job.queue_latency_ms", float64(latency.Milliseconds())),
+		),
+	)
+	defer span.End()
+	if metrics.IsEnabled() {
+		JobQueueLatency.WithLabelValues(q.opts.Name, job.Type).Observe(latency.Seconds())
+	}
+
+	if err := q.run(ctx, handler, &job); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		return q.fail(ctx, &job, err)
+	}
+	span.SetStatus(codes.Ok, "")
+	recordJob(q.opts.Name, job.Type, "succeeded")
+	return nil
+}
+
+// run 调用处理函数，捕获 panic 并转换为错误
+func (q *JobQueue) run(ctx context.Context, handler JobHandler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// fail 处理执行失败的任务：未超过最大重试次数时按退避重新调度，否则转入死信队列
+// 返回 nil 使原消息被确认，重试通过新的延迟任务完成
+func (q *JobQueue) fail(ctx context.Context, job *Job, cause error) error {
+	job.LastError = cause.Error()
+	if job.Attempt >= q.opts.MaxRetries {
+		log.FromContext(ctx).Error("Job failed permanently, moving to dead letter queue",
+			zap.String("queue", q.opts.Name),
+			zap.String("id", job.ID),
+			zap.String("type", job.Type),
+			zap.Int("attempt", job.Attempt),
+			zap.Error(cause),
+		)
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		recordJob(q.opts.Name, job.Type, "dead_lettered")
+		return q.deadLetter(ctx, string(data), job.LastError)
+	}
+
+	backoff := q.backoff(job.Attempt)
+	job.ReadyAt = time.Now().Add(backoff)
+	log.FromContext(ctx).Warn("Job failed, scheduling retry",
+		zap.String("queue", q.opts.Name),
+		zap.String("id", job.ID),
+		zap.String("type", job.Type),
+		zap.Int("attempt", job.Attempt),
+		zap.Duration("backoff", backoff),
+		zap.Error(cause),
+	)
+	recordJob(q.opts.Name, job.Type, "retried")
+	return q.schedule(ctx, job)
+}
+
+// schedule 将任务写入延迟有序集合或就绪 Stream
+func (q *JobQueue) schedule(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	if !job.ReadyAt.After(time.Now()) {
+		err = q.client.XAdd(ctx, &redis.XAddArgs{
+			Stream: q.readyKey(),
+			Values: map[string]interface{}{jobField: data},
+		}).Err()
+	} else {
+		err = q.client.ZAdd(ctx, q.delayedKey(), redis.Z{
+			Score:  float64(job.ReadyAt.UnixMilli()),
+			Member: data,
+		}).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// deadLetter 将任务写入死信 Stream
+func (q *JobQueue) deadLetter(ctx context.Context, data, reason string) error {
+	err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.readyKey() + ":dead",
+		Values: map[string]interface{}{jobField: data, "_error": reason},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to move job to dead letter queue: %w", err)
+	}
+	return nil
+}
+
+// backoff 返回第 attempt 次失败后的重试间隔
+func (q *JobQueue) backoff(attempt int) time.Duration {
+	d := q.opts.MinRetryBackoff
+	for i := 1; i < attempt && d < q.opts.MaxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.opts.MaxRetryBackoff)
+}
+
+// delayedKey 延迟任务有序集合的键
+func (q *JobQueue) delayedKey() string {
+	return "{" + q.opts.Name + "}:delayed"
+}
+
+// readyKey 就绪任务 Stream 的键
+func (q *JobQueue) readyKey() string {
+	return "{" + q.opts.Name + "}:ready"
+}
+
+// newJobID 生成随机任务 ID
+func newJobID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// recordJob 记录任务处理指标
+func recordJob(queue, jobType, status string) {
+	if metrics.IsEnabled() {
+		JobQueueJobsTotal.WithLabelValues(queue, jobType, status).Inc()
+	}
+}
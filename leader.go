@@ -0,0 +1,276 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// leaderReleaseTimeout 卸任时删除领导权键的超时
+const leaderReleaseTimeout = 5 * time.Second
+
+// leaderRenewScript 仅在仍持有领导权时续期，与 redsync 的 Extend 使用相同的语义
+// KEYS[1] 领导权键；ARGV[1] 持有者的值，ARGV[2] 有效期（毫秒）
+var leaderRenewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// LeaderElectorOptions 领导者选举配置选项
+type LeaderElectorOptions struct {
+	Key           string                    // 领导权键，与 redsync 互斥锁的键相同时两者互斥
+	ID            string                    // 候选者标识，写入键的值为 ID:随机串，默认 hostname-pid
+	TTL           time.Duration             // 领导权键的有效期，领导者异常退出后最长经过该时间重新选举，默认 15s
+	RenewInterval time.Duration             // 续期间隔，必须小于 TTL，默认 TTL/3
+	RetryInterval time.Duration             // 未当选时重新竞选的间隔，默认与 RenewInterval 相同
+	OnElected     func(ctx context.Context) // 当选时在新的 goroutine 中调用，失去领导权时 ctx 被取消
+	OnResigned    func()                    // 失去领导权（续期失败、被抢占或 Stop）时调用
+}
+
+// LeaderElector 基于 Redis 的领导者选举：以 SET NX PX 竞选带有效期的领导权键，当选后定期续期，
+// 续期失败或在键过期前无法确认续期成功时立即卸任，保证同一时刻最多一个实例认为自己是领导者。
+// 键的读写方式与 redsync 相同，可与使用 redsync 加锁的其他服务共用同一个键
+type LeaderElector struct {
+	client redis.UniversalClient
+	opts   LeaderElectorOptions
+	value  string
+
+	leader    atomic.Bool
+	expiresAt time.Time          // 领导权的保守过期时间，只在选举 goroutine 中访问
+	resign    context.CancelFunc // 取消 OnElected 的 context，只在选举 goroutine 中访问
+	callbacks sync.WaitGroup
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLeaderElector 创建领导者选举，调用 Start 后开始竞选
+func NewLeaderElector(client redis.UniversalClient, opts *LeaderElectorOptions) (*LeaderElector, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if opts == nil || opts.Key == "" {
+		return nil, fmt.Errorf("leader election key is required")
+	}
+
+	e := &LeaderElector{client: client, opts: *opts}
+	if e.opts.ID == "" {
+		host, _ := os.Hostname()
+		e.opts.ID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if e.opts.TTL <= 0 {
+		e.opts.TTL = 15 * time.Second
+	}
+	if e.opts.RenewInterval <= 0 {
+		e.opts.RenewInterval = e.opts.TTL / 3
+	}
+	if e.opts.RenewInterval >= e.opts.TTL {
+		return nil, fmt.Errorf("leader election renew interval %s must be less than ttl %s", e.opts.RenewInterval, e.opts.TTL)
+	}
+	if e.opts.RetryInterval <= 0 {
+		e.opts.RetryInterval = e.opts.RenewInterval
+	}
+	e.value = e.opts.ID + ":" + newJobID()
+	return e, nil
+}
+
+// Start 在后台开始竞选，ctx 取消或调用 Stop 时卸任
+func (e *LeaderElector) Start(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancel != nil {
+		return fmt.Errorf("leader elector for %s already started", e.opts.Key)
+	}
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+	go e.run(ctx)
+	return nil
+}
+
+// Stop 停止竞选，持有领导权时删除领导权键使其他实例立即当选，并等待 OnElected 返回
+func (e *LeaderElector) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	waited := make(chan struct{})
+	go func() {
+		e.callbacks.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsLeader 返回当前实例是否持有领导权
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Leader 返回当前领导者的标识，没有领导者时返回空字符串。由 redsync 持有时返回其随机值
+func (e *LeaderElector) Leader(ctx context.Context) (string, error) {
+	value, err := e.client.Get(ctx, e.opts.Key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if i := strings.LastIndexByte(value, ':'); i >= 0 {
+		return value[:i], nil
+	}
+	return value, nil
+}
+
+// run 竞选和续期循环
+func (e *LeaderElector) run(ctx context.Context) {
+	defer close(e.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.stepDown("resigned")
+			return
+		case <-timer.C:
+		}
+
+		if e.IsLeader() {
+			e.renew(ctx)
+		} else {
+			e.campaign(ctx)
+		}
+		if e.IsLeader() {
+			timer.Reset(e.opts.RenewInterval)
+		} else {
+			timer.Reset(e.opts.RetryInterval)
+		}
+	}
+}
+
+// campaign 尝试获取领导权键
+func (e *LeaderElector) campaign(ctx context.Context) {
+	start := time.Now()
+	ok, err := e.client.SetNX(ctx, e.opts.Key, e.value, e.opts.TTL).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warn("Failed to campaign for leadership", zap.String("key", e.opts.Key), zap.Error(err))
+		}
+		return
+	}
+	if !ok {
+		return
+	}
+
+	e.expiresAt = start.Add(e.opts.TTL)
+	e.leader.Store(true)
+	e.recordTransition("elected", 1)
+	log.Info("Elected as leader", zap.String("key", e.opts.Key), zap.String("id", e.opts.ID))
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	e.resign = cancel
+	if e.opts.OnElected != nil {
+		e.callbacks.Add(1)
+		go func() {
+			defer e.callbacks.Done()
+			e.opts.OnElected(leaderCtx)
+		}()
+	}
+}
+
+// renew 续期领导权键，键已被删除或被其他实例持有时立即卸任；
+// 续期请求失败时，若在键过期前已来不及再次续期，也视为失去领导权
+func (e *LeaderElector) renew(ctx context.Context) {
+	start := time.Now()
+	n, err := leaderRenewScript.Run(ctx, e.client, []string{e.opts.Key}, e.value, e.opts.TTL.Milliseconds()).Int64()
+	switch {
+	case err == nil && n == 1:
+		e.expiresAt = start.Add(e.opts.TTL)
+	case err == nil:
+		log.Warn("Leadership lost, key is held by another instance", zap.String("key", e.opts.Key))
+		e.stepDown("lost")
+	case ctx.Err() != nil:
+	case time.Until(e.expiresAt) <= e.opts.RenewInterval:
+		log.Warn("Leadership lost, failed to renew before expiry", zap.String("key", e.opts.Key), zap.Error(err))
+		e.stepDown("lost")
+	default:
+		log.Warn("Failed to renew leadership, retrying", zap.String("key", e.opts.Key), zap.Error(err))
+	}
+}
+
+// stepDown 卸任：取消 OnElected 的 context，主动卸任时删除领导权键
+func (e *LeaderElector) stepDown(event string) {
+	if !e.IsLeader() {
+		return
+	}
+	e.leader.Store(false)
+	if e.resign != nil {
+		e.resign()
+		e.resign = nil
+	}
+	if event == "resigned" {
+		ctx, cancel := context.WithTimeout(context.Background(), leaderReleaseTimeout)
+		if err := releaseScript.Run(ctx, e.client, []string{e.opts.Key}, e.value).Err(); err != nil {
+			log.Warn("Failed to release leadership key", zap.String("key", e.opts.Key), zap.Error(err))
+		}
+		cancel()
+	}
+	e.recordTransition(event, 0)
+	log.Info("Resigned leadership", zap.String("key", e.opts.Key), zap.String("id", e.opts.ID), zap.String("reason", event))
+	if e.opts.OnResigned != nil {
+		e.opts.OnResigned()
+	}
+}
+
+// recordTransition 记录领导权变化指标
+func (e *LeaderElector) recordTransition(event string, leader float64) {
+	if !metrics.IsEnabled() {
+		return
+	}
+	LeaderElectionIsLeader.WithLabelValues(e.opts.Key).Set(leader)
+	LeaderElectionTransitionsTotal.WithLabelValues(e.opts.Key, event).Inc()
+}
@@ -0,0 +1,220 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LeaderboardOptions 排行榜配置选项
+type LeaderboardOptions struct {
+	Ascending bool          // 分数低者排名靠前（如耗时），默认分数高者靠前
+	TTL       time.Duration // 排行榜的过期时间，每次写入时刷新，0 表示不过期，用于按天、按周等周期滚动的榜单
+}
+
+// LeaderboardEntry 排行榜条目
+type LeaderboardEntry struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+	Rank   int64   `json:"rank"` // 排名，从 1 开始，分数相同时按成员字典序排列，分数高者靠前时为逆序
+}
+
+// Leaderboard 基于 Redis 有序集合的排行榜
+type Leaderboard struct {
+	client redis.UniversalClient
+	key    string
+	opts   LeaderboardOptions
+}
+
+// NewLeaderboard 创建排行榜，key 为 Redis 中保存排行榜的有序集合键
+func NewLeaderboard(client redis.UniversalClient, key string, opts *LeaderboardOptions) (*Leaderboard, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("leaderboard key is required")
+	}
+	l := &Leaderboard{client: client, key: key}
+	if opts != nil {
+		l.opts = *opts
+	}
+	return l, nil
+}
+
+// Key 返回排行榜的键
+func (l *Leaderboard) Key() string {
+	return l.key
+}
+
+// Set 设置成员的分数
+func (l *Leaderboard) Set(ctx context.Context, member string, score float64) error {
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, l.key, redis.Z{Score: score, Member: member})
+		l.touch(ctx, pipe)
+		return nil
+	})
+	return err
+}
+
+// SetIfBetter 仅在新分数优于当前分数（或成员不存在）时更新，返回是否更新，用于记录最高分
+func (l *Leaderboard) SetIfBetter(ctx context.Context, member string, score float64) (bool, error) {
+	var cmd *redis.IntCmd
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		z := redis.ZAddArgs{Members: []redis.Z{{Score: score, Member: member}}, Ch: true}
+		if l.opts.Ascending {
+			z.LT = true
+		} else {
+			z.GT = true
+		}
+		cmd = pipe.ZAddArgs(ctx, l.key, z)
+		l.touch(ctx, pipe)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return cmd.Val() > 0, nil
+}
+
+// Incr 增加成员的分数并返回新分数，成员不存在时从 0 开始
+func (l *Leaderboard) Incr(ctx context.Context, member string, delta float64) (float64, error) {
+	var cmd *redis.FloatCmd
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		cmd = pipe.ZIncrBy(ctx, l.key, delta, member)
+		l.touch(ctx, pipe)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return cmd.Val(), nil
+}
+
+// Remove 删除成员
+func (l *Leaderboard) Remove(ctx context.Context, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	return l.client.ZRem(ctx, l.key, stringArgs(members)...).Err()
+}
+
+// Rank 返回成员的排名和分数，成员不存在时返回 ErrNotFound
+func (l *Leaderboard) Rank(ctx context.Context, member string) (*LeaderboardEntry, error) {
+	var cmd *redis.RankWithScoreCmd
+	if l.opts.Ascending {
+		cmd = l.client.ZRankWithScore(ctx, l.key, member)
+	} else {
+		cmd = l.client.ZRevRankWithScore(ctx, l.key, member)
+	}
+	rank, err := cmd.Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &LeaderboardEntry{Member: member, Score: rank.Score, Rank: rank.Rank + 1}, nil
+}
+
+// Top 返回排名前 n 的成员
+func (l *Leaderboard) Top(ctx context.Context, n int) ([]LeaderboardEntry, error) {
+	if n <= 0 {
+		return []LeaderboardEntry{}, nil
+	}
+	return l.rangeByRank(ctx, 0, int64(n)-1)
+}
+
+// Around 返回成员及其前后各 n 名，成员不存在时返回 ErrNotFound
+func (l *Leaderboard) Around(ctx context.Context, member string, n int) ([]LeaderboardEntry, error) {
+	entry, err := l.Rank(ctx, member)
+	if err != nil {
+		return nil, err
+	}
+	start := max(entry.Rank-1-int64(n), 0)
+	return l.rangeByRank(ctx, start, entry.Rank-1+int64(n))
+}
+
+// Page 偏移分页返回排行榜，page 从 1 开始，每页条数按 Paginator 规范化
+func (l *Leaderboard) Page(ctx context.Context, p *Paginator, page, size int) (*Page[LeaderboardEntry], error) {
+	size = p.size(size)
+	page = max(page, 1)
+
+	total, err := l.client.ZCard(ctx, l.key).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := &Page[LeaderboardEntry]{
+		Items:      []LeaderboardEntry{},
+		Page:       page,
+		Size:       size,
+		Total:      total,
+		TotalPages: int((total + int64(size) - 1) / int64(size)),
+	}
+	offset := int64((page - 1) * size)
+	if offset >= total {
+		return result, nil
+	}
+	if result.Items, err = l.rangeByRank(ctx, offset, offset+int64(size)-1); err != nil {
+		return nil, err
+	}
+	result.HasMore = offset+int64(len(result.Items)) < total
+	return result, nil
+}
+
+// Count 返回成员数
+func (l *Leaderboard) Count(ctx context.Context) (int64, error) {
+	return l.client.ZCard(ctx, l.key).Result()
+}
+
+// Delete 删除整个排行榜
+func (l *Leaderboard) Delete(ctx context.Context) error {
+	return l.client.Del(ctx, l.key).Err()
+}
+
+// rangeByRank 返回排名区间 [start, stop]（从 0 开始）内的成员
+func (l *Leaderboard) rangeByRank(ctx context.Context, start, stop int64) ([]LeaderboardEntry, error) {
+	var (
+		zs  []redis.Z
+		err error
+	)
+	if l.opts.Ascending {
+		zs, err = l.client.ZRangeWithScores(ctx, l.key, start, stop).Result()
+	} else {
+		zs, err = l.client.ZRevRangeWithScores(ctx, l.key, start, stop).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]LeaderboardEntry, len(zs))
+	for i, z := range zs {
+		member, _ := z.Member.(string)
+		entries[i] = LeaderboardEntry{Member: member, Score: z.Score, Rank: start + int64(i) + 1}
+	}
+	return entries, nil
+}
+
+// touch 在写入的事务中刷新过期时间
+func (l *Leaderboard) touch(ctx context.Context, pipe redis.Pipeliner) {
+	if l.opts.TTL > 0 {
+		pipe.PExpire(ctx, l.key, l.opts.TTL)
+	}
+}
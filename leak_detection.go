@@ -0,0 +1,368 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// leakScanInterval 检查未结束事务和未关闭结果集的间隔
+const leakScanInterval = time.Second
+
+// 持有连接的操作类型
+const (
+	leakHolderTx   = "transaction" // 未提交或回滚的事务
+	leakHolderRows = "rows"        // db.Rows() 返回的未关闭结果集
+)
+
+// LeakDetectionOptions 连接泄漏和长事务检测配置
+type LeakDetectionOptions struct {
+	Threshold time.Duration // 事务或结果集持有连接超过该时间时告警，默认 30s
+}
+
+// leakDetectionOptions 由配置中的阈值生成检测配置，0 时返回 nil
+func leakDetectionOptions(d time.Duration) *LeakDetectionOptions {
+	if d <= 0 {
+		return nil
+	}
+	return &LeakDetectionOptions{Threshold: d}
+}
+
+// leakRecord 一个持有连接池中连接的操作：未结束的事务或未关闭的结果集
+type leakRecord struct {
+	holder string      // leakHolderTx 或 leakHolderRows
+	start  time.Time   // 取出连接的时间
+	stack  string      // 取出连接时的调用栈
+	closed func() bool // 检查时判断是否已释放连接，nil 表示由 release 显式结束
+
+	mu      sync.Mutex
+	lastSQL string
+	warned  bool
+}
+
+// setSQL 记录最近执行的语句
+func (r *leakRecord) setSQL(query string) {
+	r.mu.Lock()
+	r.lastSQL = query
+	r.mu.Unlock()
+}
+
+// leakDetector 单个数据源的泄漏检测状态
+type leakDetector struct {
+	datasource string
+	threshold  time.Duration
+
+	mu   sync.Mutex
+	open map[*leakRecord]struct{}
+}
+
+var (
+	leakDetectorsMu sync.Mutex
+	leakDetectors   []*leakDetector
+	leakScanOnce    sync.Once
+)
+
+// EnableLeakDetection 开启连接泄漏和长事务检测，建议仅在排查问题时开启：
+// 记录每个事务和 db.Rows() 返回的结果集从连接池取出连接的时间和调用位置，
+// 未提交或回滚的事务、未关闭的结果集持有连接超过阈值时输出带调用栈的告警（事务附带最近执行的语句），
+// 并通过 db_long_transactions、db_unclosed_rows 指标上报当前超过阈值的数量。需在注册 dbresolver 等插件之前调用。
+// 直接通过 db.DB() 取得的 *sql.DB 执行的 Conn、QueryContext 等不经过 GORM，无法检测；
+// db.Connection 取出的连接在回调返回时归还，不做检测。
+// 连接通过 CloseSQLDB 或 Manager 关闭（含热更新替换）时注销检测
+func EnableLeakDetection(db *gorm.DB, datasource string, opts *LeakDetectionOptions) error {
+	sqlDB, ok := db.ConnPool.(*sql.DB)
+	if !ok {
+		return fmt.Errorf("leak detection requires *sql.DB connection pool, got %T", db.ConnPool)
+	}
+	d := &leakDetector{datasource: datasource, threshold: 30 * time.Second, open: make(map[*leakRecord]struct{})}
+	if opts != nil && opts.Threshold > 0 {
+		d.threshold = opts.Threshold
+	}
+
+	if err := db.Callback().Row().After("gorm:row").Register("leak_detection:rows", d.trackRows); err != nil {
+		return fmt.Errorf("failed to register leak detection callback: %w", err)
+	}
+	pool := &leakDetectPool{DB: sqlDB, detector: d}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+
+	leakDetectorsMu.Lock()
+	leakDetectors = append(leakDetectors, d)
+	leakDetectorsMu.Unlock()
+	onHandleClose(sqlDB, d.unregister)
+	leakScanOnce.Do(func() { go leakScanLoop() })
+	return nil
+}
+
+// unregister 停止检测，数据源没有其他检测器时删除指标
+func (d *leakDetector) unregister() {
+	leakDetectorsMu.Lock()
+	defer leakDetectorsMu.Unlock()
+	shared := false
+	for i := 0; i < len(leakDetectors); i++ {
+		switch {
+		case leakDetectors[i] == d:
+			leakDetectors = append(leakDetectors[:i], leakDetectors[i+1:]...)
+			i--
+		case leakDetectors[i].datasource == d.datasource:
+			shared = true
+		}
+	}
+	if !shared {
+		DatabaseLongTransactions.DeleteLabelValues(d.datasource)
+		DatabaseUnclosedRows.DeleteLabelValues(d.datasource)
+	}
+}
+
+// trackRows 记录 db.Rows() 返回的结果集，结果集关闭前持有连接
+func (d *leakDetector) trackRows(db *gorm.DB) {
+	rows, ok := db.Statement.Dest.(*sql.Rows)
+	if !ok || rows == nil || db.Error != nil {
+		return
+	}
+	// 已关闭的结果集 Columns 返回错误，该检查不影响结果集的读取
+	r := d.acquire(leakHolderRows, func() bool {
+		_, err := rows.Columns()
+		return err != nil
+	})
+	r.setSQL(db.Statement.SQL.String())
+}
+
+// acquire 记录取出连接的操作
+func (d *leakDetector) acquire(holder string, closed func() bool) *leakRecord {
+	r := &leakRecord{holder: holder, start: time.Now(), stack: leakCallerStack(), closed: closed}
+	d.mu.Lock()
+	d.open[r] = struct{}{}
+	d.mu.Unlock()
+	return r
+}
+
+// release 记录归还连接，超过阈值的操作结束时输出持有时间
+func (d *leakDetector) release(r *leakRecord) {
+	d.mu.Lock()
+	delete(d.open, r)
+	d.mu.Unlock()
+
+	r.mu.Lock()
+	warned := r.warned
+	r.mu.Unlock()
+	if warned {
+		log.Info("Long-held database connection released",
+			zap.String("datasource", d.datasource),
+			zap.String("holder", r.holder),
+			zap.Duration("duration", time.Since(r.start)),
+		)
+	}
+}
+
+// leakCounts 一次检查中超过阈值的数量
+type leakCounts struct {
+	tx   int // 未结束的事务
+	rows int // 未关闭的结果集
+}
+
+// scan 结束已关闭的结果集，检查持有连接超过阈值的操作，首次超过时告警，返回超过阈值的数量
+func (d *leakDetector) scan(now time.Time) leakCounts {
+	d.mu.Lock()
+	var candidates []*leakRecord
+	for r := range d.open {
+		if r.closed != nil || now.Sub(r.start) > d.threshold {
+			candidates = append(candidates, r)
+		}
+	}
+	d.mu.Unlock()
+
+	var counts leakCounts
+	var long []*leakRecord
+	for _, r := range candidates {
+		if r.closed != nil && r.closed() {
+			d.release(r)
+			continue
+		}
+		if now.Sub(r.start) <= d.threshold {
+			continue
+		}
+		long = append(long, r)
+		if r.holder == leakHolderRows {
+			counts.rows++
+		} else {
+			counts.tx++
+		}
+	}
+
+	for _, r := range long {
+		r.mu.Lock()
+		warned, lastSQL := r.warned, r.lastSQL
+		r.warned = true
+		r.mu.Unlock()
+		if warned {
+			continue
+		}
+		msg := "Long transaction holding database connection, possible connection leak"
+		if r.holder == leakHolderRows {
+			msg = "Unclosed rows holding database connection, possible connection leak"
+		}
+		log.Warn(msg,
+			zap.String("datasource", d.datasource),
+			zap.Duration("duration", now.Sub(r.start)),
+			zap.Duration("threshold", d.threshold),
+			zap.String("last_sql", lastSQL),
+			zap.String("begin_at", r.stack),
+		)
+	}
+	return counts
+}
+
+// leakScanLoop 定期检查所有数据源
+func leakScanLoop() {
+	ticker := time.NewTicker(leakScanInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		scanLeakDetectors(now)
+	}
+}
+
+// scanLeakDetectors 检查所有检测器并更新指标，热更新期间同一数据源的新旧检测器合并计数
+func scanLeakDetectors(now time.Time) {
+	leakDetectorsMu.Lock()
+	detectors := append([]*leakDetector(nil), leakDetectors...)
+	leakDetectorsMu.Unlock()
+
+	counts := make(map[string]leakCounts, len(detectors))
+	for _, d := range detectors {
+		c := d.scan(now)
+		total := counts[d.datasource]
+		total.tx += c.tx
+		total.rows += c.rows
+		counts[d.datasource] = total
+	}
+	if !metrics.IsEnabled() {
+		return
+	}
+
+	// 只更新仍在检测的数据源，避免检查期间注销的数据源的指标被重新创建
+	leakDetectorsMu.Lock()
+	defer leakDetectorsMu.Unlock()
+	for _, d := range leakDetectors {
+		if c, ok := counts[d.datasource]; ok {
+			DatabaseLongTransactions.WithLabelValues(d.datasource).Set(float64(c.tx))
+			DatabaseUnclosedRows.WithLabelValues(d.datasource).Set(float64(c.rows))
+		}
+	}
+}
+
+// leakCallerStack 返回开始事务的业务调用栈，跳过 GORM、database/sql 和本包的帧
+func leakCallerStack() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var parts []string
+	for len(parts) < 8 {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "gorm.io/") &&
+			!strings.HasPrefix(frame.Function, "database/sql.") &&
+			!strings.HasPrefix(frame.Function, "github.com/go-anyway/framework-db.") {
+			parts = append(parts, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.Join(parts, " <- ")
+}
+
+// leakDetectPool 包装连接池，记录事务的开始和结束
+type leakDetectPool struct {
+	*sql.DB
+	detector *leakDetector
+}
+
+// GetDBConn 返回底层连接池，使 db.DB() 可用
+func (p *leakDetectPool) GetDBConn() (*sql.DB, error) {
+	return p.DB, nil
+}
+
+// BeginTx 开始事务并记录调用位置
+func (p *leakDetectPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &leakDetectTx{Tx: tx, db: p.DB, detector: p.detector, record: p.detector.acquire(leakHolderTx, nil)}, nil
+}
+
+// leakDetectTx 包装事务，记录最近执行的语句，提交或回滚时结束记录
+type leakDetectTx struct {
+	*sql.Tx
+	db       *sql.DB
+	detector *leakDetector
+	record   *leakRecord
+	once     sync.Once
+}
+
+// GetDBConn 返回底层连接池，使事务中的 db.DB() 可用
+func (t *leakDetectTx) GetDBConn() (*sql.DB, error) {
+	return t.db, nil
+}
+
+// ExecContext 执行语句
+func (t *leakDetectTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	t.record.setSQL(query)
+	return t.Tx.ExecContext(ctx, query, args...)
+}
+
+// QueryContext 执行查询
+func (t *leakDetectTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	t.record.setSQL(query)
+	return t.Tx.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext 执行单行查询
+func (t *leakDetectTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	t.record.setSQL(query)
+	return t.Tx.QueryRowContext(ctx, query, args...)
+}
+
+// Commit 提交事务
+func (t *leakDetectTx) Commit() error {
+	defer t.finish()
+	return t.Tx.Commit()
+}
+
+// Rollback 回滚事务
+func (t *leakDetectTx) Rollback() error {
+	defer t.finish()
+	return t.Tx.Rollback()
+}
+
+// finish 结束记录
+func (t *leakDetectTx) finish() {
+	t.once.Do(func() { t.detector.release(t.record) })
+}
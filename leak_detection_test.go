@@ -0,0 +1,100 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// findLeakDetector 返回数据源已注册的检测器
+func findLeakDetector(datasource string) *leakDetector {
+	leakDetectorsMu.Lock()
+	defer leakDetectorsMu.Unlock()
+	for _, d := range leakDetectors {
+		if d.datasource == datasource {
+			return d
+		}
+	}
+	return nil
+}
+
+func TestLeakDetection(t *testing.T) {
+	const datasource = "leak_detection_test"
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "leak.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := EnableLeakDetection(db, datasource, &LeakDetectionOptions{Threshold: time.Minute}); err != nil {
+		t.Fatalf("EnableLeakDetection: %v", err)
+	}
+	d := findLeakDetector(datasource)
+	if d == nil {
+		t.Fatal("detector not registered")
+	}
+
+	rows, err := db.Raw("SELECT 1").Rows()
+	if err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("begin: %v", tx.Error)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Duration
+		want leakCounts
+	}{
+		{name: "within threshold", at: 0, want: leakCounts{}},
+		{name: "over threshold", at: 2 * time.Minute, want: leakCounts{tx: 1, rows: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.scan(time.Now().Add(tt.at)); got != tt.want {
+				t.Errorf("scan = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	_ = rows.Close()
+	if err := tx.Rollback().Error; err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if got := d.scan(time.Now().Add(2 * time.Minute)); got != (leakCounts{}) {
+		t.Errorf("scan after release = %+v, want none", got)
+	}
+	d.mu.Lock()
+	open := len(d.open)
+	d.mu.Unlock()
+	if open != 0 {
+		t.Errorf("open records = %d, want 0", open)
+	}
+
+	if err := CloseSQLDB(db); err != nil {
+		t.Fatalf("CloseSQLDB: %v", err)
+	}
+	if findLeakDetector(datasource) != nil {
+		t.Error("detector still registered after CloseSQLDB")
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NotificationHandler 处理 PostgreSQL 通知，返回错误时计入失败指标
+type NotificationHandler func(ctx context.Context, n *pgconn.Notification) error
+
+// ListenerOptions 通知监听配置选项
+type ListenerOptions struct {
+	Workers             int           // 处理通知的 worker 数，默认 1（保证同一 Listener 的通知按顺序处理）
+	QueueSize           int           // 待处理通知队列长度，默认 1024，队列满时阻塞接收
+	MinReconnectBackoff time.Duration // 连接断开后首次重连的等待时间，默认 100ms
+	MaxReconnectBackoff time.Duration // 重连的最大等待时间，默认 30s
+}
+
+// Listener PostgreSQL LISTEN/NOTIFY 封装：使用独立连接订阅频道并分发通知，
+// 连接断开或故障转移后自动重连并重新 LISTEN 所有频道。重连期间发送的通知会丢失，
+// 用于缓存失效等场景时应在重连后全量刷新
+type Listener struct {
+	pg   *PostgreSQLOptions
+	opts ListenerOptions
+
+	mu       sync.Mutex
+	handlers map[string]NotificationHandler
+	wake     context.CancelFunc // 中断当前的等待，使接收循环同步频道变更
+
+	queue  chan *pgconn.Notification
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	start  sync.Once
+}
+
+// NewListener 创建通知监听，需调用 Listen 注册处理函数
+func NewListener(pg *PostgreSQLOptions, opts *ListenerOptions) (*Listener, error) {
+	if pg == nil {
+		return nil, fmt.Errorf("postgresql options cannot be nil")
+	}
+
+	l := &Listener{
+		pg:       pg,
+		handlers: make(map[string]NotificationHandler),
+	}
+	if opts != nil {
+		l.opts = *opts
+	}
+	if l.opts.Workers <= 0 {
+		l.opts.Workers = 1
+	}
+	if l.opts.QueueSize <= 0 {
+		l.opts.QueueSize = 1024
+	}
+	if l.opts.MinReconnectBackoff <= 0 {
+		l.opts.MinReconnectBackoff = 100 * time.Millisecond
+	}
+	if l.opts.MaxReconnectBackoff <= 0 {
+		l.opts.MaxReconnectBackoff = 30 * time.Second
+	}
+	l.queue = make(chan *pgconn.Notification, l.opts.QueueSize)
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	return l, nil
+}
+
+// Listen 订阅频道并注册处理函数，首次订阅时建立连接并启动接收循环
+func (l *Listener) Listen(channel string, handler NotificationHandler) error {
+	if channel == "" {
+		return fmt.Errorf("channel cannot be empty")
+	}
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ctx.Err() != nil {
+		return fmt.Errorf("listener closed")
+	}
+	l.handlers[channel] = handler
+	l.run()
+	l.notifyChange()
+	return nil
+}
+
+// Unlisten 取消订阅频道
+func (l *Listener) Unlisten(channel string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.handlers, channel)
+	l.notifyChange()
+}
+
+// Close 关闭连接，等待已接收的通知处理完成后返回
+func (l *Listener) Close() error {
+	l.cancel()
+	l.wg.Wait()
+	return nil
+}
+
+// notifyChange 中断接收循环的等待以同步频道（调用方需持有锁）
+func (l *Listener) notifyChange() {
+	if l.wake != nil {
+		l.wake()
+	}
+}
+
+// run 启动接收循环和 worker 池（调用方需持有锁）
+func (l *Listener) run() {
+	l.start.Do(func() {
+		workers := &sync.WaitGroup{}
+		for i := 0; i < l.opts.Workers; i++ {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				l.work()
+			}()
+		}
+
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			l.receive()
+			// 接收结束后关闭队列，worker 处理完剩余通知后退出
+			close(l.queue)
+			workers.Wait()
+		}()
+	})
+}
+
+// receive 维护监听连接并接收通知，连接出错时按退避策略重连
+func (l *Listener) receive() {
+	var conn *pgx.Conn
+	listening := make(map[string]bool)
+	defer func() {
+		if conn != nil {
+			closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = conn.Close(closeCtx)
+		}
+	}()
+
+	backoff := l.opts.MinReconnectBackoff
+	for l.ctx.Err() == nil {
+		if conn == nil {
+			var err error
+			if conn, err = l.connect(); err != nil {
+				log.Warn("PostgreSQL listener connect failed, retrying",
+					zap.Error(err), zap.Duration("backoff", backoff))
+				if !l.sleep(backoff) {
+					return
+				}
+				backoff = min(backoff*2, l.opts.MaxReconnectBackoff)
+				continue
+			}
+			clear(listening)
+		}
+
+		waitCtx, err := l.sync(conn, listening)
+		if err == nil {
+			var n *pgconn.Notification
+			n, err = conn.WaitForNotification(waitCtx)
+			if err == nil {
+				backoff = l.opts.MinReconnectBackoff
+				select {
+				case l.queue <- n:
+				case <-l.ctx.Done():
+					return
+				}
+				continue
+			}
+			// 等待被 Listen/Unlisten 中断，连接仍可用
+			if waitCtx.Err() != nil && l.ctx.Err() == nil && !conn.IsClosed() {
+				continue
+			}
+		}
+		if l.ctx.Err() != nil {
+			return
+		}
+
+		log.Warn("PostgreSQL listener connection lost, reconnecting",
+			zap.Error(err), zap.Duration("backoff", backoff))
+		if metrics.IsEnabled() {
+			DatabaseListenerReconnectTotal.Inc()
+		}
+		closeCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_ = conn.Close(closeCtx)
+		cancel()
+		conn = nil
+		if !l.sleep(backoff) {
+			return
+		}
+		backoff = min(backoff*2, l.opts.MaxReconnectBackoff)
+	}
+}
+
+// sync 使连接上 LISTEN 的频道与已注册的频道一致，返回可被频道变更中断的等待 context
+func (l *Listener) sync(conn *pgx.Conn, listening map[string]bool) (context.Context, error) {
+	l.mu.Lock()
+	// 释放上一次等待的 context
+	if l.wake != nil {
+		l.wake()
+	}
+	waitCtx, wake := context.WithCancel(l.ctx)
+	l.wake = wake
+	var listen, unlisten []string
+	for ch := range l.handlers {
+		if !listening[ch] {
+			listen = append(listen, ch)
+		}
+	}
+	for ch := range listening {
+		if _, ok := l.handlers[ch]; !ok {
+			unlisten = append(unlisten, ch)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, ch := range listen {
+		if _, err := conn.Exec(l.ctx, "LISTEN "+pgx.Identifier{ch}.Sanitize()); err != nil {
+			return nil, fmt.Errorf("failed to listen channel %s: %w", ch, err)
+		}
+		listening[ch] = true
+	}
+	for _, ch := range unlisten {
+		if _, err := conn.Exec(l.ctx, "UNLISTEN "+pgx.Identifier{ch}.Sanitize()); err != nil {
+			return nil, fmt.Errorf("failed to unlisten channel %s: %w", ch, err)
+		}
+		delete(listening, ch)
+	}
+	return waitCtx, nil
+}
+
+// connect 建立监听连接，多节点时按 PostgreSQL 连接相同的策略选择节点
+func (l *Listener) connect() (*pgx.Conn, error) {
+	cfg, err := pgx.ParseConfig(postgreSQLDSN(l.pg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgresql dsn: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(l.ctx, 10*time.Second)
+	defer cancel()
+	if err := postgreSQLBeforeConnect(l.pg.PasswordProvider, l.pg.Dialer)(ctx, cfg); err != nil {
+		return nil, err
+	}
+	return pgx.ConnectConfig(ctx, cfg)
+}
+
+// sleep 等待 d，Listener 关闭时返回 false
+func (l *Listener) sleep(d time.Duration) bool {
+	select {
+	case <-l.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// work 从队列中取出通知并调用处理函数
+func (l *Listener) work() {
+	for n := range l.queue {
+		l.mu.Lock()
+		handler := l.handlers[n.Channel]
+		l.mu.Unlock()
+		if handler == nil {
+			recordNotification(n.Channel, "dropped")
+			continue
+		}
+		if err := l.handle(handler, n); err != nil {
+			log.Error("PostgreSQL notification handler failed",
+				zap.String("channel", n.Channel), zap.Error(err))
+			recordNotification(n.Channel, "failed")
+			continue
+		}
+		recordNotification(n.Channel, "delivered")
+	}
+}
+
+// handle 调用处理函数，捕获 panic 并转换为错误
+func (l *Listener) handle(handler NotificationHandler, n *pgconn.Notification) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	// 使用独立的 context，Close 时允许处理中的通知完成
+	return handler(context.WithoutCancel(l.ctx), n)
+}
+
+// recordNotification 记录通知处理指标
+func recordNotification(channel, status string) {
+	if metrics.IsEnabled() {
+		DatabaseListenerNotificationTotal.WithLabelValues(channel, status).Inc()
+	}
+}
+
+// Notify 通过 pg_notify 向频道发送通知，在事务中调用时通知在事务提交后才会送达
+func Notify(ctx context.Context, db *gorm.DB, channel, payload string) error {
+	return db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
+
+// Listener 为管理器中指定名称的 PostgreSQL 连接创建通知监听，使用与该连接相同的配置建立独立连接
+func (m *Manager) Listener(name string, opts *ListenerOptions) (*Listener, error) {
+	m.mu.RLock()
+	conn, ok := m.sql[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("database connection %q not found", name)
+	}
+	if conn.pg == nil {
+		return nil, fmt.Errorf("database connection %q is not postgresql", name)
+	}
+	pg, err := conn.pg.ToOptions()
+	if err != nil {
+		return nil, err
+	}
+	return NewListener(pg, opts)
+}
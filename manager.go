@@ -0,0 +1,393 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"gorm.io/gorm"
+)
+
+const (
+	driverMySQL       = "mysql"
+	driverPostgreSQL  = "postgresql"
+	driverCockroachDB = "cockroachdb"
+	driverTiDB        = "tidb"
+)
+
+// sqlConn 管理器中的 SQL 连接
+type sqlConn struct {
+	name   string
+	driver string
+	db     *gorm.DB
+	mysql  *MySQLConfig
+	pg     *PostgreSQLConfig
+	held   bool // AddMySQL 等返回的句柄可能仍被调用方持有，为 true 时不重建连接，见 ReleaseHandle
+}
+
+// redisConn 管理器中的 Redis 连接
+type redisConn struct {
+	name   string
+	client *redis.Client
+	cfg    *RedisConfig
+	health *RedisHealthChecker // 未启用健康检查时为 nil
+	held   bool                // AddRedis 返回的客户端可能仍被调用方持有，为 true 时不重建客户端，见 ReleaseHandle
+}
+
+// Manager 管理多个命名的数据库和 Redis 连接
+type Manager struct {
+	mu       sync.RWMutex
+	reloadMu sync.Mutex // 串行化热更新，建立新连接期间只持有该锁，不持有 mu
+	sql      map[string]*sqlConn
+	redis    map[string]*redisConn
+	mongo    map[string]*mongo.Client
+	onReload []func(ReloadEvent)
+}
+
+// NewManager 创建新的连接管理器
+func NewManager() *Manager {
+	return &Manager{
+		sql:   make(map[string]*sqlConn),
+		redis: make(map[string]*redisConn),
+		mongo: make(map[string]*mongo.Client),
+	}
+}
+
+// AddMySQL 根据配置创建 MySQL 连接并以指定名称注册
+// 返回的连接可能被调用方长期持有，管理器在 Close 之前不会关闭它：热更新中连接池和日志级别原地生效，
+// 需要重建连接的变更（如 DSN）返回 ErrHandleHeld。调用方改为每次通过 DB 获取连接后，
+// 可调用 ReleaseHandle 允许热更新重建连接
+func (m *Manager) AddMySQL(name string, cfg *MySQLConfig) (*gorm.DB, error) {
+	cfg, err := profiledMySQLConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := cfg.ToOptions()
+	if err != nil {
+		return nil, err
+	}
+	db, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.addSQL(&sqlConn{name: name, driver: driverMySQL, db: db, mysql: cfg, held: true}); err != nil {
+		closeGormDB(db)
+		return nil, err
+	}
+	return db, nil
+}
+
+// AddPostgreSQL 根据配置创建 PostgreSQL 连接并以指定名称注册
+// 返回的连接在热更新中的行为与 AddMySQL 相同，需要重建连接的变更返回 ErrHandleHeld，见 ReleaseHandle
+func (m *Manager) AddPostgreSQL(name string, cfg *PostgreSQLConfig) (*gorm.DB, error) {
+	cfg, err := profiledPostgreSQLConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := cfg.ToOptions()
+	if err != nil {
+		return nil, err
+	}
+	db, err := NewPostgreSQL(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.addSQL(&sqlConn{name: name, driver: driverPostgreSQL, db: db, pg: cfg, held: true}); err != nil {
+		closeGormDB(db)
+		return nil, err
+	}
+	return db, nil
+}
+
+// AddRedis 根据配置创建 Redis 客户端并以指定名称注册
+// 返回的客户端可能被调用方长期持有，管理器在 Close 之前不会关闭它：go-redis 客户端的选项不可修改，
+// 热更新的变更和健康检查的重建都需要新客户端，此时分别返回 ErrHandleHeld 和跳过重建。
+// 调用方改为每次通过 Redis 获取客户端后，可调用 ReleaseHandle 允许重建
+func (m *Manager) AddRedis(name string, cfg *RedisConfig) (*redis.Client, error) {
+	cfg, err := profiledRedisConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := cfg.ToOptions()
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewRedis(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.redis[name]; exists {
+		_ = client.Close()
+		return nil, fmt.Errorf("redis connection %q already registered", name)
+	}
+	conn := &redisConn{name: name, client: client, cfg: cfg, held: true}
+	m.redis[name] = conn
+	trackRedis(client, "redis:"+name)
+	if hc := cfg.HealthCheck.options(); hc != nil {
+		m.startRedisHealthCheck(conn, hc)
+	}
+	return client, nil
+}
+
+// startRedisHealthCheck 启动 Redis 健康检查，重建的客户端替换管理器中的客户端
+func (m *Manager) startRedisHealthCheck(conn *redisConn, opts *RedisHealthOptions) {
+	name := conn.name
+	opts.Name = "redis:" + name
+	opts.Recreate = func(context.Context) (*redis.Client, error) {
+		m.mu.RLock()
+		cfg, held := conn.cfg, conn.held
+		m.mu.RUnlock()
+		if held {
+			return nil, fmt.Errorf("redis %q: %w", name, ErrHandleHeld)
+		}
+		redisOpts, err := cfg.ToOptions()
+		if err != nil {
+			return nil, err
+		}
+		return NewRedis(redisOpts)
+	}
+	opts.OnRecreate = func(client *redis.Client) {
+		m.mu.Lock()
+		if m.redis[name] != conn {
+			m.mu.Unlock()
+			return // 已注销
+		}
+		conn.client = client
+		trackRedis(client, "redis:"+name)
+		callbacks := m.onReload
+		m.mu.Unlock()
+		emitReloadEvent(ReloadEvent{
+			Name:        name,
+			Driver:      "redis",
+			Changes:     []string{"health_check: client recreated after failed pings"},
+			Reconnected: true,
+		}, callbacks)
+	}
+
+	health, _ := NewRedisHealthChecker(conn.client, opts)
+	_ = health.Start(context.Background())
+	conn.health = health
+}
+
+// AddMongo 根据配置创建 MongoDB 客户端并以指定名称注册
+func (m *Manager) AddMongo(name string, cfg *MongoConfig) (*mongo.Client, error) {
+	opts, err := cfg.ToOptions()
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewMongo(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.mongo[name]; exists {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("mongo connection %q already registered", name)
+	}
+	m.mongo[name] = client
+	return client, nil
+}
+
+// AddRedisDatabases 使用同一份配置为多个逻辑数据库创建 Redis 客户端
+// 每个客户端以 "name/db" 的形式注册，可通过 RedisDB(name, db) 获取，热更新行为与 AddRedis 相同
+func (m *Manager) AddRedisDatabases(name string, cfg *RedisConfig, databases ...int) (map[int]*redis.Client, error) {
+	if len(databases) == 0 {
+		return nil, fmt.Errorf("at least one redis database is required")
+	}
+
+	clients := make(map[int]*redis.Client, len(databases))
+	for _, db := range databases {
+		dbCfg := cloneRedisConfig(cfg)
+		dbCfg.DB = db
+		client, err := m.AddRedis(redisDBName(name, db), dbCfg)
+		if err != nil {
+			for added := range clients {
+				m.removeRedis(redisDBName(name, added))
+			}
+			return nil, fmt.Errorf("redis db %d: %w", db, err)
+		}
+		clients[db] = client
+	}
+	return clients, nil
+}
+
+// RedisDB 返回通过 AddRedisDatabases 注册的指定逻辑数据库的客户端
+func (m *Manager) RedisDB(name string, db int) (*redis.Client, error) {
+	return m.Redis(redisDBName(name, db))
+}
+
+// removeRedis 注销并关闭 Redis 客户端
+func (m *Manager) removeRedis(name string) {
+	m.mu.Lock()
+	conn, ok := m.redis[name]
+	delete(m.redis, name)
+	m.mu.Unlock()
+	if ok {
+		if conn.health != nil {
+			_ = conn.health.Stop(context.Background())
+		}
+		_ = CloseRedis(conn.client)
+	}
+}
+
+// redisDBName 返回逻辑数据库客户端的注册名称
+func redisDBName(name string, db int) string {
+	return fmt.Sprintf("%s/%d", name, db)
+}
+
+// addSQL 注册 SQL 连接
+func (m *Manager) addSQL(conn *sqlConn) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.sql[conn.name]; exists {
+		return fmt.Errorf("database connection %q already registered", conn.name)
+	}
+	m.sql[conn.name] = conn
+	trackGormDB(conn.db, conn.driver+":"+conn.name)
+	return nil
+}
+
+// DB 返回指定名称的数据库连接
+// 调用 ReleaseHandle 之后连接可能因热更新而重建，旧连接在 30s 后关闭，获取的连接不应长期持有
+func (m *Manager) DB(name string) (*gorm.DB, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	conn, ok := m.sql[name]
+	if !ok {
+		return nil, fmt.Errorf("database connection %q not found", name)
+	}
+	return conn.db, nil
+}
+
+// Redis 返回指定名称的 Redis 客户端
+// 调用 ReleaseHandle 之后客户端可能因热更新或健康检查而重建，旧客户端在 30s 后关闭，获取的客户端不应长期持有
+func (m *Manager) Redis(name string) (*redis.Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	conn, ok := m.redis[name]
+	if !ok {
+		return nil, fmt.Errorf("redis connection %q not found", name)
+	}
+	return conn.client, nil
+}
+
+// ReleaseHandle 声明调用方不再持有 AddMySQL、AddPostgreSQL、AddRedis 返回的句柄，之后只通过 DB、Redis 等方法按需获取。
+// 此后需要重建连接的热更新和 Redis 健康检查会创建新连接替换旧连接，旧连接在 30s 后关闭
+func (m *Manager) ReleaseHandle(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conn, ok := m.sql[name]; ok {
+		conn.held = false
+		return nil
+	}
+	if conn, ok := m.redis[name]; ok {
+		conn.held = false
+		return nil
+	}
+	return fmt.Errorf("connection %q not found", name)
+}
+
+// Mongo 返回指定名称的 MongoDB 客户端
+func (m *Manager) Mongo(name string) (*mongo.Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.mongo[name]
+	if !ok {
+		return nil, fmt.Errorf("mongo connection %q not found", name)
+	}
+	return client, nil
+}
+
+// Close 关闭所有已注册的连接
+func (m *Manager) Close() error {
+	// 健康检查重建客户端时需要获取锁，先在锁外停止
+	m.mu.RLock()
+	var checkers []*RedisHealthChecker
+	for _, conn := range m.redis {
+		if conn.health != nil {
+			checkers = append(checkers, conn.health)
+		}
+	}
+	m.mu.RUnlock()
+	for _, health := range checkers {
+		_ = health.Stop(context.Background())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for name, conn := range m.sql {
+		if err := CloseSQLDB(conn.db); err != nil && !errors.Is(err, ErrInvalidHandle) {
+			errs = append(errs, fmt.Errorf("failed to close database %q: %w", name, err))
+		}
+		delete(m.sql, name)
+	}
+	for name, conn := range m.redis {
+		if err := CloseRedis(conn.client); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close redis %q: %w", name, err))
+		}
+		delete(m.redis, name)
+	}
+	for name, client := range m.mongo {
+		if err := client.Disconnect(context.Background()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close mongo %q: %w", name, err))
+		}
+		delete(m.mongo, name)
+	}
+	return errors.Join(errs...)
+}
+
+// closeGormDB 关闭 GORM 底层连接池，忽略错误
+func closeGormDB(db *gorm.DB) {
+	_ = CloseSQLDB(db)
+}
+
+// cloneMySQLConfig 复制配置，避免调用方修改影响管理器中保存的配置
+func cloneMySQLConfig(cfg *MySQLConfig) *MySQLConfig {
+	c := *cfg
+	c.IsolationReadEngines = append([]string(nil), cfg.IsolationReadEngines...)
+	c.Hosts = append([]string(nil), cfg.Hosts...)
+	c.SessionVars = cloneStringMap(cfg.SessionVars)
+	c.Params = cloneStringMap(cfg.Params)
+	return &c
+}
+
+// clonePostgreSQLConfig 复制配置，避免调用方修改影响管理器中保存的配置
+func clonePostgreSQLConfig(cfg *PostgreSQLConfig) *PostgreSQLConfig {
+	c := *cfg
+	c.Hosts = append([]string(nil), cfg.Hosts...)
+	c.SessionVars = cloneStringMap(cfg.SessionVars)
+	return &c
+}
+
+// cloneRedisConfig 复制配置，避免调用方修改影响管理器中保存的配置
+func cloneRedisConfig(cfg *RedisConfig) *RedisConfig {
+	c := *cfg
+	c.LogSkipCommands = append([]string(nil), cfg.LogSkipCommands...)
+	return &c
+}
@@ -0,0 +1,51 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCloneMySQLConfig(t *testing.T) {
+	newConfig := func() *MySQLConfig {
+		return &MySQLConfig{
+			IsolationReadEngines: []string{"tikv"},
+			Hosts:                []string{"db-1:3306"},
+			SessionVars:          map[string]string{"sql_mode": "'STRICT_ALL_TABLES'"},
+			Params:               map[string]string{"readTimeout": "3s"},
+		}
+	}
+	tests := []struct {
+		name   string
+		mutate func(c *MySQLConfig)
+	}{
+		{name: "isolation read engines", mutate: func(c *MySQLConfig) { c.IsolationReadEngines[0] = "tiflash" }},
+		{name: "hosts", mutate: func(c *MySQLConfig) { c.Hosts[0] = "db-2:3306" }},
+		{name: "session vars", mutate: func(c *MySQLConfig) { c.SessionVars["sql_mode"] = "''" }},
+		{name: "params", mutate: func(c *MySQLConfig) { c.Params["readTimeout"] = "30s" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := newConfig()
+			tt.mutate(cloneMySQLConfig(orig))
+			if !reflect.DeepEqual(orig, newConfig()) {
+				t.Errorf("mutating the clone changed the original: %+v", orig)
+			}
+		})
+	}
+}
@@ -0,0 +1,573 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 本包自有的指标，通用的查询/命令指标由 framework-metrics 提供
+var (
+	// CacheDegraded 缓存是否处于降级模式（1 表示 Redis 不可用，正在使用本地缓存）
+	CacheDegraded = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_degraded",
+			Help: "Whether the cache is serving from the local fallback (1) or Redis (0)",
+		},
+	)
+
+	// CacheDegradedOperationTotal 降级模式下处理的缓存操作总数
+	CacheDegradedOperationTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_degraded_operations_total",
+			Help: "Total number of cache operations served by the local fallback",
+		},
+		[]string{"operation", "result"},
+	)
+
+	// CacheReplayTotal 降级期间排队的写操作回放总数
+	CacheReplayTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_replay_writes_total",
+			Help: "Total number of queued cache writes replayed, expired or dropped, and of keys invalidated for dropped writes",
+		},
+		[]string{"status"},
+	)
+
+	// DatabaseSLIRequestTotal 按数据源统计的请求结果，用于计算成功率 SLI
+	DatabaseSLIRequestTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "datasource_sli_requests_total",
+			Help: "Total number of datasource requests by result, for SLI calculation",
+		},
+		[]string{"datasource", "result"},
+	)
+
+	// DatabaseSLILatencyViolationTotal 超过延迟阈值的请求数
+	DatabaseSLILatencyViolationTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "datasource_sli_latency_violations_total",
+			Help: "Total number of datasource requests slower than the configured latency threshold",
+		},
+		[]string{"datasource", "operation", "threshold"},
+	)
+
+	// DatabaseSLISuccessRatio 滚动窗口内的请求成功率
+	DatabaseSLISuccessRatio = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "datasource_sli_success_ratio",
+			Help: "Ratio of successful datasource requests over a rolling window",
+		},
+		[]string{"datasource", "window"},
+	)
+
+	// DatabaseQueriesInFlight 正在执行的查询数
+	DatabaseQueriesInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "database_queries_in_flight",
+			Help: "Number of database queries currently executing",
+		},
+		[]string{"datasource", "operation"},
+	)
+
+	// DatabaseInstanceRequestTotal 按逻辑连接名称统计的请求数（仅设置了 InstanceName 的连接）
+	DatabaseInstanceRequestTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "datasource_instance_requests_total",
+			Help: "Total number of SQL statements and Redis commands by logical connection name",
+		},
+		[]string{"instance", "system", "operation", "status"},
+	)
+
+	// DatabaseInstanceRequestDuration 按逻辑连接名称统计的请求耗时（仅设置了 InstanceName 的连接）
+	DatabaseInstanceRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "datasource_instance_request_duration_seconds",
+			Help:    "Duration of SQL statements and Redis commands by logical connection name",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"instance", "system", "operation"},
+	)
+
+	// RedisConnectionState Redis 健康检查的连接状态（1 表示最近一次 PING 成功）
+	RedisConnectionState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "redis_connection_state",
+			Help: "Whether the last Redis health check ping succeeded (1) or failed (0)",
+		},
+		[]string{"datasource"},
+	)
+
+	// RedisClientRecreateTotal 健康检查连续失败后重建 Redis 客户端的次数
+	RedisClientRecreateTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "redis_client_recreate_total",
+			Help: "Total number of Redis client recreations after sustained health check failures by result",
+		},
+		[]string{"datasource", "result"},
+	)
+
+	// CredentialRefreshTotal 服务端拒绝凭据后刷新凭据提供者的次数
+	CredentialRefreshTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_credential_refresh_total",
+			Help: "Total number of credential refreshes triggered by authentication errors by result",
+		},
+		[]string{"datasource", "result"},
+	)
+
+	// RedisPubSubMessageTotal 订阅消息处理总数（按订阅的频道或模式统计）
+	RedisPubSubMessageTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "redis_pubsub_messages_total",
+			Help: "Total number of Redis pubsub messages by subscription and status",
+		},
+		[]string{"subscription", "status"},
+	)
+
+	// RedisPubSubReconnectTotal 订阅连接断开后重新订阅的次数
+	RedisPubSubReconnectTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "redis_pubsub_reconnects_total",
+			Help: "Total number of Redis pubsub resubscriptions after connection loss",
+		},
+	)
+
+	// RedisStreamMessageTotal Stream 消费者组处理消息总数
+	RedisStreamMessageTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "redis_stream_messages_total",
+			Help: "Total number of Redis stream messages by stream, group and status",
+		},
+		[]string{"stream", "group", "status"},
+	)
+
+	// RedisStreamLag 消费者组尚未读取的消息数
+	RedisStreamLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "redis_stream_lag",
+			Help: "Number of entries in the stream not yet delivered to the consumer group",
+		},
+		[]string{"stream", "group"},
+	)
+
+	// RedisStreamPending 消费者组已读取但未确认的消息数
+	RedisStreamPending = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "redis_stream_pending",
+			Help: "Number of entries delivered to the consumer group but not yet acknowledged",
+		},
+		[]string{"stream", "group"},
+	)
+
+	// JobQueueJobsTotal 任务队列处理的任务总数
+	JobQueueJobsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "job_queue_jobs_total",
+			Help: "Total number of jobs by queue, type and status",
+		},
+		[]string{"queue", "type", "status"},
+	)
+
+	// JobQueueLatency 任务从计划执行时间到开始执行的延迟
+	JobQueueLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "job_queue_latency_seconds",
+			Help:    "Delay between a job becoming ready and a worker starting it",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"queue", "type"},
+	)
+
+	// MigrationDuration 单个迁移的执行耗时
+	MigrationDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_migration_duration_seconds",
+			Help:    "Duration of schema migrations by version and status",
+			Buckets: []float64{.1, .5, 1, 5, 10, 30, 60, 300, 900},
+		},
+		[]string{"version", "status"},
+	)
+
+	// MigrationLockWait 等待迁移咨询锁的耗时
+	MigrationLockWait = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "db_migration_lock_wait_seconds",
+			Help:    "Time spent waiting for the migration advisory lock",
+			Buckets: []float64{.01, .1, .5, 1, 5, 30, 60, 300},
+		},
+	)
+
+	// AutoMigrateDuration 托管 AutoMigrate 单个表的迁移耗时
+	AutoMigrateDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_automigrate_duration_seconds",
+			Help:    "Duration of managed AutoMigrate runs by table and status",
+			Buckets: []float64{.01, .05, .1, .5, 1, 5, 30, 60, 300},
+		},
+		[]string{"table", "status"},
+	)
+
+	// RedisDialDuration Redis 建立连接的耗时
+	RedisDialDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "redis_dial_duration_seconds",
+			Help:    "Duration of Redis connection dials by address and status",
+			Buckets: []float64{.001, .005, .01, .05, .1, .5, 1, 5},
+		},
+		[]string{"addr", "status"},
+	)
+
+	// MongoCommandTotal MongoDB 命令执行总数
+	MongoCommandTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mongo_commands_total",
+			Help: "Total number of MongoDB commands by command and status",
+		},
+		[]string{"command", "status"},
+	)
+
+	// MongoCommandDuration MongoDB 命令执行耗时
+	MongoCommandDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mongo_command_duration_seconds",
+			Help:    "Duration of MongoDB commands",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"command"},
+	)
+
+	// MongoPoolConnections MongoDB 连接池连接数（open 为已建立，in_use 为已借出）
+	MongoPoolConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mongo_pool_connections",
+			Help: "Number of MongoDB pool connections by server address and state",
+		},
+		[]string{"addr", "state"},
+	)
+
+	// MongoPoolCheckoutFailedTotal 从 MongoDB 连接池获取连接失败的次数
+	MongoPoolCheckoutFailedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mongo_pool_checkout_failed_total",
+			Help: "Total number of failed MongoDB connection checkouts by reason",
+		},
+		[]string{"addr", "reason"},
+	)
+
+	// ElasticsearchRequestTotal Elasticsearch 请求总数
+	ElasticsearchRequestTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "elasticsearch_requests_total",
+			Help: "Total number of Elasticsearch requests by operation and status",
+		},
+		[]string{"operation", "status"},
+	)
+
+	// ElasticsearchRequestDuration Elasticsearch 请求耗时
+	ElasticsearchRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "elasticsearch_request_duration_seconds",
+			Help:    "Duration of Elasticsearch requests",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"operation"},
+	)
+
+	// DatabaseCopyRowsTotal COPY 批量导入的行数
+	DatabaseCopyRowsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_copy_rows_total",
+			Help: "Total number of rows bulk-loaded with COPY by table",
+		},
+		[]string{"table"},
+	)
+
+	// DatabaseCopyDuration 单个 COPY 分块的执行耗时
+	DatabaseCopyDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_copy_chunk_duration_seconds",
+			Help:    "Duration of COPY chunks by table and status",
+			Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60},
+		},
+		[]string{"table", "status"},
+	)
+
+	// DatabaseRetentionRowsTotal 保留策略清理的行数
+	DatabaseRetentionRowsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_retention_rows_total",
+			Help: "Total number of rows purged or archived by retention policy",
+		},
+		[]string{"policy", "action"},
+	)
+
+	// DatabaseRetentionRunDuration 单次执行保留策略的耗时
+	DatabaseRetentionRunDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_retention_run_duration_seconds",
+			Help:    "Duration of retention policy runs by policy and status",
+			Buckets: []float64{.1, .5, 1, 5, 10, 30, 60, 300, 900},
+		},
+		[]string{"policy", "status"},
+	)
+
+	// DatabaseListenerNotificationTotal PostgreSQL LISTEN 收到的通知处理总数
+	DatabaseListenerNotificationTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_listener_notifications_total",
+			Help: "Total number of PostgreSQL notifications by channel and status",
+		},
+		[]string{"channel", "status"},
+	)
+
+	// DatabaseListenerReconnectTotal LISTEN 连接断开后重新连接的次数
+	DatabaseListenerReconnectTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "db_listener_reconnects_total",
+			Help: "Total number of PostgreSQL listener reconnections after connection loss",
+		},
+	)
+
+	// DatabaseCDCEventsTotal 变更数据捕获处理的事件总数
+	DatabaseCDCEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_cdc_events_total",
+			Help: "Total number of change data capture events by source, table, action and status",
+		},
+		[]string{"source", "table", "action", "status"},
+	)
+
+	// DatabaseCDCReconnectTotal 变更数据捕获连接断开后重新连接的次数
+	DatabaseCDCReconnectTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_cdc_reconnects_total",
+			Help: "Total number of change data capture reconnections by source",
+		},
+		[]string{"source"},
+	)
+
+	// DatabaseCDCLag 最近处理的变更从提交到处理完成的延迟
+	DatabaseCDCLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_cdc_lag_seconds",
+			Help: "Delay between commit and handling of the latest change data capture event",
+		},
+		[]string{"source"},
+	)
+
+	// DatabaseReplicaLag 副本的复制延迟
+	DatabaseReplicaLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_replica_lag_seconds",
+			Help: "Replication lag of database replicas measured by the lag monitor",
+		},
+		[]string{"replica"},
+	)
+
+	// DatabaseReplicaAvailable 副本是否参与读取路由（1 参与，0 因延迟过大或探测失败被移出）
+	DatabaseReplicaAvailable = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_replica_available",
+			Help: "Whether a database replica is in the read rotation (1) or removed due to lag or probe failure (0)",
+		},
+		[]string{"replica"},
+	)
+
+	// DatabaseFailoverTotal 多节点连接切换节点的次数
+	DatabaseFailoverTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_failover_total",
+			Help: "Total number of multi-host failovers by datasource and the host switched to",
+		},
+		[]string{"datasource", "host"},
+	)
+
+	// DatabaseLongTransactions 当前持有连接超过泄漏检测阈值的事务数
+	DatabaseLongTransactions = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_long_transactions",
+			Help: "Number of currently open transactions holding a connection longer than the leak detection threshold",
+		},
+		[]string{"datasource"},
+	)
+
+	// DatabaseUnclosedRows 当前持有连接超过泄漏检测阈值的未关闭结果集数
+	DatabaseUnclosedRows = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_unclosed_rows",
+			Help: "Number of result sets returned by Rows() that hold a connection longer than the leak detection threshold without being closed",
+		},
+		[]string{"datasource"},
+	)
+
+	// DatabaseShadowStatementsTotal 影子库回放语句的次数，result 为 ok、error、dropped、skipped
+	DatabaseShadowStatementsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_shadow_statements_total",
+			Help: "Total number of statements mirrored to a shadow database by operation and result",
+		},
+		[]string{"shadow", "operation", "result"},
+	)
+
+	// DatabaseShadowDivergenceTotal 影子库结果（行数或主键）与主库不一致的次数
+	DatabaseShadowDivergenceTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_shadow_divergence_total",
+			Help: "Total number of shadow database results diverging from the primary by operation",
+		},
+		[]string{"shadow", "operation"},
+	)
+
+	// DatabaseCircuitBreakerState 熔断器当前状态：0 关闭，1 半开，2 打开
+	DatabaseCircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "db_circuit_breaker_state",
+			Help: "Current circuit breaker state: 0 closed, 1 half-open, 2 open",
+		},
+		[]string{"name"},
+	)
+
+	// DatabaseCircuitBreakerTransitionsTotal 熔断器状态变化次数
+	DatabaseCircuitBreakerTransitionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_circuit_breaker_transitions_total",
+			Help: "Total number of circuit breaker state transitions",
+		},
+		[]string{"name", "from", "to"},
+	)
+
+	// DatabaseCircuitBreakerRejectedTotal 熔断器拒绝的请求数
+	DatabaseCircuitBreakerRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "db_circuit_breaker_rejected_total",
+			Help: "Total number of requests rejected by an open circuit breaker",
+		},
+		[]string{"name"},
+	)
+
+	// LeaderElectionIsLeader 当前实例是否持有领导权（1 表示是）
+	LeaderElectionIsLeader = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "leader_election_is_leader",
+			Help: "Whether this instance currently holds the leadership key (1) or not (0)",
+		},
+		[]string{"key"},
+	)
+
+	// LeaderElectionTransitionsTotal 当选和卸任的次数
+	LeaderElectionTransitionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "leader_election_transitions_total",
+			Help: "Total number of leadership transitions by event: elected, resigned, lost",
+		},
+		[]string{"key", "event"},
+	)
+
+	// SchedulerRunsTotal 定时作业的执行次数
+	SchedulerRunsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_runs_total",
+			Help: "Total number of scheduled job runs by job and status: succeeded, failed, skipped, overlapped, claim_failed",
+		},
+		[]string{"job", "status"},
+	)
+
+	// SchedulerRunDuration 定时作业的执行耗时
+	SchedulerRunDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "scheduler_run_duration_seconds",
+			Help:    "Duration of scheduled job runs executed by this instance",
+			Buckets: []float64{.1, .5, 1, 5, 10, 30, 60, 300, 900},
+		},
+		[]string{"job"},
+	)
+
+	// SchedulerLastSuccess 定时作业最近一次在本实例上执行成功的时间（Unix 秒）
+	SchedulerLastSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "scheduler_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful run of a scheduled job on this instance",
+		},
+		[]string{"job"},
+	)
+
+	// CacheWarmRunsTotal 缓存预热加载器的执行次数
+	CacheWarmRunsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_warm_runs_total",
+			Help: "Total number of cache warm loader runs by pattern and status: succeeded, failed",
+		},
+		[]string{"pattern", "status"},
+	)
+
+	// CacheWarmKeysTotal 缓存预热写入的条目数
+	CacheWarmKeysTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_warm_keys_total",
+			Help: "Total number of cache entries written by warm loaders by pattern and result: written, failed",
+		},
+		[]string{"pattern", "result"},
+	)
+
+	// CacheWarmDuration 缓存预热加载器的执行耗时
+	CacheWarmDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cache_warm_duration_seconds",
+			Help:    "Duration of cache warm loader runs",
+			Buckets: []float64{.1, .5, 1, 5, 10, 30, 60, 300, 900},
+		},
+		[]string{"pattern"},
+	)
+
+	// CounterFlushTotal 计数器持久化的条目数
+	CounterFlushTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "counter_flush_total",
+			Help: "Total number of counters flushed to the database by result: succeeded, failed",
+		},
+		[]string{"result"},
+	)
+
+	// QueryBudgetExceededTotal 请求超出查询预算的次数
+	QueryBudgetExceededTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "query_budget_exceeded_total",
+			Help: "Total number of requests that exceeded their query budget, by whether the budget is enforced",
+		},
+		[]string{"enforced"},
+	)
+
+	// SlowQuerySinkWritesTotal 慢查询输出的写入次数，result 为 written、failed、dropped
+	SlowQuerySinkWritesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "slow_query_sink_writes_total",
+			Help: "Total number of slow queries written to sinks by sink and result: written, failed, dropped",
+		},
+		[]string{"sink", "result"},
+	)
+
+	// CacheInvalidationTotal 发件箱缓存失效的键数，result 为 immediate、deferred、relayed、failed
+	CacheInvalidationTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_invalidation_total",
+			Help: "Total number of outbox cache invalidations by result: immediate, deferred, relayed, failed",
+		},
+		[]string{"result"},
+	)
+)
@@ -0,0 +1,127 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespacePattern Prometheus 指标名前缀的合法格式
+var metricsNamespacePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// connMetrics 连接的追踪插件和 Hook 记录的查询/命令指标
+type connMetrics struct {
+	queryTotal       *prometheus.CounterVec
+	queryDuration    *prometheus.HistogramVec
+	queriesInFlight  *prometheus.GaugeVec
+	redisTotal       *prometheus.CounterVec
+	redisDuration    *prometheus.HistogramVec
+	redisDial        *prometheus.HistogramVec
+	instanceTotal    *prometheus.CounterVec
+	instanceDuration *prometheus.HistogramVec
+}
+
+var (
+	// defaultConnMetrics 未设置命名空间的连接使用的指标，即 framework-metrics 和本包的全局指标
+	defaultConnMetrics = &connMetrics{
+		queryTotal:       metrics.DatabaseQueryTotal,
+		queryDuration:    metrics.DatabaseQueryDuration,
+		queriesInFlight:  DatabaseQueriesInFlight,
+		redisTotal:       metrics.RedisOperationTotal,
+		redisDuration:    metrics.RedisOperationDuration,
+		redisDial:        RedisDialDuration,
+		instanceTotal:    DatabaseInstanceRequestTotal,
+		instanceDuration: DatabaseInstanceRequestDuration,
+	}
+
+	namespacedMetricsMu sync.Mutex
+	namespacedMetrics   = make(map[string]*connMetrics)
+)
+
+// validateMetricsNamespace 检查指标命名空间
+func validateMetricsNamespace(ns string) error {
+	if ns != "" && !metricsNamespacePattern.MatchString(ns) {
+		return fmt.Errorf("metrics_namespace %q must match %s", ns, metricsNamespacePattern)
+	}
+	return nil
+}
+
+// metricsForNamespace 返回命名空间对应的指标，指标名为 <ns>_<默认名>，与默认指标的标签和分桶相同。
+// 同一命名空间的连接共享指标，首次使用时注册到默认 Registry
+func metricsForNamespace(ns string) *connMetrics {
+	if ns == "" {
+		return defaultConnMetrics
+	}
+	namespacedMetricsMu.Lock()
+	defer namespacedMetricsMu.Unlock()
+	if m, ok := namespacedMetrics[ns]; ok {
+		return m
+	}
+
+	m := &connMetrics{
+		queryTotal: registerOrExisting(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "database_queries_total", Help: "Total number of database queries",
+		}, []string{"operation", "status"})),
+		queryDuration: registerOrExisting(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "database_query_duration_seconds", Help: "Database query duration in seconds",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"operation"})),
+		queriesInFlight: registerOrExisting(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns, Name: "database_queries_in_flight", Help: "Number of database queries currently executing",
+		}, []string{"datasource", "operation"})),
+		redisTotal: registerOrExisting(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "redis_operations_total", Help: "Total number of Redis operations",
+		}, []string{"operation", "status"})),
+		redisDuration: registerOrExisting(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "redis_operation_duration_seconds", Help: "Redis operation duration in seconds",
+			Buckets: []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"})),
+		redisDial: registerOrExisting(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "redis_dial_duration_seconds", Help: "Duration of Redis connection dials by address and status",
+			Buckets: []float64{.001, .005, .01, .05, .1, .5, 1, 5},
+		}, []string{"addr", "status"})),
+		instanceTotal: registerOrExisting(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "datasource_instance_requests_total", Help: "Total number of SQL statements and Redis commands by logical connection name",
+		}, []string{"instance", "system", "operation", "status"})),
+		instanceDuration: registerOrExisting(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "datasource_instance_request_duration_seconds", Help: "Duration of SQL statements and Redis commands by logical connection name",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"instance", "system", "operation"})),
+	}
+	namespacedMetrics[ns] = m
+	return m
+}
+
+// registerOrExisting 注册指标，已注册相同指标时返回已注册的实例
+func registerOrExisting[C prometheus.Collector](c C) C {
+	if err := prometheus.Register(c); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if errors.As(err, &already) {
+			if existing, ok := already.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+	}
+	return c
+}
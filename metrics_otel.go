@@ -0,0 +1,147 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// otelMeterName OpenTelemetry 指标的 instrumentation scope 名称
+const otelMeterName = "github.com/go-anyway/framework-db"
+
+// otelDurationBuckets 耗时直方图的分桶，覆盖 Redis 命令和 SQL 语句的耗时范围（秒）
+var otelDurationBuckets = []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// otelInstruments 同一命名空间的连接共享的 OpenTelemetry instrument
+type otelInstruments struct {
+	operations metric.Int64Counter
+	duration   metric.Float64Histogram
+	inFlight   metric.Int64UpDownCounter
+	dial       metric.Float64Histogram
+}
+
+var (
+	otelInstrumentsMu sync.Mutex
+	otelInstrumentsNS = make(map[string]*otelInstruments)
+)
+
+// otelInstrumentsFor 返回命名空间对应的 instrument，instrument 名为 <ns>.db.client.*。
+// instrument 从全局 MeterProvider 创建，应用启动后再设置 MeterProvider 时会自动切换
+func otelInstrumentsFor(ns string) *otelInstruments {
+	otelInstrumentsMu.Lock()
+	defer otelInstrumentsMu.Unlock()
+	if inst, ok := otelInstrumentsNS[ns]; ok {
+		return inst
+	}
+
+	prefix := ""
+	if ns != "" {
+		prefix = ns + "."
+	}
+	meter := otel.Meter(otelMeterName)
+	inst := &otelInstruments{}
+	var errs [4]error
+	inst.operations, errs[0] = meter.Int64Counter(prefix+"db.client.operations",
+		metric.WithDescription("Number of SQL statements and Redis commands"), metric.WithUnit("{operation}"))
+	inst.duration, errs[1] = meter.Float64Histogram(prefix+"db.client.operation.duration",
+		metric.WithDescription("Duration of SQL statements and Redis commands"), metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(otelDurationBuckets...))
+	inst.inFlight, errs[2] = meter.Int64UpDownCounter(prefix+"db.client.operations.in_flight",
+		metric.WithDescription("Number of SQL statements currently executing"), metric.WithUnit("{operation}"))
+	inst.dial, errs[3] = meter.Float64Histogram(prefix+"db.client.connection.dial.duration",
+		metric.WithDescription("Duration of Redis connection dials"), metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(otelDurationBuckets...))
+	// 创建失败时 SDK 仍返回可用的 instrument，仅记录告警
+	if err := errors.Join(errs[:]...); err != nil {
+		log.Warn("Failed to create OpenTelemetry instruments", zap.String("namespace", ns), zap.Error(err))
+	}
+	otelInstrumentsNS[ns] = inst
+	return inst
+}
+
+// otelMetrics 以 OpenTelemetry instrument 记录连接的指标。与 Prometheus 不同，实例名作为 db.instance 属性
+// 附加在所有测量上，不单独记录实例指标
+type otelMetrics struct {
+	inst     *otelInstruments
+	instance string
+}
+
+// newOTelMetrics 创建连接的 OpenTelemetry 指标记录器
+func newOTelMetrics(ns, instance string) *otelMetrics {
+	return &otelMetrics{inst: otelInstrumentsFor(ns), instance: instance}
+}
+
+// enabled OpenTelemetry 指标由 MeterProvider 决定是否导出，未设置时为 no-op
+func (m *otelMetrics) enabled() bool {
+	return true
+}
+
+func (m *otelMetrics) addInFlight(ctx context.Context, datasource, kind string, delta int64) {
+	m.inst.inFlight.Add(ctx, delta, metric.WithAttributes(m.attributes(
+		attribute.String("datasource", datasource),
+		attribute.String("db.operation", kind),
+	)...))
+}
+
+func (m *otelMetrics) recordQuery(ctx context.Context, system, operation, status string, d time.Duration) {
+	m.record(ctx, system, operation, status, d)
+}
+
+func (m *otelMetrics) recordRedis(ctx context.Context, operation, status string, d time.Duration) {
+	m.record(ctx, "redis", operation, status, d)
+}
+
+func (m *otelMetrics) recordRedisDial(ctx context.Context, addr, status string, d time.Duration) {
+	m.inst.dial.Record(ctx, d.Seconds(), metric.WithAttributes(m.attributes(
+		attribute.String("db.system", "redis"),
+		attribute.String("server.address", addr),
+		attribute.String("status", status),
+	)...))
+}
+
+// recordInstance 实例名已作为属性附加在 recordQuery 和 recordRedis 的测量上
+func (m *otelMetrics) recordInstance(context.Context, string, string, string, string, time.Duration) {
+}
+
+// record 记录一次操作的次数和耗时
+func (m *otelMetrics) record(ctx context.Context, system, operation, status string, d time.Duration) {
+	attrs := metric.WithAttributes(m.attributes(
+		attribute.String("db.system", system),
+		attribute.String("db.operation", operation),
+		attribute.String("status", status),
+	)...)
+	m.inst.operations.Add(ctx, 1, attrs)
+	m.inst.duration.Record(ctx, d.Seconds(), attrs)
+}
+
+// attributes 附加实例名属性
+func (m *otelMetrics) attributes(attrs ...attribute.KeyValue) []attribute.KeyValue {
+	if m.instance != "" {
+		attrs = append(attrs, attribute.String("db.instance", m.instance))
+	}
+	return attrs
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-anyway/framework-metrics"
+)
+
+// 查询/命令指标的输出后端
+const (
+	MetricsBackendPrometheus = "prometheus" // 仅记录 Prometheus 指标（默认）
+	MetricsBackendOTel       = "otel"       // 仅记录 OpenTelemetry 指标，由全局 MeterProvider 导出（如 OTLP）
+	MetricsBackendBoth       = "both"       // 同时记录两者，用于迁移期间对比
+)
+
+// metricsRecorder 追踪插件和 Hook 记录查询/命令指标的接口，屏蔽 Prometheus 和 OpenTelemetry 的差异
+type metricsRecorder interface {
+	// enabled 返回当前是否记录指标
+	enabled() bool
+	// addInFlight 增减执行中的语句数，kind 为操作类型
+	addInFlight(ctx context.Context, datasource, kind string, delta int64)
+	// recordQuery 记录一条 SQL 语句，system 为 db.system（mysql、postgresql 等）
+	recordQuery(ctx context.Context, system, operation, status string, d time.Duration)
+	// recordRedis 记录一条 Redis 命令或一个管道
+	recordRedis(ctx context.Context, operation, status string, d time.Duration)
+	// recordRedisDial 记录一次 Redis 建连
+	recordRedisDial(ctx context.Context, addr, status string, d time.Duration)
+	// recordInstance 记录按逻辑连接名区分的请求
+	recordInstance(ctx context.Context, instance, system, operation, status string, d time.Duration)
+}
+
+// validateMetricsBackend 检查指标输出后端
+func validateMetricsBackend(backend string) error {
+	switch backend {
+	case "", MetricsBackendPrometheus, MetricsBackendOTel, MetricsBackendBoth:
+		return nil
+	}
+	return fmt.Errorf("metrics_backend %q must be one of %s, %s, %s",
+		backend, MetricsBackendPrometheus, MetricsBackendOTel, MetricsBackendBoth)
+}
+
+// newMetricsRecorder 按输出后端和命名空间创建连接的指标记录器
+func newMetricsRecorder(backend, ns, instance string) metricsRecorder {
+	switch backend {
+	case MetricsBackendOTel:
+		return newOTelMetrics(ns, instance)
+	case MetricsBackendBoth:
+		return multiMetricsRecorder{metricsForNamespace(ns), newOTelMetrics(ns, instance)}
+	default:
+		return metricsForNamespace(ns)
+	}
+}
+
+// enabled Prometheus 指标受 framework-metrics 的全局开关控制
+func (m *connMetrics) enabled() bool {
+	return metrics.IsEnabled()
+}
+
+func (m *connMetrics) addInFlight(_ context.Context, datasource, kind string, delta int64) {
+	m.queriesInFlight.WithLabelValues(datasource, kind).Add(float64(delta))
+}
+
+func (m *connMetrics) recordQuery(_ context.Context, _, operation, status string, d time.Duration) {
+	m.queryTotal.WithLabelValues(operation, status).Inc()
+	m.queryDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+func (m *connMetrics) recordRedis(_ context.Context, operation, status string, d time.Duration) {
+	m.redisTotal.WithLabelValues(operation, status).Inc()
+	m.redisDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+func (m *connMetrics) recordRedisDial(_ context.Context, addr, status string, d time.Duration) {
+	m.redisDial.WithLabelValues(addr, status).Observe(d.Seconds())
+}
+
+func (m *connMetrics) recordInstance(_ context.Context, instance, system, operation, status string, d time.Duration) {
+	m.instanceTotal.WithLabelValues(instance, system, operation, status).Inc()
+	m.instanceDuration.WithLabelValues(instance, system, operation).Observe(d.Seconds())
+}
+
+// multiMetricsRecorder 同时写入多个记录器，跳过当前未启用的记录器
+type multiMetricsRecorder []metricsRecorder
+
+func (m multiMetricsRecorder) enabled() bool {
+	for _, r := range m {
+		if r.enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiMetricsRecorder) addInFlight(ctx context.Context, datasource, kind string, delta int64) {
+	for _, r := range m {
+		if r.enabled() {
+			r.addInFlight(ctx, datasource, kind, delta)
+		}
+	}
+}
+
+func (m multiMetricsRecorder) recordQuery(ctx context.Context, system, operation, status string, d time.Duration) {
+	for _, r := range m {
+		if r.enabled() {
+			r.recordQuery(ctx, system, operation, status, d)
+		}
+	}
+}
+
+func (m multiMetricsRecorder) recordRedis(ctx context.Context, operation, status string, d time.Duration) {
+	for _, r := range m {
+		if r.enabled() {
+			r.recordRedis(ctx, operation, status, d)
+		}
+	}
+}
+
+func (m multiMetricsRecorder) recordRedisDial(ctx context.Context, addr, status string, d time.Duration) {
+	for _, r := range m {
+		if r.enabled() {
+			r.recordRedisDial(ctx, addr, status, d)
+		}
+	}
+}
+
+func (m multiMetricsRecorder) recordInstance(ctx context.Context, instance, system, operation, status string, d time.Duration) {
+	for _, r := range m {
+		if r.enabled() {
+			r.recordInstance(ctx, instance, system, operation, status, d)
+		}
+	}
+}
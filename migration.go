@@ -0,0 +1,374 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrMigrationChecksumMismatch 已执行的迁移内容与当前定义不一致
+var ErrMigrationChecksumMismatch = errors.New("migration checksum mismatch")
+
+// Migration 单个迁移，SQL、Up 与 Online 三选一
+type Migration struct {
+	Version  string                  // 版本号，按字典序执行，如 20250101120000
+	Name     string                  // 迁移描述
+	SQL      string                  // 迁移 SQL，包含多条语句时需要驱动支持（MySQL 需开启 multiStatements）
+	Up       func(tx *gorm.DB) error // 自定义迁移逻辑
+	Checksum string                  // Up 迁移的校验内容，修改迁移逻辑时应同步修改；SQL 迁移根据 SQL 自动计算
+
+	// Online MySQL 在线表结构变更，通过 gh-ost、pt-online-schema-change 或内置方式执行，不在事务中执行；
+	// 校验和根据等价的 ALTER TABLE 语句自动计算
+	Online *OnlineSchemaChange
+}
+
+// checksum 计算迁移的校验和
+func (m *Migration) checksum() string {
+	content := m.Checksum
+	switch {
+	case m.SQL != "":
+		content = m.SQL
+	case m.Online != nil:
+		content = m.Online.Statement()
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrationRecord 迁移执行历史
+type MigrationRecord struct {
+	Version    string    `gorm:"primaryKey;size:191"`
+	Name       string    `gorm:"size:255"`
+	Checksum   string    `gorm:"size:64"`
+	Success    bool      `gorm:"not null"`
+	Error      string    `gorm:"type:text"`
+	DurationMs int64     `gorm:"not null"`
+	AppliedAt  time.Time `gorm:"not null"`
+}
+
+// MigrationRunnerOptions 迁移执行器配置选项
+type MigrationRunnerOptions struct {
+	Table       string        // 执行历史表名，默认 schema_migrations
+	LockName    string        // 咨询锁名称，防止多个实例同时执行迁移，默认与表名相同
+	LockTimeout time.Duration // 等待咨询锁的超时，默认 10m
+
+	OnlineSchema *OnlineSchemaOptions // 在线表结构变更的工具配置，执行使用外部工具的 Online 迁移时需要
+}
+
+// MigrationRunner 按版本顺序执行迁移，记录执行历史并校验已执行迁移的校验和
+// MySQL 使用 GET_LOCK，PostgreSQL 使用 pg_advisory_lock 保证同一时间只有一个实例执行迁移
+type MigrationRunner struct {
+	db         *gorm.DB
+	opts       MigrationRunnerOptions
+	migrations []Migration
+}
+
+// NewMigrationRunner 创建迁移执行器
+func NewMigrationRunner(db *gorm.DB, opts *MigrationRunnerOptions, migrations ...Migration) (*MigrationRunner, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	r := &MigrationRunner{db: db}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.Table == "" {
+		r.opts.Table = "schema_migrations"
+	}
+	if r.opts.LockName == "" {
+		r.opts.LockName = r.opts.Table
+	}
+	if r.opts.LockTimeout <= 0 {
+		r.opts.LockTimeout = 10 * time.Minute
+	}
+
+	seen := make(map[string]struct{}, len(migrations))
+	for _, m := range migrations {
+		if m.Version == "" {
+			return nil, fmt.Errorf("migration version cannot be empty")
+		}
+		if _, ok := seen[m.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %s", m.Version)
+		}
+		set := 0
+		for _, ok := range []bool{m.SQL != "", m.Up != nil, m.Online != nil} {
+			if ok {
+				set++
+			}
+		}
+		if set != 1 {
+			return nil, fmt.Errorf("migration %s must set exactly one of SQL, Up and Online", m.Version)
+		}
+		if m.Online != nil {
+			if err := m.Online.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		seen[m.Version] = struct{}{}
+	}
+	r.migrations = append([]Migration(nil), migrations...)
+	sort.Slice(r.migrations, func(i, j int) bool { return r.migrations[i].Version < r.migrations[j].Version })
+	return r, nil
+}
+
+// Run 执行所有未成功执行过的迁移，返回本次执行的版本
+// 已执行迁移的校验和不一致时返回 ErrMigrationChecksumMismatch 且不执行后续迁移
+func (r *MigrationRunner) Run(ctx context.Context) ([]string, error) {
+	ctx, span := pkgtrace.StartSpan(ctx, "migration.run",
+		trace.WithAttributes(
+			attribute.String("db.system", dialectOf(r.db)),
+			attribute.Int("migration.count", len(r.migrations)),
+		),
+	)
+	defer span.End()
+
+	var applied []string
+	err := r.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		release, err := r.lock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		if err := conn.Table(r.opts.Table).AutoMigrate(&MigrationRecord{}); err != nil {
+			return fmt.Errorf("failed to create migration history table: %w", err)
+		}
+		records, err := r.records(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := range r.migrations {
+			m := &r.migrations[i]
+			if rec, ok := records[m.Version]; ok && rec.Success {
+				if rec.Checksum != m.checksum() {
+					return fmt.Errorf("%w: %s (%s)", ErrMigrationChecksumMismatch, m.Version, m.Name)
+				}
+				continue
+			}
+			if err := r.apply(ctx, conn, m); err != nil {
+				return err
+			}
+			applied = append(applied, m.Version)
+		}
+		return nil
+	})
+
+	span.SetAttributes(attribute.Int("migration.applied", len(applied)))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		return applied, err
+	}
+	span.SetStatus(codes.Ok, "")
+	return applied, nil
+}
+
+// Verify 校验执行历史：已执行迁移的校验和不一致、存在失败记录时返回错误
+func (r *MigrationRunner) Verify(ctx context.Context) error {
+	records, err := r.records(r.db.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range r.migrations {
+		m := &r.migrations[i]
+		rec, ok := records[m.Version]
+		if !ok {
+			continue
+		}
+		if !rec.Success {
+			errs = append(errs, fmt.Errorf("migration %s (%s) failed at %s: %s", m.Version, m.Name, rec.AppliedAt.Format(time.RFC3339), rec.Error))
+			continue
+		}
+		if rec.Checksum != m.checksum() {
+			errs = append(errs, fmt.Errorf("%w: %s (%s)", ErrMigrationChecksumMismatch, m.Version, m.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// History 返回按版本排序的执行历史
+func (r *MigrationRunner) History(ctx context.Context) ([]MigrationRecord, error) {
+	var records []MigrationRecord
+	if err := r.db.WithContext(ctx).Table(r.opts.Table).Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %w", err)
+	}
+	return records, nil
+}
+
+// records 读取执行历史
+func (r *MigrationRunner) records(db *gorm.DB) (map[string]MigrationRecord, error) {
+	var list []MigrationRecord
+	if err := db.Table(r.opts.Table).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %w", err)
+	}
+	records := make(map[string]MigrationRecord, len(list))
+	for _, rec := range list {
+		records[rec.Version] = rec
+	}
+	return records, nil
+}
+
+// apply 执行单个迁移并写入执行历史，Online 迁移在事务外执行，其余在事务中执行；失败时在事务外记录失败原因
+func (r *MigrationRunner) apply(ctx context.Context, conn *gorm.DB, m *Migration) error {
+	ctx, span := pkgtrace.StartSpan(ctx, "migration."+m.Version,
+		trace.WithAttributes(
+			attribute.String("migration.version", m.Version),
+			attribute.String("migration.name", m.Name),
+		),
+	)
+	defer span.End()
+
+	conn = conn.WithContext(ctx)
+	start := time.Now()
+	rec := MigrationRecord{Version: m.Version, Name: m.Name, Checksum: m.checksum(), AppliedAt: start}
+	var err error
+	if m.Online != nil {
+		// 在线变更由外部工具或 DDL 执行，无法与执行历史在同一事务中提交
+		if err = runOnlineSchemaChange(ctx, conn, m.Online, r.opts.OnlineSchema); err == nil {
+			rec.Success = true
+			rec.DurationMs = time.Since(start).Milliseconds()
+			err = conn.Table(r.opts.Table).Save(&rec).Error
+		}
+	} else {
+		err = conn.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if m.Up != nil {
+				err = m.Up(tx)
+			} else {
+				err = tx.Exec(m.SQL).Error
+			}
+			if err != nil {
+				return err
+			}
+			rec.Success = true
+			rec.DurationMs = time.Since(start).Milliseconds()
+			return tx.Table(r.opts.Table).Save(&rec).Error
+		})
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		recordMigration(m.Version, "failed", duration)
+		log.FromContext(ctx).Error("Migration failed",
+			zap.String("version", m.Version),
+			zap.String("name", m.Name),
+			zap.Duration("duration", duration),
+			zap.Error(err),
+		)
+
+		rec.Success = false
+		rec.Error = err.Error()
+		rec.DurationMs = duration.Milliseconds()
+		if saveErr := conn.Table(r.opts.Table).Save(&rec).Error; saveErr != nil {
+			log.FromContext(ctx).Error("Failed to record migration failure",
+				zap.String("version", m.Version),
+				zap.Error(saveErr),
+			)
+		}
+		return fmt.Errorf("migration %s (%s) failed: %w", m.Version, m.Name, err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+	recordMigration(m.Version, "success", duration)
+	log.FromContext(ctx).Info("Migration applied",
+		zap.String("version", m.Version),
+		zap.String("name", m.Name),
+		zap.Duration("duration", duration),
+	)
+	return nil
+}
+
+// lock 在当前连接上获取迁移咨询锁，返回释放函数
+func (r *MigrationRunner) lock(ctx context.Context, conn *gorm.DB) (func(), error) {
+	return acquireMigrationLock(ctx, conn, r.opts.LockName, r.opts.LockTimeout)
+}
+
+// acquireMigrationLock 在当前连接上获取名为 name 的咨询锁，返回释放函数；不支持咨询锁的方言直接返回
+func acquireMigrationLock(ctx context.Context, conn *gorm.DB, name string, timeout time.Duration) (func(), error) {
+	var acquire, release string
+	switch dialectOf(conn) {
+	case "mysql":
+		acquire, release = "SELECT GET_LOCK(?, ?)", "SELECT RELEASE_LOCK(?)"
+	case "postgres":
+		acquire, release = "SELECT pg_advisory_lock(hashtext(?))", "SELECT pg_advisory_unlock(hashtext(?))"
+	default:
+		return func() {}, nil
+	}
+
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	if dialectOf(conn) == "mysql" {
+		var got *int
+		err = conn.WithContext(lockCtx).Raw(acquire, name, int(timeout.Seconds())).Scan(&got).Error
+		if err == nil && (got == nil || *got != 1) {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+	} else {
+		err = conn.WithContext(lockCtx).Exec(acquire, name).Error
+	}
+	wait := time.Since(start)
+	if metrics.IsEnabled() {
+		MigrationLockWait.Observe(wait.Seconds())
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Float64("migration.lock_wait_ms", float64(wait.Milliseconds())))
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire migration lock %s: %w", name, err)
+	}
+
+	return func() {
+		// 使用独立的 context 释放锁，避免调用方 context 取消后锁残留在连接上
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := conn.WithContext(releaseCtx).Exec(release, name).Error; err != nil {
+			log.FromContext(ctx).Warn("Failed to release migration lock",
+				zap.String("lock", name),
+				zap.Error(err),
+			)
+		}
+	}, nil
+}
+
+// recordMigration 记录迁移执行指标
+func recordMigration(version, status string, duration time.Duration) {
+	if metrics.IsEnabled() {
+		MigrationDuration.WithLabelValues(version, status).Observe(duration.Seconds())
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 模型校验发现不一致时的处理方式
+const (
+	ModelValidationError = "error" // 返回 *SchemaMismatchError（默认）
+	ModelValidationWarn  = "warn"  // 只记录告警日志，返回 nil
+)
+
+// ModelValidationOptions 模型校验配置选项
+type ModelValidationOptions struct {
+	Mode string // 发现不一致时的处理方式：error（默认）或 warn
+}
+
+// SchemaMismatch 单个模型与数据库结构的不一致
+type SchemaMismatch struct {
+	Model          string   // 模型类型名
+	Table          string   // 模型对应的表（或多对多关联表）
+	MissingTable   bool     // 表不存在
+	MissingColumns []string // 表存在但缺少的列
+}
+
+// String 返回不一致的描述
+func (m SchemaMismatch) String() string {
+	if m.MissingTable {
+		return fmt.Sprintf("%s: table %s does not exist", m.Model, m.Table)
+	}
+	return fmt.Sprintf("%s: table %s is missing columns %s", m.Model, m.Table, strings.Join(m.MissingColumns, ", "))
+}
+
+// SchemaMismatchError 模型引用了数据库中不存在的表或列
+type SchemaMismatchError struct {
+	Mismatches []SchemaMismatch
+}
+
+// Error 返回全部不一致的描述
+func (e *SchemaMismatchError) Error() string {
+	parts := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		parts[i] = m.String()
+	}
+	return "schema does not match models: " + strings.Join(parts, "; ")
+}
+
+// ValidateModels 在启动时检查模型引用的表和列（含多对多关联表）在数据库中都存在，
+// 不一致时返回 *SchemaMismatchError，用于在接收流量前发现先发布代码、后执行迁移等发布顺序错误。
+// 数据库中多出的表和列不视为不一致，可兼容先加列、后发布代码的滚动发布
+func ValidateModels(ctx context.Context, db *gorm.DB, models ...any) error {
+	return ValidateModelsWithOptions(ctx, db, nil, models...)
+}
+
+// ValidateModelsWithOptions 与 ValidateModels 相同，Mode 为 warn 时只记录告警日志。
+// 读取数据库结构失败时无论 Mode 都返回错误
+func ValidateModelsWithOptions(ctx context.Context, db *gorm.DB, opts *ModelValidationOptions, models ...any) error {
+	if db == nil {
+		return fmt.Errorf("db cannot be nil")
+	}
+	mode := ModelValidationError
+	if opts != nil && opts.Mode != "" {
+		mode = opts.Mode
+	}
+	if mode != ModelValidationError && mode != ModelValidationWarn {
+		return fmt.Errorf("model validation mode must be one of: error, warn, got %s", mode)
+	}
+
+	db = db.WithContext(ctx)
+	migrator := db.Migrator()
+	checked := make(map[string]bool)
+	var mismatches []SchemaMismatch
+	check := func(model, table string, columns []string) error {
+		if checked[table] {
+			return nil
+		}
+		checked[table] = true
+		if !migrator.HasTable(table) {
+			mismatches = append(mismatches, SchemaMismatch{Model: model, Table: table, MissingTable: true})
+			return nil
+		}
+		types, err := migrator.ColumnTypes(table)
+		if err != nil {
+			return fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		existing := make(map[string]bool, len(types))
+		for _, t := range types {
+			existing[strings.ToLower(t.Name())] = true
+		}
+		var missing []string
+		for _, c := range columns {
+			if !existing[strings.ToLower(c)] {
+				missing = append(missing, c)
+			}
+		}
+		if len(missing) > 0 {
+			mismatches = append(mismatches, SchemaMismatch{Model: model, Table: table, MissingColumns: missing})
+		}
+		return nil
+	}
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		name := stmt.Schema.Name
+		if err := check(name, stmt.Schema.Table, stmt.Schema.DBNames); err != nil {
+			return err
+		}
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			if rel.JoinTable == nil {
+				continue
+			}
+			if err := check(name, rel.JoinTable.Table, rel.JoinTable.DBNames); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+	if mode == ModelValidationWarn {
+		for _, m := range mismatches {
+			log.FromContext(ctx).Warn("Model does not match database schema",
+				zap.String("model", m.Model),
+				zap.String("table", m.Table),
+				zap.Bool("missing_table", m.MissingTable),
+				zap.Strings("missing_columns", m.MissingColumns),
+			)
+		}
+		return nil
+	}
+	return &SchemaMismatchError{Mismatches: mismatches}
+}
@@ -0,0 +1,447 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const driverMongo = "mongodb"
+
+// MongoConfig MongoDB 配置结构体（用于从配置文件创建）
+type MongoConfig struct {
+	Enabled                bool               `yaml:"enabled" env:"MONGO_ENABLED" default:"true"`
+	URI                    string             `yaml:"uri" env:"MONGO_URI"`                               // 完整连接串，设置后忽略 hosts、username、password 和 replica_set
+	Hosts                  []string           `yaml:"hosts" env:"MONGO_HOSTS" default:"localhost:27017"` // 逗号分隔的 host:port 列表
+	Username               string             `yaml:"username" env:"MONGO_USERNAME"`
+	Password               string             `yaml:"password" env:"MONGO_PASSWORD"`
+	PasswordFile           string             `yaml:"password_file" env:"MONGO_PASSWORD_FILE"` // 密码文件路径，优先于 Password，仅在创建客户端时读取
+	AuthSource             string             `yaml:"auth_source" env:"MONGO_AUTH_SOURCE" default:"admin"`
+	Database               string             `yaml:"database" env:"MONGO_DATABASE"` // 默认数据库，用于启动日志
+	ReplicaSet             string             `yaml:"replica_set" env:"MONGO_REPLICA_SET"`
+	AppName                string             `yaml:"app_name" env:"MONGO_APP_NAME"`
+	MaxPoolSize            int                `yaml:"max_pool_size" env:"MONGO_MAX_POOL_SIZE" default:"100"`
+	MinPoolSize            int                `yaml:"min_pool_size" env:"MONGO_MIN_POOL_SIZE" default:"0"`
+	MaxConnecting          int                `yaml:"max_connecting" env:"MONGO_MAX_CONNECTING" default:"2"` // 同时建立的最大连接数
+	MaxConnIdleTime        pkgConfig.Duration `yaml:"max_conn_idle_time" env:"MONGO_MAX_CONN_IDLE_TIME" default:"5m"`
+	ConnectTimeout         pkgConfig.Duration `yaml:"connect_timeout" env:"MONGO_CONNECT_TIMEOUT" default:"10s"`
+	ServerSelectionTimeout pkgConfig.Duration `yaml:"server_selection_timeout" env:"MONGO_SERVER_SELECTION_TIMEOUT" default:"30s"`
+	Timeout                pkgConfig.Duration `yaml:"timeout" env:"MONGO_TIMEOUT"`                                   // 单次操作的默认超时，0 表示不限制
+	ReadPreference         string             `yaml:"read_preference" env:"MONGO_READ_PREFERENCE" default:"primary"` // primary、primaryPreferred、secondary、secondaryPreferred、nearest
+	ReadConcern            string             `yaml:"read_concern" env:"MONGO_READ_CONCERN"`                         // local、majority、linearizable、available、snapshot，空使用服务端默认值
+	WriteConcern           string             `yaml:"write_concern" env:"MONGO_WRITE_CONCERN"`                       // majority、节点数或标签名，空使用服务端默认值
+	Journal                bool               `yaml:"journal" env:"MONGO_JOURNAL"`                                   // 写入是否等待 journal 落盘
+	RetryWrites            bool               `yaml:"retry_writes" env:"MONGO_RETRY_WRITES" default:"true"`
+	RetryReads             bool               `yaml:"retry_reads" env:"MONGO_RETRY_READS" default:"true"`
+	EnableTrace            bool               `yaml:"enable_trace" env:"MONGO_ENABLE_TRACE" default:"true"`
+}
+
+// Validate 验证 MongoDB 配置
+func (c *MongoConfig) Validate() error {
+	if c == nil {
+		return fmt.Errorf("mongo config cannot be nil")
+	}
+	if !c.Enabled {
+		return nil // 如果未启用，不需要验证
+	}
+	if c.URI == "" && len(c.Hosts) == 0 {
+		return fmt.Errorf("mongo uri or hosts is required")
+	}
+	if c.MaxPoolSize < 0 {
+		return fmt.Errorf("mongo max_pool_size must be non-negative, got %d", c.MaxPoolSize)
+	}
+	if c.MinPoolSize < 0 {
+		return fmt.Errorf("mongo min_pool_size must be non-negative, got %d", c.MinPoolSize)
+	}
+	if c.MaxPoolSize > 0 && c.MinPoolSize > c.MaxPoolSize {
+		return fmt.Errorf("mongo min_pool_size (%d) must not exceed max_pool_size (%d)", c.MinPoolSize, c.MaxPoolSize)
+	}
+	if c.MaxConnecting < 0 {
+		return fmt.Errorf("mongo max_connecting must be non-negative, got %d", c.MaxConnecting)
+	}
+	if _, err := mongoReadPref(c.ReadPreference); err != nil {
+		return fmt.Errorf("mongo read_preference %w", err)
+	}
+	if _, err := mongoReadConcern(c.ReadConcern); err != nil {
+		return fmt.Errorf("mongo read_concern %w", err)
+	}
+	return nil
+}
+
+// ToOptions 转换为 MongoOptions
+func (c *MongoConfig) ToOptions() (*MongoOptions, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if !c.Enabled {
+		return nil, fmt.Errorf("mongo is not enabled")
+	}
+
+	password, _, err := resolvePassword(c.Password, c.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: %w", err)
+	}
+	readPref, _ := mongoReadPref(c.ReadPreference)
+	readConcern, _ := mongoReadConcern(c.ReadConcern)
+
+	return &MongoOptions{
+		URI:                    c.URI,
+		Hosts:                  append([]string(nil), c.Hosts...),
+		Username:               c.Username,
+		Password:               password,
+		AuthSource:             c.AuthSource,
+		Database:               c.Database,
+		ReplicaSet:             c.ReplicaSet,
+		AppName:                c.AppName,
+		MaxPoolSize:            uint64(c.MaxPoolSize),
+		MinPoolSize:            uint64(c.MinPoolSize),
+		MaxConnecting:          uint64(c.MaxConnecting),
+		MaxConnIdleTime:        c.MaxConnIdleTime.Duration(),
+		ConnectTimeout:         durationOr(c.ConnectTimeout.Duration(), 10*time.Second),
+		ServerSelectionTimeout: durationOr(c.ServerSelectionTimeout.Duration(), 30*time.Second),
+		Timeout:                c.Timeout.Duration(),
+		ReadPreference:         readPref,
+		ReadConcern:            readConcern,
+		WriteConcern:           mongoWriteConcern(c.WriteConcern, c.Journal),
+		RetryWrites:            c.RetryWrites,
+		RetryReads:             c.RetryReads,
+		EnableTrace:            c.EnableTrace,
+	}, nil
+}
+
+// MongoOptions 结构体定义了 MongoDB 客户端的配置选项（内部使用）
+type MongoOptions struct {
+	URI                    string
+	Hosts                  []string
+	Username               string
+	Password               string
+	AuthSource             string
+	Database               string
+	ReplicaSet             string
+	AppName                string
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	MaxConnecting          uint64
+	MaxConnIdleTime        time.Duration
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	Timeout                time.Duration
+	ReadPreference         *readpref.ReadPref         // nil 使用驱动默认值 primary
+	ReadConcern            *readconcern.ReadConcern   // nil 使用服务端默认值
+	WriteConcern           *writeconcern.WriteConcern // nil 使用服务端默认值
+	RetryWrites            bool
+	RetryReads             bool
+	EnableTrace            bool          // 是否启用命令追踪，用于记录 MongoDB 命令执行时间
+	ErrorLogInterval       time.Duration // 相同错误日志的去重窗口，0 使用默认值 10s，负数表示不去重
+	SLO                    *SLOOptions   // SLI 指标配置，nil 表示不统计
+}
+
+// NewMongo 根据给定的选项创建一个新的 MongoDB 客户端实例
+func NewMongo(opts *MongoOptions) (*mongo.Client, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("mongo options cannot be nil")
+	}
+
+	clientOpts := options.Client()
+	if opts.URI != "" {
+		clientOpts.ApplyURI(opts.URI)
+	} else {
+		clientOpts.SetHosts(opts.Hosts)
+		if opts.ReplicaSet != "" {
+			clientOpts.SetReplicaSet(opts.ReplicaSet)
+		}
+		if opts.Username != "" {
+			clientOpts.SetAuth(options.Credential{
+				AuthSource:  opts.AuthSource,
+				Username:    opts.Username,
+				Password:    opts.Password,
+				PasswordSet: opts.Password != "",
+			})
+		}
+	}
+	if opts.AppName != "" {
+		clientOpts.SetAppName(opts.AppName)
+	}
+	if opts.MaxPoolSize > 0 {
+		clientOpts.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	clientOpts.SetMinPoolSize(opts.MinPoolSize)
+	if opts.MaxConnecting > 0 {
+		clientOpts.SetMaxConnecting(opts.MaxConnecting)
+	}
+	if opts.MaxConnIdleTime > 0 {
+		clientOpts.SetMaxConnIdleTime(opts.MaxConnIdleTime)
+	}
+	clientOpts.SetConnectTimeout(durationOr(opts.ConnectTimeout, 10*time.Second))
+	clientOpts.SetServerSelectionTimeout(durationOr(opts.ServerSelectionTimeout, 30*time.Second))
+	if opts.Timeout > 0 {
+		clientOpts.SetTimeout(opts.Timeout)
+	}
+	if opts.ReadPreference != nil {
+		clientOpts.SetReadPreference(opts.ReadPreference)
+	}
+	if opts.ReadConcern != nil {
+		clientOpts.SetReadConcern(opts.ReadConcern)
+	}
+	if opts.WriteConcern != nil {
+		clientOpts.SetWriteConcern(opts.WriteConcern)
+	}
+	clientOpts.SetRetryWrites(opts.RetryWrites)
+	clientOpts.SetRetryReads(opts.RetryReads)
+
+	// 连接池指标始终记录，命令追踪仅在启用时添加
+	clientOpts.SetPoolMonitor(mongoPoolMonitor())
+	if opts.EnableTrace {
+		clientOpts.SetMonitor(newMongoCommandMonitor(
+			withDatasource(driverMongo),
+			WithErrorLogInterval(opts.ErrorLogInterval),
+			WithSLO(opts.SLO),
+		))
+	}
+
+	client, err := mongo.Connect(clientOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mongo client: %w", err)
+	}
+
+	// 测试连接
+	ctx, cancel := context.WithTimeout(context.Background(), durationOr(opts.ConnectTimeout, 10*time.Second))
+	defer cancel()
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("failed to connect to mongo: %w", err)
+	}
+
+	logMongoBanner(client, opts, clientOpts.TLSConfig != nil)
+	return client, nil
+}
+
+// mongoReadPref 解析读偏好，空字符串返回 nil
+func mongoReadPref(mode string) (*readpref.ReadPref, error) {
+	if mode == "" {
+		return nil, nil
+	}
+	m, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, fmt.Errorf("must be one of: primary, primaryPreferred, secondary, secondaryPreferred, nearest, got %s", mode)
+	}
+	return readpref.New(m)
+}
+
+// mongoReadConcern 解析读关注级别，空字符串返回 nil
+func mongoReadConcern(level string) (*readconcern.ReadConcern, error) {
+	switch level {
+	case "":
+		return nil, nil
+	case "local", "majority", "linearizable", "available", "snapshot":
+		return &readconcern.ReadConcern{Level: level}, nil
+	default:
+		return nil, fmt.Errorf("must be one of: local, majority, linearizable, available, snapshot, got %s", level)
+	}
+}
+
+// mongoWriteConcern 解析写关注，w 可以是 majority、节点数或标签名
+func mongoWriteConcern(w string, journal bool) *writeconcern.WriteConcern {
+	if w == "" && !journal {
+		return nil
+	}
+	wc := &writeconcern.WriteConcern{}
+	if n, err := strconv.Atoi(w); err == nil {
+		wc.W = n
+	} else if w != "" {
+		wc.W = w
+	}
+	if journal {
+		wc.Journal = &journal
+	}
+	return wc
+}
+
+// mongoCommandMonitor 基于 CommandMonitor 的追踪，为每个命令创建 span、记录日志和指标
+type mongoCommandMonitor struct {
+	opts         traceOptions
+	errorSampler *errorLogSampler
+	slo          *sloTracker
+	spans        sync.Map // 连接 ID + 请求 ID -> trace.Span
+}
+
+// newMongoCommandMonitor 创建 MongoDB 命令追踪
+func newMongoCommandMonitor(opts ...TraceOption) *event.CommandMonitor {
+	o := newTraceOptions(opts...)
+	m := &mongoCommandMonitor{
+		opts:         o,
+		errorSampler: newErrorLogSampler(o.errorLogInterval),
+		slo:          sloTrackerFor(o.datasource, o.slo),
+	}
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+// mongoSpanKey 返回命令对应的 span 键
+func mongoSpanKey(connectionID string, requestID int64) string {
+	return connectionID + "/" + strconv.FormatInt(requestID, 10)
+}
+
+func (m *mongoCommandMonitor) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.name", evt.DatabaseName),
+		attribute.String("db.operation", evt.CommandName),
+	}
+	// 命令文档的第一个字段为命令名，值通常为集合名；不记录命令文档，避免泄露数据
+	if elem, err := evt.Command.IndexErr(0); err == nil {
+		if collection, ok := elem.Value().StringValueOK(); ok {
+			attrs = append(attrs, attribute.String("db.mongodb.collection", collection))
+		}
+	}
+	_, span := pkgtrace.StartSpan(ctx, "mongo."+evt.CommandName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	m.spans.Store(mongoSpanKey(evt.ConnectionID, evt.RequestID), span)
+}
+
+func (m *mongoCommandMonitor) succeeded(ctx context.Context, evt *event.CommandSucceededEvent) {
+	m.finish(ctx, &evt.CommandFinishedEvent, nil)
+}
+
+func (m *mongoCommandMonitor) failed(ctx context.Context, evt *event.CommandFailedEvent) {
+	m.finish(ctx, &evt.CommandFinishedEvent, evt.Failure)
+}
+
+// finish 结束 span 并记录日志、指标和 SLI
+func (m *mongoCommandMonitor) finish(ctx context.Context, evt *event.CommandFinishedEvent, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+
+	if v, ok := m.spans.LoadAndDelete(mongoSpanKey(evt.ConnectionID, evt.RequestID)); ok {
+		span := v.(trace.Span)
+		span.SetAttributes(attribute.Float64("db.duration_ms", float64(evt.Duration.Milliseconds())))
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			span.RecordError(err)
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		span.End()
+	}
+
+	// 记录日志，相同错误在采样窗口内只记录一次
+	if err != nil {
+		if m.errorSampler.allow(evt.CommandName+"|"+err.Error(), zap.String("operation", evt.CommandName), zap.String("error", err.Error())) {
+			log.FromContext(ctx).Error(
+				"Mongo command failed",
+				zap.String("operation", evt.CommandName),
+				zap.String("database", evt.DatabaseName),
+				zap.Duration("duration", evt.Duration),
+				zap.String("status", status),
+				zap.Error(err),
+			)
+		}
+	} else {
+		log.FromContext(ctx).Info(
+			"Mongo command success",
+			zap.String("operation", evt.CommandName),
+			zap.String("database", evt.DatabaseName),
+			zap.Duration("duration", evt.Duration),
+			zap.String("status", status),
+		)
+	}
+
+	if metrics.IsEnabled() {
+		MongoCommandTotal.WithLabelValues(evt.CommandName, status).Inc()
+		MongoCommandDuration.WithLabelValues(evt.CommandName).Observe(evt.Duration.Seconds())
+	}
+	m.slo.record(evt.CommandName, err == nil, evt.Duration)
+}
+
+// mongoPoolMonitor 根据连接池事件维护连接数指标
+func mongoPoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			if !metrics.IsEnabled() {
+				return
+			}
+			switch evt.Type {
+			case event.ConnectionCreated:
+				MongoPoolConnections.WithLabelValues(evt.Address, "open").Inc()
+			case event.ConnectionClosed:
+				MongoPoolConnections.WithLabelValues(evt.Address, "open").Dec()
+			case event.ConnectionCheckedOut:
+				MongoPoolConnections.WithLabelValues(evt.Address, "in_use").Inc()
+			case event.ConnectionCheckedIn:
+				MongoPoolConnections.WithLabelValues(evt.Address, "in_use").Dec()
+			case event.ConnectionCheckOutFailed:
+				MongoPoolCheckoutFailedTotal.WithLabelValues(evt.Address, evt.Reason).Inc()
+			}
+		},
+	}
+}
+
+// logMongoBanner 查询 MongoDB 版本并输出启动日志
+func logMongoBanner(client *mongo.Client, opts *MongoOptions, tls bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), bannerTimeout)
+	defer cancel()
+
+	var info struct {
+		Version string `bson:"version"`
+	}
+	_ = client.Database("admin").RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&info)
+
+	hosts := opts.Hosts
+	if opts.URI != "" {
+		hosts = options.Client().ApplyURI(opts.URI).Hosts
+	}
+	datasourceBanner{
+		driver:   driverMongo,
+		addr:     strings.Join(hosts, ","),
+		database: opts.Database,
+		username: opts.Username,
+		version:  info.Version,
+		maxOpen:  int(opts.MaxPoolSize),
+		maxIdle:  int(opts.MinPoolSize),
+		tls:      tls,
+		trace:    opts.EnableTrace,
+	}.log(ctx)
+}
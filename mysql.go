@@ -17,25 +17,31 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"net/url"
+	"sort"
+	"strings"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
+const callBackStripShareLockName = "core:strip_share_lock"
+
 // New 根据给定的选项创建一个新的 GORM 数据库实例.
 func New(opts *Options) (*gorm.DB, error) {
-	// 构建 DSN (Data Source Name)
-	dsn := fmt.Sprintf(`%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=%t&loc=%s`,
-		opts.Username,
-		opts.Password,
-		opts.Host,
-		opts.Database,
-		true,    // parseTime=true 才能将 MySQL 的 DATETIME/TIMESTAMP 正确解析为 Go 的 time.Time
-		"Local") // 使用本地时区
+	if opts.CreateDatabase {
+		if err := ensureMySQLDatabase(opts); err != nil {
+			return nil, err
+		}
+	}
 
-	return newDB(dsn, opts)
+	return newDB(mySQLDSN(opts), opts)
 }
 
 // newDB 内部函数，用于创建数据库连接
@@ -49,8 +55,23 @@ func newDB(dsn string, opts *Options) (*gorm.DB, error) {
 		gormLogger = logger.Default.LogMode(opts.LogLevel)
 	}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: gormLogger,
+	dialector := mysql.Open(dsn)
+	var selector *failoverSelector
+	if len(opts.Hosts) > 1 {
+		var err error
+		if dialector, selector, err = newMySQLFailoverDialector(dsn, opts); err != nil {
+			return nil, err
+		}
+	} else if opts.PasswordProvider != nil {
+		var err error
+		if dialector, err = newMySQLDialectorWithProvider(dsn, opts.PasswordProvider); err != nil {
+			return nil, err
+		}
+	}
+
+	// 热更新通过 applyLogLevel 调整级别
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: newLevelLogger(gormLogger),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -71,13 +92,249 @@ func newDB(dsn string, opts *Options) (*gorm.DB, error) {
 	if opts.MaxIdleConnections > 0 {
 		sqlDB.SetMaxIdleConns(opts.MaxIdleConnections)
 	}
+	if opts.LeakDetection != nil {
+		if err := EnableLeakDetection(db, instanceOr(opts.InstanceName, mySQLDriver(opts)), opts.LeakDetection); err != nil {
+			return nil, fmt.Errorf("failed to enable leak detection: %w", err)
+		}
+	}
+	if selector != nil {
+		selector.attach(sqlDB, opts.MaxIdleConnections)
+		if err := db.Use(&failoverPlugin{selector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to register failover plugin: %w", err)
+		}
+	}
+
+	driver := mySQLDriver(opts)
+	if opts.IDGenerator != nil {
+		if err := db.Use(NewIDGeneratorPlugin(opts.IDGenerator)); err != nil {
+			return nil, fmt.Errorf("failed to register id generator plugin: %w", err)
+		}
+	}
+	if opts.QueryTimeout != nil {
+		if err := db.Use(NewQueryTimeoutPlugin(*opts.QueryTimeout)); err != nil {
+			return nil, fmt.Errorf("failed to register query timeout plugin: %w", err)
+		}
+	}
+	if opts.CircuitBreaker != nil {
+		breaker := NewCircuitBreaker(driver+"@"+mySQLHosts(opts), opts.CircuitBreaker)
+		if err := db.Use(&circuitBreakerPlugin{breaker: breaker}); err != nil {
+			return nil, fmt.Errorf("failed to register circuit breaker plugin: %w", err)
+		}
+	}
+	if err := db.Use(&txRetryPlugin{datasource: driver, retries: mySQLTxRetries(opts)}); err != nil {
+		return nil, fmt.Errorf("failed to register tx retry plugin: %w", err)
+	}
+	if opts.DisableShareLocks {
+		if err := db.Callback().Query().Before("gorm:query").Register(callBackStripShareLockName, stripShareLock); err != nil {
+			return nil, fmt.Errorf("failed to register share lock filter: %w", err)
+		}
+	}
 
 	// 如果启用了追踪，则注册 GormTracePlugin
 	if opts.EnableTrace {
-		if err := db.Use(NewGormTracePlugin(true)); err != nil {
+		plugin := NewGormTracePlugin(true,
+			withDatasource(driver),
+			withDBSystem(driver),
+			WithInstanceName(opts.InstanceName),
+			WithErrorLogInterval(opts.ErrorLogInterval),
+			WithErrorReporter(opts.ErrorReporter),
+			WithSlowQueryThreshold(opts.SlowQueryThreshold),
+			WithSlowQuerySinks(opts.SlowQuerySinks...),
+			WithSLO(opts.SLO),
+			WithMaxResultRows(opts.MaxResultRows, opts.AbortOnLargeResult),
+			WithPprofLabels(opts.PprofLabels),
+			WithTraceSampleRate(opts.TraceSampleRate),
+			WithMetricsDisabled(opts.DisableMetrics),
+			WithMetricsNamespace(opts.MetricsNamespace),
+			WithMetricsBackend(opts.MetricsBackend),
+		)
+		if err := db.Use(plugin); err != nil {
 			return nil, fmt.Errorf("failed to register trace plugin: %w", err)
 		}
+		onHandleClose(sqlDB, plugin.release)
+	} else if err := (resultGuard{maxRows: opts.MaxResultRows, abort: opts.AbortOnLargeResult}).register(db); err != nil {
+		return nil, fmt.Errorf("failed to register result guard: %w", err)
+	}
+	if opts.ForceUTC {
+		if err := ForceUTC(db); err != nil {
+			return nil, fmt.Errorf("failed to register utc interceptor: %w", err)
+		}
+	}
+	if opts.PasswordProvider != nil {
+		if err := enableSQLAuthRotation(db, instanceOr(opts.InstanceName, driver), opts.PasswordProvider, sqlDB, opts.MaxIdleConnections); err != nil {
+			return nil, fmt.Errorf("failed to register auth rotation interceptor: %w", err)
+		}
+	}
+
+	tls := false
+	if cfg, err := mysqldriver.ParseDSN(dsn); err == nil {
+		tls = cfg.TLSConfig != "" && cfg.TLSConfig != "false"
+	}
+	if opts.WarmUpConnections > 0 {
+		if err := warmUpOnCreate(sqlDB, opts.WarmUpConnections, opts.MaxIdleConnections); err != nil {
+			return nil, err
+		}
 	}
 
+	logSQLBanner(db, datasourceBanner{
+		driver:   driver,
+		instance: opts.InstanceName,
+		addr:     mySQLHosts(opts),
+		database: opts.Database,
+		username: opts.Username,
+		tls:      tls,
+		maxOpen:  opts.MaxOpenConnections,
+		maxIdle:  opts.MaxIdleConnections,
+		trace:    opts.EnableTrace,
+	}, "SELECT VERSION()")
+
 	return db, nil
 }
+
+// newMySQLDialectorWithProvider 创建在每次建立新连接前从凭据提供者获取密码的 MySQL Dialector
+func newMySQLDialectorWithProvider(dsn string, provider CredentialProvider) (gorm.Dialector, error) {
+	cfg, err := mySQLConfig(dsn, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := mysqldriver.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mysql connector: %w", err)
+	}
+	return mysql.New(mysql.Config{
+		DSNConfig: cfg,
+		Conn:      sql.OpenDB(connector),
+	}), nil
+}
+
+// newMySQLFailoverDialector 创建多节点故障转移的 MySQL Dialector，返回的节点选择器需在连接池创建后关联
+func newMySQLFailoverDialector(dsn string, opts *Options) (gorm.Dialector, *failoverSelector, error) {
+	cfg, err := mySQLConfig(dsn, opts.PasswordProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+	connector, err := newMySQLFailoverConnector(cfg, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mysql.New(mysql.Config{
+		DSNConfig: cfg,
+		Conn:      sql.OpenDB(connector),
+	}), connector.selector, nil
+}
+
+// mySQLConfig 解析 DSN，provider 不为 nil 时在每次建立新连接前获取最新密码
+func mySQLConfig(dsn string, provider CredentialProvider) (*mysqldriver.Config, error) {
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mysql dsn: %w", err)
+	}
+	if provider == nil {
+		return cfg, nil
+	}
+	err = cfg.Apply(mysqldriver.BeforeConnect(func(ctx context.Context, c *mysqldriver.Config) error {
+		password, err := provider.Password(ctx)
+		if err != nil {
+			return err
+		}
+		c.Passwd = password
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply mysql credential provider: %w", err)
+	}
+	return cfg, nil
+}
+
+// mySQLHosts 返回节点列表，用于启动日志
+func mySQLHosts(opts *Options) string {
+	if len(opts.Hosts) > 0 {
+		return strings.Join(opts.Hosts, ",")
+	}
+	return opts.Host
+}
+
+// mySQLDSN 构建 DSN (Data Source Name)
+func mySQLDSN(opts *Options) string {
+	dsn := fmt.Sprintf(`%s:%s@%s(%s)/%s?charset=utf8mb4&parseTime=%t&loc=%s`,
+		opts.Username,
+		opts.Password,
+		mySQLNetwork(opts.Dialer),
+		opts.Host,
+		opts.Database,
+		true,    // parseTime=true 才能将 MySQL 的 DATETIME/TIMESTAMP 正确解析为 Go 的 time.Time
+		"Local") // 使用本地时区
+	if opts.CleartextPassword {
+		// mysql_clear_password 只允许在 TLS 连接上发送密码
+		dsn += "&tls=true&allowCleartextPasswords=true"
+	}
+	if opts.Compress {
+		dsn += "&compress=true"
+	}
+	// 驱动按顺序解析参数，同名参数以最后一次为准，因此 Params 可覆盖上面的默认值
+	params := make([]string, 0, len(opts.Params))
+	for k := range opts.Params {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		dsn += "&" + k + "=" + url.QueryEscape(opts.Params[k])
+	}
+	// 未识别的 DSN 参数由驱动在建立连接后以 SET 语句设置为会话变量
+	for _, kv := range mySQLSessionVars(opts) {
+		dsn += "&" + kv[0] + "=" + url.QueryEscape(kv[1])
+	}
+	return dsn
+}
+
+// mySQLDriver 返回数据源标识，用于指标、span 和启动日志
+func mySQLDriver(opts *Options) string {
+	if opts.TiDB {
+		return driverTiDB
+	}
+	return driverMySQL
+}
+
+// mySQLSessionVars 返回按名称排序的会话变量，显式配置的值覆盖 tidb_isolation_read_engines
+func mySQLSessionVars(opts *Options) [][2]string {
+	vars := make(map[string]string, len(opts.SessionVars)+1)
+	if len(opts.IsolationReadEngines) > 0 {
+		vars["tidb_isolation_read_engines"] = "'" + strings.Join(opts.IsolationReadEngines, ",") + "'"
+	}
+	for k, v := range opts.SessionVars {
+		vars[k] = v
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([][2]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, [2]string{k, vars[k]})
+	}
+	return pairs
+}
+
+// mySQLTxRetries 返回 ExecuteTx 的最大重试次数
+func mySQLTxRetries(opts *Options) int {
+	if opts.TxRetries == 0 && opts.TiDB {
+		return defaultTiDBTxRetries
+	}
+	return opts.TxRetries
+}
+
+// stripShareLock 移除查询中的 FOR SHARE 锁，排他锁不受影响
+// TiDB 未开启 tidb_enable_noop_functions 时对共享锁报错，开启后共享锁也不会生效
+func stripShareLock(db *gorm.DB) {
+	if db.Statement == nil {
+		return
+	}
+	if c, ok := db.Statement.Clauses["FOR"]; ok {
+		if locking, ok := c.Expression.(clause.Locking); ok && locking.Strength == clause.LockingStrengthShare {
+			delete(db.Statement.Clauses, "FOR")
+		}
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/go-anyway/framework-log"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// 在线表结构变更的执行方式
+const (
+	OnlineSchemaToolGhost  = "gh-ost"                  // github/gh-ost，基于 binlog 的无触发器变更
+	OnlineSchemaToolPtOSC  = "pt-online-schema-change" // Percona Toolkit，基于触发器的变更
+	OnlineSchemaToolNative = "native"                  // 内置方式：ALTER TABLE ... ALGORITHM=INPLACE, LOCK=NONE
+)
+
+// defaultSmallTableRows 默认的小表行数阈值，估算行数低于该值的表使用内置方式变更
+const defaultSmallTableRows = 100000
+
+// OnlineSchemaChange 声明式的 MySQL 在线表结构变更，通常由迁移文件加载（见 LoadOnlineSchemaChange）：
+//
+//	version: "20250601120000"
+//	name: add index on orders.created_at
+//	table: orders
+//	alter: ADD INDEX idx_orders_created_at (created_at)
+//	tool: gh-ost
+//	chunk_size: 2000
+//	max_load: Threads_running=25
+//	args: ["--allow-on-master"]
+type OnlineSchemaChange struct {
+	Version        string   `yaml:"version"`          // 迁移版本号
+	Name           string   `yaml:"name"`             // 迁移描述
+	Table          string   `yaml:"table"`            // 变更的表
+	Alter          string   `yaml:"alter"`            // ALTER TABLE <table> 之后的变更子句，多个子句以逗号分隔
+	Tool           string   `yaml:"tool"`             // 执行方式：gh-ost（默认）、pt-online-schema-change 或 native
+	SmallTableRows int64    `yaml:"small_table_rows"` // 估算行数低于该值的表改用内置方式，默认 100000，负数表示总是使用 Tool
+	ChunkSize      int      `yaml:"chunk_size"`       // 每批复制的行数，0 使用工具的默认值
+	MaxLoad        string   `yaml:"max_load"`         // 超过该负载时暂停复制，如 Threads_running=25，为空使用工具的默认值
+	Args           []string `yaml:"args"`             // 追加的工具参数
+}
+
+// OnlineSchemaOptions 执行在线表结构变更的配置选项
+type OnlineSchemaOptions struct {
+	Connection *Options                                                  // 外部工具连接的 MySQL 配置（主机、用户名、密码、库名），通常与创建连接的配置相同
+	GhostPath  string                                                    // gh-ost 可执行文件路径，默认 gh-ost
+	PtOSCPath  string                                                    // pt-online-schema-change 可执行文件路径，默认 pt-online-schema-change
+	Exec       func(ctx context.Context, cmd *OnlineSchemaCommand) error // 执行工具命令，默认以子进程执行并将输出逐行写入日志
+}
+
+// OnlineSchemaCommand 生成的外部工具调用，执行后需调用 Close 删除凭据文件
+// 密码不出现在命令行参数中（ps 和 /proc/<pid>/cmdline 对其他用户可见），而是写入仅所有者可读的临时选项文件
+type OnlineSchemaCommand struct {
+	Tool         string   // 工具名称
+	Path         string   // 可执行文件路径
+	Args         []string // 命令行参数，不包含密码
+	DefaultsFile string   // 包含连接用户名和密码的 MySQL 选项文件（权限 0600），gh-ost 通过 --conf、pt-online-schema-change 通过 DSN 的 F= 读取
+}
+
+// Close 删除凭据文件
+func (c *OnlineSchemaCommand) Close() error {
+	if c.DefaultsFile == "" {
+		return nil
+	}
+	err := os.Remove(c.DefaultsFile)
+	c.DefaultsFile = ""
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// String 返回可用于日志和审阅的命令行，通过 args 追加的密码参数已脱敏
+func (c *OnlineSchemaCommand) String() string {
+	parts := make([]string, 0, len(c.Args)+1)
+	parts = append(parts, c.Path)
+	for _, arg := range c.Args {
+		switch {
+		case strings.HasPrefix(arg, "--password="):
+			arg = "--password=***"
+		case c.Tool == OnlineSchemaToolPtOSC && strings.HasPrefix(arg, "h="):
+			arg = redactPtOSCDSN(arg)
+		}
+		parts = append(parts, strconv.Quote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseOnlineSchemaChange 解析 YAML 格式的在线表结构变更
+func ParseOnlineSchemaChange(data []byte) (*OnlineSchemaChange, error) {
+	var c OnlineSchemaChange
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse online schema change: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// LoadOnlineSchemaChange 从迁移文件加载在线表结构变更，返回可交给 MigrationRunner 执行的迁移
+func LoadOnlineSchemaChange(fsys fs.FS, name string) (Migration, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Migration{}, fmt.Errorf("failed to read online schema change %s: %w", name, err)
+	}
+	c, err := ParseOnlineSchemaChange(data)
+	if err != nil {
+		return Migration{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return Migration{Version: c.Version, Name: c.Name, Online: c}, nil
+}
+
+// Validate 检查变更定义
+func (c *OnlineSchemaChange) Validate() error {
+	if c.Version == "" {
+		return fmt.Errorf("online schema change version cannot be empty")
+	}
+	if c.Table == "" {
+		return fmt.Errorf("online schema change %s: table is required", c.Version)
+	}
+	alter := strings.TrimSpace(c.Alter)
+	if alter == "" {
+		return fmt.Errorf("online schema change %s: alter is required", c.Version)
+	}
+	if strings.HasPrefix(strings.ToUpper(alter), "ALTER TABLE") {
+		return fmt.Errorf("online schema change %s: alter must not include ALTER TABLE <table>", c.Version)
+	}
+	switch c.Tool {
+	case "", OnlineSchemaToolGhost, OnlineSchemaToolPtOSC, OnlineSchemaToolNative:
+	default:
+		return fmt.Errorf("online schema change %s: unsupported tool %q", c.Version, c.Tool)
+	}
+	return nil
+}
+
+// Statement 返回等价的 ALTER TABLE 语句，内置方式执行该语句，也用于计算迁移的校验和
+func (c *OnlineSchemaChange) Statement() string {
+	return fmt.Sprintf("ALTER TABLE `%s` %s", c.Table, strings.TrimSuffix(strings.TrimSpace(c.Alter), ";"))
+}
+
+// Command 生成外部工具的调用，tool 为 native 时返回错误
+// 连接密码写入临时选项文件，调用方执行完成后需调用返回值的 Close 删除该文件
+func (c *OnlineSchemaChange) Command(ctx context.Context, opts *OnlineSchemaOptions) (*OnlineSchemaCommand, error) {
+	if opts == nil || opts.Connection == nil {
+		return nil, fmt.Errorf("online schema change %s: connection options are required", c.Version)
+	}
+	conn := opts.Connection
+	host, port := conn.Host, "3306"
+	if h, p, err := net.SplitHostPort(conn.Host); err == nil {
+		host, port = h, p
+	}
+	password, err := resolveMySQLPassword(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	alter := strings.TrimSuffix(strings.TrimSpace(c.Alter), ";")
+
+	tool := c.tool()
+	if tool != OnlineSchemaToolGhost && tool != OnlineSchemaToolPtOSC {
+		return nil, fmt.Errorf("online schema change %s: tool %s has no command", c.Version, tool)
+	}
+	defaultsFile, err := writeMySQLDefaultsFile(conn.Username, password)
+	if err != nil {
+		return nil, fmt.Errorf("online schema change %s: %w", c.Version, err)
+	}
+
+	switch tool {
+	case OnlineSchemaToolGhost:
+		cmd := &OnlineSchemaCommand{Tool: OnlineSchemaToolGhost, Path: opts.GhostPath, DefaultsFile: defaultsFile}
+		if cmd.Path == "" {
+			cmd.Path = OnlineSchemaToolGhost
+		}
+		cmd.Args = []string{
+			"--host=" + host,
+			"--port=" + port,
+			"--user=" + conn.Username,
+			"--conf=" + defaultsFile,
+			"--database=" + conn.Database,
+			"--table=" + c.Table,
+			"--alter=" + alter,
+		}
+		if c.ChunkSize > 0 {
+			cmd.Args = append(cmd.Args, "--chunk-size="+strconv.Itoa(c.ChunkSize))
+		}
+		if c.MaxLoad != "" {
+			cmd.Args = append(cmd.Args, "--max-load="+c.MaxLoad)
+		}
+		cmd.Args = append(cmd.Args, c.Args...)
+		cmd.Args = append(cmd.Args, "--execute")
+		return cmd, nil
+	default: // OnlineSchemaToolPtOSC
+		cmd := &OnlineSchemaCommand{Tool: OnlineSchemaToolPtOSC, Path: opts.PtOSCPath, DefaultsFile: defaultsFile}
+		if cmd.Path == "" {
+			cmd.Path = OnlineSchemaToolPtOSC
+		}
+		cmd.Args = []string{"--alter", alter}
+		if c.ChunkSize > 0 {
+			cmd.Args = append(cmd.Args, "--chunk-size", strconv.Itoa(c.ChunkSize))
+		}
+		if c.MaxLoad != "" {
+			cmd.Args = append(cmd.Args, "--max-load", c.MaxLoad)
+		}
+		cmd.Args = append(cmd.Args, c.Args...)
+		cmd.Args = append(cmd.Args, "--execute",
+			fmt.Sprintf("h=%s,P=%s,u=%s,F=%s,D=%s,t=%s", host, port, conn.Username, defaultsFile, conn.Database, c.Table))
+		return cmd, nil
+	}
+}
+
+// writeMySQLDefaultsFile 将连接凭据写入 [client] 段的临时 MySQL 选项文件，os.CreateTemp 创建的文件权限为 0600
+func writeMySQLDefaultsFile(user, password string) (string, error) {
+	f, err := os.CreateTemp("", "online-schema-change-*.cnf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create credentials file: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "[client]\nuser=%s\npassword=%s\n", quoteOptionValue(user), quoteOptionValue(password))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// quoteOptionValue 以双引号包裹选项文件的值，MySQL 选项文件和 gh-ost 使用的 gcfg 都支持其中的转义
+func quoteOptionValue(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + r.Replace(v) + `"`
+}
+
+// tool 返回配置的执行方式，默认 gh-ost
+func (c *OnlineSchemaChange) tool() string {
+	if c.Tool == "" {
+		return OnlineSchemaToolGhost
+	}
+	return c.Tool
+}
+
+// smallTableRows 返回小表行数阈值
+func (c *OnlineSchemaChange) smallTableRows() int64 {
+	if c.SmallTableRows == 0 {
+		return defaultSmallTableRows
+	}
+	return c.SmallTableRows
+}
+
+// runOnlineSchemaChange 执行在线表结构变更：小表或 tool 为 native 时在当前连接上执行 ALTER，否则调用外部工具
+func runOnlineSchemaChange(ctx context.Context, conn *gorm.DB, c *OnlineSchemaChange, opts *OnlineSchemaOptions) error {
+	if dialect := dialectOf(conn); dialect != "mysql" {
+		return fmt.Errorf("online schema change requires mysql, got %s", dialect)
+	}
+
+	tool := c.tool()
+	if tool != OnlineSchemaToolNative && c.smallTableRows() > 0 {
+		var rows *int64
+		err := conn.WithContext(ctx).Raw(
+			"SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+			c.Table).Scan(&rows).Error
+		if err != nil {
+			return fmt.Errorf("failed to estimate rows of %s: %w", c.Table, err)
+		}
+		if rows != nil && *rows < c.smallTableRows() {
+			tool = OnlineSchemaToolNative
+		}
+	}
+
+	if tool == OnlineSchemaToolNative {
+		return alterTableInPlace(ctx, conn, c)
+	}
+	cmd, err := c.Command(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cmd.Close() }()
+	log.FromContext(ctx).Info("Running online schema change",
+		zap.String("version", c.Version),
+		zap.String("table", c.Table),
+		zap.String("command", cmd.String()),
+	)
+	run := execOnlineSchemaCommand
+	if opts.Exec != nil {
+		run = opts.Exec
+	}
+	if err := run(ctx, cmd); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd.Tool, err)
+	}
+	return nil
+}
+
+// alterTableInPlace 以 ALGORITHM=INPLACE, LOCK=NONE 执行 ALTER，变更不支持原地执行时
+// （ER_ALTER_OPERATION_NOT_SUPPORTED）退回默认算法，仅用于小表
+func alterTableInPlace(ctx context.Context, conn *gorm.DB, c *OnlineSchemaChange) error {
+	stmt := c.Statement()
+	err := conn.WithContext(ctx).Exec(stmt + ", ALGORITHM=INPLACE, LOCK=NONE").Error
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) && (myErr.Number == 1845 || myErr.Number == 1846) {
+		log.FromContext(ctx).Warn("In-place ALTER not supported, falling back to default algorithm",
+			zap.String("version", c.Version),
+			zap.String("table", c.Table),
+			zap.Error(err),
+		)
+		err = conn.WithContext(ctx).Exec(stmt).Error
+	}
+	return err
+}
+
+// execOnlineSchemaCommand 以子进程执行工具命令，输出逐行写入日志
+func execOnlineSchemaCommand(ctx context.Context, c *OnlineSchemaCommand) error {
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	pr, pw := io.Pipe()
+	cmd.Stdout, cmd.Stderr = pw, pw
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			log.FromContext(ctx).Info("Online schema change output", zap.String("tool", c.Tool), zap.String("line", scanner.Text()))
+		}
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Run()
+	_ = pw.Close()
+	<-done
+	return err
+}
+
+// redactPtOSCDSN 脱敏 pt-online-schema-change DSN 参数中的密码
+func redactPtOSCDSN(dsn string) string {
+	parts := strings.Split(dsn, ",")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "p=") {
+			parts[i] = "p=***"
+		}
+	}
+	return strings.Join(parts, ",")
+}
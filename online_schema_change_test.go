@@ -0,0 +1,152 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOnlineSchemaChangeCommand(t *testing.T) {
+	const password = `p"a\ss`
+	conn := &Options{Host: "db.internal:3307", Username: "migrator", Password: password, Database: "shop"}
+	tests := []struct {
+		name     string
+		change   OnlineSchemaChange
+		opts     *OnlineSchemaOptions
+		wantPath string
+		wantArgs func(defaultsFile string) []string
+		wantErr  bool
+	}{
+		{
+			name:     "gh-ost",
+			change:   OnlineSchemaChange{Version: "1", Table: "orders", Alter: "ADD INDEX idx_created_at (created_at);", ChunkSize: 2000, MaxLoad: "Threads_running=25", Args: []string{"--allow-on-master"}},
+			opts:     &OnlineSchemaOptions{Connection: conn},
+			wantPath: "gh-ost",
+			wantArgs: func(f string) []string {
+				return []string{"--host=db.internal", "--port=3307", "--user=migrator", "--conf=" + f, "--database=shop", "--table=orders",
+					"--alter=ADD INDEX idx_created_at (created_at)", "--chunk-size=2000", "--max-load=Threads_running=25", "--allow-on-master", "--execute"}
+			},
+		},
+		{
+			name:     "pt-online-schema-change",
+			change:   OnlineSchemaChange{Version: "2", Table: "orders", Alter: "DROP COLUMN legacy", Tool: OnlineSchemaToolPtOSC, ChunkSize: 500},
+			opts:     &OnlineSchemaOptions{Connection: conn, PtOSCPath: "/usr/bin/pt-online-schema-change"},
+			wantPath: "/usr/bin/pt-online-schema-change",
+			wantArgs: func(f string) []string {
+				return []string{"--alter", "DROP COLUMN legacy", "--chunk-size", "500", "--execute",
+					"h=db.internal,P=3307,u=migrator,F=" + f + ",D=shop,t=orders"}
+			},
+		},
+		{
+			name:    "native has no command",
+			change:  OnlineSchemaChange{Version: "3", Table: "orders", Alter: "ADD COLUMN note TEXT", Tool: OnlineSchemaToolNative},
+			opts:    &OnlineSchemaOptions{Connection: conn},
+			wantErr: true,
+		},
+		{
+			name:    "missing connection",
+			change:  OnlineSchemaChange{Version: "4", Table: "orders", Alter: "ADD COLUMN note TEXT"},
+			opts:    &OnlineSchemaOptions{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := tt.change.Command(context.Background(), tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					_ = cmd.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Command: %v", err)
+			}
+			defer func() { _ = cmd.Close() }()
+
+			if cmd.Path != tt.wantPath {
+				t.Errorf("path = %q, want %q", cmd.Path, tt.wantPath)
+			}
+			want := tt.wantArgs(cmd.DefaultsFile)
+			if strings.Join(cmd.Args, "\n") != strings.Join(want, "\n") {
+				t.Errorf("args = %q, want %q", cmd.Args, want)
+			}
+			if s := cmd.String(); strings.Contains(s, password) {
+				t.Errorf("command line contains the password: %s", s)
+			}
+
+			info, err := os.Stat(cmd.DefaultsFile)
+			if err != nil {
+				t.Fatalf("stat defaults file: %v", err)
+			}
+			if perm := info.Mode().Perm(); perm != 0o600 {
+				t.Errorf("defaults file mode = %o, want 600", perm)
+			}
+			data, err := os.ReadFile(cmd.DefaultsFile)
+			if err != nil {
+				t.Fatalf("read defaults file: %v", err)
+			}
+			if wantData := "[client]\nuser=\"migrator\"\npassword=\"p\\\"a\\\\ss\"\n"; string(data) != wantData {
+				t.Errorf("defaults file = %q, want %q", data, wantData)
+			}
+
+			file := cmd.DefaultsFile
+			if err := cmd.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if _, err := os.Stat(file); !os.IsNotExist(err) {
+				t.Errorf("defaults file not removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestOnlineSchemaCommandStringRedaction(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  OnlineSchemaCommand
+		want string
+	}{
+		{
+			name: "gh-ost password argument",
+			cmd:  OnlineSchemaCommand{Tool: OnlineSchemaToolGhost, Path: "gh-ost", Args: []string{"--user=root", "--password=secret", "--execute"}},
+			want: `gh-ost "--user=root" "--password=***" "--execute"`,
+		},
+		{
+			name: "pt-osc dsn password",
+			cmd:  OnlineSchemaCommand{Tool: OnlineSchemaToolPtOSC, Path: "pt-online-schema-change", Args: []string{"--execute", "h=db,P=3306,u=root,p=secret,D=shop,t=orders"}},
+			want: `pt-online-schema-change "--execute" "h=db,P=3306,u=root,p=***,D=shop,t=orders"`,
+		},
+		{
+			name: "gh-ost dsn-like argument is untouched",
+			cmd:  OnlineSchemaCommand{Tool: OnlineSchemaToolGhost, Path: "gh-ost", Args: []string{"h=db,p=x"}},
+			want: `gh-ost "h=db,p=x"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd.String(); got != tt.want {
+				t.Errorf("String() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,27 +18,74 @@ package db
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	pkgConfig "github.com/go-anyway/framework-config"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm/logger"
 )
 
 // MySQLConfig MySQL 数据库配置结构体（用于从配置文件创建）
 type MySQLConfig struct {
-	Enabled        bool               `yaml:"enabled" env:"MYSQL_ENABLED" default:"true"`
-	Host           string             `yaml:"host" env:"MYSQL_HOST" default:"localhost"`
-	Port           int                `yaml:"port" env:"MYSQL_PORT" default:"3306"`
-	Database       string             `yaml:"database" env:"MYSQL_DATABASE" required:"true"`
-	Username       string             `yaml:"username" env:"MYSQL_USERNAME" required:"true"`
-	Password       string             `yaml:"password" env:"MYSQL_PASSWORD" required:"true"`
-	MaxConnections int                `yaml:"max_connections" env:"MYSQL_MAX_CONNECTIONS" default:"100"`
-	Timeout        pkgConfig.Duration `yaml:"timeout" env:"MYSQL_TIMEOUT" default:"30s"`
-	Charset        string             `yaml:"charset" env:"MYSQL_CHARSET" default:"utf8mb4"`
-	ParseTime      bool               `yaml:"parse_time" env:"MYSQL_PARSE_TIME" default:"true"`
-	Loc            string             `yaml:"loc" env:"MYSQL_LOC" default:"Local"`
-	EnableTrace    bool               `yaml:"enable_trace" env:"MYSQL_ENABLE_TRACE" default:"true"`
+	Enabled         bool               `yaml:"enabled" env:"MYSQL_ENABLED" default:"true"`
+	Profile         string             `yaml:"profile" env:"MYSQL_PROFILE"` // 配置预设：dev、staging、prod，仅覆盖未显式配置的项
+	Host            string             `yaml:"host" env:"MYSQL_HOST" default:"localhost"`
+	Port            int                `yaml:"port" env:"MYSQL_PORT" default:"3306"`
+	Database        string             `yaml:"database" env:"MYSQL_DATABASE" required:"true"`
+	Username        string             `yaml:"username" env:"MYSQL_USERNAME" required:"true"`
+	Password        string             `yaml:"password" env:"MYSQL_PASSWORD"`
+	PasswordFile    string             `yaml:"password_file" env:"MYSQL_PASSWORD_FILE"` // 密码文件路径，优先于 Password
+	MaxConnections  int                `yaml:"max_connections" env:"MYSQL_MAX_CONNECTIONS" default:"100"`
+	Timeout         pkgConfig.Duration `yaml:"timeout" env:"MYSQL_TIMEOUT" default:"30s"`
+	Charset         string             `yaml:"charset" env:"MYSQL_CHARSET" default:"utf8mb4"`
+	Collation       string             `yaml:"collation" env:"MYSQL_COLLATION"` // 创建数据库时的排序规则，为空时使用字符集的默认排序规则
+	ParseTime       bool               `yaml:"parse_time" env:"MYSQL_PARSE_TIME" default:"true"`
+	Loc             string             `yaml:"loc" env:"MYSQL_LOC" default:"Local"`
+	Compress        bool               `yaml:"compress" env:"MYSQL_COMPRESS"`                  // 启用 zlib 协议压缩，适用于跨地域等高延迟链路，会增加 CPU 开销；驱动暂不支持 zstd
+	LogLevel        string             `yaml:"log_level" env:"MYSQL_LOG_LEVEL" default:"info"` // silent, error, warn, info
+	EnableTrace     bool               `yaml:"enable_trace" env:"MYSQL_ENABLE_TRACE" default:"true"`
+	TraceSampleRate float64            `yaml:"trace_sample_rate" env:"MYSQL_TRACE_SAMPLE_RATE"`                    // 上游没有 span 时创建 span 的比例，取值 (0, 1]，0 表示全部创建；上游已有 span 时始终创建
+	InstanceName    string             `yaml:"instance_name" env:"MYSQL_INSTANCE_NAME"`                            // 逻辑连接名称（如 orders-primary），用于日志字段、指标标签和 span 属性
+	SlowQuery       pkgConfig.Duration `yaml:"slow_query_threshold" env:"MYSQL_SLOW_QUERY_THRESHOLD" default:"1s"` // 慢查询阈值，启用追踪时超过该值的语句记录到最近慢查询列表，0 表示不记录
+	CreateDatabase  bool               `yaml:"create_database" env:"MYSQL_CREATE_DATABASE"`                        // 连接前创建不存在的数据库，适用于预览环境和测试
+	WarmUp          int                `yaml:"warm_up_connections" env:"MYSQL_WARM_UP_CONNECTIONS"`                // 创建连接时预先建立并 Ping 的连接数，不超过最大空闲连接数，0 表示不预热
+
+	MaxResultRows      int  `yaml:"max_result_rows" env:"MYSQL_MAX_RESULT_ROWS"`             // 单次查询允许返回的最大行数，0 表示不限制
+	AbortOnLargeResult bool `yaml:"abort_on_large_result" env:"MYSQL_ABORT_ON_LARGE_RESULT"` // 超过最大行数时返回 ErrResultTooLarge，否则仅告警
+	PprofLabels        bool `yaml:"pprof_labels" env:"MYSQL_PPROF_LABELS"`                   // 启用追踪时为语句执行设置 pprof 标签（操作、表），用于在 CPU 和 goroutine profile 中归因
+
+	DisableMetrics   bool   `yaml:"disable_metrics" env:"MYSQL_DISABLE_METRICS"`     // 不记录该连接的查询指标，追踪和日志不受影响，用于批处理任务等不应混入服务指标的连接
+	MetricsNamespace string `yaml:"metrics_namespace" env:"MYSQL_METRICS_NAMESPACE"` // 查询指标名前缀，如 batch 对应 batch_database_queries_total，为空时使用默认指标
+	MetricsBackend   string `yaml:"metrics_backend" env:"MYSQL_METRICS_BACKEND"`     // 查询指标的输出后端：prometheus（默认）、otel（通过 OpenTelemetry MeterProvider 导出）、both
+
+	TiDB                 bool              `yaml:"tidb" env:"MYSQL_TIDB"`                                               // TiDB 模式：ExecuteTx 默认重试 TiDB 写冲突等可重试错误
+	IsolationReadEngines []string          `yaml:"tidb_isolation_read_engines" env:"MYSQL_TIDB_ISOLATION_READ_ENGINES"` // TiDB 读取数据的存储引擎：tikv、tiflash、tidb
+	DisableShareLocks    bool              `yaml:"disable_share_locks" env:"MYSQL_DISABLE_SHARE_LOCKS"`                 // 移除查询中的 FOR SHARE 锁（TiDB 默认不支持共享锁）
+	TxRetries            int               `yaml:"tx_retries" env:"MYSQL_TX_RETRIES"`                                   // ExecuteTx 遇到死锁或写冲突时的最大重试次数，0 时 TiDB 模式使用 5，否则不重试
+	SessionVars          map[string]string `yaml:"session_vars"`                                                        // 连接时设置的会话变量，值按 SQL 字面量写入，字符串需带单引号
+	Params               map[string]string `yaml:"params"`                                                              // 追加到 DSN 的驱动参数，如 readTimeout、writeTimeout、interpolateParams，可覆盖 charset、parseTime、loc 等默认参数
+
+	Hosts              []string `yaml:"hosts" env:"MYSQL_HOSTS"`                               // 逗号分隔的 host:port 列表，设置后忽略 host 和 port，新连接优先使用当前节点，不可用时按顺序切换到下一个节点
+	TargetSessionAttrs string   `yaml:"target_session_attrs" env:"MYSQL_TARGET_SESSION_ATTRS"` // 多节点时新连接要求的节点类型：any、read-write（只连接 read_only=OFF 的主库），默认 any
+
+	QueryTimeout           pkgConfig.Duration `yaml:"query_timeout" env:"MYSQL_QUERY_TIMEOUT"`                       // 每条语句的默认超时，context 已有更早的截止时间时不生效，0 表示不限制
+	LeakDetectionThreshold pkgConfig.Duration `yaml:"leak_detection_threshold" env:"MYSQL_LEAK_DETECTION_THRESHOLD"` // 事务持有连接超过该时间时输出带调用栈的告警，用于排查连接泄漏，0 表示不检测
+	ForceUTC               bool               `yaml:"force_utc" env:"MYSQL_FORCE_UTC"`                               // 时间统一以 UTC 存储和读取，不受 loc 影响，TIMESTAMP 列还需在 session_vars 中设置 time_zone
+
+	Tunnel  TunnelConfig  `yaml:"tunnel"`   // 通过 SOCKS5/HTTP 代理或 SSH 隧道连接，未配置时直接连接
+	AzureAD AzureADConfig `yaml:"azure_ad"` // 使用 Azure AD 托管标识获取的访问令牌作为密码，适用于 Azure Database
+
+	IDGenerator IDGeneratorConfig `yaml:"id_generator"` // 新增时自动填充零值主键（UUIDv7、ULID、雪花 ID），未启用时不填充
+
+	SlowQueryLog SlowQueryLogConfig `yaml:"slow_query_log"` // 慢查询额外写入按大小轮转的 JSON 文件，独立于应用日志，未设置 path 时不写入
+
+	explicit profileKeys // 显式设置的配置项，应用预设时不覆盖，见 MarkExplicit
 }
 
 // Validate 验证 MySQL 配置
@@ -49,14 +96,19 @@ func (c *MySQLConfig) Validate() error {
 	if !c.Enabled {
 		return nil // 如果未启用，不需要验证
 	}
+	if c.Profile != "" {
+		if _, err := lookupProfile(c.Profile); err != nil {
+			return fmt.Errorf("mysql profile: %w", err)
+		}
+	}
 	if c.Database == "" {
 		return fmt.Errorf("mysql database is required")
 	}
 	if c.Username == "" {
 		return fmt.Errorf("mysql username is required")
 	}
-	if c.Password == "" {
-		return fmt.Errorf("mysql password is required")
+	if c.Password == "" && c.PasswordFile == "" {
+		return fmt.Errorf("mysql password or password_file is required")
 	}
 	if c.Port < 1 || c.Port > 65535 {
 		return fmt.Errorf("mysql port must be between 1 and 65535, got %d", c.Port)
@@ -64,11 +116,88 @@ func (c *MySQLConfig) Validate() error {
 	if c.MaxConnections < 1 {
 		return fmt.Errorf("mysql max_connections must be greater than 0, got %d", c.MaxConnections)
 	}
+	if c.TraceSampleRate < 0 || c.TraceSampleRate > 1 {
+		return fmt.Errorf("mysql trace_sample_rate must be between 0 and 1, got %v", c.TraceSampleRate)
+	}
+	if c.MaxResultRows < 0 {
+		return fmt.Errorf("mysql max_result_rows must be non-negative, got %d", c.MaxResultRows)
+	}
+	if c.WarmUp < 0 {
+		return fmt.Errorf("mysql warm_up_connections must be non-negative, got %d", c.WarmUp)
+	}
+	if c.TxRetries < 0 {
+		return fmt.Errorf("mysql tx_retries must be non-negative, got %d", c.TxRetries)
+	}
+	for _, engine := range c.IsolationReadEngines {
+		switch strings.TrimSpace(engine) {
+		case "tikv", "tiflash", "tidb":
+		default:
+			return fmt.Errorf("mysql tidb_isolation_read_engines must be tikv, tiflash or tidb, got %q", engine)
+		}
+	}
+	for name := range c.SessionVars {
+		if !sqlOptionPattern.MatchString(name) {
+			return fmt.Errorf("mysql session_vars name contains invalid characters: %s", name)
+		}
+	}
+	for name := range c.Params {
+		if !sqlOptionPattern.MatchString(name) {
+			return fmt.Errorf("mysql params name contains invalid characters: %s", name)
+		}
+	}
+	for _, h := range c.Hosts {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(h))
+		if err != nil || host == "" {
+			return fmt.Errorf("mysql hosts must be host:port, got %q", h)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("mysql hosts port must be between 1 and 65535, got %q", h)
+		}
+	}
+	switch c.TargetSessionAttrs {
+	case "", "any", "read-write":
+	default:
+		return fmt.Errorf("mysql target_session_attrs must be any or read-write, got %s", c.TargetSessionAttrs)
+	}
+	if err := c.Tunnel.Validate(); err != nil {
+		return fmt.Errorf("mysql %w", err)
+	}
+	if err := c.SlowQueryLog.Validate(); err != nil {
+		return fmt.Errorf("mysql %w", err)
+	}
+	if err := c.IDGenerator.Validate(); err != nil {
+		return fmt.Errorf("mysql %w", err)
+	}
+	if _, err := parseLogLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("mysql log_level: %w", err)
+	}
+	if c.CreateDatabase {
+		if c.Charset != "" && !sqlOptionPattern.MatchString(c.Charset) {
+			return fmt.Errorf("mysql charset contains invalid characters: %s", c.Charset)
+		}
+		if c.Collation != "" && !sqlOptionPattern.MatchString(c.Collation) {
+			return fmt.Errorf("mysql collation contains invalid characters: %s", c.Collation)
+		}
+	}
+	if err := validateMetricsNamespace(c.MetricsNamespace); err != nil {
+		return fmt.Errorf("mysql %w", err)
+	}
+	if err := validateMetricsBackend(c.MetricsBackend); err != nil {
+		return fmt.Errorf("mysql %w", err)
+	}
 	return nil
 }
 
 // ToOptions 转换为 Options
 func (c *MySQLConfig) ToOptions() (*Options, error) {
+	// 预设应用到副本上，不修改调用方的配置
+	if c != nil && c.Profile != "" {
+		cfg := *c
+		if err := cfg.ApplyProfile(); err != nil {
+			return nil, fmt.Errorf("mysql: %w", err)
+		}
+		c = &cfg
+	}
 	if err := c.Validate(); err != nil {
 		return nil, err
 	}
@@ -76,42 +205,165 @@ func (c *MySQLConfig) ToOptions() (*Options, error) {
 		return nil, fmt.Errorf("mysql is not enabled")
 	}
 
+	password, provider, err := resolvePassword(c.Password, c.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("mysql: %w", err)
+	}
+	if p := c.AzureAD.provider(); p != nil {
+		password, provider = "", p
+	}
+	dialer, err := c.Tunnel.dialer()
+	if err != nil {
+		return nil, fmt.Errorf("mysql: %w", err)
+	}
+	idGenerator, err := c.IDGenerator.options()
+	if err != nil {
+		return nil, fmt.Errorf("mysql: %w", err)
+	}
+
 	timeout := c.Timeout.Duration()
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
+	var engines []string
+	for _, engine := range c.IsolationReadEngines {
+		engines = append(engines, strings.TrimSpace(engine))
+	}
+	host := fmt.Sprintf("%s:%d", c.Host, c.Port)
+	var hosts []string
+	for _, h := range c.Hosts {
+		hosts = append(hosts, strings.TrimSpace(h))
+	}
+	if len(hosts) > 0 {
+		host = hosts[0]
+	}
+	var sessionVars map[string]string
+	if len(c.SessionVars) > 0 {
+		sessionVars = make(map[string]string, len(c.SessionVars))
+		for k, v := range c.SessionVars {
+			sessionVars[k] = v
+		}
+	}
+	var params map[string]string
+	if len(c.Params) > 0 {
+		params = make(map[string]string, len(c.Params))
+		for k, v := range c.Params {
+			params[k] = v
+		}
+	}
+
 	return &Options{
-		Host:                  fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Host:                  host,
 		Username:              c.Username,
-		Password:              c.Password,
+		Password:              password,
+		PasswordProvider:      provider,
+		CleartextPassword:     c.AzureAD.Enabled,
+		Dialer:                dialer,
 		Database:              c.Database,
 		MaxIdleConnections:    c.MaxConnections / 10, // 默认空闲连接数为最大连接数的 10%
 		MaxOpenConnections:    c.MaxConnections,
 		MaxConnectionLifeTime: timeout,
-		LogLevel:              logger.Info,
+		LogLevel:              logLevel(c.LogLevel),
 		EnableTrace:           c.EnableTrace,
+		TraceSampleRate:       c.TraceSampleRate,
+		DisableMetrics:        c.DisableMetrics,
+		MetricsNamespace:      c.MetricsNamespace,
+		MetricsBackend:        c.MetricsBackend,
+		InstanceName:          c.InstanceName,
+		SlowQueryThreshold:    c.SlowQuery.Duration(),
+		SlowQuerySinks:        c.SlowQueryLog.sinks(),
+		CreateDatabase:        c.CreateDatabase,
+		WarmUpConnections:     c.WarmUp,
+		Charset:               c.Charset,
+		Collation:             c.Collation,
+		Compress:              c.Compress,
+		MaxResultRows:         c.MaxResultRows,
+		AbortOnLargeResult:    c.AbortOnLargeResult,
+		PprofLabels:           c.PprofLabels,
+		TiDB:                  c.TiDB,
+		IsolationReadEngines:  engines,
+		DisableShareLocks:     c.DisableShareLocks,
+		TxRetries:             c.TxRetries,
+		SessionVars:           sessionVars,
+		Params:                params,
+		Hosts:                 hosts,
+		TargetSessionAttrs:    c.TargetSessionAttrs,
+		QueryTimeout:          queryTimeoutOptions(c.QueryTimeout.Duration()),
+		LeakDetection:         leakDetectionOptions(c.LeakDetectionThreshold.Duration()),
+		ForceUTC:              c.ForceUTC,
+		IDGenerator:           idGenerator,
 	}, nil
 }
 
 // DSN 返回 MySQL 数据源名称
 func (c *MySQLConfig) DSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%t&loc=%s",
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%t&loc=%s",
 		c.Username, c.Password, c.Host, c.Port, c.Database, c.Charset, c.ParseTime, c.Loc)
+	params := make([]string, 0, len(c.Params))
+	for k := range c.Params {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		dsn += "&" + k + "=" + url.QueryEscape(c.Params[k])
+	}
+	return dsn
 }
 
 // PostgreSQLConfig PostgreSQL 配置结构体（用于从配置文件创建）
 type PostgreSQLConfig struct {
-	Enabled        bool               `yaml:"enabled" env:"POSTGRESQL_ENABLED" default:"true"`
-	Host           string             `yaml:"host" env:"POSTGRESQL_HOST" default:"localhost"`
-	Port           int                `yaml:"port" env:"POSTGRESQL_PORT" default:"5432"`
-	Database       string             `yaml:"database" env:"POSTGRESQL_DATABASE" required:"true"`
-	Username       string             `yaml:"username" env:"POSTGRESQL_USERNAME" required:"true"`
-	Password       string             `yaml:"password" env:"POSTGRESQL_PASSWORD" required:"true"`
-	SSLMode        string             `yaml:"ssl_mode" env:"POSTGRESQL_SSL_MODE" default:"disable"`
-	MaxConnections int                `yaml:"max_connections" env:"POSTGRESQL_MAX_CONNECTIONS" default:"100"`
-	Timeout        pkgConfig.Duration `yaml:"timeout" env:"POSTGRESQL_TIMEOUT" default:"30s"`
-	EnableTrace    bool               `yaml:"enable_trace" env:"POSTGRESQL_ENABLE_TRACE" default:"true"`
+	Enabled         bool               `yaml:"enabled" env:"POSTGRESQL_ENABLED" default:"true"`
+	Profile         string             `yaml:"profile" env:"POSTGRESQL_PROFILE"` // 配置预设：dev、staging、prod，仅覆盖未显式配置的项
+	Host            string             `yaml:"host" env:"POSTGRESQL_HOST" default:"localhost"`
+	Port            int                `yaml:"port" env:"POSTGRESQL_PORT" default:"5432"`
+	Database        string             `yaml:"database" env:"POSTGRESQL_DATABASE" required:"true"`
+	Username        string             `yaml:"username" env:"POSTGRESQL_USERNAME" required:"true"`
+	Password        string             `yaml:"password" env:"POSTGRESQL_PASSWORD"`
+	PasswordFile    string             `yaml:"password_file" env:"POSTGRESQL_PASSWORD_FILE"` // 密码文件路径，优先于 Password
+	SSLMode         string             `yaml:"ssl_mode" env:"POSTGRESQL_SSL_MODE" default:"disable"`
+	MaxConnections  int                `yaml:"max_connections" env:"POSTGRESQL_MAX_CONNECTIONS" default:"100"`
+	Timeout         pkgConfig.Duration `yaml:"timeout" env:"POSTGRESQL_TIMEOUT" default:"30s"`      // 连接最大存活时间，建立连接的超时见 connect_timeout
+	LogLevel        string             `yaml:"log_level" env:"POSTGRESQL_LOG_LEVEL" default:"info"` // silent, error, warn, info
+	EnableTrace     bool               `yaml:"enable_trace" env:"POSTGRESQL_ENABLE_TRACE" default:"true"`
+	TraceSampleRate float64            `yaml:"trace_sample_rate" env:"POSTGRESQL_TRACE_SAMPLE_RATE"`                    // 上游没有 span 时创建 span 的比例，取值 (0, 1]，0 表示全部创建；上游已有 span 时始终创建
+	InstanceName    string             `yaml:"instance_name" env:"POSTGRESQL_INSTANCE_NAME"`                            // 逻辑连接名称（如 orders-primary），用于日志字段、指标标签和 span 属性
+	SlowQuery       pkgConfig.Duration `yaml:"slow_query_threshold" env:"POSTGRESQL_SLOW_QUERY_THRESHOLD" default:"1s"` // 慢查询阈值，启用追踪时超过该值的语句记录到最近慢查询列表，0 表示不记录
+	CreateDatabase  bool               `yaml:"create_database" env:"POSTGRESQL_CREATE_DATABASE"`                        // 连接前创建不存在的数据库，适用于预览环境和测试
+	WarmUp          int                `yaml:"warm_up_connections" env:"POSTGRESQL_WARM_UP_CONNECTIONS"`                // 创建连接时预先建立并 Ping 的连接数，不超过最大空闲连接数，0 表示不预热
+	Template        string             `yaml:"template" env:"POSTGRESQL_TEMPLATE"`                                      // 创建数据库时使用的模板库，为空时使用 template1
+	Schema          string             `yaml:"schema" env:"POSTGRESQL_SCHEMA"`                                          // 设置 create_database 时一并创建的 schema
+
+	MaxResultRows      int  `yaml:"max_result_rows" env:"POSTGRESQL_MAX_RESULT_ROWS"`             // 单次查询允许返回的最大行数，0 表示不限制
+	AbortOnLargeResult bool `yaml:"abort_on_large_result" env:"POSTGRESQL_ABORT_ON_LARGE_RESULT"` // 超过最大行数时返回 ErrResultTooLarge，否则仅告警
+	PprofLabels        bool `yaml:"pprof_labels" env:"POSTGRESQL_PPROF_LABELS"`                   // 启用追踪时为语句执行设置 pprof 标签（操作、表），用于在 CPU 和 goroutine profile 中归因
+
+	DisableMetrics   bool   `yaml:"disable_metrics" env:"POSTGRESQL_DISABLE_METRICS"`     // 不记录该连接的查询指标，追踪和日志不受影响，用于批处理任务等不应混入服务指标的连接
+	MetricsNamespace string `yaml:"metrics_namespace" env:"POSTGRESQL_METRICS_NAMESPACE"` // 查询指标名前缀，如 batch 对应 batch_database_queries_total，为空时使用默认指标
+	MetricsBackend   string `yaml:"metrics_backend" env:"POSTGRESQL_METRICS_BACKEND"`     // 查询指标的输出后端：prometheus（默认）、otel（通过 OpenTelemetry MeterProvider 导出）、both
+
+	Cockroach   bool              `yaml:"cockroach" env:"POSTGRESQL_COCKROACH"`   // CockroachDB 模式：ExecuteTx 默认重试 40001 错误，设置推荐的会话变量
+	Hosts       []string          `yaml:"hosts" env:"POSTGRESQL_HOSTS"`           // 逗号分隔的 host:port 列表，设置后忽略 host 和 port，新连接随机选择节点以分摊负载
+	TxRetries   int               `yaml:"tx_retries" env:"POSTGRESQL_TX_RETRIES"` // ExecuteTx 遇到 40001 错误时的最大重试次数，0 时 CockroachDB 模式使用 5，否则不重试
+	SessionVars map[string]string `yaml:"session_vars"`                           // 连接时设置的会话变量，覆盖 CockroachDB 模式的推荐值
+
+	QueryTimeout           pkgConfig.Duration `yaml:"query_timeout" env:"POSTGRESQL_QUERY_TIMEOUT"`                       // 每条语句的默认超时，context 已有更早的截止时间时不生效，0 表示不限制
+	StatementTimeout       pkgConfig.Duration `yaml:"statement_timeout" env:"POSTGRESQL_STATEMENT_TIMEOUT"`               // 会话的 statement_timeout，由服务端中止超时的语句，0 使用服务端配置
+	ConnectTimeout         pkgConfig.Duration `yaml:"connect_timeout" env:"POSTGRESQL_CONNECT_TIMEOUT" default:"10s"`     // 建立连接（含 TLS 握手和认证）的超时，按秒向上取整，0 表示不限制
+	LeakDetectionThreshold pkgConfig.Duration `yaml:"leak_detection_threshold" env:"POSTGRESQL_LEAK_DETECTION_THRESHOLD"` // 事务持有连接超过该时间时输出带调用栈的告警，用于排查连接泄漏，0 表示不检测
+	ForceUTC               bool               `yaml:"force_utc" env:"POSTGRESQL_FORCE_UTC"`                               // 时间统一以 UTC 存储和读取，读取的 timestamptz 转换为 UTC 时区
+	TargetSessionAttrs     string             `yaml:"target_session_attrs" env:"POSTGRESQL_TARGET_SESSION_ATTRS"`         // 新连接要求的节点类型：any、read-write、read-only、primary、standby、prefer-standby；多节点且为 read-write 或 primary 时优先使用当前主库，故障转移后切换到新主库
+	PreferSimpleProtocol   bool               `yaml:"prefer_simple_protocol" env:"POSTGRESQL_PREFER_SIMPLE_PROTOCOL"`     // 使用简单协议执行语句，不创建预处理语句，连接 PgBouncer 事务池模式时需要启用
+	DefaultQueryExecMode   string             `yaml:"default_query_exec_mode" env:"POSTGRESQL_DEFAULT_QUERY_EXEC_MODE"`   // pgx 执行语句的方式：cache_statement、cache_describe、describe_exec、exec、simple_protocol，为空使用 cache_statement
+
+	Tunnel  TunnelConfig  `yaml:"tunnel"`   // 通过 SOCKS5/HTTP 代理或 SSH 隧道连接，未配置时直接连接
+	AzureAD AzureADConfig `yaml:"azure_ad"` // 使用 Azure AD 托管标识获取的访问令牌作为密码，适用于 Azure Database
+
+	IDGenerator IDGeneratorConfig `yaml:"id_generator"` // 新增时自动填充零值主键（UUIDv7、ULID、雪花 ID），未启用时不填充
+
+	SlowQueryLog SlowQueryLogConfig `yaml:"slow_query_log"` // 慢查询额外写入按大小轮转的 JSON 文件，独立于应用日志，未设置 path 时不写入
+
+	explicit profileKeys // 显式设置的配置项，应用预设时不覆盖，见 MarkExplicit
 }
 
 // Validate 验证 PostgreSQL 配置
@@ -122,14 +374,19 @@ func (c *PostgreSQLConfig) Validate() error {
 	if !c.Enabled {
 		return nil // 如果未启用，不需要验证
 	}
+	if c.Profile != "" {
+		if _, err := lookupProfile(c.Profile); err != nil {
+			return fmt.Errorf("postgresql profile: %w", err)
+		}
+	}
 	if c.Database == "" {
 		return fmt.Errorf("postgresql database is required")
 	}
 	if c.Username == "" {
 		return fmt.Errorf("postgresql username is required")
 	}
-	if c.Password == "" {
-		return fmt.Errorf("postgresql password is required")
+	if c.Password == "" && c.PasswordFile == "" {
+		return fmt.Errorf("postgresql password or password_file is required")
 	}
 	if c.Port < 1 || c.Port > 65535 {
 		return fmt.Errorf("postgresql port must be between 1 and 65535, got %d", c.Port)
@@ -137,6 +394,30 @@ func (c *PostgreSQLConfig) Validate() error {
 	if c.MaxConnections < 1 {
 		return fmt.Errorf("postgresql max_connections must be greater than 0, got %d", c.MaxConnections)
 	}
+	if c.TraceSampleRate < 0 || c.TraceSampleRate > 1 {
+		return fmt.Errorf("postgresql trace_sample_rate must be between 0 and 1, got %v", c.TraceSampleRate)
+	}
+	if c.MaxResultRows < 0 {
+		return fmt.Errorf("postgresql max_result_rows must be non-negative, got %d", c.MaxResultRows)
+	}
+	if c.WarmUp < 0 {
+		return fmt.Errorf("postgresql warm_up_connections must be non-negative, got %d", c.WarmUp)
+	}
+	if c.TxRetries < 0 {
+		return fmt.Errorf("postgresql tx_retries must be non-negative, got %d", c.TxRetries)
+	}
+	for _, h := range c.Hosts {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(h))
+		if err != nil || host == "" {
+			return fmt.Errorf("postgresql hosts must be host:port, got %q", h)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("postgresql hosts port must be between 1 and 65535, got %q", h)
+		}
+	}
+	if _, err := parseLogLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("postgresql log_level: %w", err)
+	}
 	// 验证 SSLMode 的有效值
 	validSSLModes := map[string]bool{
 		"disable": true, "allow": true, "prefer": true, "require": true,
@@ -145,11 +426,50 @@ func (c *PostgreSQLConfig) Validate() error {
 	if c.SSLMode != "" && !validSSLModes[c.SSLMode] {
 		return fmt.Errorf("postgresql ssl_mode must be one of: disable, allow, prefer, require, verify-ca, verify-full, got %s", c.SSLMode)
 	}
+	switch c.TargetSessionAttrs {
+	case "", "any", "read-write", "read-only", "primary", "standby", "prefer-standby":
+	default:
+		return fmt.Errorf("postgresql target_session_attrs must be one of: any, read-write, read-only, primary, standby, prefer-standby, got %s", c.TargetSessionAttrs)
+	}
+	switch c.DefaultQueryExecMode {
+	case "", "cache_statement", "cache_describe", "describe_exec", "exec", "simple_protocol":
+	default:
+		return fmt.Errorf("postgresql default_query_exec_mode must be one of: cache_statement, cache_describe, describe_exec, exec, simple_protocol, got %s", c.DefaultQueryExecMode)
+	}
+	if c.PreferSimpleProtocol && c.DefaultQueryExecMode != "" && c.DefaultQueryExecMode != "simple_protocol" {
+		return fmt.Errorf("postgresql prefer_simple_protocol conflicts with default_query_exec_mode %s", c.DefaultQueryExecMode)
+	}
+	if err := c.Tunnel.Validate(); err != nil {
+		return fmt.Errorf("postgresql %w", err)
+	}
+	if err := c.SlowQueryLog.Validate(); err != nil {
+		return fmt.Errorf("postgresql %w", err)
+	}
+	if err := c.IDGenerator.Validate(); err != nil {
+		return fmt.Errorf("postgresql %w", err)
+	}
+	if c.AzureAD.Enabled && (c.SSLMode == "" || c.SSLMode == "disable") {
+		return fmt.Errorf("postgresql azure_ad requires ssl_mode require or stricter")
+	}
+	if err := validateMetricsNamespace(c.MetricsNamespace); err != nil {
+		return fmt.Errorf("postgresql %w", err)
+	}
+	if err := validateMetricsBackend(c.MetricsBackend); err != nil {
+		return fmt.Errorf("postgresql %w", err)
+	}
 	return nil
 }
 
 // ToOptions 转换为 PostgreSQLOptions
 func (c *PostgreSQLConfig) ToOptions() (*PostgreSQLOptions, error) {
+	// 预设应用到副本上，不修改调用方的配置
+	if c != nil && c.Profile != "" {
+		cfg := *c
+		if err := cfg.ApplyProfile(); err != nil {
+			return nil, fmt.Errorf("postgresql: %w", err)
+		}
+		c = &cfg
+	}
 	if err := c.Validate(); err != nil {
 		return nil, err
 	}
@@ -157,23 +477,84 @@ func (c *PostgreSQLConfig) ToOptions() (*PostgreSQLOptions, error) {
 		return nil, fmt.Errorf("postgresql is not enabled")
 	}
 
+	password, provider, err := resolvePassword(c.Password, c.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("postgresql: %w", err)
+	}
+	if p := c.AzureAD.provider(); p != nil {
+		password, provider = "", p
+	}
+	dialer, err := c.Tunnel.dialer()
+	if err != nil {
+		return nil, fmt.Errorf("postgresql: %w", err)
+	}
+	idGenerator, err := c.IDGenerator.options()
+	if err != nil {
+		return nil, fmt.Errorf("postgresql: %w", err)
+	}
+
 	timeout := c.Timeout.Duration()
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
+	var hosts []string
+	for _, h := range c.Hosts {
+		hosts = append(hosts, strings.TrimSpace(h))
+	}
+	var sessionVars map[string]string
+	if len(c.SessionVars) > 0 {
+		sessionVars = make(map[string]string, len(c.SessionVars))
+		for k, v := range c.SessionVars {
+			sessionVars[k] = v
+		}
+	}
+
+	queryExecMode := c.DefaultQueryExecMode
+	if c.PreferSimpleProtocol {
+		queryExecMode = "simple_protocol"
+	}
+
 	return &PostgreSQLOptions{
 		Host:                  c.Host,
 		Port:                  c.Port,
 		Username:              c.Username,
-		Password:              c.Password,
+		Password:              password,
+		PasswordProvider:      provider,
+		Dialer:                dialer,
 		Database:              c.Database,
 		SSLMode:               c.SSLMode,
 		MaxIdleConnections:    c.MaxConnections / 10, // 默认空闲连接数为最大连接数的 10%
 		MaxOpenConnections:    c.MaxConnections,
 		MaxConnectionLifeTime: timeout,
-		LogLevel:              logger.Info,
+		LogLevel:              logLevel(c.LogLevel),
 		EnableTrace:           c.EnableTrace,
+		TraceSampleRate:       c.TraceSampleRate,
+		DisableMetrics:        c.DisableMetrics,
+		MetricsNamespace:      c.MetricsNamespace,
+		MetricsBackend:        c.MetricsBackend,
+		InstanceName:          c.InstanceName,
+		SlowQueryThreshold:    c.SlowQuery.Duration(),
+		SlowQuerySinks:        c.SlowQueryLog.sinks(),
+		CreateDatabase:        c.CreateDatabase,
+		WarmUpConnections:     c.WarmUp,
+		Template:              c.Template,
+		Schema:                c.Schema,
+		MaxResultRows:         c.MaxResultRows,
+		AbortOnLargeResult:    c.AbortOnLargeResult,
+		PprofLabels:           c.PprofLabels,
+		Cockroach:             c.Cockroach,
+		Hosts:                 hosts,
+		TxRetries:             c.TxRetries,
+		SessionVars:           sessionVars,
+		TargetSessionAttrs:    c.TargetSessionAttrs,
+		DefaultQueryExecMode:  queryExecMode,
+		QueryTimeout:          queryTimeoutOptions(c.QueryTimeout.Duration()),
+		StatementTimeout:      c.StatementTimeout.Duration(),
+		ConnectTimeout:        c.ConnectTimeout.Duration(),
+		LeakDetection:         leakDetectionOptions(c.LeakDetectionThreshold.Duration()),
+		ForceUTC:              c.ForceUTC,
+		IDGenerator:           idGenerator,
 	}, nil
 }
 
@@ -184,18 +565,52 @@ func (c *PostgreSQLConfig) TimeoutDuration() time.Duration {
 
 // RedisConfig Redis 配置结构体（用于从配置文件创建）
 type RedisConfig struct {
-	Enabled      bool               `yaml:"enabled" env:"REDIS_ENABLED" default:"true"`
-	Host         string             `yaml:"host" env:"REDIS_HOST" default:"localhost"`
-	Port         int                `yaml:"port" env:"REDIS_PORT" default:"6379"`
-	Password     string             `yaml:"password" env:"REDIS_PASSWORD"`
-	DB           int                `yaml:"db" env:"REDIS_DB" default:"0"`
-	PoolSize     int                `yaml:"pool_size" env:"REDIS_POOL_SIZE" default:"20"`
-	MinIdleConns int                `yaml:"min_idle_conns" env:"REDIS_MIN_IDLE_CONNS" default:"5"`
-	DialTimeout  pkgConfig.Duration `yaml:"dial_timeout" env:"REDIS_DIAL_TIMEOUT" default:"5s"`
-	ReadTimeout  pkgConfig.Duration `yaml:"read_timeout" env:"REDIS_READ_TIMEOUT" default:"3s"`
-	WriteTimeout pkgConfig.Duration `yaml:"write_timeout" env:"REDIS_WRITE_TIMEOUT" default:"3s"`
-	IdleTimeout  pkgConfig.Duration `yaml:"idle_timeout" env:"REDIS_IDLE_TIMEOUT" default:"5m"`
-	EnableTrace  bool               `yaml:"enable_trace" env:"REDIS_ENABLE_TRACE" default:"true"`
+	Enabled         bool               `yaml:"enabled" env:"REDIS_ENABLED" default:"true"`
+	Profile         string             `yaml:"profile" env:"REDIS_PROFILE"` // 配置预设：dev、staging、prod，仅覆盖未显式配置的项
+	Host            string             `yaml:"host" env:"REDIS_HOST" default:"localhost"`
+	Port            int                `yaml:"port" env:"REDIS_PORT" default:"6379"`
+	Username        string             `yaml:"username" env:"REDIS_USERNAME"` // Redis 6+ ACL 用户名
+	Password        string             `yaml:"password" env:"REDIS_PASSWORD"`
+	PasswordFile    string             `yaml:"password_file" env:"REDIS_PASSWORD_FILE"` // 密码文件路径，优先于 Password
+	DB              int                `yaml:"db" env:"REDIS_DB" default:"0"`
+	MaxDatabases    int                `yaml:"max_databases" env:"REDIS_MAX_DATABASES" default:"16"` // 服务端 databases 配置，DB 必须小于该值，0 表示不限制
+	PoolSize        int                `yaml:"pool_size" env:"REDIS_POOL_SIZE" default:"20"`
+	MinIdleConns    int                `yaml:"min_idle_conns" env:"REDIS_MIN_IDLE_CONNS" default:"5"`
+	DialTimeout     pkgConfig.Duration `yaml:"dial_timeout" env:"REDIS_DIAL_TIMEOUT" default:"5s"`
+	ReadTimeout     pkgConfig.Duration `yaml:"read_timeout" env:"REDIS_READ_TIMEOUT" default:"3s"`
+	WriteTimeout    pkgConfig.Duration `yaml:"write_timeout" env:"REDIS_WRITE_TIMEOUT" default:"3s"`
+	IdleTimeout     pkgConfig.Duration `yaml:"idle_timeout" env:"REDIS_IDLE_TIMEOUT" default:"5m"`
+	EnableTrace     bool               `yaml:"enable_trace" env:"REDIS_ENABLE_TRACE" default:"true"`
+	TraceSampleRate float64            `yaml:"trace_sample_rate" env:"REDIS_TRACE_SAMPLE_RATE"` // 上游没有 span 时创建 span 的比例，取值 (0, 1]，0 表示全部创建；上游已有 span 时始终创建
+	InstanceName    string             `yaml:"instance_name" env:"REDIS_INSTANCE_NAME"`         // 逻辑连接名称（如 orders-primary），用于日志字段、指标标签和 span 属性
+
+	ClientName            string             `yaml:"client_name" env:"REDIS_CLIENT_NAME"`                           // CLIENT SETNAME 设置的连接名称
+	Protocol              int                `yaml:"protocol" env:"REDIS_PROTOCOL" default:"3"`                     // RESP 协议版本：2 或 3
+	MaxRetries            int                `yaml:"max_retries" env:"REDIS_MAX_RETRIES" default:"3"`               // 最大重试次数，-1 表示不重试
+	MinRetryBackoff       pkgConfig.Duration `yaml:"min_retry_backoff" env:"REDIS_MIN_RETRY_BACKOFF" default:"8ms"` // 最小重试间隔
+	MaxRetryBackoff       pkgConfig.Duration `yaml:"max_retry_backoff" env:"REDIS_MAX_RETRY_BACKOFF" default:"512ms"`
+	PoolTimeout           pkgConfig.Duration `yaml:"pool_timeout" env:"REDIS_POOL_TIMEOUT"`                       // 等待空闲连接的超时，默认 ReadTimeout + 1s
+	ConnMaxLifetime       pkgConfig.Duration `yaml:"conn_max_lifetime" env:"REDIS_CONN_MAX_LIFETIME"`             // 连接最大存活时间，0 表示不限制
+	ContextTimeoutEnabled bool               `yaml:"context_timeout_enabled" env:"REDIS_CONTEXT_TIMEOUT_ENABLED"` // 是否遵循 context 的超时设置
+
+	LogSkipCommands  []string           `yaml:"log_skip_commands" env:"REDIS_LOG_SKIP_COMMANDS"`         // 不记录成功日志的命令，逗号分隔，如 ping,echo
+	LogSampleRate    float64            `yaml:"log_sample_rate" env:"REDIS_LOG_SAMPLE_RATE" default:"1"` // 成功命令日志的采样率，取值 (0, 1]，0 使用默认值 1
+	LogSlowOnly      bool               `yaml:"log_slow_only" env:"REDIS_LOG_SLOW_ONLY"`                 // 仅记录慢命令，需要设置 log_slow_threshold
+	LogSlowThreshold pkgConfig.Duration `yaml:"log_slow_threshold" env:"REDIS_LOG_SLOW_THRESHOLD"`       // 慢命令阈值，超过时以 Warn 级别记录
+	LogRedact        string             `yaml:"log_redact" env:"REDIS_LOG_REDACT"`                       // 命令日志和 span 的脱敏策略：key 仅记录命令名和键，hash 将其余参数替换为哈希，空表示不脱敏
+
+	TracePipelineCommands bool `yaml:"trace_pipeline_commands" env:"REDIS_TRACE_PIPELINE_COMMANDS"` // 是否为管道中的每条命令创建子 span（命令名、键、状态、耗时）
+
+	RequireModules []string `yaml:"require_modules" env:"REDIS_REQUIRE_MODULES"` // 启动时检查服务端已加载的模块：json、search、bloom，逗号分隔，缺少时连接失败
+
+	DisableMetrics   bool   `yaml:"disable_metrics" env:"REDIS_DISABLE_METRICS"`     // 不记录该连接的命令和连接池指标，追踪和日志不受影响，用于批处理任务等不应混入服务指标的连接
+	MetricsNamespace string `yaml:"metrics_namespace" env:"REDIS_METRICS_NAMESPACE"` // 命令指标名前缀，如 batch 对应 batch_redis_operations_total，为空时使用默认指标
+	MetricsBackend   string `yaml:"metrics_backend" env:"REDIS_METRICS_BACKEND"`     // 命令指标的输出后端：prometheus（默认）、otel（通过 OpenTelemetry MeterProvider 导出）、both
+
+	Tunnel      TunnelConfig           `yaml:"tunnel"`       // 通过 SOCKS5/HTTP 代理或 SSH 隧道连接，未配置时直接连接
+	HealthCheck RedisHealthCheckConfig `yaml:"health_check"` // 后台健康检查，仅对通过 Manager 注册的客户端生效
+
+	explicit profileKeys // 显式设置的配置项，应用预设时不覆盖，见 MarkExplicit
 }
 
 // Validate 验证 Redis 配置
@@ -206,6 +621,11 @@ func (c *RedisConfig) Validate() error {
 	if !c.Enabled {
 		return nil // 如果未启用，不需要验证
 	}
+	if c.Profile != "" {
+		if _, err := lookupProfile(c.Profile); err != nil {
+			return fmt.Errorf("redis profile: %w", err)
+		}
+	}
 	if c.Port < 1 || c.Port > 65535 {
 		return fmt.Errorf("redis port must be between 1 and 65535, got %d", c.Port)
 	}
@@ -215,14 +635,67 @@ func (c *RedisConfig) Validate() error {
 	if c.MinIdleConns < 0 {
 		return fmt.Errorf("redis min_idle_conns must be non-negative, got %d", c.MinIdleConns)
 	}
-	if c.DB < 0 || c.DB > 15 {
-		return fmt.Errorf("redis db must be between 0 and 15, got %d", c.DB)
+	if c.DB < 0 {
+		return fmt.Errorf("redis db must be non-negative, got %d", c.DB)
+	}
+	if c.MaxDatabases < 0 {
+		return fmt.Errorf("redis max_databases must be non-negative, got %d", c.MaxDatabases)
+	}
+	if c.MaxDatabases > 0 && c.DB >= c.MaxDatabases {
+		return fmt.Errorf("redis db must be between 0 and %d, got %d", c.MaxDatabases-1, c.DB)
+	}
+	if c.Protocol != 0 && c.Protocol != 2 && c.Protocol != 3 {
+		return fmt.Errorf("redis protocol must be 2 or 3, got %d", c.Protocol)
+	}
+	if c.MaxRetries < -1 {
+		return fmt.Errorf("redis max_retries must be -1 or non-negative, got %d", c.MaxRetries)
+	}
+	if minBackoff, maxBackoff := c.MinRetryBackoff.Duration(), c.MaxRetryBackoff.Duration(); minBackoff > 0 && maxBackoff > 0 && minBackoff > maxBackoff {
+		return fmt.Errorf("redis min_retry_backoff (%s) must not exceed max_retry_backoff (%s)", minBackoff, maxBackoff)
+	}
+	if c.TraceSampleRate < 0 || c.TraceSampleRate > 1 {
+		return fmt.Errorf("redis trace_sample_rate must be between 0 and 1, got %v", c.TraceSampleRate)
+	}
+	if c.LogSampleRate < 0 || c.LogSampleRate > 1 {
+		return fmt.Errorf("redis log_sample_rate must be between 0 and 1, got %v", c.LogSampleRate)
+	}
+	if c.LogSlowThreshold.Duration() < 0 {
+		return fmt.Errorf("redis log_slow_threshold must be non-negative, got %s", c.LogSlowThreshold.Duration())
+	}
+	if c.LogSlowOnly && c.LogSlowThreshold.Duration() == 0 {
+		return fmt.Errorf("redis log_slow_threshold is required when log_slow_only is enabled")
+	}
+	if err := validateRedisRedact(c.LogRedact); err != nil {
+		return fmt.Errorf("redis log_redact %w", err)
+	}
+	if err := c.Tunnel.Validate(); err != nil {
+		return fmt.Errorf("redis %w", err)
+	}
+	if err := c.HealthCheck.Validate(); err != nil {
+		return fmt.Errorf("redis %w", err)
+	}
+	if err := validateRedisModules(c.RequireModules); err != nil {
+		return fmt.Errorf("redis require_modules: %w", err)
+	}
+	if err := validateMetricsNamespace(c.MetricsNamespace); err != nil {
+		return fmt.Errorf("redis %w", err)
+	}
+	if err := validateMetricsBackend(c.MetricsBackend); err != nil {
+		return fmt.Errorf("redis %w", err)
 	}
 	return nil
 }
 
 // ToOptions 转换为 RedisOptions
 func (c *RedisConfig) ToOptions() (*RedisOptions, error) {
+	// 预设应用到副本上，不修改调用方的配置
+	if c != nil && c.Profile != "" {
+		cfg := *c
+		if err := cfg.ApplyProfile(); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		c = &cfg
+	}
 	if err := c.Validate(); err != nil {
 		return nil, err
 	}
@@ -230,6 +703,15 @@ func (c *RedisConfig) ToOptions() (*RedisOptions, error) {
 		return nil, fmt.Errorf("redis is not enabled")
 	}
 
+	password, provider, err := resolvePassword(c.Password, c.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	dialer, err := c.Tunnel.dialer()
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+
 	dialTimeout := c.DialTimeout.Duration()
 	if dialTimeout == 0 {
 		dialTimeout = 5 * time.Second
@@ -246,18 +728,49 @@ func (c *RedisConfig) ToOptions() (*RedisOptions, error) {
 	if idleTimeout == 0 {
 		idleTimeout = 5 * time.Minute
 	}
+	sampleRate := c.LogSampleRate
+	if sampleRate == 0 {
+		sampleRate = 1
+	}
 
 	return &RedisOptions{
-		Addr:         fmt.Sprintf("%s:%d", c.Host, c.Port),
-		Password:     c.Password,
-		DB:           c.DB,
-		PoolSize:     c.PoolSize,
-		MinIdleConns: c.MinIdleConns,
-		DialTimeout:  dialTimeout,
-		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
-		IdleTimeout:  idleTimeout,
-		EnableTrace:  c.EnableTrace,
+		Addr:             fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Username:         c.Username,
+		Password:         password,
+		PasswordProvider: provider,
+		Dialer:           dialer,
+		DB:               c.DB,
+		PoolSize:         c.PoolSize,
+		MinIdleConns:     c.MinIdleConns,
+		DialTimeout:      dialTimeout,
+		ReadTimeout:      readTimeout,
+		WriteTimeout:     writeTimeout,
+		IdleTimeout:      idleTimeout,
+		EnableTrace:      c.EnableTrace,
+		TraceSampleRate:  c.TraceSampleRate,
+		DisableMetrics:   c.DisableMetrics,
+		MetricsNamespace: c.MetricsNamespace,
+		MetricsBackend:   c.MetricsBackend,
+		InstanceName:     c.InstanceName,
+
+		ClientName:            c.ClientName,
+		Protocol:              c.Protocol,
+		MaxRetries:            c.MaxRetries,
+		MinRetryBackoff:       c.MinRetryBackoff.Duration(),
+		MaxRetryBackoff:       c.MaxRetryBackoff.Duration(),
+		PoolTimeout:           c.PoolTimeout.Duration(),
+		ConnMaxLifetime:       c.ConnMaxLifetime.Duration(),
+		ContextTimeoutEnabled: c.ContextTimeoutEnabled,
+
+		LogFilter: &RedisLogOptions{
+			SkipCommands:  append([]string(nil), c.LogSkipCommands...),
+			SampleRate:    sampleRate,
+			SlowOnly:      c.LogSlowOnly,
+			SlowThreshold: c.LogSlowThreshold.Duration(),
+		},
+		Redact:                c.LogRedact,
+		TracePipelineCommands: c.TracePipelineCommands,
+		RequireModules:        c.RequireModules,
 	}, nil
 }
 
@@ -286,18 +799,77 @@ func (c *RedisConfig) IdleTimeoutDuration() time.Duration {
 	return c.IdleTimeout.Duration()
 }
 
+// parseLogLevel 解析 GORM 日志级别，空字符串视为 info
+func parseLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "", "info":
+		return logger.Info, nil
+	case "warn":
+		return logger.Warn, nil
+	case "error":
+		return logger.Error, nil
+	case "silent":
+		return logger.Silent, nil
+	default:
+		return logger.Info, fmt.Errorf("must be one of: silent, error, warn, info, got %s", level)
+	}
+}
+
+// logLevel 解析 GORM 日志级别，无效值回退为 info
+func logLevel(level string) logger.LogLevel {
+	l, _ := parseLogLevel(level)
+	return l
+}
+
 // Options 结构体定义了 GORM MySQL 连接器的配置选项（内部使用）
 type Options struct {
 	Host                  string
 	Username              string
 	Password              string
+	PasswordProvider      CredentialProvider // 动态密码提供者，设置后每次建立新连接时获取最新密码
 	Database              string
 	MaxIdleConnections    int
 	MaxOpenConnections    int
 	MaxConnectionLifeTime time.Duration
 	LogLevel              logger.LogLevel // 使用 GORM 自带的 LogLevel 类型
 	Logger                logger.Interface
-	EnableTrace           bool // 是否启用 SQL 追踪插件，用于记录 SQL 执行时间
+	EnableTrace           bool                   // 是否启用 SQL 追踪插件，用于记录 SQL 执行时间
+	TraceSampleRate       float64                // context 中没有 span 时创建 span 的比例，0 表示全部创建
+	InstanceName          string                 // 逻辑连接名称，用于日志字段、指标标签和 span 属性，为空表示不区分
+	DisableMetrics        bool                   // 不记录该连接的查询指标
+	MetricsNamespace      string                 // 查询指标名前缀，为空时使用默认指标
+	MetricsBackend        string                 // 查询指标的输出后端：MetricsBackendPrometheus（默认）、MetricsBackendOTel 或 MetricsBackendBoth
+	SlowQueryThreshold    time.Duration          // 慢查询阈值，启用追踪时超过该值的语句记录到最近慢查询列表，0 表示不记录
+	SlowQuerySinks        []SlowQuerySink        // 慢查询的额外输出（如 JSON 文件、Redis Stream），需设置 SlowQueryThreshold
+	WarmUpConnections     int                    // 创建连接时预先建立并 Ping 的连接数，不超过最大空闲连接数，0 表示不预热
+	ErrorLogInterval      time.Duration          // 相同错误日志的去重窗口，0 使用默认值 10s，负数表示不去重
+	ErrorReporter         ErrorReporter          // 语句失败时的上报（如 Sentry），需启用追踪，nil 表示不上报
+	SLO                   *SLOOptions            // SLI 指标配置，nil 表示不统计
+	CircuitBreaker        *CircuitBreakerOptions // 熔断器配置，nil 表示不启用
+	CreateDatabase        bool                   // 连接前创建不存在的数据库
+	Charset               string                 // 创建数据库时的字符集
+	Collation             string                 // 创建数据库时的排序规则
+	Compress              bool                   // 启用 zlib 协议压缩
+	MaxResultRows         int                    // 单次查询允许返回的最大行数，0 表示不限制
+	AbortOnLargeResult    bool                   // 超过最大行数时返回 ErrResultTooLarge，否则仅告警
+	PprofLabels           bool                   // 启用追踪时为语句执行设置 pprof 标签
+
+	TiDB                 bool              // TiDB 模式
+	IsolationReadEngines []string          // TiDB 读取数据的存储引擎
+	DisableShareLocks    bool              // 移除查询中的 FOR SHARE 锁
+	TxRetries            int               // ExecuteTx 遇到死锁或写冲突时的最大重试次数，0 时 TiDB 模式使用 5，否则不重试
+	SessionVars          map[string]string // 连接时设置的会话变量，值按 SQL 字面量写入
+	Params               map[string]string // 追加到 DSN 的驱动参数，可覆盖默认参数
+
+	QueryTimeout       *QueryTimeoutOptions  // 语句超时配置，nil 表示不限制
+	LeakDetection      *LeakDetectionOptions // 连接泄漏和长事务检测配置，nil 表示不检测
+	ForceUTC           bool                  // 时间统一以 UTC 存储和读取，见 ForceUTC
+	Hosts              []string              // 多节点 host:port 列表，多于一个时启用故障转移，Host 为首个节点
+	TargetSessionAttrs string                // 多节点时新连接要求的节点类型：any、read-write
+	OnFailover         func(FailoverEvent)   // 切换节点时的回调
+	CleartextPassword  bool                  // 通过 TLS 以明文发送密码（mysql_clear_password），Azure AD 令牌认证需要
+	Dialer             *Dialer               // 建立网络连接的拨号器（代理、SSH 隧道），nil 时直接连接
+	IDGenerator        *IDGeneratorOptions   // 主键自动填充配置，nil 表示不填充
 }
 
 // PostgreSQLOptions 结构体定义了 GORM PostgreSQL 连接器的配置选项（内部使用）
@@ -306,6 +878,7 @@ type PostgreSQLOptions struct {
 	Port                  int
 	Username              string
 	Password              string
+	PasswordProvider      CredentialProvider // 动态密码提供者，设置后每次建立新连接时获取最新密码
 	Database              string
 	SSLMode               string
 	MaxIdleConnections    int
@@ -313,19 +886,82 @@ type PostgreSQLOptions struct {
 	MaxConnectionLifeTime time.Duration
 	LogLevel              logger.LogLevel
 	Logger                logger.Interface
-	EnableTrace           bool // 是否启用 SQL 追踪插件，用于记录 SQL 执行时间
+	EnableTrace           bool                   // 是否启用 SQL 追踪插件，用于记录 SQL 执行时间
+	TraceSampleRate       float64                // context 中没有 span 时创建 span 的比例，0 表示全部创建
+	InstanceName          string                 // 逻辑连接名称，用于日志字段、指标标签和 span 属性，为空表示不区分
+	DisableMetrics        bool                   // 不记录该连接的查询指标
+	MetricsNamespace      string                 // 查询指标名前缀，为空时使用默认指标
+	MetricsBackend        string                 // 查询指标的输出后端：MetricsBackendPrometheus（默认）、MetricsBackendOTel 或 MetricsBackendBoth
+	SlowQueryThreshold    time.Duration          // 慢查询阈值，启用追踪时超过该值的语句记录到最近慢查询列表，0 表示不记录
+	SlowQuerySinks        []SlowQuerySink        // 慢查询的额外输出（如 JSON 文件、Redis Stream），需设置 SlowQueryThreshold
+	WarmUpConnections     int                    // 创建连接时预先建立并 Ping 的连接数，不超过最大空闲连接数，0 表示不预热
+	ErrorLogInterval      time.Duration          // 相同错误日志的去重窗口，0 使用默认值 10s，负数表示不去重
+	ErrorReporter         ErrorReporter          // 语句失败时的上报（如 Sentry），需启用追踪，nil 表示不上报
+	SLO                   *SLOOptions            // SLI 指标配置，nil 表示不统计
+	CircuitBreaker        *CircuitBreakerOptions // 熔断器配置，nil 表示不启用
+	CreateDatabase        bool                   // 连接前创建不存在的数据库
+	Template              string                 // 创建数据库时使用的模板库
+	Schema                string                 // 创建数据库时一并创建的 schema
+	MaxResultRows         int                    // 单次查询允许返回的最大行数，0 表示不限制
+	AbortOnLargeResult    bool                   // 超过最大行数时返回 ErrResultTooLarge，否则仅告警
+	PprofLabels           bool                   // 启用追踪时为语句执行设置 pprof 标签
+
+	Cockroach   bool              // CockroachDB 模式
+	Hosts       []string          // 多节点 host:port 列表，设置后忽略 Host 和 Port
+	TxRetries   int               // ExecuteTx 遇到 40001 错误时的最大重试次数，0 时 CockroachDB 模式使用 5，否则不重试
+	SessionVars map[string]string // 连接时设置的会话变量
+
+	QueryTimeout         *QueryTimeoutOptions  // 语句超时配置，nil 表示不限制
+	StatementTimeout     time.Duration         // 会话的 statement_timeout，0 使用服务端配置
+	ConnectTimeout       time.Duration         // 建立连接的超时，按秒向上取整写入 DSN 的 connect_timeout，0 表示不限制
+	LeakDetection        *LeakDetectionOptions // 连接泄漏和长事务检测配置，nil 表示不检测
+	ForceUTC             bool                  // 时间统一以 UTC 存储和读取，见 ForceUTC
+	TargetSessionAttrs   string                // 新连接要求的节点类型，对应 libpq 的 target_session_attrs
+	DefaultQueryExecMode string                // pgx 执行语句的方式，simple_protocol 不使用预处理语句，适用于 PgBouncer 事务池模式，空使用 pgx 默认值
+	OnFailover           func(FailoverEvent)   // 多节点切换主库时的回调
+	Dialer               *Dialer               // 建立网络连接的拨号器（代理、SSH 隧道），nil 时直接连接
+	IDGenerator          *IDGeneratorOptions   // 主键自动填充配置，nil 表示不填充
 }
 
 // RedisOptions 结构体定义了 Redis 连接器的配置选项（内部使用）
 type RedisOptions struct {
-	Addr         string
-	Password     string
-	DB           int
-	PoolSize     int
-	MinIdleConns int
-	DialTimeout  time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
-	EnableTrace  bool // 是否启用命令追踪，用于记录 Redis 命令执行时间
+	Addr             string
+	Username         string // Redis 6+ ACL 用户名
+	Password         string
+	PasswordProvider CredentialProvider // 动态密码提供者，设置后每次建立新连接时获取最新密码
+	DB               int
+	PoolSize         int
+	MinIdleConns     int
+	DialTimeout      time.Duration
+	Dialer           *Dialer // 建立网络连接的拨号器（代理、SSH 隧道），nil 时直接连接
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+	IdleTimeout      time.Duration
+	EnableTrace      bool                   // 是否启用命令追踪，用于记录 Redis 命令执行时间
+	TraceSampleRate  float64                // context 中没有 span 时创建 span 的比例，0 表示全部创建
+	InstanceName     string                 // 逻辑连接名称，用于日志字段、指标标签和 span 属性，为空表示不区分
+	DisableMetrics   bool                   // 不记录该连接的命令和连接池指标
+	MetricsNamespace string                 // 命令指标名前缀，为空时使用默认指标
+	MetricsBackend   string                 // 命令指标的输出后端：MetricsBackendPrometheus（默认）、MetricsBackendOTel 或 MetricsBackendBoth
+	Hooks            []redis.Hook           // 自定义 Hook，添加在熔断器和追踪 Hook 之后（内层），先添加的位于外层，可用 RedisCommandHook 包装检查函数
+	ErrorLogInterval time.Duration          // 相同错误日志的去重窗口，0 使用默认值 10s，负数表示不去重
+	ErrorReporter    ErrorReporter          // 命令失败时的上报（如 Sentry），需启用追踪，nil 表示不上报
+	SLO              *SLOOptions            // SLI 指标配置，nil 表示不统计
+	CircuitBreaker   *CircuitBreakerOptions // 熔断器配置，nil 表示不启用
+
+	ClientName            string        // CLIENT SETNAME 设置的连接名称
+	Protocol              int           // RESP 协议版本：2 或 3，0 使用 go-redis 默认值
+	MaxRetries            int           // 最大重试次数，0 使用默认值 3，-1 表示不重试
+	MinRetryBackoff       time.Duration // 最小重试间隔
+	MaxRetryBackoff       time.Duration // 最大重试间隔
+	PoolTimeout           time.Duration // 等待空闲连接的超时
+	ConnMaxLifetime       time.Duration // 连接最大存活时间
+	ContextTimeoutEnabled bool          // 是否遵循 context 的超时设置
+
+	LogFilter *RedisLogOptions // 成功命令日志的过滤配置，nil 表示全部记录
+	Redact    string           // 命令日志和 span 的脱敏策略：key、hash，空表示不脱敏
+
+	TracePipelineCommands bool // 是否为管道中的每条命令创建子 span
+
+	RequireModules []string // 连接时检查服务端已加载的模块（RedisModuleJSON 等），缺少时返回 ErrRedisModuleUnavailable
 }
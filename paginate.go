@@ -0,0 +1,219 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor 分页游标无法解析或与排序列不匹配
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// SortColumn 游标分页的排序列
+type SortColumn struct {
+	Name string // 列名
+	Desc bool   // 是否降序
+}
+
+// Paginator 分页配置，零值可直接使用
+type Paginator struct {
+	DefaultSize int          // 未指定每页条数时使用的值，默认 20
+	MaxSize     int          // 每页条数上限，默认 100
+	Order       []SortColumn // 游标分页的排序列，最后一列应唯一（如主键）以保证顺序稳定，默认按主键升序
+}
+
+// Page 分页结果
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Page       int    `json:"page,omitempty"`        // 当前页码，从 1 开始，仅偏移分页
+	Size       int    `json:"size"`                  // 每页条数
+	Total      int64  `json:"total,omitempty"`       // 总条数，仅偏移分页
+	TotalPages int    `json:"total_pages,omitempty"` // 总页数，仅偏移分页
+	NextCursor string `json:"next_cursor,omitempty"` // 下一页的游标，仅游标分页
+	HasMore    bool   `json:"has_more"`              // 是否还有下一页
+}
+
+// size 返回规范化后的每页条数
+func (p *Paginator) size(n int) int {
+	defaultSize, maxSize := 20, 100
+	if p != nil {
+		if p.DefaultSize > 0 {
+			defaultSize = p.DefaultSize
+		}
+		if p.MaxSize > 0 {
+			maxSize = p.MaxSize
+		}
+	}
+	if n <= 0 {
+		n = defaultSize
+	}
+	return min(n, maxSize)
+}
+
+// Paginate 偏移分页：返回第 page 页（从 1 开始）的数据和总条数
+// 深分页时 OFFSET 开销随页码线性增长，大表翻页应使用 PaginateCursor
+func Paginate[T any](ctx context.Context, query *gorm.DB, p *Paginator, page, size int) (*Page[T], error) {
+	size = p.size(size)
+	page = max(page, 1)
+
+	q := query.Session(&gorm.Session{Context: ctx})
+	if q.Statement.Model == nil && q.Statement.Table == "" {
+		q = q.Model(new(T))
+	}
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("paginate: count: %w", err)
+	}
+
+	result := &Page[T]{
+		Items:      []T{},
+		Page:       page,
+		Size:       size,
+		Total:      total,
+		TotalPages: int((total + int64(size) - 1) / int64(size)),
+	}
+	offset := (page - 1) * size
+	if int64(offset) >= total {
+		return result, nil
+	}
+	if err := q.Offset(offset).Limit(size).Find(&result.Items).Error; err != nil {
+		return nil, fmt.Errorf("paginate: %w", err)
+	}
+	result.HasMore = int64(offset+len(result.Items)) < total
+	return result, nil
+}
+
+// PaginateCursor 游标（keyset）分页：返回 cursor 之后的一页数据，cursor 为空时返回第一页
+// 游标为上一页最后一行排序列值的编码，翻页性能与页码无关，数据变化时不会重复或遗漏
+func PaginateCursor[T any](ctx context.Context, query *gorm.DB, p *Paginator, cursor string, size int) (*Page[T], error) {
+	size = p.size(size)
+
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("paginate: %w", err)
+	}
+
+	var order []SortColumn
+	if p != nil {
+		order = p.Order
+	}
+	if len(order) == 0 {
+		if stmt.Schema.PrioritizedPrimaryField == nil {
+			return nil, fmt.Errorf("paginate: %s has no primary key, order is required", stmt.Schema.Name)
+		}
+		order = []SortColumn{{Name: stmt.Schema.PrioritizedPrimaryField.DBName}}
+	}
+	fields := make([]*schema.Field, len(order))
+	for i, c := range order {
+		if fields[i] = stmt.Schema.LookUpField(c.Name); fields[i] == nil {
+			return nil, fmt.Errorf("paginate: %s has no column %s", stmt.Schema.Name, c.Name)
+		}
+	}
+
+	q := query.Session(&gorm.Session{Context: ctx})
+	if cursor != "" {
+		values, err := decodeCursor(cursor, fields)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Where(keysetCondition(order, fields, values))
+	}
+	for i, c := range order {
+		q = q.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: fields[i].DBName}, Desc: c.Desc})
+	}
+
+	// 多取一行判断是否还有下一页
+	var items []T
+	if err := q.Limit(size + 1).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("paginate: %w", err)
+	}
+
+	result := &Page[T]{Items: items, Size: size}
+	if len(items) > size {
+		result.Items = items[:size]
+		result.HasMore = true
+
+		last := reflect.ValueOf(&result.Items[size-1]).Elem()
+		values := make([]any, len(fields))
+		for i, f := range fields {
+			values[i], _ = f.ValueOf(ctx, last)
+		}
+		next, err := json.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("paginate: encode cursor: %w", err)
+		}
+		result.NextCursor = base64.RawURLEncoding.EncodeToString(next)
+	}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	return result, nil
+}
+
+// decodeCursor 解码游标，按排序列的字段类型还原值，避免字符串与数字、时间比较
+func decodeCursor(cursor string, fields []*schema.Field) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if len(raw) != len(fields) {
+		return nil, fmt.Errorf("%w: expected %d values, got %d", ErrInvalidCursor, len(fields), len(raw))
+	}
+
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		v := reflect.New(f.FieldType)
+		if err := json.Unmarshal(raw[i], v.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCursor, f.DBName, err)
+		}
+		values[i] = v.Elem().Interface()
+	}
+	return values, nil
+}
+
+// keysetCondition 构建位于游标之后的条件：(a > x) OR (a = x AND b > y) ...，降序列使用 <
+func keysetCondition(order []SortColumn, fields []*schema.Field, values []any) clause.Expression {
+	ors := make([]clause.Expression, 0, len(order))
+	for i := range order {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: fields[j].DBName}, Value: values[j]})
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: fields[i].DBName}
+		if order[i].Desc {
+			ands = append(ands, clause.Lt{Column: column, Value: values[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: column, Value: values[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Or(ors...)
+}
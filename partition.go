@@ -0,0 +1,397 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PartitionInterval 分区的时间跨度
+type PartitionInterval string
+
+// 支持的分区时间跨度
+const (
+	PartitionDaily   PartitionInterval = "daily"
+	PartitionWeekly  PartitionInterval = "weekly" // 每周一开始
+	PartitionMonthly PartitionInterval = "monthly"
+)
+
+const partitionBoundLayout = "2006-01-02 15:04:05"
+
+// PartitionTable 按时间范围分区的表
+// PostgreSQL 表需使用 PARTITION BY RANGE (<时间列>) 声明分区，分区名为 <表名>_p<日期>；
+// MySQL 表需使用 PARTITION BY RANGE COLUMNS(<时间列>) 分区，分区名为 p<日期>，
+// 存在 VALUES LESS THAN (MAXVALUE) 的分区时新分区通过拆分该分区创建
+type PartitionTable struct {
+	Table     string            // 表名
+	Interval  PartitionInterval // 分区时间跨度，默认 daily
+	Premake   int               // 预先创建的未来分区数量（不含当前分区），默认 3
+	Retention time.Duration     // 分区保留时间，结束时间早于 now-Retention 的分区将被删除，0 表示不清理
+	Detach    bool              // 仅 PostgreSQL：分离过期分区而不删除，由调用方自行归档
+}
+
+// PartitionManagerOptions 分区管理器配置选项
+type PartitionManagerOptions struct {
+	Interval time.Duration  // 检查间隔，默认 1h
+	DryRun   bool           // 只记录将要执行的 DDL，不实际执行
+	LockName string         // 咨询锁名称，多个副本中同一时间只有一个执行分区维护，默认 partition_manager
+	Location *time.Location // 分区边界所在时区，默认 UTC
+}
+
+// PartitionManager 为已登记的表预先创建未来的时间范围分区，并按保留时间删除或分离过期分区
+type PartitionManager struct {
+	db   *gorm.DB
+	opts PartitionManagerOptions
+
+	mu     sync.RWMutex
+	tables []PartitionTable
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPartitionManager 创建分区管理器
+func NewPartitionManager(db *gorm.DB, opts *PartitionManagerOptions) (*PartitionManager, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	m := &PartitionManager{db: db}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Interval <= 0 {
+		m.opts.Interval = time.Hour
+	}
+	if m.opts.LockName == "" {
+		m.opts.LockName = "partition_manager"
+	}
+	if m.opts.Location == nil {
+		m.opts.Location = time.UTC
+	}
+	return m, nil
+}
+
+// Register 登记分区表
+func (m *PartitionManager) Register(t PartitionTable) error {
+	if !sqlOptionPattern.MatchString(t.Table) {
+		return fmt.Errorf("invalid partition table name %q", t.Table)
+	}
+	switch t.Interval {
+	case "":
+		t.Interval = PartitionDaily
+	case PartitionDaily, PartitionWeekly, PartitionMonthly:
+	default:
+		return fmt.Errorf("unsupported partition interval %q for table %s", t.Interval, t.Table)
+	}
+	if t.Premake <= 0 {
+		t.Premake = 3
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.tables {
+		if existing.Table == t.Table {
+			return fmt.Errorf("partition table %q already registered", t.Table)
+		}
+	}
+	m.tables = append(m.tables, t)
+	sort.Slice(m.tables, func(i, j int) bool { return m.tables[i].Table < m.tables[j].Table })
+	return nil
+}
+
+// Start 启动定时维护，启动时立即执行一次
+func (m *PartitionManager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		return fmt.Errorf("partition manager already started")
+	}
+	ctx, m.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.opts.Interval)
+		defer ticker.Stop()
+		for {
+			if _, err := m.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				log.FromContext(ctx).Error("Partition maintenance failed", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止定时维护，等待正在执行的维护完成
+func (m *PartitionManager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce 执行一次分区维护，返回执行（DryRun 时为计划执行）的 DDL
+// 其他副本持有咨询锁时直接返回；某张表失败时继续处理其他表，返回的错误包含所有失败的表
+func (m *PartitionManager) RunOnce(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	tables := append([]PartitionTable(nil), m.tables...)
+	m.mu.RUnlock()
+
+	var executed []string
+	err := m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		release, ok, err := tryAdvisoryLock(ctx, conn, m.opts.LockName)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.FromContext(ctx).Debug("Partition maintenance skipped, lock held by another replica",
+				zap.String("lock", m.opts.LockName))
+			return nil
+		}
+		defer release()
+
+		var errs []error
+		now := time.Now().In(m.opts.Location)
+		for _, t := range tables {
+			ddl, err := m.plan(conn, t, now)
+			if err == nil {
+				for _, stmt := range ddl {
+					if !m.opts.DryRun {
+						if err = conn.Exec(stmt).Error; err != nil {
+							err = fmt.Errorf("%s: %w", stmt, err)
+							break
+						}
+					}
+					executed = append(executed, stmt)
+					log.FromContext(ctx).Info("Partition DDL",
+						zap.String("table", t.Table),
+						zap.String("sql", stmt),
+						zap.Bool("dry_run", m.opts.DryRun),
+					)
+				}
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("partition table %s: %w", t.Table, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+	return executed, err
+}
+
+// partitionInfo 已存在的分区
+type partitionInfo struct {
+	name     string
+	maxValue bool // MySQL VALUES LESS THAN (MAXVALUE) 分区
+}
+
+// plan 比较已存在的分区，返回需要执行的 DDL
+func (m *PartitionManager) plan(conn *gorm.DB, t PartitionTable, now time.Time) ([]string, error) {
+	mysql := dialectOf(conn) == "mysql"
+	existing, err := listPartitions(conn, t.Table, mysql)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(existing))
+	var maxPartition string
+	for _, p := range existing {
+		names[p.name] = true
+		if p.maxValue {
+			maxPartition = p.name
+		}
+	}
+
+	prefix := "p"
+	if !mysql {
+		prefix = t.Table + "_p"
+	}
+
+	var ddl []string
+	start := partitionStart(t.Interval, now)
+	for i := 0; i <= t.Premake; i++ {
+		end := partitionNext(t.Interval, start)
+		name := prefix + partitionSuffix(t.Interval, start)
+		if !names[name] {
+			from, to := start.Format(partitionBoundLayout), end.Format(partitionBoundLayout)
+			switch {
+			case !mysql:
+				ddl = append(ddl, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+					quotePostgreSQLIdent(name), quotePostgreSQLIdent(t.Table), from, to))
+			case maxPartition != "":
+				ddl = append(ddl, fmt.Sprintf("ALTER TABLE %s REORGANIZE PARTITION %s INTO (PARTITION %s VALUES LESS THAN ('%s'), PARTITION %s VALUES LESS THAN (MAXVALUE))",
+					quoteMySQLIdent(t.Table), quoteMySQLIdent(maxPartition), quoteMySQLIdent(name), to, quoteMySQLIdent(maxPartition)))
+			default:
+				ddl = append(ddl, fmt.Sprintf("ALTER TABLE %s ADD PARTITION (PARTITION %s VALUES LESS THAN ('%s'))",
+					quoteMySQLIdent(t.Table), quoteMySQLIdent(name), to))
+			}
+		}
+		start = end
+	}
+
+	if t.Retention > 0 {
+		cutoff := now.Add(-t.Retention)
+		for _, p := range existing {
+			suffix, ok := strings.CutPrefix(p.name, prefix)
+			if !ok || p.maxValue {
+				continue
+			}
+			// 无法解析日期的分区（如默认分区）不处理
+			begin, err := time.ParseInLocation(partitionSuffixLayout(t.Interval), suffix, now.Location())
+			if err != nil || partitionNext(t.Interval, begin).After(cutoff) {
+				continue
+			}
+			switch {
+			case mysql:
+				ddl = append(ddl, fmt.Sprintf("ALTER TABLE %s DROP PARTITION %s", quoteMySQLIdent(t.Table), quoteMySQLIdent(p.name)))
+			case t.Detach:
+				ddl = append(ddl, fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", quotePostgreSQLIdent(t.Table), quotePostgreSQLIdent(p.name)))
+			default:
+				ddl = append(ddl, "DROP TABLE IF EXISTS "+quotePostgreSQLIdent(p.name))
+			}
+		}
+	}
+	return ddl, nil
+}
+
+// listPartitions 查询表已存在的分区
+func listPartitions(conn *gorm.DB, table string, mysql bool) ([]partitionInfo, error) {
+	var partitions []partitionInfo
+	if mysql {
+		var rows []struct {
+			Name        string
+			Description string
+		}
+		if err := conn.Raw("SELECT PARTITION_NAME AS name, COALESCE(PARTITION_DESCRIPTION, '') AS description FROM information_schema.PARTITIONS "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL", table).Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to list partitions: %w", err)
+		}
+		for _, r := range rows {
+			partitions = append(partitions, partitionInfo{name: r.Name, maxValue: strings.EqualFold(r.Description, "MAXVALUE")})
+		}
+		return partitions, nil
+	}
+
+	var names []string
+	if err := conn.Raw("SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = to_regclass(?)",
+		table).Scan(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	for _, n := range names {
+		partitions = append(partitions, partitionInfo{name: n})
+	}
+	return partitions, nil
+}
+
+// partitionStart 返回 t 所在分区的开始时间
+func partitionStart(interval PartitionInterval, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case PartitionWeekly:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PartitionMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day
+	}
+}
+
+// partitionNext 返回下一个分区的开始时间
+func partitionNext(interval PartitionInterval, start time.Time) time.Time {
+	switch interval {
+	case PartitionWeekly:
+		return start.AddDate(0, 0, 7)
+	case PartitionMonthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// partitionSuffixLayout 返回分区名中日期部分的格式
+func partitionSuffixLayout(interval PartitionInterval) string {
+	if interval == PartitionMonthly {
+		return "200601"
+	}
+	return "20060102"
+}
+
+// partitionSuffix 返回分区名中的日期部分
+func partitionSuffix(interval PartitionInterval, start time.Time) string {
+	return start.Format(partitionSuffixLayout(interval))
+}
+
+// tryAdvisoryLock 在当前连接上尝试获取咨询锁，不等待；返回是否获取成功及释放函数
+// 不支持咨询锁的方言视为获取成功
+func tryAdvisoryLock(ctx context.Context, conn *gorm.DB, name string) (func(), bool, error) {
+	var acquire, release string
+	switch dialectOf(conn) {
+	case "mysql":
+		acquire, release = "SELECT GET_LOCK(?, 0)", "SELECT RELEASE_LOCK(?)"
+	case "postgres":
+		acquire, release = "SELECT pg_try_advisory_lock(hashtext(?))::int", "SELECT pg_advisory_unlock(hashtext(?))"
+	default:
+		return func() {}, true, nil
+	}
+
+	var got *int
+	if err := conn.WithContext(ctx).Raw(acquire, name).Scan(&got).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if got == nil || *got != 1 {
+		return nil, false, nil
+	}
+	return func() {
+		// 使用独立的 context 释放锁，避免调用方 context 取消后锁残留在连接上
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := conn.WithContext(releaseCtx).Exec(release, name).Error; err != nil {
+			log.FromContext(ctx).Warn("Failed to release lock", zap.String("lock", name), zap.Error(err))
+		}
+	}, true, nil
+}
@@ -0,0 +1,587 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// PostgreSQL 数组、hstore 和范围类型，可直接用作 GORM 模型字段：
+//
+//	type Article struct {
+//		ID      uint64
+//		Tags    db.StringArray
+//		Attrs   db.HStore
+//		Publish db.TimeRange
+//	}
+//	gormDB.Where(db.ArrayContains("tags", "go")).Find(&articles)
+//
+// PostgreSQL 下分别对应 text[]、bigint[]、hstore（需要 CREATE EXTENSION hstore）和 tstzrange 列；
+// MySQL 下以 JSON 列存储，查询辅助函数改用 JSON 函数实现，行为与 PostgreSQL 一致
+
+// rangeTimeLayout MySQL 下范围边界的存储格式，固定长度的 UTC 时间，按字符串比较即按时间比较
+const rangeTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// StringArray text[] 列
+type StringArray []string
+
+// Scan 读取 PostgreSQL 数组或 JSON 数组（实现 sql.Scanner 接口），数组中的 NULL 读取为空字符串
+func (a *StringArray) Scan(src any) error {
+	elems, err := scanArray(src, a)
+	if err != nil || elems == nil {
+		return err
+	}
+	out := make(StringArray, len(elems))
+	for i, e := range elems {
+		if e != nil {
+			out[i] = *e
+		}
+	}
+	*a = out
+	return nil
+}
+
+// Value 返回 PostgreSQL 数组字面量（实现 driver.Valuer 接口）
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return formatArray(a, quoteArrayElem), nil
+}
+
+// GormValue 按方言返回写入的值，MySQL 下为 JSON 数组
+func (a StringArray) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return dialectValue(db, a == nil, a, a.Value)
+}
+
+// GormDataType 返回 GORM 通用数据类型
+func (StringArray) GormDataType() string {
+	return "array"
+}
+
+// GormDBDataType 按方言返回列类型
+func (StringArray) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return dialectDataType(db, "text[]")
+}
+
+// Int64Array bigint[] 列
+type Int64Array []int64
+
+// Scan 读取 PostgreSQL 数组或 JSON 数组（实现 sql.Scanner 接口）
+func (a *Int64Array) Scan(src any) error {
+	elems, err := scanArray(src, a)
+	if err != nil || elems == nil {
+		return err
+	}
+	out := make(Int64Array, len(elems))
+	for i, e := range elems {
+		if e == nil {
+			return fmt.Errorf("cannot scan NULL element into Int64Array")
+		}
+		if out[i], err = strconv.ParseInt(*e, 10, 64); err != nil {
+			return fmt.Errorf("invalid Int64Array element %q: %w", *e, err)
+		}
+	}
+	*a = out
+	return nil
+}
+
+// Value 返回 PostgreSQL 数组字面量（实现 driver.Valuer 接口）
+func (a Int64Array) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return formatArray(a, func(v int64) string { return strconv.FormatInt(v, 10) }), nil
+}
+
+// GormValue 按方言返回写入的值，MySQL 下为 JSON 数组
+func (a Int64Array) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return dialectValue(db, a == nil, a, a.Value)
+}
+
+// GormDataType 返回 GORM 通用数据类型
+func (Int64Array) GormDataType() string {
+	return "array"
+}
+
+// GormDBDataType 按方言返回列类型
+func (Int64Array) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return dialectDataType(db, "bigint[]")
+}
+
+// HStore hstore 列，值为 NULL 的键读取为空字符串
+type HStore map[string]string
+
+// Scan 读取 hstore 或 JSON 对象（实现 sql.Scanner 接口）
+func (h *HStore) Scan(src any) error {
+	s, ok, err := scanText(src)
+	if err != nil || !ok {
+		*h = nil
+		return err
+	}
+	if strings.HasPrefix(strings.TrimSpace(s), "{") {
+		var m map[string]string
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			return fmt.Errorf("invalid HStore json: %w", err)
+		}
+		*h = m
+		return nil
+	}
+	m, err := parseHStore(s)
+	if err != nil {
+		return err
+	}
+	*h = m
+	return nil
+}
+
+// Value 返回 hstore 字面量（实现 driver.Valuer 接口），键按字典序输出
+func (h HStore) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = quoteArrayElem(k) + "=>" + quoteArrayElem(h[k])
+	}
+	return strings.Join(pairs, ", "), nil
+}
+
+// GormValue 按方言返回写入的值，MySQL 下为 JSON 对象
+func (h HStore) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return dialectValue(db, h == nil, h, h.Value)
+}
+
+// GormDataType 返回 GORM 通用数据类型
+func (HStore) GormDataType() string {
+	return "hstore"
+}
+
+// GormDBDataType 按方言返回列类型
+func (HStore) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return dialectDataType(db, "hstore")
+}
+
+// TimeRange tstzrange 列。Lower、Upper 为零值表示该方向无界，Bounds 为空时使用 "[)"（含下界、不含上界）。
+// 列允许 NULL 时使用 *TimeRange，NULL 与无界范围才能区分
+type TimeRange struct {
+	Lower  time.Time
+	Upper  time.Time
+	Bounds string // "[)"、"[]"、"()"、"(]"
+	Empty  bool   // 空范围，不包含任何时间
+}
+
+// timeRangeJSON MySQL 下 TimeRange 的 JSON 存储格式，无界的边界不输出
+type timeRangeJSON struct {
+	Lower  string `json:"lower,omitempty"`
+	Upper  string `json:"upper,omitempty"`
+	Bounds string `json:"bounds,omitempty"`
+	Empty  bool   `json:"empty,omitempty"`
+}
+
+// Contains 判断范围是否包含时间 t
+func (r TimeRange) Contains(t time.Time) bool {
+	if r.Empty {
+		return false
+	}
+	bounds := r.bounds()
+	if !r.Lower.IsZero() && (t.Before(r.Lower) || (t.Equal(r.Lower) && bounds[0] == '(')) {
+		return false
+	}
+	if !r.Upper.IsZero() && (t.After(r.Upper) || (t.Equal(r.Upper) && bounds[1] == ')')) {
+		return false
+	}
+	return true
+}
+
+// bounds 返回边界类型，未设置时为 "[)"
+func (r TimeRange) bounds() string {
+	if len(r.Bounds) != 2 {
+		return "[)"
+	}
+	return r.Bounds
+}
+
+// Scan 读取 tstzrange 或 JSON 对象（实现 sql.Scanner 接口）
+func (r *TimeRange) Scan(src any) error {
+	s, ok, err := scanText(src)
+	if err != nil || !ok {
+		*r = TimeRange{}
+		return err
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "{") {
+		var j timeRangeJSON
+		if err := json.Unmarshal([]byte(s), &j); err != nil {
+			return fmt.Errorf("invalid TimeRange json: %w", err)
+		}
+		out := TimeRange{Bounds: j.Bounds, Empty: j.Empty}
+		if out.Lower, err = parseRangeBound(j.Lower); err != nil {
+			return err
+		}
+		if out.Upper, err = parseRangeBound(j.Upper); err != nil {
+			return err
+		}
+		*r = out
+		return nil
+	}
+	return r.parse(s)
+}
+
+// parse 解析 tstzrange 字面量，如 ["2025-01-01 00:00:00+00","2025-02-01 00:00:00+00")
+func (r *TimeRange) parse(s string) error {
+	if s == "empty" {
+		*r = TimeRange{Empty: true}
+		return nil
+	}
+	if len(s) < 3 || !strings.ContainsRune("[(", rune(s[0])) || !strings.ContainsRune("])", rune(s[len(s)-1])) {
+		return fmt.Errorf("invalid tstzrange %q", s)
+	}
+	lower, upper, ok := strings.Cut(s[1:len(s)-1], ",")
+	if !ok {
+		return fmt.Errorf("invalid tstzrange %q", s)
+	}
+	out := TimeRange{Bounds: string([]byte{s[0], s[len(s)-1]})}
+	var err error
+	if out.Lower, err = parseRangeBound(strings.Trim(lower, `"`)); err != nil {
+		return err
+	}
+	if out.Upper, err = parseRangeBound(strings.Trim(upper, `"`)); err != nil {
+		return err
+	}
+	*r = out
+	return nil
+}
+
+// Value 返回 tstzrange 字面量（实现 driver.Valuer 接口）
+func (r TimeRange) Value() (driver.Value, error) {
+	if r.Empty {
+		return "empty", nil
+	}
+	bounds := r.bounds()
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return `"` + t.UTC().Format("2006-01-02 15:04:05.999999Z07") + `"`
+	}
+	return string(bounds[0]) + format(r.Lower) + "," + format(r.Upper) + string(bounds[1]), nil
+}
+
+// GormValue 按方言返回写入的值，MySQL 下为 JSON 对象
+func (r TimeRange) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if dialectOf(db) != "mysql" {
+		v, _ := r.Value()
+		return clause.Expr{SQL: "?", Vars: []any{v}}
+	}
+	j := timeRangeJSON{Empty: r.Empty}
+	if !r.Empty {
+		j.Bounds = r.bounds()
+		if !r.Lower.IsZero() {
+			j.Lower = r.Lower.UTC().Format(rangeTimeLayout)
+		}
+		if !r.Upper.IsZero() {
+			j.Upper = r.Upper.UTC().Format(rangeTimeLayout)
+		}
+	}
+	data, _ := json.Marshal(j)
+	return clause.Expr{SQL: "?", Vars: []any{string(data)}}
+}
+
+// GormDataType 返回 GORM 通用数据类型
+func (TimeRange) GormDataType() string {
+	return "tstzrange"
+}
+
+// GormDBDataType 按方言返回列类型
+func (TimeRange) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return dialectDataType(db, "tstzrange")
+}
+
+// ArrayContains 数组列包含所有给定元素（PostgreSQL @>，MySQL JSON_CONTAINS）
+func ArrayContains[T string | int64](column string, values ...T) clause.Expression {
+	return arrayExpr(column, "@>", "JSON_CONTAINS", values)
+}
+
+// ArrayOverlaps 数组列包含任一给定元素（PostgreSQL &&，MySQL 8.0.17+ JSON_OVERLAPS）
+func ArrayOverlaps[T string | int64](column string, values ...T) clause.Expression {
+	return arrayExpr(column, "&&", "JSON_OVERLAPS", values)
+}
+
+// HStoreHasKey hstore 列包含指定键（PostgreSQL exist()，MySQL JSON_CONTAINS_PATH）
+func HStoreHasKey(column, key string) clause.Expression {
+	col := clause.Column{Name: column}
+	return dialectExpr{
+		postgres: clause.Expr{SQL: "exist(?, ?)", Vars: []any{col, key}},
+		mysql:    clause.Expr{SQL: "JSON_CONTAINS_PATH(?, 'one', ?)", Vars: []any{col, jsonKeyPath(key)}},
+	}
+}
+
+// HStoreEquals hstore 列中指定键的值等于 value
+func HStoreEquals(column, key, value string) clause.Expression {
+	col := clause.Column{Name: column}
+	return dialectExpr{
+		postgres: clause.Expr{SQL: "? -> ? = ?", Vars: []any{col, key, value}},
+		mysql:    clause.Expr{SQL: "JSON_UNQUOTE(JSON_EXTRACT(?, ?)) = ?", Vars: []any{col, jsonKeyPath(key), value}},
+	}
+}
+
+// RangeContains 范围列包含时间 t（PostgreSQL @>，MySQL 按 JSON 中的边界比较）
+func RangeContains(column string, t time.Time) clause.Expression {
+	col := clause.Column{Name: column}
+	ts := t.UTC().Format(rangeTimeLayout)
+	return dialectExpr{
+		postgres: clause.Expr{SQL: "? @> ?::timestamptz", Vars: []any{col, t}},
+		mysql: clause.Expr{
+			SQL: "(?->'$.empty' IS NULL" +
+				" AND (?->>'$.lower' IS NULL OR ?->>'$.lower' < ? OR (?->>'$.lower' = ? AND ?->>'$.bounds' LIKE '[%'))" +
+				" AND (?->>'$.upper' IS NULL OR ?->>'$.upper' > ? OR (?->>'$.upper' = ? AND ?->>'$.bounds' LIKE '%]')))",
+			Vars: []any{col, col, col, ts, col, ts, col, col, col, ts, col, ts, col},
+		},
+	}
+}
+
+// dialectExpr 按方言选择的查询条件，构建 SQL 时根据当前连接的方言确定
+type dialectExpr struct {
+	postgres clause.Expr
+	mysql    clause.Expr
+}
+
+// Build 构建当前方言的条件
+func (e dialectExpr) Build(builder clause.Builder) {
+	if stmt, ok := builder.(*gorm.Statement); ok && dialectOf(stmt.DB) == "mysql" {
+		e.mysql.Build(builder)
+		return
+	}
+	e.postgres.Build(builder)
+}
+
+// arrayExpr 构建数组比较条件
+func arrayExpr[T string | int64](column, pgOp, mysqlFunc string, values []T) clause.Expression {
+	col := clause.Column{Name: column}
+	data, _ := json.Marshal(values)
+	var literal string
+	switch vs := any(values).(type) {
+	case []string:
+		literal = formatArray(vs, quoteArrayElem)
+	case []int64:
+		literal = formatArray(vs, func(v int64) string { return strconv.FormatInt(v, 10) })
+	}
+	return dialectExpr{
+		postgres: clause.Expr{SQL: "? " + pgOp + " ?", Vars: []any{col, literal}},
+		mysql:    clause.Expr{SQL: mysqlFunc + "(?, ?)", Vars: []any{col, string(data)}},
+	}
+}
+
+// dialectValue MySQL 下将值编码为 JSON，否则使用 Value 返回的 PostgreSQL 字面量
+func dialectValue(db *gorm.DB, null bool, v any, value func() (driver.Value, error)) clause.Expr {
+	if null {
+		return clause.Expr{SQL: "NULL"}
+	}
+	if dialectOf(db) == "mysql" {
+		data, _ := json.Marshal(v)
+		return clause.Expr{SQL: "?", Vars: []any{string(data)}}
+	}
+	pv, _ := value()
+	return clause.Expr{SQL: "?", Vars: []any{pv}}
+}
+
+// dialectDataType MySQL 使用 JSON，否则使用 PostgreSQL 原生类型
+func dialectDataType(db *gorm.DB, postgres string) string {
+	if dialectOf(db) == "mysql" {
+		return "json"
+	}
+	return postgres
+}
+
+// jsonKeyPath 返回对象键的 JSON 路径，如 $."color"
+func jsonKeyPath(key string) string {
+	return `$.` + strconv.Quote(key)
+}
+
+// scanText 将驱动返回的值转换为字符串，NULL 时 ok 为 false
+func scanText(src any) (string, bool, error) {
+	switch v := src.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return v, true, nil
+	case []byte:
+		return string(v), true, nil
+	default:
+		return "", false, fmt.Errorf("cannot scan %T", src)
+	}
+}
+
+// scanArray 解析 PostgreSQL 数组字面量或 JSON 数组，NULL 时将 dest 置为 nil 并返回 nil
+func scanArray(src any, dest any) ([]*string, error) {
+	s, ok, err := scanText(src)
+	if err != nil || !ok {
+		switch d := dest.(type) {
+		case *StringArray:
+			*d = nil
+		case *Int64Array:
+			*d = nil
+		}
+		return nil, err
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		var raw []json.RawMessage
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return nil, fmt.Errorf("invalid json array: %w", err)
+		}
+		elems := make([]*string, len(raw))
+		for i, r := range raw {
+			if string(r) == "null" {
+				continue
+			}
+			var str string
+			if json.Unmarshal(r, &str) != nil {
+				str = string(r)
+			}
+			elems[i] = &str
+		}
+		return elems, nil
+	}
+	return parseArray(s)
+}
+
+// parseArray 解析一维 PostgreSQL 数组字面量，如 {a,"b c",NULL}
+func parseArray(s string) ([]*string, error) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("invalid array literal %q", s)
+	}
+	body := s[1 : len(s)-1]
+	elems := []*string{}
+	if body == "" {
+		return elems, nil
+	}
+	for i := 0; i <= len(body); {
+		elem, quoted, next, err := readArrayToken(body, i, ',')
+		if err != nil {
+			return nil, fmt.Errorf("invalid array literal %q: %w", s, err)
+		}
+		if !quoted && strings.EqualFold(elem, "NULL") {
+			elems = append(elems, nil)
+		} else {
+			elems = append(elems, &elem)
+		}
+		i = next + 1
+	}
+	return elems, nil
+}
+
+// parseHStore 解析 hstore 字面量，如 "a"=>"1", "b"=>NULL
+func parseHStore(s string) (HStore, error) {
+	h := HStore{}
+	for i := 0; i < len(s); {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i == len(s) {
+			break
+		}
+		key, _, next, err := readArrayToken(s, i, '=')
+		if err != nil || !strings.HasPrefix(s[next:], "=>") {
+			return nil, fmt.Errorf("invalid hstore %q", s)
+		}
+		value, quoted, end, err := readArrayToken(s, next+2, ',')
+		if err != nil {
+			return nil, fmt.Errorf("invalid hstore %q: %w", s, err)
+		}
+		if !quoted && strings.EqualFold(value, "NULL") {
+			value = ""
+		}
+		h[key] = value
+		i = end
+	}
+	return h, nil
+}
+
+// readArrayToken 从 i 开始读取一个带引号或不带引号的元素，返回元素、是否带引号和元素之后的位置
+func readArrayToken(s string, i int, sep byte) (string, bool, int, error) {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	if i < len(s) && s[i] == '"' {
+		var b strings.Builder
+		for i++; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+				if i < len(s) {
+					b.WriteByte(s[i])
+				}
+			case '"':
+				return b.String(), true, i + 1, nil
+			default:
+				b.WriteByte(s[i])
+			}
+		}
+		return "", false, 0, fmt.Errorf("unterminated quoted element")
+	}
+	start := i
+	for i < len(s) && s[i] != sep {
+		i++
+	}
+	return strings.TrimSpace(s[start:i]), false, i, nil
+}
+
+// formatArray 格式化为 PostgreSQL 数组字面量
+func formatArray[T any](values []T, format func(T) string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = format(v)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// quoteArrayElem 为数组或 hstore 元素加双引号并转义
+func quoteArrayElem(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// parseRangeBound 解析范围边界，空字符串和 infinity 表示无界
+func parseRangeBound(s string) (time.Time, error) {
+	switch s {
+	case "", "infinity", "-infinity":
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999Z07", "2006-01-02 15:04:05.999999Z07:00", "2006-01-02 15:04:05.999999Z07:00:00"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid range bound %q", s)
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewPgxPool 根据给定的选项创建一个 pgx 原生连接池，不经过 database/sql
+// 适用于需要 COPY、批量管道等 pgx 特性的高吞吐服务；启用追踪时通过 pgx 的 Tracer 接口记录
+// 与 GORM 追踪插件相同的 span、日志、Prometheus 指标和 SLI
+func NewPgxPool(opts *PostgreSQLOptions) (*pgxpool.Pool, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("postgresql options cannot be nil")
+	}
+	if opts.CreateDatabase {
+		if err := ensurePostgreSQLDatabase(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := pgxpool.ParseConfig(postgreSQLDSN(opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgresql dsn: %w", err)
+	}
+	if opts.MaxOpenConnections > 0 {
+		cfg.MaxConns = int32(min(opts.MaxOpenConnections, math.MaxInt32))
+	}
+	if opts.MaxConnectionLifeTime > 0 {
+		cfg.MaxConnLifetime = opts.MaxConnectionLifeTime
+	}
+	cfg.BeforeConnect = postgreSQLBeforeConnect(opts.PasswordProvider, opts.Dialer)
+
+	driver := postgreSQLDriver(opts)
+	if opts.EnableTrace {
+		cfg.ConnConfig.Tracer = &pgxTracer{tracer: newSQLTracer(
+			withDatasource(driver),
+			withDBSystem(driver),
+			WithInstanceName(opts.InstanceName),
+			WithErrorLogInterval(opts.ErrorLogInterval),
+			WithErrorReporter(opts.ErrorReporter),
+			WithSLO(opts.SLO),
+		)}
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
+	}
+	// 连接池按需建立连接，创建后立即测试连接以尽早暴露配置错误
+	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	logPgxPoolBanner(pool, opts, driver)
+	return pool, nil
+}
+
+// logPgxPoolBanner 查询服务端版本并输出启动日志
+func logPgxPoolBanner(pool *pgxpool.Pool, opts *PostgreSQLOptions, driver string) {
+	ctx, cancel := context.WithTimeout(context.Background(), bannerTimeout)
+	defer cancel()
+
+	versionSQL := "SHOW server_version"
+	if opts.Cockroach {
+		versionSQL = "SELECT version()"
+	}
+	// 直接使用底层连接查询，不经过追踪，避免启动查询计入指标
+	var version string
+	if conn, err := pool.Acquire(ctx); err == nil {
+		if res := conn.Conn().PgConn().ExecParams(ctx, versionSQL, nil, nil, nil, nil).Read(); res.Err == nil && len(res.Rows) > 0 && len(res.Rows[0]) > 0 {
+			version = string(res.Rows[0][0])
+		}
+		conn.Release()
+	}
+
+	datasourceBanner{
+		driver:   driver,
+		instance: opts.InstanceName,
+		addr:     postgreSQLHosts(opts),
+		database: opts.Database,
+		username: opts.Username,
+		version:  version,
+		tls:      opts.SSLMode != "" && opts.SSLMode != "disable",
+		maxOpen:  int(pool.Config().MaxConns),
+		maxIdle:  int(pool.Config().MinConns),
+		trace:    opts.EnableTrace,
+	}.log(ctx)
+}
+
+// pgxFinishKey 保存在 context 中的结束追踪函数
+type pgxFinishKey struct{}
+
+// pgxTracer 实现 pgx 的 QueryTracer、BatchTracer 和 CopyFromTracer 接口
+type pgxTracer struct {
+	tracer *sqlTracer
+}
+
+var (
+	_ pgx.QueryTracer    = (*pgxTracer)(nil)
+	_ pgx.BatchTracer    = (*pgxTracer)(nil)
+	_ pgx.CopyFromTracer = (*pgxTracer)(nil)
+)
+
+func (t *pgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, finish := t.tracer.start(ctx, "query", data.SQL)
+	return context.WithValue(ctx, pgxFinishKey{}, finish)
+}
+
+func (t *pgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	pgxFinish(ctx, data.Err)
+}
+
+func (t *pgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, finish := t.tracer.start(ctx, "batch", fmt.Sprintf("BATCH %d queries", data.Batch.Len()))
+	return context.WithValue(ctx, pgxFinishKey{}, finish)
+}
+
+// TraceBatchQuery 为批量中的每条语句添加 span 事件
+func (t *pgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	attrs := []attribute.KeyValue{attribute.String("db.statement", data.SQL)}
+	if data.Err != nil {
+		attrs = append(attrs, attribute.String("error", data.Err.Error()))
+	}
+	trace.SpanFromContext(ctx).AddEvent("batch.query", trace.WithAttributes(attrs...))
+}
+
+func (t *pgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	pgxFinish(ctx, data.Err)
+}
+
+func (t *pgxTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	ctx, finish := t.tracer.start(ctx, "copy", "COPY "+data.TableName.Sanitize())
+	return context.WithValue(ctx, pgxFinishKey{}, finish)
+}
+
+func (t *pgxTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	pgxFinish(ctx, data.Err)
+}
+
+// pgxFinish 结束 context 中保存的追踪
+func pgxFinish(ctx context.Context, err error) {
+	if finish, ok := ctx.Value(pgxFinishKey{}).(func(error)); ok {
+		finish(err)
+	}
+}
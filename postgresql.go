@@ -17,9 +17,20 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"math/rand/v2"
+	"net"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -27,17 +38,25 @@ import (
 
 // NewPostgreSQL 根据给定的选项创建一个新的 GORM PostgreSQL 数据库实例
 func NewPostgreSQL(opts *PostgreSQLOptions) (*gorm.DB, error) {
-	// 构建 DSN (Data Source Name)，使用 URL 格式以安全处理特殊字符
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
-		url.QueryEscape(opts.Username),
-		url.QueryEscape(opts.Password),
-		opts.Host,
-		opts.Port,
-		url.QueryEscape(opts.Database),
-		url.QueryEscape(opts.SSLMode),
-	)
+	dsn := postgreSQLDSN(opts)
 
-	return newPostgreSQLDB(dsn, opts)
+	if opts.CreateDatabase {
+		if err := ensurePostgreSQLDatabase(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := newPostgreSQLDB(dsn, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.CreateDatabase && opts.Schema != "" {
+		if err := ensurePostgreSQLSchema(db, opts.Schema); err != nil {
+			closeGormDB(db)
+			return nil, err
+		}
+	}
+	return db, nil
 }
 
 // newPostgreSQLDB 内部函数，用于创建 PostgreSQL 数据库连接
@@ -51,8 +70,24 @@ func newPostgreSQLDB(dsn string, opts *PostgreSQLOptions) (*gorm.DB, error) {
 		gormLogger = logger.Default.LogMode(opts.LogLevel)
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: gormLogger,
+	dialector := postgres.Open(dsn)
+	var selector *failoverSelector
+	if postgreSQLFailover(opts) {
+		connector, err := newPostgreSQLFailoverConnector(opts)
+		if err != nil {
+			return nil, err
+		}
+		dialector, selector = postgres.New(postgres.Config{Conn: sql.OpenDB(connector)}), connector.selector
+	} else if opts.PasswordProvider != nil || len(opts.Hosts) > 1 || opts.Dialer != nil {
+		var err error
+		if dialector, err = newPostgreSQLDialectorWithProvider(dsn, opts.PasswordProvider, opts.Dialer); err != nil {
+			return nil, err
+		}
+	}
+
+	// 热更新通过 applyLogLevel 调整级别
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: newLevelLogger(gormLogger),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -73,13 +108,224 @@ func newPostgreSQLDB(dsn string, opts *PostgreSQLOptions) (*gorm.DB, error) {
 	if opts.MaxIdleConnections > 0 {
 		sqlDB.SetMaxIdleConns(opts.MaxIdleConnections)
 	}
+	if opts.LeakDetection != nil {
+		if err := EnableLeakDetection(db, instanceOr(opts.InstanceName, postgreSQLDriver(opts)), opts.LeakDetection); err != nil {
+			return nil, fmt.Errorf("failed to enable leak detection: %w", err)
+		}
+	}
+	if selector != nil {
+		selector.attach(sqlDB, opts.MaxIdleConnections)
+		if err := db.Use(&failoverPlugin{selector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to register failover plugin: %w", err)
+		}
+	}
+
+	driver := postgreSQLDriver(opts)
+	if opts.IDGenerator != nil {
+		if err := db.Use(NewIDGeneratorPlugin(opts.IDGenerator)); err != nil {
+			return nil, fmt.Errorf("failed to register id generator plugin: %w", err)
+		}
+	}
+	if opts.QueryTimeout != nil {
+		if err := db.Use(NewQueryTimeoutPlugin(*opts.QueryTimeout)); err != nil {
+			return nil, fmt.Errorf("failed to register query timeout plugin: %w", err)
+		}
+	}
+	if opts.CircuitBreaker != nil {
+		breaker := NewCircuitBreaker(driver+"@"+postgreSQLHosts(opts), opts.CircuitBreaker)
+		if err := db.Use(&circuitBreakerPlugin{breaker: breaker}); err != nil {
+			return nil, fmt.Errorf("failed to register circuit breaker plugin: %w", err)
+		}
+	}
+	if err := db.Use(&txRetryPlugin{datasource: driver, retries: postgreSQLTxRetries(opts)}); err != nil {
+		return nil, fmt.Errorf("failed to register tx retry plugin: %w", err)
+	}
 
 	// 如果启用了追踪，则注册 GormTracePlugin（复用 MySQL 的追踪插件）
 	if opts.EnableTrace {
-		if err := db.Use(NewGormTracePlugin(true)); err != nil {
+		plugin := NewGormTracePlugin(true,
+			withDatasource(driver),
+			withDBSystem(driver),
+			WithInstanceName(opts.InstanceName),
+			WithErrorLogInterval(opts.ErrorLogInterval),
+			WithErrorReporter(opts.ErrorReporter),
+			WithSlowQueryThreshold(opts.SlowQueryThreshold),
+			WithSlowQuerySinks(opts.SlowQuerySinks...),
+			WithSLO(opts.SLO),
+			WithMaxResultRows(opts.MaxResultRows, opts.AbortOnLargeResult),
+			WithPprofLabels(opts.PprofLabels),
+			WithTraceSampleRate(opts.TraceSampleRate),
+			WithMetricsDisabled(opts.DisableMetrics),
+			WithMetricsNamespace(opts.MetricsNamespace),
+			WithMetricsBackend(opts.MetricsBackend),
+		)
+		if err := db.Use(plugin); err != nil {
 			return nil, fmt.Errorf("failed to register trace plugin: %w", err)
 		}
+		onHandleClose(sqlDB, plugin.release)
+	} else if err := (resultGuard{maxRows: opts.MaxResultRows, abort: opts.AbortOnLargeResult}).register(db); err != nil {
+		return nil, fmt.Errorf("failed to register result guard: %w", err)
+	}
+	if opts.ForceUTC {
+		if err := ForceUTC(db); err != nil {
+			return nil, fmt.Errorf("failed to register utc interceptor: %w", err)
+		}
+	}
+	if opts.PasswordProvider != nil {
+		if err := enableSQLAuthRotation(db, instanceOr(opts.InstanceName, driver), opts.PasswordProvider, sqlDB, opts.MaxIdleConnections); err != nil {
+			return nil, fmt.Errorf("failed to register auth rotation interceptor: %w", err)
+		}
+	}
+
+	versionSQL := "SHOW server_version"
+	if opts.Cockroach {
+		versionSQL = "SELECT version()"
+	}
+	if opts.WarmUpConnections > 0 {
+		if err := warmUpOnCreate(sqlDB, opts.WarmUpConnections, opts.MaxIdleConnections); err != nil {
+			return nil, err
+		}
 	}
 
+	logSQLBanner(db, datasourceBanner{
+		driver:   driver,
+		instance: opts.InstanceName,
+		addr:     postgreSQLHosts(opts),
+		database: opts.Database,
+		username: opts.Username,
+		tls:      opts.SSLMode != "" && opts.SSLMode != "disable",
+		maxOpen:  opts.MaxOpenConnections,
+		maxIdle:  opts.MaxIdleConnections,
+		trace:    opts.EnableTrace,
+	}, versionSQL)
+
 	return db, nil
 }
+
+// newPostgreSQLDialectorWithProvider 创建在每次建立新连接前从凭据提供者获取密码的 PostgreSQL Dialector
+// DSN 包含多个节点时，每次建立新连接随机选择首选节点，其余节点作为故障转移备选；provider 为 nil 时使用 DSN 中的密码
+// dialer 不为 nil 时通过其建立网络连接
+func newPostgreSQLDialectorWithProvider(dsn string, provider CredentialProvider, dialer *Dialer) (gorm.Dialector, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgresql dsn: %w", err)
+	}
+
+	sqlDB := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(postgreSQLBeforeConnect(provider, dialer)))
+	return postgres.New(postgres.Config{Conn: sqlDB}), nil
+}
+
+// postgreSQLBeforeConnect 返回建立新连接前调用的函数：多节点时随机选择首选节点，provider 不为 nil 时获取最新密码，
+// dialer 不为 nil 时通过其建立网络连接
+func postgreSQLBeforeConnect(provider CredentialProvider, dialer *Dialer) func(context.Context, *pgx.ConnConfig) error {
+	return func(ctx context.Context, cc *pgx.ConnConfig) error {
+		if dialer != nil {
+			cc.DialFunc = dialer.DialContext
+		}
+		if n := len(cc.Fallbacks); n > 0 {
+			// cc 是每次连接的副本，交换首选节点不影响其他连接
+			if i := rand.IntN(n + 1); i < n {
+				fb := *cc.Fallbacks[i]
+				fallbacks := append([]*pgconn.FallbackConfig(nil), cc.Fallbacks...)
+				fallbacks[i] = &pgconn.FallbackConfig{Host: cc.Host, Port: cc.Port, TLSConfig: cc.TLSConfig}
+				cc.Host, cc.Port, cc.TLSConfig, cc.Fallbacks = fb.Host, fb.Port, fb.TLSConfig, fallbacks
+			}
+		}
+		if provider == nil {
+			return nil
+		}
+		password, err := provider.Password(ctx)
+		if err != nil {
+			return err
+		}
+		cc.Password = password
+		return nil
+	}
+}
+
+// postgreSQLDSN 构建 DSN (Data Source Name)，使用 URL 格式以安全处理特殊字符
+func postgreSQLDSN(opts *PostgreSQLOptions) string {
+	dsn := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
+		url.QueryEscape(opts.Username),
+		url.QueryEscape(opts.Password),
+		postgreSQLHosts(opts),
+		url.QueryEscape(opts.Database),
+		url.QueryEscape(opts.SSLMode),
+	)
+	if opts.TargetSessionAttrs != "" {
+		dsn += "&target_session_attrs=" + url.QueryEscape(opts.TargetSessionAttrs)
+	}
+	if opts.DefaultQueryExecMode != "" {
+		dsn += "&default_query_exec_mode=" + url.QueryEscape(opts.DefaultQueryExecMode)
+	}
+	if opts.ConnectTimeout > 0 {
+		dsn += "&connect_timeout=" + postgreSQLConnectTimeout(opts.ConnectTimeout)
+	}
+	// 未识别的连接参数由 pgx 作为会话变量在建立连接时设置
+	for _, kv := range postgreSQLSessionVars(opts) {
+		dsn += "&" + url.QueryEscape(kv[0]) + "=" + url.QueryEscape(kv[1])
+	}
+	return dsn
+}
+
+// postgreSQLConnectTimeout 将超时转换为 connect_timeout 的秒数，不足一秒按一秒计算
+func postgreSQLConnectTimeout(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// postgreSQLDriver 返回数据源标识，用于指标、span 和启动日志
+func postgreSQLDriver(opts *PostgreSQLOptions) string {
+	if opts.Cockroach {
+		return driverCockroachDB
+	}
+	return driverPostgreSQL
+}
+
+// postgreSQLHosts 返回 DSN 中的节点列表，未设置 Hosts 时使用 Host 和 Port
+func postgreSQLHosts(opts *PostgreSQLOptions) string {
+	if len(opts.Hosts) > 0 {
+		return strings.Join(opts.Hosts, ",")
+	}
+	return net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port))
+}
+
+// cockroachSessionVars CockroachDB 模式的推荐会话变量
+var cockroachSessionVars = map[string]string{
+	"default_transaction_isolation": "serializable",    // 事务重试依赖可串行化隔离级别
+	"serial_normalization":          "unordered_rowid", // SERIAL 主键使用无序 ID，避免单调递增写入热点
+}
+
+// postgreSQLSessionVars 返回按名称排序的会话变量，显式配置的值覆盖 CockroachDB 模式的推荐值和 statement_timeout
+func postgreSQLSessionVars(opts *PostgreSQLOptions) [][2]string {
+	vars := make(map[string]string, len(opts.SessionVars)+len(cockroachSessionVars))
+	if opts.Cockroach {
+		for k, v := range cockroachSessionVars {
+			vars[k] = v
+		}
+	}
+	if opts.StatementTimeout > 0 {
+		vars["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	}
+	for k, v := range opts.SessionVars {
+		vars[k] = v
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([][2]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, [2]string{k, vars[k]})
+	}
+	return pairs
+}
+
+// postgreSQLTxRetries 返回 ExecuteTx 的最大重试次数
+func postgreSQLTxRetries(opts *PostgreSQLOptions) int {
+	if opts.TxRetries == 0 && opts.Cockroach {
+		return defaultCockroachTxRetries
+	}
+	return opts.TxRetries
+}
@@ -0,0 +1,398 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// BloomModeAuto 首次使用时检测 RedisBloom 模块，不可用时使用位图实现
+	BloomModeAuto = "auto"
+	// BloomModeModule 使用 RedisBloom 模块（BF.*）
+	BloomModeModule = "module"
+	// BloomModeBitmap 使用 Redis 位图（SETBIT/BITFIELD）实现，不依赖模块
+	BloomModeBitmap = "bitmap"
+
+	// maxBloomBits Redis 字符串的最大位数（512MB）
+	maxBloomBits = 1 << 32
+)
+
+// bloomModuleAddScript 使用 RedisBloom 添加元素，键不存在时按容量和误判率创建
+// KEYS[1] 过滤器键；ARGV[1] 误判率，ARGV[2] 容量，ARGV[3] 有效期（毫秒），ARGV[4] 是否每次写入刷新有效期，ARGV[5..] 元素
+var bloomModuleAddScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	redis.call('BF.RESERVE', KEYS[1], ARGV[1], ARGV[2])
+end
+local added = redis.call('BF.MADD', KEYS[1], unpack(ARGV, 5))
+local ttl = tonumber(ARGV[3])
+if ttl > 0 and (ARGV[4] == '1' or redis.call('PTTL', KEYS[1]) == -1) then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return added
+`)
+
+// bloomBitmapAddScript 使用位图添加元素，返回每个元素是否为新增（至少一个位原来为 0）
+// KEYS[1] 过滤器键；ARGV[1] 有效期（毫秒），ARGV[2] 是否每次写入刷新有效期，ARGV[3] 哈希函数个数 k，ARGV[4..] 每个元素的 k 个位偏移
+var bloomBitmapAddScript = redis.NewScript(`
+local k = tonumber(ARGV[3])
+local added = {}
+local idx = 4
+for i = 1, (#ARGV - 3) / k do
+	added[i] = 0
+	for j = 1, k do
+		if redis.call('SETBIT', KEYS[1], ARGV[idx], 1) == 0 then
+			added[i] = 1
+		end
+		idx = idx + 1
+	end
+end
+local ttl = tonumber(ARGV[1])
+if ttl > 0 and (ARGV[2] == '1' or redis.call('PTTL', KEYS[1]) == -1) then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return added
+`)
+
+// pfAddScript 添加元素到 HyperLogLog 并管理有效期
+// KEYS[1] HyperLogLog 键；ARGV[1] 有效期（毫秒），ARGV[2] 是否每次写入刷新有效期，ARGV[3..] 元素
+var pfAddScript = redis.NewScript(`
+local changed = redis.call('PFADD', KEYS[1], unpack(ARGV, 3))
+local ttl = tonumber(ARGV[1])
+if ttl > 0 and (ARGV[2] == '1' or redis.call('PTTL', KEYS[1]) == -1) then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return changed
+`)
+
+// BloomFilterOptions 布隆过滤器配置选项
+type BloomFilterOptions struct {
+	Capacity   int64         // 预期元素数，超过后误判率上升，默认 1000000
+	ErrorRate  float64       // 期望的误判率，取值 (0, 1)，默认 0.01
+	Mode       string        // 实现方式：auto、module、bitmap，默认 auto。同一个键的所有使用方必须使用相同的实现
+	TTL        time.Duration // 过期时间，首次写入时设置，0 表示不过期
+	SlidingTTL bool          // 每次写入都刷新过期时间，用于只在一段时间内不活跃时才过期的去重窗口
+}
+
+// BloomFilter 基于 Redis 的布隆过滤器，用于低内存的去重判断：Exists 返回 false 时元素一定未添加过，
+// 返回 true 时可能是误判。RedisBloom 模块可用时使用 BF.*，否则以位图实现，两种实现的数据不兼容
+type BloomFilter struct {
+	client redis.UniversalClient
+	key    string
+	opts   BloomFilterOptions
+	bits   uint64 // 位图实现的位数 m
+	hashes int    // 位图实现的哈希函数个数 k
+
+	mu   sync.Mutex
+	mode string // 实际使用的实现，auto 模式下检测前为空
+}
+
+// NewBloomFilter 创建布隆过滤器，key 为 Redis 中保存过滤器的键
+func NewBloomFilter(client redis.UniversalClient, key string, opts *BloomFilterOptions) (*BloomFilter, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("bloom filter key is required")
+	}
+
+	f := &BloomFilter{client: client, key: key}
+	if opts != nil {
+		f.opts = *opts
+	}
+	if f.opts.Capacity <= 0 {
+		f.opts.Capacity = 1000000
+	}
+	if f.opts.ErrorRate == 0 {
+		f.opts.ErrorRate = 0.01
+	}
+	if f.opts.ErrorRate <= 0 || f.opts.ErrorRate >= 1 {
+		return nil, fmt.Errorf("bloom filter error rate must be between 0 and 1, got %v", f.opts.ErrorRate)
+	}
+	switch f.opts.Mode {
+	case "", BloomModeAuto:
+		f.opts.Mode = BloomModeAuto
+	case BloomModeModule, BloomModeBitmap:
+		f.mode = f.opts.Mode
+	default:
+		return nil, fmt.Errorf("bloom filter mode must be auto, module or bitmap, got %q", f.opts.Mode)
+	}
+
+	// m = -n·ln(p) / (ln2)²，k = m/n·ln2
+	m := math.Ceil(-float64(f.opts.Capacity) * math.Log(f.opts.ErrorRate) / (math.Ln2 * math.Ln2))
+	if m > maxBloomBits {
+		return nil, fmt.Errorf("bloom filter requires %.0f bits, exceeding the redis string limit; reduce capacity or increase error rate", m)
+	}
+	f.bits = uint64(m)
+	f.hashes = max(int(math.Round(m/float64(f.opts.Capacity)*math.Ln2)), 1)
+	return f, nil
+}
+
+// Add 添加元素，返回每个元素是否为新增；返回 false 表示元素可能已添加过
+func (f *BloomFilter) Add(ctx context.Context, items ...string) ([]bool, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return f.withMode(func(mode string) ([]bool, error) {
+		ttl, sliding := f.opts.TTL.Milliseconds(), boolArg(f.opts.SlidingTTL)
+		if mode == BloomModeModule {
+			args := append([]any{f.opts.ErrorRate, f.opts.Capacity, ttl, sliding}, stringArgs(items)...)
+			return bloomResult(bloomModuleAddScript.Run(ctx, f.client, []string{f.key}, args...).Int64Slice())
+		}
+		args := append(make([]any, 0, 3+len(items)*f.hashes), ttl, sliding, f.hashes)
+		for _, item := range items {
+			for _, pos := range f.positions(item) {
+				args = append(args, pos)
+			}
+		}
+		return bloomResult(bloomBitmapAddScript.Run(ctx, f.client, []string{f.key}, args...).Int64Slice())
+	})
+}
+
+// Exists 判断元素是否可能已添加，返回 false 的元素一定未添加过
+func (f *BloomFilter) Exists(ctx context.Context, items ...string) ([]bool, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return f.withMode(func(mode string) ([]bool, error) {
+		if mode == BloomModeModule {
+			return f.client.BFMExists(ctx, f.key, stringArgs(items)...).Result()
+		}
+		args := make([]any, 0, len(items)*f.hashes*3)
+		for _, item := range items {
+			for _, pos := range f.positions(item) {
+				args = append(args, "GET", "u1", pos)
+			}
+		}
+		bits, err := f.client.BitField(ctx, f.key, args...).Result()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]bool, len(items))
+		for i := range items {
+			result[i] = true
+			for _, bit := range bits[i*f.hashes : (i+1)*f.hashes] {
+				if bit == 0 {
+					result[i] = false
+					break
+				}
+			}
+		}
+		return result, nil
+	})
+}
+
+// Delete 删除过滤器
+func (f *BloomFilter) Delete(ctx context.Context) error {
+	return f.client.Del(ctx, f.key).Err()
+}
+
+// Mode 返回实际使用的实现，auto 模式下首次访问 Redis 前返回 auto
+func (f *BloomFilter) Mode() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.mode == "" {
+		return BloomModeAuto
+	}
+	return f.mode
+}
+
+// withMode 以当前实现执行 fn；auto 模式下首次执行时先尝试模块，模块不可用时切换到位图实现
+func (f *BloomFilter) withMode(fn func(mode string) ([]bool, error)) ([]bool, error) {
+	f.mu.Lock()
+	mode := f.mode
+	f.mu.Unlock()
+	if mode != "" {
+		return f.wrap(fn(mode))
+	}
+
+	result, err := fn(BloomModeModule)
+	if err == nil || !isUnknownCommand(err) {
+		if err == nil {
+			f.setMode(BloomModeModule)
+		}
+		return f.wrap(result, err)
+	}
+	f.setMode(BloomModeBitmap)
+	return f.wrap(fn(BloomModeBitmap))
+}
+
+// setMode 记录检测到的实现
+func (f *BloomFilter) setMode(mode string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mode = mode
+}
+
+// wrap 为错误添加过滤器键
+func (f *BloomFilter) wrap(result []bool, err error) ([]bool, error) {
+	if err != nil {
+		return nil, fmt.Errorf("bloom filter %s: %w", f.key, err)
+	}
+	return result, nil
+}
+
+// positions 以双重哈希计算元素在位图中的 k 个位偏移：h1 + i·h2 mod m
+func (f *BloomFilter) positions(item string) []uint64 {
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(item))
+	sum := h.Sum(nil)
+	var h1, h2 uint64
+	for i := 0; i < 8; i++ {
+		h1 = h1<<8 | uint64(sum[i])
+		h2 = h2<<8 | uint64(sum[8+i])
+	}
+	h2 |= 1 // 保证步长非 0
+
+	positions := make([]uint64, f.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % f.bits
+	}
+	return positions
+}
+
+// HyperLogLogOptions HyperLogLog 配置选项
+type HyperLogLogOptions struct {
+	TTL        time.Duration // 过期时间，首次写入时设置，0 表示不过期
+	SlidingTTL bool          // 每次写入都刷新过期时间
+}
+
+// HyperLogLog 基于 Redis HyperLogLog 的基数计数器，以固定约 12KB 内存统计去重后的元素数，标准误差约 0.81%
+type HyperLogLog struct {
+	client redis.UniversalClient
+	key    string
+	opts   HyperLogLogOptions
+}
+
+// NewHyperLogLog 创建基数计数器，key 为 Redis 中保存计数器的键
+func NewHyperLogLog(client redis.UniversalClient, key string, opts *HyperLogLogOptions) (*HyperLogLog, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("hyperloglog key is required")
+	}
+	h := &HyperLogLog{client: client, key: key}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h, nil
+}
+
+// Key 返回计数器的键
+func (h *HyperLogLog) Key() string {
+	return h.key
+}
+
+// Add 添加元素，返回估算的基数是否发生变化
+func (h *HyperLogLog) Add(ctx context.Context, items ...string) (bool, error) {
+	if len(items) == 0 {
+		return false, nil
+	}
+	args := append([]any{h.opts.TTL.Milliseconds(), boolArg(h.opts.SlidingTTL)}, stringArgs(items)...)
+	changed, err := pfAddScript.Run(ctx, h.client, []string{h.key}, args...).Int64()
+	if err != nil {
+		return false, fmt.Errorf("hyperloglog %s: %w", h.key, err)
+	}
+	return changed == 1, nil
+}
+
+// Count 返回估算的基数
+func (h *HyperLogLog) Count(ctx context.Context) (int64, error) {
+	return h.client.PFCount(ctx, h.key).Result()
+}
+
+// Merge 将 others 合并到当前计数器，合并后的计数为所有计数器的并集。Redis Cluster 下各键须位于同一哈希槽
+func (h *HyperLogLog) Merge(ctx context.Context, others ...*HyperLogLog) error {
+	keys := make([]string, len(others))
+	for i, o := range others {
+		keys[i] = o.key
+	}
+	if err := h.client.PFMerge(ctx, h.key, keys...).Err(); err != nil {
+		return fmt.Errorf("hyperloglog %s: %w", h.key, err)
+	}
+	if h.opts.TTL <= 0 {
+		return nil
+	}
+	if !h.opts.SlidingTTL {
+		ttl, err := h.client.PTTL(ctx, h.key).Result()
+		if err != nil || ttl >= 0 {
+			return err
+		}
+	}
+	return h.client.PExpire(ctx, h.key, h.opts.TTL).Err()
+}
+
+// Delete 删除计数器
+func (h *HyperLogLog) Delete(ctx context.Context) error {
+	return h.client.Del(ctx, h.key).Err()
+}
+
+// CountUnion 返回多个计数器并集的估算基数，不修改任何计数器。Redis Cluster 下各键须位于同一哈希槽
+func CountUnion(ctx context.Context, counters ...*HyperLogLog) (int64, error) {
+	if len(counters) == 0 {
+		return 0, nil
+	}
+	keys := make([]string, len(counters))
+	for i, c := range counters {
+		keys[i] = c.key
+	}
+	return counters[0].client.PFCount(ctx, keys...).Result()
+}
+
+// isUnknownCommand 判断错误是否为服务端不支持的命令（如未加载 RedisBloom 模块）
+func isUnknownCommand(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unknown command") || strings.Contains(msg, "unknown redis command")
+}
+
+// bloomResult 将 0/1 结果转换为布尔值
+func bloomResult(values []int64, err error) ([]bool, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make([]bool, len(values))
+	for i, v := range values {
+		result[i] = v == 1
+	}
+	return result, nil
+}
+
+// stringArgs 将字符串切片转换为命令参数
+func stringArgs(items []string) []any {
+	args := make([]any, len(items))
+	for i, item := range items {
+		args[i] = item
+	}
+	return args
+}
+
+// boolArg 将布尔值转换为脚本参数
+func boolArg(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,296 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+)
+
+const (
+	// ProfileDev 本地开发：详细日志、小连接池
+	ProfileDev = "dev"
+	// ProfileStaging 预发环境：告警级别日志、中等连接池
+	ProfileStaging = "staging"
+	// ProfileProd 生产环境：仅错误日志、完整连接池
+	ProfileProd = "prod"
+)
+
+var (
+	profilesMu sync.RWMutex
+	// profiles 配置预设，键为配置项的 yaml 名称，不适用于某类配置的项会被忽略
+	profiles = map[string]map[string]string{
+		ProfileDev: {
+			"log_level":         "info",
+			"enable_trace":      "true",
+			"trace_sample_rate": "1",
+			"max_connections":   "10",
+			"pool_size":         "5",
+			"min_idle_conns":    "1",
+			// 开发环境尽早暴露缺少过滤条件的查询
+			"max_result_rows":       "1000",
+			"abort_on_large_result": "true",
+		},
+		ProfileStaging: {
+			"log_level":             "warn",
+			"enable_trace":          "true",
+			"trace_sample_rate":     "0.5",
+			"max_connections":       "50",
+			"pool_size":             "10",
+			"min_idle_conns":        "2",
+			"max_result_rows":       "10000",
+			"abort_on_large_result": "true",
+		},
+		ProfileProd: {
+			"log_level":    "error",
+			"enable_trace": "true",
+			// 生产环境按比例采样入口请求，上游已有 span 的链路不受影响
+			"trace_sample_rate": "0.1",
+			"max_connections":   "100",
+			"pool_size":         "20",
+			"min_idle_conns":    "5",
+			// 生产环境仅告警，避免误伤合法的大查询
+			"max_result_rows": "10000",
+		},
+	}
+)
+
+// RegisterProfile 注册或覆盖配置预设，values 的键为配置项的 yaml 名称
+func RegisterProfile(name string, values map[string]string) {
+	copied := make(map[string]string, len(values))
+	for k, v := range values {
+		copied[k] = v
+	}
+
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[name] = copied
+}
+
+// lookupProfile 返回指定名称的配置预设
+func lookupProfile(name string) (map[string]string, error) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	values, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q, must be one of: %s", name, strings.Join(names, ", "))
+	}
+	return values, nil
+}
+
+// profileKeys 显式设置的配置项（yaml 键名），写时复制，可在配置副本间共享
+type profileKeys map[string]struct{}
+
+// with 返回追加了 keys 的新集合
+func (k profileKeys) with(keys ...string) profileKeys {
+	merged := make(profileKeys, len(k)+len(keys))
+	for key := range k {
+		merged[key] = struct{}{}
+	}
+	for _, key := range keys {
+		merged[key] = struct{}{}
+	}
+	return merged
+}
+
+// yamlMappingKeys 返回 YAML 映射中出现的键名
+func yamlMappingKeys(unmarshal func(interface{}) error) ([]string, error) {
+	var raw map[string]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// envKeySet 判断配置项是否通过环境变量设置，兼容 framework-config 的 PREFIX_ 前缀
+func envKeySet(environ []string, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		if key == name || strings.HasSuffix(key, "_"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyProfile 将预设应用到配置结构体，只填充未显式设置的配置项
+// 配置文件中出现的键、已设置的环境变量和 MarkExplicit 标记的键视为显式设置；
+// 配置未经 YAML 解析且未调用 MarkExplicit 时无法区分，退化为仅覆盖为空或仍为 default 标签默认值的字段
+func applyProfile(cfg interface{}, name string, explicit profileKeys) error {
+	if name == "" {
+		return nil
+	}
+	values, err := lookupProfile(name)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	defaults := reflect.New(v.Type())
+	pkgConfig.ApplyDefaults(defaults.Interface())
+	defaults = defaults.Elem()
+	environ := os.Environ()
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if _, set := explicit[key]; set || envKeySet(environ, t.Field(i).Tag.Get("env")) {
+			continue
+		}
+		field := v.Field(i)
+		if explicit == nil && !pkgConfig.IsEmpty(field) && !reflect.DeepEqual(field.Interface(), defaults.Field(i).Interface()) {
+			continue
+		}
+		if err := pkgConfig.SetFieldByPath(cfg, key, value); err != nil {
+			return fmt.Errorf("profile %s: failed to set %s: %w", name, key, err)
+		}
+	}
+	return nil
+}
+
+// ApplyProfile 将 profile 字段指定的预设应用到配置
+func (c *MySQLConfig) ApplyProfile() error {
+	return applyProfile(c, c.Profile, c.explicit)
+}
+
+// MarkExplicit 标记显式设置的配置项（yaml 键名），应用预设时保留其值
+// 从配置文件解析时自动记录出现的键，在代码中构造或通过命令行参数设置的配置需要调用该方法
+func (c *MySQLConfig) MarkExplicit(keys ...string) {
+	c.explicit = c.explicit.with(keys...)
+}
+
+// UnmarshalYAML 解析配置并记录配置文件中出现的键
+func (c *MySQLConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain MySQLConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	keys, err := yamlMappingKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	c.MarkExplicit(keys...)
+	return nil
+}
+
+// ApplyProfile 将 profile 字段指定的预设应用到配置
+func (c *PostgreSQLConfig) ApplyProfile() error {
+	return applyProfile(c, c.Profile, c.explicit)
+}
+
+// MarkExplicit 标记显式设置的配置项（yaml 键名），应用预设时保留其值
+// 从配置文件解析时自动记录出现的键，在代码中构造或通过命令行参数设置的配置需要调用该方法
+func (c *PostgreSQLConfig) MarkExplicit(keys ...string) {
+	c.explicit = c.explicit.with(keys...)
+}
+
+// UnmarshalYAML 解析配置并记录配置文件中出现的键
+func (c *PostgreSQLConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain PostgreSQLConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	keys, err := yamlMappingKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	c.MarkExplicit(keys...)
+	return nil
+}
+
+// ApplyProfile 将 profile 字段指定的预设应用到配置
+func (c *RedisConfig) ApplyProfile() error {
+	return applyProfile(c, c.Profile, c.explicit)
+}
+
+// MarkExplicit 标记显式设置的配置项（yaml 键名），应用预设时保留其值
+// 从配置文件解析时自动记录出现的键，在代码中构造或通过命令行参数设置的配置需要调用该方法
+func (c *RedisConfig) MarkExplicit(keys ...string) {
+	c.explicit = c.explicit.with(keys...)
+}
+
+// UnmarshalYAML 解析配置并记录配置文件中出现的键
+func (c *RedisConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RedisConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	keys, err := yamlMappingKeys(unmarshal)
+	if err != nil {
+		return err
+	}
+	c.MarkExplicit(keys...)
+	return nil
+}
+
+// profiledMySQLConfig 返回应用预设后的配置副本，管理器保存和比较的都是应用预设后的配置
+func profiledMySQLConfig(cfg *MySQLConfig) (*MySQLConfig, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("mysql config cannot be nil")
+	}
+	c := cloneMySQLConfig(cfg)
+	if err := c.ApplyProfile(); err != nil {
+		return nil, fmt.Errorf("mysql: %w", err)
+	}
+	return c, nil
+}
+
+// profiledPostgreSQLConfig 返回应用预设后的配置副本
+func profiledPostgreSQLConfig(cfg *PostgreSQLConfig) (*PostgreSQLConfig, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("postgresql config cannot be nil")
+	}
+	c := clonePostgreSQLConfig(cfg)
+	if err := c.ApplyProfile(); err != nil {
+		return nil, fmt.Errorf("postgresql: %w", err)
+	}
+	return c, nil
+}
+
+// profiledRedisConfig 返回应用预设后的配置副本
+func profiledRedisConfig(cfg *RedisConfig) (*RedisConfig, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("redis config cannot be nil")
+	}
+	c := cloneRedisConfig(cfg)
+	if err := c.ApplyProfile(); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return c, nil
+}
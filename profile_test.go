@@ -0,0 +1,119 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"testing"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestApplyProfilePrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string            // 配置文件内容，为空表示在代码中构造
+		env      map[string]string // 已设置的环境变量
+		set      func(c *MySQLConfig)
+		explicit []string
+		want     MySQLConfig
+	}{
+		{
+			name: "yaml keys are kept",
+			yaml: "profile: dev\nlog_level: error\nenable_trace: false\n",
+			want: MySQLConfig{LogLevel: "error", EnableTrace: false, MaxConnections: 10},
+		},
+		{
+			name: "yaml value equal to default is kept",
+			yaml: "profile: dev\nmax_connections: 100\n",
+			want: MySQLConfig{LogLevel: "info", EnableTrace: true, MaxConnections: 100},
+		},
+		{
+			name: "env keys are kept",
+			yaml: "profile: prod\n",
+			env:  map[string]string{"APP_MYSQL_LOG_LEVEL": "warn"},
+			set:  func(c *MySQLConfig) { c.LogLevel = "warn" },
+			want: MySQLConfig{LogLevel: "warn", EnableTrace: true, MaxConnections: 100},
+		},
+		{
+			name:     "marked keys are kept",
+			set:      func(c *MySQLConfig) { c.Profile, c.LogLevel, c.MaxConnections = "dev", "silent", 100 },
+			explicit: []string{"max_connections", "log_level"},
+			want:     MySQLConfig{LogLevel: "silent", EnableTrace: true, MaxConnections: 100},
+		},
+		{
+			name: "without explicit keys only empty or default values are overridden",
+			set:  func(c *MySQLConfig) { c.Profile, c.LogLevel, c.MaxConnections = "dev", "error", 100 },
+			want: MySQLConfig{LogLevel: "error", EnableTrace: true, MaxConnections: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			var cfg MySQLConfig
+			if tt.yaml != "" {
+				if err := yaml.Unmarshal([]byte(tt.yaml), &cfg); err != nil {
+					t.Fatalf("unmarshal: %v", err)
+				}
+			}
+			pkgConfig.ApplyDefaults(&cfg)
+			if tt.set != nil {
+				tt.set(&cfg)
+			}
+			if len(tt.explicit) > 0 {
+				cfg.MarkExplicit(tt.explicit...)
+			}
+			if err := cfg.ApplyProfile(); err != nil {
+				t.Fatalf("ApplyProfile: %v", err)
+			}
+			if cfg.LogLevel != tt.want.LogLevel || cfg.EnableTrace != tt.want.EnableTrace || cfg.MaxConnections != tt.want.MaxConnections {
+				t.Errorf("got log_level=%q enable_trace=%v max_connections=%d, want log_level=%q enable_trace=%v max_connections=%d",
+					cfg.LogLevel, cfg.EnableTrace, cfg.MaxConnections,
+					tt.want.LogLevel, tt.want.EnableTrace, tt.want.MaxConnections)
+			}
+		})
+	}
+}
+
+func TestApplyProfileUnknown(t *testing.T) {
+	cfg := MySQLConfig{Profile: "qa"}
+	if err := cfg.ApplyProfile(); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+}
+
+func TestEnvKeySet(t *testing.T) {
+	environ := []string{"MYSQL_HOST=db", "APP_MYSQL_PORT=3307", "XMYSQL_USER=root"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"MYSQL_HOST", true},
+		{"MYSQL_PORT", true},
+		{"MYSQL_USER", false},
+		{"MYSQL_DATABASE", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := envKeySet(environ, tt.name); got != tt.want {
+			t.Errorf("envKeySet(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
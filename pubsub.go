@@ -0,0 +1,318 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// MessageHandler 处理订阅到的消息，返回错误时计入失败指标
+type MessageHandler func(ctx context.Context, msg *redis.Message) error
+
+// PubSubOptions 订阅配置选项
+type PubSubOptions struct {
+	Workers             int           // 处理消息的 worker 数，默认 4
+	QueueSize           int           // 待处理消息队列长度，默认 1024，队列满时阻塞接收
+	MinReconnectBackoff time.Duration // 连接断开后首次重新订阅的等待时间，默认 100ms
+	MaxReconnectBackoff time.Duration // 重新订阅的最大等待时间，默认 30s
+}
+
+// PubSub Redis 订阅封装：分发消息到 worker 池处理，连接断开后自动重新订阅
+type PubSub struct {
+	client redis.UniversalClient
+	opts   PubSubOptions
+
+	mu       sync.Mutex
+	channels map[string]MessageHandler
+	patterns map[string]MessageHandler
+	ps       *redis.PubSub
+
+	queue  chan *redis.Message
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	start  sync.Once
+}
+
+// NewPubSub 创建订阅封装，需调用 Subscribe/PSubscribe 注册处理函数
+func NewPubSub(client redis.UniversalClient, opts *PubSubOptions) (*PubSub, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+
+	p := &PubSub{
+		client:   client,
+		channels: make(map[string]MessageHandler),
+		patterns: make(map[string]MessageHandler),
+	}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.Workers <= 0 {
+		p.opts.Workers = 4
+	}
+	if p.opts.QueueSize <= 0 {
+		p.opts.QueueSize = 1024
+	}
+	if p.opts.MinReconnectBackoff <= 0 {
+		p.opts.MinReconnectBackoff = 100 * time.Millisecond
+	}
+	if p.opts.MaxReconnectBackoff <= 0 {
+		p.opts.MaxReconnectBackoff = 30 * time.Second
+	}
+	p.queue = make(chan *redis.Message, p.opts.QueueSize)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return p, nil
+}
+
+// Subscribe 订阅频道并注册处理函数，首次订阅时启动接收循环和 worker 池
+func (p *PubSub) Subscribe(ctx context.Context, channel string, handler MessageHandler) error {
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.pubsub().Subscribe(ctx, channel); err != nil {
+		return fmt.Errorf("failed to subscribe channel %s: %w", channel, err)
+	}
+	p.channels[channel] = handler
+	p.run()
+	return nil
+}
+
+// PSubscribe 按模式订阅并注册处理函数
+func (p *PubSub) PSubscribe(ctx context.Context, pattern string, handler MessageHandler) error {
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.pubsub().PSubscribe(ctx, pattern); err != nil {
+		return fmt.Errorf("failed to subscribe pattern %s: %w", pattern, err)
+	}
+	p.patterns[pattern] = handler
+	p.run()
+	return nil
+}
+
+// Unsubscribe 取消订阅频道
+func (p *PubSub) Unsubscribe(ctx context.Context, channels ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ch := range channels {
+		delete(p.channels, ch)
+	}
+	if p.ps == nil {
+		return nil
+	}
+	return p.ps.Unsubscribe(ctx, channels...)
+}
+
+// PUnsubscribe 取消模式订阅
+func (p *PubSub) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pattern := range patterns {
+		delete(p.patterns, pattern)
+	}
+	if p.ps == nil {
+		return nil
+	}
+	return p.ps.PUnsubscribe(ctx, patterns...)
+}
+
+// Close 停止接收，等待已接收的消息处理完成后返回
+func (p *PubSub) Close() error {
+	p.cancel()
+
+	p.mu.Lock()
+	var err error
+	if p.ps != nil {
+		err = p.ps.Close()
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return err
+}
+
+// pubsub 返回当前的订阅连接，不存在时创建（调用方需持有锁）
+func (p *PubSub) pubsub() *redis.PubSub {
+	if p.ps == nil {
+		p.ps = p.client.Subscribe(p.ctx)
+	}
+	return p.ps
+}
+
+// run 启动接收循环和 worker 池（调用方需持有锁）
+func (p *PubSub) run() {
+	p.start.Do(func() {
+		workers := &sync.WaitGroup{}
+		for i := 0; i < p.opts.Workers; i++ {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				p.work()
+			}()
+		}
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.receive()
+			// 接收结束后关闭队列，worker 处理完剩余消息后退出
+			close(p.queue)
+			workers.Wait()
+		}()
+	})
+}
+
+// receive 接收消息并放入队列，连接出错时按退避策略重新订阅
+func (p *PubSub) receive() {
+	backoff := p.opts.MinReconnectBackoff
+	for {
+		p.mu.Lock()
+		ps := p.ps
+		p.mu.Unlock()
+
+		msg, err := ps.Receive(p.ctx)
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warn("Redis pubsub receive failed, resubscribing",
+				zap.Error(err), zap.Duration("backoff", backoff))
+			if metrics.IsEnabled() {
+				RedisPubSubReconnectTotal.Inc()
+			}
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, p.opts.MaxReconnectBackoff)
+			p.resubscribe()
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Message:
+			backoff = p.opts.MinReconnectBackoff
+			select {
+			case p.queue <- m:
+			case <-p.ctx.Done():
+				return
+			}
+		case *redis.Subscription, *redis.Pong:
+			backoff = p.opts.MinReconnectBackoff
+		}
+	}
+}
+
+// resubscribe 关闭旧的订阅连接，使用新连接重新订阅所有频道和模式
+func (p *PubSub) resubscribe() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ps != nil {
+		_ = p.ps.Close()
+	}
+	p.ps = p.client.Subscribe(p.ctx)
+
+	channels := make([]string, 0, len(p.channels))
+	for ch := range p.channels {
+		channels = append(channels, ch)
+	}
+	patterns := make([]string, 0, len(p.patterns))
+	for pattern := range p.patterns {
+		patterns = append(patterns, pattern)
+	}
+	if len(channels) > 0 {
+		if err := p.ps.Subscribe(p.ctx, channels...); err != nil {
+			log.Warn("Redis pubsub resubscribe failed", zap.Strings("channels", channels), zap.Error(err))
+		}
+	}
+	if len(patterns) > 0 {
+		if err := p.ps.PSubscribe(p.ctx, patterns...); err != nil {
+			log.Warn("Redis pubsub resubscribe failed", zap.Strings("patterns", patterns), zap.Error(err))
+		}
+	}
+}
+
+// work 从队列中取出消息并调用处理函数
+func (p *PubSub) work() {
+	for msg := range p.queue {
+		subscription, handler := p.handlerFor(msg)
+		if handler == nil {
+			recordPubSubMessage(subscription, "dropped")
+			continue
+		}
+		if err := p.handle(handler, msg); err != nil {
+			log.Error("Redis pubsub handler failed",
+				zap.String("channel", msg.Channel), zap.String("subscription", subscription), zap.Error(err))
+			recordPubSubMessage(subscription, "failed")
+			continue
+		}
+		recordPubSubMessage(subscription, "delivered")
+	}
+}
+
+// handle 调用处理函数，捕获 panic 并转换为错误
+func (p *PubSub) handle(handler MessageHandler, msg *redis.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	// 使用独立的 context，Close 时允许处理中的消息完成
+	return handler(context.WithoutCancel(p.ctx), msg)
+}
+
+// handlerFor 查找消息对应的订阅和处理函数
+func (p *PubSub) handlerFor(msg *redis.Message) (string, MessageHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if msg.Pattern != "" {
+		return msg.Pattern, p.patterns[msg.Pattern]
+	}
+	return msg.Channel, p.channels[msg.Channel]
+}
+
+// recordPubSubMessage 记录消息处理指标
+func recordPubSubMessage(subscription, status string) {
+	if metrics.IsEnabled() {
+		RedisPubSubMessageTotal.WithLabelValues(subscription, status).Inc()
+	}
+}
+
+// PubSub 为管理器中指定名称的 Redis 客户端创建订阅封装
+func (m *Manager) PubSub(name string, opts *PubSubOptions) (*PubSub, error) {
+	client, err := m.Redis(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewPubSub(client, opts)
+}
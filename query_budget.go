@@ -0,0 +1,179 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxBudgetStatements 每个预算最多按语句统计的不同 SQL 数，超出后只计入总数
+const maxBudgetStatements = 200
+
+// ErrQueryBudgetExceeded 请求的语句数或累计耗时超出预算，仅在 QueryBudgetOptions.Enforce 时返回
+var ErrQueryBudgetExceeded = errors.New("query budget exceeded")
+
+var queryBudgetKey = NewContextKey[*QueryBudget]("query_budget")
+
+// QueryBudgetOptions 请求级查询预算配置选项
+type QueryBudgetOptions struct {
+	Name        string        // 请求标识（如路由），用于日志字段
+	MaxQueries  int           // 请求内允许执行的语句数，0 表示不限制
+	MaxDuration time.Duration // 请求内语句的累计耗时上限，0 表示不限制
+	Enforce     bool          // 超出后以 ErrQueryBudgetExceeded 拒绝后续语句，默认只记录日志和指标
+}
+
+// QueryBudgetStats 预算的使用情况
+type QueryBudgetStats struct {
+	Queries      int           `json:"queries"`       // 已执行的语句数
+	Elapsed      time.Duration `json:"elapsed"`       // 语句的累计耗时
+	TopStatement string        `json:"top_statement"` // 执行次数最多的语句（参数化 SQL），通常是 N+1 查询的来源
+	TopCount     int           `json:"top_count"`     // TopStatement 的执行次数
+	Exceeded     bool          `json:"exceeded"`      // 是否已超出预算
+}
+
+// QueryBudget 请求级查询预算：由追踪插件统计 context 上执行的语句数和累计耗时，超出时记录一次告警日志，
+// 包含执行次数最多的语句，用于在生产环境发现 N+1 查询。需要连接启用追踪插件（EnableTrace）
+type QueryBudget struct {
+	opts QueryBudgetOptions
+
+	mu         sync.Mutex
+	queries    int
+	elapsed    time.Duration
+	statements map[string]int
+	exceeded   bool
+}
+
+// WithQueryBudget 返回携带查询预算的 context，通常在请求入口调用，请求结束时可通过 Stats 记录使用情况：
+//
+//	ctx, budget := db.WithQueryBudget(r.Context(), &db.QueryBudgetOptions{Name: "GET /orders", MaxQueries: 50})
+//	defer func() { log.Info("db usage", zap.Int("queries", budget.Stats().Queries)) }()
+func WithQueryBudget(ctx context.Context, opts *QueryBudgetOptions) (context.Context, *QueryBudget) {
+	b := &QueryBudget{statements: make(map[string]int)}
+	if opts != nil {
+		b.opts = *opts
+	}
+	return queryBudgetKey.WithValue(ctx, b), b
+}
+
+// QueryBudgetFromContext 返回 context 上的查询预算
+func QueryBudgetFromContext(ctx context.Context) (*QueryBudget, bool) {
+	return queryBudgetKey.From(ctx)
+}
+
+// Stats 返回预算的使用情况
+func (b *QueryBudget) Stats() QueryBudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := QueryBudgetStats{Queries: b.queries, Elapsed: b.elapsed, Exceeded: b.exceeded}
+	stats.TopStatement, stats.TopCount = b.topStatement()
+	return stats
+}
+
+// check 在启用 Enforce 且已超出预算时返回错误
+func (b *QueryBudget) check() error {
+	if !b.opts.Enforce {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.exceeded {
+		return nil
+	}
+	return fmt.Errorf("%w: %d queries, %s elapsed", ErrQueryBudgetExceeded, b.queries, b.elapsed)
+}
+
+// record 记录一条语句，首次超出预算时记录告警日志和指标
+func (b *QueryBudget) record(ctx context.Context, statement string, duration time.Duration) {
+	b.mu.Lock()
+	b.queries++
+	b.elapsed += duration
+	if _, ok := b.statements[statement]; ok || len(b.statements) < maxBudgetStatements {
+		b.statements[statement]++
+	}
+	if b.exceeded || !b.over() {
+		b.mu.Unlock()
+		return
+	}
+	b.exceeded = true
+	queries, elapsed := b.queries, b.elapsed
+	top, count := b.topStatement()
+	b.mu.Unlock()
+
+	log.FromContext(ctx).Warn("Query budget exceeded",
+		zap.String("name", b.opts.Name),
+		zap.Int("queries", queries),
+		zap.Int("max_queries", b.opts.MaxQueries),
+		zap.Duration("elapsed", elapsed),
+		zap.Duration("max_duration", b.opts.MaxDuration),
+		zap.String("top_statement", top),
+		zap.Int("top_count", count),
+		zap.Bool("enforce", b.opts.Enforce),
+	)
+	if metrics.IsEnabled() {
+		QueryBudgetExceededTotal.WithLabelValues(strconv.FormatBool(b.opts.Enforce)).Inc()
+	}
+}
+
+// over 返回是否超出预算，调用方持有锁
+func (b *QueryBudget) over() bool {
+	return (b.opts.MaxQueries > 0 && b.queries > b.opts.MaxQueries) ||
+		(b.opts.MaxDuration > 0 && b.elapsed > b.opts.MaxDuration)
+}
+
+// topStatement 返回执行次数最多的语句，调用方持有锁
+func (b *QueryBudget) topStatement() (string, int) {
+	var top string
+	var count int
+	for stmt, n := range b.statements {
+		if n > count || (n == count && stmt < top) {
+			top, count = stmt, n
+		}
+	}
+	return top, count
+}
+
+// checkQueryBudget 语句执行前检查 context 上的预算
+func checkQueryBudget(db *gorm.DB) error {
+	if db.Statement == nil {
+		return nil
+	}
+	if b, ok := queryBudgetKey.From(db.Statement.Context); ok {
+		return b.check()
+	}
+	return nil
+}
+
+// recordQueryBudget 语句执行后计入 context 上的预算
+func recordQueryBudget(db *gorm.DB, duration time.Duration) {
+	if db.Statement == nil {
+		return
+	}
+	if b, ok := queryBudgetKey.From(db.Statement.Context); ok {
+		b.record(db.Statement.Context, db.Statement.SQL.String(), duration)
+	}
+}
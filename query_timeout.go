@@ -0,0 +1,137 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const callBackQueryTimeoutName = "core:query_timeout"
+
+// QueryTimeoutOptions 语句超时配置，未设置的操作类型使用 Default，均为 0 时不限制
+type QueryTimeoutOptions struct {
+	Default time.Duration // 默认超时
+	Query   time.Duration // 查询（Find、First 等）的超时
+	Create  time.Duration // 新增的超时
+	Update  time.Duration // 更新的超时
+	Delete  time.Duration // 删除的超时
+	Row     time.Duration // Row、Rows 和 Raw(...).Scan 的超时，从发出查询开始计算，包含读取结果的时间
+	Raw     time.Duration // Exec 原生语句的超时
+}
+
+// queryTimeoutOptions 由配置中的默认超时生成语句超时配置，0 时返回 nil
+func queryTimeoutOptions(d time.Duration) *QueryTimeoutOptions {
+	if d <= 0 {
+		return nil
+	}
+	return &QueryTimeoutOptions{Default: d}
+}
+
+// queryTimeoutCall 设置了超时的语句
+type queryTimeoutCall struct {
+	stmt   *gorm.Statement
+	cancel context.CancelFunc
+}
+
+// queryTimeoutPlugin 为每条语句的 context 设置超时，context 已有更早的截止时间时保持不变
+type queryTimeoutPlugin struct {
+	opts QueryTimeoutOptions
+}
+
+// NewQueryTimeoutPlugin 创建语句超时插件，通过 db.Use 注册
+func NewQueryTimeoutPlugin(opts QueryTimeoutOptions) gorm.Plugin {
+	return &queryTimeoutPlugin{opts: opts}
+}
+
+// Name 返回插件名称
+func (p *queryTimeoutPlugin) Name() string {
+	return "QueryTimeoutPlugin"
+}
+
+// Initialize 注册回调，超时覆盖整个回调链（包括模型钩子、关联保存和预加载）
+func (p *queryTimeoutPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if t := p.timeout(p.opts.Query); t > 0 {
+		_ = cb.Query().Before("*").Register(callBackQueryTimeoutName+":before", p.before(t, true))
+		_ = cb.Query().After("*").Register(callBackQueryTimeoutName+":after", p.after)
+	}
+	if t := p.timeout(p.opts.Create); t > 0 {
+		_ = cb.Create().Before("*").Register(callBackQueryTimeoutName+":before", p.before(t, true))
+		_ = cb.Create().After("*").Register(callBackQueryTimeoutName+":after", p.after)
+	}
+	if t := p.timeout(p.opts.Update); t > 0 {
+		_ = cb.Update().Before("*").Register(callBackQueryTimeoutName+":before", p.before(t, true))
+		_ = cb.Update().After("*").Register(callBackQueryTimeoutName+":after", p.after)
+	}
+	if t := p.timeout(p.opts.Delete); t > 0 {
+		_ = cb.Delete().Before("*").Register(callBackQueryTimeoutName+":before", p.before(t, true))
+		_ = cb.Delete().After("*").Register(callBackQueryTimeoutName+":after", p.after)
+	}
+	if t := p.timeout(p.opts.Raw); t > 0 {
+		_ = cb.Raw().Before("*").Register(callBackQueryTimeoutName+":before", p.before(t, true))
+		_ = cb.Raw().After("*").Register(callBackQueryTimeoutName+":after", p.after)
+	}
+	// Row 返回的 *sql.Rows 在回调结束后仍在读取，不能在回调结束时取消，context 到期后自动释放
+	if t := p.timeout(p.opts.Row); t > 0 {
+		_ = cb.Row().Before("*").Register(callBackQueryTimeoutName+":before", p.before(t, false))
+	}
+	return nil
+}
+
+// timeout 返回操作类型的超时，未设置时使用默认值
+func (p *queryTimeoutPlugin) timeout(t time.Duration) time.Duration {
+	if t > 0 {
+		return t
+	}
+	return p.opts.Default
+}
+
+// before 返回为语句设置超时的回调
+func (p *queryTimeoutPlugin) before(timeout time.Duration, release bool) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+			return
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		db.Statement.Context = ctx
+		if release {
+			db.Statement.Settings.Store(callBackQueryTimeoutName, &queryTimeoutCall{stmt: db.Statement, cancel: cancel})
+		} else {
+			time.AfterFunc(timeout, cancel)
+		}
+	}
+}
+
+// after 语句完成后释放 context
+func (p *queryTimeoutPlugin) after(db *gorm.DB) {
+	v, ok := db.Statement.Settings.Load(callBackQueryTimeoutName)
+	if !ok {
+		return
+	}
+	// 关联保存等嵌套语句会复制 Settings，只由设置超时的语句释放
+	if call := v.(*queryTimeoutCall); call.stmt == db.Statement {
+		db.Statement.Settings.Delete(callBackQueryTimeoutName)
+		call.cancel()
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// readYourWritesSession 请求内的写入时间，由 WithReadYourWrites 放入 context
+type readYourWritesSession struct {
+	mu      sync.Mutex
+	written time.Time
+}
+
+var readYourWritesKey = NewContextKey[*readYourWritesSession]("read_your_writes")
+
+// WithReadYourWrites 返回开启读己之写的 context：在该 context 上写入后，
+// 后续读取在 ReadYourWritesOptions.Window 内路由到主库。通常在请求入口调用一次
+func WithReadYourWrites(ctx context.Context) context.Context {
+	if _, ok := readYourWritesKey.From(ctx); ok {
+		return ctx
+	}
+	return readYourWritesKey.WithValue(ctx, &readYourWritesSession{})
+}
+
+// MarkWritten 记录 ctx 所在请求发生了写入，用于未经过 GORM 的写操作（如 sqlx、存储过程）
+func MarkWritten(ctx context.Context) {
+	if s, ok := readYourWritesKey.From(ctx); ok {
+		s.mu.Lock()
+		s.written = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+// ReadYourWritesOptions 读己之写插件配置选项
+type ReadYourWritesOptions struct {
+	Window time.Duration                            // 写入后读取路由到主库的时长，应大于副本的典型延迟，默认 5s
+	Redis  redis.UniversalClient                    // 设置后按用户记录写入时间，使同一用户的后续请求（可能在其他实例上）也读主库
+	Prefix string                                   // Redis 键前缀，默认 ryw:
+	Key    func(ctx context.Context) (string, bool) // 从 context 中获取用户键，默认使用 ActorKey
+}
+
+// ReadYourWritesPlugin 读己之写插件，配合 dbresolver 使用：
+// 写入（新增、更新、删除及非 SELECT 的原生语句）后，同一请求或同一用户在窗口内的读取强制使用主库，
+// 避免写入后立即读副本读到旧数据，其余读取仍分发到副本。
+// 设置 Redis 时每次读取会额外查询一次 Redis，Redis 不可用时读取按原规则路由
+type ReadYourWritesPlugin struct {
+	opts ReadYourWritesOptions
+}
+
+// NewReadYourWritesPlugin 创建读己之写插件，通过 db.Use 注册
+func NewReadYourWritesPlugin(opts *ReadYourWritesOptions) *ReadYourWritesPlugin {
+	p := &ReadYourWritesPlugin{}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.Window <= 0 {
+		p.opts.Window = 5 * time.Second
+	}
+	if p.opts.Prefix == "" {
+		p.opts.Prefix = "ryw:"
+	}
+	if p.opts.Key == nil {
+		p.opts.Key = ActorKey.From
+	}
+	return p
+}
+
+// Name 返回读己之写插件的名称
+func (p *ReadYourWritesPlugin) Name() string {
+	return "ReadYourWritesPlugin"
+}
+
+// Initialize 注册回调
+func (p *ReadYourWritesPlugin) Initialize(db *gorm.DB) error {
+	_ = db.Callback().Create().After("gorm:create").Register("read_your_writes:mark", p.mark)
+	_ = db.Callback().Update().After("gorm:update").Register("read_your_writes:mark", p.mark)
+	_ = db.Callback().Delete().After("gorm:delete").Register("read_your_writes:mark", p.mark)
+	_ = db.Callback().Raw().After("gorm:raw").Register("read_your_writes:mark", p.markRaw)
+
+	// 在所有回调之前选择主库，使 dbresolver 之后的回调（追踪、熔断等）看到的都是最终的连接池
+	_ = db.Callback().Query().Before("*").Register("read_your_writes:pin", p.pin)
+	_ = db.Callback().Row().Before("*").Register("read_your_writes:pin", p.pin)
+	return nil
+}
+
+// mark 写入成功后记录写入时间
+func (p *ReadYourWritesPlugin) mark(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	ctx := db.Statement.Context
+	MarkWritten(ctx)
+
+	if p.opts.Redis == nil {
+		return
+	}
+	key, ok := p.opts.Key(ctx)
+	if !ok || key == "" {
+		return
+	}
+	if err := p.opts.Redis.Set(ctx, p.opts.Prefix+key, 1, p.opts.Window).Err(); err != nil {
+		log.FromContext(ctx).Warn("Failed to record write for read-your-writes",
+			zap.String("key", key), zap.Error(err))
+	}
+}
+
+// markRaw 原生语句只有非 SELECT 时视为写入
+func (p *ReadYourWritesPlugin) markRaw(db *gorm.DB) {
+	sql := strings.TrimSpace(db.Statement.SQL.String())
+	if len(sql) >= 6 && strings.EqualFold(sql[:6], "select") {
+		return
+	}
+	p.mark(db)
+}
+
+// pin 窗口内发生过写入时将读取路由到主库。dbresolver.Write 会重新执行 gorm:db_resolver，
+// 因此与 dbresolver 的注册顺序无关：先于其执行时设置写标记，后于其执行时将已选的副本切换为主库
+func (p *ReadYourWritesPlugin) pin(db *gorm.DB) {
+	if db.Error != nil || !p.written(db.Statement.Context) {
+		return
+	}
+	dbresolver.Write.ModifyStatement(db.Statement)
+}
+
+// written 判断请求或用户是否在窗口内写入过
+func (p *ReadYourWritesPlugin) written(ctx context.Context) bool {
+	if s, ok := readYourWritesKey.From(ctx); ok {
+		s.mu.Lock()
+		written := s.written
+		s.mu.Unlock()
+		if !written.IsZero() && time.Since(written) < p.opts.Window {
+			return true
+		}
+	}
+
+	if p.opts.Redis == nil {
+		return false
+	}
+	key, ok := p.opts.Key(ctx)
+	if !ok || key == "" {
+		return false
+	}
+	n, err := p.opts.Redis.Exists(ctx, p.opts.Prefix+key).Result()
+	if err != nil {
+		log.FromContext(ctx).Warn("Failed to check write for read-your-writes",
+			zap.String("key", key), zap.Error(err))
+		return false
+	}
+	return n > 0
+}
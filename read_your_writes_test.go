@@ -0,0 +1,126 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+type rywItem struct {
+	ID   uint
+	Name string
+}
+
+// openRYWTestDB 打开主库和副本两个 SQLite 库，副本中预置一行用于区分读取落在哪个库
+func openRYWTestDB(t *testing.T, pluginFirst bool, window time.Duration) *gorm.DB {
+	t.Helper()
+	dir := t.TempDir()
+	source, replica := filepath.Join(dir, "source.db"), filepath.Join(dir, "replica.db")
+
+	replicaDB, err := gorm.Open(sqlite.Open(replica), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open replica: %v", err)
+	}
+	if err := replicaDB.AutoMigrate(&rywItem{}); err != nil {
+		t.Fatalf("migrate replica: %v", err)
+	}
+	if err := replicaDB.Create(&rywItem{Name: "replica"}).Error; err != nil {
+		t.Fatalf("seed replica: %v", err)
+	}
+	if sqlDB, err := replicaDB.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+
+	db, err := gorm.Open(sqlite.Open(source), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open source: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&rywItem{}); err != nil {
+		t.Fatalf("migrate source: %v", err)
+	}
+
+	plugin := NewReadYourWritesPlugin(&ReadYourWritesOptions{Window: window})
+	resolver := dbresolver.Register(dbresolver.Config{Replicas: []gorm.Dialector{sqlite.Open(replica)}})
+	plugins := []gorm.Plugin{resolver, plugin}
+	if pluginFirst {
+		plugins = []gorm.Plugin{plugin, resolver}
+	}
+	for _, p := range plugins {
+		if err := db.Use(p); err != nil {
+			t.Fatalf("use %s: %v", p.Name(), err)
+		}
+	}
+	return db
+}
+
+func TestReadYourWritesRouting(t *testing.T) {
+	tests := []struct {
+		name        string
+		pluginFirst bool          // 是否先于 dbresolver 注册
+		window      time.Duration // 写入后读主库的时长
+		session     bool          // 是否使用 WithReadYourWrites 的 context
+		wait        time.Duration // 写入后等待多久再读
+		want        string        // 读取落在的库
+	}{
+		{name: "read after write goes to source", window: time.Minute, session: true, want: "source"},
+		{name: "plugin registered before resolver", pluginFirst: true, window: time.Minute, session: true, want: "source"},
+		{name: "without session reads go to replica", window: time.Minute, want: "replica"},
+		{name: "reads after window go to replica", window: 20 * time.Millisecond, session: true, wait: 50 * time.Millisecond, want: "replica"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openRYWTestDB(t, tt.pluginFirst, tt.window)
+			ctx := context.Background()
+			if tt.session {
+				ctx = WithReadYourWrites(ctx)
+			}
+			if err := db.WithContext(ctx).Create(&rywItem{Name: "source"}).Error; err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			time.Sleep(tt.wait)
+
+			var item rywItem
+			if err := db.WithContext(ctx).First(&item).Error; err != nil {
+				t.Fatalf("first: %v", err)
+			}
+			if item.Name != tt.want {
+				t.Errorf("First read from %s, want %s", item.Name, tt.want)
+			}
+
+			var name string
+			if err := db.WithContext(ctx).Raw("SELECT name FROM ryw_items ORDER BY id LIMIT 1").Row().Scan(&name); err != nil {
+				t.Fatalf("row: %v", err)
+			}
+			if name != tt.want {
+				t.Errorf("Row read from %s, want %s", name, tt.want)
+			}
+		})
+	}
+}
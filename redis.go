@@ -29,8 +29,9 @@ func NewRedis(opts *RedisOptions) (*redis.Client, error) {
 		return nil, fmt.Errorf("redis options cannot be nil")
 	}
 
-	rdb := redis.NewClient(&redis.Options{
+	redisOpts := &redis.Options{
 		Addr:            opts.Addr,
+		Username:        opts.Username,
 		Password:        opts.Password,
 		DB:              opts.DB,
 		PoolSize:        opts.PoolSize,
@@ -39,7 +40,30 @@ func NewRedis(opts *RedisOptions) (*redis.Client, error) {
 		ReadTimeout:     opts.ReadTimeout,
 		WriteTimeout:    opts.WriteTimeout,
 		ConnMaxIdleTime: opts.IdleTimeout,
-	})
+
+		ClientName:            opts.ClientName,
+		Protocol:              opts.Protocol,
+		MaxRetries:            opts.MaxRetries,
+		MinRetryBackoff:       opts.MinRetryBackoff,
+		MaxRetryBackoff:       opts.MaxRetryBackoff,
+		PoolTimeout:           opts.PoolTimeout,
+		ConnMaxLifetime:       opts.ConnMaxLifetime,
+		ContextTimeoutEnabled: opts.ContextTimeoutEnabled,
+	}
+
+	if opts.Dialer != nil {
+		redisOpts.Dialer = opts.Dialer.DialContext
+	}
+	// 设置了凭据提供者时，每次建立新连接都获取最新密码；服务端拒绝凭据时强制刷新，见 IsAuthError
+	if provider := opts.PasswordProvider; provider != nil {
+		username := opts.Username
+		redisOpts.CredentialsProviderContext = func(ctx context.Context) (string, string, error) {
+			password, err := provider.Password(ctx)
+			return username, password, err
+		}
+	}
+
+	rdb := redis.NewClient(redisOpts)
 
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), opts.DialTimeout)
@@ -48,11 +72,41 @@ func NewRedis(opts *RedisOptions) (*redis.Client, error) {
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
 	}
+	if err := RequireRedisModules(ctx, rdb, opts.RequireModules...); err != nil {
+		_ = rdb.Close()
+		return nil, err
+	}
+
+	logRedisBanner(rdb, opts, redisOpts.TLSConfig != nil)
+	if !opts.DisableMetrics {
+		registerRedisPoolMetrics(opts.Addr, rdb)
+	}
+	if opts.PasswordProvider != nil {
+		rdb.AddHook(authRotationHook{rotation: &authRotation{datasource: "redis@" + opts.Addr, provider: opts.PasswordProvider}})
+	}
+	if opts.CircuitBreaker != nil {
+		rdb.AddHook(circuitBreakerHook{breaker: NewCircuitBreaker("redis@"+opts.Addr, opts.CircuitBreaker)})
+	}
 
 	// 如果启用了追踪，则添加追踪 Hook
 	if opts.EnableTrace {
-		addTraceHook(rdb, opts.EnableTrace)
+		addTraceHook(rdb, opts.EnableTrace,
+			withDatasource("redis"),
+			WithInstanceName(opts.InstanceName),
+			WithErrorLogInterval(opts.ErrorLogInterval),
+			WithErrorReporter(opts.ErrorReporter),
+			WithSLO(opts.SLO),
+			WithRedisLogOptions(opts.LogFilter),
+			WithRedisRedact(opts.Redact),
+			WithPipelineCommandSpans(opts.TracePipelineCommands),
+			WithTraceSampleRate(opts.TraceSampleRate),
+			WithMetricsDisabled(opts.DisableMetrics),
+			WithMetricsNamespace(opts.MetricsNamespace),
+			WithMetricsBackend(opts.MetricsBackend),
+		)
 	}
+	// 自定义 Hook 位于追踪 Hook 内层，其耗时和返回的错误计入追踪
+	addRedisHooks(rdb, opts.Hooks)
 
 	return rdb, nil
 }
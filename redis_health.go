@@ -0,0 +1,251 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// RedisHealthCheckConfig Redis 健康检查配置，通过 Manager 注册的客户端启用后，
+// 重建的客户端替换管理器中的客户端，并以 ReloadEvent 通知 OnReload 回调
+type RedisHealthCheckConfig struct {
+	Enabled          bool               `yaml:"enabled" env:"REDIS_HEALTH_CHECK_ENABLED"`
+	Interval         pkgConfig.Duration `yaml:"interval" env:"REDIS_HEALTH_CHECK_INTERVAL" default:"10s"`                 // PING 间隔
+	Timeout          pkgConfig.Duration `yaml:"timeout" env:"REDIS_HEALTH_CHECK_TIMEOUT" default:"2s"`                    // 单次 PING 的超时
+	FailureThreshold int                `yaml:"failure_threshold" env:"REDIS_HEALTH_CHECK_FAILURE_THRESHOLD" default:"3"` // 连续失败多少次后重建客户端
+}
+
+// Validate 验证健康检查配置
+func (c *RedisHealthCheckConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Interval.Duration() < 0 || c.Timeout.Duration() < 0 {
+		return fmt.Errorf("health_check interval and timeout must be non-negative")
+	}
+	if c.FailureThreshold < 0 {
+		return fmt.Errorf("health_check failure_threshold must be non-negative, got %d", c.FailureThreshold)
+	}
+	return nil
+}
+
+// options 转换为 RedisHealthOptions，未启用时返回 nil
+func (c *RedisHealthCheckConfig) options() *RedisHealthOptions {
+	if !c.Enabled {
+		return nil
+	}
+	return &RedisHealthOptions{
+		Interval:         c.Interval.Duration(),
+		Timeout:          c.Timeout.Duration(),
+		FailureThreshold: c.FailureThreshold,
+	}
+}
+
+// RedisHealthOptions Redis 健康检查配置选项
+type RedisHealthOptions struct {
+	Name             string                                           // 日志字段和指标标签，默认客户端地址
+	Interval         time.Duration                                    // PING 间隔，默认 10s
+	Timeout          time.Duration                                    // 单次 PING 的超时，默认 2s
+	FailureThreshold int                                              // 连续失败多少次后重建客户端，默认 3
+	Recreate         func(ctx context.Context) (*redis.Client, error) // 创建新客户端，nil 时只检查和上报状态
+	OnRecreate       func(client *redis.Client)                       // 重建成功后调用，用于替换调用方持有的客户端
+}
+
+// RedisHealthChecker 在后台定期 PING Redis，上报连接状态指标；连续失败达到阈值时重建客户端，
+// 丢弃连接池中可能已失效的连接（如被防火墙或负载均衡静默断开的空闲连接），
+// 使调用方在请求出错之前感知并替换失效的客户端。旧客户端延迟关闭，让进行中的命令有机会完成
+type RedisHealthChecker struct {
+	opts RedisHealthOptions
+
+	client   atomic.Pointer[redis.Client]
+	healthy  atomic.Bool
+	failures int // 连续失败次数，只在检查 goroutine 中访问
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRedisHealthChecker 创建 Redis 健康检查，调用 Start 后开始检查
+func NewRedisHealthChecker(client *redis.Client, opts *RedisHealthOptions) (*RedisHealthChecker, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	h := &RedisHealthChecker{}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Name == "" {
+		h.opts.Name = client.Options().Addr
+	}
+	if h.opts.Interval <= 0 {
+		h.opts.Interval = 10 * time.Second
+	}
+	if h.opts.Timeout <= 0 {
+		h.opts.Timeout = 2 * time.Second
+	}
+	if h.opts.FailureThreshold <= 0 {
+		h.opts.FailureThreshold = 3
+	}
+	h.client.Store(client)
+	h.healthy.Store(true)
+	return h, nil
+}
+
+// Start 在后台开始健康检查
+func (h *RedisHealthChecker) Start(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cancel != nil {
+		return fmt.Errorf("redis health checker for %s already started", h.opts.Name)
+	}
+	ctx, h.cancel = context.WithCancel(ctx)
+	h.done = make(chan struct{})
+	h.recordState()
+	go h.run(ctx)
+	return nil
+}
+
+// Stop 停止健康检查，等待进行中的检查或重建完成
+func (h *RedisHealthChecker) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	cancel, done := h.cancel, h.done
+	h.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Client 返回当前的客户端，重建后返回新客户端
+func (h *RedisHealthChecker) Client() *redis.Client {
+	return h.client.Load()
+}
+
+// Healthy 返回最近一次检查是否成功
+func (h *RedisHealthChecker) Healthy() bool {
+	return h.healthy.Load()
+}
+
+// setClient 替换检查的客户端，用于客户端在外部被重建（如配置热更新）的情况
+func (h *RedisHealthChecker) setClient(client *redis.Client) {
+	h.client.Store(client)
+}
+
+// run 健康检查循环
+func (h *RedisHealthChecker) run(ctx context.Context) {
+	defer close(h.done)
+
+	ticker := time.NewTicker(h.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		h.check(ctx)
+	}
+}
+
+// check 执行一次 PING，连续失败达到阈值时重建客户端
+func (h *RedisHealthChecker) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, h.opts.Timeout)
+	err := h.Client().Ping(pingCtx).Err()
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err == nil {
+		if !h.healthy.Swap(true) {
+			log.Info("Redis connection recovered", zap.String("datasource", h.opts.Name), zap.Int("failures", h.failures))
+		}
+		h.failures = 0
+		h.recordState()
+		return
+	}
+
+	h.failures++
+	if h.healthy.Swap(false) {
+		log.Warn("Redis health check failed", zap.String("datasource", h.opts.Name), zap.Error(err))
+	}
+	h.recordState()
+	if h.opts.Recreate == nil || h.failures < h.opts.FailureThreshold {
+		return
+	}
+	h.recreate(ctx, err)
+}
+
+// recreate 创建新客户端替换当前客户端，新客户端无法连接时保留当前客户端，下次检查失败时重试
+func (h *RedisHealthChecker) recreate(ctx context.Context, cause error) {
+	client, err := h.opts.Recreate(ctx)
+	if err != nil {
+		log.Warn("Failed to recreate redis client",
+			zap.String("datasource", h.opts.Name), zap.Int("failures", h.failures), zap.NamedError("cause", cause), zap.Error(err))
+		recordRedisReconnect(h.opts.Name, "failed")
+		return
+	}
+
+	old := h.client.Swap(client)
+	time.AfterFunc(reloadCloseDelay, func() { _ = CloseRedis(old) })
+	log.Warn("Recreated redis client after sustained health check failures",
+		zap.String("datasource", h.opts.Name), zap.Int("failures", h.failures), zap.NamedError("cause", cause))
+	h.failures = 0
+	h.healthy.Store(true)
+	h.recordState()
+	recordRedisReconnect(h.opts.Name, "succeeded")
+	if h.opts.OnRecreate != nil {
+		h.opts.OnRecreate(client)
+	}
+}
+
+// recordState 记录连接状态指标
+func (h *RedisHealthChecker) recordState() {
+	if !metrics.IsEnabled() {
+		return
+	}
+	state := 0.0
+	if h.healthy.Load() {
+		state = 1
+	}
+	RedisConnectionState.WithLabelValues(h.opts.Name).Set(state)
+}
+
+// recordRedisReconnect 记录客户端重建指标
+func recordRedisReconnect(name, result string) {
+	if metrics.IsEnabled() {
+		RedisClientRecreateTotal.WithLabelValues(name, result).Inc()
+	}
+}
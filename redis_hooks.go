@@ -0,0 +1,77 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCommandCheck Redis 命令执行前的检查函数，返回错误时中止命令，如键审计、租户前缀校验
+type RedisCommandCheck func(ctx context.Context, cmd redis.Cmder) error
+
+// RedisCommandHook 将检查函数包装为 redis.Hook，可通过 RedisOptions.Hooks 注册。
+// 管道中的每条命令都会检查，任一命令未通过时整个管道不执行
+func RedisCommandHook(check RedisCommandCheck) redis.Hook {
+	return redisCommandHook{check: check}
+}
+
+// redisCommandHook 在命令执行前调用检查函数
+type redisCommandHook struct {
+	check RedisCommandCheck
+}
+
+// DialHook 在建立连接时调用
+func (h redisCommandHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook 在处理命令时调用
+func (h redisCommandHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.check(ctx, cmd); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+// ProcessPipelineHook 在处理管道命令时调用
+func (h redisCommandHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := h.check(ctx, cmd); err != nil {
+				for _, c := range cmds {
+					c.SetErr(err)
+				}
+				return err
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// addRedisHooks 在内置 Hook 之后按顺序添加自定义 Hook，先添加的位于外层
+func addRedisHooks(client redis.UniversalClient, hooks []redis.Hook) {
+	for _, hook := range hooks {
+		if hook != nil {
+			client.AddHook(hook)
+		}
+	}
+}
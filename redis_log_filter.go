@@ -0,0 +1,94 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// RedisLogOptions Redis 命令日志过滤配置
+// 仅作用于成功命令的日志，失败的命令始终记录（受错误日志去重窗口控制）
+type RedisLogOptions struct {
+	SkipCommands  []string      // 不记录日志的命令（不区分大小写），如 ping、echo
+	SampleRate    float64       // 成功命令日志的采样率，取值 0-1，1 表示全部记录
+	SlowOnly      bool          // 仅记录耗时不低于 SlowThreshold 的命令
+	SlowThreshold time.Duration // 慢命令阈值，超过时以 Warn 级别记录且不参与采样，0 表示不区分慢命令
+}
+
+// redisLogDecision 单条成功命令日志的处理方式
+type redisLogDecision int
+
+const (
+	redisLogSkip redisLogDecision = iota
+	redisLogInfo
+	redisLogSlow
+)
+
+// redisLogFilter 根据 RedisLogOptions 决定是否记录成功命令的日志
+type redisLogFilter struct {
+	skip          map[string]struct{}
+	sampleRate    float64
+	slowOnly      bool
+	slowThreshold time.Duration
+}
+
+// newRedisLogFilter 创建日志过滤器，opts 为 nil 时记录全部命令
+func newRedisLogFilter(opts *RedisLogOptions) *redisLogFilter {
+	if opts == nil {
+		return nil
+	}
+	f := &redisLogFilter{
+		skip:          make(map[string]struct{}, len(opts.SkipCommands)),
+		sampleRate:    opts.SampleRate,
+		slowOnly:      opts.SlowOnly,
+		slowThreshold: opts.SlowThreshold,
+	}
+	for _, name := range opts.SkipCommands {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			f.skip[name] = struct{}{}
+		}
+	}
+	return f
+}
+
+// decide 返回成功命令日志的处理方式，operation 为小写的命令名
+func (f *redisLogFilter) decide(operation string, duration time.Duration) redisLogDecision {
+	if f == nil {
+		return redisLogInfo
+	}
+	if _, ok := f.skip[operation]; ok {
+		return redisLogSkip
+	}
+	if f.slowThreshold > 0 && duration >= f.slowThreshold {
+		return redisLogSlow
+	}
+	if f.slowOnly {
+		return redisLogSkip
+	}
+	switch {
+	case f.sampleRate >= 1:
+		return redisLogInfo
+	case f.sampleRate <= 0:
+		return redisLogSkip
+	case rand.Float64() < f.sampleRate:
+		return redisLogInfo
+	default:
+		return redisLogSkip
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// RedisModuleJSON RedisJSON 模块
+	RedisModuleJSON = "json"
+	// RedisModuleSearch RediSearch 模块
+	RedisModuleSearch = "search"
+	// RedisModuleBloom RedisBloom 模块
+	RedisModuleBloom = "bloom"
+)
+
+// ErrRedisModuleUnavailable 服务端未加载所需的 Redis 模块
+var ErrRedisModuleUnavailable = errors.New("redis module not available")
+
+// redisModuleCommands 用于探测模块是否加载的命令
+var redisModuleCommands = map[string]string{
+	RedisModuleJSON:   "JSON.GET",
+	RedisModuleSearch: "FT.SEARCH",
+	RedisModuleBloom:  "BF.ADD",
+}
+
+// RedisModules 服务端已加载的模块
+type RedisModules struct {
+	JSON   bool // RedisJSON
+	Search bool // RediSearch
+	Bloom  bool // RedisBloom
+}
+
+// Has 返回指定名称（json、search、bloom）的模块是否可用
+func (m RedisModules) Has(module string) bool {
+	switch module {
+	case RedisModuleJSON:
+		return m.JSON
+	case RedisModuleSearch:
+		return m.Search
+	case RedisModuleBloom:
+		return m.Bloom
+	}
+	return false
+}
+
+// ProbeRedisModules 通过 COMMAND INFO 探测服务端加载的模块，托管服务禁用 MODULE LIST 时同样可用
+func ProbeRedisModules(ctx context.Context, client redis.UniversalClient) (RedisModules, error) {
+	names := []string{RedisModuleJSON, RedisModuleSearch, RedisModuleBloom}
+	args := []any{"COMMAND", "INFO"}
+	for _, name := range names {
+		args = append(args, redisModuleCommands[name])
+	}
+	infos, err := client.Do(ctx, args...).Slice()
+	if err != nil {
+		return RedisModules{}, fmt.Errorf("failed to probe redis modules: %w", err)
+	}
+
+	var modules RedisModules
+	for i, info := range infos {
+		if i >= len(names) || info == nil {
+			continue
+		}
+		switch names[i] {
+		case RedisModuleJSON:
+			modules.JSON = true
+		case RedisModuleSearch:
+			modules.Search = true
+		case RedisModuleBloom:
+			modules.Bloom = true
+		}
+	}
+	return modules, nil
+}
+
+// RequireRedisModules 检查服务端已加载指定的模块，缺少时返回 ErrRedisModuleUnavailable
+func RequireRedisModules(ctx context.Context, client redis.UniversalClient, modules ...string) error {
+	if len(modules) == 0 {
+		return nil
+	}
+	available, err := ProbeRedisModules(ctx, client)
+	if err != nil {
+		return err
+	}
+	for _, module := range modules {
+		if !available.Has(module) {
+			return fmt.Errorf("%w: %s", ErrRedisModuleUnavailable, module)
+		}
+	}
+	return nil
+}
+
+// validateRedisModules 检查模块名称
+func validateRedisModules(modules []string) error {
+	for _, module := range modules {
+		if _, ok := redisModuleCommands[module]; !ok {
+			return fmt.Errorf("unknown redis module %q, must be json, search or bloom", module)
+		}
+	}
+	return nil
+}
+
+// JSONStoreOptions RedisJSON 文档存储配置选项
+type JSONStoreOptions struct {
+	Prefix string        // 键前缀，文档的键为 Prefix + id，与 RediSearch 索引的前缀一致时文档自动被索引
+	TTL    time.Duration // 文档的过期时间，每次 Set 时刷新，0 表示不过期
+}
+
+// JSONStore 基于 RedisJSON 的类型化文档存储，T 以 encoding/json 序列化
+type JSONStore[T any] struct {
+	client redis.UniversalClient
+	opts   JSONStoreOptions
+}
+
+// NewJSONStore 创建 RedisJSON 文档存储，服务端未加载 RedisJSON 时返回 ErrRedisModuleUnavailable
+func NewJSONStore[T any](ctx context.Context, client redis.UniversalClient, opts *JSONStoreOptions) (*JSONStore[T], error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if err := RequireRedisModules(ctx, client, RedisModuleJSON); err != nil {
+		return nil, err
+	}
+	s := &JSONStore[T]{client: client}
+	if opts != nil {
+		s.opts = *opts
+	}
+	return s, nil
+}
+
+// Key 返回文档的键
+func (s *JSONStore[T]) Key(id string) string {
+	return s.opts.Prefix + id
+}
+
+// Get 读取文档，不存在时返回 ErrNotFound
+func (s *JSONStore[T]) Get(ctx context.Context, id string) (*T, error) {
+	raw, err := s.client.JSONGet(ctx, s.Key(id), "$").Result()
+	if errors.Is(err, redis.Nil) || (err == nil && raw == "") {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("json store: get %s: %w", id, err)
+	}
+	return decodeJSONRoot[T](raw)
+}
+
+// MGet 批量读取文档，结果与 ids 一一对应，不存在的文档为 nil。通过管道逐个读取，Redis Cluster 下键可位于不同的哈希槽
+func (s *JSONStore[T]) MGet(ctx context.Context, ids ...string) ([]*T, error) {
+	cmds := make([]*redis.JSONCmd, len(ids))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.JSONGet(ctx, s.Key(id), "$")
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("json store: mget: %w", err)
+	}
+
+	docs := make([]*T, len(ids))
+	for i, cmd := range cmds {
+		raw, err := cmd.Result()
+		if errors.Is(err, redis.Nil) || raw == "" {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("json store: mget %s: %w", ids[i], err)
+		}
+		if docs[i], err = decodeJSONRoot[T](raw); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// Set 写入整个文档，设置了 TTL 时同时刷新过期时间
+func (s *JSONStore[T]) Set(ctx context.Context, id string, doc *T) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("json store: marshal %s: %w", id, err)
+	}
+	key := s.Key(id)
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.JSONSet(ctx, key, "$", string(data))
+		if s.opts.TTL > 0 {
+			pipe.PExpire(ctx, key, s.opts.TTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("json store: set %s: %w", id, err)
+	}
+	return nil
+}
+
+// GetPath 读取文档中 JSONPath（如 $.address.city）匹配的值，dest 接收所有匹配值组成的 JSON 数组。
+// 文档不存在时返回 ErrNotFound
+func (s *JSONStore[T]) GetPath(ctx context.Context, id, path string, dest any) error {
+	raw, err := s.client.JSONGet(ctx, s.Key(id), path).Result()
+	if errors.Is(err, redis.Nil) || (err == nil && raw == "") {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("json store: get %s %s: %w", id, path, err)
+	}
+	if err := json.Unmarshal([]byte(raw), dest); err != nil {
+		return fmt.Errorf("json store: unmarshal %s %s: %w", id, path, err)
+	}
+	return nil
+}
+
+// SetPath 更新文档中 JSONPath 匹配的值，文档必须已存在，不刷新过期时间
+func (s *JSONStore[T]) SetPath(ctx context.Context, id, path string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("json store: marshal %s %s: %w", id, path, err)
+	}
+	if err := s.client.JSONSetMode(ctx, s.Key(id), path, string(data), "XX").Err(); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("json store: set %s %s: %w", id, path, err)
+	}
+	return nil
+}
+
+// Delete 删除文档
+func (s *JSONStore[T]) Delete(ctx context.Context, id string) error {
+	return s.client.Del(ctx, s.Key(id)).Err()
+}
+
+// decodeJSONRoot 解析以 $ 路径读取的结果，结果为只包含根文档的数组
+func decodeJSONRoot[T any](raw string) (*T, error) {
+	var docs []T
+	if err := json.Unmarshal([]byte(raw), &docs); err != nil {
+		return nil, fmt.Errorf("json store: unmarshal: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &docs[0], nil
+}
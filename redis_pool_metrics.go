@@ -0,0 +1,109 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"sync"
+
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPoolStatser 可提供连接池统计的 Redis 客户端（*redis.Client、*redis.Ring 等）
+type redisPoolStatser interface {
+	PoolStats() *redis.PoolStats
+}
+
+// redisPoolCollector 采集已登记的 Redis 连接池统计，采集时实时读取 PoolStats
+type redisPoolCollector struct {
+	mu    sync.RWMutex
+	pools map[string]redisPoolStatser
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+var (
+	redisPoolCollectorOnce sync.Once
+	redisPools             *redisPoolCollector
+)
+
+// registerRedisPoolMetrics 登记 Redis 连接池，同名登记会替换之前的连接池
+func registerRedisPoolMetrics(name string, client redisPoolStatser) {
+	redisPoolCollectorOnce.Do(func() {
+		labels := []string{"datasource"}
+		redisPools = &redisPoolCollector{
+			pools:      make(map[string]redisPoolStatser),
+			hits:       prometheus.NewDesc("redis_pool_hits_total", "Number of times a free connection was found in the pool", labels, nil),
+			misses:     prometheus.NewDesc("redis_pool_misses_total", "Number of times a free connection was not found in the pool", labels, nil),
+			timeouts:   prometheus.NewDesc("redis_pool_timeouts_total", "Number of times a wait timeout occurred", labels, nil),
+			totalConns: prometheus.NewDesc("redis_pool_connections_total", "Number of total connections in the pool", labels, nil),
+			idleConns:  prometheus.NewDesc("redis_pool_connections_idle", "Number of idle connections in the pool", labels, nil),
+			staleConns: prometheus.NewDesc("redis_pool_connections_stale_total", "Number of stale connections removed from the pool", labels, nil),
+		}
+		prometheus.MustRegister(redisPools)
+	})
+
+	redisPools.mu.Lock()
+	defer redisPools.mu.Unlock()
+	redisPools.pools[name] = client
+}
+
+// unregisterRedisPoolMetrics 注销 Redis 连接池
+func unregisterRedisPoolMetrics(name string) {
+	if redisPools == nil {
+		return
+	}
+	redisPools.mu.Lock()
+	defer redisPools.mu.Unlock()
+	delete(redisPools.pools, name)
+}
+
+// Describe 实现 prometheus.Collector 接口
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+}
+
+// Collect 实现 prometheus.Collector 接口
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	if !metrics.IsEnabled() {
+		return
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for name, pool := range c.pools {
+		stats := pool.PoolStats()
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), name)
+		ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts), name)
+		ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns), name)
+		ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns), name)
+		ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns), name)
+	}
+}
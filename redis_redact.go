@@ -0,0 +1,90 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis 命令日志和 span 中 db.statement 的脱敏策略
+const (
+	// RedisRedactNone 记录完整命令及返回值
+	RedisRedactNone = ""
+	// RedisRedactKey 仅记录命令名和键
+	RedisRedactKey = "key"
+	// RedisRedactHash 记录命令名和键，其余参数替换为哈希，便于比对相同参数而不泄露内容
+	RedisRedactHash = "hash"
+)
+
+// redisKeylessCommands 第一个参数不是键或可能包含敏感内容的命令，脱敏时只记录命令名
+var redisKeylessCommands = map[string]struct{}{
+	"auth": {}, "hello": {}, "client": {}, "config": {}, "acl": {},
+	"eval": {}, "evalsha": {}, "eval_ro": {}, "evalsha_ro": {}, "fcall": {}, "fcall_ro": {},
+	"script": {}, "function": {}, "ping": {}, "echo": {}, "select": {},
+}
+
+// validateRedisRedact 校验脱敏策略
+func validateRedisRedact(mode string) error {
+	switch mode {
+	case RedisRedactNone, RedisRedactKey, RedisRedactHash:
+		return nil
+	default:
+		return fmt.Errorf("must be one of: key, hash, or empty, got %s", mode)
+	}
+}
+
+// redactRedisCmd 按脱敏策略格式化命令
+func redactRedisCmd(cmd redis.Cmder, mode string) string {
+	if mode == RedisRedactNone {
+		return cmd.String()
+	}
+
+	name := cmd.Name()
+	key, ok := redisCmdKey(cmd)
+	if !ok {
+		return name
+	}
+
+	args := cmd.Args()
+	parts := []string{name, key}
+	if mode == RedisRedactHash {
+		for _, arg := range args[2:] {
+			sum := sha256.Sum256([]byte(fmt.Sprint(arg)))
+			parts = append(parts, "sha256:"+hex.EncodeToString(sum[:4]))
+		}
+	} else if len(args) > 2 {
+		parts = append(parts, fmt.Sprintf("[%d args]", len(args)-2))
+	}
+	return strings.Join(parts, " ")
+}
+
+// redisCmdKey 返回命令的第一个键，无键或不记录键的命令返回 false
+func redisCmdKey(cmd redis.Cmder) (string, bool) {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return "", false
+	}
+	if _, ok := redisKeylessCommands[cmd.Name()]; ok {
+		return "", false
+	}
+	return fmt.Sprint(args[1]), true
+}
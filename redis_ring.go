@@ -0,0 +1,363 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// RingHashRendezvous 使用 go-redis 默认的 rendezvous 哈希
+	RingHashRendezvous = "rendezvous"
+	// RingHashKetama 使用带虚拟节点的一致性哈希环
+	RingHashKetama = "ketama"
+)
+
+// RedisRingConfig Redis Ring（客户端分片）配置结构体（用于从配置文件创建）
+type RedisRingConfig struct {
+	Enabled            bool               `yaml:"enabled" env:"REDIS_RING_ENABLED" default:"true"`
+	Shards             map[string]string  `yaml:"shards"` // 分片名称 -> 地址（host:port）
+	Username           string             `yaml:"username" env:"REDIS_RING_USERNAME"`
+	Password           string             `yaml:"password" env:"REDIS_RING_PASSWORD"`
+	PasswordFile       string             `yaml:"password_file" env:"REDIS_RING_PASSWORD_FILE"` // 密码文件路径，优先于 Password
+	DB                 int                `yaml:"db" env:"REDIS_RING_DB" default:"0"`
+	PoolSize           int                `yaml:"pool_size" env:"REDIS_RING_POOL_SIZE" default:"20"` // 每个分片的连接池大小
+	MinIdleConns       int                `yaml:"min_idle_conns" env:"REDIS_RING_MIN_IDLE_CONNS" default:"5"`
+	DialTimeout        pkgConfig.Duration `yaml:"dial_timeout" env:"REDIS_RING_DIAL_TIMEOUT" default:"5s"`
+	ReadTimeout        pkgConfig.Duration `yaml:"read_timeout" env:"REDIS_RING_READ_TIMEOUT" default:"3s"`
+	WriteTimeout       pkgConfig.Duration `yaml:"write_timeout" env:"REDIS_RING_WRITE_TIMEOUT" default:"3s"`
+	IdleTimeout        pkgConfig.Duration `yaml:"idle_timeout" env:"REDIS_RING_IDLE_TIMEOUT" default:"5m"`
+	HeartbeatFrequency pkgConfig.Duration `yaml:"heartbeat_frequency" env:"REDIS_RING_HEARTBEAT_FREQUENCY" default:"500ms"` // 分片健康检查间隔
+	Hash               string             `yaml:"hash" env:"REDIS_RING_HASH" default:"rendezvous"`                          // rendezvous 或 ketama
+	VirtualNodes       int                `yaml:"virtual_nodes" env:"REDIS_RING_VIRTUAL_NODES" default:"160"`               // ketama 每个分片的虚拟节点数
+	EnableTrace        bool               `yaml:"enable_trace" env:"REDIS_RING_ENABLE_TRACE" default:"true"`
+	InstanceName       string             `yaml:"instance_name" env:"REDIS_RING_INSTANCE_NAME"` // 逻辑连接名称（如 orders-primary），用于日志字段、指标标签和 span 属性
+	LogRedact          string             `yaml:"log_redact" env:"REDIS_RING_LOG_REDACT"`       // 命令日志和 span 的脱敏策略：key、hash，空表示不脱敏
+
+	TracePipelineCommands bool `yaml:"trace_pipeline_commands" env:"REDIS_RING_TRACE_PIPELINE_COMMANDS"` // 是否为管道中的每条命令创建子 span
+
+	DisableMetrics   bool   `yaml:"disable_metrics" env:"REDIS_RING_DISABLE_METRICS"`     // 不记录该连接的命令和连接池指标，追踪和日志不受影响
+	MetricsNamespace string `yaml:"metrics_namespace" env:"REDIS_RING_METRICS_NAMESPACE"` // 命令指标名前缀，如 batch 对应 batch_redis_operations_total，为空时使用默认指标
+	MetricsBackend   string `yaml:"metrics_backend" env:"REDIS_RING_METRICS_BACKEND"`     // 命令指标的输出后端：prometheus（默认）、otel、both
+
+	Tunnel TunnelConfig `yaml:"tunnel"` // 通过 SOCKS5/HTTP 代理或 SSH 隧道连接所有分片，未配置时直接连接
+}
+
+// Validate 验证 Redis Ring 配置
+func (c *RedisRingConfig) Validate() error {
+	if c == nil {
+		return fmt.Errorf("redis ring config cannot be nil")
+	}
+	if !c.Enabled {
+		return nil // 如果未启用，不需要验证
+	}
+	if len(c.Shards) == 0 {
+		return fmt.Errorf("redis ring shards are required")
+	}
+	for name, addr := range c.Shards {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("redis ring shard %s has invalid address %q: %w", name, addr, err)
+		}
+	}
+	if c.PoolSize < 1 {
+		return fmt.Errorf("redis ring pool_size must be greater than 0, got %d", c.PoolSize)
+	}
+	if c.MinIdleConns < 0 {
+		return fmt.Errorf("redis ring min_idle_conns must be non-negative, got %d", c.MinIdleConns)
+	}
+	if c.DB < 0 {
+		return fmt.Errorf("redis ring db must be non-negative, got %d", c.DB)
+	}
+	switch c.Hash {
+	case "", RingHashRendezvous:
+	case RingHashKetama:
+		if c.VirtualNodes < 1 {
+			return fmt.Errorf("redis ring virtual_nodes must be greater than 0, got %d", c.VirtualNodes)
+		}
+	default:
+		return fmt.Errorf("redis ring hash must be one of: rendezvous, ketama, got %s", c.Hash)
+	}
+	if err := validateRedisRedact(c.LogRedact); err != nil {
+		return fmt.Errorf("redis ring log_redact %w", err)
+	}
+	if err := c.Tunnel.Validate(); err != nil {
+		return fmt.Errorf("redis ring %w", err)
+	}
+	if err := validateMetricsNamespace(c.MetricsNamespace); err != nil {
+		return fmt.Errorf("redis ring %w", err)
+	}
+	if err := validateMetricsBackend(c.MetricsBackend); err != nil {
+		return fmt.Errorf("redis ring %w", err)
+	}
+	return nil
+}
+
+// ToOptions 转换为 RedisRingOptions
+func (c *RedisRingConfig) ToOptions() (*RedisRingOptions, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if !c.Enabled {
+		return nil, fmt.Errorf("redis ring is not enabled")
+	}
+
+	password, provider, err := resolvePassword(c.Password, c.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("redis ring: %w", err)
+	}
+	dialer, err := c.Tunnel.dialer()
+	if err != nil {
+		return nil, fmt.Errorf("redis ring: %w", err)
+	}
+
+	shards := make(map[string]string, len(c.Shards))
+	for name, addr := range c.Shards {
+		shards[name] = addr
+	}
+
+	return &RedisRingOptions{
+		Shards:             shards,
+		Username:           c.Username,
+		Password:           password,
+		PasswordProvider:   provider,
+		Dialer:             dialer,
+		DB:                 c.DB,
+		PoolSize:           c.PoolSize,
+		MinIdleConns:       c.MinIdleConns,
+		DialTimeout:        durationOr(c.DialTimeout.Duration(), 5*time.Second),
+		ReadTimeout:        durationOr(c.ReadTimeout.Duration(), 3*time.Second),
+		WriteTimeout:       durationOr(c.WriteTimeout.Duration(), 3*time.Second),
+		IdleTimeout:        durationOr(c.IdleTimeout.Duration(), 5*time.Minute),
+		HeartbeatFrequency: durationOr(c.HeartbeatFrequency.Duration(), 500*time.Millisecond),
+		Hash:               c.Hash,
+		VirtualNodes:       c.VirtualNodes,
+		EnableTrace:        c.EnableTrace,
+		InstanceName:       c.InstanceName,
+		Redact:             c.LogRedact,
+		DisableMetrics:     c.DisableMetrics,
+		MetricsNamespace:   c.MetricsNamespace,
+		MetricsBackend:     c.MetricsBackend,
+
+		TracePipelineCommands: c.TracePipelineCommands,
+	}, nil
+}
+
+// RedisRingOptions 结构体定义了 Redis Ring 连接器的配置选项（内部使用）
+type RedisRingOptions struct {
+	Shards             map[string]string
+	Username           string
+	Password           string
+	PasswordProvider   CredentialProvider // 动态密码提供者，设置后每次建立新连接时获取最新密码
+	DB                 int
+	PoolSize           int
+	MinIdleConns       int
+	DialTimeout        time.Duration
+	Dialer             *Dialer // 建立网络连接的拨号器（代理、SSH 隧道），nil 时直接连接
+	ReadTimeout        time.Duration
+	WriteTimeout       time.Duration
+	IdleTimeout        time.Duration
+	HeartbeatFrequency time.Duration
+	Hash               string       // rendezvous 或 ketama
+	VirtualNodes       int          // ketama 每个分片的虚拟节点数
+	EnableTrace        bool         // 是否启用命令追踪，用于记录 Redis 命令执行时间
+	InstanceName       string       // 逻辑连接名称，用于日志字段、指标标签和 span 属性，为空表示不区分
+	Hooks              []redis.Hook // 自定义 Hook，添加在熔断器和追踪 Hook 之后（内层），先添加的位于外层
+	ErrorLogInterval   time.Duration
+	ErrorReporter      ErrorReporter // 命令失败时的上报（如 Sentry），需启用追踪，nil 表示不上报
+	SLO                *SLOOptions
+	CircuitBreaker     *CircuitBreakerOptions // 熔断器配置，nil 表示不启用，所有分片共享同一个熔断器
+	LogFilter          *RedisLogOptions       // 成功命令日志的过滤配置，nil 表示全部记录
+	Redact             string                 // 命令日志和 span 的脱敏策略：key、hash，空表示不脱敏
+	DisableMetrics     bool                   // 不记录该连接的命令和连接池指标
+	MetricsNamespace   string                 // 命令指标名前缀，为空时使用默认指标
+	MetricsBackend     string                 // 命令指标的输出后端：MetricsBackendPrometheus（默认）、MetricsBackendOTel 或 MetricsBackendBoth
+
+	TracePipelineCommands bool // 是否为管道中的每条命令创建子 span
+}
+
+// NewRedisRing 根据给定的选项创建一个新的 Redis Ring 客户端实例
+func NewRedisRing(opts *RedisRingOptions) (*redis.Ring, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("redis ring options cannot be nil")
+	}
+	if len(opts.Shards) == 0 {
+		return nil, fmt.Errorf("redis ring shards cannot be empty")
+	}
+
+	ringOpts := &redis.RingOptions{
+		Addrs:              opts.Shards,
+		Username:           opts.Username,
+		Password:           opts.Password,
+		DB:                 opts.DB,
+		PoolSize:           opts.PoolSize,
+		MinIdleConns:       opts.MinIdleConns,
+		DialTimeout:        opts.DialTimeout,
+		ReadTimeout:        opts.ReadTimeout,
+		WriteTimeout:       opts.WriteTimeout,
+		ConnMaxIdleTime:    opts.IdleTimeout,
+		HeartbeatFrequency: opts.HeartbeatFrequency,
+	}
+	if opts.Hash == RingHashKetama {
+		replicas := opts.VirtualNodes
+		ringOpts.NewConsistentHash = func(shards []string) redis.ConsistentHash {
+			return newKetamaHash(shards, replicas)
+		}
+	}
+	if opts.Dialer != nil {
+		ringOpts.Dialer = opts.Dialer.DialContext
+	}
+	if provider := opts.PasswordProvider; provider != nil {
+		username := opts.Username
+		ringOpts.CredentialsProviderContext = func(ctx context.Context) (string, string, error) {
+			password, err := provider.Password(ctx)
+			return username, password, err
+		}
+	}
+
+	// Ring 本身不建立连接，建连追踪需要注册到每个分片的客户端上
+	var hook *traceRedisHook
+	if opts.EnableTrace {
+		hook = newTraceRedisHook(opts.EnableTrace,
+			withDatasource("redis"),
+			WithInstanceName(opts.InstanceName),
+			WithErrorLogInterval(opts.ErrorLogInterval),
+			WithErrorReporter(opts.ErrorReporter),
+			WithSLO(opts.SLO),
+			WithRedisLogOptions(opts.LogFilter),
+			WithRedisRedact(opts.Redact),
+			WithPipelineCommandSpans(opts.TracePipelineCommands),
+			WithMetricsDisabled(opts.DisableMetrics),
+			WithMetricsNamespace(opts.MetricsNamespace),
+			WithMetricsBackend(opts.MetricsBackend),
+		)
+		ringOpts.NewClient = func(opt *redis.Options) *redis.Client {
+			shard := redis.NewClient(opt)
+			shard.AddHook(redisDialTraceHook{hook: hook})
+			return shard
+		}
+	}
+
+	ring := redis.NewRing(ringOpts)
+
+	// 测试所有分片的连接
+	ctx, cancel := context.WithTimeout(context.Background(), durationOr(opts.DialTimeout, 5*time.Second))
+	defer cancel()
+
+	err := ring.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+		return shard.Ping(ctx).Err()
+	})
+	if err != nil {
+		_ = ring.Close()
+		return nil, fmt.Errorf("failed to connect to redis ring: %w", err)
+	}
+
+	addrs := make([]string, 0, len(opts.Shards))
+	for _, addr := range opts.Shards {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	if !opts.DisableMetrics {
+		registerRedisPoolMetrics("ring:"+strings.Join(addrs, ","), ring)
+	}
+	if opts.CircuitBreaker != nil {
+		ring.AddHook(circuitBreakerHook{breaker: NewCircuitBreaker("redis@ring:"+strings.Join(addrs, ","), opts.CircuitBreaker)})
+	}
+
+	// 如果启用了追踪，则添加追踪 Hook（与单实例客户端共用）
+	if hook != nil {
+		ring.AddHook(hook)
+		onHandleClose(ring, hook.release)
+	}
+	addRedisHooks(ring, opts.Hooks)
+
+	return ring, nil
+}
+
+// redisDialTraceHook 只追踪建立连接的 Hook，用于 Ring 的分片客户端，命令由 Ring 上的追踪 Hook 记录
+type redisDialTraceHook struct {
+	hook *traceRedisHook
+}
+
+// DialHook 在建立连接时调用
+func (h redisDialTraceHook) DialHook(next redis.DialHook) redis.DialHook {
+	return h.hook.DialHook(next)
+}
+
+// ProcessHook 实现 redis.Hook 接口
+func (h redisDialTraceHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+// ProcessPipelineHook 实现 redis.Hook 接口
+func (h redisDialTraceHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// ketamaHash 带虚拟节点的一致性哈希环，增减分片时只迁移少量键
+type ketamaHash struct {
+	points []uint32
+	shards map[uint32]string
+}
+
+// newKetamaHash 创建一致性哈希环
+func newKetamaHash(shards []string, replicas int) *ketamaHash {
+	h := &ketamaHash{
+		points: make([]uint32, 0, len(shards)*replicas),
+		shards: make(map[uint32]string, len(shards)*replicas),
+	}
+	for _, shard := range shards {
+		for i := 0; i < replicas; i++ {
+			point := crc32.ChecksumIEEE([]byte(shard + "#" + strconv.Itoa(i)))
+			h.points = append(h.points, point)
+			h.shards[point] = shard
+		}
+	}
+	sort.Slice(h.points, func(i, j int) bool { return h.points[i] < h.points[j] })
+	return h
+}
+
+// Get 返回键所在的分片（实现 redis.ConsistentHash 接口）
+func (h *ketamaHash) Get(key string) string {
+	if len(h.points) == 0 {
+		return ""
+	}
+	point := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(h.points), func(i int) bool { return h.points[i] >= point })
+	if idx == len(h.points) {
+		idx = 0
+	}
+	return h.shards[h.points[idx]]
+}
+
+// durationOr 返回 d，d 为 0 时返回默认值
+func durationOr(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
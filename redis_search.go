@@ -0,0 +1,350 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// SearchFieldText 全文检索字段
+	SearchFieldText = "text"
+	// SearchFieldTag 标签字段，精确匹配
+	SearchFieldTag = "tag"
+	// SearchFieldNumeric 数值字段，支持范围查询和排序
+	SearchFieldNumeric = "numeric"
+	// SearchFieldGeo 地理位置字段
+	SearchFieldGeo = "geo"
+
+	// searchSpecialChars 查询语法中需要转义的字符
+	searchSpecialChars = ",.<>{}[]\"':;!@#$%^&*()-+=~|/\\ "
+)
+
+// searchFieldTypes 字段类型到 go-redis 类型的映射
+var searchFieldTypes = map[string]redis.SearchFieldType{
+	SearchFieldText:    redis.SearchFieldTypeText,
+	SearchFieldTag:     redis.SearchFieldTypeTag,
+	SearchFieldNumeric: redis.SearchFieldTypeNumeric,
+	SearchFieldGeo:     redis.SearchFieldTypeGeo,
+}
+
+// SearchIndex RediSearch 索引定义
+type SearchIndex struct {
+	Name     string        // 索引名称
+	OnJSON   bool          // 索引 RedisJSON 文档，默认索引 Hash
+	Prefixes []string      // 被索引的键前缀，与 JSONStoreOptions.Prefix 一致
+	Fields   []SearchField // 被索引的字段
+}
+
+// SearchField 索引字段
+type SearchField struct {
+	Name     string // Hash 字段名，或 JSON 索引中的 JSONPath（如 $.name）
+	As       string // 查询中使用的字段名，JSON 索引通常需要设置
+	Type     string // 字段类型：text、tag、numeric、geo
+	Sortable bool   // 是否可用于排序
+}
+
+// EnsureSearchIndex 创建索引，同名索引已存在时不做修改，返回是否新建。
+// 服务端未加载 RediSearch 时返回 ErrRedisModuleUnavailable
+func EnsureSearchIndex(ctx context.Context, client redis.UniversalClient, index *SearchIndex) (bool, error) {
+	if client == nil {
+		return false, fmt.Errorf("redis client cannot be nil")
+	}
+	if index == nil || index.Name == "" {
+		return false, fmt.Errorf("search index name is required")
+	}
+	if len(index.Fields) == 0 {
+		return false, fmt.Errorf("search index %s requires at least one field", index.Name)
+	}
+	if err := RequireRedisModules(ctx, client, RedisModuleSearch); err != nil {
+		return false, err
+	}
+
+	schema := make([]*redis.FieldSchema, len(index.Fields))
+	for i, f := range index.Fields {
+		t, ok := searchFieldTypes[strings.ToLower(f.Type)]
+		if !ok {
+			return false, fmt.Errorf("search index %s: unknown field type %q for %s", index.Name, f.Type, f.Name)
+		}
+		schema[i] = &redis.FieldSchema{FieldName: f.Name, As: f.As, FieldType: t, Sortable: f.Sortable}
+	}
+
+	indexes, err := client.FT_List(ctx).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to list search indexes: %w", err)
+	}
+	if slices.Contains(indexes, index.Name) {
+		return false, nil
+	}
+
+	opts := &redis.FTCreateOptions{OnHash: !index.OnJSON, OnJSON: index.OnJSON}
+	for _, prefix := range index.Prefixes {
+		opts.Prefix = append(opts.Prefix, prefix)
+	}
+	if err := client.FTCreate(ctx, index.Name, opts, schema...).Err(); err != nil {
+		return false, fmt.Errorf("failed to create search index %s: %w", index.Name, err)
+	}
+	return true, nil
+}
+
+// DropSearchIndex 删除索引，不删除被索引的文档
+func DropSearchIndex(ctx context.Context, client redis.UniversalClient, name string) error {
+	return client.FTDropIndex(ctx, name).Err()
+}
+
+// SearchQuery RediSearch 查询构造器，多个条件之间为与关系，值会被转义：
+//
+//	q := db.NewSearchQuery().Match("title", "redis cluster").Tag("status", "active").Range("price", 10, math.Inf(1)).SortBy("price", false)
+type SearchQuery struct {
+	clauses []string
+	sortBy  string
+	desc    bool
+	fields  []string
+}
+
+// NewSearchQuery 创建查询，没有条件时匹配所有文档
+func NewSearchQuery() *SearchQuery {
+	return &SearchQuery{}
+}
+
+// Match 全文匹配 text 中的所有词，field 为空时匹配所有 text 字段
+func (q *SearchQuery) Match(field, text string) *SearchQuery {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return q
+	}
+	for i, w := range words {
+		words[i] = escapeSearch(w)
+	}
+	expr := "(" + strings.Join(words, " ") + ")"
+	if field != "" {
+		expr = "@" + field + ":" + expr
+	}
+	q.clauses = append(q.clauses, expr)
+	return q
+}
+
+// Tag 匹配标签字段等于 values 中任意一个值的文档
+func (q *SearchQuery) Tag(field string, values ...string) *SearchQuery {
+	if len(values) == 0 {
+		return q
+	}
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = escapeSearch(v)
+	}
+	q.clauses = append(q.clauses, "@"+field+":{"+strings.Join(escaped, " | ")+"}")
+	return q
+}
+
+// Range 匹配数值字段在 [min, max] 范围内的文档，可使用 math.Inf 表示无界
+func (q *SearchQuery) Range(field string, min, max float64) *SearchQuery {
+	q.clauses = append(q.clauses, "@"+field+":["+searchNumber(min)+" "+searchNumber(max)+"]")
+	return q
+}
+
+// Raw 添加未转义的查询表达式
+func (q *SearchQuery) Raw(expr string) *SearchQuery {
+	if expr != "" {
+		q.clauses = append(q.clauses, expr)
+	}
+	return q
+}
+
+// SortBy 按可排序字段排序，默认按相关度排序
+func (q *SearchQuery) SortBy(field string, desc bool) *SearchQuery {
+	q.sortBy, q.desc = field, desc
+	return q
+}
+
+// Return 只返回指定字段，默认返回整个文档
+func (q *SearchQuery) Return(fields ...string) *SearchQuery {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+// String 返回查询字符串
+func (q *SearchQuery) String() string {
+	if len(q.clauses) == 0 {
+		return "*"
+	}
+	return strings.Join(q.clauses, " ")
+}
+
+// args 返回 FT.SEARCH 的参数
+func (q *SearchQuery) args(index string, offset, size int) []any {
+	args := []any{"FT.SEARCH", index, q.String()}
+	if len(q.fields) > 0 {
+		args = append(args, "RETURN", len(q.fields))
+		for _, f := range q.fields {
+			args = append(args, f)
+		}
+	}
+	if q.sortBy != "" {
+		order := "ASC"
+		if q.desc {
+			order = "DESC"
+		}
+		args = append(args, "SORTBY", q.sortBy, order)
+	}
+	return append(args, "LIMIT", offset, size, "DIALECT", 2)
+}
+
+// SearchDocument 查询结果中的文档
+type SearchDocument struct {
+	ID     string            `json:"id"`     // 文档的键
+	Fields map[string]string `json:"fields"` // 返回的字段，JSON 索引未指定 Return 时整个文档在 $ 字段中
+}
+
+// Search 执行查询并偏移分页，page 从 1 开始，每页条数按 Paginator 规范化。
+// 直接解析 RESP2 和 RESP3 的响应，不要求客户端开启 UnstableResp3
+func Search(ctx context.Context, client redis.UniversalClient, index string, q *SearchQuery, p *Paginator, page, size int) (*Page[SearchDocument], error) {
+	if q == nil {
+		q = NewSearchQuery()
+	}
+	size = p.size(size)
+	page = max(page, 1)
+
+	reply, err := client.Do(ctx, q.args(index, (page-1)*size, size)...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %w", index, err)
+	}
+	total, docs, err := parseSearchReply(reply)
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %w", index, err)
+	}
+	return &Page[SearchDocument]{
+		Items:      docs,
+		Page:       page,
+		Size:       size,
+		Total:      total,
+		TotalPages: int((total + int64(size) - 1) / int64(size)),
+		HasMore:    int64((page-1)*size+len(docs)) < total,
+	}, nil
+}
+
+// SearchJSON 在 JSON 索引上执行查询，将文档解析为 T；查询不能使用 Return
+func SearchJSON[T any](ctx context.Context, client redis.UniversalClient, index string, q *SearchQuery, p *Paginator, page, size int) (*Page[T], error) {
+	if q != nil && len(q.fields) > 0 {
+		return nil, fmt.Errorf("search %s: typed json search cannot be combined with Return", index)
+	}
+	result, err := Search(ctx, client, index, q, p, page, size)
+	if err != nil {
+		return nil, err
+	}
+
+	typed := &Page[T]{
+		Items:      make([]T, len(result.Items)),
+		Page:       result.Page,
+		Size:       result.Size,
+		Total:      result.Total,
+		TotalPages: result.TotalPages,
+		HasMore:    result.HasMore,
+	}
+	for i, doc := range result.Items {
+		raw, ok := doc.Fields["$"]
+		if !ok {
+			return nil, fmt.Errorf("search %s: document %s has no json content, index must be created with OnJSON", index, doc.ID)
+		}
+		if err := json.Unmarshal([]byte(raw), &typed.Items[i]); err != nil {
+			return nil, fmt.Errorf("search %s: unmarshal %s: %w", index, doc.ID, err)
+		}
+	}
+	return typed, nil
+}
+
+// parseSearchReply 解析 FT.SEARCH 的响应：
+// RESP2 为 [total, id1, [field, value, ...], id2, ...]，RESP3 为 {total_results, results: [{id, extra_attributes}]}
+func parseSearchReply(reply any) (int64, []SearchDocument, error) {
+	switch v := reply.(type) {
+	case []any:
+		if len(v) == 0 {
+			return 0, nil, fmt.Errorf("empty search reply")
+		}
+		total, _ := v[0].(int64)
+		docs := make([]SearchDocument, 0, (len(v)-1)/2)
+		for i := 1; i < len(v); i += 2 {
+			doc := SearchDocument{ID: fmt.Sprint(v[i])}
+			if i+1 < len(v) {
+				doc.Fields = searchFields(v[i+1])
+			}
+			docs = append(docs, doc)
+		}
+		return total, docs, nil
+	case map[any]any:
+		total, _ := v["total_results"].(int64)
+		results, _ := v["results"].([]any)
+		docs := make([]SearchDocument, 0, len(results))
+		for _, r := range results {
+			m, ok := r.(map[any]any)
+			if !ok {
+				continue
+			}
+			docs = append(docs, SearchDocument{ID: fmt.Sprint(m["id"]), Fields: searchFields(m["extra_attributes"])})
+		}
+		return total, docs, nil
+	default:
+		return 0, nil, fmt.Errorf("unexpected search reply type %T", reply)
+	}
+}
+
+// searchFields 解析文档字段，RESP2 为 [field, value, ...]，RESP3 为 map
+func searchFields(v any) map[string]string {
+	fields := make(map[string]string)
+	switch f := v.(type) {
+	case []any:
+		for i := 0; i+1 < len(f); i += 2 {
+			fields[fmt.Sprint(f[i])] = fmt.Sprint(f[i+1])
+		}
+	case map[any]any:
+		for k, val := range f {
+			fields[fmt.Sprint(k)] = fmt.Sprint(val)
+		}
+	}
+	return fields
+}
+
+// escapeSearch 转义查询语法中的特殊字符
+func escapeSearch(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(searchSpecialChars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// searchNumber 格式化范围查询的边界
+func searchNumber(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,465 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+	"github.com/go-anyway/framework-log"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// reloadCloseDelay 重建连接后延迟关闭旧连接的时间，让进行中的请求有机会完成
+const reloadCloseDelay = 30 * time.Second
+
+// ErrHandleHeld 热更新需要重建连接，但 Add* 返回的句柄可能仍被调用方持有，重建后关闭旧连接会使其不可用，见 Manager.ReleaseHandle
+var ErrHandleHeld = errors.New("reload requires reconnecting a handle that may still be held by the caller")
+
+// ReloadEvent 描述一次热更新实际应用的变更
+type ReloadEvent struct {
+	Name        string   // 连接名称
+	Driver      string   // mysql、postgresql 或 redis
+	Changes     []string // 变更项，如 "max_connections: 100 -> 200"
+	Reconnected bool     // 是否因连接级配置变化而重建了连接
+}
+
+// OnReload 注册热更新回调，每次成功应用配置变更后调用
+func (m *Manager) OnReload(fn func(ReloadEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onReload = append(m.onReload, fn)
+}
+
+// ReloadMySQL 使用新配置更新指定的 MySQL 连接
+// 仅 max_connections、timeout、log_level 变化时原地生效，其他任何字段变化都会重建连接；
+// 未调用 ReleaseHandle 时不重建连接，返回 ErrHandleHeld 且不应用任何变更
+func (m *Manager) ReloadMySQL(name string, cfg *MySQLConfig) error {
+	cfg, err := profiledMySQLConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	m.mu.RLock()
+	conn := m.sql[name]
+	var old *MySQLConfig
+	var held bool
+	if conn != nil {
+		old, held = conn.mysql, conn.held
+	}
+	m.mu.RUnlock()
+	if old == nil {
+		return fmt.Errorf("mysql connection %q not found", name)
+	}
+	event := ReloadEvent{Name: name, Driver: driverMySQL, Changes: diffConfig(old, cfg)}
+	if len(event.Changes) == 0 {
+		return nil
+	}
+
+	// 在锁外建立新连接，拨号和 Ping 期间不阻塞 DB 等读取
+	var db *gorm.DB
+	if !mySQLPoolOnlyChanged(old, cfg) {
+		if held {
+			return fmt.Errorf("mysql %q: %w", name, ErrHandleHeld)
+		}
+		opts, err := cfg.ToOptions()
+		if err != nil {
+			return err
+		}
+		if db, err = New(opts); err != nil {
+			return fmt.Errorf("failed to reconnect mysql %q: %w", name, err)
+		}
+		event.Reconnected = true
+	}
+
+	callbacks, err := m.commitSQLReload(conn, db, cfg.MaxConnections, cfg.Timeout.Duration(), cfg.LogLevel, func() {
+		conn.mysql = cfg
+	})
+	if err != nil {
+		return err
+	}
+	emitReloadEvent(event, callbacks)
+	return nil
+}
+
+// ReloadPostgreSQL 使用新配置更新指定的 PostgreSQL 连接
+// 仅 max_connections、timeout、log_level 变化时原地生效，其他任何字段变化都会重建连接；
+// 未调用 ReleaseHandle 时不重建连接，返回 ErrHandleHeld 且不应用任何变更
+func (m *Manager) ReloadPostgreSQL(name string, cfg *PostgreSQLConfig) error {
+	cfg, err := profiledPostgreSQLConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	m.mu.RLock()
+	conn := m.sql[name]
+	var old *PostgreSQLConfig
+	var held bool
+	if conn != nil {
+		old, held = conn.pg, conn.held
+	}
+	m.mu.RUnlock()
+	if old == nil {
+		return fmt.Errorf("postgresql connection %q not found", name)
+	}
+	event := ReloadEvent{Name: name, Driver: driverPostgreSQL, Changes: diffConfig(old, cfg)}
+	if len(event.Changes) == 0 {
+		return nil
+	}
+
+	var db *gorm.DB
+	if !postgreSQLPoolOnlyChanged(old, cfg) {
+		if held {
+			return fmt.Errorf("postgresql %q: %w", name, ErrHandleHeld)
+		}
+		opts, err := cfg.ToOptions()
+		if err != nil {
+			return err
+		}
+		if db, err = NewPostgreSQL(opts); err != nil {
+			return fmt.Errorf("failed to reconnect postgresql %q: %w", name, err)
+		}
+		event.Reconnected = true
+	}
+
+	callbacks, err := m.commitSQLReload(conn, db, cfg.MaxConnections, cfg.Timeout.Duration(), cfg.LogLevel, func() {
+		conn.pg = cfg
+	})
+	if err != nil {
+		return err
+	}
+	emitReloadEvent(event, callbacks)
+	return nil
+}
+
+// commitSQLReload 在锁内替换重建的连接（db 不为 nil 时），否则原地调整连接池和日志级别，
+// 成功后调用 store 保存新配置并返回热更新回调。连接在重建期间被注销时关闭新连接
+func (m *Manager) commitSQLReload(conn *sqlConn, db *gorm.DB, maxConnections int, lifetime time.Duration, level string, store func()) ([]func(ReloadEvent), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sql[conn.name] != conn {
+		if db != nil {
+			closeGormDB(db)
+		}
+		return nil, fmt.Errorf("%s connection %q not found", conn.driver, conn.name)
+	}
+
+	if db != nil {
+		closeGormDBLater(conn.db)
+		conn.db = db
+		trackGormDB(db, conn.driver+":"+conn.name)
+	} else {
+		if err := applySQLPool(conn.db, maxConnections, lifetime); err != nil {
+			return nil, err
+		}
+		applyLogLevel(conn.db, level)
+	}
+	store()
+	return m.onReload, nil
+}
+
+// ReloadRedis 使用新配置更新指定的 Redis 客户端
+// go-redis 客户端的选项在连接池中被并发读取，创建后不可修改，除 max_databases、health_check 等不影响客户端的字段外，
+// 任何变化（包括超时）都会重建客户端；未调用 ReleaseHandle 时不重建客户端，返回 ErrHandleHeld 且不应用任何变更
+func (m *Manager) ReloadRedis(name string, cfg *RedisConfig) error {
+	cfg, err := profiledRedisConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	m.mu.RLock()
+	conn := m.redis[name]
+	var old *RedisConfig
+	var held bool
+	if conn != nil {
+		old, held = conn.cfg, conn.held
+	}
+	m.mu.RUnlock()
+	if conn == nil {
+		return fmt.Errorf("redis connection %q not found", name)
+	}
+	event := ReloadEvent{Name: name, Driver: "redis", Changes: diffConfig(old, cfg)}
+	if len(event.Changes) == 0 {
+		return nil
+	}
+
+	// 在锁外创建新客户端，拨号和 Ping 期间不阻塞 Redis 等读取
+	var client *redis.Client
+	if !redisClientUnchanged(old, cfg) {
+		if held {
+			return fmt.Errorf("redis %q: %w", name, ErrHandleHeld)
+		}
+		opts, err := cfg.ToOptions()
+		if err != nil {
+			return err
+		}
+		if client, err = NewRedis(opts); err != nil {
+			return fmt.Errorf("failed to reconnect redis %q: %w", name, err)
+		}
+		event.Reconnected = true
+	}
+
+	m.mu.Lock()
+	if m.redis[name] != conn {
+		m.mu.Unlock()
+		if client != nil {
+			_ = client.Close()
+		}
+		return fmt.Errorf("redis connection %q not found", name)
+	}
+	if client != nil {
+		oldClient := conn.client
+		time.AfterFunc(reloadCloseDelay, func() { _ = CloseRedis(oldClient) })
+		conn.client = client
+		trackRedis(client, "redis:"+name)
+		if conn.health != nil {
+			conn.health.setClient(client)
+		}
+	}
+	conn.cfg = cfg
+	callbacks := m.onReload
+	m.mu.Unlock()
+
+	emitReloadEvent(event, callbacks)
+	return nil
+}
+
+// applySQLPool 原地调整 sql.DB 连接池参数
+func applySQLPool(db *gorm.DB, maxConnections int, lifetime time.Duration) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(maxConnections)
+	sqlDB.SetMaxIdleConns(maxConnections / 10) // 与 ToOptions 保持一致，空闲连接数为最大连接数的 10%
+	if lifetime > 0 {
+		sqlDB.SetConnMaxLifetime(lifetime)
+	}
+	return nil
+}
+
+// applyLogLevel 原地调整 GORM 日志级别。db.Config.Logger 被执行中的语句并发读取，
+// 只调整 New、NewPostgreSQL 创建的可调整级别的日志器，不替换 Logger 字段
+func applyLogLevel(db *gorm.DB, level string) {
+	if l, ok := db.Config.Logger.(*levelLogger); ok {
+		l.setLevel(logLevel(level))
+	}
+}
+
+// mySQLPoolOnlyChanged 判断两个 MySQL 配置是否只有可原地生效的字段（连接池大小、连接最大存活时间、日志级别）不同
+func mySQLPoolOnlyChanged(oldCfg, newCfg *MySQLConfig) bool {
+	a, b := *oldCfg, *newCfg
+	// 预设已应用到各字段
+	a.Profile, a.MaxConnections, a.Timeout, a.LogLevel = b.Profile, b.MaxConnections, b.Timeout, b.LogLevel
+	a.explicit, b.explicit = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// postgreSQLPoolOnlyChanged 判断两个 PostgreSQL 配置是否只有可原地生效的字段（连接池大小、连接最大存活时间、日志级别）不同
+func postgreSQLPoolOnlyChanged(oldCfg, newCfg *PostgreSQLConfig) bool {
+	a, b := *oldCfg, *newCfg
+	a.Profile, a.MaxConnections, a.Timeout, a.LogLevel = b.Profile, b.MaxConnections, b.Timeout, b.LogLevel
+	a.explicit, b.explicit = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// redisClientUnchanged 判断两个 Redis 配置是否创建相同的客户端，即仅有不影响客户端的字段不同
+func redisClientUnchanged(oldCfg, newCfg *RedisConfig) bool {
+	a, b := *oldCfg, *newCfg
+	// 预设已应用到各字段；max_databases 只用于校验；健康检查只在注册时启动
+	a.Profile, a.MaxDatabases, a.HealthCheck = b.Profile, b.MaxDatabases, b.HealthCheck
+	a.explicit, b.explicit = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// closeGormDBLater 延迟关闭旧连接
+func closeGormDBLater(db *gorm.DB) {
+	time.AfterFunc(reloadCloseDelay, func() { closeGormDB(db) })
+}
+
+// emitReloadEvent 记录并分发热更新事件
+func emitReloadEvent(event ReloadEvent, callbacks []func(ReloadEvent)) {
+	log.Info("Database config reloaded",
+		zap.String("name", event.Name),
+		zap.String("driver", event.Driver),
+		zap.Strings("changes", event.Changes),
+		zap.Bool("reconnected", event.Reconnected),
+	)
+	for _, fn := range callbacks {
+		fn(event)
+	}
+}
+
+// diffConfig 比较两个同类型配置结构体，返回变化的字段（密码类字段不输出具体值）
+func diffConfig(oldCfg, newCfg interface{}) []string {
+	ov := reflect.ValueOf(oldCfg).Elem()
+	nv := reflect.ValueOf(newCfg).Elem()
+	t := ov.Type()
+
+	var changes []string
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		of, nf := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if reflect.DeepEqual(of, nf) {
+			continue
+		}
+		key := yamlKey(t.Field(i))
+		if strings.Contains(key, "password") {
+			changes = append(changes, key+": <redacted>")
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s: %v -> %v", key, formatConfigValue(of), formatConfigValue(nf)))
+	}
+	return changes
+}
+
+// formatConfigValue 格式化配置值用于输出
+func formatConfigValue(v interface{}) interface{} {
+	if d, ok := v.(pkgConfig.Duration); ok {
+		return d.Duration()
+	}
+	return v
+}
+
+// yamlKey 返回字段的 YAML 键名
+func yamlKey(f reflect.StructField) string {
+	if tag := strings.Split(f.Tag.Get("yaml"), ",")[0]; tag != "" && tag != "-" {
+		return tag
+	}
+	return f.Name
+}
+
+// PoolReloader 连接配置重载器（实现 framework-config 的 hotreload.Reloader 接口）
+// 需要重建连接的变更只在调用 Manager.ReleaseHandle 之后生效，否则 OnChange 返回 ErrHandleHeld
+type PoolReloader struct {
+	manager *Manager
+	module  string // 配置模块前缀，如 "mysql"
+	name    string // 管理器中的连接名称
+}
+
+// NewPoolReloader 创建连接配置重载器
+// module: 配置键前缀，如 "mysql"、"postgresql"、"redis"
+// name: 管理器中注册的连接名称
+func NewPoolReloader(manager *Manager, module, name string) *PoolReloader {
+	return &PoolReloader{
+		manager: manager,
+		module:  module,
+		name:    name,
+	}
+}
+
+// Patterns 返回配置键模式列表（实现 hotreload.Reloader 接口）
+func (r *PoolReloader) Patterns() []string {
+	cfg, err := r.currentConfig()
+	if err != nil {
+		return nil
+	}
+	t := reflect.TypeOf(cfg).Elem()
+	patterns := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		patterns = append(patterns, r.module+"."+yamlKey(t.Field(i)))
+	}
+	return patterns
+}
+
+// OnChange 配置变更回调（实现 hotreload.Reloader 接口）
+func (r *PoolReloader) OnChange(key, oldValue, newValue string) error {
+	cfg, err := r.applyChange(key, newValue)
+	if err != nil {
+		return err
+	}
+	switch c := cfg.(type) {
+	case *MySQLConfig:
+		return r.manager.ReloadMySQL(r.name, c)
+	case *PostgreSQLConfig:
+		return r.manager.ReloadPostgreSQL(r.name, c)
+	case *RedisConfig:
+		return r.manager.ReloadRedis(r.name, c)
+	default:
+		return fmt.Errorf("unsupported config type %T", cfg)
+	}
+}
+
+// Validate 验证配置值
+func (r *PoolReloader) Validate(key, value string) error {
+	cfg, err := r.applyChange(key, value)
+	if err != nil {
+		return err
+	}
+	return cfg.(interface{ Validate() error }).Validate()
+}
+
+// applyChange 复制当前配置并设置变更的字段
+func (r *PoolReloader) applyChange(key, value string) (interface{}, error) {
+	field := strings.TrimPrefix(key, r.module+".")
+	if field == key {
+		return nil, fmt.Errorf("key %s does not belong to module %s", key, r.module)
+	}
+	cfg, err := r.currentConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := pkgConfig.SetFieldByPath(cfg, field, value); err != nil {
+		return nil, fmt.Errorf("invalid value for key %s: %w", key, err)
+	}
+	// 热更新的值优先于预设
+	cfg.(interface{ MarkExplicit(...string) }).MarkExplicit(strings.Split(field, ".")[0])
+	return cfg, nil
+}
+
+// currentConfig 返回管理器中连接当前配置的副本
+func (r *PoolReloader) currentConfig() (interface{}, error) {
+	r.manager.mu.RLock()
+	defer r.manager.mu.RUnlock()
+	if conn, ok := r.manager.sql[r.name]; ok {
+		if conn.mysql != nil {
+			return cloneMySQLConfig(conn.mysql), nil
+		}
+		return clonePostgreSQLConfig(conn.pg), nil
+	}
+	if conn, ok := r.manager.redis[r.name]; ok {
+		return cloneRedisConfig(conn.cfg), nil
+	}
+	return nil, fmt.Errorf("connection %q not found", r.name)
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pkgConfig "github.com/go-anyway/framework-config"
+)
+
+func TestDiffConfig(t *testing.T) {
+	base := func() *RedisConfig {
+		c := &RedisConfig{}
+		pkgConfig.ApplyDefaults(c)
+		return c
+	}
+	tests := []struct {
+		name   string
+		change func(c *RedisConfig)
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			change: func(*RedisConfig) {},
+		},
+		{
+			name:   "plain fields",
+			change: func(c *RedisConfig) { c.PoolSize, c.Host = 50, "cache.internal" },
+			want:   []string{"host: localhost -> cache.internal", "pool_size: 20 -> 50"},
+		},
+		{
+			name:   "durations are formatted",
+			change: func(c *RedisConfig) { c.ReadTimeout = pkgConfig.Duration(time.Second) },
+			want:   []string{"read_timeout: 3s -> 1s"},
+		},
+		{
+			name:   "passwords are redacted",
+			change: func(c *RedisConfig) { c.Password, c.PasswordFile = "secret", "/run/secrets/redis" },
+			want:   []string{"password: <redacted>", "password_file: <redacted>"},
+		},
+		{
+			name:   "unexported fields are ignored",
+			change: func(c *RedisConfig) { c.MarkExplicit("pool_size") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCfg, newCfg := base(), base()
+			tt.change(newCfg)
+			if got := diffConfig(oldCfg, newCfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffConfig() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisClientUnchanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *RedisConfig)
+		want   bool
+	}{
+		{"unchanged", func(*RedisConfig) {}, true},
+		{"profile", func(c *RedisConfig) { c.Profile = ProfileProd }, true},
+		{"max databases", func(c *RedisConfig) { c.MaxDatabases = 32 }, true},
+		{"health check", func(c *RedisConfig) { c.HealthCheck.Enabled = true }, true},
+		{"explicit keys", func(c *RedisConfig) { c.MarkExplicit("pool_size") }, true},
+		{"read timeout", func(c *RedisConfig) { c.ReadTimeout = pkgConfig.Duration(time.Second) }, false},
+		{"write timeout", func(c *RedisConfig) { c.WriteTimeout = pkgConfig.Duration(time.Second) }, false},
+		{"pool size", func(c *RedisConfig) { c.PoolSize = 50 }, false},
+		{"trace", func(c *RedisConfig) { c.EnableTrace = !c.EnableTrace }, false},
+		{"log skip commands", func(c *RedisConfig) { c.LogSkipCommands = []string{"ping"} }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCfg, newCfg := &RedisConfig{}, &RedisConfig{}
+			pkgConfig.ApplyDefaults(oldCfg)
+			pkgConfig.ApplyDefaults(newCfg)
+			tt.change(newCfg)
+			if got := redisClientUnchanged(oldCfg, newCfg); got != tt.want {
+				t.Errorf("redisClientUnchanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQLPoolOnlyChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *MySQLConfig)
+		want   bool
+	}{
+		{"unchanged", func(*MySQLConfig) {}, true},
+		{"max connections", func(c *MySQLConfig) { c.MaxConnections = 200 }, true},
+		{"timeout", func(c *MySQLConfig) { c.Timeout = pkgConfig.Duration(time.Minute) }, true},
+		{"log level", func(c *MySQLConfig) { c.LogLevel = "warn" }, true},
+		{"profile", func(c *MySQLConfig) { c.Profile = ProfileProd }, true},
+		{"explicit keys", func(c *MySQLConfig) { c.MarkExplicit("log_level") }, true},
+		{"host", func(c *MySQLConfig) { c.Host = "db2" }, false},
+		{"hosts", func(c *MySQLConfig) { c.Hosts = []string{"db1:3306", "db2:3306"} }, false},
+		{"session vars", func(c *MySQLConfig) { c.SessionVars = map[string]string{"sql_mode": "ANSI"} }, false},
+		{"params", func(c *MySQLConfig) { c.Params = map[string]string{"interpolateParams": "true"} }, false},
+		{"tidb", func(c *MySQLConfig) { c.TiDB = true }, false},
+		{"query timeout", func(c *MySQLConfig) { c.QueryTimeout = pkgConfig.Duration(time.Second) }, false},
+		{"force utc", func(c *MySQLConfig) { c.ForceUTC = true }, false},
+		{"leak detection", func(c *MySQLConfig) { c.LeakDetectionThreshold = pkgConfig.Duration(time.Minute) }, false},
+		{"tunnel", func(c *MySQLConfig) { c.Tunnel.Proxy = "socks5://bastion:1080" }, false},
+		{"azure ad", func(c *MySQLConfig) { c.AzureAD.Enabled = true }, false},
+		{"trace", func(c *MySQLConfig) { c.EnableTrace = !c.EnableTrace }, false},
+		{"password file", func(c *MySQLConfig) { c.PasswordFile = "/run/secrets/mysql" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCfg, newCfg := &MySQLConfig{}, &MySQLConfig{}
+			pkgConfig.ApplyDefaults(oldCfg)
+			pkgConfig.ApplyDefaults(newCfg)
+			tt.change(newCfg)
+			if got := mySQLPoolOnlyChanged(oldCfg, newCfg); got != tt.want {
+				t.Errorf("mySQLPoolOnlyChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgreSQLPoolOnlyChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *PostgreSQLConfig)
+		want   bool
+	}{
+		{"unchanged", func(*PostgreSQLConfig) {}, true},
+		{"max connections", func(c *PostgreSQLConfig) { c.MaxConnections = 200 }, true},
+		{"timeout", func(c *PostgreSQLConfig) { c.Timeout = pkgConfig.Duration(time.Minute) }, true},
+		{"log level", func(c *PostgreSQLConfig) { c.LogLevel = "error" }, true},
+		{"host", func(c *PostgreSQLConfig) { c.Host = "pg2" }, false},
+		{"statement timeout", func(c *PostgreSQLConfig) { c.StatementTimeout = pkgConfig.Duration(time.Second) }, false},
+		{"default query exec mode", func(c *PostgreSQLConfig) { c.DefaultQueryExecMode = "simple_protocol" }, false},
+		{"connect timeout", func(c *PostgreSQLConfig) { c.ConnectTimeout = pkgConfig.Duration(time.Second) }, false},
+		{"cockroach", func(c *PostgreSQLConfig) { c.Cockroach = true }, false},
+		{"session vars", func(c *PostgreSQLConfig) { c.SessionVars = map[string]string{"search_path": "app"} }, false},
+		{"schema", func(c *PostgreSQLConfig) { c.Schema = "app" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCfg, newCfg := &PostgreSQLConfig{}, &PostgreSQLConfig{}
+			pkgConfig.ApplyDefaults(oldCfg)
+			pkgConfig.ApplyDefaults(newCfg)
+			tt.change(newCfg)
+			if got := postgreSQLPoolOnlyChanged(oldCfg, newCfg); got != tt.want {
+				t.Errorf("postgreSQLPoolOnlyChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
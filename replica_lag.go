@@ -0,0 +1,393 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// ReplicaLagOptions 副本延迟探测配置选项
+type ReplicaLagOptions struct {
+	Interval       time.Duration // 探测间隔，默认 5s
+	Timeout        time.Duration // 单个副本的探测超时，默认 2s
+	MaxLag         time.Duration // 延迟超过该值或探测失败的副本移出读取路由，追上后恢复；0 表示只采集指标
+	HeartbeatTable string        // 心跳表，设置后在主库写入心跳并在副本读取计算延迟；为空时使用数据库自身的复制状态
+}
+
+// ReplicaHeartbeat 心跳表记录
+type ReplicaHeartbeat struct {
+	ID     uint      `gorm:"primaryKey;autoIncrement:false"`
+	BeatAt time.Time `gorm:"not null;precision:6"`
+}
+
+// replicaState 单个副本的探测状态
+type replicaState struct {
+	name      string
+	db        *gorm.DB
+	pool      gorm.ConnPool
+	lag       time.Duration
+	probed    bool // 是否探测成功过
+	available bool
+}
+
+// ReplicaLagMonitor 副本延迟探测：定时测量每个副本的复制延迟并导出指标，
+// 通过 Policy 包装 dbresolver 的负载均衡策略，将延迟过大的副本移出读取路由。
+// 延迟测量方式：
+//   - 心跳表：主库写入当前时间，副本读取后与当前时间比较，适用于任意复制拓扑
+//   - MySQL：SHOW REPLICA STATUS（旧版本为 SHOW SLAVE STATUS）的 Seconds_Behind_Source，精度为秒
+//   - PostgreSQL：已回放全部 WAL 时为 0，否则为 now() - pg_last_xact_replay_timestamp()
+type ReplicaLagMonitor struct {
+	primary *gorm.DB
+	opts    ReplicaLagOptions
+
+	mu       sync.RWMutex
+	replicas []*replicaState
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewReplicaLagMonitor 创建副本延迟探测，使用心跳表时 primary 不能为空
+func NewReplicaLagMonitor(primary *gorm.DB, opts *ReplicaLagOptions) (*ReplicaLagMonitor, error) {
+	m := &ReplicaLagMonitor{primary: primary}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.HeartbeatTable != "" && primary == nil {
+		return nil, fmt.Errorf("primary cannot be nil when heartbeat table is set")
+	}
+	if m.opts.Interval <= 0 {
+		m.opts.Interval = 5 * time.Second
+	}
+	if m.opts.Timeout <= 0 {
+		m.opts.Timeout = 2 * time.Second
+	}
+	return m, nil
+}
+
+// AddReplica 登记副本，返回与该副本共享连接池的 Dialector，用于 dbresolver.Config.Replicas
+//
+//	replica, _ := db.New(replicaOpts)
+//	dialector, _ := monitor.AddReplica("replica-1", replica)
+//	primary.Use(dbresolver.Register(dbresolver.Config{
+//		Replicas: []gorm.Dialector{dialector},
+//		Policy:   monitor.Policy(dbresolver.RandomPolicy{}),
+//	}))
+func (m *ReplicaLagMonitor) AddReplica(name string, replica *gorm.DB) (gorm.Dialector, error) {
+	if replica == nil {
+		return nil, fmt.Errorf("replica cannot be nil")
+	}
+	sqlDB, err := replica.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replica sql.DB: %w", err)
+	}
+
+	var dialector gorm.Dialector
+	switch dialectOf(replica) {
+	case "mysql":
+		dialector = mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true})
+	case "postgres":
+		dialector = postgres.New(postgres.Config{Conn: sqlDB})
+	default:
+		return nil, fmt.Errorf("unsupported replica dialect %q", replica.Dialector.Name())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.replicas {
+		if r.name == name {
+			return nil, fmt.Errorf("replica %q already registered", name)
+		}
+	}
+	m.replicas = append(m.replicas, &replicaState{name: name, db: replica, pool: sqlDB, available: true})
+	return dialector, nil
+}
+
+// Lag 返回副本最近一次测得的延迟，未登记或尚未探测成功时返回 false
+func (m *ReplicaLagMonitor) Lag(name string) (time.Duration, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, r := range m.replicas {
+		if r.name == name {
+			return r.lag, r.probed
+		}
+	}
+	return 0, false
+}
+
+// Policy 包装 dbresolver 的负载均衡策略，只在可用的副本中选择；
+// 所有副本都不可用时仍在全部副本中选择，避免读取全部落到主库
+func (m *ReplicaLagMonitor) Policy(inner dbresolver.Policy) dbresolver.Policy {
+	if inner == nil {
+		inner = dbresolver.RandomPolicy{}
+	}
+	return dbresolver.PolicyFunc(func(pools []gorm.ConnPool) gorm.ConnPool {
+		m.mu.RLock()
+		available := make([]gorm.ConnPool, 0, len(pools))
+		for _, pool := range pools {
+			if m.available(pool) {
+				available = append(available, pool)
+			}
+		}
+		m.mu.RUnlock()
+		if len(available) == 0 {
+			return inner.Resolve(pools)
+		}
+		return inner.Resolve(available)
+	})
+}
+
+// available 判断连接池是否可用，未登记的连接池视为可用（调用方需持有读锁）
+func (m *ReplicaLagMonitor) available(pool gorm.ConnPool) bool {
+	for _, r := range m.replicas {
+		if r.pool == pool {
+			return r.available
+		}
+	}
+	return true
+}
+
+// Start 启动定时探测，启动时立即探测一次
+func (m *ReplicaLagMonitor) Start(ctx context.Context) error {
+	if m.opts.HeartbeatTable != "" {
+		if err := m.primary.WithContext(ctx).Table(m.opts.HeartbeatTable).AutoMigrate(&ReplicaHeartbeat{}); err != nil {
+			return fmt.Errorf("failed to create heartbeat table: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		return fmt.Errorf("replica lag monitor already started")
+	}
+	ctx, m.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.opts.Interval)
+		defer ticker.Stop()
+		for {
+			if err := m.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				log.FromContext(ctx).Warn("Replica lag probe failed", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止定时探测
+func (m *ReplicaLagMonitor) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce 探测所有副本的延迟并更新可用状态，返回的错误包含所有探测失败的副本
+func (m *ReplicaLagMonitor) RunOnce(ctx context.Context) error {
+	if m.opts.HeartbeatTable != "" {
+		beat := &ReplicaHeartbeat{ID: 1, BeatAt: time.Now().UTC()}
+		if err := m.primary.WithContext(ctx).Table(m.opts.HeartbeatTable).
+			Clauses(clause.OnConflict{UpdateAll: true}).Create(beat).Error; err != nil {
+			return fmt.Errorf("failed to write heartbeat: %w", err)
+		}
+	}
+
+	m.mu.RLock()
+	replicas := append([]*replicaState(nil), m.replicas...)
+	m.mu.RUnlock()
+
+	var errs []error
+	for _, r := range replicas {
+		probeCtx, cancel := context.WithTimeout(ctx, m.opts.Timeout)
+		lag, err := m.probe(probeCtx, r.db)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("replica %s: %w", r.name, err))
+		}
+		m.update(ctx, r, lag, err)
+	}
+	return errors.Join(errs...)
+}
+
+// update 记录探测结果，可用状态变化时输出日志
+func (m *ReplicaLagMonitor) update(ctx context.Context, r *replicaState, lag time.Duration, err error) {
+	available := m.opts.MaxLag <= 0 || (err == nil && lag <= m.opts.MaxLag)
+
+	m.mu.Lock()
+	changed := r.available != available
+	r.available = available
+	if err == nil {
+		r.lag, r.probed = lag, true
+	}
+	m.mu.Unlock()
+
+	if metrics.IsEnabled() {
+		if err == nil {
+			DatabaseReplicaLag.WithLabelValues(r.name).Set(lag.Seconds())
+		}
+		value := 0.0
+		if available {
+			value = 1
+		}
+		DatabaseReplicaAvailable.WithLabelValues(r.name).Set(value)
+	}
+	if !changed {
+		return
+	}
+	if available {
+		log.FromContext(ctx).Info("Replica caught up, back in read rotation",
+			zap.String("replica", r.name), zap.Duration("lag", lag))
+	} else {
+		log.FromContext(ctx).Warn("Replica removed from read rotation",
+			zap.String("replica", r.name), zap.Duration("lag", lag), zap.Duration("max_lag", m.opts.MaxLag), zap.Error(err))
+	}
+}
+
+// probe 测量单个副本的复制延迟
+func (m *ReplicaLagMonitor) probe(ctx context.Context, replica *gorm.DB) (time.Duration, error) {
+	tx := replica.WithContext(ctx)
+	if m.opts.HeartbeatTable != "" {
+		var beat ReplicaHeartbeat
+		if err := tx.Table(m.opts.HeartbeatTable).Where("id = ?", 1).Take(&beat).Error; err != nil {
+			return 0, fmt.Errorf("failed to read heartbeat: %w", err)
+		}
+		return max(time.Since(beat.BeatAt), 0), nil
+	}
+
+	switch dialectOf(replica) {
+	case "mysql":
+		return mySQLReplicaLag(tx)
+	case "postgres":
+		var recovery bool
+		var seconds sql.NullFloat64
+		err := tx.Raw(`SELECT pg_is_in_recovery(),
+			CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END`).
+			Row().Scan(&recovery, &seconds)
+		if err != nil {
+			return 0, err
+		}
+		if !recovery {
+			return 0, fmt.Errorf("not a standby")
+		}
+		return time.Duration(seconds.Float64 * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("unsupported replica dialect %q", replica.Dialector.Name())
+	}
+}
+
+// mySQLReplicaLag 读取 MySQL 复制状态中的延迟秒数，MySQL 8.0.22 起 SHOW SLAVE STATUS 更名为 SHOW REPLICA STATUS
+func mySQLReplicaLag(tx *gorm.DB) (time.Duration, error) {
+	var err error
+	for _, query := range []string{"SHOW REPLICA STATUS", "SHOW SLAVE STATUS"} {
+		var status map[string]any
+		if status, err = showStatus(tx, query); err != nil {
+			continue
+		}
+		if status == nil {
+			return 0, fmt.Errorf("not a replica")
+		}
+		for _, column := range []string{"Seconds_Behind_Source", "Seconds_Behind_Master"} {
+			v, ok := status[column]
+			if !ok {
+				continue
+			}
+			if v == nil {
+				return 0, fmt.Errorf("replication is not running")
+			}
+			seconds, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s %q", column, v)
+			}
+			return time.Duration(seconds) * time.Second, nil
+		}
+		return 0, fmt.Errorf("unexpected result of %s", query)
+	}
+	return 0, err
+}
+
+// showStatus 执行 SHOW 语句，返回第一行的列名到值的映射，没有结果时返回 nil
+func showStatus(tx *gorm.DB, query string) (map[string]any, error) {
+	rows, err := tx.Raw(query).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	status := make(map[string]any, len(columns))
+	for i, column := range columns {
+		if values[i] != nil {
+			status[column] = string(values[i])
+		} else {
+			status[column] = nil
+		}
+	}
+	return status, nil
+}
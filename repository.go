@@ -0,0 +1,151 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository 通用仓储，提供按主键的增删改查、条件查询、分页和 upsert
+// 方法优先使用 ctx 中由 TxManager 开启的事务；返回的错误经过 NormalizeError 转换，
+// 记录不存在时返回 ErrNotFound，违反唯一约束时返回 ErrDuplicateKey
+type Repository[T any] struct {
+	db    *gorm.DB
+	model string
+}
+
+// NewRepository 创建仓储
+func NewRepository[T any](db *gorm.DB) *Repository[T] {
+	var zero T
+	return &Repository[T]{db: db, model: fmt.Sprintf("%T", zero)}
+}
+
+// DB 返回当前 ctx 使用的连接（事务中时返回事务），用于仓储未覆盖的查询
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return dbFromContext(ctx, r.db).Model(new(T))
+}
+
+// GetByID 按主键查询
+func (r *Repository[T]) GetByID(ctx context.Context, id any) (*T, error) {
+	ctx, end := r.startSpan(ctx, "get_by_id")
+	entity := new(T)
+	err := dbFromContext(ctx, r.db).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Take(entity).Error
+	if err != nil {
+		return nil, end(err)
+	}
+	return entity, end(nil)
+}
+
+// Find 查询满足条件的所有记录，scopes 用于添加过滤、排序等条件
+func (r *Repository[T]) Find(ctx context.Context, scopes ...func(*gorm.DB) *gorm.DB) ([]T, error) {
+	ctx, end := r.startSpan(ctx, "find")
+	var items []T
+	err := dbFromContext(ctx, r.db).Scopes(scopes...).Find(&items).Error
+	return items, end(err)
+}
+
+// List 偏移分页查询满足条件的记录
+func (r *Repository[T]) List(ctx context.Context, p *Paginator, page, size int, scopes ...func(*gorm.DB) *gorm.DB) (*Page[T], error) {
+	ctx, end := r.startSpan(ctx, "list")
+	result, err := Paginate[T](ctx, dbFromContext(ctx, r.db).Model(new(T)).Scopes(scopes...), p, page, size)
+	return result, end(err)
+}
+
+// ListAfter 游标分页查询满足条件的记录
+func (r *Repository[T]) ListAfter(ctx context.Context, p *Paginator, cursor string, size int, scopes ...func(*gorm.DB) *gorm.DB) (*Page[T], error) {
+	ctx, end := r.startSpan(ctx, "list_after")
+	result, err := PaginateCursor[T](ctx, dbFromContext(ctx, r.db).Model(new(T)).Scopes(scopes...), p, cursor, size)
+	return result, end(err)
+}
+
+// Create 新增记录，自增主键等数据库生成的值会回填到 entity
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	ctx, end := r.startSpan(ctx, "create")
+	return end(dbFromContext(ctx, r.db).Create(entity).Error)
+}
+
+// Update 按主键更新全部字段，包括零值字段，记录不存在时返回 ErrNotFound
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	ctx, end := r.startSpan(ctx, "update")
+	res := dbFromContext(ctx, r.db).Model(entity).Select("*").Updates(entity)
+	if res.Error == nil && res.RowsAffected == 0 {
+		// MySQL 默认返回实际变更的行数，值未变化时也为 0，需确认记录是否存在
+		var count int64
+		if err := dbFromContext(ctx, r.db).Model(new(T)).Where(clause.Eq{Column: clause.PrimaryColumn, Value: primaryKeyOf(ctx, res)}).Count(&count).Error; err != nil {
+			return end(err)
+		}
+		if count == 0 {
+			return end(gorm.ErrRecordNotFound)
+		}
+	}
+	return end(res.Error)
+}
+
+// Delete 按主键删除，模型包含 gorm.DeletedAt 时为软删除，记录不存在时返回 ErrNotFound
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	ctx, end := r.startSpan(ctx, "delete")
+	res := dbFromContext(ctx, r.db).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Delete(new(T))
+	if res.Error == nil && res.RowsAffected == 0 {
+		return end(gorm.ErrRecordNotFound)
+	}
+	return end(res.Error)
+}
+
+// Upsert 新增记录，主键或唯一约束冲突时更新除主键外的所有字段
+func (r *Repository[T]) Upsert(ctx context.Context, entity *T) error {
+	ctx, end := r.startSpan(ctx, "upsert")
+	return end(dbFromContext(ctx, r.db).Clauses(clause.OnConflict{UpdateAll: true}).Create(entity).Error)
+}
+
+// startSpan 创建仓储操作的 span，返回的函数结束 span 并转换错误
+func (r *Repository[T]) startSpan(ctx context.Context, operation string) (context.Context, func(error) error) {
+	ctx, span := pkgtrace.StartSpan(ctx, "repository."+operation,
+		trace.WithAttributes(
+			attribute.String("repository.model", r.model),
+			attribute.String("db.operation", operation),
+		),
+	)
+	return ctx, func(err error) error {
+		err = NormalizeError(err)
+		// 记录不存在属于正常业务结果，不标记为错误
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			span.SetStatus(codes.Error, err.Error())
+			span.RecordError(err)
+		}
+		span.End()
+		return err
+	}
+}
+
+// primaryKeyOf 返回语句所操作实体的主键值
+func primaryKeyOf(ctx context.Context, db *gorm.DB) any {
+	if db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return nil
+	}
+	v, _ := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(ctx, db.Statement.ReflectValue)
+	return v
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrResultTooLarge 查询返回的行数超过了配置的上限
+var ErrResultTooLarge = errors.New("query result too large")
+
+// resultGuard 查询结果行数检查
+type resultGuard struct {
+	maxRows int  // 单次查询允许返回的最大行数
+	abort   bool // 超过时返回 ErrResultTooLarge，否则仅记录告警日志
+}
+
+// register 注册查询结果行数检查拦截器，maxRows <= 0 时不注册
+func (g resultGuard) register(db *gorm.DB) error {
+	if g.maxRows <= 0 {
+		return nil
+	}
+	return Intercept(db, g.interceptor)
+}
+
+// interceptor 在查询执行前追加 LIMIT，执行后检查行数
+func (g resultGuard) interceptor(next Handler) Handler {
+	return func(db *gorm.DB) {
+		if OperationKind(db) != "query" {
+			next(db)
+			return
+		}
+		g.limit(db)
+		next(db)
+		g.check(db)
+	}
+}
+
+// limit 中止模式下为未设置 LIMIT 的查询追加 LIMIT n+1，避免超大结果集全部加载到内存
+func (g resultGuard) limit(db *gorm.DB) {
+	if !g.abort || db.Statement == nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+	if c, ok := db.Statement.Clauses["LIMIT"]; ok {
+		if limit, ok := c.Expression.(clause.Limit); ok && limit.Limit != nil && *limit.Limit > 0 {
+			return
+		}
+	}
+	n := g.maxRows + 1
+	db.Statement.AddClause(clause.Limit{Limit: &n})
+}
+
+// check 检查扫描的行数，超过上限时返回 ErrResultTooLarge 或记录告警日志
+func (g resultGuard) check(db *gorm.DB) {
+	if db.Error != nil || db.Statement == nil || db.Statement.RowsAffected <= int64(g.maxRows) {
+		return
+	}
+
+	if g.abort {
+		_ = db.AddError(fmt.Errorf("%w: more than %d rows", ErrResultTooLarge, g.maxRows))
+		return
+	}
+	log.FromContext(db.Statement.Context).Warn(
+		"Query result exceeds row limit",
+		zap.Int64("rows", db.Statement.RowsAffected),
+		zap.Int("limit", g.maxRows),
+		zap.String("table", db.Statement.Table),
+		zap.String("sql", db.Statement.SQL.String()),
+	)
+}
@@ -0,0 +1,294 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RetentionPolicy 数据保留策略
+type RetentionPolicy struct {
+	Name         string        // 策略名称，用于日志和指标，默认为表名
+	Model        any           // 模型实例，如 &Order{}，需只有一个主键
+	Column       string        // 判断过期的时间列，默认 deleted_at（清理软删除超过 MaxAge 的行）
+	MaxAge       time.Duration // 保留时间，时间列早于 now-MaxAge 的行将被清理
+	ArchiveTable string        // 归档表，设置后删除前将行复制到该表（列需与源表一致），为空时直接删除
+}
+
+// RetentionOptions 保留策略执行器配置选项
+type RetentionOptions struct {
+	Interval      time.Duration // 执行间隔，默认 1h
+	BatchSize     int           // 每批删除的行数，默认 500
+	RowsPerSecond int           // 每秒最多删除的行数，用于降低对线上流量的影响，0 表示不限制
+}
+
+// retentionPolicy 解析后的保留策略
+type retentionPolicy struct {
+	RetentionPolicy
+	table  string
+	pk     string
+	pkType reflect.Type
+	column string
+}
+
+// RetentionPurger 按保留策略分批删除或归档过期数据，适用于 MySQL 和 PostgreSQL
+// 每批在独立事务中执行：按主键选出过期的行，复制到归档表后删除，避免长事务和大范围锁。
+// 多个实例同时运行时可能重复归档，应只在一个实例上启动
+type RetentionPurger struct {
+	db   *gorm.DB
+	opts RetentionOptions
+
+	mu       sync.RWMutex
+	policies []*retentionPolicy
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRetentionPurger 创建保留策略执行器
+func NewRetentionPurger(db *gorm.DB, opts *RetentionOptions) (*RetentionPurger, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	p := &RetentionPurger{db: db}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.Interval <= 0 {
+		p.opts.Interval = time.Hour
+	}
+	if p.opts.BatchSize <= 0 {
+		p.opts.BatchSize = 500
+	}
+	return p, nil
+}
+
+// Register 登记保留策略
+func (p *RetentionPurger) Register(policy RetentionPolicy) error {
+	if policy.Model == nil {
+		return fmt.Errorf("retention model cannot be nil")
+	}
+	if policy.MaxAge <= 0 {
+		return fmt.Errorf("retention max age must be greater than 0, got %s", policy.MaxAge)
+	}
+
+	stmt := &gorm.Statement{DB: p.db}
+	if err := stmt.Parse(policy.Model); err != nil {
+		return fmt.Errorf("retention: %w", err)
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return fmt.Errorf("retention: %s must have exactly one primary key", stmt.Schema.Name)
+	}
+	if policy.Column == "" {
+		policy.Column = "deleted_at"
+	}
+	field := stmt.Schema.LookUpField(policy.Column)
+	if field == nil {
+		return fmt.Errorf("retention: %s has no column %s", stmt.Schema.Name, policy.Column)
+	}
+	if policy.Name == "" {
+		policy.Name = stmt.Schema.Table
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, existing := range p.policies {
+		if existing.Name == policy.Name {
+			return fmt.Errorf("retention policy %q already registered", policy.Name)
+		}
+	}
+	p.policies = append(p.policies, &retentionPolicy{
+		RetentionPolicy: policy,
+		table:           stmt.Schema.Table,
+		pk:              stmt.Schema.PrioritizedPrimaryField.DBName,
+		pkType:          stmt.Schema.PrioritizedPrimaryField.FieldType,
+		column:          field.DBName,
+	})
+	sort.Slice(p.policies, func(i, j int) bool { return p.policies[i].Name < p.policies[j].Name })
+	return nil
+}
+
+// Start 启动定时执行，启动时立即执行一次
+func (p *RetentionPurger) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		return fmt.Errorf("retention purger already started")
+	}
+	ctx, p.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.opts.Interval)
+		defer ticker.Stop()
+		for {
+			if _, err := p.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				log.FromContext(ctx).Error("Retention run failed", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止定时执行，等待正在执行的批次完成
+func (p *RetentionPurger) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	cancel := p.cancel
+	p.cancel = nil
+	p.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce 依次执行所有保留策略，返回每个策略清理的行数
+// 某个策略失败时继续执行其他策略，返回的错误包含所有失败的策略
+func (p *RetentionPurger) RunOnce(ctx context.Context) (map[string]int64, error) {
+	p.mu.RLock()
+	policies := append([]*retentionPolicy(nil), p.policies...)
+	p.mu.RUnlock()
+
+	purged := make(map[string]int64, len(policies))
+	var errs []error
+	for _, policy := range policies {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		start := time.Now()
+		n, err := p.run(ctx, policy)
+		purged[policy.Name] = n
+
+		status := "success"
+		if err != nil {
+			status = "failed"
+			errs = append(errs, fmt.Errorf("retention policy %s: %w", policy.Name, err))
+		}
+		if metrics.IsEnabled() {
+			DatabaseRetentionRunDuration.WithLabelValues(policy.Name, status).Observe(time.Since(start).Seconds())
+		}
+		if n > 0 {
+			log.FromContext(ctx).Info("Retention policy applied",
+				zap.String("policy", policy.Name),
+				zap.Int64("rows", n),
+				zap.Duration("duration", time.Since(start)),
+			)
+		}
+	}
+	return purged, errors.Join(errs...)
+}
+
+// run 分批清理单个策略的过期数据，返回清理的行数
+func (p *RetentionPurger) run(ctx context.Context, policy *retentionPolicy) (int64, error) {
+	cutoff := time.Now().Add(-policy.MaxAge)
+	pk := clause.Column{Name: policy.pk}
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		batchStart := time.Now()
+		var n int64
+		err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// 按主键类型读取，避免 MySQL 文本协议返回的 []byte 与数字列比较
+			ptr := reflect.New(reflect.SliceOf(policy.pkType))
+			if err := tx.Table(policy.table).
+				Where(clause.Lt{Column: clause.Column{Name: policy.column}, Value: cutoff}).
+				Order(clause.OrderByColumn{Column: pk}).
+				Limit(p.opts.BatchSize).
+				Pluck(policy.pk, ptr.Interface()).Error; err != nil {
+				return err
+			}
+			if ptr.Elem().Len() == 0 {
+				return nil
+			}
+			keys := ptr.Elem().Interface()
+
+			if policy.ArchiveTable != "" {
+				if err := tx.Exec("INSERT INTO ? SELECT * FROM ? WHERE ? IN ?",
+					clause.Table{Name: policy.ArchiveTable}, clause.Table{Name: policy.table}, pk, keys).Error; err != nil {
+					return fmt.Errorf("archive: %w", err)
+				}
+			}
+			res := tx.Exec("DELETE FROM ? WHERE ? IN ?", clause.Table{Name: policy.table}, pk, keys)
+			if res.Error != nil {
+				return res.Error
+			}
+			n = res.RowsAffected
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if metrics.IsEnabled() && n > 0 {
+			action := "deleted"
+			if policy.ArchiveTable != "" {
+				action = "archived"
+			}
+			DatabaseRetentionRowsTotal.WithLabelValues(policy.Name, action).Add(float64(n))
+		}
+		if n < int64(p.opts.BatchSize) {
+			return total, nil
+		}
+
+		// 按每秒行数限速
+		if p.opts.RowsPerSecond > 0 {
+			wait := time.Duration(n)*time.Second/time.Duration(p.opts.RowsPerSecond) - time.Since(batchStart)
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+					return total, ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+}
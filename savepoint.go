@@ -0,0 +1,148 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// ErrNotInTx 保存点操作需要在事务中执行
+var ErrNotInTx = errors.New("savepoint requires a transaction")
+
+// savepointNamePattern 保存点名称的合法格式
+var savepointNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Savepoint 在事务 tx 中创建保存点，同名保存点会被覆盖（移动到当前位置）
+func Savepoint(ctx context.Context, tx *gorm.DB, name string) error {
+	return savepointOp(ctx, tx, "savepoint", name, func(tx *gorm.DB) error {
+		return tx.SavePoint(name).Error
+	})
+}
+
+// RollbackTo 回滚到保存点，保存点之后的修改被撤销，保存点本身保留，可再次回滚。
+// PostgreSQL 中语句失败后整个事务处于中止状态，回滚到失败前的保存点后事务可继续使用
+func RollbackTo(ctx context.Context, tx *gorm.DB, name string) error {
+	return savepointOp(ctx, tx, "rollback_to", name, func(tx *gorm.DB) error {
+		return tx.RollbackTo(name).Error
+	})
+}
+
+// ReleaseSavepoint 释放保存点，之后的修改并入外层事务，不支持释放的方言（如 Oracle）不做任何事
+func ReleaseSavepoint(ctx context.Context, tx *gorm.DB, name string) error {
+	return savepointOp(ctx, tx, "release", name, func(tx *gorm.DB) error {
+		switch dialectOf(tx) {
+		case "mysql", "postgres", "sqlite":
+			return tx.Exec("RELEASE SAVEPOINT " + name).Error
+		}
+		return nil
+	})
+}
+
+// WithinSavepoint 在事务 tx 中以保存点包裹 fn：fn 返回错误或 panic 时回滚到保存点，事务的其余部分不受影响，
+// 成功时释放保存点。用于业务流程中允许局部失败的步骤，如批量处理中跳过失败的条目：
+//
+//	for _, item := range items {
+//		if err := db.WithinSavepoint(ctx, tx, "item", func(tx *gorm.DB) error { return process(tx, item) }); err != nil {
+//			failed = append(failed, item)
+//		}
+//	}
+func WithinSavepoint(ctx context.Context, tx *gorm.DB, name string, fn func(tx *gorm.DB) error) (err error) {
+	if err := Savepoint(ctx, tx, name); err != nil {
+		return err
+	}
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			if rerr := RollbackTo(ctx, tx, name); rerr != nil && err != nil {
+				err = errors.Join(err, rerr)
+			}
+		}
+	}()
+	err = fn(tx.WithContext(ctx))
+	panicked = false
+	if err != nil {
+		return err
+	}
+	return ReleaseSavepoint(ctx, tx, name)
+}
+
+// Savepoint 在 ctx 中的事务上创建保存点，不在事务中时返回 ErrNotInTx
+func (m *TxManager) Savepoint(ctx context.Context, name string) error {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return ErrNotInTx
+	}
+	return Savepoint(ctx, tx, name)
+}
+
+// RollbackTo 将 ctx 中的事务回滚到保存点，不在事务中时返回 ErrNotInTx
+func (m *TxManager) RollbackTo(ctx context.Context, name string) error {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return ErrNotInTx
+	}
+	return RollbackTo(ctx, tx, name)
+}
+
+// Nested 执行可局部回滚的步骤：ctx 中已有事务时以保存点包裹 fn，失败时只撤销 fn 的修改；
+// 否则与 Do 相同，开启新事务执行 fn
+func (m *TxManager) Nested(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return m.Do(ctx, fn)
+	}
+	return WithinSavepoint(ctx, tx, name, func(*gorm.DB) error {
+		return fn(ctx)
+	})
+}
+
+// savepointOp 检查保存点名称和事务状态，在子 span 中执行保存点操作
+func savepointOp(ctx context.Context, tx *gorm.DB, operation, name string, fn func(tx *gorm.DB) error) error {
+	if !savepointNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid savepoint name: %q", name)
+	}
+	if tx == nil || !inTx(tx) {
+		return ErrNotInTx
+	}
+
+	ctx, span := pkgtrace.StartSpan(ctx, "db."+operation,
+		trace.WithAttributes(
+			attribute.String("db.system", dialectOf(tx)),
+			attribute.String("db.operation", operation),
+			attribute.String("db.savepoint", name),
+		),
+	)
+	defer span.End()
+
+	if err := fn(tx.WithContext(ctx)); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		return fmt.Errorf("%s %s: %w", operation, name, err)
+	}
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import "testing"
+
+func TestSavepointNamePattern(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"sp1", true},
+		{"_step", true},
+		{"Import_Batch_2", true},
+		{"", false},
+		{"1sp", false},
+		{"sp-1", false},
+		{"sp 1", false},
+		{"sp; ROLLBACK", false},
+		{"`sp`", false},
+		{"保存点", false},
+	}
+	for _, tt := range tests {
+		if got := savepointNamePattern.MatchString(tt.name); got != tt.want {
+			t.Errorf("savepointNamePattern.MatchString(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
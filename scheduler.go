@@ -0,0 +1,512 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultScheduleLease 作业执行的默认领取有效期
+	defaultScheduleLease = 10 * time.Minute
+	// maxCatchUpScan 补执行时查找错过的计划时间的最大次数，避免间隔很短的作业长时间停机后遍历过多
+	maxCatchUpScan = 100000
+)
+
+// ScheduledFunc 定时作业的执行函数，runAt 为本次执行对应的计划时间
+type ScheduledFunc func(ctx context.Context, runAt time.Time) error
+
+// ScheduleStore 协调多个实例对定时作业的执行，保证同一作业的同一计划时间只由一个实例执行
+type ScheduleStore interface {
+	// Claim 领取作业在 runAt 的执行，已被领取或作业仍在其他实例上执行时返回 false；
+	// lease 为执行的最长占用时间，超过后视为执行者已崩溃
+	Claim(ctx context.Context, job string, runAt time.Time, lease time.Duration) (bool, error)
+	// Complete 记录执行结束并释放占用，runErr 为执行结果
+	Complete(ctx context.Context, job string, runAt time.Time, runErr error) error
+	// LastRun 返回作业最近一次被领取的计划时间，从未执行时返回零值
+	LastRun(ctx context.Context, job string) (time.Time, error)
+}
+
+// redisClaimScript 计划时间晚于最近一次执行且作业未在执行中时领取
+// KEYS[1] 最近一次执行的计划时间，KEYS[2] 执行中的占用键；ARGV[1] 计划时间（毫秒），ARGV[2] 占用者，ARGV[3] 占用有效期（毫秒）
+var redisClaimScript = redis.NewScript(`
+local last = tonumber(redis.call('GET', KEYS[1]) or '0')
+if last >= tonumber(ARGV[1]) then
+	return 0
+end
+if not redis.call('SET', KEYS[2], ARGV[2], 'NX', 'PX', ARGV[3]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+return 1
+`)
+
+// RedisScheduleStore 基于 Redis 锁的作业协调，同一作业的键使用相同的 hash tag，支持 Redis Cluster
+type RedisScheduleStore struct {
+	client redis.UniversalClient
+	prefix string
+	owner  string
+}
+
+// NewRedisScheduleStore 创建基于 Redis 的作业协调，prefix 为空时使用 "scheduler:"
+func NewRedisScheduleStore(client redis.UniversalClient, prefix string) (*RedisScheduleStore, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if prefix == "" {
+		prefix = "scheduler:"
+	}
+	return &RedisScheduleStore{client: client, prefix: prefix, owner: newJobID()}, nil
+}
+
+// Claim 领取作业的执行
+func (s *RedisScheduleStore) Claim(ctx context.Context, job string, runAt time.Time, lease time.Duration) (bool, error) {
+	n, err := redisClaimScript.Run(ctx, s.client, []string{s.lastKey(job), s.lockKey(job)},
+		runAt.UnixMilli(), s.owner, lease.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("scheduler: claim %s: %w", job, err)
+	}
+	return n == 1, nil
+}
+
+// Complete 释放执行中的占用
+func (s *RedisScheduleStore) Complete(ctx context.Context, job string, _ time.Time, _ error) error {
+	return releaseScript.Run(ctx, s.client, []string{s.lockKey(job)}, s.owner).Err()
+}
+
+// LastRun 返回最近一次执行的计划时间
+func (s *RedisScheduleStore) LastRun(ctx context.Context, job string) (time.Time, error) {
+	val, err := s.client.Get(ctx, s.lastKey(job)).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("scheduler: %w", err)
+	}
+	ms, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("scheduler: invalid last run of %s: %w", job, err)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func (s *RedisScheduleStore) lastKey(job string) string {
+	return s.prefix + "{" + job + "}:last"
+}
+
+func (s *RedisScheduleStore) lockKey(job string) string {
+	return s.prefix + "{" + job + "}:lock"
+}
+
+// ScheduledJobRecord 定时作业的执行状态
+type ScheduledJobRecord struct {
+	Name        string    `gorm:"primaryKey;size:191"`
+	LastRunAt   time.Time `gorm:"not null"` // 最近一次被领取的计划时间
+	LockedUntil time.Time `gorm:"not null"` // 执行中的占用到期时间
+	LockedBy    string    `gorm:"size:64"`
+	LastError   string    `gorm:"type:text"`
+	UpdatedAt   time.Time `gorm:"not null"`
+}
+
+// TableScheduleStore 基于数据库表的作业协调，领取时以 SELECT ... FOR UPDATE SKIP LOCKED 锁定作业的行，
+// 其他实例正在领取时直接跳过而不等待。需要 MySQL 8.0+ 或 PostgreSQL 9.5+
+type TableScheduleStore struct {
+	db    *gorm.DB
+	table string
+	owner string
+}
+
+// NewTableScheduleStore 创建基于数据库表的作业协调，表不存在时自动创建，table 为空时使用 scheduled_jobs
+func NewTableScheduleStore(db *gorm.DB, table string) (*TableScheduleStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db cannot be nil")
+	}
+	if table == "" {
+		table = "scheduled_jobs"
+	}
+	if err := db.Table(table).AutoMigrate(&ScheduledJobRecord{}); err != nil {
+		return nil, fmt.Errorf("failed to create scheduled job table: %w", err)
+	}
+	return &TableScheduleStore{db: db, table: table, owner: newJobID()}, nil
+}
+
+// Claim 领取作业的执行
+func (s *TableScheduleStore) Claim(ctx context.Context, job string, runAt time.Time, lease time.Duration) (bool, error) {
+	// 作业的行不存在时先创建，之后的领取都锁定这一行
+	rec := ScheduledJobRecord{Name: job, LastRunAt: time.Unix(0, 0), LockedUntil: time.Unix(0, 0), UpdatedAt: time.Now()}
+	if err := s.db.WithContext(ctx).Table(s.table).Clauses(clause.OnConflict{DoNothing: true}).Create(&rec).Error; err != nil {
+		return false, fmt.Errorf("scheduler: claim %s: %w", job, err)
+	}
+
+	claimed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current ScheduledJobRecord
+		err := tx.Table(s.table).
+			Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where(clause.Eq{Column: clause.Column{Name: "name"}, Value: job}).
+			Take(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 其他实例正在领取
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !current.LastRunAt.Before(runAt) || current.LockedUntil.After(now) {
+			return nil
+		}
+		claimed = true
+		return tx.Table(s.table).
+			Where(clause.Eq{Column: clause.Column{Name: "name"}, Value: job}).
+			Updates(map[string]any{"last_run_at": runAt, "locked_until": now.Add(lease), "locked_by": s.owner, "updated_at": now}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("scheduler: claim %s: %w", job, err)
+	}
+	return claimed, nil
+}
+
+// Complete 记录执行结果并释放占用
+func (s *TableScheduleStore) Complete(ctx context.Context, job string, _ time.Time, runErr error) error {
+	var lastError string
+	if runErr != nil {
+		lastError = runErr.Error()
+	}
+	now := time.Now()
+	return s.db.WithContext(ctx).Table(s.table).
+		Where(clause.Eq{Column: clause.Column{Name: "name"}, Value: job}).
+		Where(clause.Eq{Column: clause.Column{Name: "locked_by"}, Value: s.owner}).
+		Updates(map[string]any{"locked_until": now, "last_error": lastError, "updated_at": now}).Error
+}
+
+// LastRun 返回最近一次执行的计划时间
+func (s *TableScheduleStore) LastRun(ctx context.Context, job string) (time.Time, error) {
+	var rec ScheduledJobRecord
+	err := s.db.WithContext(ctx).Table(s.table).Where(clause.Eq{Column: clause.Column{Name: "name"}, Value: job}).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && rec.LastRunAt.Unix() <= 0) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("scheduler: %w", err)
+	}
+	return rec.LastRunAt, nil
+}
+
+// ScheduleOptions 单个定时作业的配置选项
+type ScheduleOptions struct {
+	Timeout time.Duration // 单次执行的超时，默认不限制
+	Lease   time.Duration // 执行的最长占用时间，超过后其他实例可执行下一次计划，默认 Timeout，未设置 Timeout 时为 SchedulerOptions.Lease
+	CatchUp bool          // 启动时若最近一次执行之后错过了计划时间（如全部实例停机），立即补执行一次
+}
+
+// SchedulerOptions 调度器配置选项
+type SchedulerOptions struct {
+	Store    ScheduleStore  // 作业协调，多个实例使用相同的 Store 时每次计划只执行一次
+	Location *time.Location // 计算 cron 表达式使用的时区，默认 time.Local
+	Lease    time.Duration  // 作业执行的默认最长占用时间，默认 10m
+}
+
+// scheduledJob 已注册的定时作业
+type scheduledJob struct {
+	name     string
+	schedule *CronSchedule
+	fn       ScheduledFunc
+	opts     ScheduleOptions
+	next     time.Time // 下一次计划时间，只在调度 goroutine 中访问
+	running  atomic.Bool
+}
+
+// Scheduler 分布式定时作业调度器：每个实例按 cron 表达式计算计划时间，到期时通过 Store 领取，
+// 领取成功的实例执行作业。同一作业在本实例上一次执行未结束时跳过到期的计划，
+// 调度延迟错过多个计划时间时只执行最近的一次
+type Scheduler struct {
+	opts SchedulerOptions
+
+	mu      sync.Mutex
+	jobs    []*scheduledJob
+	cancel  context.CancelFunc
+	done    chan struct{}
+	running sync.WaitGroup
+
+	// runCtx 作业执行使用的 context，Stop 等待超时时才取消
+	runCtx    context.Context
+	runCancel context.CancelFunc
+}
+
+// NewScheduler 创建调度器
+func NewScheduler(opts *SchedulerOptions) (*Scheduler, error) {
+	if opts == nil || opts.Store == nil {
+		return nil, fmt.Errorf("schedule store cannot be nil")
+	}
+	s := &Scheduler{opts: *opts}
+	if s.opts.Location == nil {
+		s.opts.Location = time.Local
+	}
+	if s.opts.Lease <= 0 {
+		s.opts.Lease = defaultScheduleLease
+	}
+	s.runCtx, s.runCancel = context.WithCancel(context.Background())
+	return s, nil
+}
+
+// Register 注册定时作业，需在 Start 之前调用。name 在所有实例中唯一标识作业，spec 为 cron 表达式，见 ParseCron
+func (s *Scheduler) Register(name, spec string, fn ScheduledFunc, opts *ScheduleOptions) error {
+	if name == "" {
+		return fmt.Errorf("scheduled job name is required")
+	}
+	if fn == nil {
+		return fmt.Errorf("scheduled job %s func cannot be nil", name)
+	}
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+
+	job := &scheduledJob{name: name, schedule: schedule, fn: fn}
+	if opts != nil {
+		job.opts = *opts
+	}
+	if job.opts.Lease <= 0 {
+		job.opts.Lease = job.opts.Timeout
+	}
+	if job.opts.Lease <= 0 {
+		job.opts.Lease = s.opts.Lease
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return fmt.Errorf("scheduler already started")
+	}
+	for _, j := range s.jobs {
+		if j.name == name {
+			return fmt.Errorf("scheduled job %s already registered", name)
+		}
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Start 在后台开始调度，开启了 CatchUp 的作业错过计划时间时立即补执行
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return fmt.Errorf("scheduler already started")
+	}
+
+	now := time.Now().In(s.opts.Location)
+	for _, job := range s.jobs {
+		job.next = job.schedule.Next(now)
+		if !job.opts.CatchUp {
+			continue
+		}
+		last, err := s.opts.Store.LastRun(ctx, job.name)
+		if err != nil {
+			log.Warn("Failed to load last run of scheduled job, skipping catch-up", zap.String("job", job.name), zap.Error(err))
+			continue
+		}
+		if missed := latestMissed(job.schedule, last.In(s.opts.Location), now); !missed.IsZero() {
+			log.Info("Catching up missed scheduled job", zap.String("job", job.name), zap.Time("run_at", missed))
+			job.next = missed
+		}
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	go s.loop(ctx)
+	return nil
+}
+
+// Stop 停止调度并等待执行中的作业完成；ctx 超时时取消执行中作业的 context 并返回
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	waited := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		return nil
+	case <-ctx.Done():
+		s.runCancel()
+		return ctx.Err()
+	}
+}
+
+// loop 等待最近的计划时间并分派到期的作业
+func (s *Scheduler) loop(ctx context.Context) {
+	defer close(s.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now().In(s.opts.Location)
+		var earliest time.Time
+		for _, job := range s.jobs {
+			if job.next.IsZero() {
+				continue
+			}
+			if !job.next.After(now) {
+				runAt := job.next
+				job.next = job.schedule.Next(now)
+				s.dispatch(ctx, job, runAt)
+			}
+			if !job.next.IsZero() && (earliest.IsZero() || job.next.Before(earliest)) {
+				earliest = job.next
+			}
+		}
+		if earliest.IsZero() {
+			<-ctx.Done()
+			return
+		}
+		timer.Reset(time.Until(earliest))
+	}
+}
+
+// dispatch 在新的 goroutine 中领取并执行作业
+func (s *Scheduler) dispatch(ctx context.Context, job *scheduledJob, runAt time.Time) {
+	if !job.running.CompareAndSwap(false, true) {
+		log.Warn("Scheduled job still running, skipping", zap.String("job", job.name), zap.Time("run_at", runAt))
+		recordScheduledRun(job.name, "overlapped")
+		return
+	}
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer job.running.Store(false)
+
+		ok, err := s.opts.Store.Claim(ctx, job.name, runAt, job.opts.Lease)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("Failed to claim scheduled job", zap.String("job", job.name), zap.Error(err))
+			}
+			recordScheduledRun(job.name, "claim_failed")
+			return
+		}
+		if !ok {
+			recordScheduledRun(job.name, "skipped")
+			return
+		}
+		s.execute(job, runAt)
+	}()
+}
+
+// execute 执行已领取的作业并记录结果
+func (s *Scheduler) execute(job *scheduledJob, runAt time.Time) {
+	ctx := s.runCtx
+	if job.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.opts.Timeout)
+		defer cancel()
+	}
+	ctx, span := pkgtrace.StartSpan(ctx, "scheduler."+job.name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("scheduler.job", job.name),
+			attribute.String("scheduler.spec", job.schedule.String()),
+			attribute.String("scheduler.run_at", runAt.Format(time.RFC3339)),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := runScheduled(ctx, job.fn, runAt)
+	duration := time.Since(start)
+
+	if cerr := s.opts.Store.Complete(context.WithoutCancel(ctx), job.name, runAt, err); cerr != nil {
+		log.Warn("Failed to complete scheduled job", zap.String("job", job.name), zap.Error(cerr))
+	}
+	if metrics.IsEnabled() {
+		SchedulerRunDuration.WithLabelValues(job.name).Observe(duration.Seconds())
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		log.Error("Scheduled job failed", zap.String("job", job.name), zap.Time("run_at", runAt),
+			zap.Duration("duration", duration), zap.Error(err))
+		recordScheduledRun(job.name, "failed")
+		return
+	}
+	span.SetStatus(codes.Ok, "")
+	recordScheduledRun(job.name, "succeeded")
+	if metrics.IsEnabled() {
+		SchedulerLastSuccess.WithLabelValues(job.name).Set(float64(time.Now().Unix()))
+	}
+}
+
+// runScheduled 调用作业函数，捕获 panic 并转换为错误
+func runScheduled(ctx context.Context, fn ScheduledFunc, runAt time.Time) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("scheduled job panic: %v", r)
+		}
+	}()
+	return fn(ctx, runAt)
+}
+
+// latestMissed 返回 last 之后、不晚于 now 的最近一个计划时间，没有时返回零值
+func latestMissed(schedule *CronSchedule, last, now time.Time) time.Time {
+	if last.IsZero() {
+		return time.Time{}
+	}
+	var missed time.Time
+	for i, t := 0, schedule.Next(last); i < maxCatchUpScan && !t.IsZero() && !t.After(now); i++ {
+		missed, t = t, schedule.Next(t)
+	}
+	return missed
+}
+
+// recordScheduledRun 记录定时作业执行指标
+func recordScheduledRun(job, status string) {
+	if metrics.IsEnabled() {
+		SchedulerRunsTotal.WithLabelValues(job, status).Inc()
+	}
+}
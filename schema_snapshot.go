@@ -0,0 +1,282 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// snapshotTablePrefix 快照文件中表定义的起始标记
+const snapshotTablePrefix = "-- table: "
+
+// SchemaSnapshot 数据库结构快照，每张表保存一份规范化的 DDL
+// DDL 由 GORM Migrator 读取的列和索引信息生成，与具体方言的 SHOW CREATE 输出无关，便于稳定比较
+type SchemaSnapshot struct {
+	Tables map[string][]string // 表名 -> DDL 行
+}
+
+// TakeSchemaSnapshot 读取当前数据库的表结构并生成快照
+func TakeSchemaSnapshot(ctx context.Context, db *gorm.DB) (*SchemaSnapshot, error) {
+	migrator := db.WithContext(ctx).Migrator()
+	tables, err := migrator.GetTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	snapshot := &SchemaSnapshot{Tables: make(map[string][]string, len(tables))}
+	for _, table := range tables {
+		ddl, err := tableDDL(migrator, table)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Tables[table] = ddl
+	}
+	return snapshot, nil
+}
+
+// tableDDL 生成单张表的规范化 DDL
+func tableDDL(migrator gorm.Migrator, table string) ([]string, error) {
+	columns, err := migrator.ColumnTypes(table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of table %s: %w", table, err)
+	}
+	indexes, err := migrator.GetIndexes(table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexes of table %s: %w", table, err)
+	}
+
+	// 列按数据库中的顺序输出，行尾不带逗号，避免新增列时改动上一行
+	lines := []string{"CREATE TABLE " + table + " ("}
+	var primaryKeys []string
+	for _, col := range columns {
+		lines = append(lines, "  "+columnDDL(col))
+		if pk, ok := col.PrimaryKey(); ok && pk {
+			primaryKeys = append(primaryKeys, col.Name())
+		}
+	}
+	if len(primaryKeys) > 0 {
+		lines = append(lines, "  PRIMARY KEY ("+strings.Join(primaryKeys, ", ")+")")
+	}
+
+	indexLines := make([]string, 0, len(indexes))
+	for _, idx := range indexes {
+		if pk, ok := idx.PrimaryKey(); ok && pk {
+			continue
+		}
+		kind := "INDEX"
+		if unique, ok := idx.Unique(); ok && unique {
+			kind = "UNIQUE INDEX"
+		}
+		indexLines = append(indexLines, fmt.Sprintf("  %s %s (%s)", kind, idx.Name(), strings.Join(idx.Columns(), ", ")))
+	}
+	sort.Strings(indexLines)
+	lines = append(lines, indexLines...)
+	lines = append(lines, ")")
+	return lines, nil
+}
+
+// columnDDL 生成单列的规范化定义
+func columnDDL(col gorm.ColumnType) string {
+	typ, ok := col.ColumnType()
+	if !ok || typ == "" {
+		typ = col.DatabaseTypeName()
+	}
+
+	parts := []string{col.Name(), strings.ToLower(typ)}
+	if nullable, ok := col.Nullable(); ok && !nullable {
+		parts = append(parts, "NOT NULL")
+	}
+	if def, ok := col.DefaultValue(); ok && def != "" {
+		parts = append(parts, "DEFAULT "+def)
+	}
+	if autoInc, ok := col.AutoIncrement(); ok && autoInc {
+		parts = append(parts, "AUTO_INCREMENT")
+	}
+	if unique, ok := col.Unique(); ok && unique {
+		parts = append(parts, "UNIQUE")
+	}
+	return strings.Join(parts, " ")
+}
+
+// String 返回快照的文本格式，表按名称排序
+func (s *SchemaSnapshot) String() string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(snapshotTablePrefix + name + "\n")
+		for _, line := range s.Tables[name] {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// WriteFile 将快照写入文件
+func (s *SchemaSnapshot) WriteFile(path string) error {
+	if err := os.WriteFile(path, []byte(s.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write schema snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSchemaSnapshot 从文件读取快照
+func LoadSchemaSnapshot(path string) (*SchemaSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schema snapshot: %w", err)
+	}
+	defer f.Close()
+
+	snapshot := &SchemaSnapshot{Tables: make(map[string][]string)}
+	current := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, snapshotTablePrefix):
+			current = strings.TrimPrefix(line, snapshotTablePrefix)
+			snapshot.Tables[current] = nil
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(line, "--"):
+		case current != "":
+			snapshot.Tables[current] = append(snapshot.Tables[current], line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// TableDiff 单张表的结构变化
+type TableDiff struct {
+	Table   string
+	Added   []string // 新增的 DDL 行
+	Removed []string // 删除的 DDL 行
+}
+
+// SchemaDiff 两份快照之间的差异
+type SchemaDiff struct {
+	AddedTables   []string
+	RemovedTables []string
+	ChangedTables []TableDiff
+}
+
+// DiffSchemaSnapshots 比较旧快照和新快照
+func DiffSchemaSnapshots(old, new *SchemaSnapshot) *SchemaDiff {
+	diff := &SchemaDiff{}
+	for name := range new.Tables {
+		if _, ok := old.Tables[name]; !ok {
+			diff.AddedTables = append(diff.AddedTables, name)
+		}
+	}
+	for name, oldLines := range old.Tables {
+		newLines, ok := new.Tables[name]
+		if !ok {
+			diff.RemovedTables = append(diff.RemovedTables, name)
+			continue
+		}
+		td := TableDiff{
+			Table:   name,
+			Added:   subtractLines(newLines, oldLines),
+			Removed: subtractLines(oldLines, newLines),
+		}
+		if len(td.Added) > 0 || len(td.Removed) > 0 {
+			diff.ChangedTables = append(diff.ChangedTables, td)
+		}
+	}
+	sort.Strings(diff.AddedTables)
+	sort.Strings(diff.RemovedTables)
+	sort.Slice(diff.ChangedTables, func(i, j int) bool { return diff.ChangedTables[i].Table < diff.ChangedTables[j].Table })
+	return diff
+}
+
+// subtractLines 返回 a 中存在而 b 中不存在的行，保持 a 中的顺序
+func subtractLines(a, b []string) []string {
+	seen := make(map[string]int, len(b))
+	for _, line := range b {
+		seen[line]++
+	}
+	var out []string
+	for _, line := range a {
+		if seen[line] > 0 {
+			seen[line]--
+			continue
+		}
+		out = append(out, strings.TrimSpace(line))
+	}
+	return out
+}
+
+// Empty 两份快照是否一致
+func (d *SchemaDiff) Empty() bool {
+	return len(d.AddedTables) == 0 && len(d.RemovedTables) == 0 && len(d.ChangedTables) == 0
+}
+
+// String 返回可读的变更报告
+func (d *SchemaDiff) String() string {
+	if d.Empty() {
+		return "No schema changes.\n"
+	}
+
+	var b strings.Builder
+	for _, name := range d.AddedTables {
+		fmt.Fprintf(&b, "+ table %s\n", name)
+	}
+	for _, name := range d.RemovedTables {
+		fmt.Fprintf(&b, "- table %s\n", name)
+	}
+	for _, td := range d.ChangedTables {
+		fmt.Fprintf(&b, "~ table %s\n", td.Table)
+		for _, line := range td.Removed {
+			fmt.Fprintf(&b, "    - %s\n", line)
+		}
+		for _, line := range td.Added {
+			fmt.Fprintf(&b, "    + %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// DiffSchemaWithFile 将当前数据库结构与快照文件比较；快照文件不存在时视为空快照
+func DiffSchemaWithFile(ctx context.Context, db *gorm.DB, path string) (*SchemaDiff, error) {
+	current, err := TakeSchemaSnapshot(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	stored := &SchemaSnapshot{Tables: map[string][]string{}}
+	if _, err := os.Stat(path); err == nil {
+		if stored, err = LoadSchemaSnapshot(path); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to stat schema snapshot: %w", err)
+	}
+	return DiffSchemaSnapshots(stored, current), nil
+}
@@ -0,0 +1,528 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const callBackShadowName = "shadow:mirror"
+
+// ShadowOptions 影子写配置选项
+type ShadowOptions struct {
+	Name           string        // 指标和日志中的名称，默认 shadow
+	Workers        int           // 回放语句的 worker 数，默认 1（保证按主库的执行顺序回放）
+	QueueSize      int           // 待回放队列长度，默认 1024，队列满时丢弃并计入指标
+	Timeout        time.Duration // 单条语句在影子库上的超时，默认 5s
+	ReadSampleRate float64       // 对比查询结果的采样比例（0~1），0 表示不对比
+}
+
+// shadowOp 待在影子库上回放的语句
+type shadowOp struct {
+	operation string // create、update、delete、raw、query
+	sql       string
+	vars      []any
+	rows      int64    // 主库影响或返回的行数，-1 表示不对比
+	keys      []string // 主库返回的主键，仅查询对比时使用
+	keyColumn string
+}
+
+// ShadowPlugin 影子写插件：主库语句成功后，使用影子库的方言重新生成 SQL 并异步回放到影子库，
+// 可按比例对比查询结果，用于 MySQL→PostgreSQL 或集群间迁移时验证新库的数据一致性。
+// 新增时会带上主库生成的自增主键，使两边主键一致；事务中的语句缓存在事务内，提交成功后才加入回放队列，
+// 事务回滚或回滚到保存点时丢弃对应的语句。事务需在注册插件之后通过该连接开始。
+// 原生 Exec 语句只在两边方言相同时回放；影子库的错误只记录日志和指标，不影响主库的语句
+type ShadowPlugin struct {
+	secondary *gorm.DB
+	opts      ShadowOptions
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan shadowOp
+	wg     sync.WaitGroup
+}
+
+// NewShadowPlugin 创建影子写插件，通过主库的 db.Use 注册，secondary 为影子库连接
+func NewShadowPlugin(secondary *gorm.DB, opts *ShadowOptions) (*ShadowPlugin, error) {
+	if secondary == nil {
+		return nil, fmt.Errorf("shadow database cannot be nil")
+	}
+
+	p := &ShadowPlugin{secondary: secondary}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.Name == "" {
+		p.opts.Name = "shadow"
+	}
+	if p.opts.Workers <= 0 {
+		p.opts.Workers = 1
+	}
+	if p.opts.QueueSize <= 0 {
+		p.opts.QueueSize = 1024
+	}
+	if p.opts.Timeout <= 0 {
+		p.opts.Timeout = 5 * time.Second
+	}
+	p.queue = make(chan shadowOp, p.opts.QueueSize)
+	for i := 0; i < p.opts.Workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for op := range p.queue {
+				p.replay(op)
+			}
+		}()
+	}
+	return p, nil
+}
+
+// Name 返回插件名称
+func (p *ShadowPlugin) Name() string {
+	return "ShadowPlugin"
+}
+
+// Initialize 包装连接池以缓存事务中的语句，并注册回调
+func (p *ShadowPlugin) Initialize(db *gorm.DB) error {
+	// 开启 PrepareStmt 时包装预编译连接池内部的连接池，保留 GORM 对预编译事务的处理
+	if prepared, ok := db.ConnPool.(*gorm.PreparedStmtDB); ok {
+		prepared.ConnPool = &shadowPool{ConnPool: prepared.ConnPool, plugin: p}
+	} else {
+		pool := &shadowPool{ConnPool: db.ConnPool, plugin: p}
+		db.ConnPool = pool
+		db.Statement.ConnPool = pool
+	}
+	_ = db.Callback().Create().After("gorm:create").Register(callBackShadowName, p.mirror("create"))
+	_ = db.Callback().Update().After("gorm:update").Register(callBackShadowName, p.mirror("update"))
+	_ = db.Callback().Delete().After("gorm:delete").Register(callBackShadowName, p.mirror("delete"))
+	_ = db.Callback().Raw().After("gorm:raw").Register(callBackShadowName, p.mirrorRaw)
+	if p.opts.ReadSampleRate > 0 {
+		_ = db.Callback().Query().After("gorm:query").Register(callBackShadowName, p.compare)
+	}
+	return nil
+}
+
+// 确保 ShadowPlugin 实现了 gorm.Plugin 接口
+var _ gorm.Plugin = &ShadowPlugin{}
+
+// Close 停止接收新语句，等待队列中的语句回放完成，ctx 结束时不再等待
+func (p *ShadowPlugin) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mirror 返回回放写操作的回调
+func (p *ShadowPlugin) mirror(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.DryRun || db.Statement.SQL.Len() == 0 {
+			return
+		}
+		stmt := p.statement(db)
+		rows := db.RowsAffected
+		switch {
+		case operation == "create":
+			shadowCreateKeys(db, stmt)
+			stmt.Build(p.secondary.Callback().Create().Clauses...)
+		case operation == "update", db.Statement.Clauses["SET"].Expression != nil:
+			// 软删除以 UPDATE 执行；MySQL 的更新只统计值发生变化的行，不对比行数
+			stmt.Build(p.secondary.Callback().Update().Clauses...)
+			rows = -1
+		default:
+			stmt.Build(p.secondary.Callback().Delete().Clauses...)
+		}
+		// ON DUPLICATE KEY UPDATE 更新的行在 MySQL 中计为 2，不对比行数
+		if _, ok := db.Statement.Clauses["ON CONFLICT"]; ok {
+			rows = -1
+		}
+		p.submit(db, shadowOp{operation: operation, sql: stmt.SQL.String(), vars: stmt.Vars, rows: rows})
+	}
+}
+
+// mirrorRaw 回放原生 Exec 语句，方言不同时无法转换，只计入指标
+// 事务中的 SAVEPOINT 和 ROLLBACK TO SAVEPOINT 不回放，用于丢弃回滚到保存点的语句
+func (p *ShadowPlugin) mirrorRaw(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	if tx := shadowTxOf(db.Statement.ConnPool); tx != nil && tx.savepoint(db.Statement.SQL.String()) {
+		return
+	}
+	if dialectOf(db) != dialectOf(p.secondary) {
+		p.record("raw", "skipped")
+		return
+	}
+	p.submit(db, shadowOp{
+		operation: "raw",
+		sql:       db.Statement.SQL.String(),
+		vars:      slices.Clone(db.Statement.Vars),
+		rows:      db.RowsAffected,
+	})
+}
+
+// compare 按采样比例在影子库上执行相同的查询，对比行数和主键
+func (p *ShadowPlugin) compare(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.SQL.Len() == 0 || rand.Float64() >= p.opts.ReadSampleRate {
+		return
+	}
+	stmt := p.statement(db)
+	stmt.Build(p.secondary.Callback().Query().Clauses...)
+
+	op := shadowOp{operation: "query", sql: stmt.SQL.String(), vars: stmt.Vars, rows: db.RowsAffected}
+	if s := db.Statement.Schema; s != nil && s.PrioritizedPrimaryField != nil {
+		op.keyColumn = s.PrioritizedPrimaryField.DBName
+		eachAuditRow(db, func(rv reflect.Value) {
+			v, _ := s.PrioritizedPrimaryField.ValueOf(db.Statement.Context, rv)
+			op.keys = append(op.keys, fmt.Sprint(v))
+		})
+		// 查询部分列或结果不是模型实体时无法取得主键，只对比行数
+		if int64(len(op.keys)) != db.RowsAffected {
+			op.keyColumn, op.keys = "", nil
+		}
+	}
+	p.submit(db, op)
+}
+
+// statement 创建影子库上的语句，复用主库语句的子句，由影子库的方言生成 SQL
+func (p *ShadowPlugin) statement(db *gorm.DB) *gorm.Statement {
+	stmt := p.secondary.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Statement
+	stmt.Table = db.Statement.Table
+	stmt.TableExpr = db.Statement.TableExpr
+	stmt.Schema = db.Statement.Schema
+	for name, c := range db.Statement.Clauses {
+		stmt.Clauses[name] = c
+	}
+	return stmt
+}
+
+// shadowCreateKeys 新增的列中不包含自增主键时，补充主库生成的主键值
+func shadowCreateKeys(db *gorm.DB, stmt *gorm.Statement) {
+	s := db.Statement.Schema
+	c, ok := stmt.Clauses["VALUES"]
+	if s == nil || s.PrioritizedPrimaryField == nil || !ok {
+		return
+	}
+	values, ok := c.Expression.(clause.Values)
+	if !ok {
+		return
+	}
+	field := s.PrioritizedPrimaryField
+	for _, column := range values.Columns {
+		if column.Name == field.DBName {
+			return
+		}
+	}
+
+	var keys []any
+	eachAuditRow(db, func(rv reflect.Value) {
+		v, zero := field.ValueOf(db.Statement.Context, rv)
+		if !zero {
+			keys = append(keys, v)
+		}
+	})
+	if len(keys) != len(values.Values) {
+		return
+	}
+	patched := clause.Values{Columns: append(slices.Clone(values.Columns), clause.Column{Name: field.DBName})}
+	for i, row := range values.Values {
+		patched.Values = append(patched.Values, append(slices.Clone(row), keys[i]))
+	}
+	c.Expression = patched
+	stmt.Clauses["VALUES"] = c
+}
+
+// submit 事务中的语句缓存到事务，提交后回放，其他语句直接加入回放队列
+func (p *ShadowPlugin) submit(db *gorm.DB, op shadowOp) {
+	if tx := shadowTxOf(db.Statement.ConnPool); tx != nil {
+		tx.add(op)
+		return
+	}
+	p.enqueue(op)
+}
+
+// enqueue 加入回放队列，队列已满或插件已关闭时丢弃
+func (p *ShadowPlugin) enqueue(op shadowOp) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		p.record(op.operation, "dropped")
+		return
+	}
+	select {
+	case p.queue <- op:
+	default:
+		p.record(op.operation, "dropped")
+	}
+}
+
+// replay 在影子库上执行语句并对比结果
+func (p *ShadowPlugin) replay(op shadowOp) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	defer cancel()
+
+	var (
+		rows int64
+		keys []string
+		err  error
+	)
+	if op.operation == "query" {
+		rows, keys, err = p.query(ctx, op)
+	} else {
+		var result sql.Result
+		if result, err = p.secondary.ConnPool.ExecContext(ctx, op.sql, op.vars...); err == nil {
+			rows, _ = result.RowsAffected()
+		}
+	}
+	if err != nil {
+		p.record(op.operation, "error")
+		log.Warn("Shadow database statement failed",
+			zap.String("shadow", p.opts.Name),
+			zap.String("operation", op.operation),
+			zap.String("sql", op.sql),
+			zap.Error(err),
+		)
+		return
+	}
+	p.record(op.operation, "ok")
+
+	diverged := op.rows >= 0 && rows != op.rows
+	if op.keyColumn != "" && keys != nil && !diverged {
+		slices.Sort(keys)
+		slices.Sort(op.keys)
+		diverged = !slices.Equal(keys, op.keys)
+	}
+	if !diverged {
+		return
+	}
+	if metrics.IsEnabled() {
+		DatabaseShadowDivergenceTotal.WithLabelValues(p.opts.Name, op.operation).Inc()
+	}
+	log.Warn("Shadow database result diverged from primary",
+		zap.String("shadow", p.opts.Name),
+		zap.String("operation", op.operation),
+		zap.String("sql", op.sql),
+		zap.Int64("primary_rows", op.rows),
+		zap.Int64("shadow_rows", rows),
+	)
+}
+
+// query 在影子库上执行查询，返回行数和主键，结果不包含主键列时主键为 nil
+func (p *ShadowPlugin) query(ctx context.Context, op shadowOp) (int64, []string, error) {
+	rows, err := p.secondary.ConnPool.QueryContext(ctx, op.sql, op.vars...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, nil, err
+	}
+	keyIndex := slices.Index(columns, op.keyColumn)
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var (
+		count int64
+		keys  []string
+	)
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return 0, nil, err
+		}
+		count++
+		if keyIndex >= 0 {
+			v := values[keyIndex]
+			// MySQL 文本协议返回 []byte
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			keys = append(keys, fmt.Sprint(v))
+		}
+	}
+	return count, keys, rows.Err()
+}
+
+// record 记录回放结果指标
+func (p *ShadowPlugin) record(operation, result string) {
+	if metrics.IsEnabled() {
+		DatabaseShadowStatementsTotal.WithLabelValues(p.opts.Name, operation, result).Inc()
+	}
+}
+
+// shadowPool 包装主库连接池，开始的事务缓存需要回放的语句
+type shadowPool struct {
+	gorm.ConnPool
+	plugin *ShadowPlugin
+}
+
+// GetDBConn 返回底层连接池，使 db.DB() 可用
+func (p *shadowPool) GetDBConn() (*sql.DB, error) {
+	switch pool := p.ConnPool.(type) {
+	case *sql.DB:
+		return pool, nil
+	case gorm.GetDBConnector:
+		return pool.GetDBConn()
+	default:
+		return nil, gorm.ErrInvalidDB
+	}
+}
+
+// BeginTx 开始事务，返回缓存语句的事务
+func (p *shadowPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var (
+		tx  gorm.ConnPool
+		err error
+	)
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	if err != nil {
+		return nil, err
+	}
+	inner, ok := tx.(gorm.Tx)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	db, _ := p.GetDBConn()
+	return &shadowTx{Tx: inner, db: db, plugin: p.plugin}, nil
+}
+
+// shadowTx 包装事务，缓存事务中需要回放的语句，提交成功后加入回放队列
+type shadowTx struct {
+	gorm.Tx
+	db     *sql.DB
+	plugin *ShadowPlugin
+
+	mu         sync.Mutex
+	ops        []shadowOp
+	savepoints map[string]int // 保存点名称到创建时已缓存的语句数
+}
+
+// shadowTxOf 返回连接所属的影子写事务，不在事务中时返回 nil
+func shadowTxOf(pool gorm.ConnPool) *shadowTx {
+	if prepared, ok := pool.(*gorm.PreparedStmtTX); ok {
+		pool = prepared.Tx
+	}
+	tx, _ := pool.(*shadowTx)
+	return tx
+}
+
+// GetDBConn 返回底层连接池，使事务中的 db.DB() 可用
+func (t *shadowTx) GetDBConn() (*sql.DB, error) {
+	if t.db == nil {
+		return nil, gorm.ErrInvalidDB
+	}
+	return t.db, nil
+}
+
+// add 缓存语句，超过回放队列长度的部分丢弃
+func (t *shadowTx) add(op shadowOp) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.ops) >= t.plugin.opts.QueueSize {
+		t.plugin.record(op.operation, "dropped")
+		return
+	}
+	t.ops = append(t.ops, op)
+}
+
+// savepoint 处理保存点语句：记录保存点位置，回滚到保存点时丢弃之后缓存的语句，不是保存点语句时返回 false
+func (t *shadowTx) savepoint(query string) bool {
+	fields := strings.Fields(query)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case len(fields) == 2 && strings.EqualFold(fields[0], "SAVEPOINT"):
+		if t.savepoints == nil {
+			t.savepoints = make(map[string]int)
+		}
+		t.savepoints[fields[1]] = len(t.ops)
+	case len(fields) == 4 && strings.EqualFold(fields[0], "ROLLBACK") && strings.EqualFold(fields[1], "TO") && strings.EqualFold(fields[2], "SAVEPOINT"):
+		if n, ok := t.savepoints[fields[3]]; ok && n <= len(t.ops) {
+			t.ops = t.ops[:n]
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// take 取出缓存的语句
+func (t *shadowTx) take() []shadowOp {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ops := t.ops
+	t.ops, t.savepoints = nil, nil
+	return ops
+}
+
+// Commit 提交事务，成功后将缓存的语句加入回放队列
+func (t *shadowTx) Commit() error {
+	ops := t.take()
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		t.plugin.enqueue(op)
+	}
+	return nil
+}
+
+// Rollback 回滚事务，丢弃缓存的语句
+func (t *shadowTx) Rollback() error {
+	t.take()
+	return t.Tx.Rollback()
+}
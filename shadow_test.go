@@ -0,0 +1,166 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type shadowItem struct {
+	ID   uint
+	Name string
+}
+
+// openShadowTestDBs 打开主库和影子库两个 SQLite 库，在主库上注册影子写插件
+func openShadowTestDBs(t *testing.T) (*gorm.DB, *gorm.DB, *ShadowPlugin) {
+	t.Helper()
+	dir := t.TempDir()
+	open := func(name string) *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(dir, name)), &gorm.Config{Logger: logger.Discard})
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				_ = sqlDB.Close()
+			}
+		})
+		if err := db.AutoMigrate(&shadowItem{}); err != nil {
+			t.Fatalf("migrate %s: %v", name, err)
+		}
+		return db
+	}
+	primary, secondary := open("primary.db"), open("shadow.db")
+
+	plugin, err := NewShadowPlugin(secondary, nil)
+	if err != nil {
+		t.Fatalf("NewShadowPlugin: %v", err)
+	}
+	if err := primary.Use(plugin); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	return primary, secondary, plugin
+}
+
+func TestShadowPluginTransactions(t *testing.T) {
+	errRollback := errors.New("rollback")
+	tests := []struct {
+		name string
+		run  func(db *gorm.DB) error
+		want []string
+	}{
+		{
+			name: "autocommit",
+			run:  func(db *gorm.DB) error { return db.Create(&shadowItem{Name: "a"}).Error },
+			want: []string{"a"},
+		},
+		{
+			name: "committed transaction",
+			run: func(db *gorm.DB) error {
+				return db.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Create(&shadowItem{Name: "a"}).Error; err != nil {
+						return err
+					}
+					return tx.Create(&shadowItem{Name: "b"}).Error
+				})
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "rolled back transaction",
+			run: func(db *gorm.DB) error {
+				_ = db.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Create(&shadowItem{Name: "a"}).Error; err != nil {
+						return err
+					}
+					return errRollback
+				})
+				return nil
+			},
+		},
+		{
+			name: "rolled back savepoint",
+			run: func(db *gorm.DB) error {
+				return db.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Create(&shadowItem{Name: "a"}).Error; err != nil {
+						return err
+					}
+					_ = tx.Transaction(func(tx *gorm.DB) error {
+						if err := tx.Create(&shadowItem{Name: "b"}).Error; err != nil {
+							return err
+						}
+						return errRollback
+					})
+					return nil
+				})
+			},
+			want: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, secondary, plugin := openShadowTestDBs(t)
+			if err := tt.run(primary); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := plugin.Close(ctx); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			var got []string
+			if err := secondary.Model(&shadowItem{}).Order("id").Pluck("name", &got).Error; err != nil {
+				t.Fatalf("read shadow: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("shadow rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShadowPluginBuffersUntilCommit(t *testing.T) {
+	primary, _, _ := openShadowTestDBs(t)
+	tx := primary.Begin()
+	defer tx.Rollback()
+	if err := tx.Create(&shadowItem{Name: "a"}).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	stx := shadowTxOf(tx.Statement.ConnPool)
+	if stx == nil {
+		t.Fatal("transaction is not wrapped by the shadow plugin")
+	}
+	stx.mu.Lock()
+	buffered := len(stx.ops)
+	stx.mu.Unlock()
+	if buffered != 1 {
+		t.Errorf("buffered statements = %d, want 1", buffered)
+	}
+	if _, err := tx.DB(); err != nil {
+		t.Errorf("DB() in transaction: %v", err)
+	}
+}
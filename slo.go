@@ -0,0 +1,215 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"go.uber.org/zap"
+)
+
+// sloBucketWidth 滚动窗口的桶宽度，成功率以该粒度滑动
+const sloBucketWidth = 10 * time.Second
+
+// defaultSLOWindows 默认的成功率滚动窗口
+var defaultSLOWindows = []time.Duration{time.Minute, 5 * time.Minute, time.Hour}
+
+// SLOOptions SLI 指标配置
+// 数据源和配置都相同的连接共享统计；连接通过 CloseSQLDB、CloseRedis 或 Manager 关闭（含热更新替换）后停止发布
+type SLOOptions struct {
+	LatencyThresholds []time.Duration // 延迟阈值，超过阈值的请求计入违规计数，如 100ms、1s
+	Windows           []time.Duration // 成功率滚动窗口，默认 1m、5m、1h
+}
+
+// sloBucket 单个时间桶内的请求统计
+type sloBucket struct {
+	start   int64 // 桶起始时间（Unix 秒，按桶宽度对齐）
+	total   uint64
+	success uint64
+}
+
+// sloTracker 按数据源统计 SLI：滚动窗口成功率和延迟阈值违规次数
+type sloTracker struct {
+	key        string // 统计器注册键，由数据源和配置组成
+	datasource string
+	thresholds []time.Duration
+	windows    []time.Duration
+
+	mu      sync.Mutex
+	buckets []sloBucket // 环形缓冲区，覆盖最大窗口
+
+	refs int           // 引用计数，由 sloTrackersMu 保护
+	stop chan struct{} // 引用全部释放时关闭，停止发布成功率
+}
+
+var (
+	sloTrackersMu sync.Mutex
+	sloTrackers   = make(map[string]*sloTracker)
+)
+
+// sloTrackerFor 返回数据源和配置对应的 SLI 统计器，数据源和配置都相同的多个连接共享统计
+// 调用方不再使用时需调用 release，最后一个引用释放后停止发布成功率
+func sloTrackerFor(datasource string, opts *SLOOptions) *sloTracker {
+	if opts == nil {
+		return nil
+	}
+
+	windows := opts.Windows
+	if len(windows) == 0 {
+		windows = defaultSLOWindows
+	}
+	key := fmt.Sprintf("%s|%v|%v", datasource, windows, opts.LatencyThresholds)
+
+	sloTrackersMu.Lock()
+	defer sloTrackersMu.Unlock()
+	if t, ok := sloTrackers[key]; ok {
+		t.refs++
+		return t
+	}
+	for _, other := range sloTrackers {
+		if other.datasource == datasource {
+			log.Warn("SLO options differ between connections of the same datasource, success ratio gauges of shared windows are overwritten by each other",
+				zap.String("datasource", datasource),
+				zap.String("options", key),
+				zap.String("existing", other.key),
+			)
+			break
+		}
+	}
+
+	var maxWindow time.Duration
+	for _, w := range windows {
+		if w > maxWindow {
+			maxWindow = w
+		}
+	}
+
+	t := &sloTracker{
+		key:        key,
+		datasource: datasource,
+		thresholds: append([]time.Duration(nil), opts.LatencyThresholds...),
+		windows:    append([]time.Duration(nil), windows...),
+		buckets:    make([]sloBucket, int(maxWindow/sloBucketWidth)+1),
+		refs:       1,
+		stop:       make(chan struct{}),
+	}
+	sloTrackers[key] = t
+	go t.publishLoop()
+	return t
+}
+
+// release 释放一个引用，最后一个引用释放时注销统计器并停止发布成功率
+func (t *sloTracker) release() {
+	if t == nil {
+		return
+	}
+
+	sloTrackersMu.Lock()
+	defer sloTrackersMu.Unlock()
+	if t.refs == 0 {
+		return
+	}
+	t.refs--
+	if t.refs > 0 {
+		return
+	}
+	if sloTrackers[t.key] == t {
+		delete(sloTrackers, t.key)
+	}
+	close(t.stop)
+}
+
+// record 记录一次请求
+func (t *sloTracker) record(operation string, success bool, duration time.Duration) {
+	if t == nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	width := int64(sloBucketWidth / time.Second)
+	start := now - now%width
+	idx := int(start/width) % len(t.buckets)
+
+	t.mu.Lock()
+	b := &t.buckets[idx]
+	if b.start != start {
+		*b = sloBucket{start: start}
+	}
+	b.total++
+	if success {
+		b.success++
+	}
+	t.mu.Unlock()
+
+	if !metrics.IsEnabled() {
+		return
+	}
+	result := "success"
+	if !success {
+		result = "error"
+	}
+	DatabaseSLIRequestTotal.WithLabelValues(t.datasource, result).Inc()
+	for _, threshold := range t.thresholds {
+		if duration > threshold {
+			DatabaseSLILatencyViolationTotal.WithLabelValues(t.datasource, operation, threshold.String()).Inc()
+		}
+	}
+}
+
+// successRatio 计算指定窗口内的成功率，窗口内无请求时返回 1
+func (t *sloTracker) successRatio(window time.Duration) float64 {
+	now := time.Now().Unix()
+	oldest := now - int64(window/time.Second)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total, success uint64
+	for _, b := range t.buckets {
+		if b.total > 0 && b.start > oldest {
+			total += b.total
+			success += b.success
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(success) / float64(total)
+}
+
+// publishLoop 定期将滚动窗口成功率发布为 Gauge，直到引用全部释放
+func (t *sloTracker) publishLoop() {
+	ticker := time.NewTicker(sloBucketWidth)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+		if !metrics.IsEnabled() {
+			continue
+		}
+		for _, w := range t.windows {
+			DatabaseSLISuccessRatio.WithLabelValues(t.datasource, w.String()).Set(t.successRatio(w))
+		}
+	}
+}
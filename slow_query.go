@@ -0,0 +1,86 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// slowQueryCapacity 每个数据源保留的最近慢查询条数
+const slowQueryCapacity = 50
+
+// SlowQuery 一条慢查询记录
+type SlowQuery struct {
+	Datasource string        `json:"datasource"`      // 数据源标识，设置实例名时为实例名
+	SQL        string        `json:"sql"`             // 带参数值的完整 SQL
+	Operation  string        `json:"operation"`       // 操作类型，如 select、insert
+	Duration   time.Duration `json:"duration_ns"`     // 执行耗时
+	Error      string        `json:"error,omitempty"` // 执行失败时的错误
+	Time       time.Time     `json:"time"`            // 开始执行的时间
+}
+
+// slowQueryRing 单个数据源的最近慢查询，写满后覆盖最早的记录
+type slowQueryRing struct {
+	mu      sync.Mutex
+	entries []SlowQuery
+	next    int
+}
+
+// slowQueries 按数据源保存的最近慢查询
+var slowQueries sync.Map
+
+// recordSlowQuery 记录慢查询
+func recordSlowQuery(datasource string, q SlowQuery) {
+	v, _ := slowQueries.LoadOrStore(datasource, &slowQueryRing{})
+	r := v.(*slowQueryRing)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) < slowQueryCapacity {
+		r.entries = append(r.entries, q)
+		return
+	}
+	r.entries[r.next] = q
+	r.next = (r.next + 1) % slowQueryCapacity
+}
+
+// RecentSlowQueries 返回数据源最近的慢查询，按时间从新到旧排列，最多 50 条
+// 需启用追踪并设置慢查询阈值（WithSlowQueryThreshold 或配置 slow_query_threshold）
+func RecentSlowQueries(datasource string) []SlowQuery {
+	v, ok := slowQueries.Load(datasource)
+	if !ok {
+		return nil
+	}
+	r := v.(*slowQueryRing)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queries := make([]SlowQuery, 0, len(r.entries))
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		queries = append(queries, r.entries[(r.next+i)%len(r.entries)])
+	}
+	return queries
+}
+
+// slowQueryDatasources 返回有慢查询记录的数据源
+func slowQueryDatasources() []string {
+	var datasources []string
+	slowQueries.Range(func(k, _ any) bool {
+		datasources = append(datasources, k.(string))
+		return true
+	})
+	return datasources
+}
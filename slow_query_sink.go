@@ -0,0 +1,223 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// SlowQuerySink 慢查询的输出，独立于应用日志，供 DBA 直接订阅慢查询。
+// 在语句执行的 goroutine 中同步调用，实现应避免阻塞，需并发安全
+type SlowQuerySink interface {
+	WriteSlowQuery(ctx context.Context, q SlowQuery) error
+}
+
+// SlowQueryLogConfig 慢查询 JSON 文件配置，path 为空时不写入文件
+type SlowQueryLogConfig struct {
+	Path       string `yaml:"path"`         // 文件路径，每行一条 JSON 格式的慢查询
+	MaxSizeMB  int    `yaml:"max_size_mb"`  // 单个文件的最大大小，超过后轮转，默认 100
+	MaxBackups int    `yaml:"max_backups"`  // 保留的轮转文件数，0 表示全部保留
+	MaxAgeDays int    `yaml:"max_age_days"` // 轮转文件的保留天数，0 表示不按时间清理
+	Compress   bool   `yaml:"compress"`     // 是否 gzip 压缩轮转文件
+}
+
+// Validate 验证慢查询文件配置
+func (c *SlowQueryLogConfig) Validate() error {
+	if c.MaxSizeMB < 0 || c.MaxBackups < 0 || c.MaxAgeDays < 0 {
+		return fmt.Errorf("slow_query_log max_size_mb, max_backups and max_age_days must be non-negative")
+	}
+	return nil
+}
+
+// sinks 转换为慢查询输出，未设置路径时返回 nil
+func (c *SlowQueryLogConfig) sinks() []SlowQuerySink {
+	if c.Path == "" {
+		return nil
+	}
+	return []SlowQuerySink{NewSlowQueryFileSink(&SlowQueryFileSinkOptions{
+		Path:       c.Path,
+		MaxSizeMB:  c.MaxSizeMB,
+		MaxBackups: c.MaxBackups,
+		MaxAgeDays: c.MaxAgeDays,
+		Compress:   c.Compress,
+	})}
+}
+
+// SlowQueryFileSinkOptions 慢查询 JSON 文件配置选项
+type SlowQueryFileSinkOptions struct {
+	Path       string // 文件路径
+	MaxSizeMB  int    // 单个文件的最大大小，超过后轮转，默认 100
+	MaxBackups int    // 保留的轮转文件数，0 表示全部保留
+	MaxAgeDays int    // 轮转文件的保留天数，0 表示不按时间清理
+	Compress   bool   // 是否 gzip 压缩轮转文件
+}
+
+// SlowQueryFileSink 将慢查询按行写入 JSON 文件，按大小轮转，可直接 tail -f 或由日志采集器收集
+type SlowQueryFileSink struct {
+	mu  sync.Mutex
+	out *lumberjack.Logger
+}
+
+// NewSlowQueryFileSink 创建慢查询文件输出，文件在首次写入时创建
+func NewSlowQueryFileSink(opts *SlowQueryFileSinkOptions) *SlowQueryFileSink {
+	maxSize := opts.MaxSizeMB
+	if maxSize <= 0 {
+		maxSize = 100
+	}
+	return &SlowQueryFileSink{out: &lumberjack.Logger{
+		Filename:   opts.Path,
+		MaxSize:    maxSize,
+		MaxBackups: opts.MaxBackups,
+		MaxAge:     opts.MaxAgeDays,
+		Compress:   opts.Compress,
+		LocalTime:  true,
+	}}
+}
+
+// WriteSlowQuery 写入一行 JSON
+func (s *SlowQueryFileSink) WriteSlowQuery(_ context.Context, q SlowQuery) error {
+	line, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.out.Write(line)
+	recordSlowQuerySink("file", err)
+	return err
+}
+
+// Close 关闭文件
+func (s *SlowQueryFileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.out.Close()
+}
+
+// SlowQueryStreamSinkOptions 慢查询 Redis Stream 配置选项
+type SlowQueryStreamSinkOptions struct {
+	Stream string // Stream 键，默认 slow_queries
+	MaxLen int64  // Stream 保留的近似最大条数，默认 10000
+	Buffer int    // 等待写入的慢查询数，写满后丢弃新的慢查询，默认 1024
+}
+
+// SlowQueryStreamSink 将慢查询异步写入 Redis Stream，可通过 XREAD BLOCK 实时订阅或 XRANGE 查询，
+// 字段为 datasource、operation、sql、duration_ms、error、time。Redis 不可用时不阻塞语句执行，
+// 缓冲区写满后丢弃
+type SlowQueryStreamSink struct {
+	client redis.UniversalClient
+	opts   SlowQueryStreamSinkOptions
+
+	queue     chan SlowQuery
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewSlowQueryStreamSink 创建慢查询 Redis Stream 输出并启动后台写入，不再使用时调用 Close
+func NewSlowQueryStreamSink(client redis.UniversalClient, opts *SlowQueryStreamSinkOptions) (*SlowQueryStreamSink, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	s := &SlowQueryStreamSink{client: client}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Stream == "" {
+		s.opts.Stream = "slow_queries"
+	}
+	if s.opts.MaxLen <= 0 {
+		s.opts.MaxLen = 10000
+	}
+	if s.opts.Buffer <= 0 {
+		s.opts.Buffer = 1024
+	}
+	s.queue = make(chan SlowQuery, s.opts.Buffer)
+	s.done = make(chan struct{})
+	go s.run()
+	return s, nil
+}
+
+// WriteSlowQuery 将慢查询加入写入队列，队列已满时丢弃并返回错误
+func (s *SlowQueryStreamSink) WriteSlowQuery(_ context.Context, q SlowQuery) error {
+	select {
+	case s.queue <- q:
+		return nil
+	default:
+		recordSlowQuerySinkResult("redis_stream", "dropped")
+		return fmt.Errorf("slow query stream buffer full, dropped")
+	}
+}
+
+// Close 停止接收慢查询，写入队列中剩余的慢查询后返回，不会关闭底层 Redis 客户端
+func (s *SlowQueryStreamSink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.queue)
+	})
+	<-s.done
+	return nil
+}
+
+// run 后台逐条写入 Stream
+func (s *SlowQueryStreamSink) run() {
+	defer close(s.done)
+	for q := range s.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := s.client.XAdd(ctx, &redis.XAddArgs{
+			Stream: s.opts.Stream,
+			MaxLen: s.opts.MaxLen,
+			Approx: true,
+			Values: map[string]any{
+				"datasource":  q.Datasource,
+				"operation":   q.Operation,
+				"sql":         q.SQL,
+				"duration_ms": strconv.FormatFloat(float64(q.Duration.Microseconds())/1000.0, 'f', 3, 64),
+				"error":       q.Error,
+				"time":        q.Time.Format(time.RFC3339Nano),
+			},
+		}).Err()
+		cancel()
+		recordSlowQuerySink("redis_stream", err)
+	}
+}
+
+// recordSlowQuerySink 记录慢查询输出的写入结果
+func recordSlowQuerySink(sink string, err error) {
+	result := "written"
+	if err != nil {
+		result = "failed"
+	}
+	recordSlowQuerySinkResult(sink, result)
+}
+
+// recordSlowQuerySinkResult 记录慢查询输出指标
+func recordSlowQuerySinkResult(sink, result string) {
+	if metrics.IsEnabled() {
+		SlowQuerySinkWritesTotal.WithLabelValues(sink, result).Inc()
+	}
+}
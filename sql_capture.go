@@ -0,0 +1,194 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const callBackSQLCaptureName = "sql_capture:record"
+
+// errSQLCapture 捕获模式下的连接不执行任何语句
+var errSQLCapture = errors.New("sql capture mode does not execute statements")
+
+// CapturedSQL 捕获的语句
+type CapturedSQL struct {
+	Operation string // create、query、update、delete、row、raw
+	Table     string // 语句操作的表，原生语句为空
+	SQL       string // 带占位符的 SQL
+	Vars      []any  // 占位符参数
+	Statement string // 代入参数后的完整 SQL，用于审阅和生成脚本
+}
+
+// SQLSink 接收捕获的语句
+type SQLSink interface {
+	Capture(ctx context.Context, s CapturedSQL)
+}
+
+// SQLSinkFunc 函数形式的 SQLSink
+type SQLSinkFunc func(ctx context.Context, s CapturedSQL)
+
+// Capture 调用函数本身
+func (f SQLSinkFunc) Capture(ctx context.Context, s CapturedSQL) {
+	f(ctx, s)
+}
+
+// SQLCapture 在内存中保存捕获语句的 SQLSink
+type SQLCapture struct {
+	mu         sync.Mutex
+	statements []CapturedSQL
+}
+
+// NewSQLCapture 创建内存中的 SQLSink
+func NewSQLCapture() *SQLCapture {
+	return &SQLCapture{}
+}
+
+// Capture 保存语句
+func (c *SQLCapture) Capture(_ context.Context, s CapturedSQL) {
+	c.mu.Lock()
+	c.statements = append(c.statements, s)
+	c.mu.Unlock()
+}
+
+// Statements 返回按执行顺序捕获的语句
+func (c *SQLCapture) Statements() []CapturedSQL {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedSQL(nil), c.statements...)
+}
+
+// Reset 清空捕获的语句
+func (c *SQLCapture) Reset() {
+	c.mu.Lock()
+	c.statements = nil
+	c.mu.Unlock()
+}
+
+// WriteScript 将捕获的语句按顺序写成以分号结尾的 SQL 脚本，可用于生成迁移脚本
+func (c *SQLCapture) WriteScript(w io.Writer) error {
+	for _, s := range c.Statements() {
+		if _, err := fmt.Fprintf(w, "%s;\n", strings.TrimSuffix(strings.TrimSpace(s.Statement), ";")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureSQL 返回不执行任何语句、只将生成的完整 SQL 写入 sink 的会话（基于 GORM 的 DryRun）。
+// 会话使用不连接数据库的连接池，事务不会发出 BEGIN/COMMIT，依赖查询结果的操作（如 Row、Rows、Raw(...).Scan）
+// 得到空结果或 ErrDryRunModeUnsupported；关联保存等嵌套语句同样会被捕获
+func CaptureSQL(db *gorm.DB, sink SQLSink) (*gorm.DB, error) {
+	if sink == nil {
+		return nil, fmt.Errorf("sql sink cannot be nil")
+	}
+	if db.Callback().Raw().Get(callBackSQLCaptureName) == nil {
+		cb := db.Callback()
+		if err := cb.Create().After("gorm:create").Register(callBackSQLCaptureName, captureSQL("create")); err != nil {
+			return nil, fmt.Errorf("failed to register sql capture callback: %w", err)
+		}
+		if err := cb.Query().After("gorm:query").Register(callBackSQLCaptureName, captureSQL("query")); err != nil {
+			return nil, fmt.Errorf("failed to register sql capture callback: %w", err)
+		}
+		if err := cb.Update().After("gorm:update").Register(callBackSQLCaptureName, captureSQL("update")); err != nil {
+			return nil, fmt.Errorf("failed to register sql capture callback: %w", err)
+		}
+		if err := cb.Delete().After("gorm:delete").Register(callBackSQLCaptureName, captureSQL("delete")); err != nil {
+			return nil, fmt.Errorf("failed to register sql capture callback: %w", err)
+		}
+		if err := cb.Row().After("gorm:row").Register(callBackSQLCaptureName, captureSQL("row")); err != nil {
+			return nil, fmt.Errorf("failed to register sql capture callback: %w", err)
+		}
+		if err := cb.Raw().After("gorm:raw").Register(callBackSQLCaptureName, captureSQL("raw")); err != nil {
+			return nil, fmt.Errorf("failed to register sql capture callback: %w", err)
+		}
+	}
+
+	tx := db.Session(&gorm.Session{DryRun: true}).Set(callBackSQLCaptureName, sink)
+	tx.Statement.ConnPool = captureConnPool{}
+	return tx.Session(&gorm.Session{}), nil
+}
+
+// captureSQL 返回将语句写入会话 sink 的回调
+func captureSQL(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.Statement.Settings.Load(callBackSQLCaptureName)
+		if !ok || db.Statement.SQL.Len() == 0 {
+			return
+		}
+		query := db.Statement.SQL.String()
+		// 事务在捕获模式下不生效，嵌套事务的保存点没有意义
+		if upper := strings.ToUpper(query); strings.HasPrefix(upper, "SAVEPOINT ") ||
+			strings.HasPrefix(upper, "ROLLBACK TO SAVEPOINT ") || strings.HasPrefix(upper, "RELEASE SAVEPOINT ") {
+			return
+		}
+		table := db.Statement.Table
+		if operation == "raw" {
+			table = ""
+		}
+		vars := append([]any(nil), db.Statement.Vars...)
+		v.(SQLSink).Capture(db.Statement.Context, CapturedSQL{
+			Operation: operation,
+			Table:     table,
+			SQL:       query,
+			Vars:      vars,
+			Statement: db.Dialector.Explain(query, vars...),
+		})
+	}
+}
+
+// captureConnPool 捕获模式使用的连接池，不连接数据库。实现 TxCommitter 使 Transaction
+// 视为已在事务中，不会发出 BEGIN，dbresolver 也不会切换到真实的连接池
+type captureConnPool struct{}
+
+// PrepareContext 返回错误
+func (captureConnPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errSQLCapture
+}
+
+// ExecContext 返回错误
+func (captureConnPool) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	return nil, errSQLCapture
+}
+
+// QueryContext 返回错误
+func (captureConnPool) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errSQLCapture
+}
+
+// QueryRowContext 返回 nil，DryRun 模式下 GORM 不会调用
+func (captureConnPool) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return nil
+}
+
+// Commit 不执行任何操作
+func (captureConnPool) Commit() error {
+	return nil
+}
+
+// Rollback 不执行任何操作
+func (captureConnPool) Rollback() error {
+	return nil
+}
@@ -0,0 +1,451 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	pkgtrace "github.com/go-anyway/framework-trace"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// SQLDBOptions 结构体定义了不经过 GORM 的 database/sql 连接的配置选项
+type SQLDBOptions struct {
+	DriverName            string // 已注册的 database/sql 驱动名，如 mysql、pgx
+	DSN                   string
+	Datasource            string // 数据源标识，用于 SLI 指标和 span 的 db.system，默认使用 DriverName
+	MaxIdleConnections    int
+	MaxOpenConnections    int
+	MaxConnectionLifeTime time.Duration
+	EnableTrace           bool          // 是否启用驱动层追踪，记录每条语句的 span、日志和指标
+	ErrorLogInterval      time.Duration // 相同错误日志的去重窗口，0 使用默认值 10s，负数表示不去重
+	ErrorReporter         ErrorReporter // 语句失败时的上报，需启用追踪，nil 表示不上报
+	SLO                   *SLOOptions   // SLI 指标配置，nil 表示不统计
+}
+
+// SQLDBOptions 转换为 NewSQLDB 使用的选项，复用 GORM 连接器的 DSN、连接池和追踪配置
+// 动态密码提供者不生效，使用创建选项时读取的密码
+func (o *Options) SQLDBOptions() *SQLDBOptions {
+	return &SQLDBOptions{
+		DriverName:            "mysql",
+		DSN:                   mySQLDSN(o),
+		Datasource:            mySQLDriver(o),
+		MaxIdleConnections:    o.MaxIdleConnections,
+		MaxOpenConnections:    o.MaxOpenConnections,
+		MaxConnectionLifeTime: o.MaxConnectionLifeTime,
+		EnableTrace:           o.EnableTrace,
+		ErrorLogInterval:      o.ErrorLogInterval,
+		ErrorReporter:         o.ErrorReporter,
+		SLO:                   o.SLO,
+	}
+}
+
+// SQLDBOptions 转换为 NewSQLDB 使用的选项，复用 GORM 连接器的 DSN、连接池和追踪配置
+// 动态密码提供者不生效，使用创建选项时读取的密码
+func (o *PostgreSQLOptions) SQLDBOptions() *SQLDBOptions {
+	return &SQLDBOptions{
+		DriverName:            "pgx",
+		DSN:                   postgreSQLDSN(o),
+		Datasource:            postgreSQLDriver(o),
+		MaxIdleConnections:    o.MaxIdleConnections,
+		MaxOpenConnections:    o.MaxOpenConnections,
+		MaxConnectionLifeTime: o.MaxConnectionLifeTime,
+		EnableTrace:           o.EnableTrace,
+		ErrorLogInterval:      o.ErrorLogInterval,
+		ErrorReporter:         o.ErrorReporter,
+		SLO:                   o.SLO,
+	}
+}
+
+// NewSQLDB 根据给定的选项创建一个 *sql.DB，适用于 sqlc 或手写 SQL 等不使用 GORM 的场景
+// 启用追踪时在驱动层包装连接，记录与 GORM 追踪插件相同的 span、日志、Prometheus 指标和 SLI
+func NewSQLDB(opts *SQLDBOptions) (*sql.DB, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("sql db options cannot be nil")
+	}
+	if opts.DriverName == "" {
+		return nil, fmt.Errorf("sql db driver name is required")
+	}
+
+	datasource := opts.Datasource
+	if datasource == "" {
+		datasource = opts.DriverName
+	}
+
+	connector, err := openConnector(opts.DriverName, opts.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if opts.EnableTrace {
+		connector = &tracedConnector{
+			Connector: connector,
+			tracer: newSQLTracer(
+				withDatasource(datasource),
+				withDBSystem(datasource),
+				WithErrorLogInterval(opts.ErrorLogInterval),
+				WithErrorReporter(opts.ErrorReporter),
+				WithSLO(opts.SLO),
+			),
+		}
+	}
+
+	sqlDB := sql.OpenDB(connector)
+	if opts.MaxOpenConnections > 0 {
+		sqlDB.SetMaxOpenConns(opts.MaxOpenConnections)
+	}
+	if opts.MaxConnectionLifeTime > 0 {
+		sqlDB.SetConnMaxLifetime(opts.MaxConnectionLifeTime)
+	}
+	if opts.MaxIdleConnections > 0 {
+		sqlDB.SetMaxIdleConns(opts.MaxIdleConnections)
+	}
+	return sqlDB, nil
+}
+
+// openConnector 根据驱动名和 DSN 创建 driver.Connector
+func openConnector(driverName, dsn string) (driver.Connector, error) {
+	// sql.Open 不建立连接，仅用于按名称查找已注册的驱动
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sql driver %s: %w", driverName, err)
+	}
+	drv := probe.Driver()
+	_ = probe.Close()
+
+	if dc, ok := drv.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s connector: %w", driverName, err)
+		}
+		return connector, nil
+	}
+	return dsnConnector{dsn: dsn, driver: drv}, nil
+}
+
+// dsnConnector 为未实现 driver.DriverContext 的驱动提供 driver.Connector
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.driver }
+
+// sqlTracer 驱动层的语句追踪，与 GormTracePlugin 记录相同的 span、日志、指标和 SLI
+type sqlTracer struct {
+	opts         traceOptions
+	errorSampler *errorLogSampler
+	slo          *sloTracker
+}
+
+// newSQLTracer 创建驱动层语句追踪
+func newSQLTracer(opts ...TraceOption) *sqlTracer {
+	o := newTraceOptions(opts...)
+	return &sqlTracer{
+		opts:         o,
+		errorSampler: newErrorLogSampler(o.errorLogInterval),
+		slo:          sloTrackerFor(o.datasource, o.slo),
+	}
+}
+
+// start 开始追踪一条语句，返回带 span 的 context 和结束追踪的函数
+// kind 为 exec 或 query，用于并发查询数指标
+func (t *sqlTracer) start(ctx context.Context, kind, query string) (context.Context, func(error)) {
+	operation := sqlOperation(query)
+	begin := time.Now()
+
+	if t.opts.metricsEnabled() {
+		t.opts.metrics.addInFlight(ctx, t.opts.datasource, kind, 1)
+	}
+	ctx, span := pkgtrace.StartSpan(ctx, "sql."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(t.opts.spanAttributes(
+			attribute.String("db.system", t.opts.dbSystem),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", query),
+		)...),
+	)
+
+	return ctx, func(err error) {
+		duration := time.Since(begin)
+		// driver.ErrSkip 表示驱动不支持该调用，database/sql 会改用其他方式重新执行，不计入统计
+		if errors.Is(err, driver.ErrSkip) {
+			if t.opts.metricsEnabled() {
+				t.opts.metrics.addInFlight(ctx, t.opts.datasource, kind, -1)
+			}
+			span.End()
+			return
+		}
+
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+
+		span.SetAttributes(attribute.Float64("db.duration_ms", float64(duration.Milliseconds())))
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			span.RecordError(err)
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		span.End()
+
+		// 记录日志，相同错误在采样窗口内只记录一次
+		if err != nil {
+			errMsg := err.Error()
+			if t.errorSampler.allow(operation+"|"+errMsg, zap.String("operation", operation), zap.String("error", errMsg)) {
+				t.opts.logger(ctx).Error(
+					"SQL execution failed",
+					zap.Float64("cost_ms", float64(duration.Microseconds())/1000.0),
+					zap.String("sql", query),
+					zap.String("operation", operation),
+					zap.String("status", status),
+					zap.Error(err),
+				)
+			}
+		} else {
+			t.opts.logger(ctx).Info(
+				"SQL cost time",
+				zap.Float64("cost_ms", float64(duration.Microseconds())/1000.0),
+				zap.String("sql", query),
+				zap.String("operation", operation),
+				zap.String("status", status),
+			)
+		}
+
+		if t.opts.metricsEnabled() {
+			t.opts.metrics.addInFlight(ctx, t.opts.datasource, kind, -1)
+			t.opts.metrics.recordQuery(ctx, t.opts.dbSystem, operation, status, duration)
+		}
+		t.opts.recordInstance(ctx, t.opts.dbSystem, operation, status, duration)
+		t.opts.reportError(ctx, ErrorEvent{
+			System:    t.opts.dbSystem,
+			Operation: operation,
+			Statement: query,
+			Duration:  duration,
+			Err:       err,
+		})
+		t.slo.record(operation, err == nil || errors.Is(err, sql.ErrNoRows), duration)
+	}
+}
+
+// sqlOperation 根据 SQL 语句的第一个关键字确定操作类型，与 GORM 追踪插件的取值一致
+func sqlOperation(query string) string {
+	keyword := strings.TrimSpace(query)
+	if i := strings.IndexFunc(keyword, unicode.IsSpace); i >= 0 {
+		keyword = keyword[:i]
+	}
+	switch strings.ToUpper(keyword) {
+	case "SELECT":
+		return "select"
+	case "INSERT":
+		return "insert"
+	case "UPDATE":
+		return "update"
+	case "DELETE":
+		return "delete"
+	}
+	return "other"
+}
+
+// tracedConnector 为新建的连接添加追踪
+type tracedConnector struct {
+	driver.Connector
+	tracer *sqlTracer
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, tracer: c.tracer}, nil
+}
+
+// tracedConn 追踪直接在连接上执行的语句，其余可选接口透传给底层连接
+type tracedConn struct {
+	driver.Conn
+	tracer *sqlTracer
+}
+
+var (
+	_ driver.ExecerContext      = (*tracedConn)(nil)
+	_ driver.QueryerContext     = (*tracedConn)(nil)
+	_ driver.ConnPrepareContext = (*tracedConn)(nil)
+	_ driver.ConnBeginTx        = (*tracedConn)(nil)
+	_ driver.Pinger             = (*tracedConn)(nil)
+	_ driver.SessionResetter    = (*tracedConn)(nil)
+	_ driver.Validator          = (*tracedConn)(nil)
+	_ driver.NamedValueChecker  = (*tracedConn)(nil)
+)
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, finish := c.tracer.start(ctx, "exec", query)
+	res, err := execer.ExecContext(ctx, query, args)
+	finish(err)
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, finish := c.tracer.start(ctx, "query", query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	finish(err)
+	return rows, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query, tracer: c.tracer}, nil
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() // 驱动未实现 ConnBeginTx 时的回退
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// tracedStmt 追踪预编译语句的执行
+type tracedStmt struct {
+	driver.Stmt
+	query  string
+	tracer *sqlTracer
+}
+
+var (
+	_ driver.StmtExecContext   = (*tracedStmt)(nil)
+	_ driver.StmtQueryContext  = (*tracedStmt)(nil)
+	_ driver.NamedValueChecker = (*tracedStmt)(nil)
+)
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, finish := s.tracer.start(ctx, "exec", s.query)
+	var (
+		res driver.Result
+		err error
+	)
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = s.Stmt.Exec(values) // 驱动未实现 StmtExecContext 时的回退
+		}
+	}
+	finish(err)
+	return res, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, finish := s.tracer.start(ctx, "query", s.query)
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.Stmt.Query(values) // 驱动未实现 StmtQueryContext 时的回退
+		}
+	}
+	finish(err)
+	return rows, err
+}
+
+func (s *tracedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedValuesToValues 将参数转换为旧版驱动接口使用的位置参数，旧版接口不支持命名参数
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("sql driver does not support named parameter %s", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
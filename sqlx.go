@@ -0,0 +1,37 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+// NewSQLX 根据给定的选项创建一个 *sqlx.DB，连接池和驱动层追踪与 NewSQLDB 相同
+// 可通过 MySQLConfig/PostgreSQLConfig 的 ToOptions 校验配置后调用 SQLDBOptions 获得选项：
+//
+//	opts, err := cfg.ToOptions()
+//	...
+//	sqlxDB, err := db.NewSQLX(opts.SQLDBOptions())
+//
+// 占位符类型按 DriverName 确定（mysql 使用 ?，pgx 使用 $1）
+func NewSQLX(opts *SQLDBOptions) (*sqlx.DB, error) {
+	sqlDB, err := NewSQLDB(opts)
+	if err != nil {
+		return nil, err
+	}
+	return sqlx.NewDb(sqlDB, opts.DriverName), nil
+}
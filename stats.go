@@ -0,0 +1,103 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"sort"
+	"time"
+)
+
+// SQLPoolStats SQL 连接池统计，对应 sql.DBStats
+type SQLPoolStats struct {
+	Name              string        `json:"name"`                 // 注册名称
+	Type              string        `json:"type"`                 // mysql、postgresql
+	MaxOpen           int           `json:"max_open"`             // 最大连接数
+	Open              int           `json:"open"`                 // 当前连接数（使用中和空闲）
+	InUse             int           `json:"in_use"`               // 使用中的连接数
+	Idle              int           `json:"idle"`                 // 空闲连接数
+	WaitCount         int64         `json:"wait_count"`           // 等待可用连接的总次数
+	WaitDuration      time.Duration `json:"wait_duration_ns"`     // 等待可用连接的总时间
+	MaxIdleClosed     int64         `json:"max_idle_closed"`      // 因超过最大空闲数关闭的连接数
+	MaxIdleTimeClosed int64         `json:"max_idle_time_closed"` // 因超过最大空闲时间关闭的连接数
+	MaxLifetimeClosed int64         `json:"max_lifetime_closed"`  // 因超过最大存活时间关闭的连接数
+	Error             string        `json:"error,omitempty"`      // 无法取得统计的原因，如连接已关闭
+}
+
+// RedisPoolStats Redis 连接池统计，对应 redis.PoolStats
+type RedisPoolStats struct {
+	Name         string        `json:"name"`             // 注册名称
+	Hits         uint32        `json:"hits"`             // 从连接池取得空闲连接的次数
+	Misses       uint32        `json:"misses"`           // 连接池中没有空闲连接的次数
+	Timeouts     uint32        `json:"timeouts"`         // 等待连接超时的次数
+	WaitCount    uint32        `json:"wait_count"`       // 等待可用连接的次数
+	WaitDuration time.Duration `json:"wait_duration_ns"` // 等待可用连接的总时间
+	Total        uint32        `json:"total"`            // 当前连接数
+	Idle         uint32        `json:"idle"`             // 空闲连接数
+	Stale        uint32        `json:"stale"`            // 被移除的失效连接数
+}
+
+// Stats 管理器中所有连接的连接池统计快照
+type Stats struct {
+	SQL   []SQLPoolStats   `json:"sql"`   // 按名称排序
+	Redis []RedisPoolStats `json:"redis"` // 按名称排序
+}
+
+// Stats 返回所有已注册连接的连接池统计快照，可用于服务自己的管理端点
+func (m *Manager) Stats() Stats {
+	stats := Stats{SQL: []SQLPoolStats{}, Redis: []RedisPoolStats{}}
+
+	m.mu.RLock()
+	for name, conn := range m.sql {
+		s := SQLPoolStats{Name: name, Type: conn.driver}
+		sqlDB, err := SQLDB(conn.db)
+		if err != nil {
+			s.Error = err.Error()
+			stats.SQL = append(stats.SQL, s)
+			continue
+		}
+		db := sqlDB.Stats()
+		s.MaxOpen = db.MaxOpenConnections
+		s.Open = db.OpenConnections
+		s.InUse = db.InUse
+		s.Idle = db.Idle
+		s.WaitCount = db.WaitCount
+		s.WaitDuration = db.WaitDuration
+		s.MaxIdleClosed = db.MaxIdleClosed
+		s.MaxIdleTimeClosed = db.MaxIdleTimeClosed
+		s.MaxLifetimeClosed = db.MaxLifetimeClosed
+		stats.SQL = append(stats.SQL, s)
+	}
+	for name, conn := range m.redis {
+		p := conn.client.PoolStats()
+		stats.Redis = append(stats.Redis, RedisPoolStats{
+			Name:         name,
+			Hits:         p.Hits,
+			Misses:       p.Misses,
+			Timeouts:     p.Timeouts,
+			WaitCount:    p.WaitCount,
+			WaitDuration: time.Duration(p.WaitDurationNs),
+			Total:        p.TotalConns,
+			Idle:         p.IdleConns,
+			Stale:        p.StaleConns,
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(stats.SQL, func(i, j int) bool { return stats.SQL[i].Name < stats.SQL[j].Name })
+	sort.Slice(stats.Redis, func(i, j int) bool { return stats.Redis[i].Name < stats.Redis[j].Name })
+	return stats
+}
@@ -0,0 +1,415 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+	"github.com/go-anyway/framework-metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// StreamHandler 处理 Stream 消息，返回 nil 时确认消息，返回错误时消息保留在待处理列表中等待重试
+type StreamHandler func(ctx context.Context, stream string, msg redis.XMessage) error
+
+// StreamConsumerOptions Stream 消费者组配置选项
+type StreamConsumerOptions struct {
+	Streams          []string      // 消费的 Stream 列表
+	Group            string        // 消费者组名称
+	Consumer         string        // 消费者名称，默认 hostname-pid
+	StartID          string        // 创建消费者组时的起始 ID，默认 "$"（仅消费新消息）
+	Concurrency      int           // 并发处理的 worker 数，默认 1
+	BatchSize        int64         // 每次读取/认领的最大消息数，默认 10
+	Block            time.Duration // XREADGROUP 阻塞等待时间，默认 5s
+	MaxRetries       int64         // 最大投递次数，超过后转入死信 Stream，默认 3
+	DeadLetterSuffix string        // 死信 Stream 后缀，默认 ":dead"
+	ClaimMinIdle     time.Duration // 待处理消息空闲超过该时间后被重新认领，默认 1m
+	ClaimInterval    time.Duration // 认领待处理消息的间隔，默认 30s
+	LagInterval      time.Duration // 上报消费延迟指标的间隔，默认 15s
+}
+
+// streamDelivery 分发给 worker 的消息
+type streamDelivery struct {
+	stream string
+	msg    redis.XMessage
+}
+
+// StreamConsumer 基于 Redis Streams 消费者组的消费者
+// 支持并发处理、认领超时未确认的消息、超过重试次数转入死信 Stream 以及优雅停止
+type StreamConsumer struct {
+	client  redis.UniversalClient
+	opts    StreamConsumerOptions
+	handler StreamHandler
+
+	deliveries chan streamDelivery
+	ctx        context.Context
+	cancel     context.CancelFunc
+	loops      sync.WaitGroup
+	workers    sync.WaitGroup
+	startOnce  sync.Once
+	stopOnce   sync.Once
+}
+
+// NewStreamConsumer 创建 Stream 消费者，调用 Start 后开始消费
+func NewStreamConsumer(client redis.UniversalClient, opts *StreamConsumerOptions, handler StreamHandler) (*StreamConsumer, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client cannot be nil")
+	}
+	if opts == nil {
+		return nil, fmt.Errorf("stream consumer options cannot be nil")
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("handler cannot be nil")
+	}
+	if len(opts.Streams) == 0 {
+		return nil, fmt.Errorf("at least one stream is required")
+	}
+	if opts.Group == "" {
+		return nil, fmt.Errorf("consumer group is required")
+	}
+
+	c := &StreamConsumer{
+		client:  client,
+		opts:    *opts,
+		handler: handler,
+	}
+	c.opts.Streams = append([]string(nil), opts.Streams...)
+	if c.opts.Consumer == "" {
+		host, _ := os.Hostname()
+		c.opts.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if c.opts.StartID == "" {
+		c.opts.StartID = "$"
+	}
+	if c.opts.Concurrency <= 0 {
+		c.opts.Concurrency = 1
+	}
+	if c.opts.BatchSize <= 0 {
+		c.opts.BatchSize = 10
+	}
+	if c.opts.Block <= 0 {
+		c.opts.Block = 5 * time.Second
+	}
+	if c.opts.MaxRetries <= 0 {
+		c.opts.MaxRetries = 3
+	}
+	if c.opts.DeadLetterSuffix == "" {
+		c.opts.DeadLetterSuffix = ":dead"
+	}
+	if c.opts.ClaimMinIdle <= 0 {
+		c.opts.ClaimMinIdle = time.Minute
+	}
+	if c.opts.ClaimInterval <= 0 {
+		c.opts.ClaimInterval = 30 * time.Second
+	}
+	if c.opts.LagInterval <= 0 {
+		c.opts.LagInterval = 15 * time.Second
+	}
+	c.deliveries = make(chan streamDelivery)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c, nil
+}
+
+// Start 创建消费者组（已存在时忽略）并启动读取、认领、指标上报和处理协程
+func (c *StreamConsumer) Start(ctx context.Context) error {
+	for _, stream := range c.opts.Streams {
+		err := c.client.XGroupCreateMkStream(ctx, stream, c.opts.Group, c.opts.StartID).Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("failed to create consumer group %s on stream %s: %w", c.opts.Group, stream, err)
+		}
+	}
+
+	c.startOnce.Do(func() {
+		for i := 0; i < c.opts.Concurrency; i++ {
+			c.workers.Add(1)
+			go c.work()
+		}
+		c.loops.Add(3)
+		go c.readLoop()
+		go c.claimLoop()
+		go c.lagLoop()
+	})
+	return nil
+}
+
+// Stop 停止读取新消息，等待处理中的消息完成，ctx 超时后直接返回
+// 未确认的消息保留在待处理列表中，由其他消费者认领
+func (c *StreamConsumer) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	c.stopOnce.Do(func() {
+		c.cancel()
+		go func() {
+			c.loops.Wait()
+			close(c.deliveries)
+			c.workers.Wait()
+			close(done)
+		}()
+	})
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stream consumer stop: %w", ctx.Err())
+	}
+}
+
+// readLoop 通过 XREADGROUP 读取新消息
+func (c *StreamConsumer) readLoop() {
+	defer c.loops.Done()
+
+	args := make([]string, 0, len(c.opts.Streams)*2)
+	args = append(args, c.opts.Streams...)
+	for range c.opts.Streams {
+		args = append(args, ">")
+	}
+
+	for c.ctx.Err() == nil {
+		streams, err := c.client.XReadGroup(c.ctx, &redis.XReadGroupArgs{
+			Group:    c.opts.Group,
+			Consumer: c.opts.Consumer,
+			Streams:  args,
+			Count:    c.opts.BatchSize,
+			Block:    c.opts.Block,
+		}).Result()
+		if c.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, redis.Nil) {
+				log.Warn("Redis stream read failed", zap.String("group", c.opts.Group), zap.Error(err))
+				c.sleep(time.Second)
+			}
+			continue
+		}
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				if !c.dispatch(s.Stream, msg) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// claimLoop 周期性认领空闲超时的待处理消息，超过最大投递次数的消息转入死信 Stream
+func (c *StreamConsumer) claimLoop() {
+	defer c.loops.Done()
+
+	ticker := time.NewTicker(c.opts.ClaimInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, stream := range c.opts.Streams {
+			if err := c.claim(stream); err != nil && c.ctx.Err() == nil {
+				log.Warn("Redis stream claim failed",
+					zap.String("stream", stream), zap.String("group", c.opts.Group), zap.Error(err))
+			}
+		}
+	}
+}
+
+// claim 认领单个 Stream 中空闲超时的待处理消息
+func (c *StreamConsumer) claim(stream string) error {
+	pending, err := c.client.XPendingExt(c.ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  c.opts.Group,
+		Idle:   c.opts.ClaimMinIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  c.opts.BatchSize,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	retry := make([]string, 0, len(pending))
+	for _, p := range pending {
+		if p.RetryCount >= c.opts.MaxRetries {
+			if err := c.deadLetter(stream, p); err != nil {
+				return err
+			}
+			continue
+		}
+		retry = append(retry, p.ID)
+	}
+	if len(retry) == 0 {
+		return nil
+	}
+
+	msgs, err := c.client.XClaim(c.ctx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    c.opts.Group,
+		Consumer: c.opts.Consumer,
+		MinIdle:  c.opts.ClaimMinIdle,
+		Messages: retry,
+	}).Result()
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		recordStreamMessage(stream, c.opts.Group, "retried")
+		if !c.dispatch(stream, msg) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// deadLetter 将消息写入死信 Stream 并确认原消息
+func (c *StreamConsumer) deadLetter(stream string, p redis.XPendingExt) error {
+	msgs, err := c.client.XRangeN(c.ctx, stream, p.ID, p.ID, 1).Result()
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{
+		"_stream":      stream,
+		"_group":       c.opts.Group,
+		"_id":          p.ID,
+		"_retry_count": p.RetryCount,
+	}
+	if len(msgs) > 0 {
+		for k, v := range msgs[0].Values {
+			values[k] = v
+		}
+	}
+
+	// 死信 Stream 与原 Stream 可能位于不同槽位，不使用事务；消息已被裁剪时仍需确认，避免反复认领
+	if err := c.client.XAdd(c.ctx, &redis.XAddArgs{Stream: stream + c.opts.DeadLetterSuffix, Values: values}).Err(); err != nil {
+		return err
+	}
+	if err := c.client.XAck(c.ctx, stream, c.opts.Group, p.ID).Err(); err != nil {
+		return err
+	}
+
+	log.Warn("Redis stream message moved to dead letter stream",
+		zap.String("stream", stream),
+		zap.String("group", c.opts.Group),
+		zap.String("id", p.ID),
+		zap.Int64("retry_count", p.RetryCount),
+	)
+	recordStreamMessage(stream, c.opts.Group, "dead_lettered")
+	return nil
+}
+
+// lagLoop 周期性上报消费者组的延迟和待处理消息数
+func (c *StreamConsumer) lagLoop() {
+	defer c.loops.Done()
+
+	ticker := time.NewTicker(c.opts.LagInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !metrics.IsEnabled() {
+			continue
+		}
+		for _, stream := range c.opts.Streams {
+			groups, err := c.client.XInfoGroups(c.ctx, stream).Result()
+			if err != nil {
+				continue
+			}
+			for _, g := range groups {
+				if g.Name != c.opts.Group {
+					continue
+				}
+				RedisStreamPending.WithLabelValues(stream, g.Name).Set(float64(g.Pending))
+				if g.Lag >= 0 {
+					RedisStreamLag.WithLabelValues(stream, g.Name).Set(float64(g.Lag))
+				}
+			}
+		}
+	}
+}
+
+// dispatch 将消息交给 worker，消费者停止时返回 false
+func (c *StreamConsumer) dispatch(stream string, msg redis.XMessage) bool {
+	select {
+	case c.deliveries <- streamDelivery{stream: stream, msg: msg}:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// work 处理消息，成功后确认
+func (c *StreamConsumer) work() {
+	defer c.workers.Done()
+
+	for d := range c.deliveries {
+		// 使用独立的 context，停止时允许处理中的消息完成并确认
+		ctx := context.WithoutCancel(c.ctx)
+		if err := c.handle(ctx, d); err != nil {
+			log.Error("Redis stream handler failed",
+				zap.String("stream", d.stream),
+				zap.String("group", c.opts.Group),
+				zap.String("id", d.msg.ID),
+				zap.Error(err),
+			)
+			recordStreamMessage(d.stream, c.opts.Group, "failed")
+			continue
+		}
+		if err := c.client.XAck(ctx, d.stream, c.opts.Group, d.msg.ID).Err(); err != nil {
+			log.Warn("Redis stream ack failed",
+				zap.String("stream", d.stream), zap.String("id", d.msg.ID), zap.Error(err))
+			continue
+		}
+		recordStreamMessage(d.stream, c.opts.Group, "acked")
+	}
+}
+
+// handle 调用处理函数，捕获 panic 并转换为错误
+func (c *StreamConsumer) handle(ctx context.Context, d streamDelivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return c.handler(ctx, d.stream, d.msg)
+}
+
+// sleep 等待指定时间，消费者停止时提前返回
+func (c *StreamConsumer) sleep(d time.Duration) {
+	select {
+	case <-c.ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// recordStreamMessage 记录 Stream 消息处理指标
+func recordStreamMessage(stream, group, status string) {
+	if metrics.IsEnabled() {
+		RedisStreamMessageTotal.WithLabelValues(stream, group, status).Inc()
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoTenant context 中没有租户
+	ErrNoTenant = errors.New("no tenant in context")
+	// ErrTenantNotFound 租户未注册
+	ErrTenantNotFound = errors.New("tenant not found")
+)
+
+// Tenant 租户
+type Tenant struct {
+	ID                 string // 租户标识，与 WithTenant 写入 context 的值对应
+	Database           string // MySQL 数据库名或 PostgreSQL schema，默认与 ID 相同
+	MaxOpenConnections int    // 租户连接池的最大连接数，0 使用基础配置
+	MaxIdleConnections int    // 租户连接池的最大空闲连接数，0 使用基础配置
+}
+
+// TenantRouterOptions 租户路由配置选项
+type TenantRouterOptions struct {
+	Resolver       func(ctx context.Context) (string, bool) // 从 context 解析租户，默认读取 WithTenant 写入的值
+	CreateDatabase bool                                     // 首次连接租户时创建不存在的数据库或 schema
+}
+
+// tenantConn 已注册的租户及其连接池
+type tenantConn struct {
+	tenant Tenant
+
+	mu     sync.Mutex // 串行化首次连接和关闭
+	db     *gorm.DB   // 连接成功后缓存，连接失败时保持 nil，下次调用重试
+	closed bool
+}
+
+// TenantRouter 按租户路由数据库连接：MySQL 每个租户使用独立的数据库，PostgreSQL 每个租户使用独立的 schema（search_path）
+// 每个租户拥有独立的连接池，在首次使用时按基础配置创建，租户之间的连接数互不影响
+type TenantRouter struct {
+	driver  string
+	opts    TenantRouterOptions
+	connect func(t Tenant) (*gorm.DB, error)
+
+	mu      sync.RWMutex
+	tenants map[string]*tenantConn
+}
+
+// NewMySQLTenantRouter 创建 MySQL 租户路由，租户连接使用 base 的配置并将数据库替换为租户的数据库
+func NewMySQLTenantRouter(base *Options, opts *TenantRouterOptions) (*TenantRouter, error) {
+	if base == nil {
+		return nil, fmt.Errorf("base options cannot be nil")
+	}
+	r := newTenantRouter(driverMySQL, opts)
+	r.connect = func(t Tenant) (*gorm.DB, error) {
+		o := *base
+		o.Database = t.Database
+		o.CreateDatabase = r.opts.CreateDatabase
+		o.IsolationReadEngines = append([]string(nil), base.IsolationReadEngines...)
+		o.Hosts = append([]string(nil), base.Hosts...)
+		o.SessionVars = cloneStringMap(base.SessionVars)
+		applyTenantPool(t, &o.MaxOpenConnections, &o.MaxIdleConnections)
+		return New(&o)
+	}
+	return r, nil
+}
+
+// NewPostgreSQLTenantRouter 创建 PostgreSQL 租户路由，租户连接使用 base 的配置并将 search_path 设置为租户的 schema
+func NewPostgreSQLTenantRouter(base *PostgreSQLOptions, opts *TenantRouterOptions) (*TenantRouter, error) {
+	if base == nil {
+		return nil, fmt.Errorf("base options cannot be nil")
+	}
+	r := newTenantRouter(driverPostgreSQL, opts)
+	r.connect = func(t Tenant) (*gorm.DB, error) {
+		o := *base
+		o.Hosts = append([]string(nil), base.Hosts...)
+		o.SessionVars = cloneStringMap(base.SessionVars)
+		if o.SessionVars == nil {
+			o.SessionVars = make(map[string]string, 1)
+		}
+		o.SessionVars["search_path"] = quotePostgreSQLIdent(t.Database)
+		// 数据库由基础配置决定，租户只需创建 schema
+		o.CreateDatabase = r.opts.CreateDatabase
+		o.Schema = t.Database
+		applyTenantPool(t, &o.MaxOpenConnections, &o.MaxIdleConnections)
+		return NewPostgreSQL(&o)
+	}
+	return r, nil
+}
+
+// newTenantRouter 创建租户路由，填充默认配置
+func newTenantRouter(driver string, opts *TenantRouterOptions) *TenantRouter {
+	r := &TenantRouter{driver: driver, tenants: make(map[string]*tenantConn)}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.Resolver == nil {
+		r.opts.Resolver = TenantKey.From
+	}
+	return r
+}
+
+// applyTenantPool 使用租户的连接池配置覆盖基础配置
+func applyTenantPool(t Tenant, maxOpen, maxIdle *int) {
+	if t.MaxOpenConnections > 0 {
+		*maxOpen = t.MaxOpenConnections
+	}
+	if t.MaxIdleConnections > 0 {
+		*maxIdle = t.MaxIdleConnections
+	}
+}
+
+// cloneStringMap 复制 map，nil 时返回 nil
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Register 注册租户，连接池在首次使用时创建
+func (r *TenantRouter) Register(t Tenant) error {
+	if t.ID == "" {
+		return fmt.Errorf("tenant id cannot be empty")
+	}
+	if t.Database == "" {
+		t.Database = t.ID
+	}
+	if !sqlOptionPattern.MatchString(t.Database) {
+		return fmt.Errorf("invalid database name %q for tenant %s", t.Database, t.ID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tenants[t.ID]; exists {
+		return fmt.Errorf("tenant %q already registered", t.ID)
+	}
+	r.tenants[t.ID] = &tenantConn{tenant: t}
+	return nil
+}
+
+// Unregister 注销租户并关闭其连接池
+func (r *TenantRouter) Unregister(id string) error {
+	r.mu.Lock()
+	tc, ok := r.tenants[id]
+	delete(r.tenants, id)
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTenantNotFound, id)
+	}
+	return tc.close()
+}
+
+// Tenants 返回按 ID 排序的已注册租户
+func (r *TenantRouter) Tenants() []Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tenants := make([]Tenant, 0, len(r.tenants))
+	for _, tc := range r.tenants {
+		tenants = append(tenants, tc.tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+// DB 返回 ctx 中租户的连接，已绑定 ctx
+func (r *TenantRouter) DB(ctx context.Context) (*gorm.DB, error) {
+	id, ok := r.opts.Resolver(ctx)
+	if !ok || id == "" {
+		return nil, ErrNoTenant
+	}
+	db, err := r.Tenant(id)
+	if err != nil {
+		return nil, err
+	}
+	return db.WithContext(ctx), nil
+}
+
+// Tenant 返回指定租户的连接，首次调用时创建连接池，创建失败时不缓存错误，下次调用重新连接
+func (r *TenantRouter) Tenant(id string) (*gorm.DB, error) {
+	r.mu.RLock()
+	tc, ok := r.tenants[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, id)
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.closed {
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, id)
+	}
+	if tc.db == nil {
+		db, err := r.connect(tc.tenant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect tenant %s: %w", id, err)
+		}
+		tc.db = db
+	}
+	return tc.db, nil
+}
+
+// Migrate 依次在所有租户上执行迁移，返回每个租户本次执行的版本
+// 某个租户迁移失败时继续处理其他租户，返回的错误包含所有失败的租户
+func (r *TenantRouter) Migrate(ctx context.Context, opts *MigrationRunnerOptions, migrations ...Migration) (map[string][]string, error) {
+	applied := make(map[string][]string)
+	var errs []error
+	for _, t := range r.Tenants() {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		db, err := r.Tenant(t.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		runner, err := NewMigrationRunner(db, opts, migrations...)
+		if err != nil {
+			// 迁移定义错误与租户无关，直接返回
+			return applied, err
+		}
+		versions, err := runner.Run(TenantKey.WithValue(ctx, t.ID))
+		if len(versions) > 0 {
+			applied[t.ID] = versions
+		}
+		if err != nil {
+			log.FromContext(ctx).Error("Tenant migration failed",
+				zap.String("driver", r.driver),
+				zap.String("tenant", t.ID),
+				zap.Error(err),
+			)
+			errs = append(errs, fmt.Errorf("tenant %s: %w", t.ID, err))
+		}
+	}
+	return applied, errors.Join(errs...)
+}
+
+// Close 关闭所有租户的连接池
+func (r *TenantRouter) Close() error {
+	r.mu.Lock()
+	tenants := r.tenants
+	r.tenants = make(map[string]*tenantConn)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, tc := range tenants {
+		if err := tc.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// close 关闭租户的连接池，尚未创建时直接返回
+func (tc *tenantConn) close() error {
+	// 等待并发的首次连接完成后再关闭，之后的调用返回 ErrTenantNotFound
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.closed = true
+	if tc.db == nil {
+		return nil
+	}
+	return CloseSQLDB(tc.db)
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTenantRouterRetriesFailedConnect(t *testing.T) {
+	dir := t.TempDir()
+	attempts := 0
+	r := newTenantRouter(driverMySQL, nil)
+	r.connect = func(tenant Tenant) (*gorm.DB, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return gorm.Open(sqlite.Open(filepath.Join(dir, tenant.Database+".db")), &gorm.Config{Logger: logger.Discard})
+	}
+	if err := r.Register(Tenant{ID: "acme"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if _, err := r.Tenant("acme"); err == nil {
+		t.Fatal("first Tenant succeeded, want connect error")
+	}
+	db, err := r.Tenant("acme")
+	if err != nil {
+		t.Fatalf("Tenant after failed connect: %v", err)
+	}
+	again, err := r.Tenant("acme")
+	if err != nil || again != db {
+		t.Fatalf("Tenant = %p, %v, want cached %p", again, err, db)
+	}
+	if attempts != 2 {
+		t.Errorf("connect attempts = %d, want 2", attempts)
+	}
+
+	ctxDB, err := r.DB(TenantKey.WithValue(context.Background(), "acme"))
+	if err != nil || ctxDB == nil {
+		t.Fatalf("DB with tenant = %v, %v", ctxDB, err)
+	}
+	if _, err := r.DB(context.Background()); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("DB without tenant error = %v, want ErrNoTenant", err)
+	}
+
+	if err := r.Unregister("acme"); err != nil {
+		t.Fatalf("Unregister: %v", err)
+	}
+	if _, err := r.Tenant("acme"); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("Tenant after Unregister error = %v, want ErrTenantNotFound", err)
+	}
+}
+
+func TestTenantRouterRegister(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenant  Tenant
+		wantErr bool
+	}{
+		{name: "default database", tenant: Tenant{ID: "acme"}},
+		{name: "explicit database", tenant: Tenant{ID: "globex", Database: "tenant_globex"}},
+		{name: "empty id", tenant: Tenant{}, wantErr: true},
+		{name: "invalid database", tenant: Tenant{ID: "initech", Database: "a; DROP DATABASE b"}, wantErr: true},
+		{name: "duplicate", tenant: Tenant{ID: "acme"}, wantErr: true},
+	}
+	r := newTenantRouter(driverMySQL, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Register(tt.tenant); (err != nil) != tt.wantErr {
+				t.Errorf("Register error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
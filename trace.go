@@ -18,10 +18,10 @@ package db
 
 import (
 	"context"
+	"errors"
+	"net"
 	"time"
 
-	"github.com/go-anyway/framework-log"
-	"github.com/go-anyway/framework-metrics"
 	pkgtrace "github.com/go-anyway/framework-trace"
 
 	"github.com/redis/go-redis/v9"
@@ -33,19 +33,74 @@ import (
 
 // traceRedisHook 实现 redis.Hook 接口用于追踪命令（支持 OpenTelemetry）
 type traceRedisHook struct {
-	enableTrace bool // 是否启用 OpenTelemetry 追踪
+	enableTrace  bool // 是否启用 OpenTelemetry 追踪
+	opts         traceOptions
+	errorSampler *errorLogSampler // 相同错误日志去重
+	slo          *sloTracker      // SLI 统计
+	logFilter    *redisLogFilter  // 成功命令日志过滤，nil 表示全部记录
 }
 
 // newTraceRedisHook 创建新的 Redis 追踪 Hook
-func newTraceRedisHook(enableTrace bool) *traceRedisHook {
+func newTraceRedisHook(enableTrace bool, opts ...TraceOption) *traceRedisHook {
+	o := newTraceOptions(opts...)
 	return &traceRedisHook{
-		enableTrace: enableTrace,
+		enableTrace:  enableTrace,
+		opts:         o,
+		errorSampler: newErrorLogSampler(o.errorLogInterval),
+		slo:          sloTrackerFor(o.datasource, o.slo),
+		logFilter:    newRedisLogFilter(o.redisLog),
 	}
 }
 
-// DialHook 在建立连接时调用
+// DialHook 在建立连接时调用，记录建连耗时和失败，用于排查连接风暴和 DNS 问题
 func (h *traceRedisHook) DialHook(next redis.DialHook) redis.DialHook {
-	return next
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var span trace.Span
+		if h.enableTrace && h.opts.traceSampled(ctx) {
+			ctx, span = pkgtrace.StartSpan(ctx, "redis.dial",
+				trace.WithAttributes(h.opts.spanAttributes(
+					attribute.String("db.system", "redis"),
+					attribute.String("network.transport", network),
+					attribute.String("server.address", addr),
+				)...),
+			)
+			defer span.End()
+		}
+
+		start := time.Now()
+		conn, err := next(ctx, network, addr)
+		duration := time.Since(start)
+
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+
+		if h.enableTrace && span != nil {
+			span.SetAttributes(attribute.Float64("db.duration_ms", float64(duration.Milliseconds())))
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				span.RecordError(err)
+			} else {
+				span.SetStatus(codes.Ok, "")
+			}
+		}
+
+		if err != nil && h.errorSampler.allow("dial|"+addr+"|"+err.Error(), zap.String("operation", "dial"), zap.String("error", err.Error())) {
+			h.opts.logger(ctx).Error(
+				"Redis dial failed",
+				zap.String("network", network),
+				zap.String("addr", addr),
+				zap.Duration("duration", duration),
+				zap.Error(err),
+			)
+		}
+
+		if h.opts.metricsEnabled() {
+			h.opts.metrics.recordRedisDial(ctx, addr, status, duration)
+		}
+		return conn, err
+	}
 }
 
 // ProcessHook 在处理命令时调用
@@ -53,16 +108,16 @@ func (h *traceRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
 	return func(ctx context.Context, cmd redis.Cmder) error {
 		// 如果启用了追踪，创建 OpenTelemetry span
 		var span trace.Span
-		if h.enableTrace {
+		if h.enableTrace && h.opts.traceSampled(ctx) {
 			operation := cmd.Name()
 			if operation == "" {
 				operation = "unknown"
 			}
 			ctx, span = pkgtrace.StartSpan(ctx, "redis."+operation,
-				trace.WithAttributes(
+				trace.WithAttributes(h.opts.spanAttributes(
 					attribute.String("db.system", "redis"),
 					attribute.String("db.operation", operation),
-				),
+				)...),
 			)
 			defer span.End()
 		}
@@ -70,7 +125,6 @@ func (h *traceRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
 		start := time.Now()
 		err := next(ctx, cmd)
 		duration := time.Since(start)
-		durationSeconds := duration.Seconds()
 
 		// 确定操作类型和状态
 		operation := cmd.Name()
@@ -81,11 +135,13 @@ func (h *traceRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
 		if err != nil {
 			status = "error"
 		}
+		// 按脱敏策略格式化命令，日志和 span 使用同一份结果
+		statement := redactRedisCmd(cmd, h.opts.redisRedact)
 
 		// 如果启用了追踪，更新 span
 		if h.enableTrace && span != nil {
 			span.SetAttributes(
-				attribute.String("db.statement", cmd.String()),
+				attribute.String("db.statement", statement),
 				attribute.Float64("db.duration_ms", float64(duration.Milliseconds())),
 			)
 			if err != nil {
@@ -96,20 +152,57 @@ func (h *traceRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
 			}
 		}
 
-		// 记录日志
-		log.FromContext(ctx).Info(
-			"Redis command success",
-			zap.String("operation", operation),
-			zap.String("cmd", cmd.String()),
-			zap.Duration("duration", duration),
-			zap.String("status", status),
-		)
+		// 记录日志，相同错误在采样窗口内只记录一次（redis.Nil 表示键不存在，不视为故障）
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if h.errorSampler.allow(operation+"|"+err.Error(), zap.String("operation", operation), zap.String("error", err.Error())) {
+				h.opts.logger(ctx).Error(
+					"Redis command failed",
+					zap.String("operation", operation),
+					zap.String("cmd", statement),
+					zap.Duration("duration", duration),
+					zap.String("status", status),
+					zap.Error(err),
+				)
+			}
+		} else {
+			switch h.logFilter.decide(operation, duration) {
+			case redisLogInfo:
+				h.opts.logger(ctx).Info(
+					"Redis command success",
+					zap.String("operation", operation),
+					zap.String("cmd", statement),
+					zap.Duration("duration", duration),
+					zap.String("status", status),
+				)
+			case redisLogSlow:
+				h.opts.logger(ctx).Warn(
+					"Redis command slow",
+					zap.String("operation", operation),
+					zap.String("cmd", statement),
+					zap.Duration("duration", duration),
+					zap.String("status", status),
+				)
+			}
+		}
 
-		// 记录 Prometheus 指标（仅在启用时）
-		if metrics.IsEnabled() {
-			metrics.RedisOperationTotal.WithLabelValues(operation, status).Inc()
-			metrics.RedisOperationDuration.WithLabelValues(operation).Observe(durationSeconds)
+		// 记录指标（仅在启用时）
+		if h.opts.metricsEnabled() {
+			h.opts.metrics.recordRedis(ctx, operation, status, duration)
 		}
+		h.opts.recordInstance(ctx, "redis", operation, status, duration)
+		if err != nil {
+			h.opts.reportError(ctx, ErrorEvent{
+				System:      "redis",
+				Operation:   operation,
+				Statement:   redactRedisCmd(cmd, RedisRedactKey),
+				Fingerprint: QueryFingerprint(redisFingerprintStatement(cmd)),
+				Duration:    duration,
+				Err:         err,
+			})
+		}
+
+		// 记录 SLI（redis.Nil 表示键不存在，不计为失败）
+		h.slo.record(operation, err == nil || errors.Is(err, redis.Nil), duration)
 
 		return err
 	}
@@ -120,13 +213,13 @@ func (h *traceRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) red
 	return func(ctx context.Context, cmds []redis.Cmder) error {
 		// 如果启用了追踪，创建 OpenTelemetry span
 		var span trace.Span
-		if h.enableTrace {
+		if h.enableTrace && h.opts.traceSampled(ctx) {
 			ctx, span = pkgtrace.StartSpan(ctx, "redis.pipeline",
-				trace.WithAttributes(
+				trace.WithAttributes(h.opts.spanAttributes(
 					attribute.String("db.system", "redis"),
 					attribute.String("db.operation", "pipeline"),
 					attribute.Int("db.command_count", len(cmds)),
-				),
+				)...),
 			)
 			defer span.End()
 		}
@@ -134,7 +227,6 @@ func (h *traceRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) red
 		start := time.Now()
 		err := next(ctx, cmds)
 		duration := time.Since(start)
-		durationSeconds := duration.Seconds()
 
 		// 确定状态
 		status := "success"
@@ -147,6 +239,9 @@ func (h *traceRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) red
 			span.SetAttributes(
 				attribute.Float64("db.duration_ms", float64(duration.Milliseconds())),
 			)
+			if h.opts.pipelineCommandSpans {
+				h.startPipelineCommandSpans(ctx, span, cmds, start, duration)
+			}
 			if err != nil {
 				span.SetStatus(codes.Error, err.Error())
 				span.RecordError(err)
@@ -155,26 +250,105 @@ func (h *traceRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) red
 			}
 		}
 
-		// 记录日志
-		log.FromContext(ctx).Info(
-			"Redis pipeline success",
-			zap.Int("cmd_count", len(cmds)),
-			zap.Duration("duration", duration),
-			zap.String("status", status),
-		)
+		// 记录日志，相同错误在采样窗口内只记录一次
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if h.errorSampler.allow("pipeline|"+err.Error(), zap.String("operation", "pipeline"), zap.String("error", err.Error())) {
+				h.opts.logger(ctx).Error(
+					"Redis pipeline failed",
+					zap.Int("cmd_count", len(cmds)),
+					zap.Duration("duration", duration),
+					zap.String("status", status),
+					zap.Error(err),
+				)
+			}
+		} else {
+			switch h.logFilter.decide("pipeline", duration) {
+			case redisLogInfo:
+				h.opts.logger(ctx).Info(
+					"Redis pipeline success",
+					zap.Int("cmd_count", len(cmds)),
+					zap.Duration("duration", duration),
+					zap.String("status", status),
+				)
+			case redisLogSlow:
+				h.opts.logger(ctx).Warn(
+					"Redis pipeline slow",
+					zap.Int("cmd_count", len(cmds)),
+					zap.Duration("duration", duration),
+					zap.String("status", status),
+				)
+			}
+		}
 
-		// 记录 Prometheus 指标（仅在启用时，管道操作使用 "pipeline" 作为操作类型）
-		if metrics.IsEnabled() {
-			metrics.RedisOperationTotal.WithLabelValues("pipeline", status).Inc()
-			metrics.RedisOperationDuration.WithLabelValues("pipeline").Observe(durationSeconds)
+		// 记录指标（仅在启用时，管道操作使用 "pipeline" 作为操作类型）
+		if h.opts.metricsEnabled() {
+			h.opts.metrics.recordRedis(ctx, "pipeline", status, duration)
 		}
+		h.opts.recordInstance(ctx, "redis", "pipeline", status, duration)
+		if err != nil {
+			// 语句和指纹使用第一条失败的命令
+			event := ErrorEvent{System: "redis", Operation: "pipeline", Duration: duration, Err: err}
+			for _, cmd := range cmds {
+				if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+					event.Statement = redactRedisCmd(cmd, RedisRedactKey)
+					event.Fingerprint = QueryFingerprint("pipeline " + redisFingerprintStatement(cmd))
+					break
+				}
+			}
+			h.opts.reportError(ctx, event)
+		}
+
+		// 记录 SLI
+		h.slo.record("pipeline", err == nil || errors.Is(err, redis.Nil), duration)
 
 		return err
 	}
 }
 
-// addTraceHook 为 Redis 客户端添加追踪 Hook
-func addTraceHook(client *redis.Client, enableTrace bool) {
-	hook := newTraceRedisHook(enableTrace)
+// maxPipelineCommandSpans 单个管道最多创建的命令子 span 数
+const maxPipelineCommandSpans = 256
+
+// startPipelineCommandSpans 在管道 span 下为每条命令创建子 span，记录命令名、键、状态和耗时
+// 管道命令在同一次往返中发送和读取，每条命令的耗时即所在管道的往返耗时，子 span 的起止时间与管道一致
+func (h *traceRedisHook) startPipelineCommandSpans(ctx context.Context, parent trace.Span, cmds []redis.Cmder, start time.Time, duration time.Duration) {
+	end := start.Add(duration)
+	for i, cmd := range cmds {
+		if i == maxPipelineCommandSpans {
+			parent.SetAttributes(attribute.Int("db.redis.pipeline_spans_dropped", len(cmds)-i))
+			return
+		}
+		attrs := []attribute.KeyValue{
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", cmd.Name()),
+			attribute.Int("db.redis.pipeline_index", i),
+			attribute.Float64("db.duration_ms", float64(duration.Milliseconds())),
+		}
+		if key, ok := redisCmdKey(cmd); ok {
+			attrs = append(attrs, attribute.String("db.redis.key", key))
+		}
+		_, span := pkgtrace.StartSpan(ctx, "redis."+cmd.Name(),
+			trace.WithTimestamp(start),
+			trace.WithAttributes(h.opts.spanAttributes(attrs...)...),
+		)
+		// redis.Nil 表示键不存在，不视为失败
+		if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+			span.SetStatus(codes.Error, err.Error())
+			span.RecordError(err)
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		span.End(trace.WithTimestamp(end))
+	}
+}
+
+// release 释放 SLI 统计器，客户端关闭时调用
+func (h *traceRedisHook) release() {
+	h.slo.release()
+}
+
+// addTraceHook 为 Redis 客户端（单实例、Ring 等）添加追踪 Hook，客户端通过 CloseRedis 关闭时释放 SLI 统计器
+func addTraceHook(client redis.UniversalClient, enableTrace bool, opts ...TraceOption) {
+	hook := newTraceRedisHook(enableTrace, opts...)
 	client.AddHook(hook)
+	onHandleClose(client, hook.release)
 }
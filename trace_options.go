@@ -0,0 +1,281 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// TraceOption GORM 追踪插件和 Redis 追踪 Hook 的可选配置
+type TraceOption func(*traceOptions)
+
+// traceOptions 追踪插件和 Hook 共享的配置
+type traceOptions struct {
+	datasource       string        // 数据源标识，用于 SLI 等按数据源区分的指标
+	instance         string        // 逻辑连接名称，用于日志字段、指标标签和 span 属性，为空表示不区分
+	dbSystem         string        // span 的 db.system 属性，为空时 GORM 追踪插件使用 sql
+	errorLogInterval time.Duration // 相同错误日志的采样窗口，<= 0 表示不去重
+	slo              *SLOOptions   // SLI 指标配置，nil 表示不统计
+	slowQuery        time.Duration // 慢查询阈值，<= 0 表示不记录最近慢查询
+
+	slowQuerySinks []SlowQuerySink // 慢查询的额外输出，nil 表示只记录到最近慢查询列表
+	errorReporter  ErrorReporter   // 语句和命令失败时的上报，nil 表示不上报
+
+	maxResultRows      int  // 单次查询允许返回的最大行数，<= 0 表示不限制
+	abortOnLargeResult bool // 超过最大行数时返回 ErrResultTooLarge，否则仅记录告警日志
+
+	redisLog    *RedisLogOptions // Redis 成功命令日志的过滤配置，nil 表示全部记录
+	redisRedact string           // Redis 命令日志和 span 的脱敏策略
+
+	pipelineCommandSpans bool // 是否为 Redis 管道中的每条命令创建子 span
+
+	pprofLabels bool // 是否为语句执行设置 pprof 标签
+
+	traceSampleRate float64 // context 中没有 span 时创建 span 的比例，<= 0 或 >= 1 表示全部创建
+
+	metricsDisabled  bool            // 是否不记录该连接的指标
+	metricsNamespace string          // 查询/命令指标名前缀，为空时使用默认指标
+	metricsBackend   string          // 查询/命令指标的输出后端，为空时使用 Prometheus
+	metrics          metricsRecorder // 查询/命令指标，由 newTraceOptions 按后端和命名空间设置，禁用时为 nil
+}
+
+// newTraceOptions 应用选项并返回最终配置
+func newTraceOptions(opts ...TraceOption) traceOptions {
+	o := traceOptions{
+		datasource:       "default",
+		errorLogInterval: defaultErrorLogInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// 设置实例名后，按数据源区分的指标、SLI 和最近慢查询改为按实例区分
+	if o.instance != "" {
+		o.datasource = o.instance
+	}
+	if o.metricsDisabled {
+		o.slo = nil
+	} else {
+		o.metrics = newMetricsRecorder(o.metricsBackend, o.metricsNamespace, o.instance)
+	}
+	return o
+}
+
+// WithInstanceName 设置逻辑连接名称（如 orders-primary、cache），日志附加 instance 字段，
+// span 附加 db.instance 属性，并按实例记录 datasource_instance_requests_total 等指标。
+// 设置后并发查询数、SLI 和最近慢查询的 datasource 标签使用实例名
+func WithInstanceName(name string) TraceOption {
+	return func(o *traceOptions) {
+		o.instance = name
+	}
+}
+
+// WithErrorLogInterval 设置相同错误日志的去重窗口
+// 窗口内同一错误只记录首次出现，窗口结束时输出被抑制的次数
+// d 为 0 时使用默认值 10s，为负数时不去重
+func WithErrorLogInterval(d time.Duration) TraceOption {
+	return func(o *traceOptions) {
+		if d != 0 {
+			o.errorLogInterval = d
+		}
+	}
+}
+
+// WithSLO 启用按数据源聚合的 SLI 指标（滚动窗口成功率、延迟阈值违规次数）
+func WithSLO(slo *SLOOptions) TraceOption {
+	return func(o *traceOptions) {
+		o.slo = slo
+	}
+}
+
+// WithSlowQueryThreshold 记录耗时超过 d 的语句到按数据源保存的最近慢查询列表（仅对 GORM 追踪插件生效），
+// 可通过 RecentSlowQueries 或 Manager.DebugHandler 查看
+func WithSlowQueryThreshold(d time.Duration) TraceOption {
+	return func(o *traceOptions) {
+		o.slowQuery = d
+	}
+}
+
+// WithSlowQuerySinks 将慢查询额外写入 sinks（如 SlowQueryFileSink、SlowQueryStreamSink），
+// 需同时设置 WithSlowQueryThreshold（仅对 GORM 追踪插件生效）。连接关闭时不关闭 sinks
+func WithSlowQuerySinks(sinks ...SlowQuerySink) TraceOption {
+	return func(o *traceOptions) {
+		o.slowQuerySinks = append(o.slowQuerySinks, sinks...)
+	}
+}
+
+// WithErrorReporter 在 SQL 语句或 Redis 命令失败时调用 r，携带语句指纹、操作类型、耗时和错误分类
+func WithErrorReporter(r ErrorReporter) TraceOption {
+	return func(o *traceOptions) {
+		o.errorReporter = r
+	}
+}
+
+// WithMaxResultRows 限制单次查询返回的最大行数（仅对 GORM 追踪插件生效）
+// abort 为 true 时为未设置 LIMIT 的查询追加 LIMIT n+1，超过时返回 ErrResultTooLarge；为 false 时仅记录告警日志
+func WithMaxResultRows(n int, abort bool) TraceOption {
+	return func(o *traceOptions) {
+		o.maxResultRows = n
+		o.abortOnLargeResult = abort
+	}
+}
+
+// WithRedisLogOptions 设置 Redis 成功命令日志的过滤、采样和慢命令模式（仅对 Redis 追踪 Hook 生效）
+func WithRedisLogOptions(opts *RedisLogOptions) TraceOption {
+	return func(o *traceOptions) {
+		o.redisLog = opts
+	}
+}
+
+// WithRedisRedact 设置 Redis 命令日志和 span 中命令参数的脱敏策略（仅对 Redis 追踪 Hook 生效）
+// mode 为 RedisRedactKey 时仅记录命令名和键，为 RedisRedactHash 时其余参数替换为哈希
+func WithRedisRedact(mode string) TraceOption {
+	return func(o *traceOptions) {
+		o.redisRedact = mode
+	}
+}
+
+// WithPipelineCommandSpans 在 Redis 管道 span 下为每条命令创建子 span，记录命令名、键、状态和耗时（仅对 Redis 追踪 Hook 生效）
+// 单个管道最多创建 256 个子 span，用于定位大管道中失败或异常的命令
+func WithPipelineCommandSpans(enabled bool) TraceOption {
+	return func(o *traceOptions) {
+		o.pipelineCommandSpans = enabled
+	}
+}
+
+// WithPprofLabels 在语句执行期间为 goroutine 设置 pprof 标签 db_datasource、db_operation、db_table（仅对 GORM 追踪插件生效），
+// CPU 和 goroutine profile 可按标签过滤（如 go tool pprof -tagfocus=db_table=orders），定位热点查询。
+// 语句执行中派生的 goroutine 继承标签
+func WithPprofLabels(enabled bool) TraceOption {
+	return func(o *traceOptions) {
+		o.pprofLabels = enabled
+	}
+}
+
+// WithTraceSampleRate 设置 context 中没有 span 时创建 span 的比例，取值 (0, 1]，0 表示全部创建。
+// 上游已有 span 时始终创建子 span，保持链路完整；未采样的操作仍记录日志和指标
+func WithTraceSampleRate(rate float64) TraceOption {
+	return func(o *traceOptions) {
+		o.traceSampleRate = rate
+	}
+}
+
+// WithMetricsDisabled 不记录该连接的查询/命令、并发查询数、实例和 SLI 指标，用于批处理任务等
+// 与服务共享 Registry、但不希望其流量混入服务指标的连接。追踪、日志和慢查询记录不受影响
+func WithMetricsDisabled(disabled bool) TraceOption {
+	return func(o *traceOptions) {
+		o.metricsDisabled = disabled
+	}
+}
+
+// WithMetricsNamespace 为该连接的查询/命令、并发查询数和实例指标名添加前缀 <ns>_（如 batch_database_queries_total），
+// 标签和分桶与默认指标相同，同一命名空间的连接共享指标。ns 为空时使用默认指标
+func WithMetricsNamespace(ns string) TraceOption {
+	return func(o *traceOptions) {
+		o.metricsNamespace = ns
+	}
+}
+
+// WithMetricsBackend 设置查询/命令指标的输出后端：MetricsBackendPrometheus（默认）、MetricsBackendOTel 或 MetricsBackendBoth。
+// OpenTelemetry 指标通过全局 MeterProvider 记录为 db.client.operations、db.client.operation.duration 等 instrument，
+// 由应用配置的 exporter（如 OTLP）导出到 collector；Prometheus 指标仍受 framework-metrics 的全局开关控制
+func WithMetricsBackend(backend string) TraceOption {
+	return func(o *traceOptions) {
+		o.metricsBackend = backend
+	}
+}
+
+// metricsEnabled 返回是否记录该连接的指标
+func (o *traceOptions) metricsEnabled() bool {
+	return o.metrics != nil && o.metrics.enabled()
+}
+
+// withDatasource 设置数据源标识
+func withDatasource(name string) TraceOption {
+	return func(o *traceOptions) {
+		if name != "" {
+			o.datasource = name
+		}
+	}
+}
+
+// withDBSystem 设置 span 的 db.system 属性
+func withDBSystem(name string) TraceOption {
+	return func(o *traceOptions) {
+		o.dbSystem = name
+	}
+}
+
+// logger 返回附加实例名字段的日志记录器
+func (o *traceOptions) logger(ctx context.Context) *zap.Logger {
+	l := log.FromContext(ctx)
+	if o.instance != "" {
+		l = l.With(zap.String("instance", o.instance))
+	}
+	return l
+}
+
+// spanAttributes 在 span 属性中附加实例名
+func (o *traceOptions) spanAttributes(attrs ...attribute.KeyValue) []attribute.KeyValue {
+	if o.instance != "" {
+		attrs = append(attrs, attribute.String("db.instance", o.instance))
+	}
+	return attrs
+}
+
+// traceSampled 判断是否为本次操作创建 span
+func (o *traceOptions) traceSampled(ctx context.Context) bool {
+	if o.traceSampleRate <= 0 || o.traceSampleRate >= 1 {
+		return true
+	}
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return true
+	}
+	return rand.Float64() < o.traceSampleRate
+}
+
+// recordInstance 记录按实例区分的请求指标，未设置实例名时不记录
+func (o *traceOptions) recordInstance(ctx context.Context, system, operation, status string, d time.Duration) {
+	if o.instance == "" || !o.metricsEnabled() {
+		return
+	}
+	o.metrics.recordInstance(ctx, o.instance, system, operation, status, d)
+}
+
+// writeSlowQuery 将慢查询写入 sinks，写入失败只记录告警日志
+func (o *traceOptions) writeSlowQuery(ctx context.Context, q SlowQuery) {
+	for _, sink := range o.slowQuerySinks {
+		if err := sink.WriteSlowQuery(ctx, q); err != nil {
+			o.logger(ctx).Warn("Failed to write slow query to sink", zap.String("operation", q.Operation), zap.Error(err))
+		}
+	}
+}
+
+// instanceOr 返回实例名，未设置时返回 fallback
+func instanceOr(instance, fallback string) string {
+	if instance != "" {
+		return instance
+	}
+	return fallback
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// ErrTxInProgress 在已开启的事务中调用了需要设置事务特性的辅助函数。
+// 嵌套调用 Transaction 时 GORM 使用保存点，隔离级别和只读设置会被静默忽略，因此直接返回错误
+var ErrTxInProgress = errors.New("transaction characteristics cannot be changed inside a transaction")
+
+// txCharacteristicsPattern SET TRANSACTION 允许的特性：关键字、空格和逗号
+var txCharacteristicsPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z ,]*$`)
+
+// WithinReadOnlyTx 在只读事务中执行 fn，MySQL 使用 START TRANSACTION READ ONLY，PostgreSQL 使用 BEGIN READ ONLY，
+// 事务中的写语句由数据库拒绝。MySQL 的只读事务不分配事务 ID，适合一致性快照读。
+// 按连接配置重试可重试的冲突，fn 可能被执行多次
+func WithinReadOnlyTx(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if inTx(db) {
+		return ErrTxInProgress
+	}
+	return ExecuteTx(ctx, db, fn, &sql.TxOptions{ReadOnly: true})
+}
+
+// WithinTxIsolation 以指定隔离级别执行事务，如 db.WithinTxIsolation(ctx, conn, sql.LevelSerializable, fn)。
+// MySQL 和 PostgreSQL 支持 ReadUncommitted、ReadCommitted、RepeatableRead、Serializable，
+// LevelSnapshot 对应 REPEATABLE READ，其余级别返回错误。隔离级别只作用于本次事务，不影响连接池中的其他事务。
+// 按连接配置重试可重试的冲突（Serializable 下的序列化失败），fn 可能被执行多次
+func WithinTxIsolation(ctx context.Context, db *gorm.DB, level sql.IsolationLevel, fn func(tx *gorm.DB) error) error {
+	if inTx(db) {
+		return ErrTxInProgress
+	}
+	level, err := txIsolationFor(dialectOf(db), level)
+	if err != nil {
+		return err
+	}
+	return ExecuteTx(ctx, db, fn, &sql.TxOptions{Isolation: level})
+}
+
+// WithinTxSettings 以 SET TRANSACTION 设置事务特性后执行 fn，用于 sql.TxOptions 无法表达的特性，
+// 如 PostgreSQL 的 "ISOLATION LEVEL SERIALIZABLE, READ ONLY, DEFERRABLE"。特性只作用于本次事务：
+// MySQL 的 SET TRANSACTION 作用于同一会话的下一个事务，在固定的连接上于 BEGIN 之前执行；
+// PostgreSQL 要求其为事务的第一条语句，在 BEGIN 之后、fn 之前执行。
+// 直接在连接池上执行 SET TRANSACTION 可能作用于其他请求的事务，应使用此函数代替。
+// 按连接配置重试可重试的冲突，fn 可能被执行多次
+func WithinTxSettings(ctx context.Context, db *gorm.DB, characteristics string, fn func(tx *gorm.DB) error) error {
+	if inTx(db) {
+		return ErrTxInProgress
+	}
+	if !txCharacteristicsPattern.MatchString(characteristics) {
+		return fmt.Errorf("invalid transaction characteristics: %q", characteristics)
+	}
+	stmt := "SET TRANSACTION " + characteristics
+
+	switch dialectOf(db) {
+	case "mysql":
+		return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+			return executeTx(ctx, conn, func(conn *gorm.DB) error {
+				return conn.Exec(stmt).Error
+			}, fn)
+		})
+	case "postgres":
+		return ExecuteTx(ctx, db, func(tx *gorm.DB) error {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+			return fn(tx)
+		})
+	default:
+		return fmt.Errorf("WithinTxSettings does not support dialect %q", dialectOf(db))
+	}
+}
+
+// txIsolationFor 检查 MySQL 和 PostgreSQL 支持的隔离级别，返回驱动接受的级别，其他方言由驱动检查
+func txIsolationFor(dialect string, level sql.IsolationLevel) (sql.IsolationLevel, error) {
+	if dialect != "mysql" && dialect != "postgres" {
+		return level, nil
+	}
+	switch level {
+	case sql.LevelDefault, sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelRepeatableRead, sql.LevelSerializable:
+		return level, nil
+	case sql.LevelSnapshot:
+		return sql.LevelRepeatableRead, nil
+	}
+	return level, fmt.Errorf("%s does not support isolation level %s", dialect, level)
+}
+
+// inTx 判断 db 是否已在事务中
+func inTx(db *gorm.DB) bool {
+	committer, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestTxIsolationFor(t *testing.T) {
+	tests := []struct {
+		dialect string
+		level   sql.IsolationLevel
+		want    sql.IsolationLevel
+		wantErr bool
+	}{
+		{"mysql", sql.LevelDefault, sql.LevelDefault, false},
+		{"mysql", sql.LevelReadCommitted, sql.LevelReadCommitted, false},
+		{"mysql", sql.LevelSerializable, sql.LevelSerializable, false},
+		{"mysql", sql.LevelSnapshot, sql.LevelRepeatableRead, false},
+		{"mysql", sql.LevelWriteCommitted, 0, true},
+		{"mysql", sql.LevelLinearizable, 0, true},
+		{"postgres", sql.LevelReadUncommitted, sql.LevelReadUncommitted, false},
+		{"postgres", sql.LevelRepeatableRead, sql.LevelRepeatableRead, false},
+		{"postgres", sql.LevelSnapshot, sql.LevelRepeatableRead, false},
+		{"postgres", sql.LevelLinearizable, 0, true},
+		{"sqlserver", sql.LevelSnapshot, sql.LevelSnapshot, false},
+		{"oracle", sql.LevelLinearizable, sql.LevelLinearizable, false},
+	}
+	for _, tt := range tests {
+		got, err := txIsolationFor(tt.dialect, tt.level)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("txIsolationFor(%s, %s) expected error", tt.dialect, tt.level)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("txIsolationFor(%s, %s) = %s, %v, want %s", tt.dialect, tt.level, got, err, tt.want)
+		}
+	}
+}
+
+func TestTxCharacteristicsPattern(t *testing.T) {
+	tests := []struct {
+		characteristics string
+		want            bool
+	}{
+		{"READ ONLY", true},
+		{"ISOLATION LEVEL SERIALIZABLE, READ ONLY, DEFERRABLE", true},
+		{"isolation level read committed", true},
+		{"", false},
+		{" READ ONLY", false},
+		{"READ ONLY; DROP TABLE users", false},
+		{"READ ONLY -- comment", false},
+		{"SNAPSHOT '00000003-1'", false},
+		{"READ\nONLY", false},
+	}
+	for _, tt := range tests {
+		if got := txCharacteristicsPattern.MatchString(tt.characteristics); got != tt.want {
+			t.Errorf("txCharacteristicsPattern.MatchString(%q) = %v, want %v", tt.characteristics, got, tt.want)
+		}
+	}
+}
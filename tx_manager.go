@@ -0,0 +1,67 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// txContextKey 保存在 context 中的事务
+type txContextKey struct{}
+
+// TxManager 通过 context 传递事务，使多个仓储在同一事务中执行而无需显式传递 *gorm.DB
+type TxManager struct {
+	db *gorm.DB
+}
+
+// NewTxManager 创建事务管理器
+func NewTxManager(db *gorm.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// Do 在事务中执行 fn，fn 中通过 ctx 调用的仓储方法使用同一事务
+// ctx 中已有事务时直接加入该事务；否则通过 ExecuteTx 开启新事务，按连接配置重试可重试的冲突，fn 可能被执行多次
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+	return ExecuteTx(ctx, m.db, func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	}, opts...)
+}
+
+// DB 返回 ctx 中的事务，不在事务中时返回绑定 ctx 的连接
+func (m *TxManager) DB(ctx context.Context) *gorm.DB {
+	return dbFromContext(ctx, m.db)
+}
+
+// TxFromContext 返回 ctx 中由 TxManager 开启的事务
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*gorm.DB)
+	return tx, ok
+}
+
+// dbFromContext 优先使用 ctx 中的事务，否则使用 db
+func dbFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
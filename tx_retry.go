@@ -0,0 +1,130 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-anyway/framework-log"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	txRetryPluginName = "TxRetryPlugin"
+
+	defaultCockroachTxRetries = 5                      // CockroachDB 模式下 ExecuteTx 的默认最大重试次数
+	defaultTiDBTxRetries      = 5                      // TiDB 模式下 ExecuteTx 的默认最大重试次数
+	txRetryBaseBackoff        = 10 * time.Millisecond  // 首次重试前的等待时间，之后按指数增长
+	txRetryMaxBackoff         = 500 * time.Millisecond // 重试等待时间上限
+)
+
+// txRetryPlugin 保存连接的事务重试配置，供 ExecuteTx 读取
+type txRetryPlugin struct {
+	datasource string // 数据源标识，用于日志
+	retries    int    // 最大重试次数，0 表示不重试
+}
+
+// Name 返回事务重试插件的名称
+func (p *txRetryPlugin) Name() string {
+	return txRetryPluginName
+}
+
+// Initialize 事务重试在 ExecuteTx 中完成，无需注册回调
+func (p *txRetryPlugin) Initialize(*gorm.DB) error {
+	return nil
+}
+
+// 确保 txRetryPlugin 实现了 gorm.Plugin 接口
+var _ gorm.Plugin = &txRetryPlugin{}
+
+// ExecuteTx 在事务中执行 fn，遇到序列化失败、死锁或 TiDB 写冲突时回滚并重试整个事务
+// 重试次数由连接的 tx_retries 配置决定（CockroachDB 和 TiDB 模式默认 5 次），未配置时等同于 db.Transaction；
+// fn 可能被执行多次，不应包含事务之外的副作用
+func ExecuteTx(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	return executeTx(ctx, db, nil, fn, opts...)
+}
+
+// executeTx 实现 ExecuteTx，每次尝试开启事务前在 db 上执行 prepare（如在固定连接上执行 SET TRANSACTION）
+func executeTx(ctx context.Context, db *gorm.DB, prepare func(db *gorm.DB) error, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	p, _ := db.Config.Plugins[txRetryPluginName].(*txRetryPlugin)
+	for attempt := 0; ; attempt++ {
+		var err error
+		if prepare != nil {
+			err = prepare(db.WithContext(ctx))
+		}
+		if err == nil {
+			err = db.WithContext(ctx).Transaction(fn, opts...)
+		}
+		if err == nil || p == nil || attempt >= p.retries || !isTxRetryable(err) {
+			return err
+		}
+
+		backoff := txRetryBackoff(attempt)
+		log.FromContext(ctx).Warn(
+			"Retrying transaction after retryable error",
+			zap.String("datasource", p.datasource),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// isTxRetryable 判断错误是否为可通过重试整个事务解决的冲突
+func isTxRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001"
+	}
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1213, // 死锁
+			8002, // TiDB 乐观事务中 SELECT FOR UPDATE 遇到写冲突
+			8022, // TiDB 乐观事务提交时写冲突且自动重试失败
+			8028, // TiDB 事务执行期间表结构发生变更
+			9007: // TiDB 写冲突
+			return true
+		}
+	}
+	return false
+}
+
+// txRetryBackoff 返回第 attempt 次重试前的等待时间（带随机抖动的指数退避）
+func txRetryBackoff(attempt int) time.Duration {
+	d := txRetryBaseBackoff << attempt
+	if d <= 0 || d > txRetryMaxBackoff {
+		d = txRetryMaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsTxRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("boom"), false},
+		{"postgres serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"postgres unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"mysql deadlock", &mysqldriver.MySQLError{Number: 1213}, true},
+		{"mysql lock wait timeout", &mysqldriver.MySQLError{Number: 1205}, false},
+		{"mysql duplicate entry", &mysqldriver.MySQLError{Number: 1062}, false},
+		{"tidb write conflict", &mysqldriver.MySQLError{Number: 9007}, true},
+		{"tidb optimistic commit conflict", &mysqldriver.MySQLError{Number: 8022}, true},
+		{"tidb schema changed", &mysqldriver.MySQLError{Number: 8028}, true},
+		{"wrapped", fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTxRetryable(tt.err); got != tt.want {
+				t.Errorf("isTxRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTxRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		max     time.Duration // 抖动前的退避时间，结果位于 [max/2, max]
+	}{
+		{0, 10 * time.Millisecond},
+		{1, 20 * time.Millisecond},
+		{3, 80 * time.Millisecond},
+		{5, 320 * time.Millisecond},
+		{6, txRetryMaxBackoff},
+		{20, txRetryMaxBackoff},
+		{100, txRetryMaxBackoff}, // 移位溢出
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			got := txRetryBackoff(tt.attempt)
+			if got < tt.max/2 || got > tt.max {
+				t.Fatalf("txRetryBackoff(%d) = %v, want within [%v, %v]", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+}
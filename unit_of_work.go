@@ -0,0 +1,179 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnitOfWorkCommitted 工作单元已提交或已丢弃
+var ErrUnitOfWorkCommitted = errors.New("unit of work already committed")
+
+// 写操作的执行阶段，提交时按阶段顺序执行
+const (
+	uowPhaseInsert = iota
+	uowPhaseUpdate
+	uowPhaseDelete
+	uowPhaseDeferred
+)
+
+// uowOp 工作单元中登记的写操作
+type uowOp struct {
+	phase int
+	table string
+	seq   int
+	run   func(tx *gorm.DB) error
+}
+
+// UnitOfWork 收集多个仓储的写操作，在 Commit 时于同一事务中按确定的顺序执行
+// 执行顺序：新增 → 更新 → 删除 → Defer 登记的自定义操作；
+// 同一阶段内按表名排序（使并发事务以相同顺序加锁，降低死锁概率），同一张表按登记顺序执行
+type UnitOfWork struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	ops       []uowOp
+	committed bool
+}
+
+// NewUnitOfWork 创建工作单元
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// RegisterNew 登记需要新增的实体
+func (u *UnitOfWork) RegisterNew(entities ...any) {
+	for _, e := range entities {
+		entity := e
+		u.add(uowPhaseInsert, entity, func(tx *gorm.DB) error {
+			return tx.Create(entity).Error
+		})
+	}
+}
+
+// RegisterDirty 登记需要更新的实体（按主键保存全部字段）
+func (u *UnitOfWork) RegisterDirty(entities ...any) {
+	for _, e := range entities {
+		entity := e
+		u.add(uowPhaseUpdate, entity, func(tx *gorm.DB) error {
+			return tx.Save(entity).Error
+		})
+	}
+}
+
+// RegisterUpdates 登记按主键更新实体的部分字段，updates 可以是 map 或结构体
+func (u *UnitOfWork) RegisterUpdates(entity any, updates any) {
+	u.add(uowPhaseUpdate, entity, func(tx *gorm.DB) error {
+		return tx.Model(entity).Updates(updates).Error
+	})
+}
+
+// RegisterDeleted 登记需要删除的实体
+func (u *UnitOfWork) RegisterDeleted(entities ...any) {
+	for _, e := range entities {
+		entity := e
+		u.add(uowPhaseDelete, entity, func(tx *gorm.DB) error {
+			return tx.Delete(entity).Error
+		})
+	}
+}
+
+// Defer 登记自定义写操作，在所有实体写操作之后按登记顺序执行
+func (u *UnitOfWork) Defer(fn func(tx *gorm.DB) error) {
+	u.add(uowPhaseDeferred, nil, fn)
+}
+
+// Len 返回已登记的写操作数
+func (u *UnitOfWork) Len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.ops)
+}
+
+// Commit 在单个事务中执行所有登记的写操作，任一操作失败时回滚全部操作
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	u.mu.Lock()
+	if u.committed {
+		u.mu.Unlock()
+		return ErrUnitOfWorkCommitted
+	}
+	u.committed = true
+	ops := u.ops
+	u.ops = nil
+	u.mu.Unlock()
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ops[i].phase != ops[j].phase {
+			return ops[i].phase < ops[j].phase
+		}
+		if ops[i].phase == uowPhaseDeferred || ops[i].table == ops[j].table {
+			return ops[i].seq < ops[j].seq
+		}
+		return ops[i].table < ops[j].table
+	})
+
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, op := range ops {
+			if err := op.run(tx); err != nil {
+				if op.table != "" {
+					return fmt.Errorf("unit of work: %s: %w", op.table, err)
+				}
+				return fmt.Errorf("unit of work: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Discard 丢弃所有登记的写操作
+func (u *UnitOfWork) Discard() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.ops = nil
+	u.committed = true
+}
+
+// add 登记写操作
+func (u *UnitOfWork) add(phase int, entity any, run func(tx *gorm.DB) error) {
+	table := ""
+	if entity != nil {
+		table = u.tableOf(entity)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.ops = append(u.ops, uowOp{phase: phase, table: table, seq: len(u.ops), run: run})
+}
+
+// tableOf 解析实体对应的表名，解析失败时返回空字符串
+func (u *UnitOfWork) tableOf(entity any) string {
+	stmt := &gorm.Statement{DB: u.db}
+	if err := stmt.Parse(entity); err != nil || stmt.Schema == nil {
+		return ""
+	}
+	return stmt.Schema.Table
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// utcWalkDepth 转换结果中时间字段时递归的最大深度，覆盖嵌入结构体和预加载的关联
+const utcWalkDepth = 4
+
+// timeType time.Time 的反射类型
+var timeType = reflect.TypeOf(time.Time{})
+
+// ForceUTC 使连接上的时间统一以 UTC 存储和读取，不受 MySQL DSN 中 loc 设置的影响，
+// 用于消除 loc=Local 与 loc=UTC 的服务混用同一个库时的时区偏移：
+//   - 写入时将语句参数中的 time.Time（包括 sql.NullTime、gorm.DeletedAt 等返回时间的 Valuer）转换为 UTC，
+//     MySQL 下按 DSN 的 loc 调整，使 DATETIME 列中保存的是 UTC 的年月日时分秒；
+//   - 读取后将结果（结构体、切片、map）中的时间字段按 UTC 解释并转换为 UTC 时区；
+//   - 新增和更新后将模型中的时间字段（包括 CreatedAt、UpdatedAt）转换为 UTC 时区。
+//
+// 通过 Row、Rows 读取的结果不会转换。MySQL 的 TIMESTAMP 列和 NOW() 受会话 time_zone 影响，
+// 需同时将会话变量 time_zone 设置为 '+00:00'
+func ForceUTC(db *gorm.DB) error {
+	loc := time.UTC
+	if d, ok := db.Dialector.(*mysql.Dialector); ok {
+		loc = time.Local // go-sql-driver/mysql 未设置 loc 时的默认值
+		if d.DSNConfig != nil && d.DSNConfig.Loc != nil {
+			loc = d.DSNConfig.Loc
+		} else if cfg, err := mysqldriver.ParseDSN(d.DSN); err == nil && cfg.Loc != nil {
+			loc = cfg.Loc
+		}
+	}
+	return Intercept(db, utcInterceptor(loc))
+}
+
+// utcInterceptor 返回转换时间的拦截器，loc 为驱动解析和格式化时间使用的时区
+func utcInterceptor(loc *time.Location) Interceptor {
+	toDB := func(t time.Time) time.Time {
+		if loc == time.UTC {
+			return t.UTC()
+		}
+		u := t.UTC()
+		return time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), u.Nanosecond(), loc)
+	}
+	fromDB := func(t time.Time) time.Time {
+		if t.Location() == time.UTC {
+			return t // 已转换（如预加载的关联）
+		}
+		l := t.In(loc)
+		return time.Date(l.Year(), l.Month(), l.Day(), l.Hour(), l.Minute(), l.Second(), l.Nanosecond(), time.UTC)
+	}
+	toUTC := func(t time.Time) time.Time {
+		return t.UTC()
+	}
+
+	return func(next Handler) Handler {
+		return func(db *gorm.DB) {
+			pool := db.Statement.ConnPool
+			db.Statement.ConnPool = &utcConnPool{ConnPool: pool, convert: toDB}
+			next(db)
+			db.Statement.ConnPool = pool
+			if db.Error != nil || !db.Statement.ReflectValue.IsValid() {
+				return
+			}
+			switch OperationKind(db) {
+			case "query":
+				convertTimes(db.Statement.ReflectValue, fromDB, utcWalkDepth)
+			case "create", "update":
+				convertTimes(db.Statement.ReflectValue, toUTC, utcWalkDepth)
+			}
+		}
+	}
+}
+
+// convertTimes 转换 v 中可设置的时间值
+func convertTimes(v reflect.Value, fn func(time.Time) time.Time, depth int) {
+	if depth < 0 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			convertTimes(v.Elem(), fn, depth)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			convertTimes(v.Index(i), fn, depth)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if t, ok := iter.Value().Interface().(time.Time); ok {
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(fn(t)))
+			}
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			if v.CanSet() {
+				v.Set(reflect.ValueOf(fn(v.Interface().(time.Time))))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				convertTimes(v.Field(i), fn, depth-1)
+			}
+		}
+	}
+}
+
+// utcConnPool 执行语句前转换参数中的时间
+type utcConnPool struct {
+	gorm.ConnPool
+	convert func(time.Time) time.Time
+}
+
+// args 返回转换后的参数，不修改调用方的切片
+func (p *utcConnPool) args(args []any) []any {
+	var out []any
+	for i, arg := range args {
+		t, ok := utcArgTime(arg)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]any(nil), args...)
+		}
+		out[i] = p.convert(t)
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// utcArgTime 返回参数表示的时间，支持 time.Time、*time.Time 和返回 time.Time 的 Valuer
+func utcArgTime(arg any) (time.Time, bool) {
+	switch v := arg.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	case driver.Valuer:
+		if isNilValuer(v) {
+			return time.Time{}, false
+		}
+		if dv, err := v.Value(); err == nil {
+			t, ok := dv.(time.Time)
+			return t, ok
+		}
+	}
+	return time.Time{}, false
+}
+
+// isNilValuer 判断 Valuer 是否为 nil 指针，避免调用时 panic
+func isNilValuer(v driver.Valuer) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// ExecContext 执行语句
+func (p *utcConnPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return p.ConnPool.ExecContext(ctx, query, p.args(args)...)
+}
+
+// QueryContext 执行查询
+func (p *utcConnPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.ConnPool.QueryContext(ctx, query, p.args(args)...)
+}
+
+// QueryRowContext 执行单行查询
+func (p *utcConnPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.ConnPool.QueryRowContext(ctx, query, p.args(args)...)
+}
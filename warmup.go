@@ -0,0 +1,145 @@
+// Copyright 2025 zampo.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// @contact  zampo3380@gmail.com
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// warmUpTimeout 创建连接时预热连接池的超时
+const warmUpTimeout = 30 * time.Second
+
+// WarmUp 同时从连接池取出 n 个连接并逐个 Ping，全部成功后归还连接池，用于服务就绪前预先建立连接，
+// 避免部署后首批请求因建立连接产生的延迟尖峰。n 应不超过最大空闲连接数，超出部分归还时会被关闭
+func WarmUp(ctx context.Context, db *gorm.DB, n int) error {
+	sqlDB, err := SQLDB(db)
+	if err != nil {
+		return err
+	}
+	return warmUpSQL(ctx, sqlDB, n)
+}
+
+// warmUpSQL 预热 SQL 连接池
+func warmUpSQL(ctx context.Context, sqlDB *sql.DB, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	conns := make([]*sql.Conn, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := sqlDB.Conn(ctx)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			conns[i] = conn
+			errs[i] = conn.PingContext(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to warm up connection pool: %w", err)
+	}
+	return nil
+}
+
+// WarmUpRedis 同时从连接池取出 n 个连接并逐个 Ping，全部成功后归还连接池
+// 与 MinIdleConns 在后台异步建立连接不同，WarmUpRedis 在连接就绪后才返回
+func WarmUpRedis(ctx context.Context, client *redis.Client, n int) error {
+	if client == nil {
+		return ErrInvalidHandle
+	}
+	if n <= 0 {
+		return nil
+	}
+	conns := make([]*redis.Conn, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conns[i] = client.Conn()
+			errs[i] = conns[i].Ping(ctx).Err()
+		}()
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to warm up redis connection pool: %w", err)
+	}
+	return nil
+}
+
+// WarmUp 预热所有已注册的 SQL 和 Redis 连接池，每个连接池建立 n 个连接
+func (m *Manager) WarmUp(ctx context.Context, n int) error {
+	m.mu.RLock()
+	dbs := make(map[string]*gorm.DB, len(m.sql))
+	for name, conn := range m.sql {
+		dbs[name] = conn.db
+	}
+	clients := make(map[string]*redis.Client, len(m.redis))
+	for name, conn := range m.redis {
+		clients[name] = conn.client
+	}
+	m.mu.RUnlock()
+
+	var errs []error
+	for name, db := range dbs {
+		if err := WarmUp(ctx, db, n); err != nil {
+			errs = append(errs, fmt.Errorf("database %q: %w", name, err))
+		}
+	}
+	for name, client := range clients {
+		if err := WarmUpRedis(ctx, client, n); err != nil {
+			errs = append(errs, fmt.Errorf("redis %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// warmUpOnCreate 创建连接时按配置预热连接池，连接数不超过最大空闲连接数
+func warmUpOnCreate(sqlDB *sql.DB, n, maxIdle int) error {
+	if maxIdle <= 0 {
+		maxIdle = 2 // database/sql 的默认值
+	}
+	n = min(n, maxIdle)
+	ctx, cancel := context.WithTimeout(context.Background(), warmUpTimeout)
+	defer cancel()
+	return warmUpSQL(ctx, sqlDB, n)
+}